@@ -42,6 +42,14 @@ const (
 	// DefaultMaxSelectSeriesN is the maximum number of series a SELECT can run.
 	// A value of zero will make the maximum series count unlimited.
 	DefaultMaxSelectBucketsN = 0
+
+	// DefaultHeartbeatInterval is how often the data node sends a heartbeat
+	// to the meta servers, well within their data-node-liveness-timeout.
+	DefaultHeartbeatInterval = 10 * time.Second
+
+	// DefaultShardSizeReportInterval is how often the data node sends the
+	// sizes of its shards to the meta servers, for shard group auto-tuning.
+	DefaultShardSizeReportInterval = 10 * time.Minute
 )
 
 // Config represents the configuration for the clustering service.
@@ -58,6 +66,14 @@ type Config struct {
 	MaxSelectPointN           int           `toml:"max-select-point"`
 	MaxSelectSeriesN          int           `toml:"max-select-series"`
 	MaxSelectBucketsN         int           `toml:"max-select-buckets"`
+
+	// MetaServers are the HTTP addresses of the meta nodes the data node
+	// sends its heartbeats and shard sizes to. None are sent if it is empty.
+	MetaServers             []string      `toml:"meta-servers"`
+	MetaHTTPSEnabled        bool          `toml:"meta-https-enabled"`
+	MetaAuthToken           string        `toml:"meta-auth-token"`
+	HeartbeatInterval       toml.Duration `toml:"heartbeat-interval"`
+	ShardSizeReportInterval toml.Duration `toml:"shard-size-report-interval"`
 }
 
 // NewConfig returns an instance of Config with defaults.
@@ -74,5 +90,7 @@ func NewConfig() Config {
 		MaxSelectPointN:           DefaultMaxSelectPointN,
 		MaxSelectSeriesN:          DefaultMaxSelectSeriesN,
 		MaxSelectBucketsN:         DefaultMaxSelectBucketsN,
+		HeartbeatInterval:         toml.Duration(DefaultHeartbeatInterval),
+		ShardSizeReportInterval:   toml.Duration(DefaultShardSizeReportInterval),
 	}
 }
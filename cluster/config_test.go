@@ -14,6 +14,8 @@ func TestConfig_Parse(t *testing.T) {
 	if _, err := toml.Decode(`
 shard-writer-timeout = "10s"
 write-timeout = "20s"
+meta-servers = ["meta0:8091", "meta1:8091"]
+heartbeat-interval = "5s"
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -23,5 +25,9 @@ write-timeout = "20s"
 		t.Fatalf("unexpected shard-writer timeout: %s", c.ShardWriterTimeout)
 	} else if time.Duration(c.WriteTimeout) != 20*time.Second {
 		t.Fatalf("unexpected write timeout s: %s", c.WriteTimeout)
+	} else if len(c.MetaServers) != 2 || c.MetaServers[1] != "meta1:8091" {
+		t.Fatalf("unexpected meta servers: %v", c.MetaServers)
+	} else if time.Duration(c.HeartbeatInterval) != 5*time.Second {
+		t.Fatalf("unexpected heartbeat interval: %s", c.HeartbeatInterval)
 	}
 }
@@ -25,6 +25,10 @@ var (
 
 	// ErrWriteFailed is returned when no writes succeeded.
 	ErrWriteFailed = errors.New("write failed")
+
+	// ErrDatabaseWriteBlocked is returned when writing to a database that is
+	// being drained before it is dropped.
+	ErrDatabaseWriteBlocked = errors.New("database is draining, writes are blocked")
 )
 
 // PointsWriter handles writes across multiple local and remote data nodes.
@@ -38,6 +42,7 @@ type PointsWriter struct {
 
 	MetaClient interface {
 		Database(name string) (di *meta.DatabaseInfo)
+		DatabaseWriteBlocked(database string) bool
 		RetentionPolicy(database, policy string) (*meta.RetentionPolicyInfo, error)
 		CreateShardGroup(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error)
 	}
@@ -245,6 +250,9 @@ func (w *PointsWriter) WritePointsInto(p *coordinator.IntoWriteRequest) error {
 
 // WritePoints writes across multiple local and remote data nodes according the consistency level.
 func (w *PointsWriter) WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+	if w.MetaClient.DatabaseWriteBlocked(database) {
+		return ErrDatabaseWriteBlocked
+	}
 
 	if retentionPolicy == "" {
 		db := w.MetaClient.Database(database)
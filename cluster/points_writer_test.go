@@ -260,6 +260,22 @@ func TestPointsWriter_WritePoints(t *testing.T) {
 	}
 }
 
+// Ensures writes to a database being drained are refused.
+func TestPointsWriter_WritePoints_WriteBlocked(t *testing.T) {
+	ms := NewPointsWriterMetaClient()
+	ms.DatabaseWriteBlockedFn = func(database string) bool { return database == "mydb" }
+
+	c := cluster.NewPointsWriter()
+	c.MetaClient = ms
+	c.Node = &influxcloud.Node{ID: 1}
+
+	pr := &cluster.WritePointsRequest{Database: "mydb", RetentionPolicy: "myrp"}
+	pr.AddPoint("cpu", 1.0, time.Now(), nil)
+	if err := c.WritePoints(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, pr.Points); err != cluster.ErrDatabaseWriteBlocked {
+		t.Fatalf("unexpected error: got %v, exp %v", err, cluster.ErrDatabaseWriteBlocked)
+	}
+}
+
 var shardID uint64
 
 type fakeShardWriter struct {
@@ -317,6 +333,7 @@ type PointsWriterMetaClient struct {
 	RetentionPolicyFn             func(database, name string) (*meta.RetentionPolicyInfo, error)
 	CreateShardGroupIfNotExistsFn func(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error)
 	DatabaseFn                    func(database string) *meta.DatabaseInfo
+	DatabaseWriteBlockedFn        func(database string) bool
 	ShardOwnerFn                  func(shardID uint64) (string, string, *meta.ShardGroupInfo)
 }
 
@@ -334,6 +351,13 @@ func (m PointsWriterMetaClient) Database(database string) *meta.DatabaseInfo {
 	return m.DatabaseFn(database)
 }
 
+func (m PointsWriterMetaClient) DatabaseWriteBlocked(database string) bool {
+	if m.DatabaseWriteBlockedFn == nil {
+		return false
+	}
+	return m.DatabaseWriteBlockedFn(database)
+}
+
 func (m PointsWriterMetaClient) ShardOwner(shardID uint64) (string, string, *meta.ShardGroupInfo) {
 	return m.ShardOwnerFn(shardID)
 }
@@ -81,19 +81,37 @@ func (m *Main) Run(args ...string) error {
 		if err := cmd.Run(args...); err != nil {
 			return fmt.Errorf("run: %s", err)
 		}
+		// A -validate or -dry-run run doesn't start the server.
+		if cmd.Server == nil {
+			return nil
+		}
 
 		signalCh := make(chan os.Signal, 1)
 		signal.Notify(signalCh, os.Interrupt, syscall.SIGTERM)
 		m.Logger.Println("Listening for signals")
 
+		// SIGHUP reloads the config keys that can change at runtime.
+		hupCh := make(chan os.Signal, 1)
+		signal.Notify(hupCh, syscall.SIGHUP)
+
 		// Block until one of the signals above is received
-		select {
-		case <-signalCh:
-			m.Logger.Println("Signal received, initializing clean shutdown...")
-			go func() {
-				cmd.Close()
-			}()
+	wait:
+		for {
+			select {
+			case <-hupCh:
+				m.Logger.Println("SIGHUP received, reloading config")
+				if err := cmd.Reload(); err != nil {
+					m.Logger.Printf("Config reload: %s", err)
+				}
+			case <-signalCh:
+				m.Logger.Println("Signal received, initializing clean shutdown...")
+				go func() {
+					cmd.Close()
+				}()
+				break wait
+			}
 		}
+		signal.Stop(hupCh)
 
 		// Block again until another signal is received, a shutdown timeout elapses,
 		// or the Command is gracefully closed
@@ -113,6 +131,52 @@ func (m *Main) Run(args ...string) error {
 		if err := run.NewPrintConfigCommand().Run(args...); err != nil {
 			return fmt.Errorf("config: %s", err)
 		}
+	case "backup":
+		if err := run.NewBackupCommand().Run(args...); err != nil {
+			return fmt.Errorf("backup: %s", err)
+		}
+	case "check":
+		if err := run.NewCheckCommand().Run(args...); err != nil {
+			return fmt.Errorf("check: %s", err)
+		}
+	case "diff":
+		if err := run.NewDiffCommand().Run(args...); err != nil {
+			return fmt.Errorf("diff: %s", err)
+		}
+	case "replay":
+		if err := run.NewReplayCommand().Run(args...); err != nil {
+			return fmt.Errorf("replay: %s", err)
+		}
+	case "metadata-version":
+		cmd := run.NewMetadataVersionCommand()
+		cmd.Version = version
+		if err := cmd.Run(args...); err != nil {
+			return fmt.Errorf("metadata-version: %s", err)
+		}
+	case "recover-single":
+		if err := run.NewRecoverSingleCommand().Run(args...); err != nil {
+			return fmt.Errorf("recover-single: %s", err)
+		}
+	case "restore":
+		if err := run.NewRestoreCommand().Run(args...); err != nil {
+			return fmt.Errorf("restore: %s", err)
+		}
+	case "remove-node":
+		if err := run.NewRemoveNodeCommand().Run(args...); err != nil {
+			return fmt.Errorf("remove-node: %s", err)
+		}
+	case "show-cluster":
+		if err := run.NewShowClusterCommand().Run(args...); err != nil {
+			return fmt.Errorf("show-cluster: %s", err)
+		}
+	case "validate-cluster":
+		if err := run.NewValidateClusterCommand().Run(args...); err != nil {
+			return fmt.Errorf("validate-cluster: %s", err)
+		}
+	case "verify-snapshot":
+		if err := run.NewVerifySnapshotCommand().Run(args...); err != nil {
+			return fmt.Errorf("verify-snapshot: %s", err)
+		}
 	case "version":
 		if err := NewVersionCommand().Run(args...); err != nil {
 			return fmt.Errorf("version: %s", err)
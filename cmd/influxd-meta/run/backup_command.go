@@ -0,0 +1,79 @@
+package run
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/zhexuany/influxcloud/meta"
+)
+
+// BackupCommand represents the command executed by "influxd-meta backup".
+type BackupCommand struct {
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// NewBackupCommand return a new instance of BackupCommand.
+func NewBackupCommand() *BackupCommand {
+	return &BackupCommand{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Run writes a backup of the meta store to a file.
+func (cmd *BackupCommand) Run(args ...string) error {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	host := fs.String("host", "localhost:8091", "")
+	fs.Usage = func() { fmt.Fprintln(cmd.Stderr, backupUsage) }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected a backup file")
+	}
+	path := fs.Arg(0)
+
+	client := meta.NewClient(meta.NewConfig())
+	client.SetMetaServers([]string{*host})
+	defer client.Close()
+
+	// Write to a temporary file so a failed backup never leaves a partial
+	// one behind.
+	f, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	if err := client.Snapshot(f); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.Stdout, "Backed up the meta store from %s to %s\n", *host, path)
+	return nil
+}
+
+var backupUsage = `Backs up the meta store of a cluster to a file.
+
+Usage: influxd-meta backup [flags] <file>
+
+The backup holds the meta nodes, data nodes, databases, retention policies
+and users of the cluster as the leader applied them at a single raft index,
+which is recorded with a checksum in the backup's header. Restore it with
+influxd-meta restore.
+
+    -host <addr>
+            The meta service to back up the cluster from.
+            Defaults to localhost:8091.
+`
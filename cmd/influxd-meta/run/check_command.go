@@ -0,0 +1,83 @@
+package run
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/zhexuany/influxcloud/meta"
+)
+
+// CheckCommand represents the command executed by "influxd-meta check".
+type CheckCommand struct {
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// NewCheckCommand return a new instance of CheckCommand.
+func NewCheckCommand() *CheckCommand {
+	return &CheckCommand{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Run compares the state checksum of every meta node in the cluster and
+// returns an error if any of them diverged.
+func (cmd *CheckCommand) Run(args ...string) error {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	var mf metaServiceFlags
+	mf.register(fs)
+	fs.Usage = func() { fmt.Fprintln(cmd.Stderr, checkUsage) }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	data, err := fetchSnapshot(&mf)
+	if err != nil {
+		return err
+	}
+	servers := make([]string, 0, len(data.MetaNodes))
+	for _, n := range data.MetaNodes {
+		servers = append(servers, n.Host)
+	}
+
+	client := mf.client(servers)
+	sums, err := client.StateChecksum()
+	if err != nil {
+		return err
+	}
+
+	sort.Strings(servers)
+	for _, server := range servers {
+		fmt.Fprintf(cmd.Stdout, "%s\tindex=%d\t%s\n", server, sums[server].Index, sums[server].Checksum)
+	}
+	if diverged := meta.DivergedServers(sums); len(diverged) > 0 {
+		return fmt.Errorf("meta nodes diverged: %v", diverged)
+	}
+	return nil
+}
+
+var checkUsage = `Checks that every meta node reports the same metadata.
+
+Usage: influxd-meta check [flags]
+
+The cluster members are read from the given meta service. Each member is
+asked for a checksum of its state once it has applied everything the leader
+had committed, and any member that disagrees with the majority is reported.
+
+    -host <addr>
+            The meta service to read the cluster members from.
+            Defaults to localhost:8091.
+
+    -token <token>
+            The auth-token of the meta service, if it sets one.
+
+    -https
+            Talk to the meta service over HTTPS.
+
+    -skip-verify
+            Don't verify the meta service's certificate with -https.
+`
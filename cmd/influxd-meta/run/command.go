@@ -1,6 +1,7 @@
 package run
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -9,7 +10,10 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"runtime/debug"
 	"strconv"
+
+	"github.com/zhexuany/influxcloud/meta"
 )
 
 const logo = `
@@ -39,6 +43,11 @@ type Command struct {
 	Stderr io.Writer
 
 	Server *Server
+
+	// configPath is the config file the server was started with, which
+	// Reload reads again, and options the flags it applies on top.
+	configPath string
+	options    Options
 }
 
 // NewCommand return a new instance of Command.
@@ -59,6 +68,12 @@ func (cmd *Command) Run(args ...string) error {
 	if err != nil {
 		return err
 	}
+	if options.Validate {
+		return cmd.validate(options)
+	}
+	if options.DryRun {
+		return cmd.previewJoin(options)
+	}
 
 	// Print sweet InfluxDB logo.
 	fmt.Print(logo)
@@ -80,21 +95,28 @@ func (cmd *Command) Run(args ...string) error {
 	}
 
 	// Parse config
-	config, err := ParseConfig(options.GetConfigPath())
+	cmd.configPath = options.GetConfigPath()
+	config, err := ParseConfig(cmd.configPath)
 	if err != nil {
 		return fmt.Errorf("parse config: %s", err)
 	}
 
-	// Apply any environment variables on top of the parsed config
+	// Apply any environment variables on top of the parsed config, and the
+	// command line flags on top of those.
 	if err := config.ApplyEnvOverrides(); err != nil {
 		return fmt.Errorf("apply env config: %v", err)
 	}
+	options.ApplyFlags(config)
+	cmd.options = options
 
 	// Validate the configuration.
 	if err := config.Validate(); err != nil {
 		return fmt.Errorf("%s. To generate a valid configuration file run `influxd-meta config > influxdb-meta.generated.conf`", err)
 	}
 
+	// Apply the GC percentage before the server allocates anything significant.
+	log.Printf("GC percent set to %d", ApplyGCPercent(config))
+
 	// Create server from config and start it.
 	buildInfo := &BuildInfo{
 		Version: cmd.Version,
@@ -109,6 +131,7 @@ func (cmd *Command) Run(args ...string) error {
 
 	s.CPUProfile = options.CPUProfile
 	s.MemProfile = options.MemProfile
+	s.SafeMode = options.SafeMode
 	if err := s.Open(); err != nil {
 		return fmt.Errorf("open server: %s", err)
 	}
@@ -117,6 +140,87 @@ func (cmd *Command) Run(args ...string) error {
 	return nil
 }
 
+// Reload reads the config file the server was started with again, with the
+// environment variables on top, and applies the keys that can change at
+// runtime to the server, as Server.Reload does.
+func (cmd *Command) Reload() error {
+	if cmd.Server == nil {
+		return errors.New("server not running")
+	}
+	config, err := ParseConfig(cmd.configPath)
+	if err != nil {
+		return fmt.Errorf("parse config: %s", err)
+	}
+	if err := config.ApplyEnvOverrides(); err != nil {
+		return fmt.Errorf("apply env config: %v", err)
+	}
+	cmd.options.ApplyFlags(config)
+	return cmd.Server.Reload(config)
+}
+
+// validate parses and validates the config, printing the result, without
+// starting the server.
+func (cmd *Command) validate(options Options) error {
+	config, err := ParseConfig(options.GetConfigPath())
+	if err != nil {
+		return fmt.Errorf("parse config: %s", err)
+	}
+	if err := config.ApplyEnvOverrides(); err != nil {
+		return fmt.Errorf("apply env config: %v", err)
+	}
+	options.ApplyFlags(config)
+
+	if err := config.Validate(); err != nil {
+		if verr, ok := err.(*meta.ValidationError); ok {
+			for _, fe := range verr.Errors {
+				fmt.Fprintln(cmd.Stdout, fe)
+			}
+		}
+		return fmt.Errorf("invalid configuration: %s", err)
+	}
+	fmt.Fprintln(cmd.Stdout, "Configuration is valid")
+	return nil
+}
+
+// previewJoin prints the raft membership and warnings joining the cluster
+// at join-address would result in, without joining it or starting the
+// server.
+func (cmd *Command) previewJoin(options Options) error {
+	config, err := ParseConfig(options.GetConfigPath())
+	if err != nil {
+		return fmt.Errorf("parse config: %s", err)
+	}
+	if err := config.ApplyEnvOverrides(); err != nil {
+		return fmt.Errorf("apply env config: %v", err)
+	}
+	options.ApplyFlags(config)
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %s", err)
+	}
+	if config.JoinAddress == "" {
+		return fmt.Errorf("dry run: join-address is not set")
+	}
+
+	c := meta.NewClient(config)
+	c.SetTLS(config.HTTPSEnabled)
+	defer c.Close()
+	p, err := c.PreviewJoin(config.JoinAddress)
+	if err != nil {
+		return fmt.Errorf("dry run: %s", err)
+	}
+
+	fmt.Fprintf(cmd.Stdout, "Joining through %s would make this node meta node %d at %s and %s\n",
+		config.JoinAddress, p.Node.ID, p.Node.Host, p.Node.TCPHost)
+	fmt.Fprintf(cmd.Stdout, "Raft peers after joining, with a quorum of %d:\n", p.Quorum)
+	for _, peer := range p.Peers {
+		fmt.Fprintf(cmd.Stdout, "    %s\n", peer)
+	}
+	for _, w := range p.Warnings {
+		fmt.Fprintf(cmd.Stdout, "Warning: %s\n", w)
+	}
+	return nil
+}
+
 // Close shuts down the server.
 func (cmd *Command) Close() error {
 	defer close(cmd.Closed)
@@ -127,6 +231,19 @@ func (cmd *Command) Close() error {
 	return nil
 }
 
+// ApplyGCPercent applies the configured GC percentage, if any, and returns the
+// effective value.
+func ApplyGCPercent(c *meta.Config) int {
+	if c.GCPercent != 0 {
+		debug.SetGCPercent(c.GCPercent)
+	}
+
+	// SetGCPercent is the only way to read the current value back.
+	percent := debug.SetGCPercent(100)
+	debug.SetGCPercent(percent)
+	return percent
+}
+
 func (cmd *Command) monitorServerErrors() {
 	logger := log.New(cmd.Stderr, "", log.LstdFlags)
 	for {
@@ -149,6 +266,10 @@ func (cmd *Command) ParseFlags(args ...string) (Options, error) {
 	_ = fs.String("hostname", "", "")
 	fs.StringVar(&options.CPUProfile, "cpuprofile", "", "")
 	fs.StringVar(&options.MemProfile, "memprofile", "", "")
+	fs.BoolVar(&options.Validate, "validate", false, "")
+	fs.BoolVar(&options.DryRun, "dry-run", false, "")
+	fs.BoolVar(&options.SafeMode, "safe-mode", false, "")
+	fs.StringVar(&options.JoinAddress, "join", "", "")
 	fs.Usage = func() { fmt.Fprintln(cmd.Stderr, usage) }
 	if err := fs.Parse(args); err != nil {
 		return Options{}, err
@@ -195,6 +316,26 @@ Usage: influxd run [flags]
             Write CPU profiling information to a file.
     -memprofile <path>
             Write memory usage information to a file.
+    -validate
+            Validate the configuration, print the result and exit
+            without starting the server.
+    -dry-run
+            Preview joining the cluster at join-address: print the raft
+            peers and warnings joining would result in and exit without
+            joining or starting the server.
+    -safe-mode
+            Start only the diagnostic endpoints, to inspect a node in a
+            bad state: /health reports safe-mode, and /metrics and the
+            /debug endpoints that don't need raft are served. Raft and
+            the meta client are not opened.
+    -join <addr>
+            Join the cluster through the meta server at addr, overriding
+            join-address in the configuration file and environment.
+
+On SIGHUP the server reads its configuration file again and applies
+log-format, http-rate-limit, http-rate-limit-burst,
+http-rate-limit-allowlist and https-certificate. Changes to other keys are
+logged and only take effect on restart.
 `
 
 // Options represents the command line options that can be parsed.
@@ -203,6 +344,28 @@ type Options struct {
 	PIDFile    string
 	CPUProfile string
 	MemProfile string
+
+	// Validate only validates the config, without starting the server.
+	Validate bool
+
+	// DryRun only previews joining the cluster, without starting the server.
+	DryRun bool
+
+	// SafeMode starts the server with only its diagnostic endpoints.
+	SafeMode bool
+
+	// JoinAddress overrides join-address, if set.
+	JoinAddress string
+}
+
+// ApplyFlags sets the config values given on the command line, on top of
+// those of the config file and the environment, and records that they came
+// from the flags.
+func (opt *Options) ApplyFlags(c *meta.Config) {
+	if opt.JoinAddress != "" {
+		c.JoinAddress = opt.JoinAddress
+		c.SetSource("join-address", meta.ConfigSourceFlag)
+	}
 }
 
 // GetConfigPath returns the config path from the options.
@@ -0,0 +1,360 @@
+package run_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	influxdbMeta "github.com/influxdata/influxdb/services/meta"
+	"github.com/zhexuany/influxcloud/cmd/influxd-meta/run"
+	"github.com/zhexuany/influxcloud/meta"
+)
+
+// Ensure the configured GC percentage is applied to the runtime.
+func TestApplyGCPercent(t *testing.T) {
+	orig := debug.SetGCPercent(100)
+	defer debug.SetGCPercent(orig)
+
+	c := meta.NewConfig()
+	c.GCPercent = 42
+	if got := run.ApplyGCPercent(c); got != 42 {
+		t.Fatalf("unexpected effective gc percent: %d", got)
+	}
+
+	// Read the value back from the runtime.
+	if got := debug.SetGCPercent(orig); got != 42 {
+		t.Fatalf("unexpected runtime gc percent: %d", got)
+	}
+
+	// A zero value leaves the runtime setting alone.
+	debug.SetGCPercent(77)
+	if got := run.ApplyGCPercent(meta.NewConfig()); got != 77 {
+		t.Fatalf("unexpected effective gc percent: %d", got)
+	}
+}
+
+// Ensure a component whose Close blocks is abandoned after its timeout and
+// the remaining components are still closed.
+func TestCloseComponents_Timeout(t *testing.T) {
+	var buf bytes.Buffer
+	block := make(chan struct{})
+	defer close(block)
+
+	var closed []string
+	components := []run.Component{
+		{Name: "first", Timeout: time.Second, Close: func() error {
+			closed = append(closed, "first")
+			return nil
+		}},
+		{Name: "stuck", Timeout: 10 * time.Millisecond, Close: func() error {
+			<-block
+			return nil
+		}},
+		{Name: "last", Timeout: time.Second, Close: func() error {
+			closed = append(closed, "last")
+			return nil
+		}},
+	}
+
+	start := time.Now()
+	err := run.CloseComponents(components, log.New(&buf, "", 0))
+	if d := time.Since(start); d > 5*time.Second {
+		t.Fatalf("shutdown took %s", d)
+	}
+	if err == nil || !strings.Contains(err.Error(), "stuck") {
+		t.Fatalf("expected error naming the stuck component, got %v", err)
+	}
+	if !reflect.DeepEqual(closed, []string{"first", "last"}) {
+		t.Fatalf("unexpected closed components: %v", closed)
+	}
+	if !strings.Contains(buf.String(), "stuck did not close within 10ms") {
+		t.Fatalf("timeout not logged: %q", buf.String())
+	}
+}
+
+// Ensure the config subcommand reports where each value came from.
+func TestPrintConfigCommand_Sources(t *testing.T) {
+	f, err := ioutil.TempFile("", "influxd-meta-config-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("dir = \"/tmp/meta\"\ngzip-level = 1\n"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	os.Setenv("INFLUXDB_GZIP_LEVEL", "3")
+	defer os.Unsetenv("INFLUXDB_GZIP_LEVEL")
+
+	var stdout bytes.Buffer
+	cmd := run.NewPrintConfigCommand()
+	cmd.Stdout = &stdout
+	if err := cmd.Run("-config", f.Name(), "-join", "meta0:8091"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, exp := range []string{
+		`dir = "/tmp/meta"  # file`,
+		`gzip-level = 3  # env`,
+		`join-address = "meta0:8091"  # flag`,
+		`lease-duration = "1m0s"  # default`,
+	} {
+		if !strings.Contains(stdout.String(), exp+"\n") {
+			t.Errorf("missing %q in output:\n%s", exp, stdout.String())
+		}
+	}
+}
+
+// Ensure -validate prints the result of validating the config and starts
+// no server.
+func TestCommand_Validate(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "influxdb-meta.conf")
+
+	if err := ioutil.WriteFile(path, []byte("dir = \""+dir+"\"\nbind-address = \"127.0.0.1:0\"\nhttp-bind-address = \"127.0.0.1:0\"\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	var stdout bytes.Buffer
+	cmd := run.NewCommand()
+	cmd.Stdout = &stdout
+	if err := cmd.Run("-validate", "-config", path); err != nil {
+		t.Fatal(err)
+	}
+	if cmd.Server != nil {
+		t.Fatal("server started")
+	}
+	if got := stdout.String(); got != "Configuration is valid\n" {
+		t.Fatalf("unexpected output: %q", got)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("dir = \""+dir+"\"\nhttp-bind-address = \"no-port\"\nhttps-enabled = true\nhttps-certificate = \"/no/such/cert.pem\"\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	stdout.Reset()
+	cmd = run.NewCommand()
+	cmd.Stdout = &stdout
+	if err := cmd.Run("-validate", "-config", path); err == nil {
+		t.Fatal("expected an invalid config")
+	}
+	for _, field := range []string{"http-bind-address: ", "https-certificate: "} {
+		if !strings.Contains(stdout.String(), field) {
+			t.Errorf("missing %q in output:\n%s", field, stdout.String())
+		}
+	}
+}
+
+// Ensure validate-cluster reports a setting that differs between meta nodes,
+// and passes once they agree.
+func TestValidateClusterCommand(t *testing.T) {
+	dir0, dir1 := tempDir(t), tempDir(t)
+	defer os.RemoveAll(dir0)
+	defer os.RemoveAll(dir1)
+
+	s0 := openServer(t, dir0, "")
+	defer s0.Close()
+	c1 := newServerConfig(t, dir1, s0.Service.HTTPAddr())
+	s1 := openServerConfig(t, c1)
+	defer s1.Close()
+	waitMetaNodes(t, s0.MetaClient, 2)
+	servers := []*run.Server{s0, s1}
+
+	c1.DuplicateDatabase = meta.DuplicateDatabaseError
+
+	var stdout bytes.Buffer
+	cmd := run.NewValidateClusterCommand()
+	cmd.Stdout = &stdout
+	cmd.Stderr = ioutil.Discard
+	host := s0.Service.HTTPAddr()
+	if err := cmd.Run("-host", host); err == nil {
+		t.Fatal("expected the differing setting to fail validation")
+	}
+	out := stdout.String()
+	if !strings.Contains(out, "duplicate-database differs:") {
+		t.Fatalf("differing setting not reported:\n%s", out)
+	}
+	for i, value := range []string{meta.DuplicateDatabaseIgnore, meta.DuplicateDatabaseError} {
+		if line := servers[i].Service.HTTPAddr() + "\t" + strconv.Quote(value); !strings.Contains(out, line) {
+			t.Fatalf("value of %s not reported:\n%s", servers[i].Service.HTTPAddr(), out)
+		}
+	}
+	if strings.Contains(out, "write-durability differs") {
+		t.Fatalf("setting that agrees reported:\n%s", out)
+	}
+
+	c1.DuplicateDatabase = meta.DuplicateDatabaseIgnore
+	stdout.Reset()
+	if err := cmd.Run("-host", host); err != nil {
+		t.Fatalf("unexpected error: %s\n%s", err, stdout.String())
+	}
+}
+
+// Ensure validate-cluster talks to the cluster over HTTPS when the config
+// given with -config has https-enabled set.
+func TestValidateClusterCommand_HTTPSEnabled(t *testing.T) {
+	var data []byte
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/config-fingerprint" {
+			json.NewEncoder(w).Encode(meta.NewConfigFingerprint(meta.NewConfig(), ""))
+			return
+		}
+		w.Write(data)
+	}))
+	defer ts.Close()
+	host := strings.TrimPrefix(ts.URL, "https://")
+
+	snapshot := &meta.Data{Data: &influxdbMeta.Data{Index: 1}}
+	snapshot.MetaNodes = meta.NodeInfos{{ID: 1, Host: host}}
+	var err error
+	if data, err = snapshot.MarshalBinary(); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "influxdb-meta.conf")
+	if err := ioutil.WriteFile(path, []byte("https-enabled = true\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := run.NewValidateClusterCommand()
+	cmd.Stdout = ioutil.Discard
+	cmd.Stderr = ioutil.Discard
+	if err := cmd.Run("-host", host, "-skip-verify"); err == nil {
+		t.Fatal("expected plain HTTP to the HTTPS server to fail")
+	}
+	if err := cmd.Run("-host", host, "-skip-verify", "-config", path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Ensure show-cluster lists the nodes and the shard groups pending deletion
+// with when they are purged.
+func TestShowClusterCommand(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	s := openServer(t, dir, "")
+	defer s.Close()
+
+	if _, err := s.MetaClient.CreateDataNode("foo:8180", "bar:8281"); err != nil {
+		t.Fatal(err)
+	} else if _, err := s.MetaClient.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+	sg, err := s.MetaClient.CreateShardGroup("db0", "default", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	} else if err := s.MetaClient.DeleteShardGroup("db0", "default", sg.ID); err != nil {
+		t.Fatal(err)
+	}
+	pending := s.MetaClient.PendingDeletions()
+	if len(pending) != 1 {
+		t.Fatalf("unexpected pending deletions: %+v", pending)
+	}
+
+	var stdout bytes.Buffer
+	cmd := run.NewShowClusterCommand()
+	cmd.Stdout = &stdout
+	cmd.Stderr = ioutil.Discard
+	if err := cmd.Run("-host", s.Service.HTTPAddr()); err != nil {
+		t.Fatal(err)
+	}
+	out := stdout.String()
+	if !strings.Contains(out, s.Service.HTTPAddr()) || !strings.Contains(out, "foo:8180") {
+		t.Fatalf("nodes not listed:\n%s", out)
+	}
+	line := strconv.FormatUint(sg.ID, 10) + "\tdb0.default\tdeleted=" + pending[0].DeletedAt.UTC().Format(time.RFC3339) +
+		"\tpurge=" + pending[0].PurgeAt.UTC().Format(time.RFC3339)
+	if !strings.Contains(out, line) {
+		t.Fatalf("pending deletion not listed:\n%s", out)
+	}
+}
+
+// Ensure metadata-version prints the version of the cluster and raises it
+// to the one of the build.
+func TestMetadataVersionCommand(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	s := openServer(t, dir, "")
+	defer s.Close()
+
+	var stdout bytes.Buffer
+	cmd := run.NewMetadataVersionCommand()
+	cmd.Version = "unknown"
+	cmd.Stdout = &stdout
+	cmd.Stderr = ioutil.Discard
+	if err := cmd.Run("-host", s.Service.HTTPAddr(), "-raise"); err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("Cluster: %d\nThis build: %d\nRaised the metadata version of the cluster from %d to %d\n",
+		meta.MetadataVersion, meta.MetadataVersion, meta.MetadataVersion, meta.MetadataVersion)
+	if stdout.String() != want {
+		t.Fatalf("unexpected output:\n%s", stdout.String())
+	}
+}
+
+// Ensure diff fetches the current snapshot over HTTPS, authenticating with
+// the token, when asked to.
+func TestDiffCommand_HTTPS(t *testing.T) {
+	current := &meta.Data{Data: &influxdbMeta.Data{Index: 1}}
+	if err := current.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+	buf, err := current.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write(buf)
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "influxd-meta-diff-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	empty, err := (&meta.Data{Data: &influxdbMeta.Data{Index: 1}}).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "snapshot")
+	if err := ioutil.WriteFile(path, empty, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	host := strings.TrimPrefix(ts.URL, "https://")
+	var stdout bytes.Buffer
+	cmd := run.NewDiffCommand()
+	cmd.Stdout = &stdout
+	cmd.Stderr = ioutil.Discard
+	if err := cmd.Run("-host", host, "-token", "secret", path); err == nil {
+		t.Fatal("expected plain HTTP to the HTTPS server to fail")
+	}
+	if err := cmd.Run("-host", host, "-token", "secret", "-https", path); err == nil {
+		t.Fatal("expected the unverified certificate to be rejected")
+	}
+	if err := cmd.Run("-host", host, "-token", "secret", "-https", "-skip-verify", path); err != nil {
+		t.Fatal(err)
+	}
+	if out := stdout.String(); out != "added database db0\n" {
+		t.Fatalf("unexpected diff: %q", out)
+	}
+}
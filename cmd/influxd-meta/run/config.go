@@ -2,8 +2,10 @@ package run
 
 import (
 	"log"
+	"time"
 
 	"github.com/BurntSushi/toml"
+	"github.com/zhexuany/influxcloud"
 	"github.com/zhexuany/influxcloud/meta"
 )
 
@@ -22,9 +24,23 @@ func ParseConfig(path string) (*meta.Config, error) {
 	log.Printf("Using configuration at: %s\n", path)
 
 	config := meta.NewConfig()
-	if _, err := toml.DecodeFile(path, &config); err != nil {
+	start := time.Now()
+	md, err := toml.DecodeFile(path, &config)
+	influxcloud.RecordConfigLoad(time.Since(start), err)
+	if err != nil {
 		return nil, err
 	}
+	setFileSources(config, md)
 
 	return config, nil
 }
+
+// setFileSources records every key set in the decoded file as coming from
+// the file.
+func setFileSources(c *meta.Config, md toml.MetaData) {
+	for _, key := range md.Keys() {
+		if len(key) == 1 {
+			c.SetSource(key[0], meta.ConfigSourceFile)
+		}
+	}
+}
@@ -1,10 +1,12 @@
 package run
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"strings"
 
 	"github.com/BurntSushi/toml"
 	"github.com/zhexuany/influxcloud/meta"
@@ -31,34 +33,52 @@ func (cmd *PrintConfigCommand) Run(args ...string) error {
 	// Parse command flags.
 	fs := flag.NewFlagSet("", flag.ContinueOnError)
 	configPath := fs.String("config", "", "")
+	joinAddress := fs.String("join", "", "")
 	fs.Usage = func() { fmt.Fprintln(cmd.Stderr, printConfigUsage) }
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
 
 	// Parse config from path.
-	opt := Options{ConfigPath: *configPath}
+	opt := Options{ConfigPath: *configPath, JoinAddress: *joinAddress}
 	config, err := cmd.parseConfig(opt.GetConfigPath())
 	if err != nil {
 		return fmt.Errorf("parse config: %s", err)
 	}
 
-	// Apply any environment variables on top of the parsed config
+	// Apply any environment variables on top of the parsed config, and the
+	// command line flags on top of those.
 	if err := config.ApplyEnvOverrides(); err != nil {
 		return fmt.Errorf("apply env config: %v", err)
 	}
+	opt.ApplyFlags(config)
 
 	// Validate the configuration.
 	if err := config.Validate(); err != nil {
 		return fmt.Errorf("%s. To generate a valid configuration file run `influxd-meta config > influxdb.generated.conf`", err)
 	}
 
-	toml.NewEncoder(cmd.Stdout).Encode(config)
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(config); err != nil {
+		return err
+	}
+	writeAnnotatedConfig(cmd.Stdout, buf.String(), config)
 	fmt.Fprint(cmd.Stdout, "\n")
 
 	return nil
 }
 
+// writeAnnotatedConfig writes the encoded config to w, ending each value with
+// a comment naming where it came from.
+func writeAnnotatedConfig(w io.Writer, encoded string, config *meta.Config) {
+	for _, line := range strings.Split(strings.TrimRight(encoded, "\n"), "\n") {
+		if i := strings.Index(line, " = "); i > 0 {
+			line = fmt.Sprintf("%s  # %s", line, config.Source(strings.TrimSpace(line[:i])))
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
 // ParseConfig parses the config at path.
 // Returns a demo configuration if path is blank.
 func (cmd *PrintConfigCommand) parseConfig(path string) (*meta.Config, error) {
@@ -71,9 +91,11 @@ func (cmd *PrintConfigCommand) parseConfig(path string) (*meta.Config, error) {
 		return config, nil
 	}
 
-	if _, err := toml.DecodeFile(path, &config); err != nil {
+	md, err := toml.DecodeFile(path, &config)
+	if err != nil {
 		return nil, err
 	}
+	setFileSources(config, md)
 	return config, nil
 }
 
@@ -81,6 +103,11 @@ var printConfigUsage = `Displays the default configuration.
 
 Usage: influxd-meta config [flags]
 
+Each value is followed by a comment naming where it came from: default,
+file, env or flag. Environment variables override the file: INFLUXDB_ and
+the toml key, as INFLUXDB_BIND_ADDRESS, or, taking precedence, INFLUXCLOUD_META_
+and the field name, as INFLUXCLOUD_META_BINDADDRESS.
+
     -config <path>
             Set the path to the initial configuration file.
             This defaults to the environment variable INFLUXDB_CONFIG_PATH,
@@ -88,4 +115,6 @@ Usage: influxd-meta config [flags]
             is present at any of these locations.
             Disable the automatic loading of a configuration file using
             the null device (such as /dev/null).
+    -join <addr>
+            Override join-address, as the run command's -join does.
 `
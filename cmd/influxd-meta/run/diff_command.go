@@ -0,0 +1,175 @@
+package run
+
+import (
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+
+	"github.com/zhexuany/influxcloud/meta"
+)
+
+// DiffCommand represents the command executed by "influxd-meta diff".
+type DiffCommand struct {
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// NewDiffCommand return a new instance of DiffCommand.
+func NewDiffCommand() *DiffCommand {
+	return &DiffCommand{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Run prints the differences between two meta snapshots.
+func (cmd *DiffCommand) Run(args ...string) error {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	var mf metaServiceFlags
+	mf.register(fs)
+	fs.Usage = func() { fmt.Fprintln(cmd.Stderr, diffUsage) }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var a, b *meta.Data
+	var err error
+	switch fs.NArg() {
+	case 1:
+		if a, err = readSnapshotFile(fs.Arg(0)); err != nil {
+			return err
+		}
+		if b, err = fetchSnapshot(&mf); err != nil {
+			return err
+		}
+	case 2:
+		if a, err = readSnapshotFile(fs.Arg(0)); err != nil {
+			return err
+		}
+		if b, err = readSnapshotFile(fs.Arg(1)); err != nil {
+			return err
+		}
+	default:
+		fs.Usage()
+		return fmt.Errorf("expected one or two snapshots")
+	}
+
+	for _, c := range meta.DiffSnapshots(a, b) {
+		fmt.Fprintln(cmd.Stdout, c)
+	}
+	return nil
+}
+
+// readSnapshotFile reads a snapshot as served by the meta service.
+func readSnapshotFile(path string) (*meta.Data, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data := &meta.Data{}
+	if err := data.UnmarshalBinary(buf); err != nil {
+		return nil, fmt.Errorf("read snapshot %s: %s", path, err)
+	}
+	return data, nil
+}
+
+// metaServiceFlags are the flags of the commands talking to a meta service.
+type metaServiceFlags struct {
+	host       string
+	token      string
+	https      bool
+	skipVerify bool
+}
+
+// register defines the flags on fs.
+func (f *metaServiceFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.host, "host", "localhost:8091", "")
+	fs.StringVar(&f.token, "token", "", "")
+	fs.BoolVar(&f.https, "https", false, "")
+	fs.BoolVar(&f.skipVerify, "skip-verify", false, "")
+}
+
+// tlsConfig returns the TLS config to dial the meta servers with, or nil if
+// the default will do.
+func (f *metaServiceFlags) tlsConfig() *tls.Config {
+	if !f.skipVerify {
+		return nil
+	}
+	return &tls.Config{InsecureSkipVerify: true}
+}
+
+// client returns a meta client for servers, set up by the flags.
+func (f *metaServiceFlags) client(servers []string) *meta.Client {
+	config := meta.NewConfig()
+	config.AuthToken = f.token
+	client := meta.NewClient(config)
+	if t, ok := client.HTTPClient.Transport.(*http.Transport); ok && f.skipVerify {
+		t.TLSClientConfig = f.tlsConfig()
+	}
+	client.SetMetaServers(servers)
+	client.SetTLS(f.https)
+	return client
+}
+
+// fetchSnapshot returns the current snapshot of the meta service at the host
+// flag, authenticating with the token flag if it isn't empty.
+func fetchSnapshot(f *metaServiceFlags) (*meta.Data, error) {
+	scheme := "http://"
+	if f.https {
+		scheme = "https://"
+	}
+	req, err := http.NewRequest("GET", scheme+f.host+"/?index=0", nil)
+	if err != nil {
+		return nil, err
+	}
+	if f.token != "" {
+		req.Header.Set("Authorization", "Bearer "+f.token)
+	}
+	client := &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: f.tlsConfig(),
+	}}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch snapshot from %s: %s", f.host, resp.Status)
+	}
+	buf, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	data := &meta.Data{}
+	if err := data.UnmarshalBinary(buf); err != nil {
+		return nil, fmt.Errorf("fetch snapshot from %s: %s", f.host, err)
+	}
+	return data, nil
+}
+
+var diffUsage = `Displays the metadata changes between two meta snapshots.
+
+Usage: influxd-meta diff [flags] <snapshot> [<snapshot>]
+
+When a single snapshot is given it is compared to the current metadata of
+the meta service.
+
+    -host <addr>
+            The meta service to fetch the current snapshot from.
+            Defaults to localhost:8091.
+
+    -token <token>
+            The auth-token of the meta service, if it sets one.
+
+    -https
+            Talk to the meta service over HTTPS.
+
+    -skip-verify
+            Don't verify the meta service's certificate with -https.
+`
@@ -0,0 +1,106 @@
+package run
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/zhexuany/influxcloud/meta"
+)
+
+// MetadataVersionCommand represents the command executed by
+// "influxd-meta metadata-version".
+type MetadataVersionCommand struct {
+	// Version is the release of this build, by which the metadata version
+	// it reads and writes is known.
+	Version string
+
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// NewMetadataVersionCommand return a new instance of MetadataVersionCommand.
+func NewMetadataVersionCommand() *MetadataVersionCommand {
+	return &MetadataVersionCommand{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Run prints the metadata version of the cluster and of this build, and
+// raises the cluster's if asked to.
+func (cmd *MetadataVersionCommand) Run(args ...string) error {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	var mf metaServiceFlags
+	mf.register(fs)
+	raise := fs.Bool("raise", false, "")
+	to := fs.Uint64("to", 0, "")
+	fs.Usage = func() { fmt.Fprintln(cmd.Stderr, metadataVersionUsage) }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	data, err := fetchSnapshot(&mf)
+	if err != nil {
+		return err
+	}
+	build := meta.BuildInfo{Version: cmd.Version}.MetadataVersion()
+	fmt.Fprintf(cmd.Stdout, "Cluster: %d\n", data.MetadataVersion)
+	fmt.Fprintf(cmd.Stdout, "This build: %d\n", build)
+	if !*raise {
+		return nil
+	}
+
+	version := build
+	if *to != 0 {
+		version = *to
+	}
+	servers := make([]string, 0, len(data.MetaNodes))
+	for _, n := range data.MetaNodes {
+		servers = append(servers, n.Host)
+	}
+	client := mf.client(servers)
+	client.SetBuildInfo(meta.BuildInfo{Version: cmd.Version})
+	if err := client.Open(); err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := client.RaiseMetadataVersion(version); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.Stdout, "Raised the metadata version of the cluster from %d to %d\n", data.MetadataVersion, version)
+	return nil
+}
+
+var metadataVersionUsage = `Displays or raises the metadata version of the cluster.
+
+Usage: influxd-meta metadata-version [flags]
+
+The metadata version of the cluster is the one of the build that created
+it. A node whose build is more than metadata-version-tolerance versions away
+from it refuses to join or resume. Once every node runs a build of a newer
+version, as /debug/version reports, raise the cluster's to it so builds of
+older versions are refused in turn. It can't be lowered again.
+
+    -host <addr>
+            The meta service to read the cluster members from.
+            Defaults to localhost:8091.
+
+    -token <token>
+            The auth-token of the meta service, if it sets one.
+
+    -https
+            Talk to the meta service over HTTPS.
+
+    -skip-verify
+            Don't verify the meta service's certificate with -https.
+
+    -raise
+            Raise the metadata version of the cluster.
+
+    -to <version>
+            The version to raise it to. Defaults to the one of this build,
+            and can't be past it.
+`
@@ -0,0 +1,89 @@
+package run
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/zhexuany/influxcloud"
+	"github.com/zhexuany/influxcloud/meta"
+)
+
+// RecoverSingleCommand represents the command executed by
+// "influxd-meta recover-single".
+type RecoverSingleCommand struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// NewRecoverSingleCommand return a new instance of RecoverSingleCommand.
+func NewRecoverSingleCommand() *RecoverSingleCommand {
+	return &RecoverSingleCommand{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Run resets the meta data dir of a stopped meta node into a single node
+// cluster, once the operator confirms.
+func (cmd *RecoverSingleCommand) Run(args ...string) error {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	nodeID := fs.Uint64("node-id", 0, "")
+	yes := fs.Bool("yes", false, "")
+	fs.Usage = func() { fmt.Fprintln(cmd.Stderr, recoverSingleUsage) }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected a meta data dir")
+	}
+	dir := fs.Arg(0)
+
+	id := *nodeID
+	if id == 0 {
+		node, err := influxcloud.LoadNode(dir)
+		if err != nil {
+			return fmt.Errorf("read node id, or pass -node-id: %s", err)
+		}
+		id = node.ID
+	}
+
+	if !*yes {
+		fmt.Fprintf(cmd.Stdout, "This makes meta node %d in %s the only member of its cluster.\n", id, dir)
+		fmt.Fprintf(cmd.Stdout, "Every other meta node is removed and must not be restarted with its current data.\n")
+		fmt.Fprintf(cmd.Stdout, "Type 'recover' to continue: ")
+		line, _ := bufio.NewReader(cmd.Stdin).ReadString('\n')
+		if strings.TrimSpace(line) != "recover" {
+			return errors.New("not confirmed, nothing changed")
+		}
+	}
+
+	data, err := meta.RecoverSingleNode(dir, id)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.Stdout, "Recovered meta node %d at index %d. Start it without join peers.\n", id, data.Data.Index)
+	return nil
+}
+
+var recoverSingleUsage = `Resets a meta node into a single node cluster, for disaster recovery.
+
+Usage: influxd-meta recover-single [flags] <dir>
+
+The meta node whose data dir is dir must be stopped. Its metadata is rebuilt
+from the raft log and kept, with every other meta node removed, and it becomes
+the only raft voter. The old raft log and snapshots are moved aside with a
+.pre-recover suffix. Start the node without join peers afterwards.
+
+    -node-id <id>
+            The ID of the meta node, if dir has no node.json.
+    -yes
+            Don't ask for confirmation.
+`
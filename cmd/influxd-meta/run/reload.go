@@ -0,0 +1,125 @@
+package run
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zhexuany/influxcloud"
+	"github.com/zhexuany/influxcloud/meta"
+)
+
+// reloadableConfigKeys are the config keys Reload applies to a running
+// server. Every other key only takes effect on restart.
+var reloadableConfigKeys = map[string]bool{
+	"log-format":                true,
+	"http-rate-limit":           true,
+	"http-rate-limit-burst":     true,
+	"http-rate-limit-allowlist": true,
+	"https-certificate":         true,
+}
+
+// ReloadError is returned by Reload for changed config keys that can't be
+// applied to a running server.
+type ReloadError struct {
+	Keys []string
+}
+
+func (e *ReloadError) Error() string {
+	return fmt.Sprintf("config keys that can only change on restart were left as they are: %s", strings.Join(e.Keys, ", "))
+}
+
+// Reload applies the keys of c that differ from the running config and can
+// change at runtime: log-format, http-rate-limit, http-rate-limit-burst,
+// http-rate-limit-allowlist and https-certificate. Changes to any other key,
+// such as dir or bind-address, are logged and left out, and returned as a
+// *ReloadError once the rest are applied. An invalid config is refused
+// whole.
+func (s *Server) Reload(c *meta.Config) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	var applied, rejected []string
+	changed := make(map[string]bool)
+	for _, key := range s.config.Changed(c) {
+		if !reloadableConfigKeys[key] {
+			rejected = append(rejected, key)
+			continue
+		}
+		changed[key] = true
+	}
+
+	if changed["https-certificate"] {
+		if err := s.reloadCertificatePath(c.HTTPSCertificate); err != nil {
+			return fmt.Errorf("reload https-certificate: %s", err)
+		}
+		applied = append(applied, "https-certificate")
+	}
+
+	if changed["http-rate-limit"] || changed["http-rate-limit-burst"] || changed["http-rate-limit-allowlist"] {
+		if s.Service != nil {
+			if err := s.Service.SetHTTPRateLimit(c.HTTPRateLimit, c.HTTPRateLimitBurst, c.HTTPRateLimitAllowlist); err != nil {
+				return fmt.Errorf("reload http-rate-limit: %s", err)
+			}
+		}
+		s.config.HTTPRateLimit = c.HTTPRateLimit
+		s.config.HTTPRateLimitBurst = c.HTTPRateLimitBurst
+		s.config.HTTPRateLimitAllowlist = c.HTTPRateLimitAllowlist
+		for _, key := range []string{"http-rate-limit", "http-rate-limit-burst", "http-rate-limit-allowlist"} {
+			if changed[key] {
+				applied = append(applied, key)
+			}
+		}
+	}
+
+	if changed["log-format"] {
+		s.config.LogFormat = c.LogFormat
+		s.reformatLogs()
+		applied = append(applied, "log-format")
+	}
+
+	if len(applied) > 0 {
+		s.Logger.Printf("Reloaded config: %s", strings.Join(applied, ", "))
+	}
+	if len(rejected) > 0 {
+		s.Logger.Printf("WARNING: config changes to %s can't be reloaded and were left out; restart the server to apply them", strings.Join(rejected, ", "))
+		return &ReloadError{Keys: rejected}
+	}
+	return nil
+}
+
+// reloadCertificatePath has the meta service serve the HTTPS certificate at
+// path, and the meta client connect anew if it changed.
+func (s *Server) reloadCertificatePath(path string) error {
+	if s.Service != nil {
+		reloaded, err := s.Service.SetHTTPSCertificate(path)
+		if err != nil {
+			return err
+		}
+		if reloaded {
+			s.MetaClient.SetTLS(s.config.HTTPSEnabled)
+			s.MetaClient.CloseIdleConnections()
+		}
+	}
+	s.config.HTTPSCertificate = path
+	return nil
+}
+
+// reformatLogs has every logger of the server log in the configured format,
+// as SetLogOutput set them up.
+func (s *Server) reformatLogs() {
+	var nodeID uint64
+	if s.Service != nil && s.Service.Node != nil {
+		nodeID = s.Service.Node.ID
+	}
+
+	format := s.config.LogFormat
+	meta.ReformatLogger(s.Logger, s.logOutput, format, "", "server", nodeID)
+	if s.Service != nil {
+		s.Service.SetLogFormat(format)
+	}
+	if s.MetaClient != nil {
+		meta.ReformatLogger(s.MetaClient.Logger(), s.logOutput, format, "[metaclient] ", "metaclient", nodeID)
+	}
+	influxcloud.SetFileLogger(meta.NewLogger(s.logOutput, format, "[node] ", "node", nodeID))
+}
@@ -0,0 +1,137 @@
+package run
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/zhexuany/influxcloud/meta"
+)
+
+// RemoveNodeCommand represents the command executed by
+// "influxd-meta remove-node".
+type RemoveNodeCommand struct {
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// NewRemoveNodeCommand return a new instance of RemoveNodeCommand.
+func NewRemoveNodeCommand() *RemoveNodeCommand {
+	return &RemoveNodeCommand{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Run removes a meta or data node from the cluster and prints what that
+// did.
+func (cmd *RemoveNodeCommand) Run(args ...string) error {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	var mf metaServiceFlags
+	mf.register(fs)
+	format := fs.String("format", "text", "")
+	force := fs.Bool("force", false, "")
+	fs.Usage = func() { fmt.Fprintln(cmd.Stderr, removeNodeUsage) }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 || (fs.Arg(0) != "meta" && fs.Arg(0) != "data") {
+		fs.Usage()
+		return fmt.Errorf("expected a node type and id")
+	} else if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	id, err := strconv.ParseUint(fs.Arg(1), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid node id %q", fs.Arg(1))
+	}
+
+	data, err := fetchSnapshot(&mf)
+	if err != nil {
+		return err
+	}
+	servers := make([]string, 0, len(data.MetaNodes))
+	for _, n := range data.MetaNodes {
+		servers = append(servers, n.Host)
+	}
+	client := mf.client(servers)
+	if err := client.Open(); err != nil {
+		return err
+	}
+	defer client.Close()
+
+	var res *meta.RemoveNodeResult
+	if fs.Arg(0) == "meta" {
+		res, err = client.RemoveMetaNode(id, *force)
+	} else {
+		res, err = client.RemoveDataNode(id)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(cmd.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(res)
+	}
+	writeRemoveNodeResult(cmd.Stdout, res)
+	return nil
+}
+
+// writeRemoveNodeResult prints res for people.
+func writeRemoveNodeResult(w io.Writer, res *meta.RemoveNodeResult) {
+	fmt.Fprintf(w, "Removed %s node %d (%s)\n", res.NodeType, res.NodeID, res.Host)
+	if res.RaftPeerRemoved != "" {
+		fmt.Fprintf(w, "Raft peer removed: %s\n", res.RaftPeerRemoved)
+	}
+	if res.NodeType == "meta" {
+		switch {
+		case res.LeadershipTransferred:
+			fmt.Fprintf(w, "Leadership transferred: %s -> %s\n", res.LeaderBefore, res.LeaderAfter)
+		case res.LeaderAfter == "":
+			fmt.Fprintln(w, "Leadership: no leader elected yet")
+		default:
+			fmt.Fprintf(w, "Leadership unchanged: %s\n", res.LeaderAfter)
+		}
+	}
+	if res.NodeType == "data" {
+		fmt.Fprintf(w, "Shards reassigned: %d\n", len(res.ReassignedShards))
+		for _, r := range res.ReassignedShards {
+			fmt.Fprintf(w, "    %s.%s shard group %d shard %d: node %d -> %v\n",
+				r.Database, r.RetentionPolicy, r.ShardGroupID, r.ShardID, r.From, r.To)
+		}
+		if len(res.DeletedShardGroups) > 0 {
+			fmt.Fprintf(w, "Shard groups deleted, no owner left: %v\n", res.DeletedShardGroups)
+		}
+	}
+}
+
+var removeNodeUsage = `Removes a meta or data node from the cluster.
+
+Usage: influxd-meta remove-node [flags] <meta|data> <id>
+
+Removing a meta node takes it out of the raft configuration, and moves
+leadership if it was the leader. Removing a data node hands the shards it was
+the last owner of to other data nodes. What happened is printed afterwards.
+
+    -host <addr>
+            The meta service to read the cluster members from.
+            Defaults to localhost:8091.
+
+    -token <token>
+            The auth-token of the meta service, if it sets one.
+
+    -https
+            Talk to the meta service over HTTPS.
+
+    -skip-verify
+            Don't verify the meta service's certificate with -https.
+    -format <text|json>
+            How to print the result. Defaults to text.
+    -force
+            Remove a meta node even if that breaks quorum.
+`
@@ -0,0 +1,75 @@
+package run
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/zhexuany/influxcloud/meta"
+)
+
+// ReplayCommand represents the command executed by "influxd-meta replay".
+type ReplayCommand struct {
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// NewReplayCommand return a new instance of ReplayCommand.
+func NewReplayCommand() *ReplayCommand {
+	return &ReplayCommand{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Run replays the raft log of a meta data dir into a throwaway state machine
+// and prints the resulting metadata.
+func (cmd *ReplayCommand) Run(args ...string) error {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	out := fs.String("out", "", "")
+	fs.Usage = func() { fmt.Fprintln(cmd.Stderr, replayUsage) }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected a meta data dir")
+	}
+
+	data, err := meta.ReplayLog(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if *out != "" {
+		buf, err := data.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(*out, buf, 0666)
+	}
+
+	buf, err := data.CanonicalJSON()
+	if err != nil {
+		return err
+	}
+	_, err = cmd.Stdout.Write(buf)
+	return err
+}
+
+var replayUsage = `Rebuilds metadata from the raft log of a meta data dir.
+
+Usage: influxd-meta replay [flags] <dir>
+
+The raft log of dir, usually a backup, is applied to a throwaway state
+machine starting from its latest snapshot, and the resulting metadata is
+printed as JSON. The JSON is canonical, with sorted keys, so the output for
+the same metadata is always identical and can be kept in git and diffed. The
+dir is not modified.
+
+    -out <path>
+            Write the metadata as a snapshot file instead, which can be
+            compared with 'influxd-meta diff'.
+`
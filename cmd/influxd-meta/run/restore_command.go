@@ -0,0 +1,74 @@
+package run
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/zhexuany/influxcloud/meta"
+)
+
+// RestoreCommand represents the command executed by "influxd-meta restore".
+type RestoreCommand struct {
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// NewRestoreCommand return a new instance of RestoreCommand.
+func NewRestoreCommand() *RestoreCommand {
+	return &RestoreCommand{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Run restores a backup of the meta store into a fresh single node
+// cluster.
+func (cmd *RestoreCommand) Run(args ...string) error {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	host := fs.String("host", "localhost:8091", "")
+	fs.Usage = func() { fmt.Fprintln(cmd.Stderr, restoreUsage) }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected a backup file")
+	}
+	path := fs.Arg(0)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	client := meta.NewClient(meta.NewConfig())
+	client.SetMetaServers([]string{*host})
+	if err := client.Open(); err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := client.Restore(f); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.Stdout, "Restored the meta store from %s into %s\n", path, *host)
+	return nil
+}
+
+var restoreUsage = `Restores a backup of the meta store into a fresh cluster.
+
+Usage: influxd-meta restore [flags] <file>
+
+The cluster must be a single meta node just started without join peers,
+with no data nodes, databases or users yet. The backup, made with
+influxd-meta backup, is refused if it doesn't match its checksum. Its meta
+nodes are replaced by the cluster's own, and further meta nodes join it
+afterwards.
+
+    -host <addr>
+            The meta service of the cluster to restore into.
+            Defaults to localhost:8091.
+`
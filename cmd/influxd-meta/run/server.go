@@ -1,20 +1,25 @@
 package run
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
 	"net"
 	"net/http"
+	httppprof "net/http/pprof"
 	"os"
 	"path/filepath"
 	"runtime"
 	"runtime/pprof"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/influxdata/influxdb/tcp"
+	"github.com/influxdata/influxdb/toml"
 	"github.com/zhexuany/influxcloud"
 	"github.com/zhexuany/influxcloud/meta"
 )
@@ -38,6 +43,74 @@ func (bi *BuildInfo) String() string {
 		bi.Version, bi.Commit, bi.Branch, bi.Tags)
 }
 
+// errBufferSize is how many errors Err holds for a reader falling behind.
+// Errors past it are logged and dropped.
+const errBufferSize = 16
+
+// The backoff between attempts to reopen the listener of the meta service
+// after it failed.
+const (
+	minReopenBackoff = 100 * time.Millisecond
+	maxReopenBackoff = 30 * time.Second
+)
+
+// clusterCheckTimeout is how long a node resuming its membership waits for
+// join-address to check its metadata version and node.json with before
+// opening.
+const clusterCheckTimeout = 10 * time.Second
+
+// The kinds of BindError, to be matched with errors.Is.
+var (
+	// ErrBindAddressInUse means bind-address is already being listened on.
+	// This may pass, as when a previous server is still shutting down.
+	ErrBindAddressInUse = errors.New("bind address in use")
+
+	// ErrBindPermissionDenied means the server may not listen on
+	// bind-address, such as a privileged port.
+	ErrBindPermissionDenied = errors.New("bind address permission denied")
+
+	// ErrBindAddressInvalid means bind-address is malformed, or isn't an
+	// address of this host.
+	ErrBindAddressInvalid = errors.New("invalid bind address")
+)
+
+// BindError is returned by Server.Open when it can't listen on its bind
+// address. It matches its Kind with errors.Is, and unwraps to the error
+// from the listener.
+type BindError struct {
+	Addr string
+
+	// Kind is one of the ErrBind errors, or nil if the listener failed
+	// some other way.
+	Kind error
+
+	Err error
+}
+
+// newBindError returns the BindError of listening on addr failing with err.
+func newBindError(addr string, err error) *BindError {
+	var kind error
+	var addrErr *net.AddrError
+	var dnsErr *net.DNSError
+	switch {
+	case errors.Is(err, syscall.EADDRINUSE):
+		kind = ErrBindAddressInUse
+	case errors.Is(err, syscall.EACCES), errors.Is(err, syscall.EPERM):
+		kind = ErrBindPermissionDenied
+	case errors.Is(err, syscall.EADDRNOTAVAIL), errors.As(err, &addrErr), errors.As(err, &dnsErr):
+		kind = ErrBindAddressInvalid
+	}
+	return &BindError{Addr: addr, Kind: kind, Err: err}
+}
+
+func (e *BindError) Error() string { return fmt.Sprintf("listen: %s", e.Err) }
+
+// Unwrap returns the error from the listener.
+func (e *BindError) Unwrap() error { return e.Err }
+
+// Is returns whether target is the kind of e.
+func (e *BindError) Is(target error) bool { return e.Kind != nil && target == e.Kind }
+
 // Server represents a container for the metadata and storage data and services.
 // It is built using a Config and it manages the startup and shutdown of all
 // services in the proper order.
@@ -47,6 +120,14 @@ type Server struct {
 	err     chan error
 	closing chan struct{}
 
+	// errRead is set once Err was called. Until then, errors are logged
+	// rather than sent on err.
+	errRead int32
+
+	// reopening is set while the listener of the meta service is being
+	// reopened.
+	reopening int32
+
 	BindAddress string
 	Listener    net.Listener
 
@@ -63,6 +144,11 @@ type Server struct {
 	CPUProfile string
 	MemProfile string
 
+	// SafeMode opens only the diagnostic endpoints of the meta service, to
+	// inspect a node in a bad state. Neither raft nor the meta client are
+	// opened.
+	SafeMode bool
+
 	// httpAPIAddr is the host:port combination for the main HTTP API for querying and writing data
 	httpAPIAddr string
 
@@ -71,53 +157,48 @@ type Server struct {
 	// logOutput is the writer to which all services should be configured to
 	// write logs to after appension.
 	logOutput io.Writer
+
+	// pprofServer serves the pprof handlers on pprofLn, if configured.
+	pprofServer *http.Server
+	pprofLn     net.Listener
 }
 
 // NewServer returns a new instance of Server built from a config.
 func NewServer(c *meta.Config, buildInfo *BuildInfo) (*Server, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
 	// We need to ensure that a meta directory always exists even if
 	// we don't start the meta store.  node.json is always stored under
 	// the meta directory.
 
-	if err := os.MkdirAll(c.Dir, 0777); err != nil {
+	if err := meta.MkdirAll(c.Dir); err != nil {
+		return nil, fmt.Errorf("mkdir all: %s", err)
+	}
+	if err := meta.MkdirAll(c.RaftPath()); err != nil {
 		return nil, fmt.Errorf("mkdir all: %s", err)
 	}
 
-	path := filepath.Join(c.Dir, "node.json")
-
-	// check file is existed or not
-	if _, err := os.Stat(path); err == nil {
-		// load node from node.json and check the error
-		node, err := influxcloud.LoadNode(c.Dir)
-		if err != nil {
-			if os.IsNotExist(err) {
-				return nil, err
-			}
-		}
-
-		//LoadNode will just pasrse node.json file and create a instance
-		//node. So, node.json wiill not be changed util we trigger from program
-		//Hence, we have to check path in original node.json include the newPath
-		//instead of oldPath. If not, we have to save such node instance to
-		//node.json file
-		if buf, err := ioutil.ReadFile(filepath.Join(path)); err != nil {
-			if !strings.Contains(string(buf), "path") {
-				node.Save()
-			}
-		}
+	// node.json is missing until the node first joins a cluster. A corrupt
+	// one is refused rather than replaced, as that would lose the node's ID.
+	node, err := influxcloud.LoadNode(c.Dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("load node: %s", err)
 	}
 
-	bind := c.BindAddress
+	bind, err := meta.ResolveBindAddress(c.BindAddress, c.BindInterface)
+	if err != nil {
+		return nil, err
+	}
 
 	s := &Server{
 		buildInfo: *buildInfo,
-		err:       make(chan error),
+		err:       make(chan error, errBufferSize),
 		closing:   make(chan struct{}),
 
 		BindAddress: bind,
 
-		Logger: log.New(os.Stderr, "", log.LstdFlags),
-
 		MetaClient: meta.NewClient(c),
 
 		Service: meta.NewService(c),
@@ -125,46 +206,129 @@ func NewServer(c *meta.Config, buildInfo *BuildInfo) (*Server, error) {
 		httpAPIAddr: c.HTTPBindAddress,
 
 		config: c,
-
-		logOutput: os.Stderr,
 	}
+	s.Service.Node = node
+	info := meta.BuildInfo{
+		Version: buildInfo.Version,
+		Commit:  buildInfo.Commit,
+		Branch:  buildInfo.Branch,
+		Tags:    buildInfo.Tags,
+	}
+	s.Service.SetBuildInfo(info)
+	s.MetaClient.SetBuildInfo(info)
+
+	// Build every logger in the configured format.
+	s.SetLogOutput(os.Stderr)
 
 	return s, nil
 }
 
-// SetLogOutput sets the logger used for all messages. It must not be called
-// after the Open method has been called.
+// SetLogOutput sets the logger used for all messages, including those of
+// the meta service and client. It must not be called after the Open method
+// has been called.
 func (s *Server) SetLogOutput(w io.Writer) {
-	s.Logger = log.New(os.Stderr, "", log.LstdFlags)
+	var nodeID uint64
+	if s.Service != nil && s.Service.Node != nil {
+		nodeID = s.Service.Node.ID
+	}
+
+	s.Logger = meta.NewLogger(w, s.config.LogFormat, "", "server", nodeID)
 	s.logOutput = w
+	if s.Service != nil {
+		s.Service.SetLogOutput(w)
+	}
+	if s.MetaClient != nil {
+		s.MetaClient.SetLogger(meta.NewLogger(w, s.config.LogFormat, "[metaclient] ", "metaclient", nodeID))
+	}
+	influxcloud.SetFileLogger(meta.NewLogger(w, s.config.LogFormat, "[node] ", "node", nodeID))
 }
 
-// Err returns an error channel that multiplexes all out of band errors received from all services.
-func (s *Server) Err() <-chan error { return s.err }
+// Err returns an error channel that multiplexes all out of band errors
+// received from all services. It holds up to errBufferSize errors; any more
+// the reader hasn't received yet are logged and dropped. Until Err is
+// called, errors are only logged.
+func (s *Server) Err() <-chan error {
+	atomic.StoreInt32(&s.errRead, 1)
+	return s.err
+}
 
-// Open opens the meta services
-func (s *Server) Open() error {
+// Open opens the meta services. It returns a *BindError if it can't
+// listen on BindAddress or PprofBindAddress.
+func (s *Server) Open() (err error) {
 	// Start profiling, if set.
 	startProfile(s.CPUProfile, s.MemProfile)
 
-	log.Println("Opening Server for meta service")
+	s.Logger.Println("Opening Server for meta service")
+	if s.config.PprofBindAddress != "" {
+		if err := s.openPprof(); err != nil {
+			return err
+		}
+		// Don't leave pprof served by a server that failed to open.
+		defer func() {
+			if err != nil {
+				s.closePprof()
+			}
+		}()
+	}
+	if s.SafeMode {
+		return s.openSafeMode()
+	}
+
 	// Open shared TCP connection.
 	ln, err := net.Listen("tcp", s.BindAddress)
 	if err != nil {
-		return fmt.Errorf("listen: %s", err)
+		return newBindError(s.BindAddress, err)
 	}
 	s.Listener = ln
+	s.BindAddress = assignedAddr(s.BindAddress, ln)
 
 	// Multiplex listener.
 	mux := tcp.NewMux()
+	if s.Service != nil {
+		mux = s.Service.NewMux(s.logOutput)
+	}
 	go mux.Serve(ln)
 
+	if err := s.checkCluster(); err != nil {
+		return err
+	}
+
+	var joinedCluster bool
 	if s.Service != nil {
-		s.Service.RaftListener = mux.Listen(meta.MuxHeader)
+		// A fresh node joins the cluster while its meta service opens, as
+		// the leader only takes it on as a raft peer once its raft is up,
+		// and the service waits for the leader before it is open.
+		var joined chan error
+		joinCtx, cancelJoin := context.WithCancel(context.Background())
+		defer cancelJoin()
+		if s.config.JoinAddress != "" && !s.config.ObserverMode && (s.Service.Node == nil || s.Service.Node.ID == 0) {
+			joined = make(chan error, 1)
+			go func() { joined <- s.joinCluster(joinCtx) }()
+		}
+
+		// An observer takes no part in raft, so doesn't listen for it.
+		if !s.config.ObserverMode {
+			s.Service.RaftListener = mux.Listen(meta.MuxHeader)
+		}
 		// Open meta service.
 		if err := s.Service.Open(); err != nil {
+			if joined != nil {
+				cancelJoin()
+				<-joined
+			}
 			return fmt.Errorf("open meta service: %s", err)
 		}
+		if joined != nil {
+			if err := <-joined; err != nil {
+				return fmt.Errorf("join cluster: %s", err)
+			}
+			node, err := influxcloud.LoadNode(s.config.Dir)
+			if err != nil {
+				return fmt.Errorf("load node: %s", err)
+			}
+			s.Service.Node = node
+			joinedCluster = true
+		}
 
 		go s.monitorErrorChan(s.Service.Err())
 	}
@@ -172,42 +336,358 @@ func (s *Server) Open() error {
 	//initializes metaClient
 	s.initializeMetaClient()
 
-	if err := s.MetaClient.Open(); err != nil {
+	// A meta server that is reachable but can't serve the metadata, as when
+	// the cluster has no leader, would otherwise hold up startup forever.
+	ctx := context.Background()
+	if timeout := time.Duration(s.config.StartupTimeout); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if err := s.MetaClient.OpenContext(ctx); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("open meta client: gave up after startup-timeout %s: %s", s.config.StartupTimeout, err)
+		}
+		return err
+	}
+
+	// A node with no other meta server to validate node.json against
+	// before opening validates it against the metadata it now has.
+	if err := s.revalidateNode(s.MetaClient); err != nil {
+		return err
+	}
+
+	if joinedCluster {
+		leader, err := s.MetaClient.WaitForLeader(time.Duration(s.config.StartupTimeout))
+		if err != nil {
+			return fmt.Errorf("wait for leader: %s", err)
+		}
+		s.Logger.Printf("Joined cluster, leader is %s", leader)
+	}
+
+	// A node that knew no other meta server to check against before its
+	// raft opened is checked against the metadata it now has.
+	s.Logger.Printf("Metadata version of this node is %d, of the cluster %d", s.MetaClient.NodeMetadataVersion(), s.MetaClient.MetadataVersion())
+	if err := s.MetaClient.CheckMetadataVersion(); err != nil {
 		return err
 	}
 
+	if s.Service != nil && (s.config.HTTPSEnabled || s.config.InternalCert != "") && s.config.HTTPSCertificateReloadInterval > 0 {
+		go s.watchCertificate(time.Duration(s.config.HTTPSCertificateReloadInterval))
+	}
+
 	return nil
 }
 
-func (s *Server) initializeMetaClient() {
-	metaServers := []string{s.config.RemoteHostname}
-	s.MetaClient.SetMetaServers(metaServers)
+// openSafeMode opens the meta service in safe mode. The raft listener and
+// the meta client are left closed.
+func (s *Server) openSafeMode() error {
+	if err := s.Service.OpenSafeMode(); err != nil {
+		return fmt.Errorf("open meta service in safe mode: %s", err)
+	}
+	go s.monitorErrorChan(s.Service.Err())
+	s.Logger.Printf("Started in safe mode, serving diagnostics only on %s; raft and the meta client are not opened", s.Service.HTTPAddr())
+	return nil
+}
+
+// checkCluster refuses to resume a node of the cluster at join-address
+// whose build can't take part in it, by the metadata version, or whose
+// node.json names a node removed from it, before its meta service opens
+// raft: the cluster may have raised its version while the node was down, and
+// node.json may be restored from an old backup. A fresh node is checked as it
+// joins. If join-address can't serve the metadata, as while the whole cluster
+// restarts, the node is only checked once open.
+func (s *Server) checkCluster() error {
+	if s.Service == nil || s.config.ObserverMode || s.config.JoinAddress == "" || s.Service.Node == nil || s.Service.Node.ID == 0 {
+		return nil
+	}
+
+	c := meta.NewClient(s.config)
+	c.SetMetaServers([]string{s.config.JoinAddress})
+	c.SetTLS(s.config.HTTPSEnabled)
+	c.SetBuildInfo(meta.BuildInfo{Version: s.buildInfo.Version})
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), clusterCheckTimeout)
+	defer cancel()
+	if err := c.OpenContext(ctx); err != nil {
+		s.Logger.Printf("Can't check the metadata version and node.json with %s before opening: %s", s.config.JoinAddress, err)
+		return nil
+	}
+	if err := c.CheckMetadataVersion(); err != nil {
+		return err
+	}
+	return s.revalidateNode(c)
+}
+
+// joinCluster joins the node to the cluster through join-address, once the
+// meta service listens, as the addresses it registers may have assigned
+// ports. It gives up once ctx is done, the server closes, or, if set,
+// startup-timeout passes.
+func (s *Server) joinCluster(ctx context.Context) error {
+	if timeout := time.Duration(s.config.StartupTimeout); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-s.closing:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	select {
+	case <-s.Service.Listening():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	httpAddr, raftAddr := s.Service.AdvertisedAddrs()
+
+	s.MetaClient.SetTLS(s.config.HTTPSEnabled)
+	n, err := s.MetaClient.JoinClusterAsContext(ctx, s.config.JoinAddress, httpAddr, raftAddr)
+	if err != nil {
+		return err
+	}
+	s.Logger.Printf("Joined the cluster through %s as meta node %d", s.config.JoinAddress, n.ID)
+	return nil
+}
+
+// revalidateNode confirms with the cluster, by the metadata c serves, that
+// the meta node in node.json is still a member, if it last did longer than
+// node-max-age ago, and records that it did. A node.json restored from an
+// old backup may name a node removed since, which the server must not resume
+// as.
+func (s *Server) revalidateNode(c *meta.Client) error {
+	if s.Service == nil || s.config.NodeMaxAge == 0 {
+		return nil
+	}
+	node := s.Service.Node
+	if node == nil || node.ID == 0 {
+		return nil
+	}
+
+	file := filepath.Join(s.config.Dir, "node.json")
+	if validated := node.ValidatedAt(); validated.IsZero() {
+		s.Logger.Printf("%s was never validated against the cluster, validating meta node %d", file, node.ID)
+	} else if age := time.Since(validated); age > time.Duration(s.config.NodeMaxAge) {
+		s.Logger.Printf("%s was last validated against the cluster %s ago, longer than node-max-age %s, validating meta node %d",
+			file, age.Truncate(time.Second), s.config.NodeMaxAge, node.ID)
+	} else {
+		return nil
+	}
+
+	if c.Data().MetaNode(node.ID) == nil {
+		return fmt.Errorf("validate node: meta node %d in %s is no longer a member of the cluster; remove it to join the cluster as a new node", node.ID, file)
+	}
+	node.Validated = time.Now().Unix()
+	if err := node.Save(); err != nil {
+		return fmt.Errorf("validate node: %s", err)
+	}
+	s.Logger.Printf("Validated meta node %d against the cluster", node.ID)
+	return nil
+}
+
+// watchCertificate reloads the HTTPS certificate and the certificate
+// authorities of internal-ca every interval if they changed, until the
+// server is closed.
+func (s *Server) watchCertificate(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.reloadCertificate()
+		case <-s.closing:
+			return
+		}
+	}
+}
+
+// reloadCertificate has the meta service serve the HTTPS certificate and
+// verify against the internal-ca certificate authorities again if they
+// changed, and the meta client connect anew. What can't be loaded is logged,
+// and the previous one is used meanwhile.
+func (s *Server) reloadCertificate() {
+	reloaded, err := s.Service.ReloadCertificate()
+	if err != nil {
+		s.Logger.Printf("Failed to reload TLS certificates, using the previous ones: %s", err)
+		return
+	} else if !reloaded {
+		return
+	}
 	s.MetaClient.SetTLS(s.config.HTTPSEnabled)
-	if s.MetaClient.HTTPClient != nil {
-		s.MetaClient.SetHTTPClient(&http.Client{})
+	s.MetaClient.CloseIdleConnections()
+	s.Logger.Printf("Reloaded TLS certificates")
+}
+
+// openPprof starts serving the pprof handlers on pprof-bind-address. It
+// returns a *BindError if it can't listen on it.
+func (s *Server) openPprof() error {
+	ln, err := s.config.Listen(s.config.PprofBindAddress)
+	if err != nil {
+		return newBindError(s.config.PprofBindAddress, err)
 	}
-	s.MetaClient.Open()
-	s.MetaClient.WaitForDataChanged()
+	s.pprofLn = ln
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", httppprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", httppprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", httppprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", httppprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", httppprof.Trace)
+	s.pprofServer = &http.Server{Handler: mux}
+
+	go func() {
+		err := s.pprofServer.Serve(ln)
+		if err != nil && err != http.ErrServerClosed {
+			s.Logger.Printf("pprof listener failed: addr=%s, err=%s", ln.Addr(), err)
+		}
+	}()
+	s.Logger.Printf("Serving pprof on %s", ln.Addr())
+	return nil
+}
+
+// closePprof stops serving the pprof handlers, if they are. The listener
+// is closed here too, as the server may not be serving it yet.
+func (s *Server) closePprof() {
+	if s.pprofServer == nil {
+		return
+	}
+	s.pprofServer.Close()
+	s.pprofLn.Close()
+}
+
+// PprofAddr returns the address of the pprof listener, or an empty string if
+// it isn't enabled.
+func (s *Server) PprofAddr() string {
+	if s.pprofLn == nil {
+		return ""
+	}
+	return s.pprofLn.Addr().String()
+}
+
+func (s *Server) initializeMetaClient() {
+	// Without configured meta servers, talk to the local meta service. An
+	// observer reads its local copy of the metadata, which redirects writes
+	// to the leader.
+	servers := s.config.MetaServers()
+	if (len(servers) == 0 || s.config.ObserverMode) && s.Service != nil {
+		servers = []string{s.Service.HTTPAddr()}
+	}
+	s.MetaClient.SetMetaServers(servers)
+	s.MetaClient.SetTLS(s.config.HTTPSEnabled)
+	// The client keeps the HTTP client meta.NewClient gave it, whose
+	// transport closes connections idle past client-max-idle-time; it
+	// isn't replaced with a bare one.
 }
 
 // Close shuts down the meta and data stores and all services.
+//
+// If this node is the raft leader, it first hands leadership to a follower,
+// waiting up to leadership-transfer-timeout, so the cluster doesn't have to
+// notice the leader is gone to elect a new one.
+//
+// Components are closed in this order, each given up to its own
+// <component>-shutdown-timeout, or shutdown-timeout if that isn't set:
+//
+//  1. listener: stops accepting new raft and RPC connections
+//  2. pprof:    stops serving the pprof handlers, if enabled
+//  3. client:   stops the meta client and its cache updates
+//  4. service:  stops the HTTP API and shuts down the raft store
+//
+// A component that doesn't close in time is logged and abandoned so the
+// rest of the shutdown can proceed.
 func (s *Server) Close() error {
 	stopProfile()
 
-	// Close the listener first to stop any new connections
-	if s.Listener != nil {
-		s.Listener.Close()
+	if s.Service != nil && !s.config.ObserverMode && s.config.LeadershipTransferTimeout > 0 && s.Service.IsLeader() {
+		if err := s.Service.TransferLeadership(time.Duration(s.config.LeadershipTransferTimeout)); err != nil {
+			s.Logger.Printf("WARNING: could not transfer leadership before shutting down, the followers will elect a new leader once they notice: %s", err)
+		}
 	}
 
-	s.MetaClient.Close()
-
-	s.Service.Close()
+	timeout := func(d toml.Duration) time.Duration {
+		if d == 0 {
+			d = s.config.ShutdownTimeout
+		}
+		return time.Duration(d)
+	}
+	err := CloseComponents([]Component{
+		{Name: "listener", Timeout: timeout(s.config.ListenerShutdownTimeout), Close: func() error {
+			if s.Listener == nil {
+				return nil
+			}
+			return s.Listener.Close()
+		}},
+		{Name: "pprof", Timeout: timeout(s.config.PProfShutdownTimeout), Close: func() error {
+			if s.pprofServer == nil {
+				return nil
+			}
+			return s.pprofServer.Close()
+		}},
+		{Name: "client", Timeout: timeout(s.config.ClientShutdownTimeout), Close: s.MetaClient.Close},
+		{Name: "service", Timeout: timeout(s.config.ServiceShutdownTimeout), Close: s.Service.Close},
+	}, s.Logger)
 
 	close(s.closing)
+	return err
+}
+
+// Component is a part of the server that is closed on shutdown.
+type Component struct {
+	Name string
+
+	// Timeout is how long Close may take before it is abandoned. Zero waits
+	// forever.
+	Timeout time.Duration
+
+	Close func() error
+}
+
+// CloseComponents closes components one at a time in order. A component
+// that exceeds its timeout is logged and left running in the background
+// while the next one is closed. The returned error names every component
+// that timed out.
+func CloseComponents(components []Component, logger *log.Logger) error {
+	var timedOut []string
+	for _, c := range components {
+		done := make(chan error, 1)
+		go func(c Component) { done <- c.Close() }(c)
+
+		var timer *time.Timer
+		var expired <-chan time.Time
+		if c.Timeout > 0 {
+			timer = time.NewTimer(c.Timeout)
+			expired = timer.C
+		}
+
+		select {
+		case err := <-done:
+			if err != nil {
+				logger.Printf("error closing %s: %s", c.Name, err)
+			}
+		case <-expired:
+			logger.Printf("%s did not close within %s, proceeding with shutdown", c.Name, c.Timeout)
+			timedOut = append(timedOut, c.Name)
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+
+	if len(timedOut) > 0 {
+		return fmt.Errorf("shutdown timed out closing: %s", strings.Join(timedOut, ", "))
+	}
 	return nil
 }
 
-// monitorErrorChan reads an error channel and resends it through the server.
+// monitorErrorChan reads an error channel and resends it through the
+// server, without ever blocking the service sending it. A failed listener of
+// the meta service is reopened in the background, once at a time.
 func (s *Server) monitorErrorChan(ch <-chan error) {
 	for {
 		select {
@@ -215,17 +695,89 @@ func (s *Server) monitorErrorChan(ch <-chan error) {
 			if !ok {
 				return
 			}
-			s.err <- err
+			s.sendErr(err)
+			if err, ok := err.(*meta.ListenerError); ok && atomic.CompareAndSwapInt32(&s.reopening, 0, 1) {
+				go func() {
+					defer atomic.StoreInt32(&s.reopening, 0)
+					s.reopenListener(err)
+				}()
+			}
 		case <-s.closing:
 			return
 		}
 	}
 }
 
+// sendErr sends err through Err if it has a reader with room for it, and
+// logs it otherwise.
+func (s *Server) sendErr(err error) {
+	if atomic.LoadInt32(&s.errRead) == 0 {
+		s.Logger.Printf("Service error: %s", err)
+		return
+	}
+	select {
+	case s.err <- err:
+	default:
+		s.Logger.Printf("Service error, dropped as the error channel is full: %s", err)
+	}
+}
+
+// reopenListener reopens the listener of the meta service after it failed
+// with err, backing off between attempts, until it succeeds or the server
+// is closed.
+func (s *Server) reopenListener(err *meta.ListenerError) {
+	backoff := minReopenBackoff
+	for {
+		select {
+		case <-time.After(backoff):
+		case <-s.closing:
+			return
+		}
+
+		reopenErr := s.Service.ReopenListener()
+		if reopenErr == nil {
+			s.Logger.Printf("Reopened the listener on %s", err.Addr)
+			return
+		}
+		if backoff *= 2; backoff > maxReopenBackoff {
+			backoff = maxReopenBackoff
+		}
+		s.Logger.Printf("Failed to reopen the listener on %s, retrying in %s: %s", err.Addr, backoff, reopenErr)
+	}
+}
+
+// SetMaintenance puts the node in maintenance, or takes it out, as
+// meta.Service.SetMaintenance does.
+func (s *Server) SetMaintenance(on bool) {
+	s.Service.SetMaintenance(on)
+}
+
+// HTTPAddr returns the address of the HTTP API, with the port assigned to a
+// port of 0 once the meta service listens.
 func (s *Server) HTTPAddr() string {
+	if s.Service != nil {
+		select {
+		case <-s.Service.Listening():
+			return s.Service.HTTPAddr()
+		default:
+		}
+	}
 	return s.httpAPIAddr
 }
 
+// assignedAddr returns addr with a port of 0 replaced by the port assigned
+// to ln, which listens on it.
+func assignedAddr(addr string, ln net.Listener) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || port != "0" {
+		return addr
+	}
+	if _, port, err = net.SplitHostPort(ln.Addr().String()); err != nil {
+		return addr
+	}
+	return net.JoinHostPort(host, port)
+}
+
 // Service represents a service attached to the server.
 type Service interface {
 	SetLogOutput(w io.Writer)
@@ -233,31 +785,43 @@ type Service interface {
 	Close() error
 }
 
-// prof stores the file locations of active profiles.
+// prof stores the file locations of active profiles. Each is written to
+// its path with profileTempSuffix appended, and only renamed into place
+// once complete, so a crash never leaves a truncated profile at the path.
 var prof struct {
-	cpu *os.File
-	mem *os.File
+	cpu     *os.File
+	cpuPath string
+	mem     *os.File
+	memPath string
 }
 
+// profileTempSuffix is appended to the path of a profile being written.
+const profileTempSuffix = ".tmp"
+
 // StartProfile initializes the cpu and memory profile, if specified.
 func startProfile(cpuprofile, memprofile string) {
 	if cpuprofile != "" {
-		f, err := os.Create(cpuprofile)
+		f, err := os.Create(cpuprofile + profileTempSuffix)
 		if err != nil {
 			log.Fatalf("cpuprofile: %v", err)
 		}
 		log.Printf("writing CPU profile to: %s\n", cpuprofile)
-		prof.cpu = f
-		pprof.StartCPUProfile(prof.cpu)
+		prof.cpu, prof.cpuPath = f, cpuprofile
+		if err := pprof.StartCPUProfile(prof.cpu); err != nil {
+			log.Printf("cpuprofile: %v", err)
+			f.Close()
+			os.Remove(f.Name())
+			prof.cpu = nil
+		}
 	}
 
 	if memprofile != "" {
-		f, err := os.Create(memprofile)
+		f, err := os.Create(memprofile + profileTempSuffix)
 		if err != nil {
 			log.Fatalf("memprofile: %v", err)
 		}
 		log.Printf("writing mem profile to: %s\n", memprofile)
-		prof.mem = f
+		prof.mem, prof.memPath = f, memprofile
 		runtime.MemProfileRate = 4096
 	}
 }
@@ -266,14 +830,48 @@ func startProfile(cpuprofile, memprofile string) {
 func stopProfile() {
 	if prof.cpu != nil {
 		pprof.StopCPUProfile()
-		prof.cpu.Close()
-		log.Println("CPU profile stopped")
+		if err := finishProfile(prof.cpu, prof.cpuPath); err != nil {
+			log.Printf("CPU profile not written: %v", err)
+		} else {
+			log.Println("CPU profile stopped")
+		}
+		prof.cpu = nil
 	}
 	if prof.mem != nil {
-		pprof.Lookup("heap").WriteTo(prof.mem, 0)
-		prof.mem.Close()
-		log.Println("mem profile stopped")
+		err := pprof.Lookup("heap").WriteTo(prof.mem, 0)
+		if err == nil {
+			err = finishProfile(prof.mem, prof.memPath)
+		} else {
+			prof.mem.Close()
+			os.Remove(prof.mem.Name())
+		}
+		if err != nil {
+			log.Printf("mem profile not written: %v", err)
+		} else {
+			log.Println("mem profile stopped")
+		}
+		prof.mem = nil
+	}
+}
+
+// finishProfile flushes the profile written to f to disk and renames it to
+// path. An empty profile is removed instead.
+func finishProfile(f *os.File, path string) error {
+	err := f.Sync()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		var fi os.FileInfo
+		if fi, err = os.Stat(f.Name()); err == nil && fi.Size() == 0 {
+			err = fmt.Errorf("%s is empty", f.Name())
+		}
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
 	}
+	return os.Rename(f.Name(), path)
 }
 
 type tcpaddr struct{ host string }
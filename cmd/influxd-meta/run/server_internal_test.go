@@ -0,0 +1,154 @@
+package run
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zhexuany/influxcloud/meta"
+)
+
+// openServer opens a single node server logging to nowhere.
+func openServer(t *testing.T) *Server {
+	dir, err := ioutil.TempDir("", "influxd-meta-server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	c := meta.NewConfig()
+	c.Dir = dir
+	c.BindAddress = "127.0.0.1:0"
+	c.HTTPBindAddress = "127.0.0.1:0"
+	c.LeadershipTransferTimeout = 0
+	s, err := NewServer(c, &BuildInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetLogOutput(ioutil.Discard)
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// Ensure errors forwarded to an Err channel nobody reads don't block the
+// service sending them, nor Close.
+func TestServer_Close_FullErrChannel(t *testing.T) {
+	s := openServer(t)
+	s.Err()
+
+	errs := make(chan error)
+	monitored := make(chan struct{})
+	go func() {
+		s.monitorErrorChan(errs)
+		close(monitored)
+	}()
+	for i := 0; i < 2*errBufferSize; i++ {
+		select {
+		case errs <- fmt.Errorf("error %d", i):
+		case <-time.After(5 * time.Second):
+			t.Fatalf("error %d blocked on a full error channel", i)
+		}
+	}
+
+	closed := make(chan error, 1)
+	go func() { closed <- s.Close() }()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatal("Close blocked on a full error channel")
+	}
+	select {
+	case <-monitored:
+	case <-time.After(5 * time.Second):
+		t.Fatal("error monitor still running after Close")
+	}
+
+	if n := len(s.err); n != errBufferSize {
+		t.Fatalf("unexpected number of errors buffered: %d", n)
+	}
+}
+
+// Ensure a listener that can't be reopened is retried in the background,
+// while other errors are still forwarded, until the server is closed.
+func TestServer_ReopenListener_Close(t *testing.T) {
+	s := openServer(t)
+	forwarded := s.Err()
+
+	// The listener is still up, so reopening it fails.
+	errs := make(chan error)
+	monitored := make(chan struct{})
+	go func() {
+		s.monitorErrorChan(errs)
+		close(monitored)
+	}()
+	for _, err := range []error{
+		&meta.ListenerError{Addr: s.Service.HTTPAddr(), Err: fmt.Errorf("accept failed")},
+		fmt.Errorf("other error"),
+	} {
+		select {
+		case errs <- err:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s not read while reopening the listener", err)
+		}
+		select {
+		case <-forwarded:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s not forwarded", err)
+		}
+	}
+	if atomic.LoadInt32(&s.reopening) != 1 {
+		t.Fatal("listener not being reopened")
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-monitored:
+	case <-time.After(5 * time.Second):
+		t.Fatal("error monitor still running after Close")
+	}
+	for deadline := time.Now().Add(5 * time.Second); atomic.LoadInt32(&s.reopening) != 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("still reopening the listener after Close")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Ensure the profiles are only at their paths once complete, with no
+// temporary files left behind.
+func TestProfile_StartStop(t *testing.T) {
+	dir, err := ioutil.TempDir("", "influxd-meta-profile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cpu, mem := filepath.Join(dir, "cpu.pprof"), filepath.Join(dir, "mem.pprof")
+
+	startProfile(cpu, mem)
+	if _, err := os.Stat(cpu); !os.IsNotExist(err) {
+		t.Fatalf("CPU profile at its path before it is complete: %v", err)
+	}
+	stopProfile()
+
+	for _, path := range []string{cpu, mem} {
+		if fi, err := os.Stat(path); err != nil {
+			t.Fatal(err)
+		} else if fi.Size() == 0 {
+			t.Fatalf("%s is empty", path)
+		}
+		if _, err := os.Stat(path + profileTempSuffix); !os.IsNotExist(err) {
+			t.Fatalf("temporary file left behind: %v", err)
+		}
+	}
+}
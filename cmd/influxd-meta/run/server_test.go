@@ -0,0 +1,948 @@
+package run_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+	"github.com/zhexuany/influxcloud"
+	"github.com/zhexuany/influxcloud/cmd/influxd-meta/run"
+	"github.com/zhexuany/influxcloud/meta"
+)
+
+// Ensure the server, the meta service and the meta client all log to the
+// writer set with SetLogOutput.
+func TestServer_SetLogOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "influxd-meta-log-output")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := meta.NewConfig()
+	c.Dir = dir
+	c.BindAddress = "127.0.0.1:0"
+	c.HTTPBindAddress = "127.0.0.1:0"
+	c.LeadershipTransferTimeout = 0
+	s, err := run.NewServer(c, &run.BuildInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf lockedBuffer
+	s.SetLogOutput(&buf)
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for _, line := range []string{
+		"Opening Server for meta service",
+		"[metastore] ",
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Fatalf("expected %q in log output:\n%s", line, buf.String())
+		}
+	}
+	if s.MetaClient.Logger().Writer() != &buf {
+		t.Fatal("meta client not logging to the log output")
+	}
+}
+
+// Ensure the meta client of the server closes connections idle past
+// client-max-idle-time.
+func TestServer_MetaClientIdleTimeout(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	c := meta.NewConfig()
+	c.Dir = dir
+	c.BindAddress = "127.0.0.1:0"
+	c.HTTPBindAddress = "127.0.0.1:0"
+	c.LeadershipTransferTimeout = 0
+	c.ClientMaxIdleTime = toml.Duration(7 * time.Second)
+	s, err := run.NewServer(c, &run.BuildInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetLogOutput(ioutil.Discard)
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if tr, ok := s.MetaClient.HTTPClient.Transport.(*http.Transport); !ok {
+		t.Fatalf("unexpected transport: %T", s.MetaClient.HTTPClient.Transport)
+	} else if tr.IdleConnTimeout != 7*time.Second {
+		t.Fatalf("unexpected idle timeout: %s", tr.IdleConnTimeout)
+	}
+}
+
+// Ensure the pprof handlers are served on pprof-bind-address while the
+// server is open, and only then.
+func TestServer_PprofBindAddress(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	c := meta.NewConfig()
+	c.Dir = dir
+	c.BindAddress = "127.0.0.1:0"
+	c.HTTPBindAddress = "127.0.0.1:0"
+	c.PprofBindAddress = "127.0.0.1:0"
+	c.LeadershipTransferTimeout = 0
+	s, err := run.NewServer(c, &run.BuildInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetLogOutput(ioutil.Discard)
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	url := "http://" + s.PprofAddr() + "/debug/pprof/cmdline"
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status: %s", resp.Status)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := http.Get(url); err == nil {
+		resp.Body.Close()
+		t.Fatal("pprof still served after close")
+	}
+}
+
+// Ensure the HTTP API and the pprof handlers are served on Unix sockets
+// given as unix:// addresses, with socket-permissions, and that stale socket
+// files are replaced on open and removed on close.
+func TestServer_UnixSocket(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	httpSock := filepath.Join(dir, "meta.sock")
+	pprofSock := filepath.Join(dir, "pprof.sock")
+
+	// Leave a stale socket behind, as a crashed node would.
+	ln, err := net.Listen("unix", httpSock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	ln.Close()
+
+	c := meta.NewConfig()
+	c.Dir = dir
+	c.BindAddress = "127.0.0.1:0"
+	c.HTTPBindAddress = "unix://" + httpSock
+	c.PprofBindAddress = "unix://" + pprofSock
+	c.SocketPermissions = "0600"
+	c.LeadershipTransferTimeout = 0
+	s, err := run.NewServer(c, &run.BuildInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetLogOutput(ioutil.Discard)
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sock := range []string{httpSock, pprofSock} {
+		fi, err := os.Stat(sock)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode()&os.ModeSocket == 0 || fi.Mode().Perm() != 0600 {
+			t.Fatalf("unexpected mode of %s: %s", sock, fi.Mode())
+		}
+	}
+
+	get := func(sock, path string) {
+		client := &http.Client{Transport: &http.Transport{
+			Dial: func(string, string) (net.Conn, error) { return net.Dial("unix", sock) },
+		}}
+		resp, err := client.Get("http://localhost" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("unexpected status of %s: %s", path, resp.Status)
+		}
+	}
+	get(httpSock, "/health")
+	get(httpSock, "/metrics")
+	get(pprofSock, "/debug/pprof/cmdline")
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, sock := range []string{httpSock, pprofSock} {
+		if _, err := os.Stat(sock); !os.IsNotExist(err) {
+			t.Fatalf("socket %s not removed on close: %v", sock, err)
+		}
+	}
+}
+
+// Ensure a file at a unix:// address that isn't a socket is left alone and
+// the server refuses to open.
+func TestServer_UnixSocket_NotSocket(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	httpSock := filepath.Join(dir, "meta.sock")
+	if err := ioutil.WriteFile(httpSock, []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	c := meta.NewConfig()
+	c.Dir = dir
+	c.BindAddress = "127.0.0.1:0"
+	c.HTTPBindAddress = "unix://" + httpSock
+	c.LeadershipTransferTimeout = 0
+	s, err := run.NewServer(c, &run.BuildInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetLogOutput(ioutil.Discard)
+	if err := s.Open(); err == nil || !strings.Contains(err.Error(), "isn't a socket") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b, err := ioutil.ReadFile(httpSock); err != nil || string(b) != "data" {
+		t.Fatalf("file replaced: %q, %v", b, err)
+	}
+}
+
+// Ensure the pprof listener is disabled by default.
+func TestServer_PprofDisabled(t *testing.T) {
+	if addr := meta.NewConfig().PprofBindAddress; addr != "" {
+		t.Fatalf("pprof enabled by default on %s", addr)
+	}
+	if addr := (&run.Server{}).PprofAddr(); addr != "" {
+		t.Fatalf("unexpected pprof address: %s", addr)
+	}
+}
+
+// Ensure a corrupt node.json stops the server from being created, with an
+// error naming the file.
+func TestNewServer_CorruptNodeFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "influxd-meta-node-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "node.json")
+	if err := ioutil.WriteFile(path, []byte(`{"ID":`), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	c := meta.NewConfig()
+	c.Dir = dir
+	if _, err := run.NewServer(c, &run.BuildInfo{}); err == nil {
+		t.Fatal("expected an error")
+	} else if !strings.Contains(err.Error(), path+": file is truncated") {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+// Ensure the meta and raft directories are created, or the meta directory
+// tightened if it exists, readable by its owner only, and the raft log is
+// synced by default.
+func TestNewServer_DirMode(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	c := meta.NewConfig()
+	c.Dir = filepath.Join(dir, "meta")
+	c.RaftDir = filepath.Join(dir, "raft")
+	if !c.SyncWrites {
+		t.Fatal("raft log not synced by default")
+	}
+	if _, err := run.NewServer(c, &run.BuildInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{c.Dir, c.RaftDir} {
+		if fi, err := os.Stat(path); err != nil {
+			t.Fatal(err)
+		} else if perm := fi.Mode().Perm(); perm != meta.DirMode {
+			t.Fatalf("unexpected mode of %s: %s", path, perm)
+		}
+	}
+
+	if err := os.Chmod(c.Dir, 0755); err != nil {
+		t.Fatal(err)
+	} else if _, err := run.NewServer(c, &run.BuildInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(c.Dir); err != nil {
+		t.Fatal(err)
+	} else if perm := fi.Mode().Perm(); perm != meta.DirMode {
+		t.Fatalf("existing dir not tightened: %s", perm)
+	}
+}
+
+// Ensure a fresh node configured with a join address joins the cluster when
+// the server opens, and joining again returns the same meta node.
+func TestServer_JoinCluster(t *testing.T) {
+	dir0, dir1 := tempDir(t), tempDir(t)
+	defer os.RemoveAll(dir0)
+	defer os.RemoveAll(dir1)
+
+	s0 := openServer(t, dir0, "")
+	defer s0.Close()
+	s1 := openServer(t, dir1, s0.Service.HTTPAddr())
+	defer s1.Close()
+
+	node, err := influxcloud.LoadNode(dir1)
+	if err != nil {
+		t.Fatal(err)
+	} else if node.ID == 0 || s1.Service.Node.ID != node.ID {
+		t.Fatalf("unexpected node id: %d", node.ID)
+	} else if !s0.Service.IsLeader() {
+		t.Fatal("joining node took over the cluster")
+	}
+
+	if n, err := s1.MetaClient.JoinCluster(s0.Service.HTTPAddr()); err != nil {
+		t.Fatal(err)
+	} else if n.ID != node.ID {
+		t.Fatalf("rejoined as node %d, expected %d", n.ID, node.ID)
+	}
+	if nodes, err := s1.MetaClient.MetaNodes(); err != nil {
+		t.Fatal(err)
+	} else if len(nodes) != 2 || nodes[0].ID == nodes[1].ID {
+		t.Fatalf("unexpected meta nodes: %v", nodes)
+	}
+}
+
+// Ensure servers bound to a port of 0, on IPv4 and IPv6 loopback, listen on
+// the ports assigned and register those with the cluster when joining it.
+func TestServer_Open_AssignedPorts(t *testing.T) {
+	for _, host := range []string{"127.0.0.1", "::1"} {
+		t.Run(host, func(t *testing.T) {
+			if ln, err := net.Listen("tcp", net.JoinHostPort(host, "0")); err != nil {
+				t.Skipf("can't listen on %s: %s", host, err)
+			} else {
+				ln.Close()
+			}
+
+			open := func(dir, joinAddr string) *run.Server {
+				c := meta.NewConfig()
+				c.Dir = dir
+				c.BindAddress = net.JoinHostPort(host, "0")
+				c.HTTPBindAddress = net.JoinHostPort(host, "0")
+				c.LeadershipTransferTimeout = 0
+				c.JoinAddress = joinAddr
+				s, err := run.NewServer(c, &run.BuildInfo{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				s.SetLogOutput(ioutil.Discard)
+				if err := s.Open(); err != nil {
+					t.Fatal(err)
+				}
+				for _, addr := range []string{s.BindAddress, s.HTTPAddr()} {
+					if h, port, err := net.SplitHostPort(addr); err != nil || h != host || port == "0" {
+						t.Fatalf("unexpected address: %q", addr)
+					}
+				}
+				return s
+			}
+
+			dir0, dir1 := tempDir(t), tempDir(t)
+			defer os.RemoveAll(dir0)
+			defer os.RemoveAll(dir1)
+			s0 := open(dir0, "")
+			defer s0.Close()
+			s1 := open(dir1, s0.HTTPAddr())
+			defer s1.Close()
+
+			node, err := influxcloud.LoadNode(dir1)
+			if err != nil {
+				t.Fatal(err)
+			} else if node.ID == 0 {
+				t.Fatal("node.json has no ID")
+			}
+			for _, n := range waitMetaNodes(t, s1.MetaClient, 2) {
+				s := s0
+				if n.ID == node.ID {
+					s = s1
+				}
+				if n.Host != s.HTTPAddr() || n.TCPHost != s.BindAddress {
+					t.Fatalf("meta node %d registered at %s and %s, expected %s and %s", n.ID, n.Host, n.TCPHost, s.HTTPAddr(), s.BindAddress)
+				}
+			}
+		})
+	}
+}
+
+// Ensure a single-node server is ready to use as soon as it opens, within
+// less than the default raft timeouts, and other nodes can still join it.
+func TestServer_SingleNode(t *testing.T) {
+	dir0, dir1 := tempDir(t), tempDir(t)
+	defer os.RemoveAll(dir0)
+	defer os.RemoveAll(dir1)
+
+	c := meta.NewConfig()
+	c.Dir = dir0
+	c.BindAddress = freePort(t)
+	c.HTTPBindAddress = freePort(t)
+	c.LeadershipTransferTimeout = 0
+	c.SingleNode = true
+	s0, err := run.NewServer(c, &run.BuildInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s0.SetLogOutput(ioutil.Discard)
+	start := time.Now()
+	if err := s0.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s0.Close()
+	if d := time.Since(start); d >= meta.DefaultHeartbeatTimeout {
+		t.Fatalf("took %s to open", d)
+	} else if !s0.Service.IsLeader() {
+		t.Fatal("not the leader once open")
+	}
+	if _, err := s0.MetaClient.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+
+	s1 := openServer(t, dir1, s0.HTTPAddr())
+	defer s1.Close()
+	waitMetaNodes(t, s0.MetaClient, 2)
+
+	c = meta.NewConfig()
+	c.SingleNode = true
+	c.JoinAddress = s0.HTTPAddr()
+	if err := c.Validate(); err == nil || !strings.Contains(err.Error(), "join-address: must not be set with single-node") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure Server.Open returns a BindError of kind ErrBindAddressInUse when
+// its bind address is already being listened on.
+func TestServer_Open_BindAddressInUse(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	c := meta.NewConfig()
+	c.Dir = dir
+	c.BindAddress = ln.Addr().String()
+	c.HTTPBindAddress = "127.0.0.1:0"
+	s, err := run.NewServer(c, &run.BuildInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetLogOutput(ioutil.Discard)
+
+	err = s.Open()
+	if !errors.Is(err, run.ErrBindAddressInUse) {
+		t.Fatalf("unexpected error: %v", err)
+	} else if errors.Is(err, run.ErrBindAddressInvalid) || errors.Is(err, run.ErrBindPermissionDenied) {
+		t.Fatalf("error matches another kind: %v", err)
+	}
+	var bindErr *run.BindError
+	if !errors.As(err, &bindErr) {
+		t.Fatalf("not a BindError: %T", err)
+	} else if bindErr.Addr != ln.Addr().String() {
+		t.Fatalf("unexpected addr: %s", bindErr.Addr)
+	}
+}
+
+// Ensure pprof-bind-address in use fails Server.Open with a BindError, and
+// that the pprof listener is closed when Open fails after opening it.
+func TestServer_Open_PprofBindError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	open := func(bindAddr, pprofAddr string) error {
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+		c := meta.NewConfig()
+		c.Dir = dir
+		c.BindAddress = bindAddr
+		c.HTTPBindAddress = "127.0.0.1:0"
+		c.PprofBindAddress = pprofAddr
+		s, err := run.NewServer(c, &run.BuildInfo{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.SetLogOutput(ioutil.Discard)
+		return s.Open()
+	}
+
+	err = open("127.0.0.1:0", ln.Addr().String())
+	var bindErr *run.BindError
+	if !errors.Is(err, run.ErrBindAddressInUse) || !errors.As(err, &bindErr) {
+		t.Fatalf("unexpected error: %v", err)
+	} else if bindErr.Addr != ln.Addr().String() {
+		t.Fatalf("unexpected addr: %s", bindErr.Addr)
+	}
+
+	pprofAddr := freePort(t)
+	if err := open(ln.Addr().String(), pprofAddr); !errors.Is(err, run.ErrBindAddressInUse) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pprofLn, err := net.Listen("tcp", pprofAddr)
+	if err != nil {
+		t.Fatalf("pprof listener left open: %s", err)
+	}
+	pprofLn.Close()
+}
+
+// Ensure Server.Open gives up after startup-timeout when the meta servers
+// are reachable but can't serve the metadata.
+func TestServer_Open_StartupTimeout(t *testing.T) {
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no leader", http.StatusServiceUnavailable)
+	}))
+	defer unavailable.Close()
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	c := meta.NewConfig()
+	c.Dir = dir
+	c.BindAddress = "127.0.0.1:0"
+	c.HTTPBindAddress = "127.0.0.1:0"
+	c.RemoteHostname = strings.TrimPrefix(unavailable.URL, "http://")
+	c.StartupTimeout = toml.Duration(500 * time.Millisecond)
+	s, err := run.NewServer(c, &run.BuildInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetLogOutput(ioutil.Discard)
+	defer s.Close()
+
+	opened := make(chan error, 1)
+	go func() { opened <- s.Open() }()
+	select {
+	case err := <-opened:
+		if err == nil || !strings.Contains(err.Error(), "startup-timeout") {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatal("Open didn't give up after startup-timeout")
+	}
+}
+
+// openServer opens a meta server in dir on fixed ports, joining the cluster
+// through joinAddr if it is set.
+func openServer(t *testing.T, dir, joinAddr string) *run.Server {
+	return openServerConfig(t, newServerConfig(t, dir, joinAddr))
+}
+
+// Ensure a component's own shutdown timeout overrides shutdown-timeout, so a
+// stuck listener is abandoned even when the rest of the server waits forever.
+func TestServer_Close_ComponentTimeout(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	c := newServerConfig(t, dir, "")
+	c.ShutdownTimeout = 0
+	c.ListenerShutdownTimeout = toml.Duration(10 * time.Millisecond)
+	s := openServerConfig(t, c)
+
+	block := make(chan struct{})
+	defer close(block)
+	s.Listener = &blockingListener{Listener: s.Listener, block: block}
+
+	err := s.Close()
+	if err == nil || !strings.Contains(err.Error(), "listener") {
+		t.Fatalf("expected error naming the listener, got %v", err)
+	}
+}
+
+// blockingListener is a listener whose Close blocks until block is closed.
+type blockingListener struct {
+	net.Listener
+	block chan struct{}
+}
+
+func (l *blockingListener) Close() error {
+	<-l.block
+	return l.Listener.Close()
+}
+
+// newServerConfig returns the config openServer opens a meta server with.
+func newServerConfig(t *testing.T, dir, joinAddr string) *meta.Config {
+	c := meta.NewConfig()
+	c.Dir = dir
+	c.BindAddress = freePort(t)
+	c.HTTPBindAddress = freePort(t)
+	c.LeadershipTransferTimeout = 0
+	c.JoinAddress = joinAddr
+	return c
+}
+
+// openServerConfig opens a meta server with c.
+func openServerConfig(t *testing.T, c *meta.Config) *run.Server {
+	s, err := run.NewServer(c, &run.BuildInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetLogOutput(ioutil.Discard)
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "influxd-meta-join")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// freePort returns a loopback address with a port that is free to listen on.
+func freePort(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+// waitMetaNodes waits for c to have the metadata with n meta nodes, which
+// it may not have yet right after a node joined, and returns them.
+func waitMetaNodes(t *testing.T, c *meta.Client, n int) meta.NodeInfos {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		nodes, err := c.MetaNodes()
+		if err != nil {
+			t.Fatal(err)
+		} else if len(nodes) == n {
+			return nodes
+		} else if time.Now().After(deadline) {
+			t.Fatalf("unexpected meta nodes: %v", nodes)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// lockedBuffer is a bytes.Buffer that can be written from several goroutines.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// Ensure a node.json last validated longer than node-max-age ago is
+// validated against the cluster before the server resumes, and one naming a
+// node that is no longer a member is refused.
+func TestServer_Open_StaleNodeFile(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	c := meta.NewConfig()
+	c.Dir = dir
+	c.BindAddress = freePort(t)
+	c.HTTPBindAddress = freePort(t)
+	c.LeadershipTransferTimeout = 0
+	open := func(nodeFile string) (*run.Server, *lockedBuffer, error) {
+		if err := ioutil.WriteFile(filepath.Join(dir, "node.json"), []byte(nodeFile), 0666); err != nil {
+			t.Fatal(err)
+		}
+		s, err := run.NewServer(c, &run.BuildInfo{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var log lockedBuffer
+		s.SetLogOutput(&log)
+		return s, &log, s.Open()
+	}
+
+	s, _, err := open(`{"ID":0}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := s.MetaClient.Data().MetaNodes[0].ID
+	s.Close()
+
+	stale := time.Now().Add(-2 * time.Duration(c.NodeMaxAge)).Unix()
+	s, log, err := open(fmt.Sprintf(`{"ID":%d,"Validated":%d}`, id, stale))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	if !strings.Contains(log.String(), "longer than node-max-age") {
+		t.Fatalf("node not validated: %s", log.String())
+	}
+	if node, err := influxcloud.LoadNode(dir); err != nil {
+		t.Fatal(err)
+	} else if time.Since(node.ValidatedAt()) > time.Minute {
+		t.Fatalf("validation not recorded: %v", node.ValidatedAt())
+	}
+
+	s, _, err = open(fmt.Sprintf(`{"ID":%d,"Validated":%d}`, id+1, stale))
+	s.Close()
+	if err == nil || !strings.Contains(err.Error(), "no longer a member of the cluster") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure a stale node.json naming a node that is no longer a member of the
+// cluster at join-address is refused before the meta service opens raft.
+func TestServer_Open_StaleNodeFile_JoinAddress(t *testing.T) {
+	dir0, dir1 := tempDir(t), tempDir(t)
+	defer os.RemoveAll(dir0)
+	defer os.RemoveAll(dir1)
+
+	s0 := openServer(t, dir0, "")
+	defer s0.Close()
+
+	c := meta.NewConfig()
+	c.Dir = dir1
+	c.BindAddress = freePort(t)
+	c.HTTPBindAddress = freePort(t)
+	c.JoinAddress = s0.Service.HTTPAddr()
+	stale := time.Now().Add(-2 * time.Duration(c.NodeMaxAge)).Unix()
+	if err := ioutil.WriteFile(filepath.Join(dir1, "node.json"), []byte(fmt.Sprintf(`{"ID":99,"Validated":%d}`, stale)), 0666); err != nil {
+		t.Fatal(err)
+	}
+	s1, err := run.NewServer(c, &run.BuildInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s1.SetLogOutput(ioutil.Discard)
+	if err := s1.Open(); err == nil || !strings.Contains(err.Error(), "no longer a member of the cluster") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir1, "raft.db")); !os.IsNotExist(err) {
+		t.Fatalf("raft opened before node.json was validated: %v", err)
+	}
+}
+
+// Ensure an observer follows the cluster without joining raft: its meta
+// client reads the local copy of the metadata, its writes are redirected to
+// the leader, and it never leads.
+func TestServer_ObserverMode(t *testing.T) {
+	dir0, dir1 := tempDir(t), tempDir(t)
+	defer os.RemoveAll(dir0)
+	defer os.RemoveAll(dir1)
+
+	s0 := openServer(t, dir0, "")
+	defer s0.Close()
+
+	c := meta.NewConfig()
+	c.Dir = dir1
+	c.BindAddress = freePort(t)
+	c.HTTPBindAddress = freePort(t)
+	c.RemoteHostnames = []string{s0.Service.HTTPAddr()}
+	c.ObserverMode = true
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	s1, err := run.NewServer(c, &run.BuildInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s1.SetLogOutput(ioutil.Discard)
+	if err := s1.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s1.Close()
+
+	if got := s1.MetaClient.MetaServers(); len(got) != 1 || got[0] != s1.Service.HTTPAddr() {
+		t.Fatalf("observer's meta client doesn't read locally: %v", got)
+	}
+	if _, err := s1.MetaClient.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if s1.MetaClient.Data().Database("db0") == nil {
+		t.Fatal("write not read back from the observer")
+	}
+	// The next write goes to the leader the observer cached.
+	if _, err := s1.MetaClient.CreateDatabase("db1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The write reached the cluster through its leader.
+	deadline := time.Now().Add(5 * time.Second)
+	for s0.MetaClient.Data().Database("db0") == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("write not forwarded to the leader")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if s1.Service.IsLeader() {
+		t.Fatal("observer reports leadership")
+	} else if nodes, err := s0.MetaClient.MetaNodes(); err != nil {
+		t.Fatal(err)
+	} else if len(nodes) != 1 {
+		t.Fatalf("observer joined the cluster: %v", nodes)
+	}
+
+	resp, err := http.Get("http://" + s1.Service.HTTPAddr() + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("observer not healthy: %s", resp.Status)
+	}
+}
+
+// Ensure a server in safe mode serves its diagnostics, but neither opens
+// raft nor serves the metadata.
+func TestServer_SafeMode(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	c := meta.NewConfig()
+	c.Dir = dir
+	c.BindAddress = freePort(t)
+	c.HTTPBindAddress = "127.0.0.1:0"
+	c.LeadershipTransferTimeout = 0
+	s, err := run.NewServer(c, &run.BuildInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetLogOutput(ioutil.Discard)
+	s.SafeMode = true
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get("http://" + s.Service.HTTPAddr() + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(b)
+	}
+
+	if code, body := get("/health"); code != http.StatusServiceUnavailable || !strings.Contains(body, `"status":"safe-mode"`) {
+		t.Fatalf("unexpected health: %d %s", code, body)
+	}
+	for _, path := range []string{"/debug/config", "/debug/version", "/metrics"} {
+		if code, body := get(path); code != http.StatusOK {
+			t.Fatalf("unexpected response from %s: %d %s", path, code, body)
+		}
+	}
+	if code, body := get("/peers"); code != http.StatusServiceUnavailable || !strings.Contains(body, meta.ErrSafeMode.Error()) {
+		t.Fatalf("unexpected response from /peers: %d %s", code, body)
+	}
+
+	// Raft isn't listening on bind-address.
+	if conn, err := net.Dial("tcp", c.BindAddress); err == nil {
+		conn.Close()
+		t.Fatal("raft listener opened in safe mode")
+	}
+	if s.Service.IsLeader() {
+		t.Fatal("service in safe mode is the raft leader")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "raft.db")); !os.IsNotExist(err) {
+		t.Fatalf("raft store opened in safe mode: %v", err)
+	}
+}
+
+// Ensure Reload applies the keys that can change at runtime, and leaves out
+// and reports the others.
+func TestServer_Reload(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	c := meta.NewConfig()
+	c.Dir = dir
+	c.BindAddress = "127.0.0.1:0"
+	c.HTTPBindAddress = "127.0.0.1:0"
+	c.LeadershipTransferTimeout = 0
+	s, err := run.NewServer(c, &run.BuildInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf lockedBuffer
+	s.SetLogOutput(&buf)
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	reloaded := *c
+	reloaded.LogFormat = meta.LogFormatJSON
+	reloaded.HTTPRateLimit = 5
+	reloaded.HTTPRateLimitAllowlist = []string{"10.0.0.0/8"}
+	if err := s.Reload(&reloaded); err != nil {
+		t.Fatal(err)
+	}
+	if c.LogFormat != meta.LogFormatJSON || c.HTTPRateLimit != 5 || len(c.HTTPRateLimitAllowlist) != 1 {
+		t.Fatalf("config not reloaded: %+v", c)
+	}
+	if !strings.Contains(buf.String(), `"msg":"Reloaded config: http-rate-limit, http-rate-limit-allowlist, log-format"`) {
+		t.Fatalf("reload not logged as JSON:\n%s", buf.String())
+	}
+
+	// Keys that can't change at runtime are left out and reported.
+	restart := reloaded
+	restart.Dir = filepath.Join(dir, "other")
+	restart.BindAddress = "127.0.0.1:1"
+	restart.HTTPRateLimitBurst = 10
+	err = s.Reload(&restart)
+	if rerr, ok := err.(*run.ReloadError); !ok {
+		t.Fatalf("unexpected error: %v", err)
+	} else if got := strings.Join(rerr.Keys, ","); got != "bind-address,dir" {
+		t.Fatalf("unexpected keys left out: %s", got)
+	} else if !strings.Contains(err.Error(), "bind-address, dir") {
+		t.Fatalf("keys not named in error: %s", err)
+	}
+	if c.Dir != dir || c.BindAddress != "127.0.0.1:0" {
+		t.Fatalf("key that can't be reloaded changed: dir=%s bind-address=%s", c.Dir, c.BindAddress)
+	} else if c.HTTPRateLimitBurst != 10 {
+		t.Fatalf("http-rate-limit-burst not reloaded: %d", c.HTTPRateLimitBurst)
+	}
+	if !strings.Contains(buf.String(), "can't be reloaded") {
+		t.Fatalf("keys left out not logged:\n%s", buf.String())
+	}
+
+	// An invalid config is refused whole.
+	invalid := reloaded
+	invalid.LogFormat = "xml"
+	invalid.HTTPRateLimit = 1
+	if err := s.Reload(&invalid); err == nil {
+		t.Fatal("expected an error")
+	} else if c.HTTPRateLimit != 5 {
+		t.Fatalf("invalid config partly applied: http-rate-limit=%v", c.HTTPRateLimit)
+	}
+}
@@ -0,0 +1,78 @@
+package run
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ShowClusterCommand represents the command executed by
+// "influxd-meta show-cluster".
+type ShowClusterCommand struct {
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// NewShowClusterCommand return a new instance of ShowClusterCommand.
+func NewShowClusterCommand() *ShowClusterCommand {
+	return &ShowClusterCommand{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Run prints the meta and data nodes of the cluster and the shard groups
+// pending deletion.
+func (cmd *ShowClusterCommand) Run(args ...string) error {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	var mf metaServiceFlags
+	mf.register(fs)
+	fs.Usage = func() { fmt.Fprintln(cmd.Stderr, showClusterUsage) }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	data, err := fetchSnapshot(&mf)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(cmd.Stdout, "Meta nodes:")
+	for _, n := range data.MetaNodes {
+		fmt.Fprintf(cmd.Stdout, "%d\t%s\t%s\n", n.ID, n.Host, n.TCPHost)
+	}
+	fmt.Fprintln(cmd.Stdout, "Data nodes:")
+	for _, n := range data.DataNodes {
+		fmt.Fprintf(cmd.Stdout, "%d\t%s\t%s\n", n.ID, n.Host, n.TCPHost)
+	}
+	fmt.Fprintln(cmd.Stdout, "Shard groups pending deletion:")
+	for _, d := range data.PendingDeletions() {
+		fmt.Fprintf(cmd.Stdout, "%d\t%s.%s\tdeleted=%s\tpurge=%s\n", d.ShardGroupID, d.Database, d.RetentionPolicy,
+			d.DeletedAt.UTC().Format(time.RFC3339), d.PurgeAt.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+var showClusterUsage = `Displays the nodes of the cluster and the shard groups pending deletion.
+
+Usage: influxd-meta show-cluster [flags]
+
+Shard groups pending deletion have been marked deleted, as when they
+expired. Their shards are removed from the data nodes by the retention
+service, which purges them from the metadata at the listed time.
+
+    -host <addr>
+            The meta service to read the cluster from.
+            Defaults to localhost:8091.
+
+    -token <token>
+            The auth-token of the meta service, if it sets one.
+
+    -https
+            Talk to the meta service over HTTPS.
+
+    -skip-verify
+            Don't verify the meta service's certificate with -https.
+`
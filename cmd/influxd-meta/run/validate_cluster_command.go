@@ -0,0 +1,121 @@
+package run
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/zhexuany/influxcloud/meta"
+)
+
+// ValidateClusterCommand represents the command executed by
+// "influxd-meta validate-cluster".
+type ValidateClusterCommand struct {
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// NewValidateClusterCommand return a new instance of ValidateClusterCommand.
+func NewValidateClusterCommand() *ValidateClusterCommand {
+	return &ValidateClusterCommand{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Run compares the configuration fingerprint of every meta node in the
+// cluster and returns an error if any setting differs between them.
+func (cmd *ValidateClusterCommand) Run(args ...string) error {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	var mf metaServiceFlags
+	mf.register(fs)
+	configPath := fs.String("config", "", "")
+	fs.Usage = func() { fmt.Fprintln(cmd.Stderr, validateClusterUsage) }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// Talk to the cluster over HTTPS if the node's config serves it, unless
+	// -https says otherwise.
+	if *configPath != "" && !flagSet(fs, "https") {
+		config, err := ParseConfig(*configPath)
+		if err != nil {
+			return fmt.Errorf("parse config: %s", err)
+		}
+		if err := config.ApplyEnvOverrides(); err != nil {
+			return fmt.Errorf("apply env config: %v", err)
+		}
+		mf.https = config.HTTPSEnabled
+	}
+
+	data, err := fetchSnapshot(&mf)
+	if err != nil {
+		return err
+	}
+	servers := make([]string, 0, len(data.MetaNodes))
+	for _, n := range data.MetaNodes {
+		servers = append(servers, n.Host)
+	}
+
+	client := mf.client(servers)
+	fps, err := client.ConfigFingerprints()
+	if err != nil {
+		return err
+	}
+
+	sort.Strings(servers)
+	for _, server := range servers {
+		fmt.Fprintf(cmd.Stdout, "%s\tversion=%s\t%s\n", server, fps[server].Version, fps[server].Fingerprint)
+	}
+	mismatches := meta.ConfigMismatches(fps)
+	for _, m := range mismatches {
+		fmt.Fprintf(cmd.Stdout, "%s differs:\n", m.Setting)
+		for _, server := range servers {
+			fmt.Fprintf(cmd.Stdout, "    %s\t%q\n", server, m.Values[server])
+		}
+	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("meta node configuration differs in %d settings", len(mismatches))
+	}
+	return nil
+}
+
+// flagSet returns whether the flag name was given on the command line.
+func flagSet(fs *flag.FlagSet, name string) bool {
+	var set bool
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+var validateClusterUsage = `Checks that every meta node is configured alike.
+
+Usage: influxd-meta validate-cluster [flags]
+
+The cluster members are read from the given meta service. Each member is
+asked for the settings that must be the same across the cluster, such as
+its TLS, raft and write settings and its version, and every setting that
+differs between members is reported.
+
+    -host <addr>
+            The meta service to read the cluster members from.
+            Defaults to localhost:8091.
+
+    -token <token>
+            The auth-token of the meta service, if it sets one.
+
+    -https
+            Talk to the meta service over HTTPS.
+
+    -skip-verify
+            Don't verify the meta service's certificate with -https.
+
+    -config <path>
+            The configuration file of a meta node of the cluster. Its
+            https-enabled is used when -https isn't given.
+`
@@ -0,0 +1,70 @@
+package run
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/zhexuany/influxcloud/meta"
+)
+
+// VerifySnapshotCommand represents the command executed by
+// "influxd-meta verify-snapshot".
+type VerifySnapshotCommand struct {
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// NewVerifySnapshotCommand return a new instance of VerifySnapshotCommand.
+func NewVerifySnapshotCommand() *VerifySnapshotCommand {
+	return &VerifySnapshotCommand{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Run checks every raft snapshot of a meta data dir and returns an error if
+// any of them is corrupt.
+func (cmd *VerifySnapshotCommand) Run(args ...string) error {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprintln(cmd.Stderr, verifySnapshotUsage) }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected a meta data dir")
+	}
+
+	statuses, err := meta.VerifySnapshots(fs.Arg(0))
+	if err != nil {
+		return err
+	} else if len(statuses) == 0 {
+		fmt.Fprintln(cmd.Stdout, "no snapshots")
+		return nil
+	}
+
+	var corrupt int
+	for _, s := range statuses {
+		status := "ok"
+		if s.Err != nil {
+			status = s.Err.Error()
+			corrupt++
+		}
+		fmt.Fprintf(cmd.Stdout, "%s\tindex=%d\tterm=%d\t%s\n", s.ID, s.Index, s.Term, status)
+	}
+	if corrupt > 0 {
+		return fmt.Errorf("%d of %d snapshots are corrupt", corrupt, len(statuses))
+	}
+	return nil
+}
+
+var verifySnapshotUsage = `Checks the raft snapshots of a meta data dir.
+
+Usage: influxd-meta verify-snapshot <dir>
+
+Each snapshot of dir, newest first, is read back and checked against the
+checksum it was written with. A meta node passes over corrupt snapshots for
+the newest one that is intact when it starts. The dir is not modified.
+`
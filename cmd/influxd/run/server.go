@@ -35,6 +35,7 @@ import (
 	// Initialize the engine packages
 	_ "github.com/influxdata/influxdb/tsdb/engine"
 	"github.com/zhexuany/influxcloud/cluster"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
 )
 
 var startTime time.Time
@@ -96,6 +97,11 @@ type Server struct {
 	// tcpAddr is the host:port combination for the TCP listener that services mux onto
 	tcpAddr string
 
+	// heartbeatClient sends the heartbeats and shard sizes of the data node
+	// to the meta servers of cluster.meta-servers, if any. Only the
+	// heartbeat goroutine uses it.
+	heartbeatClient *cloudMeta.Client
+
 	config *Config
 }
 
@@ -104,7 +110,7 @@ func NewServer(c *Config, buildInfo *BuildInfo) (*Server, error) {
 	// We need to ensure that a meta directory always exists even if
 	// we don't start the meta store.  node.json is always stored under
 	// the meta directory.
-	if err := os.MkdirAll(c.Meta.Dir, 0777); err != nil {
+	if err := cloudMeta.MkdirAll(c.Meta.Dir); err != nil {
 		return nil, fmt.Errorf("mkdir all: %s", err)
 	}
 
@@ -447,6 +453,12 @@ func (s *Server) Open() error {
 		}
 	}
 
+	// Send heartbeats to the meta servers, if any are set.
+	if len(s.config.Cluster.MetaServers) > 0 {
+		s.heartbeatClient = newHeartbeatClient(s.config.Cluster)
+		go s.startHeartbeats()
+	}
+
 	// Start the reporting service, if not disabled.
 	if !s.reportingDisabled {
 		go s.startServerReporting()
@@ -558,6 +570,112 @@ func (s *Server) reportServer() {
 	go cl.Save(usage)
 }
 
+// newHeartbeatClient returns a client of the meta servers of c, which only
+// tries them once when opened, so sending heartbeats goes on retrying each
+// interval instead.
+func newHeartbeatClient(c cluster.Config) *cloudMeta.Client {
+	config := cloudMeta.NewConfig()
+	config.AuthToken = c.MetaAuthToken
+	config.OpenMaxAttempts = 1
+	client := cloudMeta.NewClient(config)
+	client.SetMetaServers(c.MetaServers)
+	client.SetTLS(c.MetaHTTPSEnabled)
+	return client
+}
+
+// startHeartbeats sends a heartbeat to the meta servers every
+// heartbeat-interval, and the sizes of the local shards every
+// shard-size-report-interval, until the server closes.
+func (s *Server) startHeartbeats() {
+	defer s.heartbeatClient.Close()
+
+	ticker := time.NewTicker(time.Duration(s.config.Cluster.HeartbeatInterval))
+	defer ticker.Stop()
+	sizes := time.NewTicker(time.Duration(s.config.Cluster.ShardSizeReportInterval))
+	defer sizes.Stop()
+
+	var opened bool
+	var lastErr string
+	heartbeat := func() {
+		err := s.sendHeartbeat(&opened)
+		if err != nil && err.Error() != lastErr {
+			s.Logger.Warn("sending heartbeat failed", zap.Error(err))
+		} else if err == nil && lastErr != "" {
+			s.Logger.Info("sending heartbeats again")
+		}
+		lastErr = ""
+		if err != nil {
+			lastErr = err.Error()
+		}
+	}
+
+	heartbeat()
+	for {
+		select {
+		case <-s.closing:
+			return
+		case <-ticker.C:
+			heartbeat()
+		case <-sizes.C:
+			s.reportShardSizes()
+		}
+	}
+}
+
+// reportShardSizes sends the size on disk of every local shard to the meta
+// servers.
+func (s *Server) reportShardSizes() {
+	ids := s.TSDBStore.ShardIDs()
+	sizes := make(map[uint64]int64, len(ids))
+	for _, id := range ids {
+		sh := s.TSDBStore.Shard(id)
+		if sh == nil {
+			continue
+		}
+		size, err := sh.DiskSize()
+		if err != nil {
+			s.Logger.Warn("shard size", zap.Uint64("shard", id), zap.Error(err))
+			continue
+		}
+		sizes[id] = size
+	}
+	if err := s.heartbeatClient.ReportShardSizes(sizes); err != nil {
+		s.Logger.Warn("reporting shard sizes failed", zap.Error(err))
+	}
+}
+
+// sendHeartbeat sends the heartbeat of the data node to every meta server,
+// opening the heartbeat client first if *opened is false. The meta servers
+// know the node by its TCP address, so it fails until the node is added.
+func (s *Server) sendHeartbeat(opened *bool) error {
+	if !*opened {
+		if err := s.heartbeatClient.Open(); err != nil {
+			return err
+		}
+		*opened = true
+	}
+
+	addr := remoteAddr(s.tcpAddr)
+	n, err := s.heartbeatClient.DataNodeByTCPHost(addr)
+	if err != nil {
+		return fmt.Errorf("data node %s: %s", addr, err)
+	}
+	return s.heartbeatClient.Heartbeat(n.ID)
+}
+
+// remoteAddr returns addr, with the hostname of the machine as its host if
+// it has none, as other nodes reach it.
+func remoteAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host != "" {
+		return addr
+	}
+	if host, err = os.Hostname(); err != nil {
+		return addr
+	}
+	return net.JoinHostPort(host, port)
+}
+
 // monitorErrorChan reads an error channel and resends it through the server.
 func (s *Server) monitorErrorChan(ch <-chan error) {
 	for {
@@ -0,0 +1,90 @@
+package influxcloud
+
+import (
+	"io/ioutil"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SlowFileWrite is how long a write of node.json may take before it is
+// logged as slow, a sign of a failing or saturated disk.
+const SlowFileWrite = time.Second
+
+// FileStats counts the reads and writes of node.json and loads of the config
+// file by this process, and the total time they took.
+type FileStats struct {
+	NodeReads      int64
+	NodeReadErrors int64
+	NodeReadTime   time.Duration
+
+	NodeWrites      int64
+	NodeWriteErrors int64
+	NodeWriteTime   time.Duration
+
+	ConfigLoads      int64
+	ConfigLoadErrors int64
+	ConfigLoadTime   time.Duration
+}
+
+var (
+	fileStats FileStats
+
+	fileLoggerMu sync.Mutex
+	fileLogger   = log.New(ioutil.Discard, "", 0)
+)
+
+// Stats returns the file statistics of this process so far.
+func Stats() FileStats {
+	return FileStats{
+		NodeReads:        atomic.LoadInt64(&fileStats.NodeReads),
+		NodeReadErrors:   atomic.LoadInt64(&fileStats.NodeReadErrors),
+		NodeReadTime:     time.Duration(atomic.LoadInt64((*int64)(&fileStats.NodeReadTime))),
+		NodeWrites:       atomic.LoadInt64(&fileStats.NodeWrites),
+		NodeWriteErrors:  atomic.LoadInt64(&fileStats.NodeWriteErrors),
+		NodeWriteTime:    time.Duration(atomic.LoadInt64((*int64)(&fileStats.NodeWriteTime))),
+		ConfigLoads:      atomic.LoadInt64(&fileStats.ConfigLoads),
+		ConfigLoadErrors: atomic.LoadInt64(&fileStats.ConfigLoadErrors),
+		ConfigLoadTime:   time.Duration(atomic.LoadInt64((*int64)(&fileStats.ConfigLoadTime))),
+	}
+}
+
+// SetFileLogger sets the logger slow node.json writes are logged to. They
+// are discarded until it is set.
+func SetFileLogger(l *log.Logger) {
+	fileLoggerMu.Lock()
+	defer fileLoggerMu.Unlock()
+	fileLogger = l
+}
+
+// RecordConfigLoad records a load of the config file that took d and
+// failed with err, if not nil.
+func RecordConfigLoad(d time.Duration, err error) {
+	record(&fileStats.ConfigLoads, &fileStats.ConfigLoadErrors, &fileStats.ConfigLoadTime, d, err)
+}
+
+// recordNodeRead records a read of node.json that took d.
+func recordNodeRead(d time.Duration, err error) {
+	record(&fileStats.NodeReads, &fileStats.NodeReadErrors, &fileStats.NodeReadTime, d, err)
+}
+
+// recordNodeWrite records a write of file that took d, and logs it if it
+// took longer than SlowFileWrite.
+func recordNodeWrite(file string, d time.Duration, err error) {
+	record(&fileStats.NodeWrites, &fileStats.NodeWriteErrors, &fileStats.NodeWriteTime, d, err)
+	if d > SlowFileWrite {
+		fileLoggerMu.Lock()
+		l := fileLogger
+		fileLoggerMu.Unlock()
+		l.Printf("Warning: writing %s took %s, longer than %s; check the disk it is on", file, d, SlowFileWrite)
+	}
+}
+
+func record(count, errs *int64, total *time.Duration, d time.Duration, err error) {
+	atomic.AddInt64(count, 1)
+	if err != nil {
+		atomic.AddInt64(errs, 1)
+	}
+	atomic.AddInt64((*int64)(total), int64(d))
+}
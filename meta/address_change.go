@@ -0,0 +1,253 @@
+package meta
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/hashicorp/raft"
+	"github.com/zhexuany/influxcloud"
+	"github.com/zhexuany/influxcloud/meta/internal"
+)
+
+// addressChangeTimeout bounds how long a node whose advertised addresses
+// changed asks the cluster to update its membership before it warns and
+// carries on starting.
+const addressChangeTimeout = 30 * time.Second
+
+// previousAddrs returns the HTTP and raft addresses the node advertised when
+// it last ran: those recorded in node.json, or else those of its meta node
+// in the local metadata. They are empty if neither is known.
+func (s *store) previousAddrs() (httpAddr, raftAddr string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return recordedAddrs(s.node, s.data)
+}
+
+// recordedAddrs returns the HTTP and raft addresses recorded for node in its
+// node.json, or else for its meta node in data, which may be nil.
+func recordedAddrs(node *influxcloud.Node, data *Data) (httpAddr, raftAddr string) {
+	if node.HTTPAddr != "" || node.TCPAddr != "" {
+		return node.HTTPAddr, node.TCPAddr
+	}
+	if data == nil {
+		return "", ""
+	}
+	if n := data.MetaNode(node.ID); n != nil {
+		return n.Host, n.TCPHost
+	}
+	return "", ""
+}
+
+// checkAddressChange handles the addresses the node advertises having
+// changed since it last ran, as set by address-change-policy. Its peers
+// can't reach it at the new raft address until the leader swaps it into the
+// raft membership.
+func (s *store) checkAddressChange() error {
+	if s.node == nil || s.node.ID == 0 {
+		return nil
+	}
+	httpAddr, raftAddr := s.previousAddrs()
+	if (httpAddr == "" && raftAddr == "") || (httpAddr == s.httpAddr && raftAddr == s.raftAddr) {
+		return nil
+	}
+
+	changed := fmt.Sprintf("meta node %d advertised %s and %s when it last ran, now %s and %s",
+		s.node.ID, httpAddr, raftAddr, s.httpAddr, s.raftAddr)
+	servers := s.otherMetaServers()
+	if len(servers) == 0 {
+		// As for a single meta node, which re-registers itself once it
+		// leads. One restarted before it had a snapshot needs join-address.
+		s.logger.Printf("%s; no other meta node is known to ask for its membership to be updated", changed)
+		return nil
+	}
+
+	switch s.config.AddressChangePolicy {
+	case AddressChangeFail:
+		return fmt.Errorf("%s; restore its addresses or set address-change-policy to %q", changed, AddressChangeUpdate)
+	case AddressChangeWarn:
+		s.logger.Printf("Warning: %s; its peers can't reach it until its membership is updated", changed)
+		return nil
+	}
+
+	s.logger.Printf("%s, asking the leader to update its membership", changed)
+	if err := s.requestAddressChange(servers); err != nil {
+		s.logger.Printf("Warning: updating the membership of meta node %d failed, its peers can't reach it until it is: %s", s.node.ID, err)
+		return nil
+	}
+	s.logger.Printf("Updated the membership of meta node %d", s.node.ID)
+	return nil
+}
+
+// otherMetaServers returns the HTTP addresses of the meta servers to ask for
+// an address change: the other meta nodes in the local metadata, or else
+// join-address, as the local metadata only knows of them once restored from
+// a snapshot.
+func (s *store) otherMetaServers() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var servers []string
+	for _, n := range s.data.MetaNodes {
+		if n.ID != s.node.ID {
+			servers = append(servers, n.Host)
+		}
+	}
+	if len(servers) == 0 && s.config.JoinAddress != "" {
+		servers = []string{s.config.JoinAddress}
+	}
+	return servers
+}
+
+// requestAddressChange asks the leader, through servers, to change the
+// addresses of the node to its current ones, retrying for up to
+// addressChangeTimeout.
+func (s *store) requestAddressChange(servers []string) error {
+	c := NewClient(s.config)
+	c.SetMetaServers(servers)
+	c.SetTLS(s.config.HTTPSEnabled)
+	defer c.Close()
+
+	deadline := time.Now().Add(addressChangeTimeout)
+	for {
+		err := c.UpdateMetaNode(s.node.ID, s.httpAddr, s.raftAddr)
+		if err == nil || err == ErrNodeNotFound || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// recordAddrs records the addresses the node advertises in node.json, to
+// tell on the next start whether they changed.
+func (s *store) recordAddrs() error {
+	if s.node == nil || s.node.ID == 0 {
+		return nil
+	}
+	if s.node.HTTPAddr == s.httpAddr && s.node.TCPAddr == s.raftAddr {
+		return nil
+	}
+	s.node.HTTPAddr, s.node.TCPAddr = s.httpAddr, s.raftAddr
+	return s.node.Save()
+}
+
+// updateMetaNode changes the addresses of the meta node n.ID to those of n,
+// swapping its raft address in the raft membership first if it changed.
+func (s *store) updateMetaNode(n *NodeInfo) error {
+	s.mu.RLock()
+	if s.raftState == nil {
+		s.mu.RUnlock()
+		return fmt.Errorf("store not open")
+	}
+	if s.data.TopologyFrozen {
+		s.mu.RUnlock()
+		return ErrTopologyFrozen
+	}
+	if !s.raftState.isLeader() {
+		s.mu.RUnlock()
+		return raft.ErrNotLeader
+	}
+	node := s.data.MetaNode(n.ID)
+	if node == nil {
+		s.mu.RUnlock()
+		return ErrNodeNotFound
+	}
+	if node.TCPHost != n.TCPHost {
+		if err := s.raftState.addPeer(n.TCPHost); err != nil {
+			s.mu.RUnlock()
+			return err
+		}
+		if err := s.raftState.removePeer(node.TCPHost); err != nil {
+			s.mu.RUnlock()
+			return err
+		}
+	}
+	s.mu.RUnlock()
+
+	val := &internal.UpdateMetaNodeCommand{
+		ID:       proto.Uint64(n.ID),
+		HTTPAddr: proto.String(n.Host),
+		TCPAddr:  proto.String(n.TCPHost),
+	}
+	t := internal.Command_UpdateMetaNodeCommand
+	cmd := &internal.Command{Type: &t}
+	if err := proto.SetExtension(cmd, internal.E_UpdateMetaNodeCommand_Command, val); err != nil {
+		panic(err)
+	}
+
+	b, err := proto.Marshal(cmd)
+	if err != nil {
+		return err
+	}
+	return s.apply(b)
+}
+
+// serveUpdateMetaNode changes the addresses of a meta node, as asked by one
+// whose addresses changed across a restart.
+func (h *handler) serveUpdateMetaNode(w http.ResponseWriter, r *http.Request) {
+	n := &NodeInfo{}
+	if err := json.NewDecoder(r.Body).Decode(n); err != nil {
+		h.httpError(err, w, http.StatusBadRequest)
+		return
+	}
+
+	err := h.store.updateMetaNode(n)
+	if err == raft.ErrNotLeader {
+		l := h.store.leaderHTTP()
+		if l == "" {
+			// No cluster leader. Client will have to try again later.
+			h.httpError(errors.New("no leader"), w, http.StatusServiceUnavailable)
+			return
+		}
+		scheme := "http://"
+		if h.config.HTTPSEnabled {
+			scheme = "https://"
+		}
+		http.Redirect(w, r, scheme+l+"/update-meta-node", http.StatusTemporaryRedirect)
+		return
+	}
+
+	switch err {
+	case nil:
+		w.WriteHeader(http.StatusNoContent)
+	case ErrNodeNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case ErrConfigChangeInProgress, ErrTopologyFrozen:
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		h.httpError(err, w, http.StatusInternalServerError)
+	}
+}
+
+// UpdateMetaNode asks the leader to change the addresses of the meta node id
+// to httpAddr and tcpAddr, through the first meta server that answers.
+func (c *Client) UpdateMetaNode(id uint64, httpAddr, tcpAddr string) error {
+	b, err := json.Marshal(&NodeInfo{ID: id, Host: httpAddr, TCPHost: tcpAddr})
+	if err != nil {
+		return err
+	}
+
+	for _, server := range c.MetaServers() {
+		var resp *http.Response
+		resp, err = c.httpClient().Post(c.url(server)+"/update-meta-node", "application/json", bytes.NewReader(b))
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+
+		switch resp.StatusCode {
+		case http.StatusNoContent:
+			return nil
+		case http.StatusNotFound:
+			return ErrNodeNotFound
+		}
+		err = fmt.Errorf("meta server %s returned %s", server, resp.Status)
+	}
+	if err == nil {
+		err = errors.New("no meta servers")
+	}
+	return err
+}
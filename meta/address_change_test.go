@@ -0,0 +1,102 @@
+package meta_test
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/tcp"
+	"github.com/zhexuany/influxcloud"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// raftPeers returns the raft peers reported by the meta server at addr.
+func raftPeers(t *testing.T, addr string) []string {
+	resp, err := http.Get("http://" + addr + "/raft-status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var status struct {
+		Peers []string `json:"peers"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+	return status.Peers
+}
+
+// Ensure a meta node restarted at new addresses asks the leader to update
+// its raft membership and metadata, and records the new addresses.
+func TestService_AddressChange(t *testing.T) {
+	t.Parallel()
+
+	c := cloudMeta.NewTestCluster(t, 3)
+	defer c.Close()
+
+	leader := c.Leader(5 * time.Second)
+	i := 0
+	for c.Services[i] == leader {
+		i++
+	}
+	s := c.Services[i]
+	id, oldRaft := s.Node.ID, s.RaftAddr()
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restart the node at new addresses, as with a new container IP.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	cfg := *c.Configs[i]
+	cfg.BindAddress = ln.Addr().String()
+	cfg.HTTPBindAddress = "127.0.0.1:0"
+	cfg.JoinPeers = nil
+	cfg.JoinAddress = leader.HTTPAddr()
+
+	node, err := influxcloud.LoadNode(cfg.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := tcp.NewMux()
+	restarted := cloudMeta.NewService(&cfg)
+	restarted.Node = node
+	restarted.RaftListener = mux.Listen(cloudMeta.MuxHeader)
+	go mux.Serve(ln)
+	if err := restarted.Open(); err != nil {
+		t.Fatal(err)
+	}
+	c.Services[i] = restarted
+
+	var found bool
+	for _, n := range clusterNodes(t, leader.HTTPAddr()) {
+		if n.ID != id {
+			continue
+		}
+		found = true
+		if n.HTTPAddr != restarted.HTTPAddr() || n.TCPAddr != restarted.RaftAddr() {
+			t.Fatalf("meta node %d not updated: %+v", id, n)
+		}
+	}
+	if !found {
+		t.Fatalf("meta node %d not found", id)
+	}
+
+	peers := raftPeers(t, leader.HTTPAddr())
+	if !cloudMeta.Peers(peers).Contains(restarted.RaftAddr()) || cloudMeta.Peers(peers).Contains(oldRaft) {
+		t.Fatalf("raft peers not updated from %s to %s: %v", oldRaft, restarted.RaftAddr(), peers)
+	}
+
+	node, err = influxcloud.LoadNode(cfg.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.HTTPAddr != restarted.HTTPAddr() || node.TCPAddr != restarted.RaftAddr() {
+		t.Fatalf("new addresses not recorded in node.json: %+v", node)
+	}
+}
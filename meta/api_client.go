@@ -0,0 +1,256 @@
+package meta
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/influxdata/influxdb/uuid"
+	"github.com/zhexuany/influxcloud/meta/internal"
+)
+
+// ErrNoLeader is returned by an APIClient when the meta servers know of no
+// leader, as while the cluster has no quorum.
+var ErrNoLeader = errors.New("meta cluster has no leader")
+
+// commandErrors are the errors of a command the meta service can answer
+// with, by message.
+var commandErrors = map[string]error{
+	ErrDatabaseExists.Error():      ErrDatabaseExists,
+	ErrMaintenanceReadOnly.Error(): ErrMaintenanceReadOnly,
+	ErrWriteNotDurable.Error():     ErrWriteNotDurable,
+	ErrTopologyFrozen.Error():      ErrTopologyFrozen,
+	ErrNotAuthorized.Error():       ErrNotAuthorized,
+}
+
+// APIClientConfig configures an APIClient.
+type APIClientConfig struct {
+	// Addrs are the HTTP addresses of the meta servers, as host:port. They
+	// are tried in order until one answers.
+	Addrs []string
+
+	// TLS, if set, connects over HTTPS with it.
+	TLS *tls.Config
+
+	// AuthToken, if set, is sent as a bearer token to a meta service with
+	// auth-token set.
+	AuthToken string
+
+	// HTTPClient, if set, makes the requests in place of a client of its
+	// own, and TLS only chooses HTTPS over HTTP.
+	HTTPClient *http.Client
+}
+
+// APIClient is a client of the HTTP API of the meta service for programs
+// outside the cluster. Unlike Client, it keeps no copy of the metadata:
+// every method makes a single call, which a follower forwards to the
+// leader where the API requires it, and returns once a meta server
+// answered or ctx is done.
+type APIClient struct {
+	addrs  []string
+	scheme string
+	client *http.Client
+}
+
+// NewAPIClient returns an APIClient for the meta servers of c.
+func NewAPIClient(c APIClientConfig) *APIClient {
+	client := c.HTTPClient
+	scheme := "http://"
+	if client == nil {
+		transport := newHTTPTransport(0)
+		transport.TLSClientConfig = c.TLS
+		client = &http.Client{Transport: transport}
+	}
+	if c.TLS != nil {
+		scheme = "https://"
+	}
+	return &APIClient{
+		addrs:  append([]string(nil), c.Addrs...),
+		scheme: scheme,
+		client: withAuthToken(client, c.AuthToken),
+	}
+}
+
+// Nodes returns the meta nodes of the cluster, as seen by the first meta
+// server to answer.
+func (c *APIClient) Nodes(ctx context.Context) ([]ClusterNode, error) {
+	var nodes []ClusterNode
+	if err := c.getJSON(ctx, "/cluster/nodes", &nodes); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// Leader returns the HTTP address of the leader of the cluster, or
+// ErrNoLeader if the meta servers know of none.
+func (c *APIClient) Leader(ctx context.Context) (string, error) {
+	var st raftStatus
+	if err := c.getJSON(ctx, "/raft-status", &st); err != nil {
+		return "", err
+	} else if st.LeaderHTTP == "" {
+		return "", ErrNoLeader
+	}
+	return st.LeaderHTTP, nil
+}
+
+// CreateDatabase creates the database name, with the default retention
+// policy of the meta service. It does nothing if the database exists.
+func (c *APIClient) CreateDatabase(ctx context.Context, name string) error {
+	return c.exec(ctx, internal.Command_CreateDatabaseCommand, internal.E_CreateDatabaseCommand_Command,
+		&internal.CreateDatabaseCommand{Name: proto.String(name)})
+}
+
+// DropNode removes the meta node id from the cluster, as with
+// Service.DropNode. It returns ErrNodeUnableToDropFinalNode for the last
+// meta node.
+func (c *APIClient) DropNode(ctx context.Context, id uint64) error {
+	resp, err := c.do(ctx, "POST", "/cluster/nodes/"+strconv.FormatUint(id, 10)+"/drop", nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusConflict:
+		return ErrNodeUnableToDropFinalNode
+	default:
+		return apiError(resp)
+	}
+}
+
+// exec has the leader apply the command typ, with the value of its
+// extension desc. A retry after a lost response carries the same
+// idempotency key, so the leader applies the command once. The keys are
+// only remembered by the leader that applied the command, so a retry that
+// reaches a new leader after a failover is applied again.
+func (c *APIClient) exec(ctx context.Context, typ internal.Command_Type, desc *proto.ExtensionDesc, value interface{}) error {
+	cmd := &internal.Command{Type: &typ}
+	if err := proto.SetExtension(cmd, desc, value); err != nil {
+		return err
+	}
+	b, err := proto.Marshal(cmd)
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/octet-stream")
+	header.Set(idempotencyKeyHeader, uuid.TimeUUID().String())
+	resp, err := c.do(ctx, "POST", "/execute", b, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return apiError(resp)
+	}
+
+	b, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	res := &internal.Response{}
+	if err := proto.Unmarshal(b, res); err != nil {
+		return err
+	}
+	if msg := res.GetError(); msg != "" {
+		if err, ok := commandErrors[msg]; ok {
+			return err
+		}
+		return errors.New(msg)
+	}
+	return nil
+}
+
+// getJSON decodes the JSON response to a GET of path into v.
+func (c *APIClient) getJSON(ctx context.Context, path string, v interface{}) error {
+	resp, err := c.do(ctx, "GET", path, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return apiError(resp)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// do sends the request to the meta servers in turn until one answers other
+// than 503 Service Unavailable, which a meta server answers while it knows
+// of no leader to forward to. A follower redirects a request the leader
+// must serve, which the HTTP client follows. It makes up to maxRetries
+// passes over the meta servers, errSleep apart, and returns the last error
+// if none answered, such as ErrNoLeader.
+func (c *APIClient) do(ctx context.Context, method, path string, body []byte, header http.Header) (*http.Response, error) {
+	if len(c.addrs) == 0 {
+		return nil, ErrNoMetaServers
+	}
+
+	lastErr := ErrServiceUnavailable
+	for pass := 0; ; pass++ {
+		for _, addr := range c.addrs {
+			var r io.Reader
+			if body != nil {
+				r = bytes.NewReader(body)
+			}
+			req, err := http.NewRequest(method, c.scheme+addr+path, r)
+			if err != nil {
+				return nil, err
+			}
+			for k, v := range header {
+				req.Header[k] = v
+			}
+
+			resp, err := c.client.Do(req.WithContext(ctx))
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				lastErr = err
+				continue
+			}
+			if resp.StatusCode == http.StatusServiceUnavailable {
+				resp.Body.Close()
+				lastErr = ErrNoLeader
+				continue
+			}
+			return resp, nil
+		}
+
+		if pass+1 >= maxRetries {
+			return nil, lastErr
+		}
+		select {
+		case <-time.After(errSleep):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// apiError returns the error of a response the meta service didn't serve.
+func apiError(resp *http.Response) error {
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		return ErrUnauthenticated
+	case http.StatusForbidden:
+		return ErrNotAuthorized
+	}
+	b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	if msg := strings.TrimSpace(string(b)); msg != "" {
+		return fmt.Errorf("meta service returned %s: %s", resp.Status, msg)
+	}
+	return fmt.Errorf("meta service returned %s", resp.Status)
+}
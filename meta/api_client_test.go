@@ -0,0 +1,73 @@
+package meta_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// Ensure an APIClient given only a follower reaches the leader for the calls
+// that need it, and authenticates with its token.
+func TestAPIClient(t *testing.T) {
+	t.Parallel()
+
+	c := cloudMeta.NewTestClusterWithConfig(t, 3, func(i int, cfg *cloudMeta.Config) {
+		cfg.AuthToken = "s3cret"
+	})
+	defer c.Close()
+
+	leader := c.Leader(5 * time.Second)
+	var followers []*cloudMeta.Service
+	for _, s := range c.Services {
+		if s != leader {
+			followers = append(followers, s)
+		}
+	}
+
+	api := cloudMeta.NewAPIClient(cloudMeta.APIClientConfig{
+		Addrs:     []string{followers[0].HTTPAddr()},
+		AuthToken: "s3cret",
+	})
+	ctx := context.Background()
+
+	if addr, err := api.Leader(ctx); err != nil {
+		t.Fatal(err)
+	} else if addr != leader.HTTPAddr() {
+		t.Fatalf("unexpected leader: %s, expected %s", addr, leader.HTTPAddr())
+	}
+
+	nodes, err := api.Nodes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(nodes) != 3 {
+		t.Fatalf("unexpected nodes: %+v", nodes)
+	}
+
+	if err := api.CreateDatabase(ctx, "db0"); err != nil {
+		t.Fatal(err)
+	} else if err := api.CreateDatabase(ctx, "db0"); err != nil {
+		t.Fatalf("unexpected error creating the database again: %v", err)
+	}
+
+	if err := api.DropNode(ctx, followers[1].Node.ID); err != nil {
+		t.Fatal(err)
+	}
+	if nodes, err := api.Nodes(ctx); err != nil {
+		t.Fatal(err)
+	} else if len(nodes) != 2 {
+		t.Fatalf("node not dropped: %+v", nodes)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := api.Nodes(canceled); err != context.Canceled {
+		t.Fatalf("unexpected error with a canceled context: %v", err)
+	}
+
+	anonymous := cloudMeta.NewAPIClient(cloudMeta.APIClientConfig{Addrs: []string{leader.HTTPAddr()}})
+	if _, err := anonymous.Nodes(ctx); err != cloudMeta.ErrUnauthenticated {
+		t.Fatalf("unexpected error without a token: %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package meta
+
+import (
+	"sync"
+	"time"
+
+	"github.com/zhexuany/influxcloud/meta/internal"
+)
+
+// DefaultApplyErrorHistorySize is the number of raft apply errors kept for
+// /debug/apply-errors.
+const DefaultApplyErrorHistorySize = 100
+
+// applyError is a raft command that failed to apply to the state machine.
+type applyError struct {
+	Index   uint64    `json:"index"`
+	Command string    `json:"command"`
+	Reason  string    `json:"reason"`
+	Time    time.Time `json:"time"`
+}
+
+// applyErrorHistory keeps the most recent apply errors, which are otherwise
+// only seen by the client that proposed the command. The log is replayed on
+// restart, so errors of replayed commands are recorded again.
+type applyErrorHistory struct {
+	mu     sync.Mutex
+	size   int
+	errors []applyError
+
+	// counter counts every apply error by command type.
+	counter *Counter
+}
+
+func newApplyErrorHistory(size int, counter *Counter) *applyErrorHistory {
+	return &applyErrorHistory{size: size, counter: counter}
+}
+
+// add records that the command of type typ at index failed with err.
+func (h *applyErrorHistory) add(index uint64, typ internal.Command_Type, err error) {
+	h.counter.Inc(typ.String())
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.errors) >= h.size {
+		h.errors = h.errors[1:]
+	}
+	h.errors = append(h.errors, applyError{
+		Index:   index,
+		Command: typ.String(),
+		Reason:  err.Error(),
+		Time:    now(),
+	})
+}
+
+// list returns the recorded errors, oldest first.
+func (h *applyErrorHistory) list() []applyError {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]applyError(nil), h.errors...)
+}
@@ -0,0 +1,59 @@
+package meta_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// Ensure a command that fails to apply is recorded with its reason.
+func TestMetaService_ApplyErrors(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	if err := c.SetTopologyFrozen(true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreateMetaNode("baz:8091", "baz:8088"); err == nil {
+		t.Fatal("expected membership change to fail")
+	}
+
+	get := func(path string) []byte {
+		resp, err := http.Get("http://" + s.HTTPAddr() + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	var applyErrors []struct {
+		Index   uint64 `json:"index"`
+		Command string `json:"command"`
+		Reason  string `json:"reason"`
+	}
+	if err := json.Unmarshal(get("/debug/apply-errors"), &applyErrors); err != nil {
+		t.Fatal(err)
+	} else if len(applyErrors) != 1 {
+		t.Fatalf("unexpected apply errors: %+v", applyErrors)
+	} else if e := applyErrors[0]; e.Command != "CreateMetaNodeCommand" || e.Reason != cloudMeta.ErrTopologyFrozen.Error() || e.Index == 0 {
+		t.Fatalf("unexpected apply error: %+v", e)
+	}
+
+	if body := string(get("/metrics")); !strings.Contains(body, `influxcloud_meta_raft_apply_errors_total{command="CreateMetaNodeCommand"} 1`+"\n") {
+		t.Fatalf("apply error not counted:\n%s", body)
+	}
+}
@@ -0,0 +1,192 @@
+package meta
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/uber-go/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// authRealm is the realm of the WWW-Authenticate challenges of the HTTP API.
+const authRealm = "influxcloud-meta"
+
+// DefaultAuthExempt are the handlers served without authentication by
+// default, so health probes and metrics scrapers need no credentials.
+var DefaultAuthExempt = []string{"health", "ready", "metrics"}
+
+// principalKey is the request context key of the user a request
+// authenticated as with basic auth.
+type principalKey struct{}
+
+// authRequired returns whether requests to the HTTP API must authenticate.
+func (c *Config) authRequired() bool {
+	return c.AuthToken != "" || c.AuthBasic
+}
+
+// authExempt returns whether the handler name is served without
+// authentication.
+func (c *Config) authExempt(name string) bool {
+	for _, n := range c.AuthExempt {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticating answers 401 Unauthorized to requests to the handler name
+// that carry neither auth-token nor, with auth-basic set, the password of an
+// admin user, unless name is exempt. A request with a client certificate
+// verified against internal-ca is authenticated by it.
+func authenticating(inner http.Handler, name string, h *handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.config.authRequired() || h.config.authExempt(name) || requestPrincipal(r) != "" {
+			inner.ServeHTTP(w, r)
+			return
+		}
+
+		user, ok := h.authenticate(r)
+		if !ok {
+			h.logger.Info("request not authenticated", zap.String("handler", name), zap.String("remote", r.RemoteAddr))
+			if h.s != nil {
+				h.s.unauthenticated.Inc(name)
+			}
+			if h.config.AuthToken != "" {
+				w.Header().Add("WWW-Authenticate", `Bearer realm="`+authRealm+`"`)
+			}
+			if h.config.AuthBasic {
+				w.Header().Add("WWW-Authenticate", `Basic realm="`+authRealm+`"`)
+			}
+			http.Error(w, ErrUnauthenticated.Error(), http.StatusUnauthorized)
+			return
+		}
+		if user != "" {
+			r = r.WithContext(context.WithValue(r.Context(), principalKey{}, user))
+		}
+		inner.ServeHTTP(w, r)
+	})
+}
+
+// authenticate returns whether r carries auth-token as a bearer token or,
+// with auth-basic set, the name and password of an admin user, and the name
+// of that user.
+func (h *handler) authenticate(r *http.Request) (user string, ok bool) {
+	auth := r.Header.Get("Authorization")
+	if token := h.config.AuthToken; token != "" && strings.HasPrefix(auth, "Bearer ") {
+		given := strings.TrimPrefix(auth, "Bearer ")
+		return "", subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+	}
+
+	if h.config.AuthBasic {
+		if name, password, ok := r.BasicAuth(); ok && h.authenticateUser(name, password) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// authenticateUser returns whether password is the password of the admin
+// user name of the metadata.
+func (h *handler) authenticateUser(name, password string) bool {
+	hash, ok := h.store.adminHash(name)
+	if !ok {
+		return false
+	}
+
+	if h.passwords.valid(name, hash, password) {
+		return true
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return false
+	}
+	h.passwords.add(name, hash, password)
+	return true
+}
+
+// passwordCache remembers the passwords that matched the bcrypt hash of a
+// user as a salted SHA-256 sum, so that a client authenticating every
+// request doesn't cost a bcrypt comparison each time. An entry is only
+// valid for the hash it was checked against, so it lapses once the password
+// changes.
+type passwordCache struct {
+	mu    sync.Mutex
+	users map[string]cachedPassword
+}
+
+type cachedPassword struct {
+	hash string
+	salt []byte
+	sum  []byte
+}
+
+func newPasswordCache() *passwordCache {
+	return &passwordCache{users: make(map[string]cachedPassword)}
+}
+
+// valid returns whether password was added for user with hash.
+func (c *passwordCache) valid(user, hash, password string) bool {
+	c.mu.Lock()
+	p, ok := c.users[user]
+	c.mu.Unlock()
+	if !ok || p.hash != hash {
+		return false
+	}
+	return subtle.ConstantTimeCompare(saltedSum(p.salt, password), p.sum) == 1
+}
+
+// add records that password matched hash for user.
+func (c *passwordCache) add(user, hash, password string) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.users[user] = cachedPassword{hash: hash, salt: salt, sum: saltedSum(salt, password)}
+}
+
+func saltedSum(salt []byte, password string) []byte {
+	sum := sha256.Sum256(append(append([]byte(nil), salt...), password...))
+	return sum[:]
+}
+
+// authTransport sends the bearer token of auth-token with every request.
+type authTransport struct {
+	http.RoundTripper
+	token string
+}
+
+func (t *authTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", "Bearer "+t.token)
+	return t.RoundTripper.RoundTrip(r)
+}
+
+// CloseIdleConnections closes the idle connections of the transport it
+// wraps.
+func (t *authTransport) CloseIdleConnections() {
+	if c, ok := t.RoundTripper.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// withAuthToken returns a copy of client sending token as a bearer token,
+// or client itself if token is empty.
+func withAuthToken(client *http.Client, token string) *http.Client {
+	if token == "" {
+		return client
+	}
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	other := *client
+	other.Transport = &authTransport{RoundTripper: transport, token: token}
+	return &other
+}
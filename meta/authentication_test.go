@@ -0,0 +1,157 @@
+package meta_test
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+	"github.com/zhexuany/influxcloud/meta/internal"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Ensure the HTTP API answers 401 with a challenge to requests without
+// auth-token or the password of an admin user, except to the exempt
+// handlers, and that the meta client authenticates with auth-token.
+func TestMetaService_Authentication(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	cfg.AuthToken = "s3cret"
+	cfg.AuthBasic = true
+	s := openService(t, cfg)
+	defer s.Close()
+
+	ccfg := newConfig()
+	defer os.RemoveAll(ccfg.Dir)
+	ccfg.AuthToken = cfg.AuthToken
+	c := cloudMeta.NewClient(ccfg)
+	c.SetMetaServers([]string{s.HTTPAddr()})
+	if err := c.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, err := c.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, u := range []struct {
+		name  string
+		admin bool
+	}{{"admin", true}, {"reader", false}} {
+		hash, err := bcrypt.GenerateFromPassword([]byte("pass"), bcrypt.MinCost)
+		if err != nil {
+			t.Fatal(err)
+		}
+		typ := internal.Command_CreateUserCommand
+		cmd := &internal.Command{Type: &typ}
+		if err := proto.SetExtension(cmd, internal.E_CreateUserCommand_Command, &internal.CreateUserCommand{
+			Name:  proto.String(u.name),
+			Hash:  proto.String(string(hash)),
+			Admin: proto.Bool(u.admin),
+		}); err != nil {
+			t.Fatal(err)
+		}
+		body, err := proto.Marshal(cmd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest("POST", "http://"+s.HTTPAddr()+"/execute", bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer s3cret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("create user %s: %s", u.name, resp.Status)
+		}
+	}
+
+	get := func(path string, auth func(*http.Request)) *http.Response {
+		req, err := http.NewRequest("GET", "http://"+s.HTTPAddr()+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		auth(req)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+	none := func(*http.Request) {}
+	bearer := func(token string) func(*http.Request) {
+		return func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) }
+	}
+	basic := func(user, password string) func(*http.Request) {
+		return func(r *http.Request) { r.SetBasicAuth(user, password) }
+	}
+
+	resp := get("/peers", none)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("unexpected status without credentials: %s", resp.Status)
+	}
+	if v := resp.Header["Www-Authenticate"]; len(v) != 2 || v[0] != `Bearer realm="influxcloud-meta"` || v[1] != `Basic realm="influxcloud-meta"` {
+		t.Fatalf("unexpected challenges: %q", v)
+	}
+
+	for _, tt := range []struct {
+		desc string
+		auth func(*http.Request)
+		code int
+	}{
+		{"token", bearer("s3cret"), http.StatusOK},
+		{"wrong token", bearer("guess"), http.StatusUnauthorized},
+		{"admin", basic("admin", "pass"), http.StatusOK},
+		{"admin again", basic("admin", "pass"), http.StatusOK},
+		{"wrong password", basic("admin", "guess"), http.StatusUnauthorized},
+		{"non-admin", basic("reader", "pass"), http.StatusUnauthorized},
+		{"unknown user", basic("nobody", "pass"), http.StatusUnauthorized},
+	} {
+		if resp := get("/peers", tt.auth); resp.StatusCode != tt.code {
+			t.Fatalf("%s: unexpected status: %s", tt.desc, resp.Status)
+		}
+	}
+
+	// The health and metrics endpoints are exempt by default.
+	for _, path := range []string{"/health", "/metrics"} {
+		if resp := get(path, none); resp.StatusCode == http.StatusUnauthorized {
+			t.Fatalf("%s not exempt: %s", path, resp.Status)
+		}
+	}
+}
+
+// Ensure meta nodes sharing an auth-token form a cluster.
+func TestMetaService_Authentication_Cluster(t *testing.T) {
+	t.Parallel()
+
+	c := cloudMeta.NewTestClusterWithConfig(t, 3, func(i int, cfg *cloudMeta.Config) {
+		cfg.AuthToken = "s3cret"
+	})
+	defer c.Close()
+
+	if _, err := c.Client.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range c.Services {
+		resp, err := http.Get("http://" + s.HTTPAddr() + "/ping?all=true")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("unexpected status without credentials: %s", resp.Status)
+		}
+	}
+	if err := c.Client.Ping(true); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,188 @@
+package meta
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/uber-go/zap"
+	"github.com/zhexuany/influxcloud/meta/internal"
+)
+
+// The actions an Authorizer is asked to allow.
+const (
+	// ActionRead reads the metadata or the state of the cluster.
+	ActionRead = "read"
+
+	// ActionWrite changes the metadata, such as creating a database.
+	ActionWrite = "write"
+
+	// ActionManageNodes adds nodes to the cluster or removes them.
+	ActionManageNodes = "manage-nodes"
+
+	// ActionAdmin manages the background tasks of a node.
+	ActionAdmin = "admin"
+)
+
+// Authorizer decides which operations of the HTTP and gRPC APIs a
+// principal may carry out. The principal of a request is the common name
+// of the client certificate it presented, verified against internal-ca,
+// the user it authenticated as with auth-basic, or "" otherwise.
+type Authorizer interface {
+	// Authorize returns an error if principal may not take action on
+	// resource: the command type of a write, or the path of the request
+	// otherwise.
+	Authorize(principal, action, resource string) error
+}
+
+// allowAll is the Authorizer used when none is configured.
+type allowAll struct{}
+
+func (allowAll) Authorize(principal, action, resource string) error { return nil }
+
+// authorizer returns the Authorizer of the node, which allows everything if
+// none is set.
+func (c *Config) authorizer() Authorizer {
+	if c.Authorizer != nil {
+		return c.Authorizer
+	}
+	return allowAll{}
+}
+
+// RBACAuthorizer is an Authorizer allowing a principal the actions of the
+// roles it was granted, on any resource.
+type RBACAuthorizer struct {
+	mu     sync.RWMutex
+	roles  map[string]map[string]bool
+	grants map[string][]string
+}
+
+// NewRBACAuthorizer returns an RBACAuthorizer with no roles, which allows
+// nothing.
+func NewRBACAuthorizer() *RBACAuthorizer {
+	return &RBACAuthorizer{
+		roles:  make(map[string]map[string]bool),
+		grants: make(map[string][]string),
+	}
+}
+
+// AddRole defines the role name, allowing actions.
+func (a *RBACAuthorizer) AddRole(name string, actions ...string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	allowed := make(map[string]bool, len(actions))
+	for _, action := range actions {
+		allowed[action] = true
+	}
+	a.roles[name] = allowed
+}
+
+// Grant grants role to principal.
+func (a *RBACAuthorizer) Grant(principal, role string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.grants[principal] = append(a.grants[principal], role)
+}
+
+// Authorize returns ErrNotAuthorized unless a role granted to principal
+// allows action.
+func (a *RBACAuthorizer) Authorize(principal, action, resource string) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	for _, role := range a.grants[principal] {
+		if a.roles[role][action] {
+			return nil
+		}
+	}
+	return ErrNotAuthorized
+}
+
+// handlerActions holds the action of each POST handler. Every GET handler
+// reads, while the execute handler, with no action here, authorizes the
+// command it is sent itself. A POST handler missing from it is denied.
+var handlerActions = map[string]string{
+	"execute":          "",
+	"refresh":          ActionRead,
+	"join":             ActionManageNodes,
+	"drop-node":        ActionManageNodes,
+	"update-meta-node": ActionManageNodes,
+	"shard-sizes":      ActionWrite,
+	"heartbeat":        ActionWrite,
+	"pause-task":       ActionAdmin,
+	"resume-task":      ActionAdmin,
+	"cancel-task":      ActionAdmin,
+	"maintenance":      ActionManageNodes,
+}
+
+// nodeCommands holds the commands that change the members of the cluster.
+var nodeCommands = map[internal.Command_Type]bool{
+	internal.Command_RemovePeerCommand:        true,
+	internal.Command_CreateMetaNodeCommand:    true,
+	internal.Command_SetMetaNodeCommand:       true,
+	internal.Command_UpdateMetaNodeCommand:    true,
+	internal.Command_DeleteMetaNodeCommand:    true,
+	internal.Command_CreateDataNodeCommand:    true,
+	internal.Command_UpdateDataNodeCommand:    true,
+	internal.Command_DeleteDataNodeCommand:    true,
+	internal.Command_SetTopologyFrozenCommand: true,
+	internal.Command_SetDataCommand:           true,
+	internal.Command_BootstrapCommand:         true,
+}
+
+// commandAction returns the action of the command in b, and its type.
+func commandAction(b []byte) (action, resource string, err error) {
+	var cmd internal.Command
+	if err := proto.Unmarshal(b, &cmd); err != nil {
+		return "", "", fmt.Errorf("unable to unmarshal command: %s", err)
+	}
+	if nodeCommands[cmd.GetType()] {
+		return ActionManageNodes, cmd.GetType().String(), nil
+	}
+	return ActionWrite, cmd.GetType().String(), nil
+}
+
+// requestPrincipal returns the principal of r: the user it authenticated
+// as with basic auth, the common name of the verified client certificate it
+// came with, or "".
+func requestPrincipal(r *http.Request) string {
+	if user, ok := r.Context().Value(principalKey{}).(string); ok {
+		return user
+	}
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return ""
+	}
+	return r.TLS.VerifiedChains[0][0].Subject.CommonName
+}
+
+// authorize returns whether the principal of r may take action on
+// resource, replying 403 Forbidden if not.
+func (h *handler) authorize(w http.ResponseWriter, r *http.Request, action, resource string) bool {
+	principal := requestPrincipal(r)
+	if err := h.config.authorizer().Authorize(principal, action, resource); err != nil {
+		h.logger.Info("request denied", zap.String("principal", principal), zap.String("action", action),
+			zap.String("resource", resource), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// authorizing authorizes requests to the handler name before serving them.
+func authorizing(inner http.Handler, name string, h *handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		action, ok := handlerActions[name]
+		if r.Method == "GET" {
+			action, ok = ActionRead, true
+		}
+		if !ok {
+			h.logger.Info("request denied", zap.String("handler", name), zap.String("reason", "no action"))
+			http.Error(w, ErrNotAuthorized.Error(), http.StatusForbidden)
+			return
+		}
+		if action != "" && !h.authorize(w, r, action, r.URL.Path) {
+			return
+		}
+		inner.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,81 @@
+package meta
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zhexuany/influxcloud/meta/internal"
+	"google.golang.org/grpc"
+)
+
+// Ensure every method of the gRPC control API has the action it is
+// authorized for, as a method without one is denied.
+func TestControlMethods(t *testing.T) {
+	s := grpc.NewServer()
+	defer s.Stop()
+	internal.RegisterControlServer(s, &controlServer{})
+
+	for name, info := range s.GetServiceInfo() {
+		for _, m := range info.Methods {
+			if _, ok := controlMethods["/"+name+"/"+m.Name]; !ok {
+				t.Errorf("no action for /%s/%s", name, m.Name)
+			}
+		}
+	}
+}
+
+// Ensure every POST route of the HTTP API has the action it is authorized
+// for, as a route without one is denied.
+func TestHandlerActions(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "handler.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	ast.Inspect(f, func(n ast.Node) bool {
+		cc, ok := n.(*ast.CaseClause)
+		if !ok || len(cc.List) != 1 {
+			return true
+		} else if lit, ok := cc.List[0].(*ast.BasicLit); !ok || lit.Value != `"POST"` {
+			return true
+		}
+		ast.Inspect(cc, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			if sel, ok := call.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "WrapHandler" {
+				if lit, ok := call.Args[0].(*ast.BasicLit); ok {
+					names = append(names, strings.Trim(lit.Value, `"`))
+				}
+			}
+			return true
+		})
+		return false
+	})
+	if len(names) == 0 {
+		t.Fatal("no POST routes found")
+	}
+	for _, name := range names {
+		if _, ok := handlerActions[name]; !ok {
+			t.Errorf("no action for POST handler %s", name)
+		}
+	}
+
+	// A handler without one is denied.
+	c := NewConfig()
+	h := newHandler(c, NewService(c))
+	served := false
+	w := httptest.NewRecorder()
+	h.WrapHandler("unknown", func(w http.ResponseWriter, r *http.Request) { served = true }).
+		ServeHTTP(w, httptest.NewRequest("POST", "/unknown", nil))
+	if served || w.Code != http.StatusForbidden {
+		t.Fatalf("unknown handler served: %d", w.Code)
+	}
+}
@@ -0,0 +1,144 @@
+package meta
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/uber-go/zap"
+	"github.com/zhexuany/influxcloud/meta/internal"
+)
+
+// shardSizes holds the latest size of each shard, as reported by the data
+// nodes.
+type shardSizes struct {
+	mu    sync.RWMutex
+	sizes map[uint64]int64
+}
+
+func newShardSizes() *shardSizes {
+	return &shardSizes{sizes: make(map[uint64]int64)}
+}
+
+// update records sizes, keyed by shard ID.
+func (s *shardSizes) update(sizes map[uint64]int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, size := range sizes {
+		s.sizes[id] = size
+	}
+}
+
+// groupSize returns the average size of the reported shards of sg, and false
+// if none were reported.
+func (s *shardSizes) groupSize(sg *meta.ShardGroupInfo) (int64, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var total, n int64
+	for _, sh := range sg.Shards {
+		if size, ok := s.sizes[sh.ID]; ok {
+			total += size
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return total / n, true
+}
+
+// tuneShardGroupDuration returns the shard group duration that would have
+// made a group of the given duration and average shard size reach target,
+// truncated to the minute and clamped to [min, max].
+func tuneShardGroupDuration(groupDuration time.Duration, size, target int64, min, max time.Duration) time.Duration {
+	d := max
+	if size > 0 {
+		d = time.Duration(float64(groupDuration) * float64(target) / float64(size))
+		d -= d % time.Minute
+	}
+	if d < min {
+		return min
+	} else if d > max {
+		return max
+	}
+	return d
+}
+
+// tuneShardGroupDurations updates the shard group duration of every
+// retention policy in config.ShardGroupAutoTune from the most recently ended
+// shard group with reported sizes.
+func (s *Service) tuneShardGroupDurations() {
+	data, err := s.store.snapshot()
+	if err != nil {
+		s.Logger.Error("shard group auto-tune: snapshot failed", zap.Error(err))
+		return
+	}
+
+	for _, name := range s.config.ShardGroupAutoTune {
+		i := strings.Index(name, ".")
+		database, policy := name[:i], name[i+1:]
+
+		rp, err := data.RetentionPolicy(database, policy)
+		if err != nil || rp == nil {
+			continue
+		}
+		d, ok := s.tunedShardGroupDuration(rp)
+		if !ok || d == rp.ShardGroupDuration {
+			continue
+		}
+
+		s.Logger.Info("shard group auto-tune: changing shard group duration",
+			zap.String("retention-policy", name),
+			zap.Duration("from", rp.ShardGroupDuration),
+			zap.Duration("to", d))
+
+		t := internal.Command_UpdateRetentionPolicyCommand
+		cmd := &internal.Command{Type: &t}
+		if err := proto.SetExtension(cmd, internal.E_UpdateRetentionPolicyCommand_Command, &internal.UpdateRetentionPolicyCommand{
+			Database:           proto.String(database),
+			Name:               proto.String(policy),
+			ShardGroupDuration: proto.Int64(int64(d)),
+		}); err != nil {
+			panic(err)
+		}
+		b, err := proto.Marshal(cmd)
+		if err != nil {
+			panic(err)
+		}
+		if err := s.store.apply(b); err != nil {
+			s.Logger.Error("shard group auto-tune: update failed", zap.String("retention-policy", name), zap.Error(err))
+		}
+	}
+}
+
+// tunedShardGroupDuration returns the shard group duration rp should use, and
+// false if no ended shard group of rp has reported sizes yet.
+func (s *Service) tunedShardGroupDuration(rp *meta.RetentionPolicyInfo) (time.Duration, bool) {
+	var last *meta.ShardGroupInfo
+	var size int64
+	t := s.now()
+	for i := range rp.ShardGroups {
+		sg := &rp.ShardGroups[i]
+		if sg.Deleted() || sg.EndTime.After(t) || (last != nil && !sg.EndTime.After(last.EndTime)) {
+			continue
+		}
+		if n, ok := s.shardSizes.groupSize(sg); ok {
+			last, size = sg, n
+		}
+	}
+	if last == nil {
+		return 0, false
+	}
+
+	min, max := time.Duration(s.config.ShardGroupMinDuration), time.Duration(s.config.ShardGroupMaxDuration)
+	// The shard group duration can't exceed the retention period.
+	if rp.Duration > 0 && rp.Duration < max {
+		max = rp.Duration
+		if min > max {
+			min = max
+		}
+	}
+	return tuneShardGroupDuration(last.EndTime.Sub(last.StartTime), size, s.config.ShardGroupTargetSize, min, max), true
+}
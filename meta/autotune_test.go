@@ -0,0 +1,89 @@
+package meta_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/toml"
+)
+
+// Ensure auto-tuned retention policies move their shard group duration
+// toward the target shard size, within bounds, for new shard groups only.
+func TestMetaService_ShardGroupAutoTune(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	cfg.ShardGroupAutoTune = []string{"db0.rp0"}
+	cfg.ShardGroupTargetSize = 100
+	cfg.ShardGroupMinDuration = toml.Duration(time.Hour)
+	cfg.ShardGroupMaxDuration = toml.Duration(48 * time.Hour)
+	cfg.ShardGroupAutoTuneInterval = toml.Duration(10 * time.Millisecond)
+	s := openService(t, cfg)
+	defer s.Close()
+
+	c := newClient(s)
+	defer c.Close()
+
+	if _, err := c.CreateDataNode("foo:8180", "bar:8281"); err != nil {
+		t.Fatal(err)
+	}
+	day := 24 * time.Hour
+	if _, err := c.CreateDatabaseWithRetentionPolicy("db0", &meta.RetentionPolicySpec{
+		Name:               "rp0",
+		ShardGroupDuration: day,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	old, err := c.CreateShardGroup("db0", "rp0", time.Now().Add(-3*day))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reportSize := func(size int64) {
+		sizes := make(map[uint64]int64)
+		for _, sh := range old.Shards {
+			sizes[sh.ID] = size
+		}
+		if err := c.ReportShardSizes(sizes); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitForDuration := func(exp time.Duration) {
+		var rp *meta.RetentionPolicyInfo
+		for i := 0; i < 100; i++ {
+			if rp, err = c.RetentionPolicy("db0", "rp0"); err != nil {
+				t.Fatal(err)
+			} else if rp.ShardGroupDuration == exp {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("unexpected shard group duration: got %s, expected %s", rp.ShardGroupDuration, exp)
+	}
+
+	// Shards twice the target size halve the duration.
+	reportSize(200)
+	waitForDuration(12 * time.Hour)
+
+	sg, err := c.CreateShardGroup("db0", "rp0", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	} else if d := sg.EndTime.Sub(sg.StartTime); d != 12*time.Hour {
+		t.Fatalf("unexpected duration of the next shard group: %s", d)
+	}
+	groups, err := c.ShardGroupsByTimeRange("db0", "rp0", old.StartTime, old.StartTime)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(groups) != 1 || groups[0].ID != old.ID {
+		t.Fatalf("unexpected shard groups: %+v", groups)
+	} else if d := groups[0].EndTime.Sub(groups[0].StartTime); d != day {
+		t.Fatalf("existing shard group changed: %s", d)
+	}
+
+	// Far larger shards stop at the minimum duration.
+	reportSize(4800)
+	waitForDuration(time.Hour)
+}
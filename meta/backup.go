@@ -0,0 +1,152 @@
+package meta
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// backupMagic starts a backup of the meta store. It is followed by the raft
+// index and term the metadata was taken at, each a big endian uint64, the
+// SHA-256 of the encoded metadata, then the metadata itself.
+var backupMagic = []byte("ICMBACK1")
+
+// backupHeaderSize is the size of everything in a backup before the
+// metadata.
+const backupHeaderSize = 8 + 8 + 8 + sha256.Size
+
+// ErrBackupChecksum is returned when restoring a backup whose metadata
+// doesn't match the checksum or raft index it was written with.
+var ErrBackupChecksum = errors.New("backup checksum mismatch")
+
+// writeBackup writes data as a backup to w.
+func writeBackup(w io.Writer, data *Data) error {
+	p, err := data.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	var header [backupHeaderSize]byte
+	copy(header[:], backupMagic)
+	binary.BigEndian.PutUint64(header[8:], data.Data.Index)
+	binary.BigEndian.PutUint64(header[16:], data.Data.Term)
+	sum := sha256.Sum256(p)
+	copy(header[24:], sum[:])
+
+	bw := bufio.NewWriter(w)
+	if _, err := bw.Write(header[:]); err != nil {
+		return err
+	}
+	if _, err := bw.Write(p); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// readBackup reads a backup written by writeBackup. It returns
+// ErrBackupChecksum if the metadata doesn't match its checksum, or was
+// taken at another raft index than its header says.
+func readBackup(r io.Reader) (*Data, error) {
+	var header [backupHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, fmt.Errorf("read backup header: %s", err)
+	}
+	if !bytes.Equal(header[:8], backupMagic) {
+		return nil, errors.New("not a meta store backup")
+	}
+	index := binary.BigEndian.Uint64(header[8:])
+	term := binary.BigEndian.Uint64(header[16:])
+
+	p, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if sum := sha256.Sum256(p); !bytes.Equal(sum[:], header[24:]) {
+		return nil, ErrBackupChecksum
+	}
+
+	data := &Data{}
+	if err := data.UnmarshalBinary(p); err != nil {
+		return nil, err
+	}
+	if data.Data.Index != index || data.Data.Term != term {
+		return nil, ErrBackupChecksum
+	}
+	return data, nil
+}
+
+// Snapshot writes a backup of the meta store to w: its membership,
+// databases, retention policies and users, as applied at a single raft
+// index. The metadata is taken from the leader once it has applied
+// everything it committed, and the index and term it was taken at are
+// written in the header of the backup, for Restore to verify.
+func (c *Client) Snapshot(w io.Writer) error {
+	lastErr := ErrServiceUnavailable
+	for _, server := range c.MetaServers() {
+		host, index, err := c.refreshIndex(server)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		data, err := c.getSnapshot(host, 0)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if data.Data.Index < index {
+			lastErr = fmt.Errorf("snapshot from %s at index %d, expected %d", host, data.Data.Index, index)
+			continue
+		}
+		return writeBackup(w, data)
+	}
+	return lastErr
+}
+
+// Restore loads the backup written by Snapshot from r into the cluster,
+// which must be a fresh single node cluster: one meta node, and no data
+// nodes, databases or users. The backup's meta nodes are replaced by that
+// one, as it is the only raft peer; more meta nodes join it afterwards. A
+// backup that doesn't match its checksum or raft index is refused with
+// ErrBackupChecksum. The client must be open.
+func (c *Client) Restore(r io.Reader) error {
+	backup, err := readBackup(r)
+	if err != nil {
+		return err
+	}
+
+	lastErr := ErrServiceUnavailable
+	var current *Data
+	for _, server := range c.MetaServers() {
+		host, _, err := c.refreshIndex(server)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if current, err = c.getSnapshot(host, 0); err != nil {
+			lastErr = err
+			continue
+		}
+		break
+	}
+	if current == nil {
+		return lastErr
+	}
+	if len(current.MetaNodes) != 1 || len(current.DataNodes) != 0 || len(current.Data.Databases) != 0 || len(current.Data.Users) != 0 {
+		return errors.New("restore: the cluster must be a fresh single node cluster")
+	}
+
+	self := current.MetaNodes[0]
+	if backup.DataNode(self.ID) != nil {
+		return fmt.Errorf("restore: data node %d of the backup has the ID of meta node %d", self.ID, self.ID)
+	}
+	backup.MetaNodes = NodeInfos{self}
+	if backup.MaxNodeID < self.ID {
+		backup.MaxNodeID = self.ID
+	}
+	return c.SetData(backup)
+}
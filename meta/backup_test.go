@@ -0,0 +1,73 @@
+package meta_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/influxdata/influxdb/services/meta"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// Ensure a backup restores into a fresh single node cluster, keeping its
+// databases, retention policies and data nodes, and that a corrupt backup
+// is refused.
+func TestClient_SnapshotRestore(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	if _, err := c.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreateRetentionPolicy("db0", &meta.RetentionPolicySpec{Name: "rp0"}); err != nil {
+		t.Fatal(err)
+	}
+	dn, err := c.CreateDataNode("127.0.0.1:8086", "127.0.0.1:8088")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := c.Snapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	backup := buf.Bytes()
+
+	d2, s2, c2 := newServiceAndClient()
+	defer os.RemoveAll(d2)
+	defer s2.Close()
+	defer c2.Close()
+	fresh, err := c2.MetaNodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Flip a byte of the metadata.
+	corrupt := append([]byte(nil), backup...)
+	corrupt[len(corrupt)-1] ^= 0xff
+	if err := c2.Restore(bytes.NewReader(corrupt)); err != cloudMeta.ErrBackupChecksum {
+		t.Fatalf("unexpected error restoring a corrupt backup: %v", err)
+	}
+
+	if err := c2.Restore(bytes.NewReader(backup)); err != nil {
+		t.Fatal(err)
+	}
+	if db, err := c2.Database("db0"); err != nil || db == nil || db.RetentionPolicy("rp0") == nil {
+		t.Fatalf("database not restored: %+v, %v", db, err)
+	}
+	if nodes, err := c2.DataNodes(); err != nil || len(nodes) != 1 || nodes[0].ID != dn.ID || nodes[0].TCPHost != dn.TCPHost {
+		t.Fatalf("data nodes not restored: %+v, %v", nodes, err)
+	}
+	if nodes, err := c2.MetaNodes(); err != nil || len(nodes) != 1 || nodes[0].ID != fresh[0].ID || nodes[0].TCPHost != fresh[0].TCPHost {
+		t.Fatalf("meta nodes not replaced by the cluster's own: %+v, %v", nodes, err)
+	}
+
+	// A cluster that isn't fresh anymore refuses a restore.
+	if err := c2.Restore(bytes.NewReader(backup)); err == nil {
+		t.Fatal("expected restoring into a cluster with data to fail")
+	}
+}
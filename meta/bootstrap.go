@@ -0,0 +1,257 @@
+package meta
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/gogo/protobuf/proto"
+	"github.com/hashicorp/raft"
+	"github.com/influxdata/influxdb/services/meta"
+	itoml "github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/influxdb/uuid"
+	"github.com/zhexuany/influxcloud/meta/internal"
+)
+
+// bootstrapRetryInterval is how often a node holding bootstrap-file that
+// couldn't have it applied on open tries again.
+const bootstrapRetryInterval = time.Second
+
+// bootstrapTopology is the content of a bootstrap file. For example:
+//
+//	[[data-nodes]]
+//	  host = "data0:8086"
+//	  tcp-host = "data0:8088"
+//
+//	[[databases]]
+//	  name = "telegraf"
+//	  [[databases.retention-policies]]
+//	    name = "two_weeks"
+//	    duration = "336h"
+//	    replication = 2
+//
+// The first retention policy of a database becomes its default.
+type bootstrapTopology struct {
+	MetaNodes []bootstrapNode     `toml:"meta-nodes"`
+	DataNodes []bootstrapNode     `toml:"data-nodes"`
+	Databases []bootstrapDatabase `toml:"databases"`
+}
+
+type bootstrapNode struct {
+	Host    string `toml:"host"`
+	TCPHost string `toml:"tcp-host"`
+}
+
+type bootstrapDatabase struct {
+	Name              string                     `toml:"name"`
+	RetentionPolicies []bootstrapRetentionPolicy `toml:"retention-policies"`
+}
+
+type bootstrapRetentionPolicy struct {
+	Name               string         `toml:"name"`
+	Duration           itoml.Duration `toml:"duration"`
+	ShardGroupDuration itoml.Duration `toml:"shard-group-duration"`
+	ReplicaN           int            `toml:"replication"`
+}
+
+// loadBootstrapFile reads the topology described by the file at path.
+func loadBootstrapFile(path string) (*bootstrapTopology, error) {
+	var t bootstrapTopology
+	if _, err := toml.DecodeFile(path, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// commands returns the marshaled commands creating the topology, in order.
+func (t *bootstrapTopology) commands() ([][]byte, error) {
+	var a [][]byte
+	add := func(typ internal.Command_Type, desc *proto.ExtensionDesc, value interface{}) error {
+		cmd := &internal.Command{Type: &typ}
+		if err := proto.SetExtension(cmd, desc, value); err != nil {
+			return err
+		}
+		b, err := proto.Marshal(cmd)
+		if err != nil {
+			return err
+		}
+		a = append(a, b)
+		return nil
+	}
+
+	startedAt := now().UnixNano()
+	for _, n := range t.MetaNodes {
+		if err := add(internal.Command_CreateMetaNodeCommand, internal.E_CreateMetaNodeCommand_Command, &internal.CreateMetaNodeCommand{
+			HTTPAddr:        proto.String(n.Host),
+			TCPAddr:         proto.String(n.TCPHost),
+			Rand:            proto.Uint64(uint64(rand.Int63())),
+			StartedAt:       proto.Int64(startedAt),
+			MetadataVersion: proto.Uint64(MetadataVersion),
+		}); err != nil {
+			return nil, err
+		}
+	}
+	for _, n := range t.DataNodes {
+		if err := add(internal.Command_CreateDataNodeCommand, internal.E_CreateDataNodeCommand_Command, &internal.CreateDataNodeCommand{
+			HTTPAddr: proto.String(n.Host),
+			TCPAddr:  proto.String(n.TCPHost),
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, db := range t.Databases {
+		if db.Name == "" {
+			return nil, ErrDatabaseNameRequired
+		}
+
+		cmd := &internal.CreateDatabaseCommand{Name: proto.String(db.Name)}
+		for i, rp := range db.RetentionPolicies {
+			if rp.Name == "" {
+				return nil, fmt.Errorf("database %s: %s", db.Name, ErrRetentionPolicyNameRequired)
+			}
+			b, err := rp.info().MarshalBinary()
+			if err != nil {
+				return nil, err
+			}
+
+			// The first policy is created with the database as its default.
+			if i == 0 {
+				cmd.RetentionPolicy = b
+				if err := add(internal.Command_CreateDatabaseCommand, internal.E_CreateDatabaseCommand_Command, cmd); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if err := add(internal.Command_CreateRetentionPolicyCommand, internal.E_CreateRetentionPolicyCommand_Command, &internal.CreateRetentionPolicyCommand{
+				Database:        proto.String(db.Name),
+				RetentionPolicy: b,
+			}); err != nil {
+				return nil, err
+			}
+		}
+
+		if len(db.RetentionPolicies) == 0 {
+			if err := add(internal.Command_CreateDatabaseCommand, internal.E_CreateDatabaseCommand_Command, cmd); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return a, nil
+}
+
+// info returns the retention policy described by rp.
+func (rp bootstrapRetentionPolicy) info() *meta.RetentionPolicyInfo {
+	spec := &meta.RetentionPolicySpec{
+		Name:               rp.Name,
+		ShardGroupDuration: time.Duration(rp.ShardGroupDuration),
+	}
+	if rp.Duration != 0 {
+		d := time.Duration(rp.Duration)
+		spec.Duration = &d
+	}
+	if rp.ReplicaN != 0 {
+		spec.ReplicaN = &rp.ReplicaN
+	}
+	return spec.NewRetentionPolicyInfo()
+}
+
+// bootstrap applies the configured bootstrap file as a single raft command
+// unless the cluster has already been bootstrapped. Only the leader can
+// apply it, so a node that doesn't lead keeps sending it to the leader in
+// the background, every bootstrapRetryInterval, until the cluster is
+// bootstrapped.
+func (s *store) bootstrap() error {
+	if s.config.BootstrapFile == "" {
+		return nil
+	} else if s.bootstrapped() {
+		s.logger.Printf("cluster already bootstrapped, ignoring %s", s.config.BootstrapFile)
+		return nil
+	}
+
+	t, err := loadBootstrapFile(s.config.BootstrapFile)
+	if err != nil {
+		return err
+	}
+	cmds, err := t.commands()
+	if err != nil {
+		return err
+	}
+	cmd := &internal.BootstrapCommand{Commands: cmds}
+
+	s.logger.Printf("bootstrapping cluster from %s", s.config.BootstrapFile)
+	if s.isLeader() {
+		if err := s.applyBootstrap(cmd); err != raft.ErrNotLeader {
+			return err
+		}
+	}
+	go s.retryBootstrap(cmd)
+	return nil
+}
+
+// retryBootstrap applies cmd every bootstrapRetryInterval until the cluster
+// is bootstrapped or the store is closed.
+func (s *store) retryBootstrap(cmd *internal.BootstrapCommand) {
+	ticker := time.NewTicker(bootstrapRetryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.closing:
+			return
+		}
+
+		if s.bootstrapped() {
+			return
+		}
+		err := s.applyBootstrap(cmd)
+		if err == nil {
+			s.logger.Printf("bootstrapped cluster from %s", s.config.BootstrapFile)
+			return
+		} else if _, ok := err.(errCommand); ok {
+			s.logger.Printf("leader refused bootstrapping the cluster from %s: %s", s.config.BootstrapFile, err)
+			return
+		}
+		s.logger.Printf("bootstrapping cluster from %s failed, retrying: %s", s.config.BootstrapFile, err)
+	}
+}
+
+// applyBootstrap applies cmd if the node is the leader, or else sends it to
+// the leader.
+func (s *store) applyBootstrap(cmd *internal.BootstrapCommand) error {
+	if s.isLeader() {
+		typ := internal.Command_BootstrapCommand
+		c := &internal.Command{Type: &typ}
+		if err := proto.SetExtension(c, internal.E_BootstrapCommand_Command, cmd); err != nil {
+			return err
+		}
+		b, err := proto.Marshal(c)
+		if err != nil {
+			return err
+		}
+		return s.apply(b)
+	}
+
+	leader := s.leaderHTTP()
+	if leader == "" {
+		return errNoLeader
+	}
+	c := NewClient(s.config)
+	c.SetTLS(s.config.HTTPSEnabled)
+	defer c.Close()
+	scheme := "http://"
+	if s.config.HTTPSEnabled {
+		scheme = "https://"
+	}
+	_, err := c.exec(scheme+leader+"/execute", uuid.TimeUUID().String(),
+		internal.Command_BootstrapCommand, internal.E_BootstrapCommand_Command, cmd)
+	return err
+}
+
+// bootstrapped returns whether the cluster has applied a bootstrap file.
+func (s *store) bootstrapped() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.data.Bootstrapped
+}
@@ -0,0 +1,51 @@
+package meta
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Ensure a follower holding the bootstrap file has the leader apply it.
+func TestStore_Bootstrap_Follower(t *testing.T) {
+	t.Parallel()
+
+	c := NewTestCluster(t, 3)
+	defer c.Close()
+
+	leader := c.Leader(5 * time.Second)
+	var follower *Service
+	for _, s := range c.Services {
+		if s != leader {
+			follower = s
+			break
+		}
+	}
+
+	path := filepath.Join(c.Configs[0].Dir, "bootstrap.toml")
+	if err := ioutil.WriteFile(path, []byte(`
+[[databases]]
+  name = "db0"
+`), 0666); err != nil {
+		t.Fatal(err)
+	}
+	follower.store.config.BootstrapFile = path
+	if err := follower.store.bootstrap(); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for !leader.store.bootstrapped() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the bootstrap file to be applied")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	leader.store.mu.RLock()
+	db := leader.store.data.Database("db0")
+	leader.store.mu.RUnlock()
+	if db == nil {
+		t.Fatal("expected database db0")
+	}
+}
@@ -0,0 +1,58 @@
+package meta
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"time"
+)
+
+// BuildInfo describes the build of the server a meta node runs.
+type BuildInfo struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit"`
+	Branch  string `json:"branch"`
+	Tags    string `json:"tags"`
+}
+
+// SetBuildInfo sets the build the service reports on /debug/version. Its
+// version is also the one set in the response headers.
+func (s *Service) SetBuildInfo(info BuildInfo) {
+	s.buildInfo = info
+	s.version = info.Version
+}
+
+// versionJSON is the response of /debug/version.
+type versionJSON struct {
+	BuildInfo
+	MetadataVersion uint64    `json:"metadataVersion"`
+	GoVersion       string    `json:"goVersion"`
+	OS              string    `json:"os"`
+	Arch            string    `json:"arch"`
+	StartTime       time.Time `json:"startTime"`
+	Uptime          string    `json:"uptime"`
+}
+
+// serveVersion returns the build the node runs, the metadata version it
+// reads and writes, the Go runtime it was built with and how long the
+// service has been up, to confirm every node of the cluster runs the same
+// build, as before raising the metadata version.
+func (h *handler) serveVersion(w http.ResponseWriter, r *http.Request) {
+	v := versionJSON{
+		BuildInfo:       h.s.buildInfo,
+		MetadataVersion: h.s.buildInfo.MetadataVersion(),
+		GoVersion:       runtime.Version(),
+		OS:              runtime.GOOS,
+		Arch:            runtime.GOARCH,
+		StartTime:       h.s.startedAt,
+		Uptime:          now().Sub(h.s.startedAt).Truncate(time.Second).String(),
+	}
+	if v.Version == "" {
+		v.Version = h.s.Version()
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.httpError(err, w, http.StatusInternalServerError)
+	}
+}
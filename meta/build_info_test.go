@@ -0,0 +1,54 @@
+package meta_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"testing"
+	"time"
+
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// Ensure /debug/version returns the build the node runs and every response
+// carries its version.
+func TestMetaService_Version(t *testing.T) {
+	t.Parallel()
+
+	s := newService(newConfig())
+	s.SetBuildInfo(cloudMeta.BuildInfo{Version: "1.2.3", Commit: "abc123", Branch: "master"})
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	resp, err := http.Get("http://" + s.HTTPAddr() + "/debug/version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if v := resp.Header.Get("X-Influxcloud-Version"); v != "1.2.3" {
+		t.Fatalf("unexpected version header: %q", v)
+	}
+
+	var v struct {
+		Version   string    `json:"version"`
+		Commit    string    `json:"commit"`
+		Branch    string    `json:"branch"`
+		GoVersion string    `json:"goVersion"`
+		OS        string    `json:"os"`
+		Arch      string    `json:"arch"`
+		StartTime time.Time `json:"startTime"`
+		Uptime    string    `json:"uptime"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Version != "1.2.3" || v.Commit != "abc123" || v.Branch != "master" {
+		t.Fatalf("unexpected build info: %+v", v)
+	} else if v.GoVersion != runtime.Version() || v.OS != runtime.GOOS || v.Arch != runtime.GOARCH {
+		t.Fatalf("unexpected runtime: %+v", v)
+	} else if v.StartTime.IsZero() || v.Uptime == "" {
+		t.Fatalf("start time or uptime missing: %+v", v)
+	}
+}
@@ -0,0 +1,117 @@
+package meta
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CanonicalJSON encodes data as indented JSON that only depends on its
+// content: object keys are sorted at every level, numbers are written
+// exactly as encoded, never through a float, and keys that are null or an
+// empty list or object are left out, since a snapshot read back from disk
+// does not tell an empty list from a missing one. Two exports of the same
+// state are byte for byte identical, so they can be kept in git and diffed.
+func (data *Data) CanonicalJSON() ([]byte, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := writeCanonicalJSON(&buf, v, 0); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// writeCanonicalJSON writes v, as decoded with UseNumber, at the given
+// indentation depth.
+func writeCanonicalJSON(buf *bytes.Buffer, v interface{}, depth int) error {
+	indent := func(depth int) {
+		buf.WriteByte('\n')
+		buf.WriteString(strings.Repeat("  ", depth))
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k, e := range v {
+			if !emptyJSON(e) {
+				keys = append(keys, k)
+			}
+		}
+		if len(keys) == 0 {
+			buf.WriteString("{}")
+			return nil
+		}
+		sort.Strings(keys)
+
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			indent(depth + 1)
+			kb, _ := json.Marshal(k)
+			buf.Write(kb)
+			buf.WriteString(": ")
+			if err := writeCanonicalJSON(buf, v[k], depth+1); err != nil {
+				return err
+			}
+		}
+		indent(depth)
+		buf.WriteByte('}')
+	case []interface{}:
+		if len(v) == 0 {
+			buf.WriteString("[]")
+			return nil
+		}
+		buf.WriteByte('[')
+		for i, e := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			indent(depth + 1)
+			if err := writeCanonicalJSON(buf, e, depth+1); err != nil {
+				return err
+			}
+		}
+		indent(depth)
+		buf.WriteByte(']')
+	case json.Number:
+		buf.WriteString(v.String())
+	case string, bool, nil:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+	default:
+		return fmt.Errorf("unexpected JSON value %T", v)
+	}
+	return nil
+}
+
+// emptyJSON returns whether v is null or an empty list or object.
+func emptyJSON(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
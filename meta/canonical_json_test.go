@@ -0,0 +1,75 @@
+package meta_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// Ensure two exports of identical metadata are byte for byte identical.
+func TestData_CanonicalJSON(t *testing.T) {
+	build := func() *cloudMeta.Data {
+		data := &cloudMeta.Data{Data: &meta.Data{Index: 1}}
+		if err := data.CreateDataNode("data0:8086", "data0:8088"); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"db0", "db1", "db2"} {
+			if err := data.CreateDatabase(name); err != nil {
+				t.Fatal(err)
+			}
+			rpi := meta.NewRetentionPolicyInfo("rp0")
+			rpi.Duration = 24 * time.Hour
+			if err := data.CreateRetentionPolicy(name, rpi, true); err != nil {
+				t.Fatal(err)
+			}
+			if err := data.CreateShardGroup(name, "rp0", time.Unix(0, 0).UTC()); err != nil {
+				t.Fatal(err)
+			}
+			data.SetShardGroupQuota(name, 1<<63+1)
+			for _, key := range []string{"owner", "env", "team"} {
+				if err := data.SetDatabaseAnnotation(name, key, name+"-"+key); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		return data
+	}
+
+	a, err := build().CanonicalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		b, err := build().CanonicalJSON()
+		if err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(a, b) {
+			t.Fatalf("exports differ:\n%s\n%s", a, b)
+		}
+	}
+
+	// The same metadata read back from a snapshot exports identically.
+	data := build()
+	buf, err := data.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := &cloudMeta.Data{}
+	if err := other.UnmarshalBinary(buf); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := other.CanonicalJSON(); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(a, b) {
+		t.Fatalf("export of snapshot differs:\n%s\n%s", a, b)
+	}
+
+	// Integers too large for a float64 are kept exactly.
+	if !strings.Contains(string(a), `"db0": 9223372036854775809`) {
+		t.Fatalf("quota not exported exactly:\n%s", a)
+	}
+}
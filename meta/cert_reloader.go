@@ -0,0 +1,132 @@
+package meta
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// certReloader serves the certificate of a SecretProvider to TLS
+// handshakes, and reloads it when the provider returns a new one.
+// Connections already set up keep the certificate they were made with.
+type certReloader struct {
+	// loadMu serializes loads, so a reload can't restore a replaced
+	// provider.
+	loadMu   sync.Mutex
+	provider SecretProvider
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+// newCertReloader returns a reloader serving the certificate of provider,
+// or an error if it can't be loaded.
+func newCertReloader(provider SecretProvider) (*certReloader, error) {
+	r := &certReloader{provider: provider}
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate returns the certificate last loaded, for tls.Config.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// reload loads the certificate again, and returns whether it changed since
+// it was last loaded. If it can't be loaded, as when its file is still
+// being written, the current certificate is kept and the next reload tries
+// again.
+func (r *certReloader) reload() (bool, error) {
+	r.loadMu.Lock()
+	defer r.loadMu.Unlock()
+	return r.load(r.provider)
+}
+
+// setProvider loads the certificate of provider, returns whether it changed,
+// and reloads from provider from then on. If it can't be loaded the current
+// provider and certificate stay in use.
+func (r *certReloader) setProvider(provider SecretProvider) (bool, error) {
+	r.loadMu.Lock()
+	defer r.loadMu.Unlock()
+	changed, err := r.load(provider)
+	if err != nil {
+		return false, err
+	}
+	r.provider = provider
+	return changed, nil
+}
+
+// load loads the certificate of provider, and returns whether it changed.
+func (r *certReloader) load(provider SecretProvider) (bool, error) {
+	cert, err := provider.GetCertificate()
+	if err != nil {
+		return false, err
+	} else if cert == nil || len(cert.Certificate) == 0 {
+		return false, errors.New("no certificate")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cert != nil && bytes.Equal(r.cert.Certificate[0], cert.Certificate[0]) {
+		return false, nil
+	}
+	r.cert = cert
+	return true, nil
+}
+
+// caReloader holds the certificate authorities of a SecretProvider, and
+// reloads them when the provider returns new ones. Connections are verified
+// against the authorities last loaded when they handshake.
+type caReloader struct {
+	provider SecretProvider
+
+	mu   sync.RWMutex
+	pem  []byte
+	pool *x509.CertPool
+}
+
+// newCAReloader returns a reloader holding the certificate authorities of
+// provider, or an error if they can't be loaded.
+func newCAReloader(provider SecretProvider) (*caReloader, error) {
+	r := &caReloader{provider: provider}
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Pool returns the certificate authorities last loaded.
+func (r *caReloader) Pool() *x509.CertPool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.pool
+}
+
+// reload loads the certificate authorities again, and returns whether they
+// changed since they were last loaded. If they can't be loaded the current
+// ones are kept.
+func (r *caReloader) reload() (bool, error) {
+	buf, err := r.provider.GetCA()
+	if err != nil {
+		return false, fmt.Errorf("internal-ca: %s", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pool != nil && bytes.Equal(r.pem, buf) {
+		return false, nil
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(buf) {
+		return false, errors.New("internal-ca: no certificates found")
+	}
+	r.pem, r.pool = buf, pool
+	return true, nil
+}
@@ -0,0 +1,63 @@
+package meta
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// Checksum returns a checksum of data's canonical JSON encoding. The raft
+// index and term and the node start times are left out, so nodes that agree
+// on the schema and topology report the same checksum.
+func (data *Data) Checksum() (string, error) {
+	other := data.Clone()
+	other.Data.Index, other.Data.Term = 0, 0
+	for i := range other.MetaNodes {
+		other.MetaNodes[i].StartedAt = time.Time{}
+	}
+	for i := range other.DataNodes {
+		other.DataNodes[i].StartedAt = time.Time{}
+	}
+
+	// encoding/json sorts map keys, which keeps user privileges stable.
+	b, err := json.Marshal(other)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// StateChecksum is the checksum of a meta server's state.
+type StateChecksum struct {
+	// Index is the raft index the checksum was taken at.
+	Index    uint64 `json:"index"`
+	Checksum string `json:"checksum"`
+}
+
+// DivergedServers returns the servers whose checksum differs from the one
+// reported by most servers, sorted. Ties go to the lowest checksum so the
+// result doesn't depend on map order.
+func DivergedServers(sums map[string]StateChecksum) []string {
+	counts := make(map[string]int)
+	for _, s := range sums {
+		counts[s.Checksum]++
+	}
+	var majority string
+	for sum, n := range counts {
+		if n > counts[majority] || (n == counts[majority] && sum < majority) {
+			majority = sum
+		}
+	}
+
+	var diverged []string
+	for server, s := range sums {
+		if s.Checksum != majority {
+			diverged = append(diverged, server)
+		}
+	}
+	sort.Strings(diverged)
+	return diverged
+}
@@ -0,0 +1,89 @@
+package meta
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+// Ensure healthy nodes report the same checksum and a node whose state
+// diverged from the others is flagged.
+func TestClient_StateChecksum(t *testing.T) {
+	c := NewTestCluster(t, 3)
+	defer c.Close()
+
+	if _, err := c.Client.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+
+	sums, err := c.Client.StateChecksum()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(sums) != len(c.Services) {
+		t.Fatalf("unexpected number of checksums: %v", sums)
+	}
+	for server, sum := range sums {
+		if sum.Checksum == "" {
+			t.Fatalf("missing checksum from %s", server)
+		}
+	}
+	if diverged := DivergedServers(sums); len(diverged) != 0 {
+		t.Fatalf("healthy nodes diverged: %v", diverged)
+	}
+
+	// Change one follower's state without going through raft.
+	var follower *Service
+	for _, s := range c.Services {
+		if !s.store.isLeader() {
+			follower = s
+			break
+		}
+	}
+	follower.store.mu.Lock()
+	err = follower.store.data.CreateDatabase("rogue")
+	follower.store.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sums, err = c.Client.StateChecksum()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := DivergedServers(sums), []string{follower.HTTPAddr()}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected diverged nodes: got %v, expected %v", got, exp)
+	}
+}
+
+// Ensure the checksum ignores the raft index and node start times.
+func TestData_Checksum_IgnoresVolatileFields(t *testing.T) {
+	a := &Data{Data: &meta.Data{}}
+	if err := a.CreateMetaNode("meta0:8091", "meta0:8089"); err != nil {
+		t.Fatal(err)
+	}
+	b := a.Clone()
+	b.Data.Index, b.Data.Term = 10, 2
+	b.MetaNodes[0].StartedAt = time.Unix(100, 0)
+
+	sa, err := a.Checksum()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sb, err := b.Checksum()
+	if err != nil {
+		t.Fatal(err)
+	} else if sa != sb {
+		t.Fatal("checksum changed with volatile fields")
+	}
+
+	if err := b.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+	if sc, err := b.Checksum(); err != nil {
+		t.Fatal(err)
+	} else if sc == sa {
+		t.Fatal("checksum unchanged after creating a database")
+	}
+}
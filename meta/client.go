@@ -2,6 +2,8 @@ package meta
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,18 +12,24 @@ import (
 	"log"
 	"math"
 	"math/rand"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/influxql"
 	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/uuid"
+	"github.com/zhexuany/influxcloud"
 	"github.com/zhexuany/influxcloud/meta/internal"
 
 	"github.com/gogo/protobuf/proto"
+	"github.com/hashicorp/raft"
 	"path/filepath"
 )
 
@@ -34,6 +42,10 @@ const (
 	// a failure to the caller
 	maxRetries = 10
 
+	// leaderPollInterval is how often WaitForLeader asks the meta servers
+	// for the leader.
+	leaderPollInterval = 100 * time.Millisecond
+
 	metaFile = "meta.db"
 )
 
@@ -43,6 +55,14 @@ var (
 
 	// ErrService is returned when the meta service returns an error.
 	ErrService = errors.New("meta service error")
+
+	// ErrNoMetaServers is returned when opening a client without meta
+	// servers.
+	ErrNoMetaServers = errors.New("no meta servers configured")
+
+	// errNoLeader is returned by exec when the server knows of no leader
+	// to take the command.
+	errNoLeader = errors.New("meta service has no leader")
 )
 
 // Client is used to execute commands on and read data from
@@ -55,9 +75,19 @@ type Client struct {
 	closing   chan struct{}
 	cacheData *Data
 
+	// subscriptions are the subscribers to the changes of cacheData.
+	subscriptions map[*subscription]struct{}
+
 	HTTPClient  *http.Client
 	metaServers []string
 
+	// discovered holds the meta servers each dns+srv peer last resolved to.
+	discovered map[string][]string
+
+	// leader is the HTTP address of the meta leader the client last
+	// learned of.
+	leader string
+
 	path string
 
 	retentionAutoCreate bool
@@ -66,24 +96,67 @@ type Client struct {
 
 	nodeID uint64
 
+	// metadataVersion is the metadata version of the build the node runs.
+	metadataVersion uint64
+
 	config *Config
 }
 
 // NewClient returns a new *Client.
 func NewClient(config *Config) *Client {
-	return &Client{
+	transport := newHTTPTransport(time.Duration(config.ClientMaxIdleTime))
+	c := &Client{
 		changed:             make(chan struct{}),
 		closing:             make(chan struct{}),
 		cacheData:           &Data{},
-		logger:              log.New(os.Stderr, "[metaclient] ", log.LstdFlags),
+		discovered:          make(map[string][]string),
+		logger:              NewLogger(os.Stderr, config.LogFormat, "[metaclient] ", "metaclient", 0),
 		path:                config.Dir,
 		retentionAutoCreate: config.RetentionAutoCreate,
 		config:              config,
+		HTTPClient:          &http.Client{Transport: transport},
+	}
+	if config.InternalCert != "" {
+		transport.TLSClientConfig = &tls.Config{GetClientCertificate: config.internalClientCertificate}
+		if pool, err := config.internalRootCAs(); err != nil {
+			c.logger.Printf("not trusting internal-ca for HTTPS: %s", err)
+		} else {
+			transport.TLSClientConfig.RootCAs = pool
+		}
+	}
+	return c
+}
+
+// newHTTPTransport returns a transport whose pooled connections are closed
+// once they have been idle for maxIdle, so a middlebox silently dropping a
+// long-idle connection can't fail the next request. TCP keep-alives are sent
+// well within that window. Meta servers given as unix:// addresses are
+// dialed on their Unix domain sockets.
+func newHTTPTransport(maxIdle time.Duration) *http.Transport {
+	keepAlive := 30 * time.Second
+	if maxIdle > 0 && maxIdle/2 < keepAlive {
+		keepAlive = maxIdle / 2
+	}
+
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: dialUnixSocket((&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: keepAlive,
+		}).DialContext),
+		IdleConnTimeout:     maxIdle,
+		TLSHandshakeTimeout: 10 * time.Second,
 	}
 }
 
 // Open a connection to a meta service cluster.
 func (c *Client) Open() error {
+	return c.OpenContext(context.Background())
+}
+
+// OpenContext is like Open, but gives up getting the metadata from the meta
+// servers once ctx is done.
+func (c *Client) OpenContext(ctx context.Context) error {
 	if c.closed() {
 		return ErrServiceUnavailable
 	}
@@ -115,7 +188,11 @@ func (c *Client) Open() error {
 
 	c.changed = make(chan struct{})
 	c.closing = make(chan struct{})
-	c.cacheData = c.retryUntilSnapshot(0)
+	data, err := c.openSnapshot(ctx)
+	if err != nil {
+		return err
+	}
+	c.cacheData = data
 
 	go c.pollForUpdates()
 
@@ -124,15 +201,17 @@ func (c *Client) Open() error {
 
 // Close the meta service cluster connection.
 func (c *Client) Close() error {
-	if !c.closed() {
+	if c.closed() {
 		return ErrService
 	}
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if t, ok := http.DefaultTransport.(*http.Transport); ok {
-		t.CloseIdleConnections()
+	if c.HTTPClient != nil {
+		if t, ok := c.HTTPClient.Transport.(*http.Transport); ok {
+			t.CloseIdleConnections()
+		}
 	}
 
 	select {
@@ -141,10 +220,26 @@ func (c *Client) Close() error {
 	default:
 		close(c.closing)
 	}
+	c.closeSubscriptions()
 
 	return nil
 }
 
+// sleep waits for d, returning ErrService if the client is closed or ctx's
+// error if it is done first.
+func (c *Client) sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-c.closing:
+		return ErrService
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func (c *Client) closed() bool {
 	select {
 	case <-c.closing:
@@ -238,6 +333,24 @@ func (c *Client) SetHTTPClient(httpClient *http.Client) {
 	c.mu.Unlock()
 }
 
+// httpClient returns the client used to talk to the meta servers, sending
+// auth-token with every request if it is set.
+func (c *Client) httpClient() *http.Client {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return withAuthToken(client, c.config.AuthToken)
+}
+
+// CloseIdleConnections closes the idle connections to the meta servers, so
+// the next requests set up new ones, as after a certificate change.
+func (c *Client) CloseIdleConnections() {
+	c.httpClient().CloseIdleConnections()
+}
+
 // Ping will hit the ping endpoint for the metaservice and return nil if
 // it returns 200. If checkAllMetaServers is set to true, it will hit the
 // ping endpoint and tell it to verify the health of all metaservers in the
@@ -251,10 +364,11 @@ func (c *Client) Ping(checkAllMetaServers bool) error {
 		url = url + "?all=true"
 	}
 
-	resp, err := http.Get(url)
+	resp, err := c.httpClient().Get(url)
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusOK {
 		return nil
@@ -294,10 +408,11 @@ func (c *Client) acquireLease(name string) (*meta.Lease, error) {
 	for _, server := range servers {
 		url := fmt.Sprintf("%s/lease?name=%s&nodeid=%d", c.url(server), name, c.nodeID)
 
-		resp, err := http.Get(url)
+		resp, err := c.httpClient().Get(url)
 		if err != nil {
 			return nil, err
 		}
+		defer resp.Body.Close()
 
 		switch resp.StatusCode {
 		case http.StatusOK:
@@ -359,11 +474,14 @@ func (c *Client) DataNodes() (NodeInfos, error) {
 	return c.data().DataNodes, nil
 }
 
-// CreateDataNode will create a new data node in the metastore
+// CreateDataNode will create a new data node in the metastore, or register
+// the restart of an existing one, recording when it started by the clock of
+// the leader. The client's time is only sent for leaders too old to stamp it.
 func (c *Client) CreateDataNode(httpAddr, tcpAddr string) (*NodeInfo, error) {
 	cmd := &internal.CreateDataNodeCommand{
-		HTTPAddr: proto.String(httpAddr),
-		TCPAddr:  proto.String(tcpAddr),
+		HTTPAddr:  proto.String(httpAddr),
+		TCPAddr:   proto.String(tcpAddr),
+		StartedAt: proto.Int64(time.Now().UTC().UnixNano()),
 	}
 
 	if err := c.retryUntilExec(internal.Command_CreateDataNodeCommand, internal.E_CreateDataNodeCommand_Command, cmd); err != nil {
@@ -380,15 +498,13 @@ func (c *Client) CreateDataNode(httpAddr, tcpAddr string) (*NodeInfo, error) {
 	return n, nil
 }
 
-// ShardPendingOwners returns an array of all pending ShardOwners.
+// ShardPendingOwners returns the IDs of the shards this data node is a
+// pending owner of, which it is to copy and then commit its ownership of
+// with CommitPendingShardOwner.
 func (c *Client) ShardPendingOwners() uint64arr {
-	mns := c.data().MetaNodes
-	for _, meta := range mns {
-		if meta.ID == c.nodeID {
-			return meta.PendingShardOwners
-		}
+	if n := c.data().DataNode(c.nodeID); n != nil {
+		return n.PendingShardOwners
 	}
-
 	return uint64arr{}
 }
 
@@ -402,7 +518,9 @@ func (c *Client) RemovePendingShardOwner(id, nodeid uint64) error {
 	return c.retryUntilExec(internal.Command_RemovePendingShardOwnerCommand, internal.E_RemovePendingShardOwnerCommand_Command, cmd)
 }
 
-// CommitPendingShardOwner commits pending shardOwner.
+// CommitPendingShardOwner makes the data node nodeid, a pending owner of
+// the shard id, one of its owners, once it has copied the shard. It fails
+// with ErrShardOwnerNotPending if nodeid isn't a pending owner of it.
 func (c *Client) CommitPendingShardOwner(id, nodeid uint64) error {
 	cmd := &internal.CommitPendingShardOwnerCommand{
 		ID:     proto.Uint64(id),
@@ -487,6 +605,28 @@ func (c *Client) DeleteDataNode(id uint64) error {
 	return c.retryUntilExec(internal.Command_DeleteDataNodeCommand, internal.E_DeleteDataNodeCommand_Command, cmd)
 }
 
+// RemoveDataNode deletes the data node id, handing the shards it was the last
+// owner of to other data nodes. The result lists the shards reassigned and
+// the shard groups deleted since no data node was left to own them.
+func (c *Client) RemoveDataNode(id uint64) (*RemoveNodeResult, error) {
+	before := c.Data()
+	n := before.DataNode(id)
+	if n == nil {
+		return nil, ErrNodeNotFound
+	}
+	if err := c.DeleteDataNode(id); err != nil {
+		return nil, err
+	}
+
+	res := &RemoveNodeResult{
+		NodeID:   id,
+		NodeType: "data",
+		Host:     n.Host,
+	}
+	res.ReassignedShards, res.DeletedShardGroups = shardReassignments(before, c.data(), id)
+	return res, nil
+}
+
 // MetaNodes returns the meta nodes' info.
 func (c *Client) MetaNodes() (NodeInfos, error) {
 	return c.data().MetaNodes, nil
@@ -548,16 +688,37 @@ func (c *Client) defaultRetentionPolicyInfo(name string, duration time.Duration)
 
 // CreateDatabase creates a database or returns it if it already exists
 func (c *Client) CreateDatabase(name string) (*meta.DatabaseInfo, error) {
+	return c.CreateDatabaseWithOptions(name, CreateOptions{IfNotExists: c.config.DuplicateDatabase != DuplicateDatabaseError})
+}
+
+// CreateOptions controls how a create command treats an object that already
+// exists.
+type CreateOptions struct {
+	// IfNotExists makes creating an existing object a no-op. Without it the
+	// create fails with ErrDatabaseExists or ErrRetentionPolicyExists.
+	IfNotExists bool
+}
+
+// CreateDatabaseWithOptions creates a database, either failing or succeeding
+// as a no-op if it already exists depending on opts.
+func (c *Client) CreateDatabaseWithOptions(name string, opts CreateOptions) (*meta.DatabaseInfo, error) {
 	if db, _ := c.Database(name); db != nil {
+		if !opts.IfNotExists {
+			return nil, ErrDatabaseExists
+		}
 		return db, nil
 	}
 
 	cmd := &internal.CreateDatabaseCommand{
-		Name: proto.String(name),
+		Name:        proto.String(name),
+		IfNotExists: proto.Bool(opts.IfNotExists),
 	}
 
 	err := c.retryUntilExec(internal.Command_CreateDatabaseCommand, internal.E_CreateDatabaseCommand_Command, cmd)
-	if err != nil {
+	if e, ok := err.(errCommand); ok && e.msg == ErrDatabaseExists.Error() {
+		// Another client created the database first.
+		return nil, ErrDatabaseExists
+	} else if err != nil {
 		return nil, err
 	}
 
@@ -566,8 +727,8 @@ func (c *Client) CreateDatabase(name string) (*meta.DatabaseInfo, error) {
 
 // CreateDatabaseWithRetentionPolicy creates a database with the specified retention policy.
 func (c *Client) CreateDatabaseWithRetentionPolicy(name string, spec *meta.RetentionPolicySpec) (*meta.DatabaseInfo, error) {
-	if spec.Duration != nil && *spec.Duration < MinRetentionPolicyDuration && *spec.Duration != 0 {
-		return nil, ErrRetentionPolicyDurationTooLow
+	if err := validateRetentionPolicySpec(spec, false); err != nil {
+		return nil, err
 	}
 
 	if db, _ := c.Database(name); db != nil {
@@ -610,12 +771,22 @@ func (c *Client) DropDatabase(name string) error {
 
 // CreateRetentionPolicy creates a retention policy on the specified database.
 func (c *Client) CreateRetentionPolicy(database string, spec *meta.RetentionPolicySpec) (*meta.RetentionPolicyInfo, error) {
-	if rp, _ := c.RetentionPolicy(database, spec.Name); rp != nil {
-		return rp, nil
+	return c.CreateRetentionPolicyWithOptions(database, spec, CreateOptions{IfNotExists: true})
+}
+
+// CreateRetentionPolicyWithOptions creates a retention policy on database,
+// either failing or succeeding as a no-op if it already exists depending on
+// opts.
+func (c *Client) CreateRetentionPolicyWithOptions(database string, spec *meta.RetentionPolicySpec, opts CreateOptions) (*meta.RetentionPolicyInfo, error) {
+	if err := validateRetentionPolicySpec(spec, true); err != nil {
+		return nil, err
 	}
 
-	if spec.Duration != nil && *spec.Duration < MinRetentionPolicyDuration && *spec.Duration != 0 {
-		return nil, ErrRetentionPolicyDurationTooLow
+	if rp, _ := c.RetentionPolicy(database, spec.Name); rp != nil {
+		if !opts.IfNotExists {
+			return nil, ErrRetentionPolicyExists
+		}
+		return rp, nil
 	}
 
 	rpiB, err := spec.NewRetentionPolicyInfo().MarshalBinary()
@@ -626,6 +797,7 @@ func (c *Client) CreateRetentionPolicy(database string, spec *meta.RetentionPoli
 	cmd := &internal.CreateRetentionPolicyCommand{
 		Database:        proto.String(database),
 		RetentionPolicy: rpiB,
+		IfNotExists:     proto.Bool(opts.IfNotExists),
 	}
 
 	if err := c.retryUntilExec(internal.Command_CreateRetentionPolicyCommand, internal.E_CreateRetentionPolicyCommand_Command, cmd); err != nil {
@@ -688,17 +860,43 @@ func (c *Client) UpdateRetentionPolicy(database, name string, rpu *meta.Retentio
 		replicaN = &value
 	}
 
+	var shardGroupDuration *int64
+	if rpu.ShardGroupDuration != nil {
+		value := int64(*rpu.ShardGroupDuration)
+		shardGroupDuration = &value
+	}
+
 	cmd := &internal.UpdateRetentionPolicyCommand{
-		Database: proto.String(database),
-		Name:     proto.String(name),
-		NewName:  newName,
-		Duration: duration,
-		ReplicaN: replicaN,
+		Database:           proto.String(database),
+		Name:               proto.String(name),
+		NewName:            newName,
+		Duration:           duration,
+		ReplicaN:           replicaN,
+		ShardGroupDuration: shardGroupDuration,
 	}
 
 	return c.retryUntilExec(internal.Command_UpdateRetentionPolicyCommand, internal.E_UpdateRetentionPolicyCommand_Command, cmd)
 }
 
+// AlterRetentionPolicyReplicaN changes the replication factor of a retention
+// policy, adding or removing replicas of its existing shards to match, each
+// on a distinct data node. Added replicas start as pending owners, which
+// serve no reads until their data node has copied the shard and committed
+// them with CommitPendingShardOwner. A replicaN below 1 fails with
+// ErrReplicationFactorTooLow.
+func (c *Client) AlterRetentionPolicyReplicaN(database, name string, replicaN int) error {
+	if replicaN < 1 {
+		return ErrReplicationFactorTooLow
+	}
+	return c.retryUntilExec(internal.Command_AlterRetentionPolicyReplicaNCommand, internal.E_AlterRetentionPolicyReplicaNCommand_Command,
+		&internal.AlterRetentionPolicyReplicaNCommand{
+			Database: proto.String(database),
+			Name:     proto.String(name),
+			ReplicaN: proto.Uint32(uint32(replicaN)),
+		},
+	)
+}
+
 // ShardIDs returns a list of all shard ids.
 func (c *Client) ShardIDs() []uint64 {
 	var a []uint64
@@ -855,43 +1053,69 @@ func (c *Client) ShardOwner(shardID uint64) (database, policy string, si *meta.S
 }
 
 // JoinMetaServer will add the passed in tcpAddr to the raft peers and add a MetaNode to
-// the metastore
+// the metastore. It retries until the join succeeds. Every attempt carries the
+// same idempotency key, and the metastore matches meta nodes by tcpAddr, so a
+// join whose response was lost, even to a leader change, still results in a
+// single meta node.
 func (c *Client) JoinMetaServer(httpAddr, tcpAddr string) (*NodeInfo, error) {
+	return c.joinMetaServer(context.Background(), httpAddr, tcpAddr)
+}
+
+// joinMetaServer is like JoinMetaServer, but gives up once ctx is done or
+// the client is closed.
+func (c *Client) joinMetaServer(ctx context.Context, httpAddr, tcpAddr string) (*NodeInfo, error) {
+	// The leader records the time it took the node in instead of
+	// StartedAt, which only leaders too old to do so keep.
 	node := &NodeInfo{
-		Host:    httpAddr,
-		TCPHost: tcpAddr,
+		Host:      httpAddr,
+		TCPHost:   tcpAddr,
+		StartedAt: time.Now().UTC(),
 	}
 	b, err := json.Marshal(node)
 	if err != nil {
 		return nil, err
 	}
 
+	key := uuid.TimeUUID().String()
 	currentServer := 0
 	redirectServer := ""
-	//TODO spinning is ok but need a way to log this.
-	// or after a specific seconds just return error
 	for {
+		if c.closed() {
+			return nil, ErrService
+		} else if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		// get the server to try to join against
 		var url string
 		if redirectServer != "" {
 			url = redirectServer
 			redirectServer = ""
 		} else {
-			c.mu.RLock()
-
-			if currentServer >= len(c.metaServers) {
+			if currentServer >= len(c.MetaServers()) {
 				// We've tried every server, wait a second before
 				// trying again
-				time.Sleep(time.Second)
+				if err := c.sleep(ctx, time.Second); err != nil {
+					return nil, err
+				}
 				currentServer = 0
 			}
+			c.mu.RLock()
 			server := c.metaServers[currentServer]
 			c.mu.RUnlock()
 
 			url = c.url(server) + "/join"
 		}
 
-		resp, err := http.Post(url, "application/json", bytes.NewBuffer(b))
+		req, err := http.NewRequest("POST", url, bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		req = req.WithContext(ctx)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(idempotencyKeyHeader, key)
+
+		resp, err := c.httpClient().Do(req)
 		if err != nil {
 			currentServer++
 			continue
@@ -899,7 +1123,9 @@ func (c *Client) JoinMetaServer(httpAddr, tcpAddr string) (*NodeInfo, error) {
 
 		// Successfully joined
 		if resp.StatusCode == http.StatusOK {
-			if err := json.NewDecoder(resp.Body).Decode(&node); err != nil {
+			err := json.NewDecoder(resp.Body).Decode(&node)
+			resp.Body.Close()
+			if err != nil {
 				return nil, err
 			}
 			break
@@ -921,12 +1147,123 @@ func (c *Client) JoinMetaServer(httpAddr, tcpAddr string) (*NodeInfo, error) {
 	return node, nil
 }
 
+// JoinCluster joins this node to the cluster through the meta server at
+// addr, which redirects to the leader if need be, and saves the node's ID
+// to node.json. The node registers the addresses its config advertises.
+// Joining a cluster the node is already a member of returns its existing
+// meta node. A node refuses to join a cluster whose metadata version is
+// more than metadata-version-tolerance away from its own.
+//
+// Once joined, the client talks to the cluster's meta servers.
+func (c *Client) JoinCluster(addr string) (*NodeInfo, error) {
+	httpAddr, raftAddr, err := c.config.advertisedAddrs()
+	if err != nil {
+		return nil, err
+	}
+	return c.JoinClusterAs(addr, httpAddr, raftAddr)
+}
+
+// JoinClusterAs joins the cluster as JoinCluster does, registering the node
+// at httpAddr and raftAddr in place of the addresses its config advertises,
+// as a node listening on ports assigned with a port of 0 does once it knows
+// them.
+func (c *Client) JoinClusterAs(addr, httpAddr, raftAddr string) (*NodeInfo, error) {
+	return c.JoinClusterAsContext(context.Background(), addr, httpAddr, raftAddr)
+}
+
+// JoinClusterAsContext is like JoinClusterAs, but gives up once ctx is done
+// or the client is closed.
+func (c *Client) JoinClusterAsContext(ctx context.Context, addr, httpAddr, raftAddr string) (*NodeInfo, error) {
+	c.SetMetaServers([]string{addr})
+	data, err := c.getSnapshot(addr, 0)
+	if err != nil {
+		return nil, err
+	} else if err := checkMetadataVersion(data.MetadataVersion, c.NodeMetadataVersion(), c.config.MetadataVersionTolerance); err != nil {
+		return nil, err
+	}
+
+	// A node.json copied from another host names a meta node that may still
+	// be live there.
+	if node, err := influxcloud.LoadNode(c.Path()); err == nil && node.ID != 0 {
+		if m := data.MetaNode(node.ID); m != nil && m.Host != httpAddr && liveNodeID(m.Host, c) == node.ID {
+			return nil, &DuplicateNodeError{ID: node.ID, Addr: m.Host, LocalAddr: httpAddr}
+		}
+	}
+
+	n, err := c.joinMetaServer(ctx, httpAddr, raftAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Wait for addr to learn of the node, which it won't have yet if it
+	// isn't the leader, to read the cluster's meta servers from it.
+	for {
+		if c.closed() {
+			return nil, ErrService
+		}
+		data, err := c.getSnapshot(addr, 0)
+		if err == nil && data.MetaNode(n.ID) != nil {
+			servers := make([]string, len(data.MetaNodes))
+			for i, m := range data.MetaNodes {
+				servers[i] = m.Host
+			}
+
+			c.mu.Lock()
+			c.cacheData = data
+			c.metaServers = servers
+			c.mu.Unlock()
+			break
+		}
+		if err := c.sleep(ctx, errSleep); err != nil {
+			return nil, err
+		}
+	}
+
+	node := influxcloud.NewNode(c.Path())
+	node.ID = n.ID
+	node.Validated = time.Now().Unix()
+	if err := node.Save(); err != nil {
+		return nil, err
+	}
+	c.nodeID = n.ID
+
+	return n, nil
+}
+
+// LeaveCluster removes the meta node nodeID from the cluster, refusing with
+// ErrQuorumUnsafe if that would break quorum. A node that isn't a member
+// has nothing to leave. If nodeID is this node, its ID is cleared from
+// node.json, so it joins the cluster as a fresh node when it next starts.
+func (c *Client) LeaveCluster(nodeID uint64) error {
+	if _, err := c.RemoveMetaNode(nodeID, false); err != nil && err != ErrNodeNotFound {
+		return err
+	}
+
+	node, err := influxcloud.LoadNode(c.Path())
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if node.ID != nodeID {
+		return nil
+	}
+	node.ID = 0
+	if err := node.Save(); err != nil {
+		return err
+	}
+	c.nodeID = 0
+
+	return nil
+}
+
 // CreateMetaNode creates meta node.
 func (c *Client) CreateMetaNode(httpAddr, tcpAddr string) (*NodeInfo, error) {
 	cmd := &internal.CreateMetaNodeCommand{
-		HTTPAddr: proto.String(httpAddr),
-		TCPAddr:  proto.String(tcpAddr),
-		Rand:     proto.Uint64(uint64(rand.Int63())),
+		HTTPAddr:        proto.String(httpAddr),
+		TCPAddr:         proto.String(tcpAddr),
+		Rand:            proto.Uint64(uint64(rand.Int63())),
+		MetadataVersion: proto.Uint64(c.NodeMetadataVersion()),
 	}
 
 	if err := c.retryUntilExec(internal.Command_CreateMetaNodeCommand, internal.E_CreateMetaNodeCommand_Command, cmd); err != nil {
@@ -952,6 +1289,226 @@ func (c *Client) DeleteMetaNode(id uint64) error {
 	return c.retryUntilExec(internal.Command_DeleteMetaNodeCommand, internal.E_DeleteMetaNodeCommand_Command, cmd)
 }
 
+// QuorumStatus returns the current voter count and quorum size, and whether
+// removing the meta node id would break quorum. The voters are the peers in
+// the leader's raft configuration, which differ from the meta nodes while a
+// join or removal is being applied. It returns ErrNoLeader if no meta server
+// knows of a leader.
+func (c *Client) QuorumStatus(id uint64) (*QuorumStatus, error) {
+	n := c.data().MetaNode(id)
+	if n == nil {
+		return nil, ErrNodeNotFound
+	}
+	st, err := c.leaderRaftStatus()
+	if err != nil {
+		return nil, err
+	}
+	return newQuorumStatus(st.Peers, n.TCPHost), nil
+}
+
+// leaderRaftStatus returns the raft status of the leader, asking the meta
+// servers in turn for it.
+func (c *Client) leaderRaftStatus() (*raftStatus, error) {
+	for _, server := range c.MetaServers() {
+		st, err := c.raftStatus(server)
+		if err != nil {
+			continue
+		} else if st.State == raft.Leader.String() {
+			return st, nil
+		} else if st.LeaderHTTP == "" {
+			continue
+		}
+		if st, err := c.raftStatus(st.LeaderHTTP); err == nil && st.State == raft.Leader.String() {
+			return st, nil
+		}
+	}
+	return nil, ErrNoLeader
+}
+
+// RemoveMetaNode deletes the meta node id, refusing with ErrQuorumUnsafe if
+// that would break quorum, unless force is set. The result names the raft
+// peer removed and, when id was the leader, waits for and names the new one.
+func (c *Client) RemoveMetaNode(id uint64, force bool) (*RemoveNodeResult, error) {
+	if !force {
+		qs, err := c.QuorumStatus(id)
+		if err != nil {
+			return nil, err
+		} else if !qs.RemovalSafe {
+			return nil, ErrQuorumUnsafe
+		}
+	}
+
+	n := c.data().MetaNode(id)
+	if n == nil {
+		return nil, ErrNodeNotFound
+	}
+	res := &RemoveNodeResult{
+		NodeID:             id,
+		NodeType:           "meta",
+		Host:               n.Host,
+		RaftPeerRemoved:    n.TCPHost,
+		LeaderBefore:       c.raftLeader(""),
+		ReassignedShards:   []ShardReassignment{},
+		DeletedShardGroups: []uint64{},
+	}
+	if err := c.DeleteMetaNode(id); err != nil {
+		return nil, err
+	}
+
+	// The removed node doesn't know of the new leader, if it was the leader.
+	deadline := time.Now().Add(removeNodeLeaderTimeout)
+	for {
+		res.LeaderAfter = c.raftLeader(n.Host)
+		if (res.LeaderAfter != "" && res.LeaderAfter != n.TCPHost) || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(errSleep)
+	}
+	if res.LeaderAfter == n.TCPHost {
+		res.LeaderAfter = ""
+	}
+	res.LeadershipTransferred = res.LeaderAfter != "" && res.LeaderAfter != res.LeaderBefore
+	return res, nil
+}
+
+// raftLeader returns the raft leader known to the first meta server, other
+// than skip, that knows of one, or an empty string.
+func (c *Client) raftLeader(skip string) string {
+	for _, server := range c.MetaServers() {
+		if server == skip {
+			continue
+		}
+		if st, err := c.raftStatus(server); err == nil && st.Leader != "" {
+			return st.Leader
+		}
+	}
+	return ""
+}
+
+// raftStatus returns the raft status of the meta server.
+func (c *Client) raftStatus(server string) (*raftStatus, error) {
+	return c.raftStatusContext(context.Background(), server)
+}
+
+// raftStatusContext is raftStatus, giving up once ctx is done.
+func (c *Client) raftStatusContext(ctx context.Context, server string) (*raftStatus, error) {
+	req, err := http.NewRequest("GET", c.url(server)+"/raft-status", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient().Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("meta server returned %s", resp.Status)
+	}
+
+	st := &raftStatus{}
+	if err := json.NewDecoder(resp.Body).Decode(st); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// LeaderAddr returns the HTTP address of the meta leader the client last
+// learned of, by a write or WaitForLeader, or an empty string if it knows of
+// none.
+func (c *Client) LeaderAddr() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.leader
+}
+
+func (c *Client) setLeader(addr string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.leader = addr
+}
+
+// WaitForLeader blocks until the meta servers know of a leader, and returns
+// its HTTP address. The servers are asked every leaderPollInterval, so an
+// election mid-wait ends it promptly. While they disagree, as a deposed
+// leader may not know yet, the leader of the highest term is taken. It
+// returns ErrLeaderWaitTimeout after timeout, unless timeout is zero.
+func (c *Client) WaitForLeader(timeout time.Duration) (string, error) {
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	ticker := time.NewTicker(leaderPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if leader := c.pollLeader(); leader != "" {
+			c.setLeader(leader)
+			return leader, nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-expired:
+			return "", ErrLeaderWaitTimeout
+		case <-c.closing:
+			return "", ErrServiceUnavailable
+		}
+	}
+}
+
+// pollLeader asks every meta server at once for the raft status, and returns
+// the HTTP address of the leader of the highest term any of them answered
+// with in errSleep, once the leader itself confirms it still leads, or an
+// empty string. The confirmation keeps a follower that hasn't noticed its
+// leader is down from ending the wait.
+func (c *Client) pollLeader() string {
+	type result struct {
+		server string
+		st     *raftStatus
+	}
+	servers := c.MetaServers()
+	ch := make(chan result, len(servers))
+	for _, server := range servers {
+		go func(server string) {
+			st, _ := c.raftStatus(server)
+			ch <- result{server: server, st: st}
+		}(server)
+	}
+
+	statuses := make(map[string]*raftStatus)
+	var best *raftStatus
+	timeout := time.After(errSleep)
+	for range servers {
+		select {
+		case r := <-ch:
+			if r.st == nil {
+				continue
+			}
+			statuses[r.server] = r.st
+			if r.st.LeaderHTTP != "" && (best == nil || r.st.Term > best.Term) {
+				best = r.st
+			}
+			continue
+		case <-timeout:
+		}
+		break
+	}
+	if best == nil {
+		return ""
+	}
+
+	st, ok := statuses[best.LeaderHTTP]
+	if !ok {
+		st, _ = c.raftStatus(best.LeaderHTTP)
+	}
+	if st == nil || st.State != raft.Leader.String() {
+		return ""
+	}
+	return best.LeaderHTTP
+}
+
 // CreateContinuousQuery creates continue query in cluster.
 func (c *Client) CreateContinuousQuery(database, name, query string) error {
 	return c.retryUntilExec(internal.Command_CreateContinuousQueryCommand, internal.E_CreateContinuousQueryCommand_Command,
@@ -997,6 +1554,146 @@ func (c *Client) DropSubscription(database, rp, name string) error {
 	)
 }
 
+// SetTopologyFrozen freezes or unfreezes the cluster topology. While frozen,
+// no node can be added or removed and no shard can change owners.
+func (c *Client) SetTopologyFrozen(frozen bool) error {
+	return c.retryUntilExec(internal.Command_SetTopologyFrozenCommand, internal.E_SetTopologyFrozenCommand_Command,
+		&internal.SetTopologyFrozenCommand{
+			Frozen: proto.Bool(frozen),
+		},
+	)
+}
+
+// TopologyFrozen returns true if the cluster topology is currently frozen.
+func (c *Client) TopologyFrozen() bool {
+	return c.data().TopologyFrozen
+}
+
+// SetShardGroupQuota caps the number of shard groups of database at max.
+// Creating a shard group past it fails with ErrShardGroupQuotaExceeded. A
+// max of zero removes the quota. The quota of a database is dropped with it.
+func (c *Client) SetShardGroupQuota(database string, max uint64) error {
+	return c.retryUntilExec(internal.Command_SetShardGroupQuotaCommand, internal.E_SetShardGroupQuotaCommand_Command,
+		&internal.SetShardGroupQuotaCommand{
+			Database:       proto.String(database),
+			MaxShardGroups: proto.Uint64(max),
+		},
+	)
+}
+
+// ShardGroupQuota returns the shard group quota of database, or zero if it
+// has none.
+func (c *Client) ShardGroupQuota(database string) uint64 {
+	return c.data().ShardGroupQuotas[database]
+}
+
+// SetDatabaseAnnotation sets the annotation key of database to value. An
+// empty value removes it. Keys are limited to MaxAnnotationKeySize bytes,
+// values to MaxAnnotationValueSize bytes and databases to
+// MaxDatabaseAnnotations annotations.
+func (c *Client) SetDatabaseAnnotation(database, key, value string) error {
+	return c.retryUntilExec(internal.Command_SetDatabaseAnnotationCommand, internal.E_SetDatabaseAnnotationCommand_Command,
+		&internal.SetDatabaseAnnotationCommand{
+			Database: proto.String(database),
+			Key:      proto.String(key),
+			Value:    proto.String(value),
+		},
+	)
+}
+
+// DatabaseAnnotations returns the annotations of database.
+func (c *Client) DatabaseAnnotations(database string) map[string]string {
+	annotations := make(map[string]string)
+	for k, v := range c.data().DatabaseAnnotations[database] {
+		annotations[k] = v
+	}
+	return annotations
+}
+
+// DrainDatabase blocks writes to database, which data nodes then refuse,
+// and waits until the writes already under way have had drain-settle-time
+// to finish. The database can then be dropped without writes landing in it
+// meanwhile.
+func (c *Client) DrainDatabase(database string) error {
+	if err := c.retryUntilExec(internal.Command_SetDatabaseWriteBlockedCommand, internal.E_SetDatabaseWriteBlockedCommand_Command,
+		&internal.SetDatabaseWriteBlockedCommand{
+			Database: proto.String(database),
+			Blocked:  proto.Bool(true),
+			Time:     proto.Int64(time.Now().UnixNano()),
+		},
+	); err != nil {
+		return err
+	}
+
+	// A database drained before keeps the time it was first blocked at.
+	blocked, ok := c.data().WriteBlockedDatabases[database]
+	if !ok {
+		return influxdb.ErrDatabaseNotFound(database)
+	}
+	select {
+	case <-time.After(blocked.Add(time.Duration(c.config.DrainSettleTime)).Sub(time.Now())):
+		return nil
+	case <-c.closing:
+		return ErrServiceUnavailable
+	}
+}
+
+// ResumeDatabaseWrites lets data nodes take writes to a drained database
+// again.
+func (c *Client) ResumeDatabaseWrites(database string) error {
+	return c.retryUntilExec(internal.Command_SetDatabaseWriteBlockedCommand, internal.E_SetDatabaseWriteBlockedCommand_Command,
+		&internal.SetDatabaseWriteBlockedCommand{
+			Database: proto.String(database),
+			Blocked:  proto.Bool(false),
+			Time:     proto.Int64(time.Now().UnixNano()),
+		},
+	)
+}
+
+// DatabaseWriteBlocked returns whether writes to database are blocked since
+// it is being drained.
+func (c *Client) DatabaseWriteBlocked(database string) bool {
+	_, ok := c.data().WriteBlockedDatabases[database]
+	return ok
+}
+
+// AcquireRestartLock takes the cluster wide restart lock for holder, a
+// rolling restart orchestrator, while it restarts the node nodeID, so that
+// only one node restarts at a time. The lock expires after ttl unless it is
+// acquired again by the same holder; it fails with ErrRestartLockHeld while
+// another holder has it. The leader stamps the time the lock is acquired at
+// by its own clock.
+func (c *Client) AcquireRestartLock(holder string, nodeID uint64, ttl time.Duration) error {
+	return c.retryUntilExec(internal.Command_AcquireRestartLockCommand, internal.E_AcquireRestartLockCommand_Command,
+		&internal.AcquireRestartLockCommand{
+			Holder: proto.String(holder),
+			NodeID: proto.Uint64(nodeID),
+			Time:   proto.Int64(time.Now().UnixNano()),
+			TTL:    proto.Int64(int64(ttl)),
+		},
+	)
+}
+
+// ReleaseRestartLock releases the restart lock held by holder.
+func (c *Client) ReleaseRestartLock(holder string) error {
+	return c.retryUntilExec(internal.Command_ReleaseRestartLockCommand, internal.E_ReleaseRestartLockCommand_Command,
+		&internal.ReleaseRestartLockCommand{
+			Holder: proto.String(holder),
+		},
+	)
+}
+
+// RestartLock returns the restart lock, or nil if nobody holds it. The lock
+// may have expired.
+func (c *Client) RestartLock() *RestartLock {
+	l := c.data().RestartLock
+	if l == nil {
+		return nil
+	}
+	other := *l
+	return &other
+}
+
 // Data returns a reference of data.
 func (c *Client) Data() *Data {
 	return c.data().Clone()
@@ -1012,6 +1709,232 @@ func (c *Client) SetData(data *Data) error {
 	)
 }
 
+// Refresh fetches the latest metadata right away instead of waiting for the
+// next update, and returns its index. It's meant for tooling that changed the
+// metadata out of band and needs the cache to reflect it.
+func (c *Client) Refresh() (uint64, error) {
+	var lastErr error
+	for _, server := range c.MetaServers() {
+		host, index, err := c.refreshIndex(server)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		// Fetch from the server that answered, normally the leader, since it
+		// has applied everything up to index.
+		data, err := c.getSnapshot(host, 0)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if data.Data.Index < index {
+			lastErr = fmt.Errorf("snapshot from %s at index %d, expected %d", host, data.Data.Index, index)
+			continue
+		}
+
+		c.mu.Lock()
+		if idx := c.cacheData.Data.Index; idx < data.Data.Index {
+			c.cacheNewer(data)
+		}
+		index = c.cacheData.Data.Index
+		c.mu.Unlock()
+		return index, nil
+	}
+
+	if lastErr == nil {
+		lastErr = ErrServiceUnavailable
+	}
+	return 0, lastErr
+}
+
+// refreshIndex asks server for the latest applied index. It returns the host
+// that answered, which is the leader if server redirected.
+func (c *Client) refreshIndex(server string) (string, uint64, error) {
+	resp, err := c.httpClient().Post(c.url(server)+"/meta/refresh", "application/octet-stream", nil)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("meta server returned non-200: %s", resp.Status)
+	}
+
+	var body struct {
+		Index uint64 `json:"index"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", 0, err
+	}
+	return resp.Request.URL.Host, body.Index, nil
+}
+
+// StateChecksum returns the checksum of every meta server's state, keyed by
+// server. Each server is asked once it has applied everything the leader had
+// committed, so healthy servers report the same checksum as long as the
+// metadata doesn't change meanwhile. Use DivergedServers to find mismatches.
+func (c *Client) StateChecksum() (map[string]StateChecksum, error) {
+	var index uint64
+	lastErr := ErrServiceUnavailable
+	for _, server := range c.MetaServers() {
+		_, idx, err := c.refreshIndex(server)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		index, lastErr = idx, nil
+		break
+	}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+
+	sums := make(map[string]StateChecksum)
+	for _, server := range c.MetaServers() {
+		resp, err := c.httpClient().Get(c.url(server) + fmt.Sprintf("/checksum?index=%d", index))
+		if err != nil {
+			return nil, err
+		}
+		var sum StateChecksum
+		if resp.StatusCode != http.StatusOK {
+			err = fmt.Errorf("meta server %s returned %s", server, resp.Status)
+		} else {
+			err = json.NewDecoder(resp.Body).Decode(&sum)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		sums[server] = sum
+	}
+	return sums, nil
+}
+
+// ReportShardSizes sends the size in bytes of each shard, keyed by shard ID,
+// to every meta server. The sizes drive shard group duration auto-tuning.
+// As with Heartbeat, a server that fails doesn't stop the others from being
+// sent the sizes; the last error is returned.
+func (c *Client) ReportShardSizes(sizes map[uint64]int64) error {
+	b, err := json.Marshal(sizes)
+	if err != nil {
+		return err
+	}
+
+	var last error
+	for _, server := range c.MetaServers() {
+		resp, err := c.httpClient().Post(c.url(server)+"/shard-sizes", "application/json", bytes.NewReader(b))
+		if err != nil {
+			last = err
+			continue
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusNoContent {
+			last = fmt.Errorf("meta server %s returned %s", server, resp.Status)
+		}
+	}
+	return last
+}
+
+// Heartbeat tells every meta server that the data node id is up. Data nodes
+// call it periodically, well within data-node-liveness-timeout. Each meta
+// server tracks liveness on its own, so a server that fails doesn't stop the
+// others from being sent the heartbeat; the last error is returned.
+func (c *Client) Heartbeat(id uint64) error {
+	var last error
+	for _, server := range c.MetaServers() {
+		resp, err := c.httpClient().Post(fmt.Sprintf("%s/heartbeat?id=%d", c.url(server), id), "application/octet-stream", nil)
+		if err != nil {
+			last = err
+			continue
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusNoContent {
+			last = fmt.Errorf("meta server %s returned %s", server, resp.Status)
+		}
+	}
+	return last
+}
+
+// ShardHealth returns the replica health of every shard in the live shard
+// groups, from the first meta server that answers.
+func (c *Client) ShardHealth() ([]ShardHealth, error) {
+	var err error
+	for _, server := range c.MetaServers() {
+		var resp *http.Response
+		resp, err = c.httpClient().Get(c.url(server) + "/shard-health")
+		if err != nil {
+			continue
+		}
+
+		var health []ShardHealth
+		if resp.StatusCode != http.StatusOK {
+			err = fmt.Errorf("meta server %s returned %s", server, resp.Status)
+		} else {
+			err = json.NewDecoder(resp.Body).Decode(&health)
+		}
+		resp.Body.Close()
+		if err == nil {
+			return health, nil
+		}
+	}
+	if err == nil {
+		err = errors.New("no meta servers")
+	}
+	return nil, err
+}
+
+// PauseLeaderTask pauses the named leader task on every meta server, so it
+// stays paused if leadership moves. It's meant for maintenance windows where
+// background work such as shard precreation should hold off. The task is
+// paused on every server or none: if a server fails to pause it, it is
+// resumed on those that already had and the error is returned.
+func (c *Client) PauseLeaderTask(name string) error {
+	var paused []string
+	for _, server := range c.MetaServers() {
+		if err := c.controlLeaderTask(server, "/tasks/pause", name); err != nil {
+			for _, p := range paused {
+				if rerr := c.controlLeaderTask(p, "/tasks/resume", name); rerr != nil {
+					c.logger.Printf("failed to resume leader task %s on %s after failing to pause it everywhere: %s", name, p, rerr)
+				}
+			}
+			return err
+		}
+		paused = append(paused, server)
+	}
+	return nil
+}
+
+// ResumeLeaderTask resumes the named leader task on every meta server. Every
+// server is tried even if some fail, and the first error is returned.
+func (c *Client) ResumeLeaderTask(name string) error {
+	var err error
+	for _, server := range c.MetaServers() {
+		if e := c.controlLeaderTask(server, "/tasks/resume", name); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// controlLeaderTask posts the named task to path on server.
+func (c *Client) controlLeaderTask(server, path, name string) error {
+	resp, err := c.httpClient().Post(c.url(server)+path+"?name="+url.QueryEscape(name), "application/octet-stream", nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrLeaderTaskNotFound
+	} else if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("meta server %s returned %s", server, resp.Status)
+	}
+	return nil
+}
+
 // WaitForDataChanged will return a channel that will get closed when
 // the metastore data has changed
 func (c *Client) WaitForDataChanged() chan struct{} {
@@ -1020,6 +1943,20 @@ func (c *Client) WaitForDataChanged() chan struct{} {
 	return c.changed
 }
 
+// WaitForDataChangedContext blocks until the metastore data has changed. It
+// returns ctx.Err() if ctx is done first, or ErrServiceUnavailable if the
+// client is closed.
+func (c *Client) WaitForDataChangedContext(ctx context.Context) error {
+	select {
+	case <-c.WaitForDataChanged():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.closing:
+		return ErrServiceUnavailable
+	}
+}
+
 // MarshalBinary marshals data into a bianry form.
 func (c *Client) MarshalBinary() ([]byte, error) {
 	c.mu.RLock()
@@ -1046,14 +1983,23 @@ func (c *Client) index() uint64 {
 }
 
 // retryUntilExec will attempt the command on each of the metaservers until it either succeeds or
-// hits the max number of tries
+// hits the max number of tries. Every attempt carries the same idempotency key, which
+// dedupes retries reaching the leader that applied the command, but not a new leader
+// after a failover: the keys aren't replicated through raft.
 func (c *Client) retryUntilExec(typ internal.Command_Type, desc *proto.ExtensionDesc, value interface{}) error {
 	var err error
 	var index uint64
 	tries := 0
+
+	// Every attempt carries the same key so the leader applies the command once.
+	key := uuid.TimeUUID().String()
 	currentServer := 0
 	var redirectServer string
 
+	// noLeaderSince is when the servers started answering that there is no
+	// leader, as they do while the cluster has no quorum.
+	var noLeaderSince time.Time
+
 	for {
 		c.mu.RLock()
 		// exit if we're closed
@@ -1087,7 +2033,7 @@ func (c *Client) retryUntilExec(typ internal.Command_Type, desc *proto.Extension
 			}
 		}
 
-		index, err = c.exec(url, typ, desc, value)
+		index, err = c.exec(url, key, typ, desc, value)
 		tries++
 		currentServer++
 
@@ -1096,6 +2042,21 @@ func (c *Client) retryUntilExec(typ internal.Command_Type, desc *proto.Extension
 			return nil
 		}
 
+		if err == errNoLeader {
+			c.setLeader("")
+			if noLeaderSince.IsZero() {
+				noLeaderSince = time.Now()
+			}
+			if c.config.QuorumLossWrites == QuorumLossBlock {
+				// Wait for quorum to return, however long it takes.
+				tries = 0
+			} else if time.Since(noLeaderSince) >= time.Duration(c.config.QuorumLossTimeout) {
+				return ErrQuorumLost
+			}
+		} else {
+			noLeaderSince = time.Time{}
+		}
+
 		if tries > maxRetries {
 			return err
 		}
@@ -1105,7 +2066,18 @@ func (c *Client) retryUntilExec(typ internal.Command_Type, desc *proto.Extension
 			continue
 		}
 
-		if _, ok := err.(errCommand); ok {
+		if e, ok := err.(errCommand); ok {
+			if e.msg == ErrWriteNotDurable.Error() {
+				// The write is committed, so it isn't retried.
+				return ErrWriteNotDurable
+			} else if e.msg == ErrMaintenanceReadOnly.Error() {
+				return ErrMaintenanceReadOnly
+			} else if e.msg == ErrQuorumLost.Error() {
+				return ErrQuorumLost
+			} else if e.msg == ErrCommitTimeout.Error() {
+				// The write may still be applied, so it isn't retried.
+				return ErrCommitTimeout
+			}
 			return err
 		}
 
@@ -1113,7 +2085,7 @@ func (c *Client) retryUntilExec(typ internal.Command_Type, desc *proto.Extension
 	}
 }
 
-func (c *Client) exec(url string, typ internal.Command_Type, desc *proto.ExtensionDesc, value interface{}) (index uint64, err error) {
+func (c *Client) exec(url, key string, typ internal.Command_Type, desc *proto.ExtensionDesc, value interface{}) (index uint64, err error) {
 	// Create command.
 	cmd := &internal.Command{Type: &typ}
 	if err := proto.SetExtension(cmd, desc, value); err != nil {
@@ -1125,14 +2097,32 @@ func (c *Client) exec(url string, typ internal.Command_Type, desc *proto.Extensi
 		return 0, err
 	}
 
-	resp, err := http.Post(url, "application/octet-stream", bytes.NewBuffer(b))
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(b))
 	if err != nil {
 		return 0, err
 	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set(idempotencyKeyHeader, key)
+	if c.config.WriteDurability != "" {
+		req.Header.Set(DurabilityHeader, c.config.WriteDurability)
+	}
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
 
 	// read the response
 	if resp.StatusCode == http.StatusTemporaryRedirect {
 		return 0, errRedirect{host: resp.Header.Get("Location")}
+	} else if resp.StatusCode == http.StatusServiceUnavailable {
+		// Only a server without a leader says so; one that is closing or
+		// in maintenance is just passed over.
+		if body, _ := ioutil.ReadAll(resp.Body); strings.TrimSpace(string(body)) == errNoLeader.Error() {
+			return 0, errNoLeader
+		}
+		return 0, fmt.Errorf("meta service returned %s", resp.Status)
 	} else if resp.StatusCode != http.StatusOK {
 		return 0, fmt.Errorf("meta service returned %s", resp.Status)
 	}
@@ -1152,6 +2142,9 @@ func (c *Client) exec(url string, typ internal.Command_Type, desc *proto.Extensi
 		return 0, errCommand{msg: es}
 	}
 
+	// The server that took the command, after any redirect, is the leader.
+	c.setLeader(resp.Request.URL.Host)
+
 	return res.GetIndex(), nil
 }
 
@@ -1179,11 +2172,10 @@ func (c *Client) pollForUpdates() {
 
 		// update the data and notify of the change
 		c.mu.Lock()
-		idx := c.cacheData.Data.Index
-		c.cacheData = data
-		if idx < data.Data.Index {
-			close(c.changed)
-			c.changed = make(chan struct{})
+		if c.cacheData.Data.Index < data.Data.Index {
+			c.cacheNewer(data)
+		} else {
+			c.cacheData = data
 		}
 		c.mu.Unlock()
 	}
@@ -1194,25 +2186,26 @@ func (c *Client) getSnapshot(server string, index uint64) (*Data, error) {
 		return nil, errors.New("server host empty")
 	}
 	// resp, err := c.get(server + fmt.Sprintf("?index=%d", index))
-	resp, err := http.Get(c.url(server) + fmt.Sprintf("?index=%d", index))
+	url := c.url(server) + fmt.Sprintf("?index=%d", index)
+	if c.config.SnapshotStreaming {
+		url += "&stream=true"
+	}
+	resp, err := c.httpClient().Get(url)
 
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("meta server returned non-200: %s", resp.Status)
+		return nil, errStatus{code: resp.StatusCode, status: resp.Status}
 	}
 
-	b, err := ioutil.ReadAll(resp.Body)
+	// Servers that don't stream snapshots answer with a plain one.
+	data, err := readSnapshot(resp.Body, resp.Header.Get("Content-Type"), c.config.MaxSnapshotSize)
 	if err != nil {
-		return nil, err
-	}
-	data := &Data{}
-	if err := data.UnmarshalBinary(b); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("snapshot from %s: %s", server, err)
 	}
-
 	return data, nil
 }
 
@@ -1221,21 +2214,24 @@ func (c *Client) peers() []string {
 
 	var peers Peers
 	// query each server and keep track of who their peers are
-	for _, server := range c.metaServers {
+	for _, server := range c.MetaServers() {
 		url := c.url(server) + "/peers"
-		resp, err := http.Get(url)
+		resp, err := c.httpClient().Get(url)
 		if err != nil {
 			continue
 		}
 
 		// This meta-server might not be ready to answer, continue on
 		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
 			continue
 		}
 
 		dec := json.NewDecoder(resp.Body)
 		var p []string
-		if err := dec.Decode(&p); err != nil {
+		err = dec.Decode(&p)
+		resp.Body.Close()
+		if err != nil {
 			continue
 		}
 		peers = peers.Append(p...)
@@ -1246,6 +2242,9 @@ func (c *Client) peers() []string {
 }
 
 func (c *Client) url(server string) string {
+	if path, ok := unixSocketPath(server); ok {
+		server = unixSocketHost(path)
+	}
 	url := fmt.Sprintf("://%s", server)
 
 	if c.tls {
@@ -1282,6 +2281,49 @@ func (c *Client) retryUntilSnapshot(idx uint64) *Data {
 	}
 }
 
+// openSnapshot fetches the meta data from the first meta server that serves
+// it, trying each in turn. Servers that can't be reached or can't serve it
+// yet are retried with backoff, up to open-max-attempts times. A server
+// failing with a certificate or authorization error is given up on, but
+// the others are still tried; the errors of every server are returned once
+// none is left to retry.
+func (c *Client) openSnapshot(ctx context.Context) (*Data, error) {
+	for attempt := 1; ; attempt++ {
+		servers := c.MetaServers()
+		if len(servers) == 0 {
+			return nil, ErrNoMetaServers
+		}
+
+		var errs []string
+		retryable := false
+		for _, server := range servers {
+			if c.closed() {
+				return nil, ErrServiceUnavailable
+			}
+			data, err := c.getSnapshot(server, 0)
+			if err == nil {
+				return data, nil
+			}
+			c.Logger().Printf("failure getting snapshot from %s: %s", server, err.Error())
+			errs = append(errs, fmt.Sprintf("meta server %s: %s", server, err))
+			if openRetryable(err) {
+				retryable = true
+			}
+		}
+		if !retryable {
+			return nil, errors.New(strings.Join(errs, "; "))
+		}
+		if max := c.config.OpenMaxAttempts; max > 0 && attempt >= max {
+			return nil, fmt.Errorf("no snapshot from the meta servers after %d attempts: %s", attempt, strings.Join(errs, "; "))
+		}
+		select {
+		case <-time.After(c.openBackoff(attempt)):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("no snapshot from the meta servers %v: %s", servers, ctx.Err())
+		}
+	}
+}
+
 func (c *Client) updateMetaServers() error {
 	copy := c.data()
 	if copy == nil {
@@ -1298,7 +2340,7 @@ func (c *Client) saveMetaServers() error {
 		return errors.New("path is not set")
 	}
 
-	if err := os.MkdirAll(path, 0777); err != nil {
+	if err := MkdirAll(path); err != nil {
 		return err
 	}
 
@@ -0,0 +1,222 @@
+package meta_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/toml"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// Ensure WaitForDataChangedContext returns once the data changes, and gives
+// up when its context is done.
+func TestMetaService_WaitForDataChangedContext(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := c.WaitForDataChangedContext(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		if _, err := c.CreateDatabase("db0"); err != nil {
+			t.Error(err)
+		}
+	}()
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := c.WaitForDataChangedContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMetaClient_MaxIdleTimeReplacesConnection(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var conns int
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			conns++
+			mu.Unlock()
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	cfg.ClientMaxIdleTime = toml.Duration(100 * time.Millisecond)
+	c := cloudMeta.NewClient(cfg)
+
+	get := func() {
+		resp, err := c.HTTPClient.Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return conns
+	}
+
+	// Back to back requests share the pooled connection.
+	get()
+	get()
+	if n := count(); n != 1 {
+		t.Fatalf("expected 1 connection, got %d", n)
+	}
+
+	// A connection idle past the max is replaced.
+	time.Sleep(300 * time.Millisecond)
+	get()
+	if n := count(); n != 2 {
+		t.Fatalf("expected 2 connections, got %d", n)
+	}
+}
+
+// Ensure Refresh pulls in a change made through another client right away.
+func TestMetaClient_Refresh(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	other := newClient(s)
+	defer other.Close()
+	if _, err := other.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+	exp := other.Data().Index
+
+	index, err := c.Refresh()
+	if err != nil {
+		t.Fatal(err)
+	} else if index != exp {
+		t.Fatalf("unexpected index: got %d, expected %d", index, exp)
+	}
+	if db, _ := c.Database("db0"); db == nil {
+		t.Fatal("expected refreshed cache to include db0")
+	}
+
+	// The endpoint reports the same index.
+	resp, err := http.Post("http://"+s.HTTPAddr()+"/meta/refresh", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		Index uint64 `json:"index"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	} else if body.Index != exp {
+		t.Fatalf("unexpected index from endpoint: got %d, expected %d", body.Index, exp)
+	}
+}
+
+// Ensure a client opens against the first reachable meta server, and only
+// fails to open when none can be reached.
+func TestClient_Open_MetaServerFailover(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	down := freePorts(2)
+	c2 := cloudMeta.NewClient(newConfig())
+	c2.SetMetaServers([]string{down[0], s.HTTPAddr(), down[1]})
+	if err := c2.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer c2.Close()
+
+	// Writes go to the live server too.
+	if _, err := c2.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := newConfig()
+	cfg.OpenMaxAttempts = 2
+	c3 := cloudMeta.NewClient(cfg)
+	c3.SetMetaServers(down)
+	if err := c3.Open(); err == nil {
+		t.Fatal("expected an error opening a client without reachable meta servers")
+	}
+
+	c4 := cloudMeta.NewClient(newConfig())
+	if err := c4.Open(); err != cloudMeta.ErrNoMetaServers {
+		t.Fatalf("unexpected error: got %v, expected %v", err, cloudMeta.ErrNoMetaServers)
+	}
+}
+
+// Ensure joining a cluster whose leader never takes the node on gives up
+// once its context is done, or once the client is closed.
+func TestClient_JoinClusterAsContext(t *testing.T) {
+	t.Parallel()
+
+	snapshot, err := (&cloudMeta.Data{Data: &meta.Data{Index: 1}}).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaderless := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/join" {
+			http.Error(w, "no leader", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(snapshot)
+	}))
+	defer leaderless.Close()
+	addr := strings.TrimPrefix(leaderless.URL, "http://")
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	c := cloudMeta.NewClient(cfg)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := c.JoinClusterAsContext(ctx, addr, "127.0.0.1:8091", "127.0.0.1:8088"); err != context.DeadlineExceeded {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	joined := make(chan error, 1)
+	go func() {
+		_, err := c.JoinClusterAsContext(context.Background(), addr, "127.0.0.1:8091", "127.0.0.1:8088")
+		joined <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	c.Close()
+	select {
+	case err := <-joined:
+		if err != cloudMeta.ErrService {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("join not given up on close")
+	}
+}
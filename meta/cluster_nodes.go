@@ -0,0 +1,86 @@
+package meta
+
+import "time"
+
+// The roles of a ClusterNode.
+const (
+	NodeRoleLeader   = "leader"
+	NodeRoleFollower = "follower"
+	NodeRoleObserver = "observer"
+)
+
+// ClusterNode describes a member of the meta cluster, as seen by the node
+// reporting it.
+type ClusterNode struct {
+	ID       uint64 `json:"id"`
+	TCPAddr  string `json:"tcpAddr"`
+	HTTPAddr string `json:"httpAddr"`
+	Role     string `json:"role"`
+
+	// LastContact is when the reporting node last heard from the node over
+	// raft, or zero if it hasn't. The leader hears from every follower,
+	// while a follower only hears from the leader.
+	LastContact time.Time `json:"lastContact"`
+}
+
+// Nodes returns the meta nodes of the cluster. An observer, which isn't a
+// member, lists itself too.
+func (s *Service) Nodes() ([]ClusterNode, error) {
+	data, err := s.store.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	var leader string
+	if st := s.store.raftStatus(); st != nil {
+		leader = st.Leader
+	}
+	contacts := s.store.raftContacts()
+	self := s.store.raftAddr
+
+	nodes := make([]ClusterNode, 0, len(data.MetaNodes)+1)
+	for _, n := range data.MetaNodes {
+		node := ClusterNode{
+			ID:          n.ID,
+			TCPAddr:     n.TCPHost,
+			HTTPAddr:    n.Host,
+			Role:        NodeRoleFollower,
+			LastContact: contacts[n.TCPHost],
+		}
+		if n.TCPHost == leader {
+			node.Role = NodeRoleLeader
+		}
+		if n.TCPHost == self {
+			node.LastContact = now()
+		}
+		nodes = append(nodes, node)
+	}
+	if s.config.ObserverMode {
+		nodes = append(nodes, ClusterNode{
+			HTTPAddr:    s.remoteAddr(s.httpAddr),
+			Role:        NodeRoleObserver,
+			LastContact: now(),
+		})
+	}
+	return nodes, nil
+}
+
+// DropNode removes the meta node id from the raft peers and the metadata,
+// as when it is dead and won't come back. It refuses to drop the last meta
+// node, and does nothing if the node is already gone. It returns
+// raft.ErrNotLeader on a follower.
+func (s *Service) DropNode(id uint64) error {
+	data, err := s.store.snapshot()
+	if err != nil {
+		return err
+	}
+	if data.MetaNode(id) == nil {
+		return nil
+	} else if len(data.MetaNodes) == 1 {
+		return ErrNodeUnableToDropFinalNode
+	}
+
+	if err := s.store.deleteMetaNode(id); err != nil && err != ErrNodeNotFound {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package meta_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// clusterNodes returns the meta nodes listed by the meta server at addr.
+func clusterNodes(t *testing.T, addr string) []cloudMeta.ClusterNode {
+	resp, err := http.Get("http://" + addr + "/cluster/nodes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status: %s", resp.Status)
+	}
+	var nodes []cloudMeta.ClusterNode
+	if err := json.NewDecoder(resp.Body).Decode(&nodes); err != nil {
+		t.Fatal(err)
+	}
+	return nodes
+}
+
+// dropNode asks the meta server at addr to drop the meta node id, and
+// returns the response status.
+func dropNode(t *testing.T, addr string, id uint64) int {
+	resp, err := http.Post(fmt.Sprintf("http://%s/cluster/nodes/%d/drop", addr, id), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+// Ensure the cluster nodes endpoint lists every meta node with its role,
+// and a dead one is dropped from the cluster, once.
+func TestService_ClusterNodes(t *testing.T) {
+	c := cloudMeta.NewTestCluster(t, 3)
+	defer c.Close()
+	leader := c.Leader(time.Second)
+
+	// Give the leader a heartbeat round to hear from every follower.
+	time.Sleep(500 * time.Millisecond)
+	nodes := clusterNodes(t, leader.HTTPAddr())
+	if len(nodes) != 3 {
+		t.Fatalf("unexpected nodes: %+v", nodes)
+	}
+	var dead *cloudMeta.Service
+	var deadID uint64
+	for _, n := range nodes {
+		if n.LastContact.IsZero() {
+			t.Fatalf("no contact with node %d", n.ID)
+		}
+		switch {
+		case n.TCPAddr == leader.RaftAddr() && n.Role != cloudMeta.NodeRoleLeader:
+			t.Fatalf("leader listed as %s", n.Role)
+		case n.TCPAddr != leader.RaftAddr() && n.Role != cloudMeta.NodeRoleFollower:
+			t.Fatalf("follower listed as %s", n.Role)
+		}
+		for _, s := range c.Services {
+			if s != leader && s.RaftAddr() == n.TCPAddr {
+				dead, deadID = s, n.ID
+			}
+		}
+	}
+
+	if err := dead.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if status := dropNode(t, leader.HTTPAddr(), deadID); status != http.StatusOK {
+		t.Fatalf("unexpected drop status: %d", status)
+	}
+	if nodes := clusterNodes(t, leader.HTTPAddr()); len(nodes) != 2 {
+		t.Fatalf("node not dropped: %+v", nodes)
+	}
+	if status := dropNode(t, leader.HTTPAddr(), deadID); status != http.StatusOK {
+		t.Fatalf("unexpected status dropping a node already gone: %d", status)
+	}
+}
+
+// Ensure the last meta node of a cluster isn't dropped.
+func TestService_DropNode_Last(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	nodes := clusterNodes(t, s.HTTPAddr())
+	if len(nodes) != 1 || nodes[0].Role != cloudMeta.NodeRoleLeader {
+		t.Fatalf("unexpected nodes: %+v", nodes)
+	}
+	if status := dropNode(t, s.HTTPAddr(), nodes[0].ID); status != http.StatusConflict {
+		t.Fatalf("unexpected status dropping the last node: %d", status)
+	}
+	if n := len(c.Data().MetaNodes); n != 1 {
+		t.Fatalf("last node dropped: %d meta nodes", n)
+	}
+}
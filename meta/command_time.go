@@ -0,0 +1,82 @@
+package meta
+
+import (
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/zhexuany/influxcloud/meta/internal"
+)
+
+// stampCommand returns the marshaled command b with the times the leader
+// stamps set to its own clock, as those of clients may be skewed. It
+// returns other commands as they are.
+func (h *handler) stampCommand(b []byte) ([]byte, error) {
+	var cmd internal.Command
+	if err := proto.Unmarshal(b, &cmd); err != nil {
+		return nil, err
+	}
+
+	switch cmd.GetType() {
+	case internal.Command_AcquireRestartLockCommand:
+		// The lock expires by the time it was acquired at.
+		ext, err := proto.GetExtension(&cmd, internal.E_AcquireRestartLockCommand_Command)
+		if err != nil {
+			return nil, err
+		}
+		v := ext.(*internal.AcquireRestartLockCommand)
+		v.Time = proto.Int64(h.now().UnixNano())
+		if err := proto.SetExtension(&cmd, internal.E_AcquireRestartLockCommand_Command, v); err != nil {
+			return nil, err
+		}
+	case internal.Command_DeleteShardGroupCommand:
+		// The shard group is purged by the time it was deleted at.
+		ext, err := proto.GetExtension(&cmd, internal.E_DeleteShardGroupCommand_Command)
+		if err != nil {
+			return nil, err
+		}
+		v := ext.(*internal.DeleteShardGroupCommand)
+		v.DeletedAt = proto.Int64(h.now().UTC().UnixNano())
+		if err := proto.SetExtension(&cmd, internal.E_DeleteShardGroupCommand_Command, v); err != nil {
+			return nil, err
+		}
+	case internal.Command_CreateDataNodeCommand:
+		// A node that records a start, as a data node registering does,
+		// started when the leader took it in.
+		ext, err := proto.GetExtension(&cmd, internal.E_CreateDataNodeCommand_Command)
+		if err != nil {
+			return nil, err
+		}
+		v := ext.(*internal.CreateDataNodeCommand)
+		if v.StartedAt == nil {
+			return b, nil
+		}
+		v.StartedAt = proto.Int64(h.now().UTC().UnixNano())
+		if err := proto.SetExtension(&cmd, internal.E_CreateDataNodeCommand_Command, v); err != nil {
+			return nil, err
+		}
+	case internal.Command_CreateMetaNodeCommand:
+		ext, err := proto.GetExtension(&cmd, internal.E_CreateMetaNodeCommand_Command)
+		if err != nil {
+			return nil, err
+		}
+		v := ext.(*internal.CreateMetaNodeCommand)
+		if v.StartedAt == nil {
+			return b, nil
+		}
+		v.StartedAt = proto.Int64(h.now().UTC().UnixNano())
+		if err := proto.SetExtension(&cmd, internal.E_CreateMetaNodeCommand_Command, v); err != nil {
+			return nil, err
+		}
+	default:
+		return b, nil
+	}
+	return proto.Marshal(&cmd)
+}
+
+// now returns the time by the clock of the service, if any.
+func (h *handler) now() time.Time {
+	if h.s != nil {
+		return h.s.now()
+	}
+	return now()
+}
@@ -0,0 +1,65 @@
+package meta_test
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// Ensure a data node's start time is taken from the clock of the leader, not
+// of the client registering it.
+func TestMetaService_DataNodeStartedAt_LeaderTime(t *testing.T) {
+	t.Parallel()
+
+	started := time.Date(2026, 10, 17, 11, 0, 0, 0, time.UTC)
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	cfg.Clock = &fakeClock{t: started}
+	s := openService(t, cfg)
+	defer s.Close()
+	c := newClient(s)
+	defer c.Close()
+
+	if n, err := c.CreateDataNode("foo:8180", "bar:8281"); err != nil {
+		t.Fatal(err)
+	} else if !n.StartedAt.Equal(started) {
+		t.Fatalf("unexpected start time: %v", n.StartedAt)
+	}
+}
+
+func TestMetaService_DataNodeRestartAdvancesStartedAt(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	n1, err := c.CreateDataNode("foo:8180", "bar:8281")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	// Rejoining with the same address is how a restarted data node registers.
+	n2, err := c.CreateDataNode("foo:8180", "bar:8281")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n1.ID != n2.ID {
+		t.Fatalf("node ID changed on restart: %d != %d", n1.ID, n2.ID)
+	} else if !n2.StartedAt.After(n1.StartedAt) {
+		t.Fatalf("start time did not advance: %v -> %v", n1.StartedAt, n2.StartedAt)
+	}
+
+	nodes, err := c.MetaNodes()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(nodes) != 1 {
+		t.Fatalf("expected 1 meta node, got %d", len(nodes))
+	} else if nodes[0].StartedAt.IsZero() {
+		t.Fatal("expected meta node start time to be set")
+	}
+}
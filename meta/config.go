@@ -1,13 +1,16 @@
 package meta
 
 import (
-	"errors"
+	"compress/gzip"
 	"fmt"
+	"io/ioutil"
 	"net"
+	"net/url"
 	"os"
 	"os/user"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -28,6 +31,11 @@ const (
 	// DefaultHTTPBindAddress is the default address to bind the API to.
 	DefaultHTTPBindAddress = ":8091"
 
+	// DefaultSocketPermissions are the default file permissions of the Unix
+	// domain sockets the node listens on: read and write for the owner and
+	// group only.
+	DefaultSocketPermissions = "0660"
+
 	// DefaultHeartbeatTimeout is the default heartbeat timeout for the store.
 	DefaultHeartbeatTimeout = 1000 * time.Millisecond
 
@@ -40,6 +48,19 @@ const (
 	// DefaultCommitTimeout is the default commit timeout for the store.
 	DefaultCommitTimeout = 50 * time.Millisecond
 
+	// SingleNodeRaftTimeout is the heartbeat and election timeout of a node
+	// in single-node mode, which its leader lease is half of.
+	SingleNodeRaftTimeout = 50 * time.Millisecond
+
+	// DefaultSyncWrites is the default for fsyncing the raft log before a
+	// write is acknowledged.
+	DefaultSyncWrites = true
+
+	// DirMode is the mode the meta directories are created with. They
+	// hold the raft log and snapshots, with the password hashes of the
+	// users, so only their owner may read them.
+	DirMode os.FileMode = 0700
+
 	// DefaultRaftPromotionEnabled is the default for auto promoting a node to a raft node when needed
 	DefaultRaftPromotionEnabled = true
 
@@ -48,25 +69,367 @@ const (
 
 	// DefaultLoggingEnabled determines if log messages are printed for the meta service
 	DefaultLoggingEnabled = true
+
+	// DefaultClientMaxIdleTime is the default time a pooled meta client
+	// connection may sit idle before it is closed.
+	DefaultClientMaxIdleTime = 60 * time.Second
+
+	// DefaultGCPercent is the default GC percentage. Zero leaves the runtime
+	// setting (GOGC) untouched.
+	DefaultGCPercent = 0
+
+	// DefaultGzipLevel is the default compression level of HTTP API responses.
+	DefaultGzipLevel = gzip.DefaultCompression
+
+	// DefaultLeadershipTransferTimeout is the default time a leader shutting
+	// down waits for another meta node to take over.
+	DefaultLeadershipTransferTimeout = 10 * time.Second
+
+	// DefaultShutdownTimeout is the default time each component is given to
+	// close when the server shuts down.
+	DefaultShutdownTimeout = 30 * time.Second
+
+	// DefaultHTTPSCertificateReloadInterval is the default time between
+	// checks of the HTTPS certificate file for a new certificate.
+	DefaultHTTPSCertificateReloadInterval = time.Minute
+
+	// DefaultStartupTimeout is the default time the server waits on startup
+	// for the metadata from the meta servers.
+	DefaultStartupTimeout = 5 * time.Minute
+
+	// DefaultMuxHandshakeTimeout is the default time a connection to
+	// bind-address has to send its mux header byte.
+	DefaultMuxHandshakeTimeout = 30 * time.Second
+
+	// DefaultDiscoveryInterval is the default time between resolutions of
+	// the dns+srv join peers.
+	DefaultDiscoveryInterval = 30 * time.Second
+
+	// DefaultOpenMaxAttempts is the default number of times the meta client
+	// tries the meta servers while opening.
+	DefaultOpenMaxAttempts = 20
+
+	// DefaultOpenMaxInterval is the default longest wait between attempts
+	// to open the meta client.
+	DefaultOpenMaxInterval = 10 * time.Second
+
+	// DefaultNodeMaxAge is the default time after which node.json must be
+	// validated against the cluster again.
+	DefaultNodeMaxAge = 7 * 24 * time.Hour
+
+	// DefaultMetadataVersionTolerance is the default number of metadata
+	// versions a node may be apart from its cluster, enough to roll through
+	// an upgrade one release at a time.
+	DefaultMetadataVersionTolerance = 1
+
+	// DefaultDebugBindAddress is the default address of the debug listener.
+	// It is empty, leaving the listener disabled.
+	DefaultDebugBindAddress = ""
+
+	// DefaultShardGroupTargetSize is the default shard size auto-tuned
+	// retention policies aim for.
+	DefaultShardGroupTargetSize = 1 << 30
+
+	// DefaultShardGroupMinDuration and DefaultShardGroupMaxDuration bound
+	// auto-tuned shard group durations by default.
+	DefaultShardGroupMinDuration = time.Hour
+	DefaultShardGroupMaxDuration = 7 * 24 * time.Hour
+
+	// DefaultOrphanCheckInterval is how often the leader looks for orphaned
+	// metadata by default.
+	DefaultOrphanCheckInterval = 10 * time.Minute
+
+	// DefaultShardGroupAutoTuneInterval is how often the leader re-tunes
+	// shard group durations by default.
+	DefaultShardGroupAutoTuneInterval = time.Hour
+
+	// DefaultShardPrecreationCheckInterval and
+	// DefaultShardPrecreationAdvancePeriod are how often the leader
+	// precreates shard groups, and how long before the last one ends, by
+	// default.
+	DefaultShardPrecreationCheckInterval = 10 * time.Minute
+	DefaultShardPrecreationAdvancePeriod = 30 * time.Minute
+
+	// DefaultShardGroupReapInterval is how often the leader purges expired
+	// pending deletions by default.
+	DefaultShardGroupReapInterval = 30 * time.Minute
+
+	// DefaultDataNodeHeartbeatCheckInterval is how often the leader checks
+	// the heartbeats of data nodes by default.
+	DefaultDataNodeHeartbeatCheckInterval = 10 * time.Second
+
+	// DefaultLeaderWarmTimeout is the default bound on warming up after
+	// gaining leadership.
+	DefaultLeaderWarmTimeout = 5 * time.Second
+
+	// DefaultLeaderDrainTimeout is the default time the connections that
+	// carried writes to a node losing leadership have to finish their
+	// requests.
+	DefaultLeaderDrainTimeout = 5 * time.Second
+
+	// DefaultMaxRequestTimeout is the default cap on the timeout clients
+	// request with the X-Meta-Timeout header.
+	DefaultMaxRequestTimeout = time.Minute
+
+	// DefaultShardGroupQuotaNearRatio is the default share of a database's
+	// shard group quota past which the near quota hook is called.
+	DefaultShardGroupQuotaNearRatio = 0.9
+
+	// DefaultMaxSnapshotSize is the default limit on snapshots the client
+	// reads into a single buffer.
+	DefaultMaxSnapshotSize = 512 << 20
+
+	// DefaultMaxRaftMessageSize is the default limit on the commands a meta
+	// node proposes to raft.
+	DefaultMaxRaftMessageSize = 64 << 20
+
+	// DefaultMaxConcurrentSnapshots is the default number of snapshots a
+	// meta node sends at once.
+	DefaultMaxConcurrentSnapshots = 4
+
+	// DefaultVerifySnapshots is whether raft snapshots are checked against
+	// their checksum when loaded by default.
+	DefaultVerifySnapshots = true
+
+	// DefaultDataNodeLivenessTimeout is how long a data node may go without
+	// a heartbeat before it is considered down.
+	DefaultDataNodeLivenessTimeout = 30 * time.Second
+
+	// DefaultSnapshotStreaming is whether the client streams snapshots by
+	// default.
+	DefaultSnapshotStreaming = true
+
+	// DefaultQuorumLossTimeout is the default time a write waits for a
+	// quorum before failing, when failing fast.
+	DefaultQuorumLossTimeout = 5 * time.Second
+
+	// DefaultWriteDurabilityTimeout is the default time a write waits for
+	// the durability it was made with, once raft has committed it.
+	DefaultWriteDurabilityTimeout = 10 * time.Second
+
+	// DefaultLogTailMaxTailers is the default number of clients that may
+	// tail the log at once.
+	DefaultLogTailMaxTailers = 2
+
+	// DefaultDrainSettleTime is the default time writes to a drained
+	// database are given to finish, a little over the data nodes' write
+	// timeout.
+	DefaultDrainSettleTime = 10 * time.Second
+)
+
+// What writes do while the meta cluster has no quorum, as set by
+// Config.QuorumLossWrites.
+const (
+	// QuorumLossFailFast fails writes with ErrQuorumLost once they have
+	// waited QuorumLossTimeout for a quorum.
+	QuorumLossFailFast = "fail-fast"
+
+	// QuorumLossBlock retries writes until quorum returns or the client is
+	// closed.
+	QuorumLossBlock = "block"
+)
+
+// What CreateDatabase does when the database already exists, including
+// when another client created it at the same time, as set by
+// Config.DuplicateDatabase.
+const (
+	// DuplicateDatabaseIgnore makes creating an existing database a no-op
+	// that returns the existing database.
+	DuplicateDatabaseIgnore = "ignore"
+
+	// DuplicateDatabaseError fails creating an existing database with
+	// ErrDatabaseExists.
+	DuplicateDatabaseError = "error"
+)
+
+// The durability a metadata write is acknowledged with, as set by
+// Config.WriteDurability or per request with the X-Meta-Durability header.
+const (
+	// WriteDurabilityQuorum acknowledges a write once raft has committed
+	// it, that is once it is fsynced to the raft logs of a majority of the
+	// meta nodes.
+	WriteDurabilityQuorum = "quorum"
+
+	// WriteDurabilityAll acknowledges a write only once it is fsynced to
+	// the raft log of every meta node.
+	WriteDurabilityAll = "all"
+)
+
+// What a meta node does on startup when the addresses it advertises changed
+// since it last ran, as set by Config.AddressChangePolicy.
+const (
+	// AddressChangeUpdate asks the leader to update the node's raft
+	// membership and metadata to the new addresses, and warns if it can't.
+	AddressChangeUpdate = "update"
+
+	// AddressChangeWarn only warns, leaving the update to an operator.
+	AddressChangeWarn = "warn"
+
+	// AddressChangeFail refuses to start.
+	AddressChangeFail = "fail"
+)
+
+// The formats a meta node can log in, as set by Config.LogFormat.
+const (
+	// LogFormatText logs free-text lines, as the stdlib log package does.
+	LogFormatText = "text"
+
+	// LogFormatJSON logs each entry as a JSON object on its own line.
+	LogFormatJSON = "json"
+
+	// LogFormatLogfmt logs each entry as a line of key=value pairs.
+	LogFormatLogfmt = "logfmt"
 )
 
 // Config represents the meta configuration.
 type Config struct {
 	Enabled bool   `toml:"enabled"`
 	Dir     string `toml:"dir"`
+
+	// RaftDir is where the raft log and snapshots are kept, for example on
+	// a dedicated fast disk. It defaults to Dir, which always holds
+	// node.json.
+	RaftDir string `toml:"raft-dir"`
+
 	// RemoteHostname is the hostname portion to use when registering meta node
 	// addresses.  This hostname must be resolvable from other nodes.
+	//
+	// Deprecated: as the meta server the client talks to, it is only used
+	// when RemoteHostnames is empty.
 	RemoteHostname string `toml:"-"`
 
+	// RemoteHostnames are the meta servers, as host:port, that the meta
+	// client talks to. An entry may hold several comma separated servers.
+	// The client fails over between them when one can't be reached.
+	RemoteHostnames []string `toml:"remote-hostnames"`
+
 	// this is deprecated. Should use the address from run/config.go
 	BindAddress string `toml:"bind-address"`
 
-	// HTTPBindAddress is the bind address for the metaservice HTTP API
-	HTTPBindAddress  string `toml:"http-bind-address"`
+	// BindInterface, if set, binds Raft to the address of the named network
+	// interface instead of the host part of BindAddress.
+	BindInterface string `toml:"bind-interface"`
+
+	// HTTPBindAddress is the bind address for the metaservice HTTP API. A
+	// unix:// prefix serves it on the Unix domain socket at the path that
+	// follows instead of a TCP port, as for a sidecar only reachable on its
+	// host. The socket address is what the node registers, so it suits a
+	// meta node whose clients all run on the same host.
+	HTTPBindAddress string `toml:"http-bind-address"`
+
+	// HTTPBindInterface, if set, binds the HTTP API to the address of the
+	// named network interface instead of the host part of HTTPBindAddress.
+	HTTPBindInterface string `toml:"http-bind-interface"`
+
+	// DebugBindAddress, if set, serves /metrics, /debug/*, /health and /ready
+	// on a separate plain HTTP listener that only binds to a loopback
+	// address. /metrics and the debug endpoints are then no longer served on
+	// the HTTP API. A missing host binds to 127.0.0.1.
+	DebugBindAddress string `toml:"debug-bind-address"`
+
+	// GRPCBindAddress, if set, serves the gRPC control API on a listener of
+	// its own: the status of the cluster, adding and removing nodes, and
+	// transferring leadership. It is served over TLS if HTTPS is enabled.
+	GRPCBindAddress string `toml:"grpc-bind-address"`
+
+	// LogTailToken, if set, enables /debug/log/tail, which streams the
+	// meta service's log to clients sending it as a bearer token.
+	// LogTailMaxTailers bounds how many clients tail the log at once.
+	LogTailToken      string `toml:"log-tail-token"`
+	LogTailMaxTailers int    `toml:"log-tail-max-tailers"`
+
+	// PprofBindAddress, if set, serves the net/http/pprof handlers under
+	// /debug/pprof/ on a listener of their own, for profiling a live node.
+	// It should not be reachable from untrusted networks. Like
+	// http-bind-address, it may be a unix:// socket.
+	PprofBindAddress string `toml:"pprof-bind-address"`
+
+	// SocketPermissions are the octal file permissions of the Unix domain
+	// sockets the HTTP API and pprof listen on, if given unix:// addresses.
+	SocketPermissions string `toml:"socket-permissions"`
+
 	HTTPSEnabled     bool   `toml:"https-enabled"`
 	HTTPSCertificate string `toml:"https-certificate"`
-	// JoinPeers if specified gives other metastore servers to join this server to the cluster
-	JoinPeers            []string      `toml:"-"`
+
+	// HTTPSCertificateReloadInterval is how often the server checks the
+	// https-certificate and internal-ca files, or the SecretProvider, for a
+	// new certificate or certificate authorities, as when they are renewed,
+	// and reloads them without a restart. Zero never reloads them.
+	HTTPSCertificateReloadInterval toml.Duration `toml:"https-certificate-reload-interval"`
+
+	// InternalCert and InternalKey, if set, are the PEM files of the
+	// certificate and key the node presents to the other meta nodes. Raft
+	// traffic between meta nodes is then sent over TLS, verified against
+	// the certificate authorities in InternalCA, and the meta client
+	// presents the certificate to meta servers asking for one and trusts
+	// HTTPS certificates InternalCA signed.
+	InternalCA   string `toml:"internal-ca"`
+	InternalCert string `toml:"internal-cert"`
+	InternalKey  string `toml:"internal-key"`
+
+	// RequireClientCert, if set, rejects raft connections from meta nodes
+	// that don't present a certificate signed by InternalCA.
+	RequireClientCert bool `toml:"require-client-cert"`
+
+	// SecretProvider, if set, supplies the HTTPS certificate and the
+	// certificate authorities of InternalCA in place of their files.
+	SecretProvider SecretProvider `toml:"-"`
+
+	// Authorizer, if set, decides which operations of the HTTP API each
+	// principal may carry out. Everything is allowed if it isn't set.
+	Authorizer Authorizer `toml:"-"`
+
+	// AuthToken, if set, is a shared token requests to the HTTP API must
+	// carry as "Authorization: Bearer <token>", or they are answered 401
+	// Unauthorized. The meta client sends it with every request. A request
+	// with a client certificate verified against InternalCA needs none.
+	AuthToken string `toml:"auth-token"`
+
+	// AuthBasic, if set, also authenticates requests carrying the name and
+	// password of an admin user of the metadata with HTTP basic auth. The
+	// user is the principal of the request for the Authorizer.
+	AuthBasic bool `toml:"auth-basic"`
+
+	// AuthExempt are the handlers, by their handler label in the metrics,
+	// served without authentication, such as health and metrics for probes
+	// and scrapers.
+	AuthExempt []string `toml:"auth-exempt"`
+
+	// JoinPeers if specified gives other metastore servers to join this server to the cluster.
+	// A peer given as a dns+srv://_meta._tcp.example.com URL stands for the
+	// meta servers its SRV records name, resolved again every
+	// DiscoveryInterval.
+	JoinPeers []string `toml:"-"`
+
+	// DiscoveryInterval is how often the dns+srv join peers are resolved.
+	DiscoveryInterval toml.Duration `toml:"discovery-interval"`
+
+	// SRVResolver, if set, looks up the SRV records of dns+srv join peers in
+	// place of the system resolver.
+	SRVResolver SRVResolver `toml:"-"`
+
+	// JoinAddress, if set, is a meta server, as host:port, that a fresh node
+	// joins the cluster through when it starts. A node whose node.json
+	// already holds an ID ignores it. A node listening on ports assigned
+	// with a port of 0 joins once it knows them.
+	JoinAddress string `toml:"join-address"`
+
+	// ObserverMode makes the node an observer: it takes no part in raft,
+	// never votes or leads, and follows the metadata of the meta servers
+	// in remote-hostnames, by long polling them over HTTP, to serve local
+	// reads. Writes are redirected to the leader.
+	ObserverMode bool `toml:"observer-mode"`
+
+	// SingleNode runs the node as a cluster of its own, for development and
+	// tests: it takes leadership within SingleNodeRaftTimeout in place of
+	// the raft timeouts, so Open returns a ready meta service at once. It
+	// is not for production. Those timeouts are too short for raft over a
+	// network, so the node only uses them while it is the cluster's sole
+	// meta node: once another joins it, with join-address or JoinCluster,
+	// it restarts raft with the configured timeouts, and it opens with them
+	// if it has been joined before.
+	SingleNode bool `toml:"single-node"`
+
 	RetentionAutoCreate  bool          `toml:"retention-autocreate"`
 	ElectionTimeout      toml.Duration `toml:"election-timeout"`
 	HeartbeatTimeout     toml.Duration `toml:"heartbeat-timeout"`
@@ -78,6 +441,286 @@ type Config struct {
 	PprofEnabled         bool          `toml:"pprof-enabled"`
 
 	LeaseDuration toml.Duration `toml:"lease-duration"`
+
+	// LogFormat is how the node logs: LogFormatText, the default, for
+	// free-text lines, or LogFormatJSON or LogFormatLogfmt for one
+	// structured entry per line with level, ts, msg, service and node_id
+	// fields.
+	LogFormat string `toml:"log-format"`
+
+	// ClientMaxIdleTime is how long a pooled meta client connection may be
+	// idle before it is closed and a fresh one dialed on the next request.
+	ClientMaxIdleTime toml.Duration `toml:"client-max-idle-time"`
+
+	// GCPercent overrides the garbage collection target percentage at startup.
+	// Zero keeps the value from GOGC; a negative value disables collection.
+	GCPercent int `toml:"gc-percent"`
+
+	// GzipLevel is the compression level used for gzipped HTTP API responses,
+	// from -2 (huffman only) through 9 (best compression). Lower levels trade
+	// bandwidth for leader CPU.
+	GzipLevel int `toml:"gzip-level"`
+
+	// BootstrapFile is a toml file describing the nodes and databases the
+	// cluster starts with. It is applied on first bootstrap only, through the
+	// leader, so it needn't be set on the node that wins the first election.
+	BootstrapFile string `toml:"bootstrap-file"`
+
+	// ShutdownTimeout is how long each component is given to close on
+	// shutdown before it is abandoned and the next one is closed, unless the
+	// component has a timeout of its own below. Zero waits forever.
+	ShutdownTimeout toml.Duration `toml:"shutdown-timeout"`
+
+	// ListenerShutdownTimeout, PProfShutdownTimeout, ClientShutdownTimeout
+	// and ServiceShutdownTimeout are how long the listener, the pprof server,
+	// the meta client and the meta service are each given to close. Zero
+	// uses shutdown-timeout.
+	ListenerShutdownTimeout toml.Duration `toml:"listener-shutdown-timeout"`
+	PProfShutdownTimeout    toml.Duration `toml:"pprof-shutdown-timeout"`
+	ClientShutdownTimeout   toml.Duration `toml:"client-shutdown-timeout"`
+	ServiceShutdownTimeout  toml.Duration `toml:"service-shutdown-timeout"`
+
+	// StartupTimeout is how long the server waits on startup for the meta
+	// client to get the metadata from the meta servers, and for a fresh node
+	// to join the cluster, before it fails to open. Zero waits forever.
+	StartupTimeout toml.Duration `toml:"startup-timeout"`
+
+	// MuxHandshakeTimeout is how long a connection to bind-address has to
+	// send the header byte that routes it to raft or another service. One
+	// that doesn't, or sends an unknown one, is closed, logged and counted
+	// on /metrics, as port scanners and misconfigured clients do.
+	MuxHandshakeTimeout toml.Duration `toml:"mux-handshake-timeout"`
+
+	// OpenMaxAttempts is how many times the meta client tries the meta
+	// servers while opening, as they may not be up or have a leader yet
+	// when the cluster starts. It backs off exponentially between attempts,
+	// up to OpenMaxInterval. Zero retries until startup-timeout.
+	OpenMaxAttempts int           `toml:"open-max-attempts"`
+	OpenMaxInterval toml.Duration `toml:"open-max-interval"`
+
+	// NodeMaxAge is how long ago node.json may have last been validated
+	// against the cluster for the server to start without validating it
+	// again. One restored from an old backup may name a node removed since,
+	// which the server then refuses to start as. Zero never validates it.
+	NodeMaxAge toml.Duration `toml:"node-max-age"`
+
+	// AddressChangePolicy is what the node does on startup when the HTTP or
+	// raft address it advertises changed since it last ran, as when its
+	// container got a new IP, and its peers can't reach it until the raft
+	// membership is updated: AddressChangeUpdate, the default,
+	// AddressChangeWarn or AddressChangeFail.
+	AddressChangePolicy string `toml:"address-change-policy"`
+
+	// MaintenanceWindows are the recurring windows during which the node
+	// refuses writes or avoids leading the cluster, as for maintenance
+	// automation. The windows of different meta nodes shouldn't overlap in
+	// avoiding leadership, lest the cluster have no leader.
+	MaintenanceWindows []MaintenanceWindow `toml:"maintenance-window"`
+
+	// Clock, if set, tells the time by which the maintenance windows open
+	// and close, and which the node stamps the commands it leads with, in
+	// place of the system clock.
+	Clock Clock `toml:"-"`
+
+	// MetadataVersionTolerance is how many metadata versions the node may be
+	// apart from the cluster. A node further apart refuses to join the
+	// cluster or start, rather than read or write metadata it doesn't
+	// understand.
+	MetadataVersionTolerance int `toml:"metadata-version-tolerance"`
+
+	// LeadershipTransferTimeout is how long a leader shutting down waits for
+	// a follower to take over before it closes anyway. Zero closes without
+	// handing leadership off.
+	LeadershipTransferTimeout toml.Duration `toml:"leadership-transfer-timeout"`
+
+	// LeaderWarmTimeout bounds how long a node that gains leadership warms
+	// up before it reports ready and starts its leader tasks. Zero skips
+	// warming up.
+	LeaderWarmTimeout toml.Duration `toml:"leader-warm-timeout"`
+
+	// LeaderDrainTimeout is how long the HTTP API connections that carried
+	// writes to a node losing leadership have to finish the request in
+	// progress before they are closed, so their clients find the new
+	// leader. Idle ones are closed at once. Zero leaves them open.
+	LeaderDrainTimeout toml.Duration `toml:"leader-drain-timeout"`
+
+	// LeaderChangeWebhook, if set, is an http or https URL every node POSTs
+	// a LeaderChange to as JSON when it sees the leader of the cluster
+	// change. Delivery is best effort: a failed POST is retried once, then
+	// logged and dropped.
+	LeaderChangeWebhook string `toml:"leader-change-webhook"`
+
+	// DataNodeLivenessTimeout is how long a data node may go without a
+	// heartbeat before its shard replicas are reported down.
+	DataNodeLivenessTimeout toml.Duration `toml:"data-node-liveness-timeout"`
+
+	// StartupDelayMin and StartupDelayMax bound a random delay before the
+	// node opens raft and joins its peers, so that nodes restarted together
+	// don't all hit the cluster at once. Zero for both skips the delay.
+	StartupDelayMin toml.Duration `toml:"startup-delay-min"`
+	StartupDelayMax toml.Duration `toml:"startup-delay-max"`
+
+	// MaxRequestTimeout caps the per request timeout clients set with the
+	// X-Meta-Timeout header.
+	MaxRequestTimeout toml.Duration `toml:"max-request-timeout"`
+
+	// QuorumLossWrites is what writes do while the cluster has no quorum:
+	// QuorumLossFailFast or QuorumLossBlock. It applies both to the client
+	// and to the leader waiting on raft to commit a write.
+	QuorumLossWrites  string        `toml:"quorum-loss-writes"`
+	QuorumLossTimeout toml.Duration `toml:"quorum-loss-timeout"`
+
+	// WriteDurability is the durability writes are acknowledged with:
+	// WriteDurabilityQuorum or WriteDurabilityAll. The leader applies it
+	// to writes without the X-Meta-Durability header, and the client sends
+	// it with its writes. A write that isn't as durable as asked within
+	// WriteDurabilityTimeout fails with ErrWriteNotDurable, though it is
+	// committed.
+	WriteDurability        string        `toml:"write-durability"`
+	WriteDurabilityTimeout toml.Duration `toml:"write-durability-timeout"`
+
+	// SyncWrites, if set, fsyncs the raft log before a write is
+	// acknowledged. Unset, writes are faster, but an acknowledged write
+	// only reaches the disk of a meta node once its OS flushes the page
+	// cache, typically within 30 seconds: it is lost if a quorum of meta
+	// nodes lose power or their OS crashes within that window, though a
+	// crash of the process alone loses nothing. WriteDurabilityAll then
+	// only waits for every meta node to have a write, not to have synced
+	// it.
+	SyncWrites bool `toml:"sync-writes"`
+
+	// DrainSettleTime is how long DrainDatabase waits, once writes to the
+	// database are blocked, for the writes already under way to finish.
+	DrainSettleTime toml.Duration `toml:"drain-settle-time"`
+
+	// DuplicateDatabase is what CreateDatabase does when the database
+	// already exists: DuplicateDatabaseIgnore or DuplicateDatabaseError.
+	// Of several clients racing to create the same database, exactly one
+	// creates it either way.
+	DuplicateDatabase string `toml:"duplicate-database"`
+
+	// ShardGroupQuotaNearRatio is the share of a database's shard group
+	// quota past which new shard groups call the hook registered with
+	// OnShardGroupQuotaNear.
+	ShardGroupQuotaNearRatio float64 `toml:"shard-group-quota-near-ratio"`
+
+	// SnapshotStreaming makes the client fetch snapshots as a stream it
+	// decodes one database at a time, instead of in a single buffer.
+	SnapshotStreaming bool `toml:"snapshot-streaming"`
+
+	// MaxSnapshotSize is the largest snapshot, in bytes, the client reads
+	// into a single buffer, and the largest single database it reads when
+	// streaming. Zero means no limit.
+	MaxSnapshotSize int64 `toml:"max-snapshot-size"`
+
+	// MaxRaftMessageSize is the largest command, in bytes, the node proposes
+	// to raft. Larger ones, such as a big batch of metadata changes, are
+	// rejected before they reach the raft log, and raft connections sending
+	// one are closed before it is read in. Zero means no limit.
+	MaxRaftMessageSize int64 `toml:"max-raft-message-size"`
+
+	// MaxConcurrentSnapshots is the number of snapshots a meta node sends
+	// to clients at once. Further requests wait in line for their turn.
+	MaxConcurrentSnapshots int `toml:"max-concurrent-snapshots"`
+
+	// VerifySnapshots makes the node check the checksum of each raft
+	// snapshot it loads, and refuse ones that don't match.
+	VerifySnapshots bool `toml:"verify-snapshots"`
+
+	// IdempotencyCacheSize is the number of command responses kept so that a
+	// retried request with the same idempotency key isn't applied twice. The
+	// least recently used response is evicted past it. Responses are kept in
+	// memory by the node that applied the command, and not replicated through
+	// raft, so a retry reaching a new leader after a failover, or the same
+	// node after a restart, is applied again.
+	IdempotencyCacheSize int `toml:"idempotency-cache-size"`
+
+	// IdempotencyCacheTTL is how long a command response is kept. Zero keeps
+	// responses until they are evicted.
+	IdempotencyCacheTTL toml.Duration `toml:"idempotency-cache-ttl"`
+
+	// IdempotencyCacheMaxBytes is the most the cached idempotency keys and
+	// command responses may add up to. The least recently used response is
+	// evicted past it. Zero doesn't limit it.
+	IdempotencyCacheMaxBytes int64 `toml:"idempotency-cache-max-bytes"`
+
+	// IdempotencyCacheCompactInterval is how often expired command responses
+	// are dropped, freeing their memory before they would be evicted. Zero
+	// never compacts the cache.
+	IdempotencyCacheCompactInterval toml.Duration `toml:"idempotency-cache-compact-interval"`
+
+	// HTTPRateLimit is how many requests a second each remote IP may make
+	// to the HTTP API, after a burst of HTTPRateLimitBurst, before it is
+	// answered 429 Too Many Requests. Zero doesn't limit requests. The meta
+	// and data nodes of the cluster, and the IPs and CIDR networks in
+	// HTTPRateLimitAllowlist, are never limited.
+	HTTPRateLimit          float64  `toml:"http-rate-limit"`
+	HTTPRateLimitBurst     int      `toml:"http-rate-limit-burst"`
+	HTTPRateLimitAllowlist []string `toml:"http-rate-limit-allowlist"`
+
+	// MetricsNodeLabels adds node_id, node_addr and cluster_name labels to
+	// every exported metric, so that several meta nodes can share one
+	// Prometheus. node_id is MetricsNodeID, or the node's ID if unset, and
+	// node_addr is the node's remote HTTP address.
+	MetricsNodeLabels  bool   `toml:"metrics-node-labels"`
+	MetricsNodeID      string `toml:"metrics-node-id"`
+	MetricsClusterName string `toml:"metrics-cluster-name"`
+
+	// ShardGroupAutoTune lists the retention policies, as "db.rp", whose
+	// shard group duration is tuned so their shards approach
+	// ShardGroupTargetSize, based on the shard sizes data nodes report. Only
+	// shard groups created after a change are affected.
+	ShardGroupAutoTune         []string      `toml:"shard-group-auto-tune"`
+	ShardGroupTargetSize       int64         `toml:"shard-group-target-size"`
+	ShardGroupMinDuration      toml.Duration `toml:"shard-group-min-duration"`
+	ShardGroupMaxDuration      toml.Duration `toml:"shard-group-max-duration"`
+	ShardGroupAutoTuneInterval toml.Duration `toml:"shard-group-auto-tune-interval"`
+
+	// OrphanCheckInterval is how often the leader looks for orphaned
+	// metadata, left referring to shards or databases that were dropped,
+	// and logs it. Zero disables the check. The orphans are only purged if
+	// PurgeOrphans is set.
+	OrphanCheckInterval toml.Duration `toml:"orphan-check-interval"`
+	PurgeOrphans        bool          `toml:"purge-orphans"`
+
+	// ShardPrecreationCheckInterval is how often the leader creates the next
+	// shard group of every retention policy whose last one ends within
+	// ShardPrecreationAdvancePeriod, so writes don't wait on raft to create
+	// it. Zero disables precreation.
+	ShardPrecreationCheckInterval toml.Duration `toml:"shard-precreation-check-interval"`
+	ShardPrecreationAdvancePeriod toml.Duration `toml:"shard-precreation-advance-period"`
+
+	// ShardGroupReapInterval is how often the leader purges the shard groups
+	// whose pending deletion has expired from the metadata. Zero disables
+	// purging.
+	ShardGroupReapInterval toml.Duration `toml:"shard-group-reap-interval"`
+
+	// DataNodeHeartbeatCheckInterval is how often the leader logs the data
+	// nodes that stopped or resumed sending heartbeats. A node that never
+	// sent one isn't logged, so nothing is until data nodes set
+	// cluster.meta-servers. Zero disables the check.
+	DataNodeHeartbeatCheckInterval toml.Duration `toml:"data-node-heartbeat-check-interval"`
+
+	// sources records where each value that isn't a default came from,
+	// keyed by toml name.
+	sources map[string]ConfigSource
+}
+
+// ConfigSource is where a config value came from.
+type ConfigSource string
+
+// Config sources, from lowest to highest precedence.
+const (
+	ConfigSourceDefault ConfigSource = "default"
+	ConfigSourceFile    ConfigSource = "file"
+	ConfigSourceEnv     ConfigSource = "env"
+	ConfigSourceFlag    ConfigSource = "flag"
+)
+
+// ConfigValue is a config value along with where it came from.
+type ConfigValue struct {
+	Value  interface{}  `json:"value"`
+	Source ConfigSource `json:"source"`
 }
 
 // NewConfig builds a new configuration with default values.
@@ -86,6 +729,7 @@ func NewConfig() *Config {
 		Enabled:              true, // enabled by default
 		BindAddress:          DefaultRaftBindAddress,
 		HTTPBindAddress:      DefaultHTTPBindAddress,
+		SocketPermissions:    DefaultSocketPermissions,
 		RetentionAutoCreate:  true,
 		ElectionTimeout:      toml.Duration(DefaultElectionTimeout),
 		HeartbeatTimeout:     toml.Duration(DefaultHeartbeatTimeout),
@@ -93,8 +737,65 @@ func NewConfig() *Config {
 		CommitTimeout:        toml.Duration(DefaultCommitTimeout),
 		RaftPromotionEnabled: DefaultRaftPromotionEnabled,
 		LeaseDuration:        toml.Duration(DefaultLeaseDuration),
+		ClientMaxIdleTime:    toml.Duration(DefaultClientMaxIdleTime),
 		LoggingEnabled:       DefaultLoggingEnabled,
+		LogFormat:            LogFormatText,
 		JoinPeers:            []string{},
+		GCPercent:            DefaultGCPercent,
+		GzipLevel:            DefaultGzipLevel,
+		ShutdownTimeout:      toml.Duration(DefaultShutdownTimeout),
+		StartupTimeout:       toml.Duration(DefaultStartupTimeout),
+		MuxHandshakeTimeout:  toml.Duration(DefaultMuxHandshakeTimeout),
+		DiscoveryInterval:    toml.Duration(DefaultDiscoveryInterval),
+		OpenMaxAttempts:      DefaultOpenMaxAttempts,
+		OpenMaxInterval:      toml.Duration(DefaultOpenMaxInterval),
+		DebugBindAddress:     DefaultDebugBindAddress,
+		LeaderWarmTimeout:    toml.Duration(DefaultLeaderWarmTimeout),
+		LeaderDrainTimeout:   toml.Duration(DefaultLeaderDrainTimeout),
+		MaxRequestTimeout:    toml.Duration(DefaultMaxRequestTimeout),
+		IdempotencyCacheSize: DefaultIdempotencyCacheSize,
+		IdempotencyCacheTTL:  toml.Duration(DefaultIdempotencyCacheTTL),
+		QuorumLossWrites:     QuorumLossFailFast,
+		QuorumLossTimeout:    toml.Duration(DefaultQuorumLossTimeout),
+		WriteDurability:      WriteDurabilityQuorum,
+		SyncWrites:           DefaultSyncWrites,
+		DrainSettleTime:      toml.Duration(DefaultDrainSettleTime),
+		DuplicateDatabase:    DuplicateDatabaseIgnore,
+		LogTailMaxTailers:    DefaultLogTailMaxTailers,
+
+		LeadershipTransferTimeout: toml.Duration(DefaultLeadershipTransferTimeout),
+		DataNodeLivenessTimeout:   toml.Duration(DefaultDataNodeLivenessTimeout),
+		WriteDurabilityTimeout:    toml.Duration(DefaultWriteDurabilityTimeout),
+		ShardGroupQuotaNearRatio:  DefaultShardGroupQuotaNearRatio,
+		SnapshotStreaming:         DefaultSnapshotStreaming,
+		MaxSnapshotSize:           DefaultMaxSnapshotSize,
+		MaxRaftMessageSize:        DefaultMaxRaftMessageSize,
+		MaxConcurrentSnapshots:    DefaultMaxConcurrentSnapshots,
+		VerifySnapshots:           DefaultVerifySnapshots,
+
+		ShardGroupTargetSize:       DefaultShardGroupTargetSize,
+		ShardGroupMinDuration:      toml.Duration(DefaultShardGroupMinDuration),
+		ShardGroupMaxDuration:      toml.Duration(DefaultShardGroupMaxDuration),
+		ShardGroupAutoTuneInterval: toml.Duration(DefaultShardGroupAutoTuneInterval),
+
+		OrphanCheckInterval: toml.Duration(DefaultOrphanCheckInterval),
+
+		ShardPrecreationCheckInterval:  toml.Duration(DefaultShardPrecreationCheckInterval),
+		ShardPrecreationAdvancePeriod:  toml.Duration(DefaultShardPrecreationAdvancePeriod),
+		ShardGroupReapInterval:         toml.Duration(DefaultShardGroupReapInterval),
+		DataNodeHeartbeatCheckInterval: toml.Duration(DefaultDataNodeHeartbeatCheckInterval),
+
+		HTTPSCertificateReloadInterval: toml.Duration(DefaultHTTPSCertificateReloadInterval),
+
+		IdempotencyCacheMaxBytes:        DefaultIdempotencyCacheMaxBytes,
+		IdempotencyCacheCompactInterval: toml.Duration(DefaultIdempotencyCacheCompactInterval),
+
+		HTTPRateLimitBurst: DefaultHTTPRateLimitBurst,
+		AuthExempt:         append([]string(nil), DefaultAuthExempt...),
+
+		NodeMaxAge:               toml.Duration(DefaultNodeMaxAge),
+		AddressChangePolicy:      AddressChangeUpdate,
+		MetadataVersionTolerance: DefaultMetadataVersionTolerance,
 	}
 	return cfg
 }
@@ -119,20 +820,434 @@ func NewDemoConfig() *Config {
 	return c
 }
 
-// Validate validates a config.
+// Validate validates a config. Every invalid field is reported in the
+// returned *ValidationError, keyed by its toml name. Besides the values it
+// checks that the directories are writable and the TLS files exist, but
+// opens no listener, so a config can be checked before it is rolled out.
 func (c *Config) Validate() error {
+	var v ValidationError
 	if c.Dir == "" {
-		return errors.New("Meta.Dir must be specified")
+		v.add("dir", "must be specified")
+	} else if err := checkWritableDir(c.Dir); err != nil {
+		v.add("dir", "%s", err)
 	}
-	return nil
+	if c.RaftDir != "" {
+		if err := checkWritableDir(c.RaftDir); err != nil {
+			v.add("raft-dir", "%s", err)
+		}
+	}
+	if c.BindAddress == "" {
+		v.add("bind-address", "must be specified")
+	} else if _, _, err := net.SplitHostPort(c.BindAddress); err != nil {
+		v.add("bind-address", "%s", err)
+	}
+	if c.HTTPBindAddress == "" {
+		v.add("http-bind-address", "must be specified")
+	} else if err := checkListenAddress(c.HTTPBindAddress); err != nil {
+		v.add("http-bind-address", "%s", err)
+	}
+	if _, err := ResolveBindAddress(c.BindAddress, c.BindInterface); err != nil {
+		v.add("bind-interface", "%s", err)
+	}
+	if _, err := ResolveBindAddress(c.HTTPBindAddress, c.HTTPBindInterface); err != nil {
+		v.add("http-bind-interface", "%s", err)
+	}
+	if len(c.RemoteHostnames) > 0 {
+		for _, server := range c.MetaServers() {
+			if err := checkListenAddress(server); err != nil {
+				v.add("remote-hostnames", "%q: %s", server, err)
+			}
+		}
+		// An observer following itself would never see any metadata.
+		if httpAddr, _, err := c.advertisedAddrs(); err == nil && c.ObserverMode && Peers(c.MetaServers()).Contains(httpAddr) {
+			v.add("remote-hostnames", "must not hold this node's own address %s in observer-mode", httpAddr)
+		}
+	}
+	if c.HTTPSEnabled && c.SecretProvider == nil {
+		if c.HTTPSCertificate == "" {
+			v.add("https-certificate", "must be set with https-enabled")
+		} else if _, err := os.Stat(c.HTTPSCertificate); err != nil {
+			v.add("https-certificate", "%s", err)
+		}
+	}
+	if c.GzipLevel < gzip.HuffmanOnly || c.GzipLevel > gzip.BestCompression {
+		v.add("gzip-level", "must be between %d and %d, got %d", gzip.HuffmanOnly, gzip.BestCompression, c.GzipLevel)
+	}
+	if c.DebugBindAddress != "" {
+		if _, err := DebugListenAddress(c.DebugBindAddress); err != nil {
+			v.add("debug-bind-address", "%s", err)
+		}
+	}
+	if c.GRPCBindAddress != "" {
+		if _, _, err := net.SplitHostPort(c.GRPCBindAddress); err != nil {
+			v.add("grpc-bind-address", "%s", err)
+		}
+	}
+	if c.OrphanCheckInterval < 0 {
+		v.add("orphan-check-interval", "must not be negative")
+	}
+	if c.ShardPrecreationCheckInterval < 0 {
+		v.add("shard-precreation-check-interval", "must not be negative")
+	} else if c.ShardPrecreationCheckInterval > 0 && c.ShardPrecreationAdvancePeriod <= 0 {
+		v.add("shard-precreation-advance-period", "must be positive")
+	}
+	if c.ShardGroupReapInterval < 0 {
+		v.add("shard-group-reap-interval", "must not be negative")
+	}
+	if c.DataNodeHeartbeatCheckInterval < 0 {
+		v.add("data-node-heartbeat-check-interval", "must not be negative")
+	}
+	if c.LogTailMaxTailers <= 0 {
+		v.add("log-tail-max-tailers", "must be positive")
+	}
+	if _, err := c.socketMode(); err != nil {
+		v.add("socket-permissions", "%s", err)
+	}
+	if _, ok := unixSocketPath(c.HTTPBindAddress); ok && c.HTTPSEnabled {
+		v.add("https-enabled", "is not supported with a unix socket http-bind-address")
+	}
+	if c.PprofBindAddress != "" {
+		if err := checkListenAddress(c.PprofBindAddress); err != nil {
+			v.add("pprof-bind-address", "%s", err)
+		}
+	}
+	if c.JoinAddress != "" {
+		if _, _, err := net.SplitHostPort(c.JoinAddress); err != nil {
+			v.add("join-address", "%s", err)
+		}
+	}
+	for _, peer := range c.JoinPeers {
+		if _, err := parseSRVPeer(peer); err != nil {
+			v.add("join-peers", "%s", err)
+		}
+	}
+	if c.DiscoveryInterval <= 0 {
+		v.add("discovery-interval", "must be positive")
+	}
+	if c.ObserverMode {
+		if len(c.MetaServers()) == 0 {
+			v.add("observer-mode", "needs remote-hostnames, the meta servers to follow")
+		}
+		if c.JoinAddress != "" {
+			v.add("join-address", "must not be set in observer-mode")
+		}
+	}
+	if c.SingleNode {
+		if c.ObserverMode {
+			v.add("single-node", "must not be set in observer-mode")
+		}
+		if c.JoinAddress != "" {
+			v.add("join-address", "must not be set with single-node")
+		}
+		if len(c.JoinPeers) > 0 {
+			v.add("join-peers", "must not be set with single-node")
+		}
+	}
+	if c.ShutdownTimeout < 0 {
+		v.add("shutdown-timeout", "must not be negative")
+	}
+	if c.ListenerShutdownTimeout < 0 {
+		v.add("listener-shutdown-timeout", "must not be negative")
+	}
+	if c.PProfShutdownTimeout < 0 {
+		v.add("pprof-shutdown-timeout", "must not be negative")
+	}
+	if c.ClientShutdownTimeout < 0 {
+		v.add("client-shutdown-timeout", "must not be negative")
+	}
+	if c.ServiceShutdownTimeout < 0 {
+		v.add("service-shutdown-timeout", "must not be negative")
+	}
+	if c.HTTPSCertificateReloadInterval < 0 {
+		v.add("https-certificate-reload-interval", "must not be negative")
+	}
+	if (c.InternalCert == "") != (c.InternalKey == "") {
+		v.add("internal-key", "must be set together with internal-cert")
+	}
+	if c.InternalCert != "" && c.InternalCA == "" && c.SecretProvider == nil {
+		v.add("internal-ca", "must be set with internal-cert")
+	}
+	if c.InternalCert != "" {
+		if _, err := os.Stat(c.InternalCert); err != nil {
+			v.add("internal-cert", "%s", err)
+		}
+	}
+	if c.InternalKey != "" {
+		if _, err := os.Stat(c.InternalKey); err != nil {
+			v.add("internal-key", "%s", err)
+		}
+	}
+	if c.InternalCA != "" && c.SecretProvider == nil {
+		if _, err := os.Stat(c.InternalCA); err != nil {
+			v.add("internal-ca", "%s", err)
+		}
+	}
+	if c.RequireClientCert && c.InternalCert == "" {
+		v.add("require-client-cert", "needs internal-cert, internal-key and internal-ca")
+	}
+	if c.StartupTimeout < 0 {
+		v.add("startup-timeout", "must not be negative")
+	}
+	if c.MuxHandshakeTimeout <= 0 {
+		v.add("mux-handshake-timeout", "must be positive")
+	}
+	if c.OpenMaxAttempts < 0 {
+		v.add("open-max-attempts", "must not be negative")
+	}
+	if c.OpenMaxInterval <= 0 {
+		v.add("open-max-interval", "must be positive")
+	}
+	if c.NodeMaxAge < 0 {
+		v.add("node-max-age", "must not be negative")
+	}
+	if c.AddressChangePolicy != AddressChangeUpdate && c.AddressChangePolicy != AddressChangeWarn && c.AddressChangePolicy != AddressChangeFail {
+		v.add("address-change-policy", "must be %q, %q or %q, got %q", AddressChangeUpdate, AddressChangeWarn, AddressChangeFail, c.AddressChangePolicy)
+	}
+	if c.MetadataVersionTolerance < 0 {
+		v.add("metadata-version-tolerance", "must not be negative")
+	}
+	for i := range c.MaintenanceWindows {
+		if err := c.MaintenanceWindows[i].validate(); err != nil {
+			v.add("maintenance-window", "%s", err)
+		}
+	}
+	if c.LeadershipTransferTimeout < 0 {
+		v.add("leadership-transfer-timeout", "must not be negative")
+	}
+	if c.LeaderWarmTimeout < 0 {
+		v.add("leader-warm-timeout", "must not be negative")
+	}
+	if c.LeaderDrainTimeout < 0 {
+		v.add("leader-drain-timeout", "must not be negative")
+	}
+	if c.LeaderChangeWebhook != "" {
+		if u, err := url.Parse(c.LeaderChangeWebhook); err != nil {
+			v.add("leader-change-webhook", "%s", err)
+		} else if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.add("leader-change-webhook", "must be an http or https URL, got %q", c.LeaderChangeWebhook)
+		}
+	}
+	if c.DataNodeLivenessTimeout <= 0 {
+		v.add("data-node-liveness-timeout", "must be positive")
+	}
+	if c.StartupDelayMin < 0 {
+		v.add("startup-delay-min", "must not be negative")
+	}
+	if c.StartupDelayMax < c.StartupDelayMin {
+		v.add("startup-delay-max", "must not be less than startup-delay-min")
+	}
+	if c.MaxRequestTimeout <= 0 {
+		v.add("max-request-timeout", "must be positive")
+	}
+	if c.LogFormat != LogFormatText && c.LogFormat != LogFormatJSON && c.LogFormat != LogFormatLogfmt {
+		v.add("log-format", "must be %q, %q or %q, got %q", LogFormatText, LogFormatJSON, LogFormatLogfmt, c.LogFormat)
+	}
+	if c.QuorumLossWrites != QuorumLossFailFast && c.QuorumLossWrites != QuorumLossBlock {
+		v.add("quorum-loss-writes", "must be %q or %q, got %q", QuorumLossFailFast, QuorumLossBlock, c.QuorumLossWrites)
+	}
+	if c.QuorumLossWrites == QuorumLossFailFast && c.QuorumLossTimeout <= 0 {
+		v.add("quorum-loss-timeout", "must be positive")
+	}
+	if c.WriteDurability != WriteDurabilityQuorum && c.WriteDurability != WriteDurabilityAll {
+		v.add("write-durability", "must be %q or %q, got %q", WriteDurabilityQuorum, WriteDurabilityAll, c.WriteDurability)
+	}
+	if c.WriteDurabilityTimeout <= 0 {
+		v.add("write-durability-timeout", "must be positive")
+	}
+	if c.DuplicateDatabase != DuplicateDatabaseIgnore && c.DuplicateDatabase != DuplicateDatabaseError {
+		v.add("duplicate-database", "must be %q or %q, got %q", DuplicateDatabaseIgnore, DuplicateDatabaseError, c.DuplicateDatabase)
+	}
+	if c.DrainSettleTime < 0 {
+		v.add("drain-settle-time", "must not be negative")
+	}
+	if c.IdempotencyCacheSize <= 0 {
+		v.add("idempotency-cache-size", "must be positive")
+	}
+	if c.IdempotencyCacheTTL < 0 {
+		v.add("idempotency-cache-ttl", "must not be negative")
+	}
+	if c.IdempotencyCacheMaxBytes < 0 {
+		v.add("idempotency-cache-max-bytes", "must not be negative")
+	}
+	if c.IdempotencyCacheCompactInterval < 0 {
+		v.add("idempotency-cache-compact-interval", "must not be negative")
+	}
+	if c.HTTPRateLimit < 0 {
+		v.add("http-rate-limit", "must not be negative")
+	} else if c.HTTPRateLimit > 0 && c.HTTPRateLimitBurst < 1 {
+		v.add("http-rate-limit-burst", "must be at least 1 with http-rate-limit set")
+	}
+	if _, err := parseAllowlist(c.HTTPRateLimitAllowlist); err != nil {
+		v.add("http-rate-limit-allowlist", "%s", err)
+	}
+	if strings.TrimSpace(c.AuthToken) != c.AuthToken {
+		v.add("auth-token", "must not begin or end with whitespace")
+	}
+	if c.ShardGroupQuotaNearRatio <= 0 || c.ShardGroupQuotaNearRatio > 1 {
+		v.add("shard-group-quota-near-ratio", "must be greater than 0 and at most 1, got %v", c.ShardGroupQuotaNearRatio)
+	}
+	if c.MaxSnapshotSize < 0 {
+		v.add("max-snapshot-size", "must not be negative")
+	}
+	if c.MaxRaftMessageSize < 0 {
+		v.add("max-raft-message-size", "must not be negative")
+	}
+	if c.MaxConcurrentSnapshots <= 0 {
+		v.add("max-concurrent-snapshots", "must be positive")
+	}
+	if len(c.ShardGroupAutoTune) > 0 {
+		for _, name := range c.ShardGroupAutoTune {
+			if i := strings.Index(name, "."); i <= 0 || i == len(name)-1 {
+				v.add("shard-group-auto-tune", "%q is not of the form db.rp", name)
+			}
+		}
+		if c.ShardGroupTargetSize <= 0 {
+			v.add("shard-group-target-size", "must be positive")
+		}
+		if c.ShardGroupMinDuration <= 0 {
+			v.add("shard-group-min-duration", "must be positive")
+		}
+		if c.ShardGroupMaxDuration < c.ShardGroupMinDuration {
+			v.add("shard-group-max-duration", "must not be less than shard-group-min-duration")
+		}
+		if c.ShardGroupAutoTuneInterval <= 0 {
+			v.add("shard-group-auto-tune-interval", "must be positive")
+		}
+	}
+	return v.err()
+}
+
+// DebugListenAddress returns the address the debug listener binds to for
+// addr, defaulting the host to 127.0.0.1. It refuses hosts that aren't
+// loopback addresses so the debug endpoints are never exposed remotely.
+func DebugListenAddress(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	if host != "localhost" {
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			return "", fmt.Errorf("host %s is not a loopback address", host)
+		}
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// Features returns every feature flag derived from the config, keyed by its
+// toml name.
+func (c *Config) Features() map[string]bool {
+	return map[string]bool{
+		"https-enabled":          c.HTTPSEnabled,
+		"retention-autocreate":   c.RetentionAutoCreate,
+		"cluster-tracing":        c.ClusterTracing,
+		"raft-promotion-enabled": c.RaftPromotionEnabled,
+		"logging-enabled":        c.LoggingEnabled,
+		"pprof-enabled":          c.PprofEnabled,
+	}
+}
+
+// SetSource records that the value of the toml key came from src.
+func (c *Config) SetSource(key string, src ConfigSource) {
+	if c.sources == nil {
+		c.sources = make(map[string]ConfigSource)
+	}
+	c.sources[key] = src
+}
+
+// Source returns where the value of the toml key came from.
+func (c *Config) Source(key string) ConfigSource {
+	if src, ok := c.sources[key]; ok {
+		return src
+	}
+	return ConfigSourceDefault
 }
 
+// Values returns every config value and its source, keyed by toml name.
+// Values of keys that look like secrets are redacted.
+func (c *Config) Values() map[string]ConfigValue {
+	m := make(map[string]ConfigValue)
+	v := reflect.ValueOf(c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		key := v.Type().Field(i).Tag.Get("toml")
+		if key == "" || key == "-" {
+			continue
+		}
+
+		var value interface{} = "<redacted>"
+		if !isSecretConfigKey(key) {
+			value = v.Field(i).Interface()
+		}
+		m[key] = ConfigValue{Value: value, Source: c.Source(key)}
+	}
+	return m
+}
+
+// Changed returns the toml keys, sorted, whose values differ in other. An
+// empty list is the same as no list.
+func (c *Config) Changed(other *Config) []string {
+	var keys []string
+	a, b := reflect.ValueOf(c).Elem(), reflect.ValueOf(other).Elem()
+	for i := 0; i < a.NumField(); i++ {
+		key := a.Type().Field(i).Tag.Get("toml")
+		if key == "" || key == "-" {
+			continue
+		}
+
+		x, y := a.Field(i), b.Field(i)
+		if x.Kind() == reflect.Slice && x.Len() == 0 && y.Len() == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(x.Interface(), y.Interface()) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// isSecretConfigKey returns whether the value of the toml key must not be
+// shown.
+func isSecretConfigKey(key string) bool {
+	for _, s := range []string{"password", "secret", "token", "private-key"} {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// EnvPrefix is the prefix of the environment variables that override a
+// config field by its Go name, as INFLUXCLOUD_META_BINDADDRESS. They take
+// precedence over the INFLUXDB_ variables named after toml keys.
+const EnvPrefix = "INFLUXCLOUD_META"
+
 // ApplyEnvOverrides apply the environment configuration on top of the config.
 func (c *Config) ApplyEnvOverrides() error {
-	return c.applyEnvOverrides("INFLUXDB", reflect.ValueOf(c))
+	if err := c.applyEnvOverrides("INFLUXDB", reflect.ValueOf(c), tomlEnvName); err != nil {
+		return err
+	}
+	return c.applyEnvOverrides(EnvPrefix, reflect.ValueOf(c), fieldEnvName)
+}
+
+// tomlEnvName names the environment variable of a field after its toml key,
+// with hyphens replaced by underscores to avoid issues with shells.
+func tomlEnvName(f reflect.StructField) string {
+	name := f.Tag.Get("toml")
+	if name == "-" {
+		return ""
+	}
+	return strings.Replace(name, "-", "_", -1)
+}
+
+// fieldEnvName names the environment variable of a field after its Go name.
+func fieldEnvName(f reflect.StructField) string {
+	return f.Name
 }
 
-func (c *Config) applyEnvOverrides(prefix string, spec reflect.Value) error {
+func (c *Config) applyEnvOverrides(prefix string, spec reflect.Value, envName func(reflect.StructField) string) error {
 	// If we have a pointer, dereference it
 	s := spec
 	if spec.Kind() == reflect.Ptr {
@@ -147,93 +1262,201 @@ func (c *Config) applyEnvOverrides(prefix string, spec reflect.Value) error {
 	typeOfSpec := s.Type()
 	for i := 0; i < s.NumField(); i++ {
 		f := s.Field(i)
-		// Get the toml tag to determine what env var name to use
-		configName := typeOfSpec.Field(i).Tag.Get("toml")
-		// Replace hyphens with underscores to avoid issues with shells
-		configName = strings.Replace(configName, "-", "_", -1)
+		configName := envName(typeOfSpec.Field(i))
 		fieldKey := typeOfSpec.Field(i).Name
 
-		// Skip any fields that we cannot set
-		if f.CanSet() || f.Kind() == reflect.Slice {
+		// Skip any fields that we cannot set or name
+		if configName == "" || !(f.CanSet() || f.Kind() == reflect.Slice) {
+			continue
+		}
 
-			// Use the upper-case prefix and toml name for the env var
-			key := strings.ToUpper(configName)
-			if prefix != "" {
-				key = strings.ToUpper(fmt.Sprintf("%s_%s", prefix, configName))
-			}
-			value := os.Getenv(key)
-
-			// If the type is s slice, apply to each using the index as a suffix
-			// e.g. GRAPHITE_0
-			if f.Kind() == reflect.Slice || f.Kind() == reflect.Array {
-				for i := 0; i < f.Len(); i++ {
-					if err := c.applyEnvOverrides(fmt.Sprintf("%s_%d", key, i), f.Index(i)); err != nil {
-						return err
-					}
+		// Use the upper-case prefix and name for the env var
+		key := strings.ToUpper(configName)
+		if prefix != "" {
+			key = strings.ToUpper(fmt.Sprintf("%s_%s", prefix, configName))
+		}
+		value := os.Getenv(key)
+
+		// A slice of strings may be set as a whole, comma-separated.
+		if f.Kind() == reflect.Slice && f.Type().Elem().Kind() == reflect.String && value != "" {
+			var a []string
+			for _, v := range strings.Split(value, ",") {
+				if v = strings.TrimSpace(v); v != "" {
+					a = append(a, v)
 				}
-				continue
 			}
+			f.Set(reflect.ValueOf(a).Convert(f.Type()))
+			c.setEnvSource(s, typeOfSpec.Field(i))
+			continue
+		}
 
-			// If it's a sub-config, recursively apply
-			if f.Kind() == reflect.Struct || f.Kind() == reflect.Ptr {
-				if err := c.applyEnvOverrides(key, f); err != nil {
+		// If the type is s slice, apply to each using the index as a suffix
+		// e.g. GRAPHITE_0
+		if f.Kind() == reflect.Slice || f.Kind() == reflect.Array {
+			for i := 0; i < f.Len(); i++ {
+				if err := c.applyEnvOverrides(fmt.Sprintf("%s_%d", key, i), f.Index(i), envName); err != nil {
 					return err
 				}
-				continue
 			}
+			continue
+		}
 
-			// Skip any fields we don't have a value to set
-			if value == "" {
-				continue
+		// If it's a sub-config, recursively apply
+		if f.Kind() == reflect.Struct || f.Kind() == reflect.Ptr {
+			if err := c.applyEnvOverrides(key, f, envName); err != nil {
+				return err
 			}
+			continue
+		}
 
-			switch f.Kind() {
-			case reflect.String:
-				f.SetString(value)
-			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-
-				var intValue int64
-
-				// Handle toml.Duration
-				if f.Type().Name() == "Duration" {
-					dur, err := time.ParseDuration(value)
-					if err != nil {
-						return fmt.Errorf("failed to apply %v to %v using type %v and value '%v'", key, fieldKey, f.Type().String(), value)
-					}
-					intValue = dur.Nanoseconds()
-				} else {
-					var err error
-					intValue, err = strconv.ParseInt(value, 0, f.Type().Bits())
-					if err != nil {
-						return fmt.Errorf("failed to apply %v to %v using type %v and value '%v'", key, fieldKey, f.Type().String(), value)
-					}
-				}
+		// Skip any fields we don't have a value to set
+		if value == "" {
+			continue
+		}
 
-				f.SetInt(intValue)
-			case reflect.Bool:
-				boolValue, err := strconv.ParseBool(value)
-				if err != nil {
-					return fmt.Errorf("failed to apply %v to %v using type %v and value '%v'", key, fieldKey, f.Type().String(), value)
+		switch f.Kind() {
+		case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Bool, reflect.Float32, reflect.Float64:
+			if err := setEnvValue(f, value); err != nil {
+				return fmt.Errorf("failed to apply %v to %v using type %v and value '%v': %v", key, fieldKey, f.Type().String(), value, err)
+			}
+			c.setEnvSource(s, typeOfSpec.Field(i))
+		default:
+			if err := c.applyEnvOverrides(key, f, envName); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
 
-				}
-				f.SetBool(boolValue)
-			case reflect.Float32, reflect.Float64:
-				floatValue, err := strconv.ParseFloat(value, f.Type().Bits())
-				if err != nil {
-					return fmt.Errorf("failed to apply %v to %v using type %v and value '%v'", key, fieldKey, f.Type().String(), value)
+// setEnvSource records that a top-level field of the config came from the
+// environment.
+func (c *Config) setEnvSource(s reflect.Value, f reflect.StructField) {
+	if name := f.Tag.Get("toml"); s.Type() == reflect.TypeOf(*c) && name != "" && name != "-" {
+		c.SetSource(name, ConfigSourceEnv)
+	}
+}
 
-				}
-				f.SetFloat(floatValue)
-			default:
-				if err := c.applyEnvOverrides(key, f); err != nil {
-					return err
-				}
+// setEnvValue parses value into f by its kind. A toml.Duration is parsed as
+// a duration, and a bool as true/false or 1/0.
+func setEnvValue(f reflect.Value, value string) error {
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		// Handle toml.Duration
+		if f.Type().Name() == "Duration" {
+			dur, err := time.ParseDuration(value)
+			if err != nil {
+				return err
 			}
+			f.SetInt(dur.Nanoseconds())
+			return nil
+		}
+		intValue, err := strconv.ParseInt(value, 0, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetInt(intValue)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		uintValue, err := strconv.ParseUint(value, 0, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetUint(uintValue)
+	case reflect.Bool:
+		boolValue, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
 		}
+		f.SetBool(boolValue)
+	case reflect.Float32, reflect.Float64:
+		floatValue, err := strconv.ParseFloat(value, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetFloat(floatValue)
 	}
 	return nil
 }
 
+// MkdirAll creates the directory path, and any parents it lacks, with
+// DirMode. A directory that already exists with looser permissions, as one
+// created by an older version, is tightened to DirMode.
+func MkdirAll(path string) error {
+	if err := os.MkdirAll(path, DirMode); err != nil {
+		return err
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	} else if fi.Mode().Perm()&^DirMode == 0 {
+		return nil
+	}
+	return os.Chmod(path, DirMode)
+}
+
+// RaftPath returns the dir the raft log and snapshots are kept in.
+func (c *Config) RaftPath() string {
+	if c.RaftDir != "" {
+		return c.RaftDir
+	}
+	return c.Dir
+}
+
+// MetaServers returns the meta servers the meta client talks to, from
+// RemoteHostnames or else RemoteHostname.
+func (c *Config) MetaServers() []string {
+	hosts := c.RemoteHostnames
+	if len(hosts) == 0 {
+		hosts = []string{c.RemoteHostname}
+	}
+
+	var a []string
+	for _, h := range hosts {
+		for _, s := range strings.Split(h, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				a = append(a, s)
+			}
+		}
+	}
+	return a
+}
+
+// advertisedAddrs returns the HTTP and raft addresses the meta service
+// registers itself with. They are worked out from the config, rather than
+// the listeners, so a node can join a cluster before its service opens.
+func (c *Config) advertisedAddrs() (string, string, error) {
+	httpAddr, err := ResolveBindAddress(c.HTTPBindAddress, c.HTTPBindInterface)
+	if err != nil {
+		return "", "", err
+	}
+	raftAddr, err := ResolveBindAddress(c.BindAddress, c.BindInterface)
+	if err != nil {
+		return "", "", err
+	}
+	if autoAssignPort(httpAddr) || autoAssignPort(raftAddr) {
+		return "", "", fmt.Errorf("http-bind-address %q and bind-address %q must have fixed ports", httpAddr, raftAddr)
+	}
+	return c.remoteAddr(httpAddr), c.remoteAddr(raftAddr), nil
+}
+
+// remoteAddr returns addr with an empty or unspecified host replaced by
+// RemoteHostname, or DefaultHostname if that isn't set.
+func (c *Config) remoteAddr(addr string) string {
+	hostname := c.RemoteHostname
+	if hostname == "" {
+		hostname = DefaultHostname
+	}
+	remote, err := DefaultHost(hostname, addr)
+	if err != nil {
+		return addr
+	}
+	return remote
+}
+
 func (c *Config) defaultHost(addr string) string {
 	address, err := DefaultHost(DefaultHostname, addr)
 	if nil != err {
@@ -242,6 +1465,103 @@ func (c *Config) defaultHost(addr string) string {
 	return address
 }
 
+// checkListenAddress returns an error unless addr is a host:port, or the
+// path of a Unix socket prefixed by unix://.
+func checkListenAddress(addr string) error {
+	if path, ok := unixSocketPath(addr); ok {
+		if path == "" {
+			return fmt.Errorf("unix socket %q has no path", addr)
+		}
+		return nil
+	}
+	_, _, err := net.SplitHostPort(addr)
+	return err
+}
+
+// checkWritableDir returns an error unless the node can write to dir, or
+// create it in its nearest existing parent directory.
+func checkWritableDir(dir string) error {
+	path := dir
+	for {
+		fi, err := os.Stat(path)
+		if err == nil {
+			if !fi.IsDir() {
+				return fmt.Errorf("%s is not a directory", path)
+			}
+			break
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return err
+		}
+		path = parent
+	}
+
+	f, err := ioutil.TempFile(path, ".validate")
+	if err != nil {
+		return fmt.Errorf("%s is not writable: %s", path, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// ResolveBindAddress returns addr with its host replaced by the address of
+// the network interface iface, preferring IPv4. When addr already names a
+// host it must be one of the interface's addresses. An empty iface returns
+// addr unchanged.
+func ResolveBindAddress(addr, iface string) (string, error) {
+	if iface == "" {
+		return addr, nil
+	}
+	if _, ok := unixSocketPath(addr); ok {
+		return "", fmt.Errorf("unix socket %s can't be bound to interface %s", addr, iface)
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return "", err
+	}
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return "", err
+	}
+
+	var ips []net.IP
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok {
+			ips = append(ips, ipnet.IP)
+		}
+	}
+	if len(ips) == 0 {
+		return "", fmt.Errorf("interface %s has no addresses", iface)
+	}
+
+	if host != "" && host != "0.0.0.0" && host != "::" {
+		for _, ip := range ips {
+			if ip.String() == host {
+				return addr, nil
+			}
+		}
+		return "", fmt.Errorf("address %s is not on interface %s", host, iface)
+	}
+
+	ip := ips[0]
+	for _, candidate := range ips {
+		if candidate.To4() != nil {
+			ip = candidate
+			break
+		}
+	}
+	return net.JoinHostPort(ip.String(), port), nil
+}
+
 // DefaultHost return defaultHost
 func DefaultHost(hostname, addr string) (string, error) {
 	host, port, err := net.SplitHostPort(addr)
@@ -0,0 +1,148 @@
+package meta
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// clusterConfigKeys are the toml keys of the settings that must be the same
+// on every meta node. Nodes that disagree on them behave differently
+// depending on which one leads or serves a client.
+var clusterConfigKeys = []string{
+	"https-enabled",
+	"require-client-cert",
+	"retention-autocreate",
+	"election-timeout",
+	"heartbeat-timeout",
+	"leader-lease-timeout",
+	"commit-timeout",
+	"raft-promotion-enabled",
+	"lease-duration",
+	"quorum-loss-writes",
+	"quorum-loss-timeout",
+	"write-durability",
+	"write-durability-timeout",
+	"duplicate-database",
+	"snapshot-streaming",
+	"max-snapshot-size",
+	"max-raft-message-size",
+	"verify-snapshots",
+	"shard-group-auto-tune",
+	"shard-group-target-size",
+	"shard-group-min-duration",
+	"shard-group-max-duration",
+	"orphan-check-interval",
+	"purge-orphans",
+	"shard-precreation-check-interval",
+	"shard-precreation-advance-period",
+	"shard-group-reap-interval",
+}
+
+// ConfigFingerprint is the part of a meta node's effective configuration
+// that must agree across the cluster, as served by /config-fingerprint.
+type ConfigFingerprint struct {
+	Version string `json:"version"`
+
+	// Settings are the values of the cluster wide settings, keyed by toml
+	// name.
+	Settings map[string]string `json:"settings"`
+
+	// Fingerprint is a checksum of Version and Settings, the same on nodes
+	// configured alike.
+	Fingerprint string `json:"fingerprint"`
+}
+
+// NewConfigFingerprint returns the fingerprint of c on a node running
+// version.
+func NewConfigFingerprint(c *Config, version string) *ConfigFingerprint {
+	values := c.Values()
+	fp := &ConfigFingerprint{Version: version, Settings: make(map[string]string)}
+	for _, key := range clusterConfigKeys {
+		fp.Settings[key] = fmt.Sprint(values[key].Value)
+	}
+
+	// encoding/json sorts map keys, which keeps the fingerprint stable.
+	b, err := json.Marshal(struct {
+		Version  string
+		Settings map[string]string
+	}{fp.Version, fp.Settings})
+	if err != nil {
+		panic(err)
+	}
+	sum := sha256.Sum256(b)
+	fp.Fingerprint = hex.EncodeToString(sum[:])
+	return fp
+}
+
+// ConfigMismatch is a setting that differs between meta nodes.
+type ConfigMismatch struct {
+	Setting string `json:"setting"`
+
+	// Values are the values of the setting, keyed by server.
+	Values map[string]string `json:"values"`
+}
+
+// ConfigMismatches returns the settings, and the version, that differ
+// between the fingerprints of servers, sorted by setting.
+func ConfigMismatches(fps map[string]*ConfigFingerprint) []ConfigMismatch {
+	var mismatches []ConfigMismatch
+	compare := func(setting string, value func(fp *ConfigFingerprint) string) {
+		values := make(map[string]string, len(fps))
+		distinct := make(map[string]bool)
+		for server, fp := range fps {
+			values[server] = value(fp)
+			distinct[values[server]] = true
+		}
+		if len(distinct) > 1 {
+			mismatches = append(mismatches, ConfigMismatch{Setting: setting, Values: values})
+		}
+	}
+
+	compare("version", func(fp *ConfigFingerprint) string { return fp.Version })
+	for _, key := range clusterConfigKeys {
+		compare(key, func(fp *ConfigFingerprint) string { return fp.Settings[key] })
+	}
+	sort.Slice(mismatches, func(i, j int) bool { return mismatches[i].Setting < mismatches[j].Setting })
+	return mismatches
+}
+
+// serveConfigFingerprint responds with the configuration fingerprint of
+// the node as JSON.
+func (h *handler) serveConfigFingerprint(w http.ResponseWriter, r *http.Request) {
+	var version string
+	if h.s != nil {
+		version = h.s.Version()
+	}
+	w.Header().Add("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(NewConfigFingerprint(h.config, version)); err != nil {
+		h.httpError(err, w, http.StatusInternalServerError)
+	}
+}
+
+// ConfigFingerprints returns the configuration fingerprint of every meta
+// server, keyed by server. Use ConfigMismatches to find what differs.
+func (c *Client) ConfigFingerprints() (map[string]*ConfigFingerprint, error) {
+	fps := make(map[string]*ConfigFingerprint)
+	for _, server := range c.MetaServers() {
+		resp, err := c.httpClient().Get(c.url(server) + "/config-fingerprint")
+		if err != nil {
+			return nil, err
+		}
+		fp := &ConfigFingerprint{}
+		if resp.StatusCode != http.StatusOK {
+			err = fmt.Errorf("meta server %s returned %s", server, resp.Status)
+		} else {
+			err = json.NewDecoder(resp.Body).Decode(fp)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		fps[server] = fp
+	}
+	return fps, nil
+}
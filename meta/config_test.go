@@ -1,6 +1,12 @@
 package meta_test
 
 import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -44,3 +50,195 @@ logging-enabled = false
 		t.Fatalf("unexpected logging enabled: %v", c.LoggingEnabled)
 	}
 }
+
+func TestConfig_Validate_GzipLevel(t *testing.T) {
+	c := meta.NewConfig()
+	c.Dir = "/tmp/foo"
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected error for default level: %s", err)
+	}
+
+	for _, level := range []int{gzip.HuffmanOnly - 1, gzip.BestCompression + 1} {
+		c.GzipLevel = level
+		if err := c.Validate(); err == nil {
+			t.Fatalf("expected level %d to be rejected", level)
+		}
+	}
+}
+
+func TestConfig_Validate_ReportsEveryField(t *testing.T) {
+	c := meta.NewConfig()
+	c.Dir = ""
+	c.GzipLevel = 42
+	c.HTTPBindInterface = "no-such-interface0"
+
+	err := c.Validate()
+	verr, ok := err.(*meta.ValidationError)
+	if !ok {
+		t.Fatalf("expected a *meta.ValidationError, got %T: %v", err, err)
+	}
+
+	var fields []string
+	for _, fe := range verr.Errors {
+		if fe.Message == "" {
+			t.Fatalf("field %s has no message", fe.Field)
+		}
+		fields = append(fields, fe.Field)
+	}
+	if exp := []string{"dir", "http-bind-interface", "gzip-level"}; !reflect.DeepEqual(fields, exp) {
+		t.Fatalf("unexpected fields: got %v, expected %v", fields, exp)
+	}
+}
+
+func TestConfig_Validate_DebugBindAddress(t *testing.T) {
+	for addr, valid := range map[string]bool{
+		"":               true,
+		":8092":          true,
+		"127.0.0.1:8092": true,
+		"localhost:8092": true,
+		"[::1]:8092":     true,
+		"0.0.0.0:8092":   false,
+		"10.0.0.1:8092":  false,
+		"8092":           false,
+	} {
+		c := meta.NewConfig()
+		c.Dir = "/tmp/meta"
+		c.DebugBindAddress = addr
+		if err := c.Validate(); (err == nil) != valid {
+			t.Errorf("%q: unexpected validation result: %v", addr, err)
+		}
+	}
+}
+
+func TestConfig_MetaServers(t *testing.T) {
+	var c meta.Config
+	if _, err := toml.Decode(`
+remote-hostnames = ["meta0:8091", "meta1:8091, meta2:8091"]
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+	c.RemoteHostname = "old:8091"
+	if got, exp := c.MetaServers(), []string{"meta0:8091", "meta1:8091", "meta2:8091"}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected meta servers: got %v, expected %v", got, exp)
+	}
+
+	// The deprecated single hostname is used when no list is set.
+	c.RemoteHostnames = nil
+	if got, exp := c.MetaServers(), []string{"old:8091"}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected meta servers: got %v, expected %v", got, exp)
+	}
+}
+
+func TestConfig_Validate_Environment(t *testing.T) {
+	dir, err := ioutil.TempDir("", "meta-config-validate-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(file, nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	c := meta.NewConfig()
+	c.Dir = filepath.Join(dir, "meta", "not-yet-created")
+	c.BindAddress = "127.0.0.1:8088"
+	c.HTTPBindAddress = "127.0.0.1:8091"
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	c.Dir = filepath.Join(file, "meta")
+	c.BindAddress = ""
+	c.HTTPBindAddress = "localhost"
+	c.HTTPSEnabled = true
+	c.HTTPSCertificate = filepath.Join(dir, "missing.pem")
+	c.RemoteHostnames = []string{"127.0.0.1:8091", "meta1"}
+
+	verr, ok := c.Validate().(*meta.ValidationError)
+	if !ok {
+		t.Fatalf("expected a *meta.ValidationError")
+	}
+	got := make(map[string]int)
+	for _, fe := range verr.Errors {
+		got[fe.Field]++
+	}
+	for _, field := range []string{"dir", "bind-address", "http-bind-address", "https-certificate"} {
+		if got[field] != 1 {
+			t.Errorf("expected %s to be reported once, got %d: %v", field, got[field], verr)
+		}
+	}
+	if got["remote-hostnames"] != 1 {
+		t.Errorf("expected the malformed remote hostname to be reported: %v", verr)
+	}
+
+	// An observer must not follow itself.
+	c = meta.NewConfig()
+	c.Dir = dir
+	c.HTTPBindAddress = "127.0.0.1:8091"
+	c.ObserverMode = true
+	c.RemoteHostnames = []string{"127.0.0.1:8091"}
+	if err := c.Validate(); err == nil || !strings.Contains(err.Error(), "remote-hostnames") {
+		t.Fatalf("expected remote-hostnames to be reported, got %v", err)
+	}
+}
+
+// Ensure INFLUXCLOUD_META_ variables, named after the Go fields, override the
+// file and the INFLUXDB_ variables.
+func TestConfig_ApplyEnvOverrides(t *testing.T) {
+	c := meta.NewConfig()
+	if _, err := toml.Decode(`
+dir = "/tmp/meta"
+bind-address = ":8089"
+https-enabled = true
+`, c); err != nil {
+		t.Fatal(err)
+	}
+
+	for k, v := range map[string]string{
+		"INFLUXDB_BIND_ADDRESS":           ":9000",
+		"INFLUXCLOUD_META_BINDADDRESS":    ":9001",
+		"INFLUXCLOUD_META_DIR":            "/var/lib/meta",
+		"INFLUXCLOUD_META_HTTPSENABLED":   "0",
+		"INFLUXCLOUD_META_LEASEDURATION":  "2m",
+		"INFLUXCLOUD_META_HTTPRATELIMIT":  "2.5",
+		"INFLUXCLOUD_META_JOINPEERS":      "meta0:8091, meta1:8091",
+		"INFLUXCLOUD_META_LOGGINGENABLED": "false",
+	} {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+	if err := c.ApplyEnvOverrides(); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.BindAddress != ":9001" {
+		t.Errorf("unexpected bind address: %s", c.BindAddress)
+	} else if c.Dir != "/var/lib/meta" {
+		t.Errorf("unexpected dir: %s", c.Dir)
+	} else if c.HTTPSEnabled {
+		t.Errorf("https still enabled")
+	} else if time.Duration(c.LeaseDuration) != 2*time.Minute {
+		t.Errorf("unexpected lease duration: %s", c.LeaseDuration)
+	} else if c.HTTPRateLimit != 2.5 {
+		t.Errorf("unexpected rate limit: %v", c.HTTPRateLimit)
+	} else if exp := []string{"meta0:8091", "meta1:8091"}; !reflect.DeepEqual(c.JoinPeers, exp) {
+		t.Errorf("unexpected join peers: %v", c.JoinPeers)
+	} else if c.LoggingEnabled {
+		t.Errorf("logging still enabled")
+	}
+	if src := c.Source("dir"); src != meta.ConfigSourceEnv {
+		t.Errorf("unexpected source of dir: %s", src)
+	}
+}
+
+// Ensure a malformed value names the variable, the field and the value.
+func TestConfig_ApplyEnvOverrides_Malformed(t *testing.T) {
+	os.Setenv("INFLUXCLOUD_META_HTTPSENABLED", "yes")
+	defer os.Unsetenv("INFLUXCLOUD_META_HTTPSENABLED")
+
+	err := meta.NewConfig().ApplyEnvOverrides()
+	if err == nil || !strings.Contains(err.Error(), "INFLUXCLOUD_META_HTTPSENABLED to HTTPSEnabled using type bool and value 'yes'") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
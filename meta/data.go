@@ -12,6 +12,7 @@ import (
 )
 
 //go:generate protoc --gogo_out=. internal/meta.proto
+//go:generate protoc --gogo_out=plugins=grpc:. internal/control.proto
 
 const (
 	// DefaultRetentionPolicyReplicaN is the default value of RetentionPolicyInfo.ReplicaN.
@@ -25,6 +26,14 @@ const (
 
 	// MinRetentionPolicyDuration represents the minimum duration for a policy.
 	MinRetentionPolicyDuration = time.Hour
+
+	// MaxDatabaseAnnotations is the number of annotations a database may have.
+	MaxDatabaseAnnotations = 32
+
+	// MaxAnnotationKeySize and MaxAnnotationValueSize are the largest
+	// annotation key and value, in bytes.
+	MaxAnnotationKeySize   = 128
+	MaxAnnotationValueSize = 1024
 )
 
 // Data represents the top level collection of all metadata.
@@ -36,6 +45,42 @@ type Data struct {
 	DataNodes NodeInfos
 	MaxNodeID uint64
 	ClusterID uint64
+
+	// TopologyFrozen rejects every membership and shard placement change
+	// until it is cleared again.
+	TopologyFrozen bool
+
+	// Bootstrapped is set once the bootstrap file has been applied.
+	Bootstrapped bool
+
+	// MetadataVersion is the version of the metadata schema the cluster
+	// was created with, or zero if it predates the version being recorded.
+	MetadataVersion uint64
+
+	// ShardGroupQuotas caps the number of shard groups of a database, keyed
+	// by database name.
+	ShardGroupQuotas map[string]uint64
+
+	// DatabaseAnnotations holds user defined key/value labels of a database,
+	// keyed by database name.
+	DatabaseAnnotations map[string]map[string]string
+
+	// WriteBlockedDatabases holds the databases being drained, which data
+	// nodes take no more writes for, keyed by name with the time writes were
+	// blocked.
+	WriteBlockedDatabases map[string]time.Time
+
+	// RestartLock is the advisory lock a rolling restart takes for the node
+	// it restarts, or nil if nobody holds it.
+	RestartLock *RestartLock
+}
+
+// RestartLock is held by a single restart orchestrator at a time, for the
+// node it restarts, until it is released or expires.
+type RestartLock struct {
+	Holder     string    `json:"holder"`
+	NodeID     uint64    `json:"nodeID"`
+	Expiration time.Time `json:"expiration"`
 }
 
 // Clone returns a copy of data with a new version.
@@ -61,6 +106,40 @@ func (data *Data) Clone() *Data {
 		}
 	}
 
+	// Copy shard group quotas.
+	if data.ShardGroupQuotas != nil {
+		other.ShardGroupQuotas = make(map[string]uint64, len(data.ShardGroupQuotas))
+		for db, max := range data.ShardGroupQuotas {
+			other.ShardGroupQuotas[db] = max
+		}
+	}
+
+	// Copy database annotations.
+	if data.DatabaseAnnotations != nil {
+		other.DatabaseAnnotations = make(map[string]map[string]string, len(data.DatabaseAnnotations))
+		for db, annotations := range data.DatabaseAnnotations {
+			m := make(map[string]string, len(annotations))
+			for k, v := range annotations {
+				m[k] = v
+			}
+			other.DatabaseAnnotations[db] = m
+		}
+	}
+
+	// Copy write blocked databases.
+	if data.WriteBlockedDatabases != nil {
+		other.WriteBlockedDatabases = make(map[string]time.Time, len(data.WriteBlockedDatabases))
+		for db, t := range data.WriteBlockedDatabases {
+			other.WriteBlockedDatabases[db] = t
+		}
+	}
+
+	// Copy the restart lock.
+	if data.RestartLock != nil {
+		l := *data.RestartLock
+		other.RestartLock = &l
+	}
+
 	return &other
 }
 
@@ -77,10 +156,19 @@ type NodeInfo struct {
 	Host               string
 	TCPHost            string
 	PendingShardOwners uint64arr
+
+	// StartedAt is the time the node last (re)joined the cluster. A value
+	// that keeps moving forward points at a crash-looping node.
+	StartedAt time.Time
 }
 
 // clone returns a deep copy of ni.
-func (ni NodeInfo) clone() NodeInfo { return ni }
+func (ni NodeInfo) clone() NodeInfo {
+	if ni.PendingShardOwners != nil {
+		ni.PendingShardOwners = append(uint64arr(nil), ni.PendingShardOwners...)
+	}
+	return ni
+}
 
 // marshal serializes to a protobuf representation.
 func (ni NodeInfo) marshal() *internal.NodeInfo {
@@ -88,10 +176,13 @@ func (ni NodeInfo) marshal() *internal.NodeInfo {
 	pb.ID = proto.Uint64(ni.ID)
 	pb.Host = proto.String(ni.Host)
 	pb.TCPHost = proto.String(ni.TCPHost)
-	pb.PendingShardOwners = make(uint64arr, len(ni.PendingShardOwners))
+	pb.PendingShardOwners = make(uint64arr, 0, len(ni.PendingShardOwners))
 	for _, pso := range ni.PendingShardOwners {
 		pb.PendingShardOwners = append(pb.PendingShardOwners, *proto.Uint64(pso))
 	}
+	if !ni.StartedAt.IsZero() {
+		pb.StartedAt = proto.Int64(ni.StartedAt.UnixNano())
+	}
 	return pb
 }
 
@@ -101,6 +192,20 @@ func (ni *NodeInfo) unmarshal(pb *internal.NodeInfo) {
 	ni.Host = pb.GetHost()
 	ni.TCPHost = pb.GetTCPHost()
 	ni.PendingShardOwners = pb.GetPendingShardOwners()
+	if pb.StartedAt != nil {
+		ni.StartedAt = time.Unix(0, pb.GetStartedAt()).UTC()
+	}
+}
+
+// setNodeStartedAt records the last start time of the node in nodes with the
+// given TCP host. It is a no-op if no such node exists.
+func setNodeStartedAt(nodes NodeInfos, tcpHost string, t time.Time) {
+	for i := range nodes {
+		if nodes[i].TCPHost == tcpHost {
+			nodes[i].StartedAt = t
+			return
+		}
+	}
 }
 
 // MetaNode return meta node info according to nodeID
@@ -113,6 +218,36 @@ func (data *Data) MetaNode(id uint64) *NodeInfo {
 	return nil
 }
 
+// QuorumStatus describes the raft quorum of the meta nodes, by the voters in
+// the leader's raft configuration, and whether removing one of them keeps it.
+type QuorumStatus struct {
+	Voters int `json:"voters"`
+	Quorum int `json:"quorum"`
+
+	// RemovalSafe is whether the voters left after the removal still make
+	// up the current quorum.
+	RemovalSafe bool `json:"removalSafe"`
+}
+
+// newQuorumStatus returns the quorum status of the raft voters for removing
+// the peer at addr. Removing a peer that isn't a voter, as one whose join
+// isn't yet in the raft configuration, leaves the voters as they are.
+func newQuorumStatus(voters []string, addr string) *QuorumStatus {
+	quorum := len(voters)/2 + 1
+	left := len(voters)
+	for _, v := range voters {
+		if v == addr {
+			left--
+			break
+		}
+	}
+	return &QuorumStatus{
+		Voters:      len(voters),
+		Quorum:      quorum,
+		RemovalSafe: left >= quorum,
+	}
+}
+
 // CreateMetaNode creats a meta node info according to host and tcpHost. NodeID are automaitically generated by program itself.
 func (data *Data) CreateMetaNode(host, tcpHost string) error {
 	// Ensure a node with the same host doesn't already exist.
@@ -122,26 +257,22 @@ func (data *Data) CreateMetaNode(host, tcpHost string) error {
 		}
 	}
 
-	// If an existing meta node exists with the same TCPHost address,
-	// then these nodes are actually the same so re-use the existing ID
-	var existingID uint64
-	for _, n := range data.MetaNodes {
-		if n.TCPHost == tcpHost {
-			existingID = n.ID
-			break
+	// If an existing meta node exists with the same TCPHost address, then
+	// these nodes are actually the same. This is a retried join, possibly
+	// through a new leader, so update the existing entry instead of adding a
+	// second one.
+	for i := range data.MetaNodes {
+		if data.MetaNodes[i].TCPHost == tcpHost {
+			data.MetaNodes[i].Host = host
+			return nil
 		}
 	}
 
-	// We didn't find an existing node, so assign it a new node ID
-	if existingID == 0 {
-		data.MaxNodeID++
-		existingID = data.MaxNodeID
-	}
-
 	// Append new node.
+	data.MaxNodeID++
 	pendingShardOwners := make(uint64arr, 0)
 	data.MetaNodes = append(data.MetaNodes, NodeInfo{
-		ID:                 existingID,
+		ID:                 data.MaxNodeID,
 		Host:               host,
 		TCPHost:            tcpHost,
 		PendingShardOwners: pendingShardOwners,
@@ -173,6 +304,18 @@ func (data *Data) SetMetaNode(nodeID uint64, host, tcpHost string) error {
 	return nil
 }
 
+// UpdateMetaNode changes the addresses of the meta node nodeID, as when
+// they changed across a restart.
+func (data *Data) UpdateMetaNode(nodeID uint64, host, tcpHost string) error {
+	n := data.MetaNode(nodeID)
+	if n == nil {
+		return ErrNodeNotFound
+	}
+	n.Host = host
+	n.TCPHost = tcpHost
+	return nil
+}
+
 // DeleteMetaNode remove a meta node info from metastore according to nodeID.
 // Data resides in that node will be also removed. It is buggy for now. Improvement is
 // scheduled in future. TODO.
@@ -433,6 +576,62 @@ func (data *Data) marshal() *internal.ClusterData {
 		pb.DataNodes[i] = data.DataNodes[i].marshal()
 	}
 
+	pb.TopologyFrozen = proto.Bool(data.TopologyFrozen)
+	pb.Bootstrapped = proto.Bool(data.Bootstrapped)
+	pb.MetadataVersion = proto.Uint64(data.MetadataVersion)
+
+	dbs := make([]string, 0, len(data.ShardGroupQuotas))
+	for db := range data.ShardGroupQuotas {
+		dbs = append(dbs, db)
+	}
+	sort.Strings(dbs)
+	for _, db := range dbs {
+		pb.ShardGroupQuotas = append(pb.ShardGroupQuotas, &internal.ShardGroupQuota{
+			Database:       proto.String(db),
+			MaxShardGroups: proto.Uint64(data.ShardGroupQuotas[db]),
+		})
+	}
+
+	dbs = dbs[:0]
+	for db := range data.DatabaseAnnotations {
+		dbs = append(dbs, db)
+	}
+	sort.Strings(dbs)
+	for _, db := range dbs {
+		keys := make([]string, 0, len(data.DatabaseAnnotations[db]))
+		for k := range data.DatabaseAnnotations[db] {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			pb.DatabaseAnnotations = append(pb.DatabaseAnnotations, &internal.DatabaseAnnotation{
+				Database: proto.String(db),
+				Key:      proto.String(k),
+				Value:    proto.String(data.DatabaseAnnotations[db][k]),
+			})
+		}
+	}
+
+	dbs = dbs[:0]
+	for db := range data.WriteBlockedDatabases {
+		dbs = append(dbs, db)
+	}
+	sort.Strings(dbs)
+	for _, db := range dbs {
+		pb.WriteBlockedDatabases = append(pb.WriteBlockedDatabases, &internal.WriteBlockedDatabase{
+			Database: proto.String(db),
+			Time:     proto.Int64(data.WriteBlockedDatabases[db].UnixNano()),
+		})
+	}
+
+	if l := data.RestartLock; l != nil {
+		pb.RestartLock = &internal.RestartLock{
+			Holder:     proto.String(l.Holder),
+			NodeID:     proto.Uint64(l.NodeID),
+			Expiration: proto.Int64(l.Expiration.UnixNano()),
+		}
+	}
+
 	return pb
 }
 
@@ -448,6 +647,8 @@ func (data *Data) UnmarshalBinary(buf []byte) error {
 
 // unmarshal deserializes from a protobuf representation.
 func (data *Data) unmarshal(pb *internal.ClusterData) {
+	data.MaxNodeID = pb.GetMaxNodeID()
+
 	data.Data = &meta.Data{}
 	data.Data.UnmarshalBinary(pb.GetData())
 
@@ -461,6 +662,45 @@ func (data *Data) unmarshal(pb *internal.ClusterData) {
 		data.DataNodes[i].unmarshal(d)
 	}
 
+	data.TopologyFrozen = pb.GetTopologyFrozen()
+	data.Bootstrapped = pb.GetBootstrapped()
+	data.MetadataVersion = pb.GetMetadataVersion()
+
+	data.ShardGroupQuotas = nil
+	for _, q := range pb.GetShardGroupQuotas() {
+		if data.ShardGroupQuotas == nil {
+			data.ShardGroupQuotas = make(map[string]uint64)
+		}
+		data.ShardGroupQuotas[q.GetDatabase()] = q.GetMaxShardGroups()
+	}
+
+	data.DatabaseAnnotations = nil
+	for _, a := range pb.GetDatabaseAnnotations() {
+		if data.DatabaseAnnotations == nil {
+			data.DatabaseAnnotations = make(map[string]map[string]string)
+		}
+		if data.DatabaseAnnotations[a.GetDatabase()] == nil {
+			data.DatabaseAnnotations[a.GetDatabase()] = make(map[string]string)
+		}
+		data.DatabaseAnnotations[a.GetDatabase()][a.GetKey()] = a.GetValue()
+	}
+
+	data.WriteBlockedDatabases = nil
+	for _, b := range pb.GetWriteBlockedDatabases() {
+		if data.WriteBlockedDatabases == nil {
+			data.WriteBlockedDatabases = make(map[string]time.Time)
+		}
+		data.WriteBlockedDatabases[b.GetDatabase()] = time.Unix(0, b.GetTime()).UTC()
+	}
+
+	data.RestartLock = nil
+	if l := pb.GetRestartLock(); l != nil {
+		data.RestartLock = &RestartLock{
+			Holder:     l.GetHolder(),
+			NodeID:     l.GetNodeID(),
+			Expiration: time.Unix(0, l.GetExpiration()).UTC(),
+		}
+	}
 }
 
 // CreateShardGroup creates a shard group on a database and policy for a given timestamp.
@@ -483,6 +723,11 @@ func (data *Data) CreateShardGroup(database, policy string, timestamp time.Time)
 		return nil
 	}
 
+	// Enforce the database's shard group quota.
+	if max := data.ShardGroupQuotas[database]; max > 0 && data.ShardGroupCount(database) >= max {
+		return ErrShardGroupQuotaExceeded
+	}
+
 	// Require at least one replica but no more replicas than nodes.
 	replicaN := rpi.ReplicaN
 	if replicaN == 0 {
@@ -515,6 +760,218 @@ func (data *Data) CreateShardGroup(database, policy string, timestamp time.Time)
 	return nil
 }
 
+// SetShardGroupQuota caps the number of shard groups of database at max. A
+// max of zero removes the quota, even of a database that no longer exists.
+func (data *Data) SetShardGroupQuota(database string, max uint64) error {
+	if max == 0 {
+		delete(data.ShardGroupQuotas, database)
+		return nil
+	}
+	if data.Data.Database(database) == nil {
+		return influxdb.ErrDatabaseNotFound(database)
+	}
+	if data.ShardGroupQuotas == nil {
+		data.ShardGroupQuotas = make(map[string]uint64)
+	}
+	data.ShardGroupQuotas[database] = max
+	return nil
+}
+
+// SetDatabaseAnnotation sets the annotation key of database to value. An
+// empty value removes it.
+func (data *Data) SetDatabaseAnnotation(database, key, value string) error {
+	if data.Data.Database(database) == nil {
+		return influxdb.ErrDatabaseNotFound(database)
+	} else if key == "" {
+		return ErrAnnotationKeyRequired
+	} else if len(key) > MaxAnnotationKeySize || len(value) > MaxAnnotationValueSize {
+		return ErrAnnotationTooLarge
+	}
+
+	annotations := data.DatabaseAnnotations[database]
+	if value == "" {
+		delete(annotations, key)
+		if len(annotations) == 0 {
+			delete(data.DatabaseAnnotations, database)
+		}
+		return nil
+	}
+	if _, ok := annotations[key]; !ok && len(annotations) >= MaxDatabaseAnnotations {
+		return ErrTooManyAnnotations
+	}
+
+	if annotations == nil {
+		if data.DatabaseAnnotations == nil {
+			data.DatabaseAnnotations = make(map[string]map[string]string)
+		}
+		annotations = make(map[string]string)
+		data.DatabaseAnnotations[database] = annotations
+	}
+	annotations[key] = value
+	return nil
+}
+
+// SetDatabaseWriteBlocked blocks or unblocks writes to database, as of t
+// when blocking. Blocking an already blocked database keeps the time it was
+// first blocked at.
+func (data *Data) SetDatabaseWriteBlocked(database string, blocked bool, t time.Time) error {
+	if !blocked {
+		delete(data.WriteBlockedDatabases, database)
+		return nil
+	}
+	if data.Data.Database(database) == nil {
+		return influxdb.ErrDatabaseNotFound(database)
+	}
+	if _, ok := data.WriteBlockedDatabases[database]; ok {
+		return nil
+	}
+	if data.WriteBlockedDatabases == nil {
+		data.WriteBlockedDatabases = make(map[string]time.Time)
+	}
+	data.WriteBlockedDatabases[database] = t.UTC()
+	return nil
+}
+
+// AcquireRestartLock gives the restart lock to holder, restarting the node
+// nodeID, until ttl after t. It fails with ErrRestartLockHeld if another
+// holder has the lock and it hasn't expired at t; the holder acquiring it
+// again renews it.
+func (data *Data) AcquireRestartLock(holder string, nodeID uint64, t time.Time, ttl time.Duration) error {
+	if holder == "" {
+		return ErrRestartLockHolderRequired
+	}
+	if l := data.RestartLock; l != nil && l.Holder != holder && t.Before(l.Expiration) {
+		return ErrRestartLockHeld
+	}
+	data.RestartLock = &RestartLock{
+		Holder:     holder,
+		NodeID:     nodeID,
+		Expiration: t.Add(ttl).UTC(),
+	}
+	return nil
+}
+
+// ReleaseRestartLock releases the restart lock held by holder.
+func (data *Data) ReleaseRestartLock(holder string) error {
+	if holder == "" {
+		return ErrRestartLockHolderRequired
+	} else if data.RestartLock == nil || data.RestartLock.Holder != holder {
+		return ErrRestartLockNotHeld
+	}
+	data.RestartLock = nil
+	return nil
+}
+
+// AlterRetentionPolicyReplicaN sets the replication factor of a retention
+// policy and re-places the shards of its shard groups that aren't deleted to
+// match: shards short of replicas gain pending owners among the data nodes
+// that don't already hold a replica, which become owners once they have
+// copied the shard, and those with too many lose pending owners first, then
+// owners. Within a shard group replicas are added to, and removed from, the
+// data nodes owning the fewest and the most of its shards respectively, so
+// the group stays balanced. It fails with ErrNoReplicaNode if a shard needs
+// a replica no data node is left to hold.
+func (data *Data) AlterRetentionPolicyReplicaN(database, name string, replicaN int) error {
+	if replicaN < 1 {
+		return ErrReplicationFactorTooLow
+	}
+	rpi, err := data.Data.RetentionPolicy(database, name)
+	if err != nil {
+		return err
+	} else if rpi == nil {
+		return influxdb.ErrRetentionPolicyNotFound(name)
+	}
+	rpi.ReplicaN = replicaN
+
+	// There can't be more replicas than data nodes.
+	n := replicaN
+	if n > len(data.DataNodes) {
+		n = len(data.DataNodes)
+	}
+
+	for i := range rpi.ShardGroups {
+		sgi := &rpi.ShardGroups[i]
+		if sgi.Deleted() {
+			continue
+		}
+
+		owned := make(map[uint64]int)
+		for _, si := range sgi.Shards {
+			for _, o := range si.Owners {
+				owned[o.NodeID]++
+			}
+			for _, id := range data.pendingShardOwners(si.ID) {
+				owned[id]++
+			}
+		}
+
+		for j := range sgi.Shards {
+			si := &sgi.Shards[j]
+			pending := data.pendingShardOwners(si.ID)
+			for len(si.Owners)+len(pending) < n {
+				var best *NodeInfo
+				for k := range data.DataNodes {
+					node := &data.DataNodes[k]
+					if si.OwnedBy(node.ID) || containsUint64(pending, node.ID) {
+						continue
+					}
+					if best == nil || owned[node.ID] < owned[best.ID] {
+						best = node
+					}
+				}
+				if best == nil {
+					return ErrNoReplicaNode
+				}
+				if err := data.AddPendingShardOwner(si.ID, best.ID); err != nil {
+					return err
+				}
+				pending = append(pending, best.ID)
+				owned[best.ID]++
+			}
+			for len(pending) > 0 && len(si.Owners)+len(pending) > replicaN {
+				worst := 0
+				for k, id := range pending {
+					if w := pending[worst]; owned[id] > owned[w] || (owned[id] == owned[w] && id > w) {
+						worst = k
+					}
+				}
+				data.RemovePendingShardOwner(si.ID, pending[worst])
+				owned[pending[worst]]--
+				pending = append(pending[:worst], pending[worst+1:]...)
+			}
+			for len(si.Owners) > replicaN {
+				worst := 0
+				for k, o := range si.Owners {
+					if w := si.Owners[worst].NodeID; owned[o.NodeID] > owned[w] || (owned[o.NodeID] == owned[w] && o.NodeID > w) {
+						worst = k
+					}
+				}
+				owned[si.Owners[worst].NodeID]--
+				si.Owners = append(si.Owners[:worst], si.Owners[worst+1:]...)
+			}
+		}
+	}
+	return nil
+}
+
+// ShardGroupCount returns the number of shard groups of database that
+// haven't been deleted, across all its retention policies.
+func (data *Data) ShardGroupCount(database string) uint64 {
+	di := data.Data.Database(database)
+	if di == nil {
+		return 0
+	}
+	var n uint64
+	for _, rp := range di.RetentionPolicies {
+		for _, sg := range rp.ShardGroups {
+			if !sg.Deleted() {
+				n++
+			}
+		}
+	}
+	return n
+}
+
 func (data *Data) gcd() {
 
 }
@@ -542,24 +999,94 @@ func (data *Data) TruncateShardsGrops(sg *meta.ShardGroupInfo) error {
 	return nil
 }
 
-// AddPendingShardOwner adds a pending shardOwner according to nodeID.
-func (data *Data) AddPendingShardOwner(id uint64) {
-	for _, node := range data.MetaNodes {
-		node.PendingShardOwners = append(node.PendingShardOwners, id)
+// AddPendingShardOwner makes the data node nodeID a pending owner of the
+// shard id: one that is to hold a replica of it, but has yet to copy it.
+func (data *Data) AddPendingShardOwner(id, nodeID uint64) error {
+	node := data.DataNode(nodeID)
+	if node == nil {
+		return ErrNodeNotFound
+	}
+	for _, pso := range node.PendingShardOwners {
+		if pso == id {
+			return nil
+		}
+	}
+	node.PendingShardOwners = append(node.PendingShardOwners, id)
+	return nil
+}
+
+// RemovePendingShardOwner drops the pending ownership of the shard id by the
+// data node nodeID, as when it gives up copying the shard.
+func (data *Data) RemovePendingShardOwner(id, nodeID uint64) {
+	if node := data.DataNode(nodeID); node != nil {
+		node.PendingShardOwners = removeUint64(node.PendingShardOwners, id)
+	}
+}
+
+// CommitPendingShardOwner makes the data node nodeID, a pending owner of
+// the shard id, one of its owners, once it has copied the shard.
+func (data *Data) CommitPendingShardOwner(id, nodeID uint64) error {
+	node := data.DataNode(nodeID)
+	if node == nil {
+		return ErrNodeNotFound
+	}
+	if !containsUint64(node.PendingShardOwners, id) {
+		return ErrShardOwnerNotPending
+	}
+
+	si := data.shard(id)
+	if si == nil {
+		return ErrShardNotFound
+	}
+	node.PendingShardOwners = removeUint64(node.PendingShardOwners, id)
+	if !si.OwnedBy(nodeID) {
+		si.Owners = append(si.Owners, meta.ShardOwner{NodeID: nodeID})
+	}
+	return nil
+}
+
+// pendingShardOwners returns the data nodes that are pending owners of the
+// shard id.
+func (data *Data) pendingShardOwners(id uint64) []uint64 {
+	var nodes []uint64
+	for _, n := range data.DataNodes {
+		for _, pso := range n.PendingShardOwners {
+			if pso == id {
+				nodes = append(nodes, n.ID)
+			}
+		}
 	}
+	return nodes
 }
 
-// RemovePendingShardOwner removes a pending shardOwner according to nodeID.
-func (data *Data) RemovePendingShardOwner(id uint64) {
-	for _, node := range data.MetaNodes {
-		newPso := uint64arr{}
-		for _, pso := range node.PendingShardOwners {
-			if id != pso {
-				newPso = append(newPso, pso)
+// shard returns the shard id, so it can be changed in place, or nil if it
+// doesn't exist.
+func (data *Data) shard(id uint64) *meta.ShardInfo {
+	for i := range data.Data.Databases {
+		dbi := &data.Data.Databases[i]
+		for j := range dbi.RetentionPolicies {
+			rpi := &dbi.RetentionPolicies[j]
+			for k := range rpi.ShardGroups {
+				sgi := &rpi.ShardGroups[k]
+				for l := range sgi.Shards {
+					if sgi.Shards[l].ID == id {
+						return &sgi.Shards[l]
+					}
+				}
 			}
 		}
-		node.PendingShardOwners = newPso
 	}
+	return nil
+}
+
+// containsUint64 returns whether a holds v.
+func containsUint64(a []uint64, v uint64) bool {
+	for _, x := range a {
+		if x == v {
+			return true
+		}
+	}
+	return false
 }
 
 // ShardOwners is an array ot ShardOwner.
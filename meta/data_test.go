@@ -30,3 +30,49 @@ func testnewShardOwner(t *testing.T) {
 		t.Errorf("got owner frequencies %v, expected %v", got, exp)
 	}
 }
+
+// Ensure the highest node ID handed out survives a round trip through the
+// binary encoding, so a node ID is never reused once its node is deleted.
+func TestData_UnmarshalBinary_MaxNodeID(t *testing.T) {
+	data := &Data{Data: &meta.Data{}}
+	if err := data.CreateMetaNode("meta0:8091", "meta0:8089"); err != nil {
+		t.Fatal(err)
+	}
+	if err := data.CreateDataNode("data0:8086", "data0:8088"); err != nil {
+		t.Fatal(err)
+	}
+	id := data.DataNodes[0].ID
+	if err := data.DeleteDataNode(id); err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := data.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := &Data{}
+	if err := other.UnmarshalBinary(buf); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := other.MaxNodeID, data.MaxNodeID; got != exp {
+		t.Fatalf("got max node id %d, expected %d", got, exp)
+	}
+
+	if err := other.CreateDataNode("data1:8086", "data1:8088"); err != nil {
+		t.Fatal(err)
+	} else if got := other.DataNodes[0].ID; got <= id {
+		t.Fatalf("new data node got id %d, expected one above the deleted node's %d", got, id)
+	}
+}
+
+// Ensure the quorum status counts the raft voters, and a peer that isn't
+// one yet doesn't make its removal unsafe.
+func TestNewQuorumStatus(t *testing.T) {
+	voters := []string{"meta0:8089", "meta1:8089"}
+	if qs := newQuorumStatus(voters, "meta1:8089"); qs.Voters != 2 || qs.Quorum != 2 || qs.RemovalSafe {
+		t.Fatalf("unexpected quorum status removing a voter: %+v", qs)
+	}
+	if qs := newQuorumStatus(voters, "meta2:8089"); qs.Voters != 2 || qs.Quorum != 2 || !qs.RemovalSafe {
+		t.Fatalf("unexpected quorum status removing a peer that isn't a voter: %+v", qs)
+	}
+}
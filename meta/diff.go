@@ -0,0 +1,111 @@
+package meta
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+// ChangeOp is the kind of change an object went through between two
+// snapshots.
+type ChangeOp string
+
+// Change operations reported by DiffSnapshots.
+const (
+	ChangeAdded   ChangeOp = "added"
+	ChangeRemoved ChangeOp = "removed"
+	ChangeChanged ChangeOp = "changed"
+)
+
+// Change is a single difference between two snapshots.
+type Change struct {
+	Op ChangeOp
+
+	// Kind is the type of object: database, retention policy, shard group,
+	// meta node or data node.
+	Kind string
+
+	// Name identifies the object. Retention policies are named db.rp, shard
+	// groups db.rp.id and nodes by their ID.
+	Name string
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s %s %s", c.Op, c.Kind, c.Name)
+}
+
+// DiffSnapshots returns every database, retention policy, shard group and
+// node that was added, removed or changed going from a to b, sorted by kind
+// and name.
+func DiffSnapshots(a, b *Data) []Change {
+	var changes []Change
+	diff := func(kind string, x, y map[string]interface{}) {
+		for name, xv := range x {
+			if yv, ok := y[name]; !ok {
+				changes = append(changes, Change{Op: ChangeRemoved, Kind: kind, Name: name})
+			} else if !reflect.DeepEqual(xv, yv) {
+				changes = append(changes, Change{Op: ChangeChanged, Kind: kind, Name: name})
+			}
+		}
+		for name := range y {
+			if _, ok := x[name]; !ok {
+				changes = append(changes, Change{Op: ChangeAdded, Kind: kind, Name: name})
+			}
+		}
+	}
+
+	adbs, arps, asgs := snapshotSchema(a)
+	bdbs, brps, bsgs := snapshotSchema(b)
+	diff("database", adbs, bdbs)
+	diff("retention policy", arps, brps)
+	diff("shard group", asgs, bsgs)
+	diff("meta node", snapshotNodes(a.MetaNodes), snapshotNodes(b.MetaNodes))
+	diff("data node", snapshotNodes(a.DataNodes), snapshotNodes(b.DataNodes))
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Kind != changes[j].Kind {
+			return changes[i].Kind < changes[j].Kind
+		}
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
+
+// snapshotSchema indexes the databases, retention policies and shard groups
+// of data by name, keeping only the fields that count as a change.
+func snapshotSchema(data *Data) (dbs, rps, sgs map[string]interface{}) {
+	dbs = make(map[string]interface{})
+	rps = make(map[string]interface{})
+	sgs = make(map[string]interface{})
+	if data == nil || data.Data == nil {
+		return dbs, rps, sgs
+	}
+
+	for _, db := range data.Databases {
+		dbs[db.Name] = db.DefaultRetentionPolicy
+		for _, rp := range db.RetentionPolicies {
+			rpName := db.Name + "." + rp.Name
+			rps[rpName] = [3]interface{}{rp.Duration, rp.ShardGroupDuration, rp.ReplicaN}
+			for _, sg := range rp.ShardGroups {
+				sgs[rpName+"."+strconv.FormatUint(sg.ID, 10)] = struct {
+					StartTime, EndTime, DeletedAt, TruncatedAt interface{}
+					Shards                                     []meta.ShardInfo
+				}{sg.StartTime.UnixNano(), sg.EndTime.UnixNano(), sg.DeletedAt.UnixNano(), sg.TruncatedAt.UnixNano(), sg.Shards}
+			}
+		}
+	}
+	return dbs, rps, sgs
+}
+
+// snapshotNodes indexes nodes by ID. Start times are left out since they
+// move on every restart.
+func snapshotNodes(nodes NodeInfos) map[string]interface{} {
+	m := make(map[string]interface{}, len(nodes))
+	for _, n := range nodes {
+		m[strconv.FormatUint(n.ID, 10)] = [2]string{n.Host, n.TCPHost}
+	}
+	return m
+}
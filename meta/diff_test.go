@@ -0,0 +1,80 @@
+package meta_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// Ensure the diff of two snapshots lists exactly what changed between them.
+func TestDiffSnapshots(t *testing.T) {
+	a := &cloudMeta.Data{Data: &meta.Data{Index: 1}}
+	for _, host := range []string{"data0", "data1"} {
+		if err := a.CreateDataNode(host+":8086", host+":8088"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.CreateMetaNode("meta0:8091", "meta0:8089"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"db0", "db1"} {
+		if err := a.CreateDatabase(name); err != nil {
+			t.Fatal(err)
+		}
+		rpi := meta.NewRetentionPolicyInfo("rp0")
+		rpi.Duration = 24 * time.Hour
+		if err := a.CreateRetentionPolicy(name, rpi, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.CreateShardGroup("db0", "rp0", time.Unix(0, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Round trip through the snapshot encoding like a backup file would.
+	buf, err := a.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &cloudMeta.Data{}
+	if err := b.UnmarshalBinary(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if changes := cloudMeta.DiffSnapshots(a, b); len(changes) != 0 {
+		t.Fatalf("unexpected changes between identical snapshots: %v", changes)
+	}
+
+	if err := b.DropDatabase("db1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.CreateDatabase("db2"); err != nil {
+		t.Fatal(err)
+	}
+	b.Database("db0").RetentionPolicy("rp0").Duration = 48 * time.Hour
+	if err := b.CreateShardGroup("db0", "rp0", time.Unix(0, 0).Add(48*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.DeleteDataNode(2); err != nil {
+		t.Fatal(err)
+	}
+	b.MetaNodes[0].Host = "meta0:9091"
+
+	exp := []cloudMeta.Change{
+		{Op: cloudMeta.ChangeRemoved, Kind: "data node", Name: "2"},
+		{Op: cloudMeta.ChangeRemoved, Kind: "database", Name: "db1"},
+		{Op: cloudMeta.ChangeAdded, Kind: "database", Name: "db2"},
+		{Op: cloudMeta.ChangeChanged, Kind: "meta node", Name: "3"},
+		{Op: cloudMeta.ChangeChanged, Kind: "retention policy", Name: "db0.rp0"},
+		{Op: cloudMeta.ChangeRemoved, Kind: "retention policy", Name: "db1.rp0"},
+		// Dropping data node 2 also removes it from the shard owners.
+		{Op: cloudMeta.ChangeChanged, Kind: "shard group", Name: "db0.rp0.1"},
+		{Op: cloudMeta.ChangeAdded, Kind: "shard group", Name: "db0.rp0.2"},
+	}
+	if changes := cloudMeta.DiffSnapshots(a, b); !reflect.DeepEqual(changes, exp) {
+		t.Fatalf("unexpected changes:\ngot: %v\nexp: %v", changes, exp)
+	}
+}
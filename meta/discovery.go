@@ -0,0 +1,166 @@
+package meta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// srvScheme prefixes a join peer that stands for the meta servers named by
+// the SRV records of a DNS name, as in dns+srv://_meta._tcp.example.com.
+const srvScheme = "dns+srv://"
+
+// srvLookupTimeout bounds a single lookup of the SRV records of a join peer.
+const srvLookupTimeout = 10 * time.Second
+
+// SRVResolver looks up the SRV records of a DNS name. *net.Resolver
+// implements it. It is set as Config.SRVResolver; without one, the system
+// resolver is used.
+type SRVResolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (cname string, addrs []*net.SRV, err error)
+}
+
+// parseSRVPeer returns the DNS name whose SRV records the join peer stands
+// for, or "" if the peer is a host:port.
+func parseSRVPeer(peer string) (string, error) {
+	if !strings.HasPrefix(peer, srvScheme) {
+		return "", nil
+	}
+	name := strings.TrimSuffix(strings.TrimPrefix(peer, srvScheme), "/")
+	if name == "" || strings.ContainsAny(name, "/:?#@") {
+		return "", fmt.Errorf("%s: expected %s followed by a DNS name", peer, srvScheme)
+	}
+	return name, nil
+}
+
+// hasSRVPeer returns whether any of peers is a dns+srv peer.
+func hasSRVPeer(peers []string) bool {
+	for _, peer := range peers {
+		if strings.HasPrefix(peer, srvScheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// DiscoverMetaServers sets the meta servers of the client to peers, with
+// each dns+srv peer replaced by the meta servers its SRV records name. Those
+// are resolved again every discovery-interval until the client is closed,
+// so the client follows the meta servers as they come and go. A peer that
+// fails to resolve keeps the meta servers it last resolved to; it only fails
+// DiscoverMetaServers if it never resolved.
+func (c *Client) DiscoverMetaServers(peers []string) error {
+	servers, err := c.resolvePeers(peers)
+	if err != nil {
+		return err
+	}
+	c.SetMetaServers(servers)
+
+	if hasSRVPeer(peers) {
+		go c.rediscoverMetaServers(peers)
+	}
+	return nil
+}
+
+// rediscoverMetaServers resolves peers every discovery-interval, updating
+// the meta servers of the client when they change, until it is closed.
+func (c *Client) rediscoverMetaServers(peers []string) {
+	interval := time.Duration(c.config.DiscoveryInterval)
+	if interval <= 0 {
+		interval = DefaultDiscoveryInterval
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+		case <-c.closing:
+			return
+		}
+		servers, err := c.resolvePeers(peers)
+		if err != nil {
+			c.logger.Printf("Resolving the meta servers of %s failed: %s", strings.Join(peers, ", "), err)
+			continue
+		}
+		if !sameServers(servers, c.MetaServers()) {
+			c.logger.Printf("Meta servers changed to %s", strings.Join(servers, ", "))
+			c.SetMetaServers(servers)
+		}
+	}
+}
+
+// resolvePeers returns peers with each dns+srv peer replaced by the meta
+// servers it resolves to, or those it last resolved to if resolving it
+// fails.
+func (c *Client) resolvePeers(peers []string) ([]string, error) {
+	var servers []string
+	for _, peer := range peers {
+		name, err := parseSRVPeer(peer)
+		if err != nil {
+			return nil, err
+		} else if name == "" {
+			servers = append(servers, peer)
+			continue
+		}
+
+		addrs, err := c.lookupSRV(name)
+		c.mu.Lock()
+		if err == nil {
+			c.discovered[peer] = addrs
+		} else if cached, ok := c.discovered[peer]; ok {
+			c.logger.Printf("Warning: resolving %s failed, using the meta servers it last resolved to: %s", peer, err)
+			addrs = cached
+		} else {
+			c.mu.Unlock()
+			return nil, fmt.Errorf("resolve %s: %s", peer, err)
+		}
+		c.mu.Unlock()
+		servers = append(servers, addrs...)
+	}
+	return servers, nil
+}
+
+// lookupSRV returns the host:port of each target of the SRV records of
+// name, sorted.
+func (c *Client) lookupSRV(name string) ([]string, error) {
+	var r SRVResolver = net.DefaultResolver
+	if c.config.SRVResolver != nil {
+		r = c.config.SRVResolver
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), srvLookupTimeout)
+	defer cancel()
+	_, srvs, err := r.LookupSRV(ctx, "", "", name)
+	if err != nil {
+		return nil, err
+	} else if len(srvs) == 0 {
+		return nil, errors.New("no SRV records")
+	}
+
+	addrs := make([]string, 0, len(srvs))
+	for _, srv := range srvs {
+		addrs = append(addrs, net.JoinHostPort(strings.TrimSuffix(srv.Target, "."), strconv.Itoa(int(srv.Port))))
+	}
+	sort.Strings(addrs)
+	return addrs, nil
+}
+
+// sameServers returns whether a and b list the same meta servers in the
+// same order.
+func sameServers(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
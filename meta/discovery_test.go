@@ -0,0 +1,126 @@
+package meta_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// srvResolver is a SRVResolver answering every lookup with its records, or
+// its error if set.
+type srvResolver struct {
+	mu    sync.Mutex
+	srvs  []*net.SRV
+	err   error
+	names []string
+}
+
+func (r *srvResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.names = append(r.names, name)
+	return name, r.srvs, r.err
+}
+
+func (r *srvResolver) set(srvs []*net.SRV, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.srvs, r.err = srvs, err
+}
+
+// newSRV returns the SRV record of the meta server at addr.
+func newSRV(t *testing.T, addr string) *net.SRV {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &net.SRV{Target: host + ".", Port: uint16(p)}
+}
+
+// Ensure a client given a dns+srv peer uses the meta servers its SRV records
+// resolve to, keeps them while resolving fails, and follows them as they
+// change.
+func TestClient_DiscoverMetaServers(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	r := &srvResolver{srvs: []*net.SRV{newSRV(t, s.HTTPAddr())}}
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	cfg.SRVResolver = r
+	cfg.DiscoveryInterval = toml.Duration(10 * time.Millisecond)
+
+	client := cloudMeta.NewClient(cfg)
+	if err := client.DiscoverMetaServers([]string{"dns+srv://_meta._tcp.example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if got, exp := client.MetaServers(), []string{s.HTTPAddr()}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected meta servers: got %v, exp %v", got, exp)
+	}
+	r.mu.Lock()
+	name := r.names[0]
+	r.mu.Unlock()
+	if name != "_meta._tcp.example.com" {
+		t.Fatalf("unexpected SRV lookup: %s", name)
+	}
+	if _, err := client.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A failed resolution keeps the meta servers last resolved.
+	r.set(nil, errors.New("no such host"))
+	time.Sleep(50 * time.Millisecond)
+	if got, exp := client.MetaServers(), []string{s.HTTPAddr()}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected meta servers after a failed lookup: got %v, exp %v", got, exp)
+	}
+
+	r.set([]*net.SRV{newSRV(t, s.HTTPAddr()), {Target: "127.0.0.1.", Port: 1}}, nil)
+	exp := []string{"127.0.0.1:1", s.HTTPAddr()}
+	if exp[1] < exp[0] {
+		exp[0], exp[1] = exp[1], exp[0]
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !reflect.DeepEqual(client.MetaServers(), exp) {
+		if time.Now().After(deadline) {
+			t.Fatalf("meta servers not updated: got %v, exp %v", client.MetaServers(), exp)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Ensure a dns+srv peer that never resolved fails discovery.
+func TestClient_DiscoverMetaServers_Unresolved(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	cfg.SRVResolver = &srvResolver{err: errors.New("no such host")}
+
+	client := cloudMeta.NewClient(cfg)
+	defer client.Close()
+	if err := client.DiscoverMetaServers([]string{"dns+srv://_meta._tcp.example.com"}); err == nil {
+		t.Fatal("expected an error")
+	}
+}
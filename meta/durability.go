@@ -0,0 +1,65 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/uber-go/zap"
+)
+
+// DurabilityHeader is the /execute request header a client sets to the
+// durability, WriteDurabilityQuorum or WriteDurabilityAll, its write must
+// have before it is acknowledged.
+const DurabilityHeader = "X-Meta-Durability"
+
+// durabilityPollInterval is how often the leader checks the raft logs of
+// the other meta nodes while a write waits for WriteDurabilityAll.
+const durabilityPollInterval = 10 * time.Millisecond
+
+// writeDurability returns the durability the write r asks for, or
+// write-durability if it doesn't say.
+func (h *handler) writeDurability(r *http.Request) (string, error) {
+	switch v := r.Header.Get(DurabilityHeader); v {
+	case "":
+		return h.config.WriteDurability, nil
+	case WriteDurabilityQuorum, WriteDurabilityAll:
+		return v, nil
+	default:
+		return "", fmt.Errorf("invalid %s header %q", DurabilityHeader, v)
+	}
+}
+
+// waitDurable blocks until the raft log of every other meta node holds the
+// entry at index, as reported by its /raft-status. Raft committing the entry
+// already means a majority of them fsynced it. It returns ErrWriteNotDurable
+// if that takes longer than write-durability-timeout, or ctx is done first.
+func (h *handler) waitDurable(ctx context.Context, index uint64) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(h.config.WriteDurabilityTimeout))
+	defer cancel()
+
+	lagging := h.store.otherMetaServersHTTP()
+	for {
+		var behind []string
+		for _, server := range lagging {
+			if st, err := h.peerClient.raftStatusContext(ctx, server); err != nil || st.LastIndex < index {
+				behind = append(behind, server)
+			}
+		}
+		if len(behind) == 0 {
+			return nil
+		}
+		lagging = behind
+
+		select {
+		case <-time.After(durabilityPollInterval):
+		case <-ctx.Done():
+			h.logger.Warn("write not fsynced on every meta node in time",
+				zap.Uint64("index", index),
+				zap.String("lagging", strings.Join(lagging, ",")))
+			return ErrWriteNotDurable
+		}
+	}
+}
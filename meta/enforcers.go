@@ -0,0 +1,174 @@
+package meta
+
+import (
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/uber-go/zap"
+	"github.com/zhexuany/influxcloud/meta/internal"
+)
+
+// The names of the leader tasks enforcing the shard group lifecycle and data
+// node liveness, as passed to PauseLeaderTask and ResumeLeaderTask.
+const (
+	// ShardPrecreatorTask creates the next shard group of retention
+	// policies whose last one ends soon.
+	ShardPrecreatorTask = "shard-precreator"
+
+	// ShardGroupReaperTask purges shard groups from the metadata once their
+	// pending deletion expires.
+	ShardGroupReaperTask = "shard-group-reaper"
+
+	// HeartbeatCheckerTask logs the data nodes that stopped or resumed
+	// sending heartbeats.
+	HeartbeatCheckerTask = "heartbeat-checker"
+)
+
+// precreateShardGroups creates the shard group following the last one of
+// every retention policy, if that ends within the advance period.
+func (s *Service) precreateShardGroups() {
+	data, err := s.store.snapshot()
+	if err != nil {
+		s.Logger.Error("shard precreation: snapshot failed", zap.Error(err))
+		return
+	}
+
+	from := s.now()
+	to := from.Add(time.Duration(s.config.ShardPrecreationAdvancePeriod))
+	for _, di := range data.Databases {
+		for _, rp := range di.RetentionPolicies {
+			if len(rp.ShardGroups) == 0 {
+				continue
+			}
+			// Groups wholly in the past are never precreated.
+			g := rp.ShardGroups[len(rp.ShardGroups)-1]
+			if g.Deleted() || !g.EndTime.Before(to) || !g.EndTime.After(from) {
+				continue
+			}
+
+			t := internal.Command_CreateShardGroupCommand
+			cmd := &internal.Command{Type: &t}
+			if err := proto.SetExtension(cmd, internal.E_CreateShardGroupCommand_Command, &internal.CreateShardGroupCommand{
+				Database:  proto.String(di.Name),
+				Policy:    proto.String(rp.Name),
+				Timestamp: proto.Int64(g.EndTime.Add(time.Nanosecond).UnixNano()),
+			}); err != nil {
+				panic(err)
+			}
+			b, err := proto.Marshal(cmd)
+			if err != nil {
+				panic(err)
+			}
+			if err := s.store.apply(b); err != nil {
+				s.Logger.Error("shard precreation: create failed",
+					zap.String("database", di.Name),
+					zap.String("retention-policy", rp.Name),
+					zap.Uint64("after-shard-group", g.ID),
+					zap.Error(err))
+				continue
+			}
+			s.Logger.Info("shard precreation: created shard group",
+				zap.String("database", di.Name),
+				zap.String("retention-policy", rp.Name),
+				zap.Uint64("after-shard-group", g.ID))
+		}
+	}
+}
+
+// reapShardGroups purges the shard groups whose pending deletion has
+// expired from the metadata.
+func (s *Service) reapShardGroups() {
+	data, err := s.store.snapshot()
+	if err != nil {
+		s.Logger.Error("shard group reaper: snapshot failed", zap.Error(err))
+		return
+	}
+	t := s.now()
+	var n int
+	for _, d := range data.PendingDeletions() {
+		if d.PurgeAt.Before(t) {
+			n++
+		}
+	}
+	if n == 0 {
+		return
+	}
+
+	// The purge time is part of the command so every node purges the same
+	// shard groups whatever its own clock says.
+	typ := internal.Command_PurgeShardGroupsCommand
+	cmd := &internal.Command{Type: &typ}
+	if err := proto.SetExtension(cmd, internal.E_PurgeShardGroupsCommand_Command, &internal.PurgeShardGroupsCommand{
+		Time: proto.Int64(t.UnixNano()),
+	}); err != nil {
+		panic(err)
+	}
+	b, err := proto.Marshal(cmd)
+	if err != nil {
+		panic(err)
+	}
+	if err := s.store.apply(b); err != nil {
+		s.Logger.Error("shard group reaper: purge failed", zap.Error(err))
+		return
+	}
+	s.Logger.Info("shard group reaper: purged expired shard groups", zap.Int("shard-groups", n))
+}
+
+// PurgeShardGroups removes the shard groups whose pending deletion expired
+// before t and returns how many it removed.
+func (data *Data) PurgeShardGroups(t time.Time) int {
+	expiration := t.Add(meta.ShardGroupDeletedExpiration)
+	var n int
+	for i := range data.Databases {
+		for j := range data.Databases[i].RetentionPolicies {
+			rp := &data.Databases[i].RetentionPolicies[j]
+			var remaining []meta.ShardGroupInfo
+			for _, sg := range rp.ShardGroups {
+				if sg.Deleted() && expiration.After(sg.DeletedAt) {
+					n++
+					continue
+				}
+				remaining = append(remaining, sg)
+			}
+			rp.ShardGroups = remaining
+		}
+	}
+	return n
+}
+
+func (fsm *storeFSM) applyPurgeShardGroupsCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_PurgeShardGroupsCommand_Command)
+	v := ext.(*internal.PurgeShardGroupsCommand)
+
+	other := fsm.data.Clone()
+	if other.PurgeShardGroups(time.Unix(0, v.GetTime())) == 0 {
+		return nil
+	}
+	fsm.data = other
+	return nil
+}
+
+// checkHeartbeats logs the data nodes whose heartbeats stopped or resumed
+// since the last check.
+func (s *Service) checkHeartbeats() {
+	data, err := s.store.snapshot()
+	if err != nil {
+		s.Logger.Error("heartbeat check: snapshot failed", zap.Error(err))
+		return
+	}
+	ids := make([]uint64, 0, len(data.DataNodes))
+	for _, n := range data.DataNodes {
+		ids = append(ids, n.ID)
+	}
+
+	down, up := s.liveness.changed(ids)
+	for _, id := range down {
+		s.Logger.Warn("heartbeat check: data node stopped sending heartbeats",
+			zap.Uint64("node-id", id),
+			zap.Duration("timeout", s.liveness.timeout))
+	}
+	for _, id := range up {
+		s.Logger.Info("heartbeat check: data node is sending heartbeats again", zap.Uint64("node-id", id))
+	}
+}
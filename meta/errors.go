@@ -30,6 +30,91 @@ var (
 	// ErrNodeUnableToDropFinalNode is returned if the node being dropped is the last
 	// node in the cluster
 	ErrNodeUnableToDropFinalNode = errors.New("unable to drop the final node in a cluster")
+
+	// ErrQuorumUnsafe is returned when removing a meta node would leave
+	// fewer voters than the current quorum.
+	ErrQuorumUnsafe = errors.New("removing meta node would break quorum")
+
+	// ErrTopologyFrozen is returned when a membership or shard placement
+	// change is attempted while the cluster topology is frozen.
+	ErrTopologyFrozen = errors.New("cluster topology is frozen")
+
+	// ErrShardGroupQuotaExceeded is returned when creating a shard group
+	// would take a database past its shard group quota.
+	ErrShardGroupQuotaExceeded = errors.New("shard group quota exceeded")
+
+	// ErrQuorumLost is returned when a write fails fast since the meta
+	// cluster has had no quorum to commit it for the quorum-loss-timeout.
+	ErrQuorumLost = errors.New("meta cluster has no quorum")
+
+	// ErrCommitTimeout is returned when a write isn't committed within the
+	// quorum-loss-timeout although the leader still has a quorum, as when
+	// the followers are slow. The write may still be applied.
+	ErrCommitTimeout = errors.New("timed out waiting for the write to commit")
+
+	// ErrWriteNotDurable is returned when a committed write isn't fsynced
+	// to as many raft logs as its durability asks for in time.
+	ErrWriteNotDurable = errors.New("write not as durable as requested")
+
+	// ErrLeaderWaitTimeout is returned when no meta server knew of a
+	// leader in time.
+	ErrLeaderWaitTimeout = errors.New("timed out waiting for a meta leader")
+
+	// ErrApplyTimeout is returned when a raft log entry isn't applied to
+	// the local state machine in time.
+	ErrApplyTimeout = errors.New("timed out waiting for index to be applied")
+
+	// ErrLeaderNotVerified is returned when a quorum doesn't confirm the
+	// node's leadership in time.
+	ErrLeaderNotVerified = errors.New("leadership not verified")
+
+	// ErrConfigChangeInProgress is returned when a raft membership change is
+	// requested while another one is still being applied.
+	ErrConfigChangeInProgress = errors.New("raft configuration change in progress")
+
+	// ErrLeaderTaskNotFound is returned when pausing or resuming a leader
+	// task that isn't registered.
+	ErrLeaderTaskNotFound = errors.New("leader task not found")
+
+	// ErrNoLeadershipTarget is returned when handing off leadership while
+	// no other meta node could take it over.
+	ErrNoLeadershipTarget = errors.New("no follower to transfer leadership to")
+
+	// ErrLeadershipTransferTimeout is returned when no other meta node took
+	// over leadership in time.
+	ErrLeadershipTransferTimeout = errors.New("timed out waiting for a new leader")
+
+	// ErrTaskNotFound is returned when canceling a background task that
+	// isn't running.
+	ErrTaskNotFound = errors.New("task not found")
+
+	// ErrNotAuthorized is returned when a principal may not carry out an
+	// operation.
+	ErrNotAuthorized = errors.New("not authorized")
+
+	// ErrUnauthenticated is returned for a request to the HTTP API that
+	// carries no valid credentials while auth-token or auth-basic is set.
+	ErrUnauthenticated = errors.New("authentication required")
+
+	// ErrRestartLockHeld is returned when acquiring the restart lock while
+	// another holder has it.
+	ErrRestartLockHeld = errors.New("restart lock is held")
+
+	// ErrRestartLockNotHeld is returned when releasing the restart lock
+	// without holding it.
+	ErrRestartLockNotHeld = errors.New("restart lock is not held")
+
+	// ErrRestartLockHolderRequired is returned when acquiring or releasing
+	// the restart lock without a holder.
+	ErrRestartLockHolderRequired = errors.New("restart lock holder required")
+
+	// ErrMetadataVersionLowered is returned when setting the metadata
+	// version of the cluster below its current one.
+	ErrMetadataVersionLowered = errors.New("metadata version can't be lowered")
+
+	// ErrSnapshotChecksum is returned when loading a raft snapshot whose
+	// metadata doesn't match the checksum it was written with.
+	ErrSnapshotChecksum = errors.New("snapshot checksum mismatch")
 )
 
 var (
@@ -41,6 +126,19 @@ var (
 
 	// ErrDatabaseNameRequired is returned when creating a database without a name.
 	ErrDatabaseNameRequired = errors.New("database name required")
+
+	// ErrAnnotationKeyRequired is returned when setting an annotation
+	// without a key.
+	ErrAnnotationKeyRequired = errors.New("annotation key required")
+
+	// ErrAnnotationTooLarge is returned when an annotation key or value is
+	// past its size limit.
+	ErrAnnotationTooLarge = fmt.Errorf("annotation keys are limited to %d bytes and values to %d bytes",
+		MaxAnnotationKeySize, MaxAnnotationValueSize)
+
+	// ErrTooManyAnnotations is returned when adding an annotation to a
+	// database that has as many as it may have.
+	ErrTooManyAnnotations = fmt.Errorf("databases are limited to %d annotations", MaxDatabaseAnnotations)
 )
 
 var (
@@ -82,6 +180,17 @@ var (
 	// ErrShardNotReplicated is returned if the node requested to be dropped has
 	// the last copy of a shard present and the force keyword was not used
 	ErrShardNotReplicated = errors.New("shard not replicated")
+
+	// ErrShardNotFound is returned when mutating a shard that doesn't exist.
+	ErrShardNotFound = errors.New("shard not found")
+
+	// ErrShardOwnerNotPending is returned when committing the ownership of
+	// a shard by a data node that isn't pending.
+	ErrShardOwnerNotPending = errors.New("shard owner not pending")
+
+	// ErrNoReplicaNode is returned when a shard needs another replica but
+	// every data node already holds one.
+	ErrNoReplicaNode = errors.New("no data node left to hold a replica")
 )
 
 var (
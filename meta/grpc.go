@@ -0,0 +1,306 @@
+package meta
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/uber-go/zap"
+	"github.com/zhexuany/influxcloud/meta/internal"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// defaultResignTimeout is how long ResignLeadership waits for a new leader
+// when the call sets no timeout.
+const defaultResignTimeout = 10 * time.Second
+
+// controlMethod is the handler name a method of the gRPC control API is
+// known by to auth-exempt and the unauthenticated counter, and the action
+// it is authorized for.
+type controlMethod struct {
+	name   string
+	action string
+}
+
+// controlMethods holds the methods of the gRPC control API by full name. A
+// method missing from it is denied.
+var controlMethods = map[string]controlMethod{
+	"/internal.Control/Status":           {name: "control-status", action: ActionRead},
+	"/internal.Control/AddMetaNode":      {name: "control-add-meta-node", action: ActionManageNodes},
+	"/internal.Control/RemoveNode":       {name: "control-remove-node", action: ActionManageNodes},
+	"/internal.Control/ResignLeadership": {name: "control-resign-leadership", action: ActionAdmin},
+}
+
+// openGRPC starts serving the gRPC control API on grpc-bind-address, with
+// TLS if HTTPS is enabled.
+func (s *Service) openGRPC(h *handler) error {
+	opts := []grpc.ServerOption{grpc.UnaryInterceptor(h.authenticatingControl)}
+	if s.https {
+		config, err := s.tlsConfig("h2")
+		if err != nil {
+			return err
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(config)))
+	}
+
+	ln, err := net.Listen("tcp", s.config.GRPCBindAddress)
+	if err != nil {
+		return fmt.Errorf("grpc bind address: %s", err)
+	}
+	s.grpcLn = ln
+	s.grpcServer = grpc.NewServer(opts...)
+	internal.RegisterControlServer(s.grpcServer, &controlServer{s: s})
+
+	go func() {
+		err := s.grpcServer.Serve(ln)
+		if err != nil && !strings.Contains(err.Error(), "closed") {
+			s.sendErr(fmt.Errorf("grpc listener failed: addr=%s, err=%s", ln.Addr(), err))
+		}
+	}()
+	return nil
+}
+
+// authenticatingControl authenticates and authorizes the calls to the gRPC
+// control API as authenticating and authorizing do the requests to the
+// HTTP API. The "authorization" metadata of a call carries its bearer token
+// or basic auth credentials, and the resource authorized is the full name
+// of the method.
+func (h *handler) authenticatingControl(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	m, ok := controlMethods[info.FullMethod]
+	if !ok {
+		return nil, grpc.Errorf(codes.Unimplemented, "unknown method %s", info.FullMethod)
+	}
+
+	r := controlRequest(ctx)
+	principal := requestPrincipal(r)
+	if h.config.authRequired() && !h.config.authExempt(m.name) && principal == "" {
+		user, ok := h.authenticate(r)
+		if !ok {
+			h.logger.Info("request not authenticated", zap.String("handler", m.name), zap.String("remote", r.RemoteAddr))
+			h.s.unauthenticated.Inc(m.name)
+			return nil, grpc.Errorf(codes.Unauthenticated, "%s", ErrUnauthenticated)
+		}
+		principal = user
+	}
+
+	if err := h.config.authorizer().Authorize(principal, m.action, info.FullMethod); err != nil {
+		h.logger.Info("request denied", zap.String("principal", principal), zap.String("action", m.action),
+			zap.String("resource", info.FullMethod), zap.Error(err))
+		return nil, grpc.Errorf(codes.PermissionDenied, "%s", err)
+	}
+	return handler(ctx, req)
+}
+
+// controlRequest returns an HTTP request standing for the gRPC call of ctx,
+// with its authorization metadata, remote address and TLS state, to
+// authenticate the call as the HTTP API would.
+func controlRequest(ctx context.Context) *http.Request {
+	r := &http.Request{Header: make(http.Header)}
+	if md, ok := metadata.FromContext(ctx); ok {
+		for _, v := range md["authorization"] {
+			r.Header.Add("Authorization", v)
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		r.RemoteAddr = p.Addr.String()
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			r.TLS = &info.State
+		}
+	}
+	return r
+}
+
+// controlServer serves the gRPC control API of a meta node, going through
+// a Client of the cluster's meta servers to change it, as the command line
+// does.
+type controlServer struct {
+	s *Service
+}
+
+// Status returns the local raft status, as /raft-status does, and the nodes
+// of the cluster.
+func (c *controlServer) Status(ctx context.Context, req *internal.StatusRequest) (*internal.StatusResponse, error) {
+	st := c.s.store.raftStatus()
+	if st == nil {
+		return nil, grpc.Errorf(codes.Unavailable, "raft not open")
+	}
+	data, err := c.s.store.snapshot()
+	if err != nil {
+		return nil, controlError(err)
+	}
+
+	resp := &internal.StatusResponse{
+		State:      proto.String(st.State),
+		Leader:     proto.String(st.Leader),
+		LeaderHTTP: proto.String(c.s.store.leaderHTTP()),
+		Peers:      st.Peers,
+		LastIndex:  proto.Uint64(st.LastIndex),
+		MetaNodes:  controlNodes(data.MetaNodes),
+		DataNodes:  controlNodes(data.DataNodes),
+	}
+	if c.s.Node != nil {
+		resp.NodeID = proto.Uint64(c.s.Node.ID)
+	}
+	return resp, nil
+}
+
+// AddMetaNode joins the meta node at the addresses of req to the cluster,
+// as JoinMetaServer does.
+func (c *controlServer) AddMetaNode(ctx context.Context, req *internal.AddMetaNodeRequest) (*internal.AddMetaNodeResponse, error) {
+	if c.s.Maintenance() {
+		return nil, controlError(ErrMaintenance)
+	}
+	client, err := c.client()
+	if err != nil {
+		return nil, controlError(err)
+	}
+	defer closeOnDone(ctx, client)()
+
+	n, err := client.JoinMetaServer(req.GetHTTPAddr(), req.GetTCPAddr())
+	if err != nil {
+		return nil, controlError(err)
+	}
+	return &internal.AddMetaNodeResponse{Node: controlNode(*n)}, nil
+}
+
+// RemoveNode removes the meta or data node of req from the cluster, as
+// RemoveMetaNode and RemoveDataNode do.
+func (c *controlServer) RemoveNode(ctx context.Context, req *internal.RemoveNodeRequest) (*internal.RemoveNodeResponse, error) {
+	if c.s.Maintenance() {
+		return nil, controlError(ErrMaintenance)
+	}
+	if typ := req.GetType(); typ != "meta" && typ != "data" {
+		return nil, grpc.Errorf(codes.InvalidArgument, "unknown node type %q, expected meta or data", typ)
+	}
+	client, err := c.client()
+	if err != nil {
+		return nil, controlError(err)
+	}
+	if err := client.Open(); err != nil {
+		return nil, controlError(err)
+	}
+	defer closeOnDone(ctx, client)()
+
+	var res *RemoveNodeResult
+	if req.GetType() == "meta" {
+		res, err = client.RemoveMetaNode(req.GetID(), req.GetForce())
+	} else {
+		res, err = client.RemoveDataNode(req.GetID())
+	}
+	if err != nil {
+		return nil, controlError(err)
+	}
+
+	resp := &internal.RemoveNodeResponse{
+		NodeID:                proto.Uint64(res.NodeID),
+		NodeType:              proto.String(res.NodeType),
+		Host:                  proto.String(res.Host),
+		RaftPeerRemoved:       proto.String(res.RaftPeerRemoved),
+		LeaderBefore:          proto.String(res.LeaderBefore),
+		LeaderAfter:           proto.String(res.LeaderAfter),
+		LeadershipTransferred: proto.Bool(res.LeadershipTransferred),
+		DeletedShardGroups:    res.DeletedShardGroups,
+	}
+	for _, r := range res.ReassignedShards {
+		resp.ReassignedShards = append(resp.ReassignedShards, &internal.ShardReassignment{
+			Database:        proto.String(r.Database),
+			RetentionPolicy: proto.String(r.RetentionPolicy),
+			ShardGroupID:    proto.Uint64(r.ShardGroupID),
+			ShardID:         proto.Uint64(r.ShardID),
+			From:            proto.Uint64(r.From),
+			To:              r.To,
+		})
+	}
+	return resp, nil
+}
+
+// ResignLeadership has the node transfer leadership, as the Service's
+// ResignLeadership does, and returns the leader.
+func (c *controlServer) ResignLeadership(ctx context.Context, req *internal.ResignLeadershipRequest) (*internal.ResignLeadershipResponse, error) {
+	timeout := defaultResignTimeout
+	if req.GetTimeout() > 0 {
+		timeout = time.Duration(req.GetTimeout())
+	}
+	leader, err := c.s.ResignLeadership(timeout)
+	if err != nil {
+		return nil, controlError(err)
+	}
+	return &internal.ResignLeadershipResponse{Leader: proto.String(leader)}, nil
+}
+
+// client returns a Client of the meta servers of the cluster, as this node
+// knows them.
+func (c *controlServer) client() (*Client, error) {
+	data, err := c.s.store.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	servers := make([]string, 0, len(data.MetaNodes))
+	for _, n := range data.MetaNodes {
+		servers = append(servers, n.Host)
+	}
+
+	client := NewClient(c.s.config)
+	client.SetMetaServers(servers)
+	client.SetTLS(c.s.config.HTTPSEnabled)
+	return client, nil
+}
+
+// closeOnDone closes client once ctx is done, so a call given up on stops
+// retrying, and returns a function closing it at once.
+func closeOnDone(ctx context.Context, client *Client) func() {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			client.Close()
+		case <-done:
+		}
+	}()
+	return func() {
+		close(done)
+		client.Close()
+	}
+}
+
+// controlNodes returns nodes as the nodes of the gRPC control API.
+func controlNodes(nodes NodeInfos) []*internal.ControlNode {
+	a := make([]*internal.ControlNode, len(nodes))
+	for i, n := range nodes {
+		a[i] = controlNode(n)
+	}
+	return a
+}
+
+func controlNode(n NodeInfo) *internal.ControlNode {
+	return &internal.ControlNode{
+		ID:      proto.Uint64(n.ID),
+		Host:    proto.String(n.Host),
+		TCPHost: proto.String(n.TCPHost),
+	}
+}
+
+// controlError returns err as an error of the gRPC control API, with the
+// status code matching it.
+func controlError(err error) error {
+	code := codes.Unknown
+	switch err {
+	case ErrNodeNotFound:
+		code = codes.NotFound
+	case ErrQuorumUnsafe, ErrNoLeadershipTarget:
+		code = codes.FailedPrecondition
+	case ErrLeadershipTransferTimeout:
+		code = codes.DeadlineExceeded
+	case ErrMaintenance, ErrService, ErrServiceUnavailable:
+		code = codes.Unavailable
+	}
+	return grpc.Errorf(code, "%s", err)
+}
@@ -0,0 +1,147 @@
+package meta_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+	"github.com/zhexuany/influxcloud/meta/internal"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// newGRPCCluster starts a cluster of n meta nodes serving the gRPC control
+// API and requiring token.
+func newGRPCCluster(t *testing.T, n int, token string) *cloudMeta.TestCluster {
+	return cloudMeta.NewTestClusterWithConfig(t, n, func(i int, c *cloudMeta.Config) {
+		c.GRPCBindAddress = "127.0.0.1:0"
+		c.AuthToken = token
+	})
+}
+
+// dialControl returns a client of the gRPC control API of s.
+func dialControl(t *testing.T, s *cloudMeta.Service) (internal.ControlClient, func()) {
+	conn, err := grpc.Dial(s.GRPCAddr(), grpc.WithInsecure(), grpc.WithBlock(), grpc.WithTimeout(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return internal.NewControlClient(conn), func() { conn.Close() }
+}
+
+// withToken returns a context sending token as a bearer token.
+func withToken(token string) context.Context {
+	return metadata.NewContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+}
+
+// Ensure the gRPC status of every node reports the leader /raft-status
+// reports, and that calls without auth-token are refused.
+func TestService_GRPCStatus(t *testing.T) {
+	t.Parallel()
+
+	c := newGRPCCluster(t, 3, "s3cret")
+	defer c.Close()
+	c.Leader(5 * time.Second)
+
+	for i, s := range c.Services {
+		control, closeControl := dialControl(t, s)
+		defer closeControl()
+
+		if _, err := control.Status(context.Background(), &internal.StatusRequest{}); grpc.Code(err) != codes.Unauthenticated {
+			t.Fatalf("node %d: status without a token: got %v, want Unauthenticated", i, err)
+		}
+
+		st, err := control.Status(withToken("s3cret"), &internal.StatusRequest{})
+		if err != nil {
+			t.Fatalf("node %d: %s", i, err)
+		} else if len(st.GetMetaNodes()) != 3 {
+			t.Fatalf("node %d: got %d meta nodes, want 3", i, len(st.GetMetaNodes()))
+		}
+
+		req, err := http.NewRequest("GET", "http://"+s.HTTPAddr()+"/raft-status", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer s3cret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var status struct {
+			Leader string `json:"leader"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&status)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if status.Leader == "" || st.GetLeader() != status.Leader {
+			t.Fatalf("node %d: gRPC status reports leader %q, /raft-status %q", i, st.GetLeader(), status.Leader)
+		}
+	}
+}
+
+// Ensure a leader resigning leadership over gRPC hands it to a follower and
+// goes on as a follower itself.
+func TestService_GRPCResignLeadership(t *testing.T) {
+	t.Parallel()
+
+	c := newGRPCCluster(t, 3, "s3cret")
+	defer c.Close()
+	leader := c.Leader(5 * time.Second)
+
+	control, closeControl := dialControl(t, leader)
+	defer closeControl()
+	resp, err := control.ResignLeadership(withToken("s3cret"), &internal.ResignLeadershipRequest{
+		Timeout: proto.Int64(int64(10 * time.Second)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if resp.GetLeader() == "" || resp.GetLeader() == leader.RaftAddr() {
+		t.Fatalf("got leader %q after resigning, want another node", resp.GetLeader())
+	}
+
+	if l := c.Leader(5 * time.Second); l == nil || l == leader {
+		t.Fatal("still leader after resigning")
+	} else if _, err := c.Client.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+	st, err := control.Status(withToken("s3cret"), &internal.StatusRequest{})
+	if err != nil {
+		t.Fatal(err)
+	} else if st.GetState() != "Follower" {
+		t.Fatalf("got state %q after resigning, want Follower", st.GetState())
+	}
+}
+
+// Ensure a gRPC call is only allowed what the roles of its principal
+// grant: an operator, which may not administer nodes, reads the status but
+// can't resign leadership.
+func TestService_GRPCAuthorizer(t *testing.T) {
+	t.Parallel()
+
+	c := cloudMeta.NewTestClusterWithConfig(t, 1, func(i int, c *cloudMeta.Config) {
+		c.GRPCBindAddress = "127.0.0.1:0"
+		authorizer := cloudMeta.NewRBACAuthorizer()
+		authorizer.AddRole("operator", cloudMeta.ActionRead, cloudMeta.ActionWrite, cloudMeta.ActionManageNodes)
+		authorizer.Grant("", "operator")
+		c.Authorizer = authorizer
+	})
+	defer c.Close()
+	leader := c.Leader(5 * time.Second)
+
+	control, closeControl := dialControl(t, leader)
+	defer closeControl()
+	if _, err := control.Status(context.Background(), &internal.StatusRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := control.ResignLeadership(context.Background(), &internal.ResignLeadershipRequest{})
+	if code := grpc.Code(err); code != codes.PermissionDenied {
+		t.Fatalf("got %v resigning leadership as an operator, want PermissionDenied", err)
+	}
+}
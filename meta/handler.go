@@ -2,6 +2,8 @@ package meta
 
 import (
 	"compress/gzip"
+	"context"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -21,6 +23,14 @@ import (
 	"github.com/zhexuany/influxcloud/meta/internal"
 )
 
+// refreshBarrierTimeout bounds how long /meta/refresh waits for the leader to
+// apply outstanding commands.
+const refreshBarrierTimeout = 5 * time.Second
+
+// verifyLeaderTimeout bounds how long the verifyLeader read option waits for
+// a quorum to confirm the node's leadership.
+const verifyLeaderTimeout = 5 * time.Second
+
 // handler represents an HTTP handler for the meta service.
 type handler struct {
 	config *Config
@@ -34,16 +44,36 @@ type handler struct {
 		leader() string
 		leaderHTTP() string
 		snapshot() (*Data, error)
+		marshaledSnapshot() ([]byte, error)
 		apply(b []byte) error
 		join(n *NodeInfo) (*NodeInfo, error)
+		updateMetaNode(n *NodeInfo) error
+		previewJoin(n *NodeInfo) (*JoinPreview, error)
 		otherMetaServersHTTP() []string
 		peers() []string
+		isLeader() bool
+		applied(timeout time.Duration) error
+		verifyLeader(timeout time.Duration) error
+		raftStatus() *raftStatus
+		adminHash(name string) (string, bool)
+		metaNodeID(tcpHost string) uint64
 	}
 	s *Service
 
 	mu      sync.RWMutex
 	closing chan struct{}
 	leases  *Leases
+
+	idempotency *idempotencyCache
+	inflight    *inflightRequests
+	passwords   *passwordCache
+
+	// peerClient talks to the other meta servers, reusing its connections
+	// across requests.
+	peerClient *Client
+
+	// safeMode serves only the endpoints that don't need the store.
+	safeMode bool
 }
 
 // newHandler returns a new instance of handler with routes.
@@ -55,7 +85,12 @@ func newHandler(c *Config, s *Service) *handler {
 		loggingEnabled: c.ClusterTracing,
 		closing:        make(chan struct{}),
 		leases:         NewLeases(time.Duration(c.LeaseDuration)),
+		idempotency:    s.idempotency,
+		inflight:       newInflightRequests(),
+		passwords:      newPasswordCache(),
+		peerClient:     NewClient(c),
 	}
+	h.peerClient.SetTLS(c.HTTPSEnabled)
 
 	return h
 }
@@ -64,8 +99,15 @@ func newHandler(c *Config, s *Service) *handler {
 func (h *handler) WrapHandler(name string, hf http.HandlerFunc) http.Handler {
 	var handler http.Handler
 	handler = http.HandlerFunc(hf)
-	handler = gzipFilter(handler)
+	handler = refusingMaintenance(handler, name, h)
+	handler = authorizing(handler, name, h)
+	handler = authenticating(handler, name, h)
+	handler = rateLimiting(handler, name, h)
+	handler = counting(handler, name, h)
+	handler = gzipFilter(handler, h.config.GzipLevel)
+	handler = requestTimeout(handler, time.Duration(h.config.MaxRequestTimeout))
 	handler = versionHeader(handler, h)
+	handler = tracking(handler, h.inflight)
 	handler = requestID(handler)
 	if h.loggingEnabled {
 		handler = logging(handler, name, h.logger)
@@ -77,6 +119,17 @@ func (h *handler) WrapHandler(name string, hf http.HandlerFunc) http.Handler {
 
 // ServeHTTP responds to HTTP request to the handler.
 func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// The metrics and debug endpoints move to the debug listener when there
+	// is one.
+	if h.config.DebugBindAddress != "" && (r.URL.Path == "/metrics" || strings.HasPrefix(r.URL.Path, "/debug/")) {
+		http.NotFound(w, r)
+		return
+	}
+	if h.safeMode {
+		h.serveSafeModeHTTP(w, r)
+		return
+	}
+
 	switch r.Method {
 	case "GET":
 		switch r.URL.Path {
@@ -86,6 +139,46 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			h.WrapHandler("lease", h.serveLease).ServeHTTP(w, r)
 		case "/peers":
 			h.WrapHandler("peers", h.servePeers).ServeHTTP(w, r)
+		case "/raft-status":
+			h.WrapHandler("raft-status", h.serveRaftStatus).ServeHTTP(w, r)
+		case "/health":
+			h.WrapHandler("health", h.serveHealth).ServeHTTP(w, r)
+		case "/ready":
+			h.WrapHandler("ready", h.serveReady).ServeHTTP(w, r)
+		case "/debug/features":
+			h.WrapHandler("features", h.serveFeatures).ServeHTTP(w, r)
+		case "/debug/config":
+			h.WrapHandler("config", h.serveConfig).ServeHTTP(w, r)
+		case "/debug/requests":
+			h.WrapHandler("requests", h.serveRequests).ServeHTTP(w, r)
+		case "/debug/apply-errors":
+			h.WrapHandler("apply-errors", h.serveApplyErrors).ServeHTTP(w, r)
+		case "/debug/tls":
+			h.WrapHandler("tls", h.serveTLSSessions).ServeHTTP(w, r)
+		case "/debug/log/tail":
+			h.WrapHandler("log-tail", h.serveLogTail).ServeHTTP(w, r)
+		case "/debug/orphans":
+			h.WrapHandler("orphans", h.serveOrphans).ServeHTTP(w, r)
+		case "/debug/version":
+			h.WrapHandler("version", h.serveVersion).ServeHTTP(w, r)
+		case "/debug/profile/cpu":
+			h.WrapHandler("cpu-profile", h.serveCPUProfile).ServeHTTP(w, r)
+		case "/metrics":
+			h.WrapHandler("metrics", h.serveMetrics).ServeHTTP(w, r)
+		case "/databases":
+			h.WrapHandler("databases", h.serveDatabases).ServeHTTP(w, r)
+		case "/checksum":
+			h.WrapHandler("checksum", h.serveChecksum).ServeHTTP(w, r)
+		case "/config-fingerprint":
+			h.WrapHandler("config-fingerprint", h.serveConfigFingerprint).ServeHTTP(w, r)
+		case "/shards":
+			h.WrapHandler("shards", h.serveShardMap).ServeHTTP(w, r)
+		case "/shard-health":
+			h.WrapHandler("shard-health", h.serveShardHealth).ServeHTTP(w, r)
+		case "/admin/tasks":
+			h.WrapHandler("tasks", h.serveTasks).ServeHTTP(w, r)
+		case "/cluster/nodes":
+			h.WrapHandler("cluster-nodes", h.serveClusterNodes).ServeHTTP(w, r)
 		default:
 			h.WrapHandler("snapshot", h.serveSnapshot).ServeHTTP(w, r)
 		}
@@ -93,7 +186,27 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case "/join":
 			h.WrapHandler("join", h.serveJoin).ServeHTTP(w, r)
+		case "/update-meta-node":
+			h.WrapHandler("update-meta-node", h.serveUpdateMetaNode).ServeHTTP(w, r)
+		case "/meta/refresh":
+			h.WrapHandler("refresh", h.serveRefresh).ServeHTTP(w, r)
+		case "/shard-sizes":
+			h.WrapHandler("shard-sizes", h.serveShardSizes).ServeHTTP(w, r)
+		case "/heartbeat":
+			h.WrapHandler("heartbeat", h.serveHeartbeat).ServeHTTP(w, r)
+		case "/tasks/pause":
+			h.WrapHandler("pause-task", h.servePauseTask).ServeHTTP(w, r)
+		case "/tasks/resume":
+			h.WrapHandler("resume-task", h.serveResumeTask).ServeHTTP(w, r)
+		case "/admin/tasks/cancel":
+			h.WrapHandler("cancel-task", h.serveCancelTask).ServeHTTP(w, r)
+		case "/cluster/maintenance":
+			h.WrapHandler("maintenance", h.serveMaintenance).ServeHTTP(w, r)
 		default:
+			if strings.HasPrefix(r.URL.Path, "/cluster/nodes/") {
+				h.WrapHandler("drop-node", h.serveDropNode).ServeHTTP(w, r)
+				return
+			}
 			h.WrapHandler("execute", h.serveExec).ServeHTTP(w, r)
 
 		}
@@ -102,6 +215,47 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// serveDebugHTTP responds to requests on the debug listener.
+func (h *handler) serveDebugHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.safeMode {
+		h.serveSafeModeHTTP(w, r)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/health":
+		h.WrapHandler("health", h.serveHealth).ServeHTTP(w, r)
+	case "/ready":
+		h.WrapHandler("ready", h.serveReady).ServeHTTP(w, r)
+	case "/metrics":
+		h.WrapHandler("metrics", h.serveMetrics).ServeHTTP(w, r)
+	case "/debug/features":
+		h.WrapHandler("features", h.serveFeatures).ServeHTTP(w, r)
+	case "/debug/config":
+		h.WrapHandler("config", h.serveConfig).ServeHTTP(w, r)
+	case "/debug/requests":
+		h.WrapHandler("requests", h.serveRequests).ServeHTTP(w, r)
+	case "/debug/apply-errors":
+		h.WrapHandler("apply-errors", h.serveApplyErrors).ServeHTTP(w, r)
+	case "/debug/tls":
+		h.WrapHandler("tls", h.serveTLSSessions).ServeHTTP(w, r)
+	case "/debug/log/tail":
+		h.WrapHandler("log-tail", h.serveLogTail).ServeHTTP(w, r)
+	case "/debug/orphans":
+		h.WrapHandler("orphans", h.serveOrphans).ServeHTTP(w, r)
+	case "/debug/version":
+		h.WrapHandler("version", h.serveVersion).ServeHTTP(w, r)
+	case "/debug/profile/cpu":
+		h.WrapHandler("cpu-profile", h.serveCPUProfile).ServeHTTP(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
 func (h *handler) Close() error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
@@ -110,6 +264,7 @@ func (h *handler) Close() error {
 		// do nothing here
 	default:
 		close(h.closing)
+		h.peerClient.Close()
 	}
 	return nil
 }
@@ -126,12 +281,36 @@ func (h *handler) isClosed() bool {
 }
 
 func (h *handler) serveJoin(w http.ResponseWriter, r *http.Request) {
+	// A retried join that already succeeded here gets the original response,
+	// and one racing the original waits for it.
+	key := r.Header.Get(idempotencyKeyHeader)
+	var cached []byte
+	if key != "" {
+		b, ok, err := h.idempotency.acquire(r.Context(), key)
+		if err != nil {
+			return
+		} else if ok {
+			w.Header().Add("Content-Type", "application/json")
+			w.Write(b)
+			return
+		}
+		defer func() { h.idempotency.release(key, cached) }()
+	}
+
 	n := &NodeInfo{}
 	if err := json.NewDecoder(r.Body).Decode(n); err != nil {
 		h.httpError(err, w, http.StatusInternalServerError)
 		return
 	}
 
+	// A dry run reports what joining would change, without joining.
+	if r.URL.Query().Get("dry-run") == "true" {
+		h.serveJoinPreview(w, r, n)
+		return
+	}
+	// The node joined when the leader took it in, by the leader's clock.
+	n.StartedAt = h.now().UTC()
+
 	node, err := h.store.join(n)
 	if err == raft.ErrNotLeader {
 		l := h.store.leaderHTTP()
@@ -150,18 +329,24 @@ func (h *handler) serveJoin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err != nil {
+	if err == ErrConfigChangeInProgress {
+		// Tell the caller why so it knows to retry once the change is done.
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
 		h.httpError(err, w, http.StatusInternalServerError)
 		return
 	}
 
 	// Return the node with newly assigned ID as json
-	w.Header().Add("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(node); err != nil {
+	b, err := json.Marshal(node)
+	if err != nil {
 		h.httpError(err, w, http.StatusInternalServerError)
+		return
 	}
-
-	return
+	cached = b
+	w.Header().Add("Content-Type", "application/json")
+	w.Write(b)
 }
 
 // serveExec executes the requested command.
@@ -171,6 +356,30 @@ func (h *handler) serveExec(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The connection is drained should the node lose leadership.
+	if h.s != nil {
+		h.s.writeConns.add(r)
+	}
+
+	// A retried command that was already applied gets the original
+	// response, and one racing the original waits for it rather than
+	// proposing the command again. Only commands applied through this node
+	// since it started are known.
+	key := r.Header.Get(idempotencyKeyHeader)
+	var cached []byte
+	if key != "" {
+		b, ok, err := h.idempotency.acquire(r.Context(), key)
+		if err != nil {
+			h.logger.Info("client disconnected, command not proposed", zap.String("idempotency-key", key))
+			return
+		} else if ok {
+			w.Header().Add("Content-Type", "application/octet-stream")
+			w.Write(b)
+			return
+		}
+		defer func() { h.idempotency.release(key, cached) }()
+	}
+
 	// Read the command from the request body.
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
@@ -178,21 +387,51 @@ func (h *handler) serveExec(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Make sure it's a valid command.
-	if err := validateCommand(body); err != nil {
+	// Make sure it's a valid command the client may send.
+	action, resource, err := commandAction(body)
+	if err != nil {
 		h.httpError(err, w, http.StatusBadRequest)
 		return
+	} else if !h.authorize(w, r, action, resource) {
+		return
+	}
+	durability, err := h.writeDurability(r)
+	if err != nil {
+		h.httpError(err, w, http.StatusBadRequest)
+		return
+	}
+	if body, err = h.stampCommand(body); err != nil {
+		h.httpError(err, w, http.StatusBadRequest)
+		return
+	}
+
+	// Don't propose a command for a client that has already gone away; it
+	// will retry with the same idempotency key.
+	if err := r.Context().Err(); err != nil {
+		h.logger.Info("client disconnected, command not proposed", zap.String("idempotency-key", key))
+		return
 	}
 
-	// Apply the command to the store.
+	// Apply the command to the store, unless the node is in a read-only
+	// maintenance window.
 	var resp *internal.Response
-	if err := h.store.apply(body); err != nil {
+	var applyErr error
+	if h.s != nil && h.s.InMaintenance(MaintenanceReadOnly) {
+		applyErr = ErrMaintenanceReadOnly
+	} else {
+		applyErr = h.store.apply(body)
+	}
+	if applyErr == nil && durability == WriteDurabilityAll {
+		applyErr = h.waitDurable(r.Context(), h.store.index())
+	}
+	if err := applyErr; err != nil {
 		// If we aren't the leader, redirect client to the leader.
 		if err == raft.ErrNotLeader {
 			l := h.store.leaderHTTP()
 			if l == "" {
-				// No cluster leader. Client will have to try again later.
-				h.httpError(errors.New("no leader"), w, http.StatusServiceUnavailable)
+				// No cluster leader. Client will have to try again later,
+				// and tells this apart from other 503s by the body.
+				http.Error(w, errNoLeader.Error(), http.StatusServiceUnavailable)
 				return
 			}
 			scheme := "http://"
@@ -225,18 +464,20 @@ func (h *handler) serveExec(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Send response to client.
-	w.Header().Add("Content-Type", "application/octet-stream")
-	w.Write(b)
-}
+	// Remember the outcome so a retry doesn't apply the command twice.
+	if applyErr == nil {
+		cached = b
+	}
 
-func validateCommand(b []byte) error {
-	// Ensure command can be deserialized before applying.
-	if err := proto.Unmarshal(b, &internal.Command{}); err != nil {
-		return fmt.Errorf("unable to unmarshal command: %s", err)
+	// The command is committed even if nobody is left to hear about it.
+	if r.Context().Err() != nil {
+		h.logger.Info("client disconnected, command completed", zap.String("idempotency-key", key))
+		return
 	}
 
-	return nil
+	// Send response to client.
+	w.Header().Add("Content-Type", "application/octet-stream")
+	w.Write(b)
 }
 
 // serveSnapshot is a long polling http connection to server cache updates
@@ -246,6 +487,10 @@ func (h *handler) serveSnapshot(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.verifyLeader(w, r) {
+		return
+	}
+
 	// get the current index that client has
 	index, err := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64)
 	if err != nil {
@@ -254,13 +499,26 @@ func (h *handler) serveSnapshot(w http.ResponseWriter, r *http.Request) {
 
 	select {
 	case <-h.store.afterIndex(index):
+		// Only so many snapshots are sent at once; the rest wait their turn.
+		if !h.s.snapshotTransfers.acquire(r.Context().Done()) {
+			return
+		}
+		defer h.s.snapshotTransfers.release()
+
 		// Send updated snapshot to client.
-		ss, err := h.store.snapshot()
-		if err != nil {
-			h.httpError(err, w, http.StatusInternalServerError)
+		if stream, _ := strconv.ParseBool(r.URL.Query().Get("stream")); stream {
+			ss, err := h.store.snapshot()
+			if err != nil {
+				h.httpError(err, w, http.StatusInternalServerError)
+				return
+			}
+			w.Header().Add("Content-Type", SnapshotStreamContentType)
+			if err := ss.WriteStream(w); err != nil {
+				h.logger.Info("stream snapshot failed", zap.Error(err))
+			}
 			return
 		}
-		b, err := ss.MarshalBinary()
+		b, err := h.store.marshaledSnapshot()
 		if err != nil {
 			h.httpError(err, w, http.StatusInternalServerError)
 			return
@@ -277,6 +535,21 @@ func (h *handler) serveSnapshot(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// verifyLeader implements the verifyLeader read option: when it is set the
+// read is refused with a 503 unless a quorum confirms the node is still the
+// leader, so a partitioned old leader can't serve stale metadata. It returns
+// whether the read may go ahead.
+func (h *handler) verifyLeader(w http.ResponseWriter, r *http.Request) bool {
+	if v, _ := strconv.ParseBool(r.URL.Query().Get("verifyLeader")); !v {
+		return true
+	}
+	if err := h.store.verifyLeader(verifyLeaderTimeout); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return false
+	}
+	return true
+}
+
 // servePing will return if the server is up, or if specified will check the status
 // of the other metaservers as well
 func (h *handler) servePing(w http.ResponseWriter, r *http.Request) {
@@ -296,7 +569,7 @@ func (h *handler) servePing(w http.ResponseWriter, r *http.Request) {
 		}
 		url := scheme + n + "/ping"
 
-		resp, err := http.Get(url)
+		resp, err := withAuthToken(http.DefaultClient, h.config.AuthToken).Get(url)
 		if err != nil {
 			healthy = false
 			break
@@ -331,6 +604,561 @@ func (h *handler) servePeers(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// healthJSON is the response of /health and /ready.
+type healthJSON struct {
+	Status string `json:"status"`
+	NodeID uint64 `json:"nodeID,omitempty"`
+	Leader string `json:"leader,omitempty"`
+	Term   uint64 `json:"term,omitempty"`
+}
+
+// health returns the liveness of the node: whether it is serving, has
+// joined the cluster and knows of a raft leader.
+func (h *handler) health() (healthJSON, *raftStatus) {
+	health := healthJSON{Status: "ok"}
+	st := h.store.raftStatus()
+	if st != nil {
+		health.Leader, health.Term = st.Leader, st.Term
+	}
+	health.NodeID = h.store.metaNodeID(h.s.RemoteRaftAddr())
+
+	switch {
+	case h.isClosed():
+		health.Status = "closed"
+	case h.config.ObserverMode:
+		// An observer has no raft state, and is up once it follows the
+		// cluster.
+		if !h.s.initialized() {
+			health.Status = "initializing"
+		}
+	case !h.s.initialized() || st == nil:
+		health.Status = "initializing"
+	case health.Leader == "":
+		health.Status = "no leader"
+	}
+	return health, st
+}
+
+// serveHealth reports whether the node is up: serving, joined to the
+// cluster and aware of a raft leader.
+func (h *handler) serveHealth(w http.ResponseWriter, r *http.Request) {
+	health, _ := h.health()
+	h.writeHealth(w, health)
+}
+
+// serveReady reports whether the node is up, has applied every committed
+// entry it knows of and has finished warming up after gaining leadership.
+func (h *handler) serveReady(w http.ResponseWriter, r *http.Request) {
+	health, st := h.health()
+	if health.Status == "ok" {
+		if st != nil && st.AppliedIndex < st.CommitIndex {
+			health.Status = "catching up"
+		} else if !h.s.Ready() {
+			health.Status = "warming"
+		} else if h.s.Maintenance() {
+			health.Status = "maintenance"
+		}
+	}
+	h.writeHealth(w, health)
+}
+
+// writeHealth writes health, with a 503 unless its status is ok.
+func (h *handler) writeHealth(w http.ResponseWriter, health healthJSON) {
+	status := http.StatusOK
+	if health.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(health)
+}
+
+// serveRaftStatus returns the local raft state, including any membership
+// change being applied.
+func (h *handler) serveRaftStatus(w http.ResponseWriter, r *http.Request) {
+	st := h.store.raftStatus()
+	if st == nil {
+		h.httpError(errors.New("raft not open"), w, http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(st); err != nil {
+		h.httpError(err, w, http.StatusInternalServerError)
+	}
+}
+
+// serveFeatures returns the current value of every feature flag.
+func (h *handler) serveFeatures(w http.ResponseWriter, r *http.Request) {
+	features := h.config.Features()
+
+	// The frozen topology flag is cluster state rather than config.
+	ss, err := h.store.snapshot()
+	if err != nil {
+		h.httpError(err, w, http.StatusInternalServerError)
+		return
+	}
+	features["topology-frozen"] = ss.TopologyFrozen
+
+	for name, paused := range h.s.leaderTasks.pausedTasks() {
+		features[name+"-paused"] = paused
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(features); err != nil {
+		h.httpError(err, w, http.StatusInternalServerError)
+	}
+}
+
+// serveChecksum returns the checksum of the local state. If an index is
+// given it first waits for the node to apply it, so a follower can be
+// compared with the leader.
+func (h *handler) serveChecksum(w http.ResponseWriter, r *http.Request) {
+	if v := r.URL.Query().Get("index"); v != "" {
+		index, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			http.Error(w, "error parsing index", http.StatusBadRequest)
+			return
+		}
+
+		if index > 0 {
+			select {
+			case <-h.store.afterIndex(index - 1):
+			case <-time.After(refreshBarrierTimeout):
+				h.httpError(fmt.Errorf("timed out waiting for index %d", index), w, http.StatusServiceUnavailable)
+				return
+			case <-r.Context().Done():
+				return
+			case <-h.closing:
+				h.httpError(fmt.Errorf("server closed"), w, http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	ss, err := h.store.snapshot()
+	if err != nil {
+		h.httpError(err, w, http.StatusInternalServerError)
+		return
+	}
+	sum, err := ss.Checksum()
+	if err != nil {
+		h.httpError(err, w, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(StateChecksum{Index: ss.Data.Index, Checksum: sum}); err != nil {
+		h.httpError(err, w, http.StatusInternalServerError)
+	}
+}
+
+// serveShardSizes records the shard sizes reported by a data node, as a JSON
+// object of sizes in bytes keyed by shard ID.
+func (h *handler) serveShardSizes(w http.ResponseWriter, r *http.Request) {
+	var sizes map[uint64]int64
+	if err := json.NewDecoder(r.Body).Decode(&sizes); err != nil {
+		h.httpError(err, w, http.StatusBadRequest)
+		return
+	}
+	h.s.shardSizes.update(sizes)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveHeartbeat records that the data node named by the id parameter is up.
+func (h *handler) serveHeartbeat(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "error parsing id", http.StatusBadRequest)
+		return
+	}
+	h.s.liveness.heartbeat(id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveShardHealth returns the replica health of every shard, based on the
+// heartbeats this node received.
+func (h *handler) serveShardHealth(w http.ResponseWriter, r *http.Request) {
+	ss, err := h.store.snapshot()
+	if err != nil {
+		h.httpError(err, w, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(shardHealth(ss, h.s.liveness.live)); err != nil {
+		h.httpError(err, w, http.StatusInternalServerError)
+	}
+}
+
+// servePauseTask pauses the leader task named by the name parameter on this
+// node.
+func (h *handler) servePauseTask(w http.ResponseWriter, r *http.Request) {
+	h.serveTaskControl(w, r, h.s.PauseLeaderTask)
+}
+
+// serveResumeTask resumes the leader task named by the name parameter on this
+// node.
+func (h *handler) serveResumeTask(w http.ResponseWriter, r *http.Request) {
+	h.serveTaskControl(w, r, h.s.ResumeLeaderTask)
+}
+
+func (h *handler) serveTaskControl(w http.ResponseWriter, r *http.Request, fn func(name string) error) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		h.httpError(errors.New("name is required"), w, http.StatusBadRequest)
+		return
+	}
+
+	if err := fn(name); err == ErrLeaderTaskNotFound {
+		h.httpError(err, w, http.StatusNotFound)
+		return
+	} else if err != nil {
+		h.httpError(err, w, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveTasks returns the background tasks running on this node.
+func (h *handler) serveTasks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.s.Tasks()); err != nil {
+		h.httpError(err, w, http.StatusInternalServerError)
+	}
+}
+
+// serveCancelTask cancels the background task named by the id parameter.
+func (h *handler) serveCancelTask(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "error parsing id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.s.CancelTask(id); err == ErrTaskNotFound {
+		h.httpError(err, w, http.StatusNotFound)
+		return
+	} else if err != nil {
+		h.httpError(err, w, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// serveMaintenance puts this node in maintenance, or takes it out, as the
+// enabled parameter says. See Service.SetMaintenance.
+func (h *handler) serveMaintenance(w http.ResponseWriter, r *http.Request) {
+	on, err := strconv.ParseBool(r.URL.Query().Get("enabled"))
+	if err != nil {
+		http.Error(w, "error parsing enabled", http.StatusBadRequest)
+		return
+	}
+	h.s.SetMaintenance(on)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveClusterNodes returns the meta nodes of the cluster, with their role
+// and when this node last heard from them.
+func (h *handler) serveClusterNodes(w http.ResponseWriter, r *http.Request) {
+	nodes, err := h.s.Nodes()
+	if err != nil {
+		h.httpError(err, w, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(nodes); err != nil {
+		h.httpError(err, w, http.StatusInternalServerError)
+	}
+}
+
+// serveDropNode removes the meta node named by /cluster/nodes/{id}/drop
+// from the cluster. Followers redirect to the leader.
+func (h *handler) serveDropNode(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/cluster/nodes/")
+	if !strings.HasSuffix(path, "/drop") {
+		http.NotFound(w, r)
+		return
+	}
+	id, err := strconv.ParseUint(strings.TrimSuffix(path, "/drop"), 10, 64)
+	if err != nil {
+		http.Error(w, "error parsing id", http.StatusBadRequest)
+		return
+	}
+
+	switch err := h.s.DropNode(id); err {
+	case nil:
+		w.WriteHeader(http.StatusOK)
+	case ErrNodeUnableToDropFinalNode:
+		h.httpError(err, w, http.StatusConflict)
+	case raft.ErrNotLeader:
+		l := h.store.leaderHTTP()
+		if l == "" {
+			h.httpError(errors.New("no leader"), w, http.StatusServiceUnavailable)
+			return
+		}
+		scheme := "http://"
+		if h.config.HTTPSEnabled {
+			scheme = "https://"
+		}
+		http.Redirect(w, r, scheme+l+r.URL.Path, http.StatusTemporaryRedirect)
+	default:
+		h.httpError(err, w, http.StatusInternalServerError)
+	}
+}
+
+// serveConfig returns the config the process runs with, annotating each value
+// with where it came from. Secrets are redacted.
+func (h *handler) serveConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.config.Values()); err != nil {
+		h.httpError(err, w, http.StatusInternalServerError)
+	}
+}
+
+// serveRefresh waits for the leader to apply every committed command and
+// returns the resulting index, so a client can fetch a snapshot at least that
+// recent. Followers redirect to the leader.
+func (h *handler) serveRefresh(w http.ResponseWriter, r *http.Request) {
+	if !h.store.isLeader() {
+		l := h.store.leaderHTTP()
+		if l == "" {
+			h.httpError(errors.New("no leader"), w, http.StatusServiceUnavailable)
+			return
+		}
+		scheme := "http://"
+		if h.config.HTTPSEnabled {
+			scheme = "https://"
+		}
+		http.Redirect(w, r, scheme+l+"/meta/refresh", http.StatusTemporaryRedirect)
+		return
+	}
+
+	if err := h.store.applied(refreshBarrierTimeout); err != nil {
+		h.httpError(err, w, http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		Index uint64 `json:"index"`
+	}{h.store.index()}); err != nil {
+		h.httpError(err, w, http.StatusInternalServerError)
+	}
+}
+
+// serveApplyErrors lists the raft commands that recently failed to apply to
+// the local state machine, oldest first.
+func (h *handler) serveApplyErrors(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.s.applyErrors.list()); err != nil {
+		h.httpError(err, w, http.StatusInternalServerError)
+	}
+}
+
+// tlsSummaryJSON is the response of /debug/tls.
+type tlsSummaryJSON struct {
+	Sessions     []TLSSession   `json:"sessions"`
+	Versions     map[string]int `json:"versions"`
+	CipherSuites map[string]int `json:"cipherSuites"`
+}
+
+// serveTLSSessions lists the TLS connections open on the HTTP API along with
+// how many use each version and cipher suite.
+func (h *handler) serveTLSSessions(w http.ResponseWriter, r *http.Request) {
+	summary := tlsSummaryJSON{
+		Sessions:     h.s.tlsSessions.list(),
+		Versions:     make(map[string]int),
+		CipherSuites: make(map[string]int),
+	}
+	for _, s := range summary.Sessions {
+		summary.Versions[s.Version]++
+		summary.CipherSuites[s.CipherSuite]++
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(summary); err != nil {
+		h.httpError(err, w, http.StatusInternalServerError)
+	}
+}
+
+// serveRequests lists the HTTP requests currently in flight.
+func (h *handler) serveRequests(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.inflight.list()); err != nil {
+		h.httpError(err, w, http.StatusInternalServerError)
+	}
+}
+
+// serveMetrics writes the service metrics, in OpenMetrics format when the
+// client asks for it and the Prometheus text format otherwise.
+func (h *handler) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	if acceptsOpenMetrics(r.Header.Get("Accept")) {
+		w.Header().Set("Content-Type", openMetricsContentType)
+		h.s.Metrics.WriteOpenMetrics(w)
+		return
+	}
+	w.Header().Set("Content-Type", prometheusContentType)
+	h.s.Metrics.WritePrometheus(w)
+}
+
+// databaseJSON is the JSON representation of a database and its retention
+// policies returned by /databases.
+type databaseJSON struct {
+	Name                   string                `json:"name"`
+	DefaultRetentionPolicy string                `json:"defaultRetentionPolicy"`
+	RetentionPolicies      []retentionPolicyJSON `json:"retentionPolicies"`
+	Annotations            map[string]string     `json:"annotations,omitempty"`
+	WriteBlocked           bool                  `json:"writeBlocked,omitempty"`
+}
+
+// retentionPolicyJSON is the JSON representation of a retention policy.
+// Durations are always given in nanoseconds; the *Human fields are only set
+// when asked for with ?human=true.
+type retentionPolicyJSON struct {
+	Name                    string `json:"name"`
+	ReplicaN                int    `json:"replicaN"`
+	Duration                int64  `json:"duration"`
+	ShardGroupDuration      int64  `json:"shardGroupDuration"`
+	DurationHuman           string `json:"durationHuman,omitempty"`
+	ShardGroupDurationHuman string `json:"shardGroupDurationHuman,omitempty"`
+}
+
+// serveDatabases returns every database with its retention policies.
+func (h *handler) serveDatabases(w http.ResponseWriter, r *http.Request) {
+	if !h.verifyLeader(w, r) {
+		return
+	}
+	human, _ := strconv.ParseBool(r.URL.Query().Get("human"))
+
+	ss, err := h.store.snapshot()
+	if err != nil {
+		h.httpError(err, w, http.StatusInternalServerError)
+		return
+	}
+
+	dbs := make([]databaseJSON, 0, len(ss.Data.Databases))
+	for _, di := range ss.Data.Databases {
+		db := databaseJSON{
+			Name:                   di.Name,
+			DefaultRetentionPolicy: di.DefaultRetentionPolicy,
+			RetentionPolicies:      make([]retentionPolicyJSON, 0, len(di.RetentionPolicies)),
+			Annotations:            ss.DatabaseAnnotations[di.Name],
+		}
+		_, db.WriteBlocked = ss.WriteBlockedDatabases[di.Name]
+		for _, rpi := range di.RetentionPolicies {
+			rp := retentionPolicyJSON{
+				Name:               rpi.Name,
+				ReplicaN:           rpi.ReplicaN,
+				Duration:           int64(rpi.Duration),
+				ShardGroupDuration: int64(rpi.ShardGroupDuration),
+			}
+			if human {
+				rp.DurationHuman = rpi.Duration.String()
+				rp.ShardGroupDurationHuman = rpi.ShardGroupDuration.String()
+			}
+			db.RetentionPolicies = append(db.RetentionPolicies, rp)
+		}
+		dbs = append(dbs, db)
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(dbs); err != nil {
+		h.httpError(err, w, http.StatusInternalServerError)
+	}
+}
+
+// shardMapHeader names the columns of the shard map, in order.
+var shardMapHeader = []string{"database", "rp", "shardGroupID", "shardID", "nodeID", "startTime", "endTime"}
+
+// shardOwnerJSON is a row of the shard map: one owner of one shard. A shard
+// without owners has a row with no node ID.
+type shardOwnerJSON struct {
+	Database        string    `json:"database"`
+	RetentionPolicy string    `json:"rp"`
+	ShardGroupID    uint64    `json:"shardGroupID"`
+	ShardID         uint64    `json:"shardID"`
+	NodeID          uint64    `json:"nodeID,omitempty"`
+	StartTime       time.Time `json:"startTime"`
+	EndTime         time.Time `json:"endTime"`
+}
+
+// serveShardMap returns the owners of every shard in the live shard groups,
+// as JSON or, with ?format=csv, as CSV with a header row.
+func (h *handler) serveShardMap(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		http.Error(w, "format must be json or csv", http.StatusBadRequest)
+		return
+	}
+
+	ss, err := h.store.snapshot()
+	if err != nil {
+		h.httpError(err, w, http.StatusInternalServerError)
+		return
+	}
+
+	rows := []shardOwnerJSON{}
+	for _, di := range ss.Data.Databases {
+		for _, rpi := range di.RetentionPolicies {
+			for _, sgi := range rpi.ShardGroups {
+				if sgi.Deleted() {
+					continue
+				}
+				for _, si := range sgi.Shards {
+					row := shardOwnerJSON{
+						Database:        di.Name,
+						RetentionPolicy: rpi.Name,
+						ShardGroupID:    sgi.ID,
+						ShardID:         si.ID,
+						StartTime:       sgi.StartTime.UTC(),
+						EndTime:         sgi.EndTime.UTC(),
+					}
+					if len(si.Owners) == 0 {
+						rows = append(rows, row)
+					}
+					for _, o := range si.Owners {
+						row.NodeID = o.NodeID
+						rows = append(rows, row)
+					}
+				}
+			}
+		}
+	}
+
+	if format != "csv" {
+		w.Header().Add("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(rows); err != nil {
+			h.httpError(err, w, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Add("Content-Type", "text/csv; charset=utf-8")
+	cw := csv.NewWriter(w)
+	cw.Write(shardMapHeader)
+	for _, row := range rows {
+		var nodeID string
+		if row.NodeID != 0 {
+			nodeID = strconv.FormatUint(row.NodeID, 10)
+		}
+		cw.Write([]string{
+			row.Database,
+			row.RetentionPolicy,
+			strconv.FormatUint(row.ShardGroupID, 10),
+			strconv.FormatUint(row.ShardID, 10),
+			nodeID,
+			row.StartTime.Format(time.RFC3339Nano),
+			row.EndTime.Format(time.RFC3339Nano),
+		})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		h.logger.Info("write shard map failed", zap.Error(err))
+	}
+}
+
 // serveLease
 func (h *handler) serveLease(w http.ResponseWriter, r *http.Request) {
 	var name, nodeIDStr string
@@ -409,32 +1237,173 @@ func (w gzipResponseWriter) Write(b []byte) (int, error) {
 
 func (w gzipResponseWriter) Flush() {
 	w.Writer.(*gzip.Writer).Flush()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
 }
 
 func (w gzipResponseWriter) CloseNotify() <-chan bool {
 	return w.ResponseWriter.(http.CloseNotifier).CloseNotify()
 }
 
+// counting counts every request served by the named handler.
+func counting(inner http.Handler, name string, h *handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.s != nil {
+			h.s.httpRequests.Inc(name)
+		}
+		inner.ServeHTTP(w, r)
+	})
+}
+
 // determines if the client can accept compressed responses, and encodes accordingly
-func gzipFilter(inner http.Handler) http.Handler {
+func gzipFilter(inner http.Handler, level int) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
 			inner.ServeHTTP(w, r)
 			return
 		}
+		gz, err := gzip.NewWriterLevel(w, level)
+		if err != nil {
+			// The level is validated with the config, so this is a bug.
+			gz = gzip.NewWriter(w)
+		}
 		w.Header().Set("Content-Encoding", "gzip")
-		gz := gzip.NewWriter(w)
 		defer gz.Close()
 		gzw := gzipResponseWriter{Writer: gz, ResponseWriter: w}
 		inner.ServeHTTP(gzw, r)
 	})
 }
 
+// TimeoutHeader is the request header clients set to a duration, such as
+// "500ms", to bound how long the server spends on the request.
+const TimeoutHeader = "X-Meta-Timeout"
+
+// requestTimeout sets the deadline of requests carrying TimeoutHeader,
+// capped at max, and responds 504 if inner hasn't responded by then. Requests
+// without the header are passed through untouched.
+func requestTimeout(inner http.Handler, max time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v := r.Header.Get(TimeoutHeader)
+		if v == "" {
+			inner.ServeHTTP(w, r)
+			return
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			http.Error(w, fmt.Sprintf("invalid %s header %q", TimeoutHeader, v), http.StatusBadRequest)
+			return
+		}
+		if max > 0 && d > max {
+			d = max
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		defer cancel()
+
+		tw := &timeoutWriter{w: w, h: make(http.Header)}
+		done := make(chan struct{})
+		panicked := make(chan interface{}, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicked <- p
+				}
+			}()
+			inner.ServeHTTP(tw, r.WithContext(ctx))
+			close(done)
+		}()
+
+		select {
+		case p := <-panicked:
+			panic(p)
+		case <-done:
+		case <-ctx.Done():
+			if !tw.timeout() {
+				// The handler is already responding; let it finish.
+				select {
+				case p := <-panicked:
+					panic(p)
+				case <-done:
+				}
+			}
+		}
+	})
+}
+
+// timeoutWriter is the response writer of a request with a deadline. Once
+// the deadline passes, writes the handler hasn't started are dropped in favor
+// of a 504.
+type timeoutWriter struct {
+	mu       sync.Mutex
+	w        http.ResponseWriter
+	h        http.Header
+	wrote    bool
+	timedOut bool
+}
+
+func (tw *timeoutWriter) Header() http.Header { return tw.h }
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.writeHeader(code)
+}
+
+// writeHeader sends the handler's headers. tw.mu must be held.
+func (tw *timeoutWriter) writeHeader(code int) {
+	if tw.timedOut || tw.wrote {
+		return
+	}
+	tw.wrote = true
+	for k, v := range tw.h {
+		tw.w.Header()[k] = v
+	}
+	tw.w.WriteHeader(code)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.writeHeader(http.StatusOK)
+	return tw.w.Write(b)
+}
+
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if f, ok := tw.w.(http.Flusher); ok && !tw.timedOut {
+		f.Flush()
+	}
+}
+
+func (tw *timeoutWriter) CloseNotify() <-chan bool {
+	return tw.w.(http.CloseNotifier).CloseNotify()
+}
+
+// timeout responds 504 unless the handler has already started responding,
+// and returns whether it did.
+func (tw *timeoutWriter) timeout() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.wrote {
+		return false
+	}
+	tw.timedOut = true
+	http.Error(tw.w, "request exceeded its "+TimeoutHeader, http.StatusGatewayTimeout)
+	return true
+}
+
 // versionHeader takes a HTTP handler and returns a HTTP handler
-// and adds the X-INFLUXBD-VERSION header to outgoing responses.
+// and adds the X-INFLUXBD-VERSION and X-Influxcloud-Version headers to
+// outgoing responses.
 func versionHeader(inner http.Handler, h *handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("X-InfluxDB-Version", h.s.Version())
+		w.Header().Add("X-Influxcloud-Version", h.s.Version())
 		inner.ServeHTTP(w, r)
 	})
 }
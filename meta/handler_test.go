@@ -0,0 +1,523 @@
+package meta_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/toml"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+	"github.com/zhexuany/influxcloud/meta/internal"
+)
+
+func TestMetaService_ExecCanceledClientRetry(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	typ := internal.Command_CreateUserCommand
+	cmd := &internal.Command{Type: &typ}
+	if err := proto.SetExtension(cmd, internal.E_CreateUserCommand_Command, &internal.CreateUserCommand{
+		Name:  proto.String("susy"),
+		Hash:  proto.String("hash"),
+		Admin: proto.Bool(false),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	body, err := proto.Marshal(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	url := "http://" + s.HTTPAddr() + "/execute"
+	const key = "create-user-susy"
+
+	// Cancel the request after only half the command has been sent.
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequest("POST", url, &stallingReader{b: body[:len(body)/2], stall: ctx.Done()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Idempotency-Key", key)
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, err := http.DefaultClient.Do(req); err == nil {
+		t.Fatal("expected canceled request to fail")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if u := c.Data().User("susy"); u != nil {
+		t.Fatal("command from canceled request was applied")
+	}
+
+	// Retrying with the same key applies the command once; a second retry
+	// returns the original response instead of "user already exists".
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Idempotency-Key", key)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res := &internal.Response{}
+		if err := proto.Unmarshal(b, res); err != nil {
+			t.Fatal(err)
+		} else if res.GetError() != "" {
+			t.Fatalf("retry %d: unexpected error: %s", i, res.GetError())
+		}
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if users := c.Data().Users; len(users) != 1 || users[0].Name != "susy" {
+		t.Fatalf("unexpected users: %v", users)
+	}
+}
+
+// Ensure a meta server refusing a write with a 503 for a reason other than
+// having no leader, as in maintenance, is passed over rather than taken for
+// lost quorum.
+func TestMetaService_Exec_Unavailable(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "", http.StatusServiceUnavailable)
+	}))
+	defer unavailable.Close()
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	cfg.QuorumLossTimeout = toml.Duration(time.Nanosecond)
+	c2 := cloudMeta.NewClient(cfg)
+	c2.SetMetaServers([]string{s.HTTPAddr()})
+	if err := c2.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer c2.Close()
+	c2.SetMetaServers([]string{strings.TrimPrefix(unavailable.URL, "http://"), s.HTTPAddr()})
+
+	if _, err := c2.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMetaService_DebugFeatures(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	cfg.PprofEnabled = true
+	cfg.RetentionAutoCreate = false
+	s := openService(t, cfg)
+	defer s.Close()
+
+	c := newClient(s)
+	defer c.Close()
+	if err := c.SetTopologyFrozen(true); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Get("http://" + s.HTTPAddr() + "/debug/features")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var features map[string]bool
+	if err := json.NewDecoder(resp.Body).Decode(&features); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, exp := range map[string]bool{
+		"pprof-enabled":          true,
+		"retention-autocreate":   false,
+		"raft-promotion-enabled": true,
+		"topology-frozen":        true,
+	} {
+		if got, ok := features[name]; !ok {
+			t.Fatalf("feature %q not listed: %v", name, features)
+		} else if got != exp {
+			t.Fatalf("feature %q: got %v, expected %v", name, got, exp)
+		}
+	}
+}
+
+// Ensure the shard map is served as CSV, with a row per shard owner and
+// fields quoted where needed.
+func TestMetaService_ShardMapCSV(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	if _, err := c.CreateDataNode("foo:8180", "bar:8281"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreateDataNode("foo:8280", "bar:8381"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two groups with a single shard owned by both nodes.
+	const db = `cap,"plan"`
+	two := 2
+	if _, err := c.CreateDatabaseWithRetentionPolicy(db, &meta.RetentionPolicySpec{
+		Name:     "rp0",
+		ReplicaN: &two,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// Weekly shard groups start on a Monday.
+	start := time.Date(2017, 1, 2, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		if _, err := c.CreateShardGroup(db, "rp0", start.Add(time.Duration(i)*7*24*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resp, err := http.Get("http://" + s.HTTPAddr() + "/shards?format=csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if typ := resp.Header.Get("Content-Type"); !strings.HasPrefix(typ, "text/csv") {
+		t.Fatalf("unexpected content type: %s", typ)
+	}
+	records, err := csv.NewReader(resp.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exp := []string{"database", "rp", "shardGroupID", "shardID", "nodeID", "startTime", "endTime"}; !reflect.DeepEqual(records[0], exp) {
+		t.Fatalf("unexpected header: %v", records[0])
+	} else if len(records) != 5 {
+		t.Fatalf("unexpected number of records: %v", records)
+	}
+	for _, rec := range records[1:] {
+		if rec[0] != db || rec[1] != "rp0" || rec[4] == "" {
+			t.Fatalf("unexpected row: %v", rec)
+		}
+	}
+	if records[1][5] != "2017-01-02T00:00:00Z" {
+		t.Fatalf("unexpected start time: %s", records[1][5])
+	}
+}
+
+func TestMetaService_DatabasesHumanDurations(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	week := 7 * 24 * time.Hour
+	if _, err := c.CreateDatabaseWithRetentionPolicy("db0", &meta.RetentionPolicySpec{
+		Name:               "rp0",
+		Duration:           &week,
+		ShardGroupDuration: 24 * time.Hour,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var dbs []struct {
+		Name              string
+		RetentionPolicies []struct {
+			Name                    string
+			Duration                int64
+			ShardGroupDuration      int64
+			DurationHuman           string
+			ShardGroupDurationHuman string
+		}
+	}
+	get := func(path string) {
+		resp, err := http.Get("http://" + s.HTTPAddr() + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		dbs = nil
+		if err := json.NewDecoder(resp.Body).Decode(&dbs); err != nil {
+			t.Fatal(err)
+		}
+		if len(dbs) != 1 || len(dbs[0].RetentionPolicies) != 1 {
+			t.Fatalf("unexpected databases: %+v", dbs)
+		}
+	}
+
+	get("/databases?human=true")
+	rp := dbs[0].RetentionPolicies[0]
+	if rp.Duration != int64(7*24*time.Hour) || rp.ShardGroupDuration != int64(24*time.Hour) {
+		t.Fatalf("unexpected raw durations: %+v", rp)
+	} else if rp.DurationHuman != "168h0m0s" || rp.ShardGroupDurationHuman != "24h0m0s" {
+		t.Fatalf("unexpected human durations: %+v", rp)
+	} else if d, _ := time.ParseDuration(rp.DurationHuman); int64(d) != rp.Duration {
+		t.Fatalf("human duration %s does not match raw %d", rp.DurationHuman, rp.Duration)
+	}
+
+	// The human readable fields are omitted unless asked for.
+	get("/databases")
+	if rp := dbs[0].RetentionPolicies[0]; rp.DurationHuman != "" || rp.Duration != int64(7*24*time.Hour) {
+		t.Fatalf("unexpected retention policy: %+v", rp)
+	}
+}
+
+// Ensure responses are compressed at the configured gzip level.
+func TestMetaService_GzipLevel(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	cfg.GzipLevel = gzip.NoCompression
+	s := openService(t, cfg)
+	defer s.Close()
+
+	req, err := http.NewRequest("GET", "http://"+s.HTTPAddr()+"/metrics", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	// Disable transparent decompression so the raw stream can be inspected.
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if enc := resp.Header.Get("Content-Encoding"); enc != "gzip" {
+		t.Fatalf("unexpected content encoding: %q", enc)
+	}
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without compression the payload is stored verbatim in the stream.
+	if !bytes.Contains(raw, []byte("influxcloud_meta_uptime_seconds")) {
+		t.Fatalf("expected uncompressed payload, got %q", raw)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := ioutil.ReadAll(gz); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Contains(b, []byte("# TYPE influxcloud_meta_uptime_seconds gauge")) {
+		t.Fatalf("unexpected body: %s", b)
+	}
+}
+
+// Ensure requests exceeding their X-Meta-Timeout, capped at
+// max-request-timeout, get a 504.
+func TestMetaService_RequestTimeout(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	cfg.MaxRequestTimeout = toml.Duration(100 * time.Millisecond)
+	s := openService(t, cfg)
+	defer s.Close()
+
+	c := newClient(s)
+	defer c.Close()
+
+	// Waiting for an index that is never reached is slow.
+	for _, timeout := range []string{"10ms", "1h"} {
+		req, err := http.NewRequest("GET", "http://"+s.HTTPAddr()+"/checksum?index=1000000", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(cloudMeta.TimeoutHeader, timeout)
+
+		start := time.Now()
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusGatewayTimeout {
+			t.Fatalf("timeout %s: unexpected status: %d", timeout, resp.StatusCode)
+		} else if d := time.Since(start); d > 2*time.Second {
+			t.Fatalf("timeout %s: took %s", timeout, d)
+		}
+	}
+
+	req, err := http.NewRequest("GET", "http://"+s.HTTPAddr()+"/ping", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(cloudMeta.TimeoutHeader, "soon")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("unexpected status for an invalid timeout: %d", resp.StatusCode)
+	}
+}
+
+// Ensure a node gaining leadership runs its warmers before reporting ready,
+// and reports ready once the warm up timeout passes even if a warmer is stuck.
+func TestMetaService_LeaderWarmup(t *testing.T) {
+	t.Parallel()
+
+	const timeout = 200 * time.Millisecond
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	cfg.LeaderWarmTimeout = toml.Duration(timeout)
+	s := newService(cfg)
+
+	var warmedReady int32 = -1
+	warmed := make(chan time.Time, 1)
+	s.RegisterLeaderWarmer("topology", func() {
+		time.Sleep(20 * time.Millisecond)
+		if s.Ready() {
+			atomic.StoreInt32(&warmedReady, 1)
+		} else {
+			atomic.StoreInt32(&warmedReady, 0)
+		}
+		warmed <- time.Now()
+	})
+	stuck := make(chan struct{})
+	defer close(stuck)
+	s.RegisterLeaderWarmer("stuck", func() { <-stuck })
+
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var warmedAt time.Time
+	select {
+	case warmedAt = <-warmed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the warmer to run")
+	}
+	if atomic.LoadInt32(&warmedReady) != 0 {
+		t.Fatal("node reported ready while warming")
+	}
+
+	var readyAt time.Time
+	for i := 0; i < 100; i++ {
+		resp, err := http.Get("http://" + s.HTTPAddr() + "/ready")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			readyAt = time.Now()
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if readyAt.IsZero() {
+		t.Fatal("node never reported ready")
+	} else if !s.Ready() {
+		t.Fatal("ready ok but service not warmed up")
+	}
+
+	// The warmer finished well within the bound, but the stuck one holds
+	// readiness back until the bound.
+	if d := readyAt.Sub(warmedAt); d > timeout+time.Second {
+		t.Fatalf("ready %s after warming, want within %s", d, timeout)
+	}
+}
+
+// Ensure /health and /ready report the node, its leader and the raft term
+// once the node is up and caught up.
+func TestMetaService_HealthReady(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	get := func(path string) (int, map[string]interface{}) {
+		resp, err := http.Get("http://" + s.HTTPAddr() + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var body map[string]interface{}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, body
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if code, _ := get("/ready"); code == http.StatusOK {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("node never reported ready: %d", code)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for _, path := range []string{"/health", "/ready"} {
+		code, body := get(path)
+		if code != http.StatusOK || body["status"] != "ok" {
+			t.Fatalf("unexpected %s response: %d %v", path, code, body)
+		} else if body["leader"] != s.RemoteRaftAddr() {
+			t.Fatalf("unexpected %s leader: %v", path, body["leader"])
+		} else if term, _ := body["term"].(float64); term < 1 {
+			t.Fatalf("unexpected %s term: %v", path, body["term"])
+		} else if id, _ := body["nodeID"].(float64); id < 1 {
+			t.Fatalf("unexpected %s node id: %v", path, body["nodeID"])
+		}
+	}
+}
+
+// stallingReader returns b and then blocks until stall is closed, simulating a
+// client that goes away halfway through sending a request.
+type stallingReader struct {
+	b     []byte
+	stall <-chan struct{}
+}
+
+func (r *stallingReader) Read(p []byte) (int, error) {
+	if len(r.b) > 0 {
+		n := copy(p, r.b)
+		r.b = r.b[n:]
+		return n, nil
+	}
+	<-r.stall
+	return 0, io.ErrUnexpectedEOF
+}
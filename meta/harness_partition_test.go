@@ -0,0 +1,16 @@
+//go:build raftpartition
+// +build raftpartition
+
+package meta
+
+// Partition cuts s off from the other services: every raft connection to or
+// from it is closed and new ones are refused until Heal is called. Its HTTP
+// API stays reachable.
+func (c *TestCluster) Partition(s *Service) {
+	s.store.raftState.raftLayer.setPartitioned(true)
+}
+
+// Heal reconnects a service cut off with Partition.
+func (c *TestCluster) Heal(s *Service) {
+	s.store.raftState.raftLayer.setPartitioned(false)
+}
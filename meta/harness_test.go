@@ -0,0 +1,275 @@
+package meta
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/tcp"
+	"github.com/influxdata/influxdb/toml"
+	"github.com/zhexuany/influxcloud"
+)
+
+// testClusterLeaderTimeout is how long NewTestCluster waits for a leader.
+const testClusterLeaderTimeout = 10 * time.Second
+
+// TestCluster is a set of meta services running on ephemeral local ports,
+// meant for tests that need a real cluster. It is declared in a test file of
+// package meta, so that the tests of the package, internal and external,
+// can use it.
+type TestCluster struct {
+	Services []*Service
+	Configs  []*Config
+
+	// Client is connected to every service in the cluster.
+	Client *Client
+
+	listeners []*trackingListener
+}
+
+// trackingListener remembers every accepted connection so they can all be
+// closed on teardown. The raft transport doesn't close the connections it
+// accepted when it shuts down.
+type trackingListener struct {
+	net.Listener
+
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func (l *trackingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	l.mu.Lock()
+	l.conns = append(l.conns, conn)
+	l.mu.Unlock()
+	return conn, nil
+}
+
+func (l *trackingListener) Close() error {
+	err := l.Listener.Close()
+	l.mu.Lock()
+	for _, conn := range l.conns {
+		conn.Close()
+	}
+	l.conns = nil
+	l.mu.Unlock()
+	return err
+}
+
+// NewTestCluster starts n meta services on ephemeral ports, waits for a
+// leader to be elected and returns the cluster. The caller must call Close
+// to stop the services and remove their data directories.
+func NewTestCluster(t testing.TB, n int) *TestCluster {
+	return NewTestClusterWithConfig(t, n, nil)
+}
+
+// NewTestClusterWithConfig is like NewTestCluster, but has configure, if
+// not nil, adjust the config of the i'th node before it opens.
+func NewTestClusterWithConfig(t testing.TB, n int, configure func(i int, c *Config)) *TestCluster {
+	c := &TestCluster{}
+
+	// Listen on the HTTP addresses up front since every node needs the
+	// full list of join peers before it can open.
+	httpAddrs := make([]string, n)
+	httpListeners := make([]net.Listener, n)
+	for i := range httpAddrs {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			for _, ln := range httpListeners[:i] {
+				ln.Close()
+			}
+			t.Fatal(err)
+		}
+		httpListeners[i], httpAddrs[i] = ln, ln.Addr().String()
+	}
+
+	for i := 0; i < n; i++ {
+		dir, err := ioutil.TempDir("", fmt.Sprintf("meta-test-cluster-%d-", i))
+		if err != nil {
+			c.closeListeners(httpListeners[i:])
+			t.Fatal(err)
+		}
+
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			os.RemoveAll(dir)
+			c.closeListeners(httpListeners[i:])
+			t.Fatal(err)
+		}
+		ln := &trackingListener{Listener: l}
+		c.listeners = append(c.listeners, ln)
+
+		cfg := NewConfig()
+		cfg.Dir = dir
+		cfg.BindAddress = ln.Addr().String()
+		cfg.HTTPBindAddress = httpAddrs[i]
+		cfg.LoggingEnabled = false
+		cfg.LeaseDuration = toml.Duration(time.Second)
+		if n > 1 {
+			cfg.JoinPeers = httpAddrs
+		}
+		if configure != nil {
+			configure(i, cfg)
+		}
+		c.Configs = append(c.Configs, cfg)
+
+		s := newTestService(cfg, ln)
+		s.HTTPListener = httpListeners[i]
+		c.Services = append(c.Services, s)
+	}
+
+	// The nodes wait on each other while opening, so open them all at once.
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i, s := range c.Services {
+		wg.Add(1)
+		go func(i int, s *Service) {
+			defer wg.Done()
+			errs[i] = s.Open()
+		}(i, s)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			c.Close()
+			t.Fatalf("open meta service %d: %s", i, err)
+		}
+	}
+
+	if c.Leader(testClusterLeaderTimeout) == nil {
+		c.Close()
+		t.Fatal("timed out waiting for a leader")
+	}
+
+	c.Client = NewClient(c.Configs[0])
+	c.Client.SetMetaServers(httpAddrs)
+	c.Client.SetTLS(c.Configs[0].HTTPSEnabled)
+	if err := c.Client.Open(); err != nil {
+		c.Close()
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+// newTestConfig returns the config of a meta service listening on ephemeral
+// local ports, with a new temp dir prefixed with prefix. The caller must
+// remove the dir.
+func newTestConfig(t testing.TB, prefix string) *Config {
+	dir, err := ioutil.TempDir("", prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := NewConfig()
+	cfg.Dir = dir
+	cfg.BindAddress = "127.0.0.1:0"
+	cfg.HTTPBindAddress = "127.0.0.1:0"
+	return cfg
+}
+
+// newTestService returns a meta service with cfg, taking the raft
+// connections accepted by ln.
+func newTestService(cfg *Config, ln net.Listener) *Service {
+	mux := tcp.NewMux()
+	s := NewService(cfg)
+	s.Node = influxcloud.NewNode(cfg.Dir)
+	s.RaftListener = mux.Listen(MuxHeader)
+	go mux.Serve(ln)
+	return s
+}
+
+// openTestService opens a meta service with cfg, listening for raft on
+// cfg.BindAddress, and returns it along with that listener. As the server
+// does, it joins the cluster through cfg.JoinAddress, if set, while the
+// service opens.
+func openTestService(t testing.TB, cfg *Config) (*Service, net.Listener) {
+	ln, err := net.Listen("tcp", cfg.BindAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.BindAddress = ln.Addr().String()
+
+	s := newTestService(cfg, ln)
+
+	joined := make(chan error, 1)
+	if cfg.JoinAddress != "" {
+		go func() {
+			<-s.Listening()
+			httpAddr, raftAddr := s.AdvertisedAddrs()
+			c := NewClient(cfg)
+			defer c.Close()
+			_, err := c.JoinClusterAs(cfg.JoinAddress, httpAddr, raftAddr)
+			joined <- err
+		}()
+	} else {
+		joined <- nil
+	}
+	if err := s.Open(); err != nil {
+		ln.Close()
+		t.Fatal(err)
+	}
+	if err := <-joined; err != nil {
+		s.Close()
+		ln.Close()
+		t.Fatal(err)
+	}
+	return s, ln
+}
+
+// Leader returns the service that is currently the raft leader, waiting up to
+// timeout for one to be elected. It returns nil if no leader is elected.
+func (c *TestCluster) Leader(timeout time.Duration) *Service {
+	deadline := time.Now().Add(timeout)
+	for {
+		for _, s := range c.Services {
+			if s.store != nil && s.store.isLeader() {
+				return s
+			}
+		}
+		if time.Now().After(deadline) {
+			return nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// closeListeners closes the HTTP listeners of the services not created yet,
+// and closes the cluster.
+func (c *TestCluster) closeListeners(httpListeners []net.Listener) {
+	for _, ln := range httpListeners {
+		ln.Close()
+	}
+	c.Close()
+}
+
+// Close stops the client and every service and removes their data.
+func (c *TestCluster) Close() error {
+	var err error
+	if c.Client != nil {
+		c.Client.Close()
+	}
+	for _, s := range c.Services {
+		if s.handler == nil {
+			// The service didn't open, so its HTTP listener is left.
+			s.HTTPListener.Close()
+			continue
+		}
+		if e := s.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	for _, ln := range c.listeners {
+		ln.Close()
+	}
+	for _, cfg := range c.Configs {
+		os.RemoveAll(cfg.Dir)
+	}
+	return err
+}
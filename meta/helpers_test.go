@@ -0,0 +1,187 @@
+package meta_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/tcp"
+	"github.com/influxdata/influxdb/toml"
+	"github.com/zhexuany/influxcloud"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// newServiceAndClient returns new data directory, *Service, and *Client or panics.
+// Caller is responsible for deleting data dir and closing client.
+func newServiceAndClient() (string, *testService, *cloudMeta.Client) {
+	cfg := newConfig()
+	s := newService(cfg)
+	if err := s.Open(); err != nil {
+		panic(err)
+	}
+
+	c := newClient(s)
+
+	return cfg.Dir, s, c
+}
+
+// newClient will create a meta client and also open it
+func newClient(s *testService) *cloudMeta.Client {
+	cfg := newConfig()
+	c := cloudMeta.NewClient(cfg)
+	c.SetMetaServers([]string{s.HTTPAddr()})
+	if err := c.Open(); err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func newConfig() *cloudMeta.Config {
+	cfg := cloudMeta.NewConfig()
+	cfg.BindAddress = "127.0.0.1:0"
+	cfg.HTTPBindAddress = "127.0.0.1:0"
+	cfg.Dir = testTempDir(2)
+	cfg.LeaseDuration = toml.Duration(1 * time.Second)
+	return cfg
+}
+
+func testTempDir(skip int) string {
+	// Get name of the calling function.
+	pc, _, _, ok := runtime.Caller(skip)
+	if !ok {
+		panic("failed to get name of test function")
+	}
+	_, prefix := path.Split(runtime.FuncForPC(pc).Name())
+	// Make a temp dir prefixed with calling function's name.
+	dir, err := ioutil.TempDir(os.TempDir(), prefix)
+	if err != nil {
+		panic(err)
+	}
+	return dir
+}
+
+type testService struct {
+	*cloudMeta.Service
+	ln net.Listener
+}
+
+func (t *testService) Close() error {
+	if err := t.Service.Close(); err != nil {
+		return err
+	}
+	return t.ln.Close()
+}
+
+func newService(cfg *cloudMeta.Config) *testService {
+	// Open shared TCP connection.
+	ln, err := net.Listen("tcp", cfg.BindAddress)
+	if err != nil {
+		panic(err)
+	}
+
+	// Multiplex listener.
+	mux := tcp.NewMux()
+
+	if err != nil {
+		panic(err)
+	}
+	// A node restarted in a directory goes on as the node its node.json
+	// names.
+	node, err := influxcloud.LoadNode(cfg.Dir)
+	if os.IsNotExist(err) {
+		node = influxcloud.NewNode(cfg.Dir)
+	} else if err != nil {
+		panic(err)
+	}
+	s := cloudMeta.NewService(cfg)
+	s.Node = node
+	s.RaftListener = mux.Listen(cloudMeta.MuxHeader)
+
+	go mux.Serve(ln)
+
+	return &testService{Service: s, ln: ln}
+}
+
+// openService opens a new *Service with cfg, failing t if it can't. The
+// caller is responsible for closing it and deleting cfg.Dir.
+func openService(t testing.TB, cfg *cloudMeta.Config) *testService {
+	s := newService(cfg)
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func freePort() string {
+	l, _ := net.Listen("tcp", "127.0.0.1:0")
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func freePorts(i int) []string {
+	var ports []string
+	for j := 0; j < i; j++ {
+		ports = append(ports, freePort())
+	}
+	return ports
+}
+
+func mustParseStatement(s string) influxql.Statement {
+	stmt, err := influxql.ParseStatement(s)
+	if err != nil {
+		panic(err)
+	}
+	return stmt
+}
+
+func mustMarshalJSON(v interface{}) string {
+	b, e := json.Marshal(v)
+	if e != nil {
+		panic(e)
+	}
+	return string(b)
+}
+
+// fakeClock is a clock that only moves when set.
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = t
+}
+
+// lockedBuffer is a bytes.Buffer safe for concurrent use.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
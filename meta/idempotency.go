@@ -0,0 +1,253 @@
+package meta
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// idempotencyKeyHeader is the request header carrying the key that
+	// identifies a single logical command across client retries.
+	idempotencyKeyHeader = "Idempotency-Key"
+
+	// DefaultIdempotencyCacheSize is the number of command responses kept so
+	// that a retried request is answered without proposing it again.
+	DefaultIdempotencyCacheSize = 1024
+
+	// DefaultIdempotencyCacheTTL is how long a command response is kept.
+	DefaultIdempotencyCacheTTL = 10 * time.Minute
+
+	// DefaultIdempotencyCacheMaxBytes is the most the cached keys and
+	// responses may add up to.
+	DefaultIdempotencyCacheMaxBytes = 64 * 1024 * 1024
+
+	// DefaultIdempotencyCacheCompactInterval is how often expired responses
+	// are dropped from the cache.
+	DefaultIdempotencyCacheCompactInterval = time.Minute
+)
+
+// idempotencyCache remembers the responses of recently applied commands by
+// their idempotency key. The least recently used entry is evicted once the
+// cache is full, or holds more than maxBytes, and entries expire ttl after
+// they were set.
+//
+// The cache is kept in memory by the node that applied the command, and
+// isn't replicated through raft: a retry that reaches another leader after
+// a failover, or this node after a restart, is applied again.
+type idempotencyCache struct {
+	mu       sync.Mutex
+	size     int
+	maxBytes int64
+	bytes    int64
+	ttl      time.Duration
+	order    *list.List // of *idempotencyEntry, most recently used first
+	entries  map[string]*list.Element
+
+	// inflight holds the keys reserved by acquire, closed once released.
+	inflight map[string]chan struct{}
+
+	// lookups counts every lookup, by result: "hit" or "miss".
+	lookups *Counter
+
+	// compacted, if set, counts the expired entries dropped by compact.
+	compacted *Counter
+}
+
+type idempotencyEntry struct {
+	key      string
+	response []byte
+	expires  time.Time
+}
+
+// entrySize is how much an entry counts towards maxBytes.
+func (e *idempotencyEntry) entrySize() int64 {
+	return int64(len(e.key) + len(e.response))
+}
+
+// newIdempotencyCache returns a cache holding at most size responses for
+// ttl each. A zero ttl keeps responses until they are evicted.
+func newIdempotencyCache(size int, ttl time.Duration, lookups *Counter) *idempotencyCache {
+	return &idempotencyCache{
+		size:     size,
+		ttl:      ttl,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+		inflight: make(map[string]chan struct{}),
+		lookups:  lookups,
+	}
+}
+
+// get returns the cached response for key, if any.
+func (c *idempotencyCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lookup(key)
+}
+
+// acquire returns the cached response for key, if any. Otherwise it
+// reserves key, first waiting for the request that reserved it before to
+// release it, so concurrent retries of a command propose it once. The
+// caller must then release key. It returns ctx.Err() if ctx is done while
+// waiting.
+func (c *idempotencyCache) acquire(ctx context.Context, key string) ([]byte, bool, error) {
+	for {
+		c.mu.Lock()
+		ch, ok := c.inflight[key]
+		if !ok {
+			b, ok := c.lookup(key)
+			if !ok {
+				c.inflight[key] = make(chan struct{})
+			}
+			c.mu.Unlock()
+			return b, ok, nil
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+	}
+}
+
+// release releases key reserved by acquire, recording b as its response
+// unless b is nil, as for a command that wasn't applied.
+func (c *idempotencyCache) release(key string, b []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if b != nil {
+		c.store(key, b)
+	}
+	if ch, ok := c.inflight[key]; ok {
+		close(ch)
+		delete(c.inflight, key)
+	}
+}
+
+// lookup returns the cached response for key, if any. c.mu must be held.
+func (c *idempotencyCache) lookup(key string) ([]byte, bool) {
+	e, ok := c.entries[key]
+	if ok && c.expired(e.Value.(*idempotencyEntry), now()) {
+		c.remove(e)
+		ok = false
+	}
+	if !ok {
+		c.lookups.Inc("miss")
+		return nil, false
+	}
+	c.lookups.Inc("hit")
+	c.order.MoveToFront(e)
+	return e.Value.(*idempotencyEntry).response, true
+}
+
+// set records the response for key.
+func (c *idempotencyCache) set(key string, b []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.store(key, b)
+}
+
+// store records the response for key. c.mu must be held.
+func (c *idempotencyCache) store(key string, b []byte) {
+	t := now()
+	var expires time.Time
+	if c.ttl > 0 {
+		expires = t.Add(c.ttl)
+	}
+
+	if e, ok := c.entries[key]; ok {
+		entry := e.Value.(*idempotencyEntry)
+		c.bytes += int64(len(b) - len(entry.response))
+		entry.response, entry.expires = b, expires
+		c.order.MoveToFront(e)
+		c.trim()
+		return
+	}
+
+	// Make room, dropping expired entries on the way.
+	for back := c.order.Back(); back != nil; back = c.order.Back() {
+		if c.order.Len() < c.size && !c.expired(back.Value.(*idempotencyEntry), t) {
+			break
+		}
+		c.remove(back)
+	}
+	entry := &idempotencyEntry{key: key, response: b, expires: expires}
+	c.entries[key] = c.order.PushFront(entry)
+	c.bytes += entry.entrySize()
+	c.trim()
+}
+
+// trim evicts the least recently used entries until the cache holds no more
+// than maxBytes, keeping the most recent entry whatever its size.
+func (c *idempotencyCache) trim() {
+	if c.maxBytes <= 0 {
+		return
+	}
+	for c.bytes > c.maxBytes && c.order.Len() > 1 {
+		c.remove(c.order.Back())
+	}
+}
+
+// compact drops the expired entries, which are otherwise only dropped once
+// looked up or evicted, and returns how many it dropped.
+func (c *idempotencyCache) compact() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := now()
+	var n int
+	for e := c.order.Back(); e != nil; {
+		prev := e.Prev()
+		if c.expired(e.Value.(*idempotencyEntry), t) {
+			c.remove(e)
+			n++
+		}
+		e = prev
+	}
+	if c.compacted != nil {
+		c.compacted.Add(float64(n))
+	}
+	return n
+}
+
+// compactEvery compacts the cache every interval until done is closed.
+func (c *idempotencyCache) compactEvery(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.compact()
+		case <-done:
+			return
+		}
+	}
+}
+
+// len returns the number of cached responses, including expired ones not
+// yet dropped.
+func (c *idempotencyCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// sizeBytes returns the total size of the cached keys and responses.
+func (c *idempotencyCache) sizeBytes() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.bytes
+}
+
+func (c *idempotencyCache) expired(entry *idempotencyEntry, t time.Time) bool {
+	return !entry.expires.IsZero() && !t.Before(entry.expires)
+}
+
+func (c *idempotencyCache) remove(e *list.Element) {
+	entry := e.Value.(*idempotencyEntry)
+	c.order.Remove(e)
+	delete(c.entries, entry.key)
+	c.bytes -= entry.entrySize()
+}
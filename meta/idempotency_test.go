@@ -0,0 +1,160 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Ensure a full idempotency cache evicts its least recently used keys while
+// recent keys still dedupe, and that lookups are counted.
+func TestIdempotencyCache_Evict(t *testing.T) {
+	lookups := NewRegistry().NewCounter("lookups", "", "result")
+	c := newIdempotencyCache(10, 0, lookups)
+	for i := 0; i < 10; i++ {
+		c.set(fmt.Sprintf("key%d", i), []byte{byte(i)})
+	}
+
+	// A lookup makes key0 recently used, so key1 is the first to go.
+	if b, ok := c.get("key0"); !ok || b[0] != 0 {
+		t.Fatalf("unexpected response for key0: %v, %v", b, ok)
+	}
+	for i := 10; i < 15; i++ {
+		c.set(fmt.Sprintf("key%d", i), []byte{byte(i)})
+	}
+
+	if n := c.len(); n != 10 {
+		t.Fatalf("unexpected cache size: %d", n)
+	}
+	for i := 1; i <= 5; i++ {
+		if _, ok := c.get(fmt.Sprintf("key%d", i)); ok {
+			t.Fatalf("key%d not evicted", i)
+		}
+	}
+	for _, i := range []int{0, 6, 9, 14} {
+		if b, ok := c.get(fmt.Sprintf("key%d", i)); !ok || b[0] != byte(i) {
+			t.Fatalf("unexpected response for key%d: %v, %v", i, b, ok)
+		}
+	}
+
+	if hits, misses := lookups.Value("hit"), lookups.Value("miss"); hits != 5 || misses != 5 {
+		t.Fatalf("unexpected lookups: %v hits, %v misses", hits, misses)
+	}
+}
+
+// Ensure idempotency cache entries expire after the ttl.
+func TestIdempotencyCache_TTL(t *testing.T) {
+	c := newIdempotencyCache(10, 10*time.Millisecond, NewRegistry().NewCounter("lookups", "", "result"))
+	c.set("key", []byte("response"))
+	if _, ok := c.get("key"); !ok {
+		t.Fatal("expected key to be cached")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := c.get("key"); ok {
+		t.Fatal("expected key to expire")
+	} else if n := c.len(); n != 0 {
+		t.Fatalf("unexpected cache size: %d", n)
+	}
+}
+
+// Ensure compaction drops the entries past the ttl without them being looked
+// up, while recent ones remain.
+func TestIdempotencyCache_Compact(t *testing.T) {
+	c := newIdempotencyCache(10, time.Hour, NewRegistry().NewCounter("lookups", "", "result"))
+	c.compacted = NewRegistry().NewCounter("compacted", "")
+	c.set("old1", []byte("response"))
+	c.set("old2", []byte("response"))
+	c.set("recent", []byte("response"))
+
+	// Age the old entries past the ttl.
+	for _, key := range []string{"old1", "old2"} {
+		c.entries[key].Value.(*idempotencyEntry).expires = now().Add(-time.Second)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go c.compactEvery(10*time.Millisecond, done)
+	for i := 0; c.len() != 1; i++ {
+		if i == 100 {
+			t.Fatalf("expired entries not compacted: %d entries", c.len())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if n := c.compacted.Value(); n != 2 {
+		t.Fatalf("unexpected compacted count: %v", n)
+	} else if n := c.sizeBytes(); n != int64(len("recent")+len("response")) {
+		t.Fatalf("unexpected cache bytes: %d", n)
+	} else if _, ok := c.get("recent"); !ok {
+		t.Fatal("recent key compacted")
+	}
+}
+
+// Ensure the least recently used entries are evicted once the cache holds
+// more than maxBytes.
+func TestIdempotencyCache_MaxBytes(t *testing.T) {
+	c := newIdempotencyCache(10, 0, NewRegistry().NewCounter("lookups", "", "result"))
+	c.maxBytes = 30
+	for i := 0; i < 4; i++ {
+		c.set(fmt.Sprintf("key%d", i), []byte("response"))
+	}
+
+	// Each entry is 12 bytes, so only the two most recent fit.
+	if n := c.len(); n != 2 {
+		t.Fatalf("unexpected cache size: %d", n)
+	} else if n := c.sizeBytes(); n != 24 {
+		t.Fatalf("unexpected cache bytes: %d", n)
+	}
+	for _, key := range []string{"key2", "key3"} {
+		if _, ok := c.get(key); !ok {
+			t.Fatalf("%s evicted", key)
+		}
+	}
+}
+
+// Ensure a key acquired by one request makes a concurrent request with the
+// same key wait, and then get its response rather than apply it again.
+func TestIdempotencyCache_Acquire(t *testing.T) {
+	c := newIdempotencyCache(10, 0, NewRegistry().NewCounter("lookups", "", "result"))
+	if _, ok, err := c.acquire(context.Background(), "key"); ok || err != nil {
+		t.Fatalf("unexpected acquire: %v, %v", ok, err)
+	}
+
+	type result struct {
+		b   []byte
+		ok  bool
+		err error
+	}
+	results := make(chan result, 1)
+	go func() {
+		b, ok, err := c.acquire(context.Background(), "key")
+		results <- result{b, ok, err}
+	}()
+	select {
+	case r := <-results:
+		t.Fatalf("acquired a reserved key: %+v", r)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	c.release("key", []byte("response"))
+	if r := <-results; !r.ok || string(r.b) != "response" || r.err != nil {
+		t.Fatalf("unexpected acquire after release: %+v", r)
+	}
+
+	// A key released without a response, as for a failed command, can be
+	// reserved again.
+	if _, ok, _ := c.acquire(context.Background(), "other"); ok {
+		t.Fatal("unexpected cached response")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, _, err := c.acquire(ctx, "other"); err != context.DeadlineExceeded {
+		t.Fatalf("unexpected error waiting for a reserved key: %v", err)
+	}
+	c.release("other", nil)
+	if _, ok, err := c.acquire(context.Background(), "other"); ok || err != nil {
+		t.Fatalf("unexpected acquire of a released key: %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,78 @@
+package meta
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// inflightRequest describes an HTTP request that is still being served.
+type inflightRequest struct {
+	ID         string    `json:"id"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	RemoteAddr string    `json:"remoteAddr"`
+	Started    time.Time `json:"started"`
+	Age        string    `json:"age"`
+}
+
+// inflightRequests tracks the requests currently being served so stuck
+// handlers can be found through /debug/requests.
+type inflightRequests struct {
+	mu   sync.Mutex
+	next uint64
+	m    map[uint64]*inflightRequest
+}
+
+func newInflightRequests() *inflightRequests {
+	return &inflightRequests{m: make(map[uint64]*inflightRequest)}
+}
+
+// add records r as in flight and returns the id to remove it with.
+func (t *inflightRequests) add(r *http.Request) uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.next++
+	t.m[t.next] = &inflightRequest{
+		ID:         r.Header.Get("Request-Id"),
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		RemoteAddr: r.RemoteAddr,
+		Started:    time.Now(),
+	}
+	return t.next
+}
+
+// remove forgets the request added under id.
+func (t *inflightRequests) remove(id uint64) {
+	t.mu.Lock()
+	delete(t.m, id)
+	t.mu.Unlock()
+}
+
+// list returns the in-flight requests, oldest first.
+func (t *inflightRequests) list() []inflightRequest {
+	t.mu.Lock()
+	a := make([]inflightRequest, 0, len(t.m))
+	for _, req := range t.m {
+		a = append(a, *req)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(a, func(i, j int) bool { return a[i].Started.Before(a[j].Started) })
+	for i := range a {
+		a[i].Age = time.Since(a[i].Started).String()
+	}
+	return a
+}
+
+// tracking records every request in the in-flight registry while it is
+// being served.
+func tracking(inner http.Handler, t *inflightRequests) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := t.add(r)
+		defer t.remove(id)
+		inner.ServeHTTP(w, r)
+	})
+}
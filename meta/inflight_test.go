@@ -0,0 +1,70 @@
+package meta_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+// Ensure a slow request is listed by /debug/requests until it completes.
+func TestMetaService_DebugRequests(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	inflight := func() map[string]bool {
+		resp, err := http.Get("http://" + s.HTTPAddr() + "/debug/requests")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		var reqs []struct {
+			Method string `json:"method"`
+			Path   string `json:"path"`
+			Age    string `json:"age"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&reqs); err != nil {
+			t.Fatal(err)
+		}
+		paths := make(map[string]bool)
+		for _, r := range reqs {
+			paths[r.Method+" "+r.Path] = true
+		}
+		return paths
+	}
+
+	// A snapshot request for a future index blocks until the data changes.
+	done := make(chan error)
+	go func() {
+		resp, err := http.Get("http://" + s.HTTPAddr() + "/slow?index=1000000")
+		if err == nil {
+			resp.Body.Close()
+		}
+		done <- err
+	}()
+
+	timeout := time.Now().Add(5 * time.Second)
+	for !inflight()["GET /slow"] {
+		if time.Now().After(timeout) {
+			t.Fatal("slow request never listed as in flight")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Changing the data completes the request.
+	if _, err := c.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if inflight()["GET /slow"] {
+		t.Fatal("completed request still listed as in flight")
+	}
+}
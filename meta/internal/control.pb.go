@@ -0,0 +1,651 @@
+// Code generated by protoc-gen-gogo.
+// source: internal/control.proto
+// DO NOT EDIT!
+
+/*
+Package internal is a generated protocol buffer package.
+
+It is generated from these files:
+	internal/control.proto
+
+It has these top-level messages:
+	ControlNode
+	StatusRequest
+	StatusResponse
+	AddMetaNodeRequest
+	AddMetaNodeResponse
+	RemoveNodeRequest
+	ShardReassignment
+	RemoveNodeResponse
+	ResignLeadershipRequest
+	ResignLeadershipResponse
+*/
+package internal
+
+import proto "github.com/gogo/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+import (
+	context "golang.org/x/net/context"
+	grpc "google.golang.org/grpc"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.GoGoProtoPackageIsVersion2 // please upgrade the proto package
+
+type ControlNode struct {
+	ID               *uint64 `protobuf:"varint,1,req,name=ID" json:"ID,omitempty"`
+	Host             *string `protobuf:"bytes,2,req,name=Host" json:"Host,omitempty"`
+	TCPHost          *string `protobuf:"bytes,3,opt,name=TCPHost" json:"TCPHost,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *ControlNode) Reset()                    { *m = ControlNode{} }
+func (m *ControlNode) String() string            { return proto.CompactTextString(m) }
+func (*ControlNode) ProtoMessage()               {}
+func (*ControlNode) Descriptor() ([]byte, []int) { return fileDescriptorControl, []int{0} }
+
+func (m *ControlNode) GetID() uint64 {
+	if m != nil && m.ID != nil {
+		return *m.ID
+	}
+	return 0
+}
+
+func (m *ControlNode) GetHost() string {
+	if m != nil && m.Host != nil {
+		return *m.Host
+	}
+	return ""
+}
+
+func (m *ControlNode) GetTCPHost() string {
+	if m != nil && m.TCPHost != nil {
+		return *m.TCPHost
+	}
+	return ""
+}
+
+type StatusRequest struct {
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *StatusRequest) Reset()                    { *m = StatusRequest{} }
+func (m *StatusRequest) String() string            { return proto.CompactTextString(m) }
+func (*StatusRequest) ProtoMessage()               {}
+func (*StatusRequest) Descriptor() ([]byte, []int) { return fileDescriptorControl, []int{1} }
+
+type StatusResponse struct {
+	State            *string        `protobuf:"bytes,1,req,name=State" json:"State,omitempty"`
+	Leader           *string        `protobuf:"bytes,2,req,name=Leader" json:"Leader,omitempty"`
+	LeaderHTTP       *string        `protobuf:"bytes,3,opt,name=LeaderHTTP" json:"LeaderHTTP,omitempty"`
+	NodeID           *uint64        `protobuf:"varint,4,opt,name=NodeID" json:"NodeID,omitempty"`
+	Peers            []string       `protobuf:"bytes,5,rep,name=Peers" json:"Peers,omitempty"`
+	LastIndex        *uint64        `protobuf:"varint,6,req,name=LastIndex" json:"LastIndex,omitempty"`
+	MetaNodes        []*ControlNode `protobuf:"bytes,7,rep,name=MetaNodes" json:"MetaNodes,omitempty"`
+	DataNodes        []*ControlNode `protobuf:"bytes,8,rep,name=DataNodes" json:"DataNodes,omitempty"`
+	XXX_unrecognized []byte         `json:"-"`
+}
+
+func (m *StatusResponse) Reset()                    { *m = StatusResponse{} }
+func (m *StatusResponse) String() string            { return proto.CompactTextString(m) }
+func (*StatusResponse) ProtoMessage()               {}
+func (*StatusResponse) Descriptor() ([]byte, []int) { return fileDescriptorControl, []int{2} }
+
+func (m *StatusResponse) GetState() string {
+	if m != nil && m.State != nil {
+		return *m.State
+	}
+	return ""
+}
+
+func (m *StatusResponse) GetLeader() string {
+	if m != nil && m.Leader != nil {
+		return *m.Leader
+	}
+	return ""
+}
+
+func (m *StatusResponse) GetLeaderHTTP() string {
+	if m != nil && m.LeaderHTTP != nil {
+		return *m.LeaderHTTP
+	}
+	return ""
+}
+
+func (m *StatusResponse) GetNodeID() uint64 {
+	if m != nil && m.NodeID != nil {
+		return *m.NodeID
+	}
+	return 0
+}
+
+func (m *StatusResponse) GetPeers() []string {
+	if m != nil {
+		return m.Peers
+	}
+	return nil
+}
+
+func (m *StatusResponse) GetLastIndex() uint64 {
+	if m != nil && m.LastIndex != nil {
+		return *m.LastIndex
+	}
+	return 0
+}
+
+func (m *StatusResponse) GetMetaNodes() []*ControlNode {
+	if m != nil {
+		return m.MetaNodes
+	}
+	return nil
+}
+
+func (m *StatusResponse) GetDataNodes() []*ControlNode {
+	if m != nil {
+		return m.DataNodes
+	}
+	return nil
+}
+
+type AddMetaNodeRequest struct {
+	HTTPAddr         *string `protobuf:"bytes,1,req,name=HTTPAddr" json:"HTTPAddr,omitempty"`
+	TCPAddr          *string `protobuf:"bytes,2,req,name=TCPAddr" json:"TCPAddr,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *AddMetaNodeRequest) Reset()                    { *m = AddMetaNodeRequest{} }
+func (m *AddMetaNodeRequest) String() string            { return proto.CompactTextString(m) }
+func (*AddMetaNodeRequest) ProtoMessage()               {}
+func (*AddMetaNodeRequest) Descriptor() ([]byte, []int) { return fileDescriptorControl, []int{3} }
+
+func (m *AddMetaNodeRequest) GetHTTPAddr() string {
+	if m != nil && m.HTTPAddr != nil {
+		return *m.HTTPAddr
+	}
+	return ""
+}
+
+func (m *AddMetaNodeRequest) GetTCPAddr() string {
+	if m != nil && m.TCPAddr != nil {
+		return *m.TCPAddr
+	}
+	return ""
+}
+
+type AddMetaNodeResponse struct {
+	Node             *ControlNode `protobuf:"bytes,1,req,name=Node" json:"Node,omitempty"`
+	XXX_unrecognized []byte       `json:"-"`
+}
+
+func (m *AddMetaNodeResponse) Reset()                    { *m = AddMetaNodeResponse{} }
+func (m *AddMetaNodeResponse) String() string            { return proto.CompactTextString(m) }
+func (*AddMetaNodeResponse) ProtoMessage()               {}
+func (*AddMetaNodeResponse) Descriptor() ([]byte, []int) { return fileDescriptorControl, []int{4} }
+
+func (m *AddMetaNodeResponse) GetNode() *ControlNode {
+	if m != nil {
+		return m.Node
+	}
+	return nil
+}
+
+type RemoveNodeRequest struct {
+	// Type is "meta" or "data".
+	Type             *string `protobuf:"bytes,1,req,name=Type" json:"Type,omitempty"`
+	ID               *uint64 `protobuf:"varint,2,req,name=ID" json:"ID,omitempty"`
+	Force            *bool   `protobuf:"varint,3,opt,name=Force" json:"Force,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *RemoveNodeRequest) Reset()                    { *m = RemoveNodeRequest{} }
+func (m *RemoveNodeRequest) String() string            { return proto.CompactTextString(m) }
+func (*RemoveNodeRequest) ProtoMessage()               {}
+func (*RemoveNodeRequest) Descriptor() ([]byte, []int) { return fileDescriptorControl, []int{5} }
+
+func (m *RemoveNodeRequest) GetType() string {
+	if m != nil && m.Type != nil {
+		return *m.Type
+	}
+	return ""
+}
+
+func (m *RemoveNodeRequest) GetID() uint64 {
+	if m != nil && m.ID != nil {
+		return *m.ID
+	}
+	return 0
+}
+
+func (m *RemoveNodeRequest) GetForce() bool {
+	if m != nil && m.Force != nil {
+		return *m.Force
+	}
+	return false
+}
+
+type ShardReassignment struct {
+	Database         *string  `protobuf:"bytes,1,req,name=Database" json:"Database,omitempty"`
+	RetentionPolicy  *string  `protobuf:"bytes,2,req,name=RetentionPolicy" json:"RetentionPolicy,omitempty"`
+	ShardGroupID     *uint64  `protobuf:"varint,3,req,name=ShardGroupID" json:"ShardGroupID,omitempty"`
+	ShardID          *uint64  `protobuf:"varint,4,req,name=ShardID" json:"ShardID,omitempty"`
+	From             *uint64  `protobuf:"varint,5,req,name=From" json:"From,omitempty"`
+	To               []uint64 `protobuf:"varint,6,rep,name=To" json:"To,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *ShardReassignment) Reset()                    { *m = ShardReassignment{} }
+func (m *ShardReassignment) String() string            { return proto.CompactTextString(m) }
+func (*ShardReassignment) ProtoMessage()               {}
+func (*ShardReassignment) Descriptor() ([]byte, []int) { return fileDescriptorControl, []int{6} }
+
+func (m *ShardReassignment) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *ShardReassignment) GetRetentionPolicy() string {
+	if m != nil && m.RetentionPolicy != nil {
+		return *m.RetentionPolicy
+	}
+	return ""
+}
+
+func (m *ShardReassignment) GetShardGroupID() uint64 {
+	if m != nil && m.ShardGroupID != nil {
+		return *m.ShardGroupID
+	}
+	return 0
+}
+
+func (m *ShardReassignment) GetShardID() uint64 {
+	if m != nil && m.ShardID != nil {
+		return *m.ShardID
+	}
+	return 0
+}
+
+func (m *ShardReassignment) GetFrom() uint64 {
+	if m != nil && m.From != nil {
+		return *m.From
+	}
+	return 0
+}
+
+func (m *ShardReassignment) GetTo() []uint64 {
+	if m != nil {
+		return m.To
+	}
+	return nil
+}
+
+type RemoveNodeResponse struct {
+	NodeID                *uint64              `protobuf:"varint,1,req,name=NodeID" json:"NodeID,omitempty"`
+	NodeType              *string              `protobuf:"bytes,2,req,name=NodeType" json:"NodeType,omitempty"`
+	Host                  *string              `protobuf:"bytes,3,req,name=Host" json:"Host,omitempty"`
+	RaftPeerRemoved       *string              `protobuf:"bytes,4,opt,name=RaftPeerRemoved" json:"RaftPeerRemoved,omitempty"`
+	LeaderBefore          *string              `protobuf:"bytes,5,opt,name=LeaderBefore" json:"LeaderBefore,omitempty"`
+	LeaderAfter           *string              `protobuf:"bytes,6,opt,name=LeaderAfter" json:"LeaderAfter,omitempty"`
+	LeadershipTransferred *bool                `protobuf:"varint,7,opt,name=LeadershipTransferred" json:"LeadershipTransferred,omitempty"`
+	ReassignedShards      []*ShardReassignment `protobuf:"bytes,8,rep,name=ReassignedShards" json:"ReassignedShards,omitempty"`
+	DeletedShardGroups    []uint64             `protobuf:"varint,9,rep,name=DeletedShardGroups" json:"DeletedShardGroups,omitempty"`
+	XXX_unrecognized      []byte               `json:"-"`
+}
+
+func (m *RemoveNodeResponse) Reset()                    { *m = RemoveNodeResponse{} }
+func (m *RemoveNodeResponse) String() string            { return proto.CompactTextString(m) }
+func (*RemoveNodeResponse) ProtoMessage()               {}
+func (*RemoveNodeResponse) Descriptor() ([]byte, []int) { return fileDescriptorControl, []int{7} }
+
+func (m *RemoveNodeResponse) GetNodeID() uint64 {
+	if m != nil && m.NodeID != nil {
+		return *m.NodeID
+	}
+	return 0
+}
+
+func (m *RemoveNodeResponse) GetNodeType() string {
+	if m != nil && m.NodeType != nil {
+		return *m.NodeType
+	}
+	return ""
+}
+
+func (m *RemoveNodeResponse) GetHost() string {
+	if m != nil && m.Host != nil {
+		return *m.Host
+	}
+	return ""
+}
+
+func (m *RemoveNodeResponse) GetRaftPeerRemoved() string {
+	if m != nil && m.RaftPeerRemoved != nil {
+		return *m.RaftPeerRemoved
+	}
+	return ""
+}
+
+func (m *RemoveNodeResponse) GetLeaderBefore() string {
+	if m != nil && m.LeaderBefore != nil {
+		return *m.LeaderBefore
+	}
+	return ""
+}
+
+func (m *RemoveNodeResponse) GetLeaderAfter() string {
+	if m != nil && m.LeaderAfter != nil {
+		return *m.LeaderAfter
+	}
+	return ""
+}
+
+func (m *RemoveNodeResponse) GetLeadershipTransferred() bool {
+	if m != nil && m.LeadershipTransferred != nil {
+		return *m.LeadershipTransferred
+	}
+	return false
+}
+
+func (m *RemoveNodeResponse) GetReassignedShards() []*ShardReassignment {
+	if m != nil {
+		return m.ReassignedShards
+	}
+	return nil
+}
+
+func (m *RemoveNodeResponse) GetDeletedShardGroups() []uint64 {
+	if m != nil {
+		return m.DeletedShardGroups
+	}
+	return nil
+}
+
+type ResignLeadershipRequest struct {
+	// Timeout is how long to wait for a new leader, in nanoseconds.
+	Timeout          *int64 `protobuf:"varint,1,opt,name=Timeout" json:"Timeout,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *ResignLeadershipRequest) Reset()                    { *m = ResignLeadershipRequest{} }
+func (m *ResignLeadershipRequest) String() string            { return proto.CompactTextString(m) }
+func (*ResignLeadershipRequest) ProtoMessage()               {}
+func (*ResignLeadershipRequest) Descriptor() ([]byte, []int) { return fileDescriptorControl, []int{8} }
+
+func (m *ResignLeadershipRequest) GetTimeout() int64 {
+	if m != nil && m.Timeout != nil {
+		return *m.Timeout
+	}
+	return 0
+}
+
+type ResignLeadershipResponse struct {
+	Leader           *string `protobuf:"bytes,1,req,name=Leader" json:"Leader,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *ResignLeadershipResponse) Reset()                    { *m = ResignLeadershipResponse{} }
+func (m *ResignLeadershipResponse) String() string            { return proto.CompactTextString(m) }
+func (*ResignLeadershipResponse) ProtoMessage()               {}
+func (*ResignLeadershipResponse) Descriptor() ([]byte, []int) { return fileDescriptorControl, []int{9} }
+
+func (m *ResignLeadershipResponse) GetLeader() string {
+	if m != nil && m.Leader != nil {
+		return *m.Leader
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*ControlNode)(nil), "internal.ControlNode")
+	proto.RegisterType((*StatusRequest)(nil), "internal.StatusRequest")
+	proto.RegisterType((*StatusResponse)(nil), "internal.StatusResponse")
+	proto.RegisterType((*AddMetaNodeRequest)(nil), "internal.AddMetaNodeRequest")
+	proto.RegisterType((*AddMetaNodeResponse)(nil), "internal.AddMetaNodeResponse")
+	proto.RegisterType((*RemoveNodeRequest)(nil), "internal.RemoveNodeRequest")
+	proto.RegisterType((*ShardReassignment)(nil), "internal.ShardReassignment")
+	proto.RegisterType((*RemoveNodeResponse)(nil), "internal.RemoveNodeResponse")
+	proto.RegisterType((*ResignLeadershipRequest)(nil), "internal.ResignLeadershipRequest")
+	proto.RegisterType((*ResignLeadershipResponse)(nil), "internal.ResignLeadershipResponse")
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion3
+
+// Client API for Control service
+
+type ControlClient interface {
+	// Status returns the raft status of the node and the nodes of the
+	// cluster.
+	Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusResponse, error)
+	// AddMetaNode adds a meta node to the cluster, as joining it does.
+	AddMetaNode(ctx context.Context, in *AddMetaNodeRequest, opts ...grpc.CallOption) (*AddMetaNodeResponse, error)
+	// RemoveNode removes a meta or data node from the cluster.
+	RemoveNode(ctx context.Context, in *RemoveNodeRequest, opts ...grpc.CallOption) (*RemoveNodeResponse, error)
+	// ResignLeadership has the node, if it leads the cluster, transfer
+	// leadership to another meta node.
+	ResignLeadership(ctx context.Context, in *ResignLeadershipRequest, opts ...grpc.CallOption) (*ResignLeadershipResponse, error)
+}
+
+type controlClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewControlClient(cc *grpc.ClientConn) ControlClient {
+	return &controlClient{cc}
+}
+
+func (c *controlClient) Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusResponse, error) {
+	out := new(StatusResponse)
+	err := grpc.Invoke(ctx, "/internal.Control/Status", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) AddMetaNode(ctx context.Context, in *AddMetaNodeRequest, opts ...grpc.CallOption) (*AddMetaNodeResponse, error) {
+	out := new(AddMetaNodeResponse)
+	err := grpc.Invoke(ctx, "/internal.Control/AddMetaNode", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) RemoveNode(ctx context.Context, in *RemoveNodeRequest, opts ...grpc.CallOption) (*RemoveNodeResponse, error) {
+	out := new(RemoveNodeResponse)
+	err := grpc.Invoke(ctx, "/internal.Control/RemoveNode", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) ResignLeadership(ctx context.Context, in *ResignLeadershipRequest, opts ...grpc.CallOption) (*ResignLeadershipResponse, error) {
+	out := new(ResignLeadershipResponse)
+	err := grpc.Invoke(ctx, "/internal.Control/ResignLeadership", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Server API for Control service
+
+type ControlServer interface {
+	// Status returns the raft status of the node and the nodes of the
+	// cluster.
+	Status(context.Context, *StatusRequest) (*StatusResponse, error)
+	// AddMetaNode adds a meta node to the cluster, as joining it does.
+	AddMetaNode(context.Context, *AddMetaNodeRequest) (*AddMetaNodeResponse, error)
+	// RemoveNode removes a meta or data node from the cluster.
+	RemoveNode(context.Context, *RemoveNodeRequest) (*RemoveNodeResponse, error)
+	// ResignLeadership has the node, if it leads the cluster, transfer
+	// leadership to another meta node.
+	ResignLeadership(context.Context, *ResignLeadershipRequest) (*ResignLeadershipResponse, error)
+}
+
+func RegisterControlServer(s *grpc.Server, srv ControlServer) {
+	s.RegisterService(&_Control_serviceDesc, srv)
+}
+
+func _Control_Status_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).Status(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/internal.Control/Status",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).Status(ctx, req.(*StatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_AddMetaNode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddMetaNodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).AddMetaNode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/internal.Control/AddMetaNode",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).AddMetaNode(ctx, req.(*AddMetaNodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_RemoveNode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveNodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).RemoveNode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/internal.Control/RemoveNode",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).RemoveNode(ctx, req.(*RemoveNodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_ResignLeadership_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResignLeadershipRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).ResignLeadership(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/internal.Control/ResignLeadership",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).ResignLeadership(ctx, req.(*ResignLeadershipRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Control_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "internal.Control",
+	HandlerType: (*ControlServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Status",
+			Handler:    _Control_Status_Handler,
+		},
+		{
+			MethodName: "AddMetaNode",
+			Handler:    _Control_AddMetaNode_Handler,
+		},
+		{
+			MethodName: "RemoveNode",
+			Handler:    _Control_RemoveNode_Handler,
+		},
+		{
+			MethodName: "ResignLeadership",
+			Handler:    _Control_ResignLeadership_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: fileDescriptorControl,
+}
+
+func init() { proto.RegisterFile("internal/control.proto", fileDescriptorControl) }
+
+var fileDescriptorControl = []byte{
+	// 670 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x54, 0x4b, 0x6f, 0xd3, 0x40,
+	0x10, 0x56, 0x9c, 0xa7, 0x27, 0xd0, 0xc7, 0xd2, 0xc7, 0x2a, 0x14, 0x64, 0x7c, 0x32, 0x97, 0x20,
+	0xb5, 0xdc, 0xb8, 0x50, 0x1a, 0xb5, 0x4d, 0x69, 0x51, 0xb4, 0xf5, 0x85, 0xa3, 0xa9, 0x27, 0x34,
+	0x52, 0xe2, 0x0d, 0xbb, 0x1b, 0x44, 0xff, 0x07, 0xbf, 0x85, 0x0b, 0x3f, 0x8d, 0x0b, 0xda, 0x57,
+	0xec, 0x36, 0x69, 0x6f, 0xfe, 0xbe, 0x79, 0xed, 0x7c, 0x33, 0x63, 0xd8, 0x9b, 0x14, 0x0a, 0x45,
+	0x91, 0x4d, 0xdf, 0xdd, 0xf0, 0x42, 0x09, 0x3e, 0xed, 0xcf, 0x05, 0x57, 0x9c, 0x74, 0x3c, 0x1f,
+	0x7f, 0x86, 0xee, 0x89, 0x35, 0x7d, 0xe1, 0x39, 0x92, 0x0d, 0x08, 0x86, 0x03, 0x5a, 0x8b, 0x82,
+	0xa4, 0xc1, 0x82, 0xe1, 0x80, 0x10, 0x68, 0x9c, 0x73, 0xa9, 0x68, 0x10, 0x05, 0x49, 0xc8, 0xcc,
+	0x37, 0xa1, 0xd0, 0x4e, 0x4f, 0x46, 0x86, 0xae, 0x47, 0xb5, 0x24, 0x64, 0x1e, 0xc6, 0x9b, 0xf0,
+	0xfc, 0x5a, 0x65, 0x6a, 0x21, 0x19, 0xfe, 0x58, 0xa0, 0x54, 0xf1, 0xef, 0x00, 0x36, 0x3c, 0x23,
+	0xe7, 0xbc, 0x90, 0x48, 0x76, 0xa0, 0xa9, 0x19, 0x34, 0x45, 0x42, 0x66, 0x01, 0xd9, 0x83, 0xd6,
+	0x25, 0x66, 0x39, 0x0a, 0x57, 0xc9, 0x21, 0xf2, 0x1a, 0xc0, 0x7e, 0x9d, 0xa7, 0xe9, 0xc8, 0x95,
+	0xab, 0x30, 0x3a, 0x4e, 0xbf, 0x7b, 0x38, 0xa0, 0x8d, 0xa8, 0x96, 0x34, 0x98, 0x43, 0xba, 0xca,
+	0x08, 0x51, 0x48, 0xda, 0x8c, 0xea, 0xba, 0x8a, 0x01, 0xe4, 0x00, 0xc2, 0xcb, 0x4c, 0xaa, 0x61,
+	0x91, 0xe3, 0x2f, 0xda, 0x32, 0x4d, 0x96, 0x04, 0x39, 0x82, 0xf0, 0x0a, 0x55, 0xa6, 0x33, 0x48,
+	0xda, 0x8e, 0xea, 0x49, 0xf7, 0x70, 0xb7, 0xef, 0x85, 0xea, 0x57, 0x54, 0x62, 0xa5, 0x9f, 0x0e,
+	0x1a, 0x64, 0x3e, 0xa8, 0xf3, 0x64, 0xd0, 0xd2, 0x2f, 0xbe, 0x00, 0x72, 0x9c, 0xe7, 0x3e, 0x89,
+	0x13, 0x8b, 0xf4, 0xa0, 0xa3, 0x7b, 0x3a, 0xce, 0x73, 0xe1, 0xc4, 0x59, 0x62, 0xa7, 0xb9, 0x31,
+	0x59, 0x81, 0x3c, 0x8c, 0x3f, 0xc2, 0x8b, 0x7b, 0xb9, 0x9c, 0xcc, 0x6f, 0xa1, 0xa1, 0xb1, 0x49,
+	0xf4, 0xe8, 0x93, 0x8c, 0x4b, 0x7c, 0x05, 0xdb, 0x0c, 0x67, 0xfc, 0x27, 0x56, 0x1f, 0x43, 0xa0,
+	0x91, 0xde, 0xcd, 0xfd, 0x94, 0xcc, 0xb7, 0x5b, 0x8e, 0x60, 0xb9, 0x1c, 0x3b, 0xd0, 0x3c, 0xe5,
+	0xe2, 0x06, 0xcd, 0x5c, 0x3a, 0xcc, 0x82, 0xf8, 0x6f, 0x0d, 0xb6, 0xaf, 0x6f, 0x33, 0x91, 0x33,
+	0xcc, 0xa4, 0x9c, 0x7c, 0x2f, 0x66, 0x58, 0x98, 0xe6, 0x74, 0xff, 0xdf, 0x32, 0xe9, 0x73, 0x2e,
+	0x31, 0x49, 0x60, 0x93, 0xa1, 0xc2, 0x42, 0x4d, 0x78, 0x31, 0xe2, 0xd3, 0xc9, 0xcd, 0x9d, 0x6b,
+	0xf2, 0x21, 0x4d, 0x62, 0x78, 0x66, 0x52, 0x9f, 0x09, 0xbe, 0x98, 0x0f, 0x07, 0xb4, 0x6e, 0xde,
+	0x72, 0x8f, 0xd3, 0x52, 0x19, 0x6c, 0x76, 0x42, 0x9b, 0x3d, 0xd4, 0x3d, 0x9d, 0x0a, 0x3e, 0xa3,
+	0x4d, 0x43, 0x9b, 0x6f, 0xdd, 0x53, 0xca, 0x69, 0x2b, 0xaa, 0xeb, 0x9e, 0x52, 0x1e, 0xff, 0x0b,
+	0x80, 0x54, 0xd5, 0x70, 0x72, 0x96, 0x7b, 0x66, 0x6f, 0xc3, 0x21, 0xdd, 0x96, 0xfe, 0x32, 0x52,
+	0xd9, 0x37, 0x2f, 0xf1, 0xf2, 0x76, 0xea, 0x95, 0xdb, 0xd1, 0xad, 0x66, 0x63, 0xa5, 0xd7, 0xd1,
+	0x56, 0xc9, 0xcd, 0xe2, 0x86, 0xec, 0x21, 0xad, 0x5b, 0xb5, 0x7b, 0xfe, 0x09, 0xc7, 0x5c, 0x20,
+	0x6d, 0x1a, 0xb7, 0x7b, 0x1c, 0x89, 0xa0, 0x6b, 0xf1, 0xf1, 0x58, 0xa1, 0xa0, 0x2d, 0xe3, 0x52,
+	0xa5, 0xc8, 0x7b, 0xd8, 0xb5, 0x50, 0xde, 0x4e, 0xe6, 0xa9, 0xc8, 0x0a, 0x39, 0x46, 0x21, 0x30,
+	0xa7, 0x6d, 0x33, 0xb2, 0xf5, 0x46, 0x72, 0x06, 0x5b, 0x7e, 0x78, 0x98, 0x1b, 0xf5, 0xfc, 0x6e,
+	0xbf, 0x2c, 0x17, 0x69, 0x65, 0xc6, 0x6c, 0x25, 0x88, 0xf4, 0x81, 0x0c, 0x70, 0x8a, 0x0a, 0xf3,
+	0x72, 0x44, 0x92, 0x86, 0x46, 0xed, 0x35, 0x96, 0xf8, 0x08, 0xf6, 0x19, 0xea, 0x0c, 0xe5, 0xbb,
+	0xfc, 0x42, 0xea, 0x0b, 0x98, 0xcc, 0x90, 0x2f, 0x14, 0xad, 0x45, 0xb5, 0xa4, 0xce, 0x3c, 0x8c,
+	0x0f, 0x81, 0xae, 0x06, 0x95, 0x73, 0xb3, 0xac, 0x5b, 0x3a, 0x87, 0x0e, 0xff, 0x04, 0xd0, 0x76,
+	0x97, 0x40, 0x3e, 0x40, 0xcb, 0xfe, 0xa3, 0xc8, 0x7e, 0xa5, 0xbb, 0xea, 0x7f, 0xac, 0x47, 0x57,
+	0x0d, 0xae, 0xc0, 0x05, 0x74, 0x2b, 0xe7, 0x47, 0x0e, 0x4a, 0xc7, 0xd5, 0x0b, 0xef, 0xbd, 0x7a,
+	0xc4, 0xea, 0x72, 0x9d, 0x01, 0x94, 0xab, 0x47, 0x2a, 0x52, 0xaf, 0x9c, 0x67, 0xef, 0x60, 0xbd,
+	0xd1, 0x25, 0xfa, 0x0a, 0x5b, 0x0f, 0x15, 0x21, 0x6f, 0xaa, 0x11, 0x6b, 0x25, 0xee, 0xc5, 0x4f,
+	0xb9, 0xd8, 0xd4, 0xff, 0x07, 0x00, 0xd5, 0x43, 0x9d, 0x45, 0x52, 0x06, 0x00, 0x00,
+}
@@ -61,6 +61,22 @@ It has these top-level messages:
 	ChangeRoleNameCommand
 	ImportDataCommand
 	CreateBalancedShardGroupCommand
+	SetTopologyFrozenCommand
+	BootstrapCommand
+	ShardGroupQuota
+	SetShardGroupQuotaCommand
+	DatabaseAnnotation
+	SetDatabaseAnnotationCommand
+	RestartLock
+	AcquireRestartLockCommand
+	ReleaseRestartLockCommand
+	AlterRetentionPolicyReplicaNCommand
+	WriteBlockedDatabase
+	SetDatabaseWriteBlockedCommand
+	PurgeOrphansCommand
+	UpdateMetaNodeCommand
+	PurgeShardGroupsCommand
+	SetMetadataVersionCommand
 */
 package internal
 
@@ -82,50 +98,62 @@ const _ = proto.GoGoProtoPackageIsVersion2 // please upgrade the proto package
 type Command_Type int32
 
 const (
-	Command_CreateDatabaseCommand            Command_Type = 1
-	Command_DropDatabaseCommand              Command_Type = 2
-	Command_CreateRetentionPolicyCommand     Command_Type = 3
-	Command_DropRetentionPolicyCommand       Command_Type = 4
-	Command_SetDefaultRetentionPolicyCommand Command_Type = 5
-	Command_UpdateRetentionPolicyCommand     Command_Type = 6
-	Command_CreateShardGroupCommand          Command_Type = 7
-	Command_DeleteShardGroupCommand          Command_Type = 8
-	Command_CreateContinuousQueryCommand     Command_Type = 9
-	Command_DropContinuousQueryCommand       Command_Type = 10
-	Command_CreateUserCommand                Command_Type = 11
-	Command_DropUserCommand                  Command_Type = 12
-	Command_UpdateUserCommand                Command_Type = 13
-	Command_SetPrivilegeCommand              Command_Type = 14
-	Command_CreateRoleCommand                Command_Type = 15
-	Command_DropRoleCommand                  Command_Type = 16
-	Command_AddRoleUsersCommand              Command_Type = 17
-	Command_RemoveRoleUsersCommand           Command_Type = 18
-	Command_AddRolePermissionsCommand        Command_Type = 19
-	Command_RemoveRolePermissionsCommand     Command_Type = 20
-	Command_SetDataCommand                   Command_Type = 21
-	Command_SetAdminPrivilegeCommand         Command_Type = 22
-	Command_CreateSubscriptionCommand        Command_Type = 23
-	Command_DropSubscriptionCommand          Command_Type = 24
-	Command_RemovePeerCommand                Command_Type = 25
-	Command_CreateMetaNodeCommand            Command_Type = 26
-	Command_CreateDataNodeCommand            Command_Type = 27
-	Command_UpdateDataNodeCommand            Command_Type = 28
-	Command_DeleteMetaNodeCommand            Command_Type = 29
-	Command_DeleteDataNodeCommand            Command_Type = 30
-	Command_SetMetaNodeCommand               Command_Type = 31
-	Command_DropShardCommand                 Command_Type = 32
-	Command_ImportDataCommand                Command_Type = 33
-	Command_SetUserPasswordCommand           Command_Type = 34
-	Command_AddUserPermissionsCommand        Command_Type = 35
-	Command_RemoveUserPermissionsCommand     Command_Type = 36
-	Command_AddShardOwnerCommand             Command_Type = 37
-	Command_RemoveShardOwnerCommand          Command_Type = 38
-	Command_AddPendingShardOwnerCommand      Command_Type = 39
-	Command_CommitPendingShardOwnerCommand   Command_Type = 40
-	Command_RemovePendingShardOwnerCommand   Command_Type = 41
-	Command_TruncateShardGroupsCommand       Command_Type = 42
-	Command_ChangeRoleNameCommand            Command_Type = 43
-	Command_CreateBalancedShardGroupCommand  Command_Type = 44
+	Command_CreateDatabaseCommand               Command_Type = 1
+	Command_DropDatabaseCommand                 Command_Type = 2
+	Command_CreateRetentionPolicyCommand        Command_Type = 3
+	Command_DropRetentionPolicyCommand          Command_Type = 4
+	Command_SetDefaultRetentionPolicyCommand    Command_Type = 5
+	Command_UpdateRetentionPolicyCommand        Command_Type = 6
+	Command_CreateShardGroupCommand             Command_Type = 7
+	Command_DeleteShardGroupCommand             Command_Type = 8
+	Command_CreateContinuousQueryCommand        Command_Type = 9
+	Command_DropContinuousQueryCommand          Command_Type = 10
+	Command_CreateUserCommand                   Command_Type = 11
+	Command_DropUserCommand                     Command_Type = 12
+	Command_UpdateUserCommand                   Command_Type = 13
+	Command_SetPrivilegeCommand                 Command_Type = 14
+	Command_CreateRoleCommand                   Command_Type = 15
+	Command_DropRoleCommand                     Command_Type = 16
+	Command_AddRoleUsersCommand                 Command_Type = 17
+	Command_RemoveRoleUsersCommand              Command_Type = 18
+	Command_AddRolePermissionsCommand           Command_Type = 19
+	Command_RemoveRolePermissionsCommand        Command_Type = 20
+	Command_SetDataCommand                      Command_Type = 21
+	Command_SetAdminPrivilegeCommand            Command_Type = 22
+	Command_CreateSubscriptionCommand           Command_Type = 23
+	Command_DropSubscriptionCommand             Command_Type = 24
+	Command_RemovePeerCommand                   Command_Type = 25
+	Command_CreateMetaNodeCommand               Command_Type = 26
+	Command_CreateDataNodeCommand               Command_Type = 27
+	Command_UpdateDataNodeCommand               Command_Type = 28
+	Command_DeleteMetaNodeCommand               Command_Type = 29
+	Command_DeleteDataNodeCommand               Command_Type = 30
+	Command_SetMetaNodeCommand                  Command_Type = 31
+	Command_DropShardCommand                    Command_Type = 32
+	Command_ImportDataCommand                   Command_Type = 33
+	Command_SetUserPasswordCommand              Command_Type = 34
+	Command_AddUserPermissionsCommand           Command_Type = 35
+	Command_RemoveUserPermissionsCommand        Command_Type = 36
+	Command_AddShardOwnerCommand                Command_Type = 37
+	Command_RemoveShardOwnerCommand             Command_Type = 38
+	Command_AddPendingShardOwnerCommand         Command_Type = 39
+	Command_CommitPendingShardOwnerCommand      Command_Type = 40
+	Command_RemovePendingShardOwnerCommand      Command_Type = 41
+	Command_TruncateShardGroupsCommand          Command_Type = 42
+	Command_ChangeRoleNameCommand               Command_Type = 43
+	Command_CreateBalancedShardGroupCommand     Command_Type = 44
+	Command_SetTopologyFrozenCommand            Command_Type = 45
+	Command_BootstrapCommand                    Command_Type = 46
+	Command_SetShardGroupQuotaCommand           Command_Type = 47
+	Command_SetDatabaseAnnotationCommand        Command_Type = 48
+	Command_AcquireRestartLockCommand           Command_Type = 49
+	Command_ReleaseRestartLockCommand           Command_Type = 50
+	Command_AlterRetentionPolicyReplicaNCommand Command_Type = 51
+	Command_SetDatabaseWriteBlockedCommand      Command_Type = 52
+	Command_PurgeOrphansCommand                 Command_Type = 53
+	Command_UpdateMetaNodeCommand               Command_Type = 54
+	Command_PurgeShardGroupsCommand             Command_Type = 55
+	Command_SetMetadataVersionCommand           Command_Type = 56
 )
 
 var Command_Type_name = map[int32]string{
@@ -173,52 +201,76 @@ var Command_Type_name = map[int32]string{
 	42: "TruncateShardGroupsCommand",
 	43: "ChangeRoleNameCommand",
 	44: "CreateBalancedShardGroupCommand",
+	45: "SetTopologyFrozenCommand",
+	46: "BootstrapCommand",
+	47: "SetShardGroupQuotaCommand",
+	48: "SetDatabaseAnnotationCommand",
+	49: "AcquireRestartLockCommand",
+	50: "ReleaseRestartLockCommand",
+	51: "AlterRetentionPolicyReplicaNCommand",
+	52: "SetDatabaseWriteBlockedCommand",
+	53: "PurgeOrphansCommand",
+	54: "UpdateMetaNodeCommand",
+	55: "PurgeShardGroupsCommand",
+	56: "SetMetadataVersionCommand",
 }
 var Command_Type_value = map[string]int32{
-	"CreateDatabaseCommand":            1,
-	"DropDatabaseCommand":              2,
-	"CreateRetentionPolicyCommand":     3,
-	"DropRetentionPolicyCommand":       4,
-	"SetDefaultRetentionPolicyCommand": 5,
-	"UpdateRetentionPolicyCommand":     6,
-	"CreateShardGroupCommand":          7,
-	"DeleteShardGroupCommand":          8,
-	"CreateContinuousQueryCommand":     9,
-	"DropContinuousQueryCommand":       10,
-	"CreateUserCommand":                11,
-	"DropUserCommand":                  12,
-	"UpdateUserCommand":                13,
-	"SetPrivilegeCommand":              14,
-	"CreateRoleCommand":                15,
-	"DropRoleCommand":                  16,
-	"AddRoleUsersCommand":              17,
-	"RemoveRoleUsersCommand":           18,
-	"AddRolePermissionsCommand":        19,
-	"RemoveRolePermissionsCommand":     20,
-	"SetDataCommand":                   21,
-	"SetAdminPrivilegeCommand":         22,
-	"CreateSubscriptionCommand":        23,
-	"DropSubscriptionCommand":          24,
-	"RemovePeerCommand":                25,
-	"CreateMetaNodeCommand":            26,
-	"CreateDataNodeCommand":            27,
-	"UpdateDataNodeCommand":            28,
-	"DeleteMetaNodeCommand":            29,
-	"DeleteDataNodeCommand":            30,
-	"SetMetaNodeCommand":               31,
-	"DropShardCommand":                 32,
-	"ImportDataCommand":                33,
-	"SetUserPasswordCommand":           34,
-	"AddUserPermissionsCommand":        35,
-	"RemoveUserPermissionsCommand":     36,
-	"AddShardOwnerCommand":             37,
-	"RemoveShardOwnerCommand":          38,
-	"AddPendingShardOwnerCommand":      39,
-	"CommitPendingShardOwnerCommand":   40,
-	"RemovePendingShardOwnerCommand":   41,
-	"TruncateShardGroupsCommand":       42,
-	"ChangeRoleNameCommand":            43,
-	"CreateBalancedShardGroupCommand":  44,
+	"CreateDatabaseCommand":               1,
+	"DropDatabaseCommand":                 2,
+	"CreateRetentionPolicyCommand":        3,
+	"DropRetentionPolicyCommand":          4,
+	"SetDefaultRetentionPolicyCommand":    5,
+	"UpdateRetentionPolicyCommand":        6,
+	"CreateShardGroupCommand":             7,
+	"DeleteShardGroupCommand":             8,
+	"CreateContinuousQueryCommand":        9,
+	"DropContinuousQueryCommand":          10,
+	"CreateUserCommand":                   11,
+	"DropUserCommand":                     12,
+	"UpdateUserCommand":                   13,
+	"SetPrivilegeCommand":                 14,
+	"CreateRoleCommand":                   15,
+	"DropRoleCommand":                     16,
+	"AddRoleUsersCommand":                 17,
+	"RemoveRoleUsersCommand":              18,
+	"AddRolePermissionsCommand":           19,
+	"RemoveRolePermissionsCommand":        20,
+	"SetDataCommand":                      21,
+	"SetAdminPrivilegeCommand":            22,
+	"CreateSubscriptionCommand":           23,
+	"DropSubscriptionCommand":             24,
+	"RemovePeerCommand":                   25,
+	"CreateMetaNodeCommand":               26,
+	"CreateDataNodeCommand":               27,
+	"UpdateDataNodeCommand":               28,
+	"DeleteMetaNodeCommand":               29,
+	"DeleteDataNodeCommand":               30,
+	"SetMetaNodeCommand":                  31,
+	"DropShardCommand":                    32,
+	"ImportDataCommand":                   33,
+	"SetUserPasswordCommand":              34,
+	"AddUserPermissionsCommand":           35,
+	"RemoveUserPermissionsCommand":        36,
+	"AddShardOwnerCommand":                37,
+	"RemoveShardOwnerCommand":             38,
+	"AddPendingShardOwnerCommand":         39,
+	"CommitPendingShardOwnerCommand":      40,
+	"RemovePendingShardOwnerCommand":      41,
+	"TruncateShardGroupsCommand":          42,
+	"ChangeRoleNameCommand":               43,
+	"CreateBalancedShardGroupCommand":     44,
+	"SetTopologyFrozenCommand":            45,
+	"BootstrapCommand":                    46,
+	"SetShardGroupQuotaCommand":           47,
+	"SetDatabaseAnnotationCommand":        48,
+	"AcquireRestartLockCommand":           49,
+	"ReleaseRestartLockCommand":           50,
+	"AlterRetentionPolicyReplicaNCommand": 51,
+	"SetDatabaseWriteBlockedCommand":      52,
+	"PurgeOrphansCommand":                 53,
+	"UpdateMetaNodeCommand":               54,
+	"PurgeShardGroupsCommand":             55,
+	"SetMetadataVersionCommand":           56,
 }
 
 func (x Command_Type) Enum() *Command_Type {
@@ -240,13 +292,20 @@ func (x *Command_Type) UnmarshalJSON(data []byte) error {
 func (Command_Type) EnumDescriptor() ([]byte, []int) { return fileDescriptorMeta, []int{7, 0} }
 
 type ClusterData struct {
-	Data             []byte      `protobuf:"bytes,1,req,name=Data" json:"Data,omitempty"`
-	MaxNodeID        *uint64     `protobuf:"varint,2,req,name=MaxNodeID" json:"MaxNodeID,omitempty"`
-	DataNodes        []*NodeInfo `protobuf:"bytes,3,rep,name=DataNodes" json:"DataNodes,omitempty"`
-	MetaNodes        []*NodeInfo `protobuf:"bytes,4,rep,name=MetaNodes" json:"MetaNodes,omitempty"`
-	Roles            []*RoleInfo `protobuf:"bytes,5,rep,name=Roles" json:"Roles,omitempty"`
-	Users            []*UserInfo `protobuf:"bytes,6,rep,name=Users" json:"Users,omitempty"`
-	XXX_unrecognized []byte      `json:"-"`
+	Data                  []byte                  `protobuf:"bytes,1,req,name=Data" json:"Data,omitempty"`
+	MaxNodeID             *uint64                 `protobuf:"varint,2,req,name=MaxNodeID" json:"MaxNodeID,omitempty"`
+	DataNodes             []*NodeInfo             `protobuf:"bytes,3,rep,name=DataNodes" json:"DataNodes,omitempty"`
+	MetaNodes             []*NodeInfo             `protobuf:"bytes,4,rep,name=MetaNodes" json:"MetaNodes,omitempty"`
+	Roles                 []*RoleInfo             `protobuf:"bytes,5,rep,name=Roles" json:"Roles,omitempty"`
+	Users                 []*UserInfo             `protobuf:"bytes,6,rep,name=Users" json:"Users,omitempty"`
+	TopologyFrozen        *bool                   `protobuf:"varint,7,opt,name=TopologyFrozen" json:"TopologyFrozen,omitempty"`
+	Bootstrapped          *bool                   `protobuf:"varint,8,opt,name=Bootstrapped" json:"Bootstrapped,omitempty"`
+	ShardGroupQuotas      []*ShardGroupQuota      `protobuf:"bytes,9,rep,name=ShardGroupQuotas" json:"ShardGroupQuotas,omitempty"`
+	DatabaseAnnotations   []*DatabaseAnnotation   `protobuf:"bytes,10,rep,name=DatabaseAnnotations" json:"DatabaseAnnotations,omitempty"`
+	RestartLock           *RestartLock            `protobuf:"bytes,11,opt,name=RestartLock" json:"RestartLock,omitempty"`
+	WriteBlockedDatabases []*WriteBlockedDatabase `protobuf:"bytes,12,rep,name=WriteBlockedDatabases" json:"WriteBlockedDatabases,omitempty"`
+	MetadataVersion       *uint64                 `protobuf:"varint,13,opt,name=MetadataVersion" json:"MetadataVersion,omitempty"`
+	XXX_unrecognized      []byte                  `json:"-"`
 }
 
 func (m *ClusterData) Reset()                    { *m = ClusterData{} }
@@ -296,11 +355,61 @@ func (m *ClusterData) GetUsers() []*UserInfo {
 	return nil
 }
 
+func (m *ClusterData) GetTopologyFrozen() bool {
+	if m != nil && m.TopologyFrozen != nil {
+		return *m.TopologyFrozen
+	}
+	return false
+}
+
+func (m *ClusterData) GetBootstrapped() bool {
+	if m != nil && m.Bootstrapped != nil {
+		return *m.Bootstrapped
+	}
+	return false
+}
+
+func (m *ClusterData) GetShardGroupQuotas() []*ShardGroupQuota {
+	if m != nil {
+		return m.ShardGroupQuotas
+	}
+	return nil
+}
+
+func (m *ClusterData) GetDatabaseAnnotations() []*DatabaseAnnotation {
+	if m != nil {
+		return m.DatabaseAnnotations
+	}
+	return nil
+}
+
+func (m *ClusterData) GetRestartLock() *RestartLock {
+	if m != nil {
+		return m.RestartLock
+	}
+	return nil
+}
+
+func (m *ClusterData) GetWriteBlockedDatabases() []*WriteBlockedDatabase {
+	if m != nil {
+		return m.WriteBlockedDatabases
+	}
+	return nil
+}
+
+func (m *ClusterData) GetMetadataVersion() uint64 {
+	if m != nil && m.MetadataVersion != nil {
+		return *m.MetadataVersion
+	}
+	return 0
+}
+
 type NodeInfo struct {
 	ID                 *uint64  `protobuf:"varint,1,req,name=ID" json:"ID,omitempty"`
 	Host               *string  `protobuf:"bytes,2,req,name=Host" json:"Host,omitempty"`
 	TCPHost            *string  `protobuf:"bytes,3,opt,name=TCPHost" json:"TCPHost,omitempty"`
 	PendingShardOwners []uint64 `protobuf:"varint,4,rep,name=PendingShardOwners" json:"PendingShardOwners,omitempty"`
+	StartedAt          *int64   `protobuf:"varint,5,opt,name=StartedAt" json:"StartedAt,omitempty"`
 	XXX_unrecognized   []byte   `json:"-"`
 }
 
@@ -337,6 +446,13 @@ func (m *NodeInfo) GetPendingShardOwners() []uint64 {
 	return nil
 }
 
+func (m *NodeInfo) GetStartedAt() int64 {
+	if m != nil && m.StartedAt != nil {
+		return *m.StartedAt
+	}
+	return 0
+}
+
 type RoleInfo struct {
 	Name             *string        `protobuf:"bytes,1,req,name=Name" json:"Name,omitempty"`
 	Permissions      *UserPrivilege `protobuf:"bytes,2,req,name=Permissions" json:"Permissions,omitempty"`
@@ -515,6 +631,7 @@ func (m *Command) GetType() Command_Type {
 type CreateDatabaseCommand struct {
 	Name             *string `protobuf:"bytes,1,req,name=Name" json:"Name,omitempty"`
 	RetentionPolicy  []byte  `protobuf:"bytes,2,opt,name=RetentionPolicy" json:"RetentionPolicy,omitempty"`
+	IfNotExists      *bool   `protobuf:"varint,3,opt,name=IfNotExists" json:"IfNotExists,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
@@ -537,6 +654,13 @@ func (m *CreateDatabaseCommand) GetRetentionPolicy() []byte {
 	return nil
 }
 
+func (m *CreateDatabaseCommand) GetIfNotExists() bool {
+	if m != nil && m.IfNotExists != nil {
+		return *m.IfNotExists
+	}
+	return false
+}
+
 var E_CreateDatabaseCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
 	ExtensionType: (*CreateDatabaseCommand)(nil),
@@ -573,6 +697,7 @@ var E_DropDatabaseCommand_Command = &proto.ExtensionDesc{
 type CreateRetentionPolicyCommand struct {
 	Database         *string `protobuf:"bytes,1,req,name=Database" json:"Database,omitempty"`
 	RetentionPolicy  []byte  `protobuf:"bytes,2,req,name=RetentionPolicy" json:"RetentionPolicy,omitempty"`
+	IfNotExists      *bool   `protobuf:"varint,3,opt,name=IfNotExists" json:"IfNotExists,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
@@ -597,6 +722,13 @@ func (m *CreateRetentionPolicyCommand) GetRetentionPolicy() []byte {
 	return nil
 }
 
+func (m *CreateRetentionPolicyCommand) GetIfNotExists() bool {
+	if m != nil && m.IfNotExists != nil {
+		return *m.IfNotExists
+	}
+	return false
+}
+
 var E_CreateRetentionPolicyCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
 	ExtensionType: (*CreateRetentionPolicyCommand)(nil),
@@ -674,12 +806,13 @@ var E_SetDefaultRetentionPolicyCommand_Command = &proto.ExtensionDesc{
 }
 
 type UpdateRetentionPolicyCommand struct {
-	Database         *string `protobuf:"bytes,1,req,name=Database" json:"Database,omitempty"`
-	Name             *string `protobuf:"bytes,2,req,name=Name" json:"Name,omitempty"`
-	NewName          *string `protobuf:"bytes,3,opt,name=NewName" json:"NewName,omitempty"`
-	Duration         *int64  `protobuf:"varint,4,opt,name=Duration" json:"Duration,omitempty"`
-	ReplicaN         *uint32 `protobuf:"varint,5,opt,name=ReplicaN" json:"ReplicaN,omitempty"`
-	XXX_unrecognized []byte  `json:"-"`
+	Database           *string `protobuf:"bytes,1,req,name=Database" json:"Database,omitempty"`
+	Name               *string `protobuf:"bytes,2,req,name=Name" json:"Name,omitempty"`
+	NewName            *string `protobuf:"bytes,3,opt,name=NewName" json:"NewName,omitempty"`
+	Duration           *int64  `protobuf:"varint,4,opt,name=Duration" json:"Duration,omitempty"`
+	ReplicaN           *uint32 `protobuf:"varint,5,opt,name=ReplicaN" json:"ReplicaN,omitempty"`
+	ShardGroupDuration *int64  `protobuf:"varint,6,opt,name=ShardGroupDuration" json:"ShardGroupDuration,omitempty"`
+	XXX_unrecognized   []byte  `json:"-"`
 }
 
 func (m *UpdateRetentionPolicyCommand) Reset()         { *m = UpdateRetentionPolicyCommand{} }
@@ -724,6 +857,13 @@ func (m *UpdateRetentionPolicyCommand) GetReplicaN() uint32 {
 	return 0
 }
 
+func (m *UpdateRetentionPolicyCommand) GetShardGroupDuration() int64 {
+	if m != nil && m.ShardGroupDuration != nil {
+		return *m.ShardGroupDuration
+	}
+	return 0
+}
+
 var E_UpdateRetentionPolicyCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
 	ExtensionType: (*UpdateRetentionPolicyCommand)(nil),
@@ -777,6 +917,7 @@ type DeleteShardGroupCommand struct {
 	Database         *string `protobuf:"bytes,1,req,name=Database" json:"Database,omitempty"`
 	Policy           *string `protobuf:"bytes,2,req,name=Policy" json:"Policy,omitempty"`
 	ShardGroupID     *uint64 `protobuf:"varint,3,req,name=ShardGroupID" json:"ShardGroupID,omitempty"`
+	DeletedAt        *int64  `protobuf:"varint,4,opt,name=DeletedAt" json:"DeletedAt,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
@@ -806,6 +947,13 @@ func (m *DeleteShardGroupCommand) GetShardGroupID() uint64 {
 	return 0
 }
 
+func (m *DeleteShardGroupCommand) GetDeletedAt() int64 {
+	if m != nil && m.DeletedAt != nil {
+		return *m.DeletedAt
+	}
+	return 0
+}
+
 var E_DeleteShardGroupCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
 	ExtensionType: (*DeleteShardGroupCommand)(nil),
@@ -1327,6 +1475,8 @@ type CreateMetaNodeCommand struct {
 	HTTPAddr         *string `protobuf:"bytes,1,req,name=HTTPAddr" json:"HTTPAddr,omitempty"`
 	TCPAddr          *string `protobuf:"bytes,2,req,name=TCPAddr" json:"TCPAddr,omitempty"`
 	Rand             *uint64 `protobuf:"varint,3,req,name=Rand" json:"Rand,omitempty"`
+	StartedAt        *int64  `protobuf:"varint,4,opt,name=StartedAt" json:"StartedAt,omitempty"`
+	MetadataVersion  *uint64 `protobuf:"varint,5,opt,name=MetadataVersion" json:"MetadataVersion,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
@@ -1356,6 +1506,20 @@ func (m *CreateMetaNodeCommand) GetRand() uint64 {
 	return 0
 }
 
+func (m *CreateMetaNodeCommand) GetStartedAt() int64 {
+	if m != nil && m.StartedAt != nil {
+		return *m.StartedAt
+	}
+	return 0
+}
+
+func (m *CreateMetaNodeCommand) GetMetadataVersion() uint64 {
+	if m != nil && m.MetadataVersion != nil {
+		return *m.MetadataVersion
+	}
+	return 0
+}
+
 var E_CreateMetaNodeCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
 	ExtensionType: (*CreateMetaNodeCommand)(nil),
@@ -1367,6 +1531,7 @@ var E_CreateMetaNodeCommand_Command = &proto.ExtensionDesc{
 type CreateDataNodeCommand struct {
 	HTTPAddr         *string `protobuf:"bytes,1,req,name=HTTPAddr" json:"HTTPAddr,omitempty"`
 	TCPAddr          *string `protobuf:"bytes,2,req,name=TCPAddr" json:"TCPAddr,omitempty"`
+	StartedAt        *int64  `protobuf:"varint,3,opt,name=StartedAt" json:"StartedAt,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
@@ -1389,6 +1554,13 @@ func (m *CreateDataNodeCommand) GetTCPAddr() string {
 	return ""
 }
 
+func (m *CreateDataNodeCommand) GetStartedAt() int64 {
+	if m != nil && m.StartedAt != nil {
+		return *m.StartedAt
+	}
+	return 0
+}
+
 var E_CreateDataNodeCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
 	ExtensionType: (*CreateDataNodeCommand)(nil),
@@ -1494,6 +1666,8 @@ type SetMetaNodeCommand struct {
 	HTTPAddr         *string `protobuf:"bytes,1,req,name=HTTPAddr" json:"HTTPAddr,omitempty"`
 	TCPAddr          *string `protobuf:"bytes,2,req,name=TCPAddr" json:"TCPAddr,omitempty"`
 	Rand             *uint64 `protobuf:"varint,3,req,name=Rand" json:"Rand,omitempty"`
+	StartedAt        *int64  `protobuf:"varint,4,opt,name=StartedAt" json:"StartedAt,omitempty"`
+	MetadataVersion  *uint64 `protobuf:"varint,5,opt,name=MetadataVersion" json:"MetadataVersion,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
@@ -1523,6 +1697,20 @@ func (m *SetMetaNodeCommand) GetRand() uint64 {
 	return 0
 }
 
+func (m *SetMetaNodeCommand) GetStartedAt() int64 {
+	if m != nil && m.StartedAt != nil {
+		return *m.StartedAt
+	}
+	return 0
+}
+
+func (m *SetMetaNodeCommand) GetMetadataVersion() uint64 {
+	if m != nil && m.MetadataVersion != nil {
+		return *m.MetadataVersion
+	}
+	return 0
+}
+
 var E_SetMetaNodeCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
 	ExtensionType: (*SetMetaNodeCommand)(nil),
@@ -1750,7 +1938,7 @@ func (m *AddPendingShardOwnerCommand) GetNodeID() uint64 {
 
 var E_AddPendingShardOwnerCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
-	ExtensionType: (*SetMetaNodeCommand)(nil),
+	ExtensionType: (*AddPendingShardOwnerCommand)(nil),
 	Field:         138,
 	Name:          "internal.AddPendingShardOwnerCommand.command",
 	Tag:           "bytes,138,opt,name=command",
@@ -1785,7 +1973,7 @@ func (m *RemovePendingShardOwnerCommand) GetNodeID() uint64 {
 
 var E_RemovePendingShardOwnerCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
-	ExtensionType: (*SetMetaNodeCommand)(nil),
+	ExtensionType: (*RemovePendingShardOwnerCommand)(nil),
 	Field:         139,
 	Name:          "internal.RemovePendingShardOwnerCommand.command",
 	Tag:           "bytes,139,opt,name=command",
@@ -1820,7 +2008,7 @@ func (m *CommitPendingShardOwnerCommand) GetNodeID() uint64 {
 
 var E_CommitPendingShardOwnerCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
-	ExtensionType: (*SetMetaNodeCommand)(nil),
+	ExtensionType: (*CommitPendingShardOwnerCommand)(nil),
 	Field:         140,
 	Name:          "internal.CommitPendingShardOwnerCommand.command",
 	Tag:           "bytes,140,opt,name=command",
@@ -2008,6 +2196,516 @@ var E_CreateBalancedShardGroupCommand_Command = &proto.ExtensionDesc{
 	Tag:           "bytes,144,opt,name=command",
 }
 
+type SetTopologyFrozenCommand struct {
+	Frozen           *bool  `protobuf:"varint,1,req,name=Frozen" json:"Frozen,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *SetTopologyFrozenCommand) Reset()                    { *m = SetTopologyFrozenCommand{} }
+func (m *SetTopologyFrozenCommand) String() string            { return proto.CompactTextString(m) }
+func (*SetTopologyFrozenCommand) ProtoMessage()               {}
+func (*SetTopologyFrozenCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{52} }
+
+func (m *SetTopologyFrozenCommand) GetFrozen() bool {
+	if m != nil && m.Frozen != nil {
+		return *m.Frozen
+	}
+	return false
+}
+
+var E_SetTopologyFrozenCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*SetTopologyFrozenCommand)(nil),
+	Field:         145,
+	Name:          "internal.SetTopologyFrozenCommand.command",
+	Tag:           "bytes,145,opt,name=command",
+}
+
+type BootstrapCommand struct {
+	Commands         [][]byte `protobuf:"bytes,1,rep,name=Commands" json:"Commands,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *BootstrapCommand) Reset()                    { *m = BootstrapCommand{} }
+func (m *BootstrapCommand) String() string            { return proto.CompactTextString(m) }
+func (*BootstrapCommand) ProtoMessage()               {}
+func (*BootstrapCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{53} }
+
+func (m *BootstrapCommand) GetCommands() [][]byte {
+	if m != nil {
+		return m.Commands
+	}
+	return nil
+}
+
+var E_BootstrapCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*BootstrapCommand)(nil),
+	Field:         146,
+	Name:          "internal.BootstrapCommand.command",
+	Tag:           "bytes,146,opt,name=command",
+}
+
+type ShardGroupQuota struct {
+	Database         *string `protobuf:"bytes,1,req,name=Database" json:"Database,omitempty"`
+	MaxShardGroups   *uint64 `protobuf:"varint,2,req,name=MaxShardGroups" json:"MaxShardGroups,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *ShardGroupQuota) Reset()                    { *m = ShardGroupQuota{} }
+func (m *ShardGroupQuota) String() string            { return proto.CompactTextString(m) }
+func (*ShardGroupQuota) ProtoMessage()               {}
+func (*ShardGroupQuota) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{54} }
+
+func (m *ShardGroupQuota) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *ShardGroupQuota) GetMaxShardGroups() uint64 {
+	if m != nil && m.MaxShardGroups != nil {
+		return *m.MaxShardGroups
+	}
+	return 0
+}
+
+type SetShardGroupQuotaCommand struct {
+	Database         *string `protobuf:"bytes,1,req,name=Database" json:"Database,omitempty"`
+	MaxShardGroups   *uint64 `protobuf:"varint,2,req,name=MaxShardGroups" json:"MaxShardGroups,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *SetShardGroupQuotaCommand) Reset()                    { *m = SetShardGroupQuotaCommand{} }
+func (m *SetShardGroupQuotaCommand) String() string            { return proto.CompactTextString(m) }
+func (*SetShardGroupQuotaCommand) ProtoMessage()               {}
+func (*SetShardGroupQuotaCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{55} }
+
+func (m *SetShardGroupQuotaCommand) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *SetShardGroupQuotaCommand) GetMaxShardGroups() uint64 {
+	if m != nil && m.MaxShardGroups != nil {
+		return *m.MaxShardGroups
+	}
+	return 0
+}
+
+var E_SetShardGroupQuotaCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*SetShardGroupQuotaCommand)(nil),
+	Field:         147,
+	Name:          "internal.SetShardGroupQuotaCommand.command",
+	Tag:           "bytes,147,opt,name=command",
+}
+
+type DatabaseAnnotation struct {
+	Database         *string `protobuf:"bytes,1,req,name=Database" json:"Database,omitempty"`
+	Key              *string `protobuf:"bytes,2,req,name=Key" json:"Key,omitempty"`
+	Value            *string `protobuf:"bytes,3,req,name=Value" json:"Value,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *DatabaseAnnotation) Reset()                    { *m = DatabaseAnnotation{} }
+func (m *DatabaseAnnotation) String() string            { return proto.CompactTextString(m) }
+func (*DatabaseAnnotation) ProtoMessage()               {}
+func (*DatabaseAnnotation) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{56} }
+
+func (m *DatabaseAnnotation) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *DatabaseAnnotation) GetKey() string {
+	if m != nil && m.Key != nil {
+		return *m.Key
+	}
+	return ""
+}
+
+func (m *DatabaseAnnotation) GetValue() string {
+	if m != nil && m.Value != nil {
+		return *m.Value
+	}
+	return ""
+}
+
+type SetDatabaseAnnotationCommand struct {
+	Database         *string `protobuf:"bytes,1,req,name=Database" json:"Database,omitempty"`
+	Key              *string `protobuf:"bytes,2,req,name=Key" json:"Key,omitempty"`
+	Value            *string `protobuf:"bytes,3,req,name=Value" json:"Value,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *SetDatabaseAnnotationCommand) Reset()         { *m = SetDatabaseAnnotationCommand{} }
+func (m *SetDatabaseAnnotationCommand) String() string { return proto.CompactTextString(m) }
+func (*SetDatabaseAnnotationCommand) ProtoMessage()    {}
+func (*SetDatabaseAnnotationCommand) Descriptor() ([]byte, []int) {
+	return fileDescriptorMeta, []int{57}
+}
+
+func (m *SetDatabaseAnnotationCommand) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *SetDatabaseAnnotationCommand) GetKey() string {
+	if m != nil && m.Key != nil {
+		return *m.Key
+	}
+	return ""
+}
+
+func (m *SetDatabaseAnnotationCommand) GetValue() string {
+	if m != nil && m.Value != nil {
+		return *m.Value
+	}
+	return ""
+}
+
+var E_SetDatabaseAnnotationCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*SetDatabaseAnnotationCommand)(nil),
+	Field:         148,
+	Name:          "internal.SetDatabaseAnnotationCommand.command",
+	Tag:           "bytes,148,opt,name=command",
+}
+
+type RestartLock struct {
+	Holder           *string `protobuf:"bytes,1,req,name=Holder" json:"Holder,omitempty"`
+	NodeID           *uint64 `protobuf:"varint,2,req,name=NodeID" json:"NodeID,omitempty"`
+	Expiration       *int64  `protobuf:"varint,3,req,name=Expiration" json:"Expiration,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *RestartLock) Reset()                    { *m = RestartLock{} }
+func (m *RestartLock) String() string            { return proto.CompactTextString(m) }
+func (*RestartLock) ProtoMessage()               {}
+func (*RestartLock) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{58} }
+
+func (m *RestartLock) GetHolder() string {
+	if m != nil && m.Holder != nil {
+		return *m.Holder
+	}
+	return ""
+}
+
+func (m *RestartLock) GetNodeID() uint64 {
+	if m != nil && m.NodeID != nil {
+		return *m.NodeID
+	}
+	return 0
+}
+
+func (m *RestartLock) GetExpiration() int64 {
+	if m != nil && m.Expiration != nil {
+		return *m.Expiration
+	}
+	return 0
+}
+
+type AcquireRestartLockCommand struct {
+	Holder           *string `protobuf:"bytes,1,req,name=Holder" json:"Holder,omitempty"`
+	NodeID           *uint64 `protobuf:"varint,2,req,name=NodeID" json:"NodeID,omitempty"`
+	Time             *int64  `protobuf:"varint,3,req,name=Time" json:"Time,omitempty"`
+	TTL              *int64  `protobuf:"varint,4,req,name=TTL" json:"TTL,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *AcquireRestartLockCommand) Reset()                    { *m = AcquireRestartLockCommand{} }
+func (m *AcquireRestartLockCommand) String() string            { return proto.CompactTextString(m) }
+func (*AcquireRestartLockCommand) ProtoMessage()               {}
+func (*AcquireRestartLockCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{59} }
+
+func (m *AcquireRestartLockCommand) GetHolder() string {
+	if m != nil && m.Holder != nil {
+		return *m.Holder
+	}
+	return ""
+}
+
+func (m *AcquireRestartLockCommand) GetNodeID() uint64 {
+	if m != nil && m.NodeID != nil {
+		return *m.NodeID
+	}
+	return 0
+}
+
+func (m *AcquireRestartLockCommand) GetTime() int64 {
+	if m != nil && m.Time != nil {
+		return *m.Time
+	}
+	return 0
+}
+
+func (m *AcquireRestartLockCommand) GetTTL() int64 {
+	if m != nil && m.TTL != nil {
+		return *m.TTL
+	}
+	return 0
+}
+
+var E_AcquireRestartLockCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*AcquireRestartLockCommand)(nil),
+	Field:         149,
+	Name:          "internal.AcquireRestartLockCommand.command",
+	Tag:           "bytes,149,opt,name=command",
+}
+
+type ReleaseRestartLockCommand struct {
+	Holder           *string `protobuf:"bytes,1,req,name=Holder" json:"Holder,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *ReleaseRestartLockCommand) Reset()                    { *m = ReleaseRestartLockCommand{} }
+func (m *ReleaseRestartLockCommand) String() string            { return proto.CompactTextString(m) }
+func (*ReleaseRestartLockCommand) ProtoMessage()               {}
+func (*ReleaseRestartLockCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{60} }
+
+func (m *ReleaseRestartLockCommand) GetHolder() string {
+	if m != nil && m.Holder != nil {
+		return *m.Holder
+	}
+	return ""
+}
+
+var E_ReleaseRestartLockCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*ReleaseRestartLockCommand)(nil),
+	Field:         150,
+	Name:          "internal.ReleaseRestartLockCommand.command",
+	Tag:           "bytes,150,opt,name=command",
+}
+
+type AlterRetentionPolicyReplicaNCommand struct {
+	Database         *string `protobuf:"bytes,1,req,name=Database" json:"Database,omitempty"`
+	Name             *string `protobuf:"bytes,2,req,name=Name" json:"Name,omitempty"`
+	ReplicaN         *uint32 `protobuf:"varint,3,req,name=ReplicaN" json:"ReplicaN,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *AlterRetentionPolicyReplicaNCommand) Reset()         { *m = AlterRetentionPolicyReplicaNCommand{} }
+func (m *AlterRetentionPolicyReplicaNCommand) String() string { return proto.CompactTextString(m) }
+func (*AlterRetentionPolicyReplicaNCommand) ProtoMessage()    {}
+func (*AlterRetentionPolicyReplicaNCommand) Descriptor() ([]byte, []int) {
+	return fileDescriptorMeta, []int{61}
+}
+
+func (m *AlterRetentionPolicyReplicaNCommand) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *AlterRetentionPolicyReplicaNCommand) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *AlterRetentionPolicyReplicaNCommand) GetReplicaN() uint32 {
+	if m != nil && m.ReplicaN != nil {
+		return *m.ReplicaN
+	}
+	return 0
+}
+
+var E_AlterRetentionPolicyReplicaNCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*AlterRetentionPolicyReplicaNCommand)(nil),
+	Field:         151,
+	Name:          "internal.AlterRetentionPolicyReplicaNCommand.command",
+	Tag:           "bytes,151,opt,name=command",
+}
+
+type WriteBlockedDatabase struct {
+	Database         *string `protobuf:"bytes,1,req,name=Database" json:"Database,omitempty"`
+	Time             *int64  `protobuf:"varint,2,req,name=Time" json:"Time,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *WriteBlockedDatabase) Reset()                    { *m = WriteBlockedDatabase{} }
+func (m *WriteBlockedDatabase) String() string            { return proto.CompactTextString(m) }
+func (*WriteBlockedDatabase) ProtoMessage()               {}
+func (*WriteBlockedDatabase) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{62} }
+
+func (m *WriteBlockedDatabase) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *WriteBlockedDatabase) GetTime() int64 {
+	if m != nil && m.Time != nil {
+		return *m.Time
+	}
+	return 0
+}
+
+type SetDatabaseWriteBlockedCommand struct {
+	Database         *string `protobuf:"bytes,1,req,name=Database" json:"Database,omitempty"`
+	Blocked          *bool   `protobuf:"varint,2,req,name=Blocked" json:"Blocked,omitempty"`
+	Time             *int64  `protobuf:"varint,3,req,name=Time" json:"Time,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *SetDatabaseWriteBlockedCommand) Reset()         { *m = SetDatabaseWriteBlockedCommand{} }
+func (m *SetDatabaseWriteBlockedCommand) String() string { return proto.CompactTextString(m) }
+func (*SetDatabaseWriteBlockedCommand) ProtoMessage()    {}
+func (*SetDatabaseWriteBlockedCommand) Descriptor() ([]byte, []int) {
+	return fileDescriptorMeta, []int{63}
+}
+
+func (m *SetDatabaseWriteBlockedCommand) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *SetDatabaseWriteBlockedCommand) GetBlocked() bool {
+	if m != nil && m.Blocked != nil {
+		return *m.Blocked
+	}
+	return false
+}
+
+func (m *SetDatabaseWriteBlockedCommand) GetTime() int64 {
+	if m != nil && m.Time != nil {
+		return *m.Time
+	}
+	return 0
+}
+
+var E_SetDatabaseWriteBlockedCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*SetDatabaseWriteBlockedCommand)(nil),
+	Field:         152,
+	Name:          "internal.SetDatabaseWriteBlockedCommand.command",
+	Tag:           "bytes,152,opt,name=command",
+}
+
+type PurgeOrphansCommand struct {
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *PurgeOrphansCommand) Reset()                    { *m = PurgeOrphansCommand{} }
+func (m *PurgeOrphansCommand) String() string            { return proto.CompactTextString(m) }
+func (*PurgeOrphansCommand) ProtoMessage()               {}
+func (*PurgeOrphansCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{64} }
+
+var E_PurgeOrphansCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*PurgeOrphansCommand)(nil),
+	Field:         153,
+	Name:          "internal.PurgeOrphansCommand.command",
+	Tag:           "bytes,153,opt,name=command",
+}
+
+type UpdateMetaNodeCommand struct {
+	ID               *uint64 `protobuf:"varint,1,req,name=ID" json:"ID,omitempty"`
+	HTTPAddr         *string `protobuf:"bytes,2,req,name=HTTPAddr" json:"HTTPAddr,omitempty"`
+	TCPAddr          *string `protobuf:"bytes,3,req,name=TCPAddr" json:"TCPAddr,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *UpdateMetaNodeCommand) Reset()                    { *m = UpdateMetaNodeCommand{} }
+func (m *UpdateMetaNodeCommand) String() string            { return proto.CompactTextString(m) }
+func (*UpdateMetaNodeCommand) ProtoMessage()               {}
+func (*UpdateMetaNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{65} }
+
+func (m *UpdateMetaNodeCommand) GetID() uint64 {
+	if m != nil && m.ID != nil {
+		return *m.ID
+	}
+	return 0
+}
+
+func (m *UpdateMetaNodeCommand) GetHTTPAddr() string {
+	if m != nil && m.HTTPAddr != nil {
+		return *m.HTTPAddr
+	}
+	return ""
+}
+
+func (m *UpdateMetaNodeCommand) GetTCPAddr() string {
+	if m != nil && m.TCPAddr != nil {
+		return *m.TCPAddr
+	}
+	return ""
+}
+
+var E_UpdateMetaNodeCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*UpdateMetaNodeCommand)(nil),
+	Field:         154,
+	Name:          "internal.UpdateMetaNodeCommand.command",
+	Tag:           "bytes,154,opt,name=command",
+}
+
+type PurgeShardGroupsCommand struct {
+	Time             *int64 `protobuf:"varint,1,req,name=Time" json:"Time,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *PurgeShardGroupsCommand) Reset()                    { *m = PurgeShardGroupsCommand{} }
+func (m *PurgeShardGroupsCommand) String() string            { return proto.CompactTextString(m) }
+func (*PurgeShardGroupsCommand) ProtoMessage()               {}
+func (*PurgeShardGroupsCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{66} }
+
+func (m *PurgeShardGroupsCommand) GetTime() int64 {
+	if m != nil && m.Time != nil {
+		return *m.Time
+	}
+	return 0
+}
+
+var E_PurgeShardGroupsCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*PurgeShardGroupsCommand)(nil),
+	Field:         155,
+	Name:          "internal.PurgeShardGroupsCommand.command",
+	Tag:           "bytes,155,opt,name=command",
+}
+
+type SetMetadataVersionCommand struct {
+	Version          *uint64 `protobuf:"varint,1,req,name=Version" json:"Version,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *SetMetadataVersionCommand) Reset()                    { *m = SetMetadataVersionCommand{} }
+func (m *SetMetadataVersionCommand) String() string            { return proto.CompactTextString(m) }
+func (*SetMetadataVersionCommand) ProtoMessage()               {}
+func (*SetMetadataVersionCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{67} }
+
+func (m *SetMetadataVersionCommand) GetVersion() uint64 {
+	if m != nil && m.Version != nil {
+		return *m.Version
+	}
+	return 0
+}
+
+var E_SetMetadataVersionCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*SetMetadataVersionCommand)(nil),
+	Field:         156,
+	Name:          "internal.SetMetadataVersionCommand.command",
+	Tag:           "bytes,156,opt,name=command",
+}
+
 func init() {
 	proto.RegisterType((*ClusterData)(nil), "internal.ClusterData")
 	proto.RegisterType((*NodeInfo)(nil), "internal.NodeInfo")
@@ -2061,6 +2759,22 @@ func init() {
 	proto.RegisterType((*ChangeRoleNameCommand)(nil), "internal.ChangeRoleNameCommand")
 	proto.RegisterType((*ImportDataCommand)(nil), "internal.ImportDataCommand")
 	proto.RegisterType((*CreateBalancedShardGroupCommand)(nil), "internal.CreateBalancedShardGroupCommand")
+	proto.RegisterType((*SetTopologyFrozenCommand)(nil), "internal.SetTopologyFrozenCommand")
+	proto.RegisterType((*BootstrapCommand)(nil), "internal.BootstrapCommand")
+	proto.RegisterType((*ShardGroupQuota)(nil), "internal.ShardGroupQuota")
+	proto.RegisterType((*SetShardGroupQuotaCommand)(nil), "internal.SetShardGroupQuotaCommand")
+	proto.RegisterType((*DatabaseAnnotation)(nil), "internal.DatabaseAnnotation")
+	proto.RegisterType((*SetDatabaseAnnotationCommand)(nil), "internal.SetDatabaseAnnotationCommand")
+	proto.RegisterType((*RestartLock)(nil), "internal.RestartLock")
+	proto.RegisterType((*AcquireRestartLockCommand)(nil), "internal.AcquireRestartLockCommand")
+	proto.RegisterType((*ReleaseRestartLockCommand)(nil), "internal.ReleaseRestartLockCommand")
+	proto.RegisterType((*AlterRetentionPolicyReplicaNCommand)(nil), "internal.AlterRetentionPolicyReplicaNCommand")
+	proto.RegisterType((*WriteBlockedDatabase)(nil), "internal.WriteBlockedDatabase")
+	proto.RegisterType((*SetDatabaseWriteBlockedCommand)(nil), "internal.SetDatabaseWriteBlockedCommand")
+	proto.RegisterType((*PurgeOrphansCommand)(nil), "internal.PurgeOrphansCommand")
+	proto.RegisterType((*UpdateMetaNodeCommand)(nil), "internal.UpdateMetaNodeCommand")
+	proto.RegisterType((*PurgeShardGroupsCommand)(nil), "internal.PurgeShardGroupsCommand")
+	proto.RegisterType((*SetMetadataVersionCommand)(nil), "internal.SetMetadataVersionCommand")
 	proto.RegisterEnum("internal.Command_Type", Command_Type_name, Command_Type_value)
 	proto.RegisterExtension(E_CreateDatabaseCommand_Command)
 	proto.RegisterExtension(E_DropDatabaseCommand_Command)
@@ -2106,133 +2820,193 @@ func init() {
 	proto.RegisterExtension(E_ChangeRoleNameCommand_Command)
 	proto.RegisterExtension(E_ImportDataCommand_Command)
 	proto.RegisterExtension(E_CreateBalancedShardGroupCommand_Command)
+	proto.RegisterExtension(E_SetTopologyFrozenCommand_Command)
+	proto.RegisterExtension(E_BootstrapCommand_Command)
+	proto.RegisterExtension(E_SetShardGroupQuotaCommand_Command)
+	proto.RegisterExtension(E_SetDatabaseAnnotationCommand_Command)
+	proto.RegisterExtension(E_AcquireRestartLockCommand_Command)
+	proto.RegisterExtension(E_ReleaseRestartLockCommand_Command)
+	proto.RegisterExtension(E_AlterRetentionPolicyReplicaNCommand_Command)
+	proto.RegisterExtension(E_SetDatabaseWriteBlockedCommand_Command)
+	proto.RegisterExtension(E_PurgeOrphansCommand_Command)
+	proto.RegisterExtension(E_UpdateMetaNodeCommand_Command)
+	proto.RegisterExtension(E_PurgeShardGroupsCommand_Command)
+	proto.RegisterExtension(E_SetMetadataVersionCommand_Command)
 }
 
 func init() { proto.RegisterFile("internal/meta.proto", fileDescriptorMeta) }
 
 var fileDescriptorMeta = []byte{
-	// 1964 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x5a, 0xcd, 0x6f, 0x1b, 0xc7,
-	0x15, 0xc7, 0xf0, 0x43, 0x22, 0x47, 0xb2, 0x2c, 0x8f, 0x68, 0x69, 0xf5, 0x61, 0x89, 0xa6, 0x5d,
-	0x97, 0x55, 0x0b, 0xb5, 0xe0, 0xad, 0x47, 0x55, 0xb4, 0x6a, 0xd6, 0x95, 0x44, 0x2f, 0x65, 0x14,
-	0x3d, 0xf4, 0xb0, 0xe6, 0x8e, 0xa5, 0xad, 0xc9, 0x5d, 0x76, 0x77, 0xa9, 0x8f, 0xb6, 0x49, 0xe4,
-	0x38, 0x8e, 0x13, 0xc7, 0xce, 0x07, 0x10, 0x20, 0x87, 0x20, 0xc8, 0x21, 0x39, 0x25, 0x40, 0x72,
-	0x4b, 0x10, 0xe4, 0x10, 0x04, 0xf9, 0x77, 0x02, 0xe4, 0x14, 0xe4, 0x98, 0x60, 0x66, 0x77, 0x38,
-	0xfb, 0x31, 0x3b, 0xab, 0x75, 0x94, 0x9c, 0xc4, 0x79, 0xef, 0xcd, 0x7b, 0xbf, 0xf7, 0xe6, 0xed,
-	0xdb, 0x37, 0x6f, 0x05, 0x67, 0x0c, 0xd3, 0xc5, 0xb6, 0xa9, 0xf5, 0xfe, 0xd8, 0xc7, 0xae, 0xb6,
-	0x36, 0xb0, 0x2d, 0xd7, 0x42, 0x25, 0x46, 0xac, 0xfd, 0x00, 0xe0, 0xc4, 0x46, 0x6f, 0xe8, 0xb8,
-	0xd8, 0x6e, 0x6a, 0xae, 0x86, 0x10, 0x2c, 0x90, 0xbf, 0x0a, 0xa8, 0xe6, 0xea, 0x93, 0x2a, 0xfd,
-	0x8d, 0x96, 0x60, 0x79, 0x4b, 0x3b, 0xda, 0xb6, 0x74, 0xdc, 0x6a, 0x2a, 0xb9, 0x6a, 0xae, 0x5e,
-	0x50, 0x39, 0x01, 0xfd, 0x09, 0x96, 0x89, 0x14, 0x59, 0x39, 0x4a, 0xbe, 0x9a, 0xaf, 0x4f, 0x34,
-	0xd0, 0x1a, 0xd3, 0xbf, 0x46, 0x85, 0xcc, 0xbb, 0x96, 0xca, 0x85, 0xc8, 0x8e, 0x2d, 0xcc, 0x76,
-	0x14, 0x92, 0x77, 0x8c, 0x84, 0x50, 0x1d, 0x16, 0x55, 0xab, 0x87, 0x1d, 0xa5, 0x18, 0x95, 0x26,
-	0x64, 0x2a, 0xed, 0x09, 0x10, 0xc9, 0xdb, 0x0e, 0xb6, 0x1d, 0x65, 0x2c, 0x2a, 0x49, 0xc8, 0x9e,
-	0x24, 0x15, 0xa8, 0x1d, 0xc1, 0x12, 0x33, 0x85, 0xa6, 0x60, 0xae, 0xd5, 0xa4, 0x3e, 0x17, 0xd4,
-	0x5c, 0xab, 0x49, 0xa2, 0x70, 0xc3, 0x72, 0x5c, 0xea, 0x6c, 0x59, 0xa5, 0xbf, 0x91, 0x02, 0xc7,
-	0x77, 0x37, 0xda, 0x94, 0x9c, 0xaf, 0x82, 0x7a, 0x59, 0x65, 0x4b, 0xb4, 0x06, 0x51, 0x1b, 0x9b,
-	0xba, 0x61, 0xee, 0x75, 0xf6, 0x35, 0x5b, 0xdf, 0x39, 0x34, 0x09, 0x00, 0xe2, 0x58, 0x41, 0x15,
-	0x70, 0x6a, 0x0f, 0x00, 0x2c, 0x31, 0xdc, 0xc4, 0xd4, 0xb6, 0xd6, 0xc7, 0xd4, 0x78, 0x59, 0xa5,
-	0xbf, 0xd1, 0x9f, 0xe1, 0x44, 0x1b, 0xdb, 0x7d, 0xc3, 0x71, 0x0c, 0xcb, 0x74, 0x28, 0x8a, 0x89,
-	0xc6, 0x5c, 0xd8, 0x95, 0xb6, 0x6d, 0x1c, 0x18, 0x3d, 0xbc, 0x87, 0xd5, 0xa0, 0x2c, 0xf7, 0x3f,
-	0x4f, 0x37, 0x49, 0xfc, 0xef, 0xc3, 0x12, 0x23, 0x09, 0x41, 0x90, 0x18, 0x68, 0xce, 0xfe, 0x28,
-	0x06, 0x9a, 0xb3, 0x1f, 0x05, 0xe6, 0x9d, 0xf6, 0xa9, 0x80, 0xd5, 0x5a, 0xf0, 0x5c, 0x88, 0x8b,
-	0x16, 0x60, 0x89, 0xa4, 0xc4, 0x1d, 0xcd, 0x61, 0x76, 0x47, 0x6b, 0x92, 0x71, 0x23, 0x41, 0x0a,
-	0xa0, 0xa8, 0x72, 0x42, 0xed, 0x1e, 0x9c, 0xee, 0x74, 0xad, 0x01, 0xd6, 0xb9, 0x7e, 0xb2, 0x43,
-	0xc5, 0x8e, 0x35, 0xb4, 0xbb, 0xd8, 0xf1, 0x93, 0x97, 0x13, 0x7e, 0x46, 0x40, 0x6b, 0x9b, 0xb0,
-	0xa4, 0x62, 0x67, 0x60, 0x99, 0x0e, 0x26, 0x69, 0xb2, 0x73, 0x93, 0x6a, 0x2f, 0xa9, 0xb9, 0x9d,
-	0x9b, 0xa8, 0x02, 0x8b, 0xd7, 0x6d, 0xdb, 0xb2, 0x95, 0x1c, 0x4d, 0x08, 0x6f, 0x41, 0xa8, 0x2d,
-	0x53, 0xc7, 0x47, 0x34, 0x4d, 0x0a, 0xaa, 0xb7, 0xa8, 0x7d, 0x02, 0xe1, 0xf8, 0x86, 0xd5, 0xef,
-	0x6b, 0xa6, 0x8e, 0x56, 0x61, 0xc1, 0x3d, 0x1e, 0x78, 0x6e, 0x4f, 0x35, 0x66, 0x39, 0x0e, 0x5f,
-	0x60, 0x6d, 0xf7, 0x78, 0x80, 0x55, 0x2a, 0x53, 0xfb, 0xb6, 0x0c, 0x0b, 0x64, 0x89, 0xe6, 0xe1,
-	0xc5, 0x0d, 0x1b, 0x6b, 0x2e, 0x66, 0x51, 0xf2, 0x85, 0xa7, 0x01, 0x9a, 0x83, 0x33, 0x4d, 0xdb,
-	0x1a, 0x44, 0x19, 0x39, 0x54, 0x85, 0x4b, 0xde, 0x1e, 0x15, 0xbb, 0xd8, 0x74, 0x0d, 0xcb, 0x6c,
-	0x5b, 0x3d, 0xa3, 0x7b, 0xcc, 0x24, 0xf2, 0x68, 0x19, 0x2e, 0x90, 0xad, 0x09, 0xfc, 0x02, 0xba,
-	0x0a, 0xab, 0x1d, 0xec, 0x36, 0xf1, 0x5d, 0x6d, 0xd8, 0x73, 0x13, 0xa4, 0x8a, 0xc4, 0xce, 0xed,
-	0x81, 0x9e, 0x6c, 0x67, 0x0c, 0x2d, 0xc2, 0x39, 0x0f, 0x09, 0x7d, 0x10, 0xfe, 0x6a, 0x5b, 0xc3,
-	0x01, 0x63, 0x8e, 0x13, 0x66, 0x13, 0xf7, 0xb0, 0x88, 0x59, 0xe2, 0x3e, 0x6c, 0x58, 0xa6, 0x6b,
-	0x98, 0x43, 0x6b, 0xe8, 0xdc, 0x1a, 0x62, 0x7b, 0xa4, 0xbb, 0xcc, 0x7c, 0x48, 0xe0, 0x43, 0x74,
-	0x11, 0x5e, 0xf0, 0x34, 0x90, 0x63, 0x66, 0xe4, 0x09, 0x34, 0x03, 0xcf, 0x93, 0x6d, 0x41, 0xe2,
-	0x24, 0x91, 0xf5, 0x3c, 0x09, 0x92, 0xcf, 0x91, 0x08, 0x77, 0xb0, 0x3b, 0x4a, 0x11, 0xc6, 0x98,
-	0xe2, 0xba, 0xc9, 0x03, 0xcd, 0xc8, 0xe7, 0x99, 0xee, 0x20, 0x71, 0x9a, 0x28, 0x59, 0xd7, 0x75,
-	0x42, 0xa3, 0x4f, 0x20, 0x63, 0x5c, 0x40, 0x0b, 0x70, 0x56, 0xc5, 0x7d, 0xeb, 0x00, 0xc7, 0x78,
-	0x08, 0x5d, 0x82, 0xf3, 0xfe, 0xa6, 0x40, 0x56, 0x32, 0xf6, 0x0c, 0x89, 0x0e, 0xdf, 0x2a, 0x90,
-	0xa8, 0x20, 0x04, 0xa7, 0xc8, 0x09, 0x6a, 0xae, 0xc6, 0x68, 0x17, 0xd1, 0x12, 0x54, 0x3a, 0xd8,
-	0x5d, 0xd7, 0xfb, 0x86, 0x19, 0xf3, 0x69, 0x96, 0x98, 0xf4, 0xcf, 0x6a, 0x78, 0xc7, 0xe9, 0xda,
-	0xc6, 0x80, 0x1c, 0x28, 0x63, 0xcf, 0xd1, 0xd3, 0xb2, 0xad, 0x81, 0x88, 0xa9, 0x90, 0x78, 0x78,
-	0x78, 0xda, 0x98, 0xc7, 0x6f, 0x9e, 0x27, 0x2f, 0xab, 0xe9, 0x8c, 0xb5, 0x10, 0xce, 0xeb, 0x20,
-	0x6b, 0x91, 0xb0, 0xbc, 0xc3, 0x88, 0xb2, 0x96, 0x08, 0xcb, 0x4b, 0x99, 0xa8, 0xc2, 0x4b, 0x9c,
-	0x15, 0xdd, 0xb5, 0x8c, 0x66, 0x21, 0xea, 0x60, 0x37, 0xba, 0x65, 0x05, 0x55, 0xe0, 0x34, 0x75,
-	0x89, 0xa4, 0x1f, 0xa3, 0x56, 0x89, 0x2f, 0xad, 0xfe, 0xc0, 0xb2, 0x43, 0xc1, 0xbb, 0x4c, 0x4e,
-	0xab, 0x83, 0x5d, 0x5a, 0x32, 0x34, 0xc7, 0x39, 0xb4, 0xf8, 0x96, 0x9a, 0x7f, 0x5a, 0x94, 0x17,
-	0x3f, 0x8b, 0x2b, 0xfc, 0xb4, 0x12, 0x24, 0xae, 0x22, 0x05, 0x56, 0xd6, 0x75, 0x9d, 0xbf, 0x2d,
-	0x18, 0xe7, 0x37, 0x24, 0xec, 0xde, 0xde, 0x38, 0xf3, 0x1a, 0x5a, 0x81, 0x8b, 0xeb, 0xba, 0x1e,
-	0x7b, 0xd7, 0x30, 0x81, 0xdf, 0xa2, 0x1a, 0x5c, 0x26, 0x0b, 0xc3, 0x4d, 0x94, 0xa9, 0x13, 0x19,
-	0x76, 0x76, 0x09, 0x32, 0xbf, 0x23, 0xcf, 0xda, 0xae, 0x3d, 0x34, 0xbb, 0xa1, 0x27, 0x79, 0x84,
-	0x7f, 0x95, 0x9e, 0xe6, 0xbe, 0x66, 0xee, 0xd1, 0x7c, 0x24, 0xef, 0x11, 0xc6, 0xfa, 0x3d, 0xba,
-	0x02, 0x57, 0xbc, 0x83, 0xfe, 0x8b, 0xd6, 0xd3, 0xcc, 0x2e, 0xd6, 0xe3, 0x4f, 0xfb, 0x1f, 0x56,
-	0x4b, 0x25, 0x7d, 0xfa, 0xe4, 0xe4, 0xe4, 0x24, 0x57, 0xfb, 0x00, 0x24, 0x14, 0x3c, 0xe1, 0xdb,
-	0xaa, 0x0e, 0xcf, 0x47, 0x6a, 0x0f, 0x2d, 0xca, 0x93, 0x6a, 0x94, 0xdc, 0xf8, 0x3b, 0x1c, 0xef,
-	0xfa, 0x8a, 0x2e, 0xc4, 0x2a, 0xaf, 0x82, 0xab, 0xa0, 0x3e, 0xd1, 0x58, 0x09, 0x30, 0x44, 0x10,
-	0x54, 0xa6, 0xa2, 0x36, 0x14, 0x96, 0x5e, 0x11, 0xc4, 0xc6, 0xdf, 0xa4, 0x86, 0xef, 0x52, 0xc3,
-	0x97, 0x38, 0x43, 0xa0, 0x96, 0x9b, 0xfd, 0x02, 0xc8, 0x2b, 0xbb, 0xf4, 0xed, 0x2a, 0x8c, 0x55,
-	0x4e, 0x14, 0xab, 0x8e, 0x14, 0xf2, 0x1e, 0x85, 0x7c, 0x2d, 0x1a, 0x2b, 0x31, 0x22, 0x8e, 0xfd,
-	0x7d, 0x20, 0x7b, 0xe7, 0x48, 0x91, 0xb3, 0xb0, 0xe6, 0x02, 0x61, 0xbd, 0x25, 0xc5, 0xb8, 0x4f,
-	0x31, 0x5e, 0x0d, 0x87, 0x35, 0x0d, 0xe1, 0xc7, 0x20, 0xfd, 0xad, 0x97, 0x19, 0xe7, 0x3f, 0xa4,
-	0x38, 0x0d, 0x8a, 0x73, 0x95, 0x33, 0xd2, 0xec, 0x73, 0xb4, 0xdf, 0x03, 0xf9, 0xdb, 0x37, 0x2b,
-	0x52, 0xd2, 0xe9, 0x6e, 0xe3, 0x43, 0x4a, 0xf6, 0x3b, 0x5d, 0x7f, 0x49, 0x35, 0x0d, 0x6d, 0x8d,
-	0x98, 0x50, 0x0a, 0x55, 0x50, 0xcf, 0xab, 0xa3, 0x35, 0xe1, 0xa9, 0x78, 0xd0, 0x33, 0xba, 0xda,
-	0xb6, 0x52, 0xac, 0x82, 0xfa, 0x39, 0x75, 0xb4, 0x4e, 0xc9, 0xa3, 0x7f, 0x47, 0xf3, 0x48, 0xe6,
-	0x0d, 0xf7, 0xfb, 0x4b, 0x90, 0xd8, 0x53, 0x48, 0x5d, 0x9e, 0x85, 0x63, 0x81, 0xac, 0x2f, 0xab,
-	0xfe, 0x8a, 0xb4, 0x90, 0xbb, 0x46, 0x1f, 0x3b, 0xae, 0xd6, 0x1f, 0xd0, 0xf6, 0x39, 0xaf, 0x72,
-	0x42, 0x63, 0x5b, 0xea, 0xc2, 0x3d, 0xea, 0xc2, 0xe5, 0xe8, 0xa3, 0x10, 0x03, 0xc6, 0xd1, 0x7f,
-	0x0d, 0x12, 0x9b, 0x9e, 0x67, 0x42, 0x5f, 0x83, 0x93, 0x5c, 0x51, 0xab, 0x49, 0x1d, 0x28, 0xa8,
-	0x21, 0x5a, 0x8a, 0x0f, 0xbd, 0xa8, 0x0f, 0x09, 0xf0, 0x44, 0x55, 0x48, 0xdc, 0x7b, 0x65, 0xce,
-	0xbc, 0x0a, 0x2c, 0xd2, 0xfd, 0x14, 0x7d, 0x59, 0xf5, 0x16, 0x29, 0xd9, 0xd3, 0x17, 0x57, 0x21,
-	0x31, 0xa2, 0x78, 0x15, 0x3a, 0x1b, 0xe4, 0x29, 0x55, 0xc8, 0x14, 0x55, 0xa1, 0x34, 0x84, 0xef,
-	0x02, 0x41, 0xdf, 0x7a, 0xea, 0xab, 0x5a, 0x05, 0x16, 0x69, 0x7f, 0x47, 0x43, 0x59, 0x52, 0xbd,
-	0x45, 0xe3, 0x86, 0x14, 0xa6, 0x45, 0x61, 0x2e, 0x46, 0x43, 0x19, 0x30, 0xcf, 0xd1, 0xf5, 0x63,
-	0xdd, 0xb3, 0xf0, 0xa5, 0xb7, 0x29, 0x35, 0x38, 0xa0, 0x06, 0xe7, 0xc3, 0x71, 0x11, 0x9a, 0x7b,
-	0x08, 0x04, 0x8d, 0xf9, 0x69, 0x83, 0x91, 0xe2, 0xf6, 0x7f, 0xa2, 0x6e, 0xc7, 0x0c, 0x71, 0x1c,
-	0x9f, 0x03, 0xe1, 0x4d, 0x80, 0xe4, 0x0b, 0x91, 0x37, 0x39, 0x9a, 0xd1, 0x3a, 0x94, 0x4b, 0x39,
-	0xd9, 0x4d, 0x37, 0x1f, 0xb9, 0xe9, 0xa6, 0xb4, 0x0c, 0x76, 0xb4, 0x65, 0x10, 0x00, 0xe3, 0xc8,
-	0xff, 0x25, 0xb8, 0xa9, 0xa4, 0x04, 0xc6, 0x11, 0xe7, 0x43, 0x40, 0x01, 0x57, 0xff, 0xcf, 0xd8,
-	0x8d, 0x27, 0xe5, 0xec, 0x5d, 0xd1, 0xd9, 0x0b, 0x55, 0x6b, 0xc2, 0x7b, 0x53, 0x4a, 0x70, 0x86,
-	0xd1, 0xe0, 0x08, 0x54, 0x70, 0x13, 0x7b, 0x49, 0x37, 0xb0, 0xc6, 0x96, 0xd4, 0xca, 0x01, 0xb5,
-	0x52, 0x0d, 0xcc, 0xa3, 0x84, 0x5a, 0x82, 0x8f, 0x4d, 0xf2, 0x75, 0xae, 0xd1, 0x96, 0xda, 0x3a,
-	0xa4, 0xb6, 0xae, 0xc4, 0x3c, 0x8a, 0x2b, 0xe2, 0xe6, 0x1c, 0xf9, 0xf5, 0x30, 0xa5, 0xb4, 0x1e,
-	0x45, 0x4b, 0xab, 0x4c, 0x17, 0x37, 0x7a, 0x2f, 0x7a, 0xe3, 0x14, 0x4d, 0x15, 0x1b, 0xd7, 0xa5,
-	0xa6, 0x8f, 0xa9, 0x69, 0x25, 0xdc, 0x0f, 0x71, 0x8d, 0xdc, 0xd8, 0x7b, 0x20, 0xf9, 0x2e, 0x2b,
-	0x7d, 0x2a, 0x47, 0x05, 0x32, 0x17, 0x2c, 0x90, 0x3b, 0x52, 0x54, 0xff, 0xa5, 0xa8, 0x6a, 0x21,
-	0x54, 0x42, 0xcb, 0x1c, 0xdf, 0x8f, 0x40, 0x72, 0x9b, 0x16, 0x16, 0x30, 0x59, 0xb9, 0x10, 0xb4,
-	0xee, 0xde, 0xab, 0x32, 0x4a, 0x26, 0x9a, 0xb7, 0x2c, 0x1d, 0x2b, 0x05, 0x4f, 0x33, 0xf9, 0x4d,
-	0x7a, 0x84, 0x26, 0x76, 0x5c, 0xc3, 0xa4, 0x1d, 0x9b, 0x37, 0x4d, 0x2d, 0xab, 0x21, 0x5a, 0x4a,
-	0x0e, 0xfe, 0x2f, 0x9a, 0x83, 0x89, 0xae, 0xf1, 0x08, 0x7c, 0x03, 0x12, 0x07, 0x06, 0xbf, 0x9c,
-	0xff, 0x29, 0xbd, 0xce, 0xff, 0x63, 0xbd, 0x8e, 0x18, 0x20, 0xf7, 0xe2, 0x3e, 0x10, 0x4c, 0x36,
-	0x46, 0x83, 0x63, 0xc0, 0x07, 0xc7, 0xeb, 0xba, 0x6e, 0xb3, 0x97, 0x0f, 0xf9, 0x9d, 0x52, 0x63,
-	0x9f, 0x8b, 0xd6, 0xd8, 0x98, 0x11, 0x8e, 0xe1, 0x53, 0x90, 0x30, 0x46, 0x21, 0x31, 0xbb, 0xb1,
-	0xbb, 0xdb, 0xa6, 0xb6, 0xfd, 0x44, 0x67, 0x6b, 0x7f, 0x70, 0x1d, 0x80, 0xc5, 0x96, 0x04, 0xad,
-	0x4a, 0x30, 0x78, 0xbd, 0x22, 0xfd, 0x9d, 0x72, 0x3d, 0x7e, 0x5e, 0x7c, 0x3d, 0x8e, 0xc0, 0x09,
-	0xf5, 0x30, 0xe2, 0xe9, 0xce, 0xb3, 0x21, 0x4e, 0x41, 0xf7, 0x42, 0xf2, 0xe5, 0x5d, 0x88, 0xee,
-	0x43, 0x90, 0x30, 0x60, 0xca, 0xfe, 0x41, 0x20, 0x17, 0xf8, 0x20, 0x90, 0xf2, 0xce, 0x38, 0x01,
-	0x51, 0x98, 0x42, 0x0c, 0x1c, 0xe6, 0x41, 0xc2, 0xac, 0x2b, 0x8a, 0x32, 0xc5, 0xee, 0xfd, 0x98,
-	0x5d, 0xa1, 0x56, 0x81, 0xdd, 0x94, 0xe8, 0xa4, 0xd8, 0x7d, 0x31, 0xc1, 0x6e, 0xa2, 0xbf, 0x1f,
-	0x01, 0xd1, 0x98, 0xee, 0x0c, 0x73, 0x5c, 0xde, 0x39, 0x3c, 0xf0, 0xf0, 0x2e, 0x85, 0xaa, 0x7c,
-	0x62, 0x90, 0xcc, 0xf8, 0xe8, 0x30, 0x16, 0x1f, 0xb9, 0xbd, 0x97, 0x32, 0xd9, 0x7b, 0x02, 0x92,
-	0xc6, 0x8f, 0xa7, 0xee, 0x86, 0xe5, 0x70, 0x1e, 0x66, 0x82, 0xf3, 0x19, 0x90, 0x4c, 0x3c, 0xcf,
-	0xf8, 0xe3, 0x56, 0x0a, 0xf0, 0x97, 0x33, 0x01, 0x27, 0x77, 0x57, 0xd9, 0x2c, 0xf6, 0xd7, 0xc5,
-	0xfe, 0x28, 0x13, 0xf6, 0xc7, 0x40, 0x3c, 0x25, 0x8e, 0x95, 0xad, 0x59, 0x38, 0x16, 0xfa, 0x6c,
-	0xeb, 0xaf, 0x52, 0xc0, 0xbc, 0x92, 0x09, 0xcc, 0x53, 0x90, 0x38, 0x98, 0x3e, 0x23, 0x3c, 0xaf,
-	0x66, 0xc2, 0xf3, 0x16, 0x90, 0xce, 0xc2, 0xcf, 0x08, 0xd3, 0xe3, 0x4c, 0x98, 0xde, 0x06, 0x69,
-	0xa3, 0xf5, 0x33, 0x82, 0xf5, 0x5a, 0x66, 0x58, 0xf2, 0xaf, 0x02, 0x67, 0x04, 0xeb, 0x49, 0x26,
-	0x58, 0x8f, 0x00, 0x9c, 0x8f, 0x7f, 0x64, 0x60, 0x88, 0x96, 0x21, 0x64, 0xcc, 0x75, 0xd7, 0x47,
-	0x16, 0xa0, 0xa4, 0x20, 0x79, 0x9a, 0x09, 0xc9, 0x3b, 0x20, 0xe1, 0x73, 0x06, 0x79, 0xe1, 0xec,
-	0xf4, 0xf4, 0x40, 0x81, 0x60, 0xcb, 0xe0, 0xf4, 0xd4, 0x7f, 0x15, 0xf9, 0xcb, 0x14, 0x64, 0xaf,
-	0x67, 0x42, 0xf6, 0x5d, 0x4e, 0xf0, 0x71, 0x4a, 0xf8, 0xdf, 0x1b, 0x15, 0x58, 0xdc, 0xb4, 0xec,
-	0x2e, 0x66, 0xf7, 0x1c, 0xba, 0x08, 0x35, 0xd9, 0xf9, 0xf4, 0x26, 0xbb, 0x20, 0xbe, 0x64, 0x28,
-	0x70, 0x9c, 0x1e, 0x50, 0x4b, 0x57, 0x8a, 0xf4, 0x20, 0xd8, 0x12, 0x55, 0xe1, 0xc4, 0x36, 0x3e,
-	0x1c, 0x99, 0x18, 0xa3, 0xfb, 0x83, 0x24, 0xb4, 0x06, 0xd1, 0x36, 0x3e, 0x8c, 0x1a, 0x1a, 0xa7,
-	0xc8, 0x05, 0x1c, 0xd4, 0x80, 0x15, 0x4a, 0xa5, 0x23, 0x65, 0x42, 0xdf, 0xd4, 0xba, 0xae, 0x65,
-	0x2b, 0x25, 0x6a, 0x58, 0xc8, 0x4b, 0x89, 0xf8, 0x1b, 0x99, 0x22, 0xfe, 0x15, 0x48, 0xfd, 0x7e,
-	0x95, 0x61, 0x70, 0x3b, 0x79, 0xca, 0xb1, 0xb3, 0xdc, 0x83, 0x37, 0xb3, 0x78, 0xf0, 0x53, 0x00,
-	0x00, 0x00, 0xff, 0xff, 0x6b, 0xb4, 0x5d, 0x43, 0x0b, 0x24, 0x00, 0x00,
+	// 2721 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xcc, 0x5a, 0x4b, 0x77, 0x1c, 0x47,
+	0x15, 0x3e, 0x35, 0x0f, 0x69, 0x54, 0x92, 0x65, 0xb9, 0x2c, 0xcb, 0x6d, 0x5b, 0xb6, 0x27, 0x63,
+	0xc7, 0x19, 0x0c, 0x51, 0xc2, 0xf0, 0x08, 0x2c, 0x65, 0xcb, 0xc2, 0xc2, 0xb6, 0x24, 0xf7, 0x8c,
+	0x1d, 0x38, 0x1c, 0x16, 0xed, 0xe9, 0xb2, 0xd4, 0x78, 0xa6, 0x7b, 0xd2, 0xdd, 0x63, 0xc9, 0x01,
+	0x82, 0x42, 0x9e, 0x84, 0x84, 0x57, 0x20, 0x81, 0x84, 0xd7, 0x02, 0x56, 0xb0, 0x62, 0xc1, 0xe3,
+	0xb0, 0xe0, 0xf0, 0xda, 0xf2, 0x07, 0xe0, 0x3f, 0x70, 0x0e, 0x1b, 0x96, 0x70, 0x6e, 0x75, 0xd7,
+	0x54, 0x77, 0x75, 0x75, 0xb5, 0xc6, 0x11, 0x9c, 0xac, 0x66, 0xea, 0xde, 0x5b, 0xf7, 0x7e, 0xf7,
+	0xd6, 0xad, 0xd7, 0xad, 0xc6, 0x47, 0x1d, 0x37, 0xa4, 0xbe, 0x6b, 0xf5, 0x9e, 0xe8, 0xd3, 0xd0,
+	0x5a, 0x1a, 0xf8, 0x5e, 0xe8, 0x91, 0x1a, 0x27, 0x36, 0xde, 0xac, 0xe2, 0xe9, 0xcb, 0xbd, 0x61,
+	0x10, 0x52, 0x7f, 0xc5, 0x0a, 0x2d, 0x42, 0x70, 0x05, 0x7e, 0x0d, 0x54, 0x2f, 0x35, 0x67, 0x4c,
+	0xf6, 0x9f, 0x2c, 0xe2, 0xa9, 0x1b, 0xd6, 0xee, 0xba, 0x67, 0xd3, 0xb5, 0x15, 0xa3, 0x54, 0x2f,
+	0x35, 0x2b, 0xa6, 0x20, 0x90, 0x27, 0xf1, 0x14, 0x48, 0x41, 0x2b, 0x30, 0xca, 0xf5, 0x72, 0x73,
+	0xba, 0x45, 0x96, 0xb8, 0xfe, 0x25, 0x26, 0xe4, 0xde, 0xf5, 0x4c, 0x21, 0x04, 0x3d, 0x6e, 0x50,
+	0xde, 0xa3, 0x92, 0xdf, 0x63, 0x24, 0x44, 0x9a, 0xb8, 0x6a, 0x7a, 0x3d, 0x1a, 0x18, 0x55, 0x59,
+	0x1a, 0xc8, 0x4c, 0x3a, 0x12, 0x00, 0xc9, 0x5b, 0x01, 0xf5, 0x03, 0x63, 0x42, 0x96, 0x04, 0x72,
+	0x24, 0xc9, 0x04, 0xc8, 0x05, 0x3c, 0xdb, 0xf1, 0x06, 0x5e, 0xcf, 0xdb, 0x7a, 0xb0, 0xea, 0x7b,
+	0xcf, 0x52, 0xd7, 0x98, 0xac, 0xa3, 0x66, 0xcd, 0x94, 0xa8, 0xa4, 0x81, 0x67, 0x2e, 0x79, 0x5e,
+	0x18, 0x84, 0xbe, 0x35, 0x18, 0x50, 0xdb, 0xa8, 0x31, 0xa9, 0x14, 0x8d, 0x5c, 0xc1, 0x73, 0xed,
+	0x6d, 0xcb, 0xb7, 0x3f, 0xe5, 0x7b, 0xc3, 0xc1, 0xcd, 0xa1, 0x17, 0x5a, 0x81, 0x31, 0xc5, 0x00,
+	0x9c, 0x10, 0x00, 0x24, 0x09, 0x33, 0xd3, 0x85, 0xac, 0xe3, 0xa3, 0x10, 0xa5, 0x3b, 0x56, 0x40,
+	0x97, 0x5d, 0xd7, 0x0b, 0xad, 0xd0, 0xf1, 0xdc, 0xc0, 0xc0, 0x4c, 0xd3, 0xa2, 0xd0, 0x94, 0x15,
+	0x32, 0x55, 0x1d, 0xc9, 0x53, 0x78, 0xda, 0xa4, 0x41, 0x68, 0xf9, 0xe1, 0x75, 0xaf, 0x7b, 0xcf,
+	0x98, 0xae, 0xa3, 0xe6, 0x74, 0xeb, 0x58, 0x22, 0x78, 0x82, 0x69, 0x26, 0x25, 0x49, 0x07, 0x1f,
+	0x7b, 0xda, 0x77, 0x42, 0x7a, 0xa9, 0xe7, 0x75, 0xef, 0x51, 0x9b, 0xeb, 0x0e, 0x8c, 0x19, 0x06,
+	0xe5, 0x8c, 0x50, 0xa1, 0x12, 0x33, 0xd5, 0x9d, 0x49, 0x13, 0x1f, 0x86, 0x21, 0xb5, 0xad, 0xd0,
+	0xba, 0x4d, 0xfd, 0xc0, 0xf1, 0x5c, 0xe3, 0x50, 0x1d, 0x35, 0x2b, 0xa6, 0x4c, 0x6e, 0xbc, 0x85,
+	0x70, 0x8d, 0xe7, 0x01, 0x99, 0xc5, 0xa5, 0xb5, 0x15, 0x96, 0x90, 0x15, 0xb3, 0xb4, 0xb6, 0x02,
+	0x29, 0x7a, 0xd5, 0x0b, 0x42, 0x96, 0x89, 0x53, 0x26, 0xfb, 0x4f, 0x0c, 0x3c, 0xd9, 0xb9, 0xbc,
+	0xc9, 0xc8, 0xe5, 0x3a, 0x6a, 0x4e, 0x99, 0xbc, 0x49, 0x96, 0x30, 0xd9, 0xa4, 0xae, 0xed, 0xb8,
+	0x5b, 0x2c, 0xdc, 0x1b, 0x3b, 0x2e, 0xf5, 0xa3, 0xac, 0xab, 0x98, 0x0a, 0x0e, 0x24, 0x7b, 0x1b,
+	0xe2, 0x40, 0xed, 0xe5, 0xd0, 0xa8, 0xd6, 0x51, 0xb3, 0x6c, 0x0a, 0x42, 0xe3, 0x05, 0x84, 0x6b,
+	0x3c, 0xe5, 0x00, 0xc8, 0xba, 0xd5, 0xa7, 0x0c, 0xda, 0x94, 0xc9, 0xfe, 0x93, 0x4f, 0xe2, 0xe9,
+	0x4d, 0xea, 0xf7, 0x9d, 0x20, 0x60, 0x43, 0x07, 0x18, 0xa7, 0x5b, 0xc7, 0xd3, 0x59, 0xb8, 0xe9,
+	0x3b, 0xf7, 0x9d, 0x1e, 0xdd, 0xa2, 0x66, 0x52, 0x56, 0xa4, 0x6e, 0xb9, 0x5e, 0xd2, 0xa6, 0x6e,
+	0xa3, 0x8f, 0x6b, 0x9c, 0xa4, 0x04, 0x01, 0x11, 0xb2, 0x82, 0xed, 0x51, 0x84, 0xac, 0x60, 0x5b,
+	0x06, 0x16, 0x4d, 0xd4, 0x7d, 0x01, 0x6b, 0xac, 0xe1, 0x43, 0x29, 0x2e, 0x39, 0x89, 0x6b, 0x7c,
+	0x54, 0x63, 0xbb, 0xa3, 0x36, 0xc4, 0x6f, 0x24, 0xc8, 0x00, 0x54, 0x4d, 0x41, 0x68, 0xdc, 0xc3,
+	0x73, 0xed, 0xae, 0x37, 0xa0, 0xb6, 0xd0, 0x0f, 0x3d, 0x4c, 0x1a, 0x78, 0x43, 0xbf, 0x4b, 0x83,
+	0x78, 0xdd, 0x11, 0x84, 0xf7, 0x10, 0xd0, 0xc6, 0x2a, 0xae, 0x99, 0x34, 0x18, 0x78, 0x6e, 0x40,
+	0x21, 0x89, 0x36, 0xae, 0x31, 0xed, 0x35, 0xb3, 0xb4, 0x71, 0x8d, 0xcc, 0xe3, 0xea, 0x15, 0xdf,
+	0xf7, 0x7c, 0xa3, 0xc4, 0xd2, 0x25, 0x6a, 0x00, 0x75, 0xcd, 0xb5, 0xe9, 0x2e, 0x4b, 0xa2, 0x8a,
+	0x19, 0x35, 0x1a, 0x3f, 0x3e, 0x84, 0x27, 0x2f, 0x7b, 0xfd, 0xbe, 0xe5, 0xda, 0xe4, 0x22, 0xae,
+	0x84, 0x0f, 0x06, 0x91, 0xdb, 0xb3, 0xad, 0x05, 0x81, 0x23, 0x16, 0x58, 0xea, 0x3c, 0x18, 0x50,
+	0x93, 0xc9, 0x34, 0xfe, 0x31, 0x83, 0x2b, 0xd0, 0x24, 0x27, 0xf0, 0xb1, 0xcb, 0x3e, 0xb5, 0x42,
+	0xca, 0xa3, 0x14, 0x0b, 0xcf, 0x21, 0x72, 0x1c, 0x1f, 0x5d, 0xf1, 0xbd, 0x81, 0xcc, 0x28, 0x91,
+	0x3a, 0x5e, 0x8c, 0xfa, 0x98, 0x34, 0xa4, 0x2e, 0xcc, 0xe7, 0x4d, 0xaf, 0xe7, 0x74, 0x1f, 0x70,
+	0x89, 0x32, 0x39, 0x83, 0x4f, 0x42, 0xd7, 0x1c, 0x7e, 0x85, 0x9c, 0xc7, 0xf5, 0x36, 0x0d, 0x57,
+	0xe8, 0x5d, 0x6b, 0xd8, 0x0b, 0x73, 0xa4, 0xaa, 0x60, 0xe7, 0xd6, 0xc0, 0xce, 0xb7, 0x33, 0x41,
+	0x4e, 0xe1, 0xe3, 0x11, 0x12, 0xb1, 0x5e, 0x71, 0xe6, 0x24, 0x30, 0x57, 0x68, 0x8f, 0xaa, 0x98,
+	0x35, 0xe1, 0xc3, 0x65, 0xcf, 0x0d, 0x1d, 0x77, 0xe8, 0x0d, 0x83, 0x9b, 0x43, 0xea, 0x8f, 0x74,
+	0x4f, 0x71, 0x1f, 0x72, 0xf8, 0x98, 0x1c, 0xc3, 0x47, 0x22, 0x0d, 0x30, 0xcc, 0x9c, 0x3c, 0x4d,
+	0x8e, 0xe2, 0xc3, 0xd0, 0x2d, 0x49, 0x9c, 0x01, 0xd9, 0xc8, 0x93, 0x24, 0xf9, 0x10, 0x44, 0xb8,
+	0x4d, 0xc3, 0x51, 0x8a, 0x70, 0xc6, 0xac, 0xd0, 0x0d, 0x13, 0x9a, 0x93, 0x0f, 0x73, 0xdd, 0x49,
+	0xe2, 0x1c, 0x28, 0x59, 0xb6, 0x6d, 0xa0, 0xb1, 0x19, 0xc8, 0x19, 0x47, 0xc8, 0x49, 0xbc, 0x60,
+	0xd2, 0xbe, 0x77, 0x9f, 0x66, 0x78, 0x84, 0x9c, 0xc6, 0x27, 0xe2, 0x4e, 0x89, 0xac, 0xe4, 0xec,
+	0xa3, 0x10, 0x1d, 0xd1, 0x55, 0x21, 0x31, 0x4f, 0x08, 0x9e, 0x85, 0x11, 0xb4, 0x42, 0x8b, 0xd3,
+	0x8e, 0x91, 0x45, 0x6c, 0xb4, 0x69, 0xb8, 0x6c, 0xf7, 0x1d, 0x37, 0xe3, 0xd3, 0x02, 0x98, 0x8c,
+	0xc7, 0x6a, 0x78, 0x27, 0xe8, 0xfa, 0xce, 0x00, 0x06, 0x94, 0xb3, 0x8f, 0xb3, 0xd1, 0xf2, 0xbd,
+	0x81, 0x8a, 0x69, 0x40, 0x3c, 0x22, 0x3c, 0x9b, 0x54, 0xc4, 0xef, 0x84, 0x48, 0x5e, 0xbe, 0x1d,
+	0x73, 0xd6, 0xc9, 0x74, 0x5e, 0x27, 0x59, 0xa7, 0x80, 0x15, 0x0d, 0x86, 0xcc, 0x5a, 0x04, 0x56,
+	0x94, 0x32, 0xb2, 0xc2, 0xd3, 0x82, 0x25, 0xf7, 0x3a, 0x43, 0x16, 0x30, 0x69, 0xd3, 0x50, 0xee,
+	0x72, 0x96, 0xcc, 0xe3, 0x39, 0xe6, 0x12, 0xa4, 0x1f, 0xa7, 0xd6, 0xc1, 0x97, 0xb5, 0xfe, 0xc0,
+	0xf3, 0x53, 0xc1, 0x7b, 0x04, 0x46, 0xab, 0x4d, 0x43, 0xb6, 0x64, 0x58, 0x41, 0xb0, 0xe3, 0x89,
+	0x2e, 0x8d, 0x78, 0xb4, 0x18, 0x2f, 0x3b, 0x16, 0xe7, 0xc4, 0x68, 0xe5, 0x48, 0x9c, 0x27, 0x06,
+	0x9e, 0x5f, 0xb6, 0x6d, 0xb1, 0x97, 0x70, 0xce, 0xa3, 0x10, 0xf6, 0xa8, 0x6f, 0x96, 0x79, 0x81,
+	0x9c, 0xc5, 0xa7, 0x96, 0x6d, 0x3b, 0xb3, 0x13, 0x71, 0x81, 0xc7, 0x48, 0x03, 0x9f, 0x81, 0x86,
+	0x13, 0xe6, 0xca, 0x34, 0x41, 0x86, 0x8f, 0x5d, 0x8e, 0xcc, 0x07, 0x60, 0xae, 0x75, 0xfc, 0xa1,
+	0xdb, 0x4d, 0xcd, 0xe4, 0x11, 0xfe, 0x8b, 0x6c, 0x34, 0xb7, 0x2d, 0x77, 0x8b, 0xe5, 0x23, 0xec,
+	0x23, 0x9c, 0xf5, 0x41, 0x72, 0x0e, 0x9f, 0x8d, 0x06, 0xfa, 0x92, 0xd5, 0xb3, 0xdc, 0x2e, 0xb5,
+	0xb3, 0xb3, 0xfd, 0x43, 0x71, 0x66, 0xa6, 0x4f, 0x4f, 0x9c, 0xfb, 0x38, 0x8c, 0xd3, 0xe8, 0xc8,
+	0xc4, 0xa9, 0x4b, 0x10, 0xf4, 0x36, 0x0d, 0xa5, 0x83, 0x10, 0x67, 0x3f, 0x01, 0x41, 0x8f, 0x27,
+	0x40, 0xfa, 0x68, 0xc3, 0x25, 0x9e, 0x64, 0xa3, 0xd6, 0x7d, 0x66, 0xe8, 0xf8, 0x34, 0x71, 0x7e,
+	0xe1, 0xec, 0x0f, 0x03, 0xdb, 0xa4, 0x3d, 0x6a, 0x05, 0x2a, 0x76, 0x8b, 0x3c, 0x86, 0xcf, 0x2d,
+	0xf7, 0x42, 0xea, 0x4b, 0x6b, 0x9f, 0x49, 0x07, 0x3d, 0xa7, 0x6b, 0xad, 0x73, 0xc1, 0x8f, 0x40,
+	0x7c, 0x13, 0x40, 0x92, 0xc7, 0x1b, 0x2e, 0xf3, 0x51, 0x58, 0x23, 0x36, 0x87, 0xfe, 0x16, 0xdd,
+	0xf0, 0x07, 0xdb, 0x96, 0x48, 0x8c, 0x8f, 0x89, 0xb9, 0x20, 0x67, 0xef, 0xc7, 0x21, 0x33, 0x58,
+	0x1f, 0xc5, 0x80, 0x3c, 0x15, 0x07, 0x47, 0x3a, 0x1b, 0x71, 0xf6, 0x27, 0x2e, 0xd6, 0x6a, 0xf6,
+	0xdc, 0xde, 0xde, 0xde, 0x5e, 0xa9, 0xf1, 0x57, 0x94, 0xb3, 0xc1, 0x28, 0x4f, 0x07, 0x4d, 0x7c,
+	0x58, 0xf2, 0x97, 0x6d, 0x82, 0x33, 0xa6, 0x4c, 0x26, 0x75, 0x3c, 0xbd, 0x76, 0x77, 0xdd, 0x0b,
+	0xaf, 0xec, 0x3a, 0x41, 0x18, 0xb0, 0x4d, 0xb1, 0x66, 0x26, 0x49, 0xad, 0xeb, 0x78, 0xb2, 0x1b,
+	0x9b, 0x3a, 0x92, 0xd9, 0x0b, 0x0d, 0xca, 0x0e, 0x9c, 0x67, 0x13, 0x0c, 0x15, 0x48, 0x93, 0xab,
+	0x68, 0x0c, 0x95, 0x9b, 0xa1, 0xca, 0x89, 0xd6, 0xa7, 0xb5, 0x86, 0xef, 0x32, 0xc3, 0xa7, 0x05,
+	0x43, 0xa1, 0x56, 0x98, 0xfd, 0x3b, 0xd2, 0xef, 0xb5, 0xda, 0xf3, 0x8e, 0x32, 0x9a, 0xa5, 0x87,
+	0x8b, 0x66, 0x5b, 0xeb, 0xd4, 0x16, 0x73, 0xea, 0x82, 0x1c, 0x4d, 0x35, 0x66, 0xe1, 0xdd, 0x4f,
+	0x90, 0xee, 0x9c, 0xa0, 0xf5, 0x8d, 0x07, 0xbe, 0x94, 0x08, 0xfc, 0x4d, 0x2d, 0xc6, 0x6d, 0x86,
+	0xf1, 0x7c, 0x3a, 0xf0, 0x45, 0x08, 0x7f, 0x81, 0x8a, 0x4f, 0x2a, 0x63, 0xe3, 0x7c, 0x5a, 0x8b,
+	0xd3, 0x61, 0x38, 0x2f, 0x0a, 0x46, 0x91, 0x7d, 0x81, 0xf6, 0xe7, 0x25, 0xfd, 0x89, 0x69, 0x5c,
+	0xa4, 0x70, 0x77, 0x59, 0xa7, 0x3b, 0x8c, 0x1c, 0xdf, 0x5d, 0xe2, 0x26, 0xd3, 0x34, 0xf4, 0xd9,
+	0x8a, 0x67, 0x54, 0xd8, 0x55, 0x64, 0xd4, 0x06, 0x1e, 0x5f, 0xa6, 0xd8, 0x35, 0xe5, 0x90, 0x39,
+	0x6a, 0xc3, 0x9d, 0x47, 0x2c, 0x28, 0x23, 0x0d, 0x13, 0x4c, 0x83, 0x82, 0x53, 0x90, 0x77, 0x5f,
+	0x90, 0xf3, 0x4e, 0xe7, 0xbd, 0x88, 0xd3, 0xef, 0x51, 0xee, 0xb9, 0x51, 0x1b, 0xa2, 0x05, 0x3c,
+	0x91, 0x98, 0x47, 0x53, 0x66, 0xdc, 0x82, 0x6b, 0x42, 0xc7, 0xe9, 0xc3, 0x2a, 0xde, 0x1f, 0xb0,
+	0x2b, 0x52, 0xd9, 0x14, 0x84, 0xd6, 0xba, 0xd6, 0x85, 0x7b, 0xcc, 0x85, 0x47, 0xe4, 0xa9, 0x93,
+	0x01, 0x26, 0xd0, 0xff, 0x11, 0xe5, 0x1e, 0x6c, 0x1f, 0x0a, 0x7d, 0x03, 0xcf, 0x08, 0x45, 0x6b,
+	0x2b, 0xcc, 0x81, 0x8a, 0x99, 0xa2, 0x15, 0xf8, 0xd0, 0x93, 0x7d, 0xc8, 0x81, 0x27, 0x7c, 0xf8,
+	0x1d, 0xd2, 0x9f, 0xbf, 0xc7, 0xce, 0xd4, 0x79, 0x5c, 0x65, 0xfd, 0x19, 0xfa, 0x29, 0x33, 0x6a,
+	0x14, 0x64, 0x4f, 0x5f, 0xbd, 0x6a, 0xa9, 0x11, 0x65, 0x57, 0xad, 0x83, 0x41, 0x5e, 0xb0, 0x6a,
+	0xb9, 0xaa, 0x55, 0xab, 0x08, 0xe1, 0xbb, 0x48, 0x71, 0x37, 0xd9, 0xf7, 0x75, 0x7c, 0x1e, 0x57,
+	0xd9, 0x19, 0x9e, 0x85, 0xb2, 0x66, 0x46, 0x8d, 0xd6, 0x55, 0x2d, 0x4c, 0x8f, 0xc1, 0x3c, 0x25,
+	0x87, 0x32, 0x61, 0x5e, 0xa0, 0xeb, 0x67, 0x6e, 0x48, 0xca, 0x6d, 0x74, 0x55, 0x6b, 0x70, 0x50,
+	0x47, 0xe9, 0x12, 0x96, 0xa4, 0x52, 0x98, 0x7b, 0x09, 0x29, 0x2e, 0x5f, 0xfb, 0x0d, 0x46, 0x81,
+	0xdb, 0xcf, 0xc8, 0x6e, 0x67, 0x0c, 0x09, 0x1c, 0xbf, 0x41, 0xca, 0xdb, 0x1e, 0xe4, 0x0b, 0xc8,
+	0xbb, 0x02, 0xcd, 0xa8, 0x9d, 0xca, 0xa5, 0x92, 0xae, 0x9a, 0x51, 0x96, 0xaa, 0x19, 0x05, 0x87,
+	0x10, 0x5f, 0x3e, 0x84, 0x28, 0x80, 0x09, 0xe4, 0x9f, 0x57, 0xdc, 0x46, 0x0b, 0x02, 0x13, 0xa8,
+	0xf3, 0x21, 0xa1, 0x40, 0xa8, 0xff, 0x6c, 0xe6, 0x56, 0x5b, 0x30, 0xf6, 0xa1, 0x6a, 0xec, 0x95,
+	0xaa, 0x2d, 0xe5, 0xdd, 0xb8, 0x20, 0x38, 0x43, 0x39, 0x38, 0x0a, 0x15, 0xc2, 0xc4, 0x56, 0xde,
+	0x2d, 0xbb, 0x75, 0x43, 0x6b, 0xe5, 0x3e, 0xb3, 0x52, 0x17, 0x0c, 0xb5, 0x96, 0xe4, 0xb4, 0xc9,
+	0xbf, 0xb2, 0xb7, 0x36, 0xb5, 0xb6, 0x76, 0x98, 0xad, 0x73, 0x19, 0x8f, 0xb2, 0x8a, 0x84, 0xb9,
+	0x40, 0x5f, 0x02, 0x28, 0x58, 0x5a, 0x77, 0xe5, 0xa5, 0x55, 0xa7, 0x4b, 0x18, 0xbd, 0x27, 0x57,
+	0x15, 0x54, 0x45, 0xff, 0xd6, 0x15, 0xad, 0xe9, 0x07, 0xcc, 0xb4, 0x91, 0x3e, 0x3f, 0x09, 0x8d,
+	0xc2, 0xd8, 0x8f, 0x50, 0x7e, 0xbd, 0x42, 0x3b, 0x2b, 0x47, 0x0b, 0x64, 0x29, 0xb9, 0x40, 0x6e,
+	0x68, 0x51, 0x3d, 0xcb, 0x50, 0x35, 0x52, 0xa8, 0x94, 0x96, 0x05, 0xbe, 0xff, 0x20, 0x4d, 0xc5,
+	0x44, 0xb9, 0x80, 0xe9, 0x96, 0x0b, 0xc5, 0x65, 0x20, 0xda, 0x2a, 0x65, 0x32, 0x68, 0xbe, 0xe1,
+	0xd9, 0xd4, 0xa8, 0x44, 0x9a, 0xe1, 0x3f, 0x9c, 0x11, 0x56, 0x68, 0x10, 0x3a, 0x6e, 0x5c, 0xf7,
+	0x87, 0xc7, 0x8e, 0x29, 0x33, 0x45, 0x2b, 0xc8, 0xc1, 0x2f, 0xca, 0x39, 0x98, 0xeb, 0x9a, 0x88,
+	0xc0, 0x9f, 0x51, 0x6e, 0x51, 0xe8, 0x7f, 0xe7, 0x7f, 0xc1, 0x59, 0xe7, 0x4b, 0x99, 0xb3, 0x8e,
+	0x1a, 0xa0, 0xf0, 0xe2, 0x79, 0xa4, 0xa8, 0x5e, 0x8d, 0x9e, 0x0e, 0x90, 0x78, 0x3a, 0x58, 0xb6,
+	0x6d, 0x9f, 0x6f, 0x3e, 0xf0, 0xbf, 0x60, 0x8d, 0xfd, 0xb2, 0xbc, 0xc6, 0x66, 0x8c, 0x08, 0x0c,
+	0xff, 0x46, 0x39, 0xa5, 0x32, 0x88, 0xd9, 0xd5, 0x4e, 0x67, 0x93, 0xd9, 0x8e, 0x13, 0x9d, 0xb7,
+	0xe3, 0xa7, 0x8b, 0x04, 0x2c, 0xde, 0x04, 0xb4, 0x26, 0x60, 0x88, 0xce, 0x8a, 0xec, 0x7f, 0xfa,
+	0x79, 0xa2, 0x22, 0x3d, 0x4f, 0xa8, 0x5e, 0x58, 0xaa, 0xca, 0x17, 0x96, 0x82, 0x8b, 0xfb, 0x73,
+	0xea, 0x8b, 0xbb, 0xe4, 0x56, 0xea, 0xa4, 0xa9, 0xae, 0x04, 0x3e, 0xa4, 0xe7, 0x29, 0x2f, 0xcb,
+	0x92, 0x97, 0x05, 0xd8, 0xbf, 0x92, 0x5f, 0x74, 0x50, 0x62, 0xff, 0x19, 0xca, 0x29, 0x55, 0x8e,
+	0xff, 0xf0, 0x54, 0x4a, 0x3c, 0x3c, 0x15, 0xec, 0x4c, 0x7b, 0x48, 0x86, 0xa9, 0xc4, 0x20, 0x60,
+	0xde, 0xcf, 0xa9, 0x9a, 0xca, 0x28, 0x0b, 0xec, 0x3e, 0x9f, 0xb1, 0xab, 0xd4, 0xaa, 0xb0, 0xbb,
+	0x62, 0xbd, 0x17, 0xbb, 0x5f, 0xcd, 0xb1, 0x9b, 0xeb, 0xef, 0xbf, 0x90, 0xaa, 0xe0, 0xfb, 0x3e,
+	0x9c, 0x49, 0xfa, 0x73, 0xce, 0x0b, 0x91, 0xdf, 0x8b, 0xa9, 0x3d, 0x29, 0x37, 0xd8, 0x6e, 0xb6,
+	0x98, 0x9d, 0x89, 0xb3, 0xde, 0xde, 0x8b, 0x63, 0xd9, 0x7b, 0x1d, 0xe5, 0x15, 0xc4, 0xf7, 0x7d,
+	0x76, 0xd7, 0xc3, 0x79, 0x69, 0x2c, 0x38, 0xbf, 0x46, 0x9a, 0x1a, 0xfc, 0x01, 0x3f, 0xb7, 0x16,
+	0x00, 0x7f, 0x79, 0x2c, 0xe0, 0x70, 0xd3, 0xd6, 0xbd, 0x0e, 0xfc, 0x7f, 0xb1, 0xbf, 0x32, 0x16,
+	0xf6, 0xd7, 0x90, 0xfa, 0xdd, 0x22, 0xb3, 0xfc, 0x2d, 0xe0, 0x89, 0xd4, 0x37, 0x20, 0x71, 0xab,
+	0x00, 0xcc, 0xab, 0x63, 0x81, 0x79, 0x03, 0xe5, 0x3e, 0x95, 0x1c, 0x10, 0x9e, 0xaf, 0x8d, 0x85,
+	0xe7, 0x1d, 0xa4, 0x7d, 0x9d, 0xd9, 0x37, 0x26, 0xfd, 0x41, 0xfe, 0xb5, 0x08, 0xd3, 0xa3, 0xa9,
+	0xbb, 0x43, 0x9e, 0x4d, 0x01, 0xee, 0xa7, 0xa8, 0xe8, 0xd5, 0x67, 0xdf, 0xf8, 0x6e, 0x6b, 0xf1,
+	0x7d, 0x3d, 0xc2, 0xd7, 0xcc, 0x9e, 0x82, 0xf6, 0x03, 0x51, 0xff, 0x78, 0x75, 0x40, 0x10, 0x5f,
+	0xcf, 0x40, 0xd4, 0x9b, 0x15, 0x10, 0x5f, 0x41, 0xf8, 0x44, 0xf6, 0x5d, 0x8c, 0xa3, 0x3b, 0x83,
+	0x31, 0x67, 0x2e, 0x87, 0x31, 0xca, 0x04, 0xa5, 0x20, 0xd9, 0xde, 0x18, 0x2b, 0xd9, 0xde, 0x46,
+	0x39, 0x2f, 0x70, 0xb0, 0xb3, 0x6d, 0xf4, 0xec, 0xc4, 0x0a, 0xc2, 0x9b, 0xc9, 0xe2, 0x71, 0xbc,
+	0xe7, 0xc5, 0xcd, 0x02, 0x64, 0xdf, 0x18, 0x0b, 0xd9, 0x3f, 0x4b, 0x8a, 0xf7, 0x54, 0xe5, 0xb7,
+	0x62, 0xf3, 0xb8, 0xba, 0xea, 0xf9, 0x5d, 0xca, 0xaf, 0x6d, 0xac, 0x91, 0xba, 0x33, 0x94, 0x8b,
+	0xef, 0x0c, 0x15, 0xf5, 0x9d, 0xc9, 0xc0, 0x93, 0x6c, 0x80, 0xd6, 0x6c, 0xa3, 0xca, 0x06, 0x82,
+	0x37, 0xe1, 0x69, 0x65, 0x9d, 0xee, 0x8c, 0x4c, 0x4c, 0xb0, 0xfe, 0x49, 0x12, 0x94, 0xc4, 0xd7,
+	0xe9, 0x8e, 0x6c, 0x68, 0x92, 0x21, 0x57, 0x70, 0x48, 0x0b, 0xcf, 0x33, 0x2a, 0xab, 0xa8, 0x03,
+	0x7d, 0xd5, 0xea, 0x86, 0x9e, 0x6f, 0xd4, 0x98, 0x61, 0x25, 0xaf, 0x20, 0xe2, 0xdf, 0x1c, 0x2b,
+	0xe2, 0x7f, 0x40, 0x85, 0x4f, 0xae, 0x63, 0xd4, 0xa1, 0x67, 0xf6, 0x59, 0x45, 0xd7, 0x7b, 0xf0,
+	0xad, 0xb1, 0x3c, 0x78, 0x11, 0xe5, 0xbf, 0x07, 0x03, 0xbc, 0x88, 0x10, 0x7f, 0x92, 0x13, 0xb7,
+	0x0a, 0xae, 0xb7, 0xdf, 0x46, 0x8a, 0x0b, 0xbe, 0xd2, 0x80, 0x80, 0xb1, 0x9b, 0x7d, 0x77, 0x86,
+	0xc0, 0xc5, 0x7f, 0xe1, 0x83, 0xa3, 0x72, 0x73, 0xc6, 0x1c, 0xb5, 0x0b, 0xae, 0x83, 0xdf, 0x89,
+	0x10, 0x9c, 0x14, 0x1c, 0x59, 0xb9, 0xb0, 0x7c, 0x0b, 0x1f, 0x96, 0x1e, 0xb6, 0xb5, 0x23, 0x76,
+	0x01, 0xcf, 0xde, 0xb0, 0x76, 0x45, 0x8f, 0x20, 0x5e, 0xff, 0x24, 0x6a, 0xe3, 0x57, 0x48, 0xf3,
+	0x66, 0x7e, 0x10, 0x16, 0x0a, 0x8a, 0xe5, 0x6f, 0x22, 0xb9, 0xc8, 0x90, 0x8b, 0x46, 0xc4, 0xe2,
+	0x33, 0x98, 0x64, 0x5f, 0xf1, 0xb5, 0x60, 0xe7, 0x70, 0xf9, 0x1a, 0xe5, 0xaf, 0x28, 0xf0, 0x17,
+	0x96, 0x96, 0xdb, 0x56, 0x6f, 0xc8, 0x57, 0x90, 0xa8, 0xd1, 0xf8, 0x2d, 0xd2, 0x7f, 0x23, 0x70,
+	0x10, 0x46, 0x5a, 0x1d, 0x6d, 0x44, 0xbe, 0x8b, 0xe4, 0x42, 0x9c, 0x0e, 0x50, 0xb2, 0xe4, 0x9b,
+	0xfa, 0xe8, 0x72, 0x01, 0x4f, 0x5c, 0xf5, 0x7a, 0x36, 0xe5, 0x17, 0x9b, 0xb8, 0x95, 0xb7, 0x21,
+	0xc2, 0xd6, 0x74, 0x65, 0x77, 0xe0, 0xc4, 0xaf, 0x7b, 0xd1, 0x5c, 0x4e, 0x50, 0x1a, 0x7f, 0x41,
+	0x9a, 0x6f, 0x23, 0xc6, 0xb6, 0x46, 0x70, 0x05, 0xd6, 0x89, 0xd8, 0x0e, 0xfb, 0x0f, 0xe1, 0xeb,
+	0x74, 0xae, 0xb3, 0xe5, 0xba, 0x6c, 0xc2, 0xdf, 0x82, 0xd4, 0xf9, 0x5e, 0x26, 0x75, 0x72, 0xf1,
+	0x89, 0x28, 0xbd, 0x8c, 0x34, 0xdf, 0x70, 0xe4, 0xb9, 0x51, 0x00, 0xe4, 0xad, 0x0c, 0x90, 0x5c,
+	0x0b, 0x02, 0xc8, 0xdf, 0xd0, 0xbe, 0xbe, 0x16, 0x19, 0xfb, 0x55, 0x2d, 0xf9, 0x92, 0x0b, 0xd1,
+	0x4d, 0xbc, 0xe4, 0xb6, 0x3e, 0xa7, 0x75, 0xe3, 0xed, 0xc8, 0x8d, 0xc7, 0x13, 0xf1, 0x2c, 0xc6,
+	0x27, 0x1c, 0x5a, 0xc5, 0xf3, 0xaa, 0x0f, 0x75, 0x8b, 0x1c, 0xe8, 0x38, 0xb1, 0x03, 0x71, 0x1a,
+	0x34, 0xfe, 0x84, 0x8a, 0xbe, 0x8e, 0xd1, 0xaa, 0x34, 0xf0, 0x64, 0x2c, 0x1d, 0x1f, 0x1a, 0x78,
+	0x53, 0x95, 0x73, 0x05, 0xc7, 0xc0, 0xef, 0x67, 0x8e, 0x81, 0x7a, 0x60, 0x22, 0x18, 0x77, 0x94,
+	0x5f, 0xef, 0xb4, 0xae, 0x69, 0xcd, 0xfd, 0x00, 0xc9, 0xcf, 0x18, 0x0a, 0x1d, 0xc2, 0xc6, 0x2f,
+	0x51, 0xce, 0x97, 0x40, 0x99, 0x43, 0x70, 0xb2, 0xcc, 0x51, 0xca, 0x2f, 0x73, 0x94, 0x53, 0x65,
+	0x8e, 0x82, 0x12, 0xcc, 0x3b, 0x39, 0x25, 0xa7, 0xdc, 0x1d, 0xfc, 0xb9, 0xdc, 0x8f, 0x93, 0x46,
+	0x23, 0x83, 0x12, 0x23, 0xa3, 0xaf, 0xcd, 0xbf, 0x8b, 0xe4, 0x9a, 0x6e, 0x8e, 0x6e, 0x61, 0xff,
+	0x55, 0xa4, 0xf9, 0x00, 0x0a, 0xc2, 0x10, 0x53, 0xe2, 0xb8, 0xf1, 0x66, 0xc1, 0xdc, 0xff, 0xa1,
+	0x6a, 0xff, 0x52, 0xdb, 0x18, 0x41, 0xf9, 0xef, 0x00, 0x84, 0xbb, 0x21, 0x47, 0x46, 0x31, 0x00,
+	0x00,
 }
@@ -0,0 +1,83 @@
+package meta
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// raftHandshakeTimeout bounds the TLS handshake of an accepted raft
+// connection, and so how long a stalled peer holds its connection open.
+const raftHandshakeTimeout = 10 * time.Second
+
+// internalTLSConfigs returns the TLS configs the raft layer accepts and
+// dials connections with, built from internal-cert, internal-key and the
+// certificate authorities cas last loaded from the SecretProvider,
+// internal-ca by default. Accepted connections must present a certificate
+// signed by internal-ca if require-client-cert is set, and one that is
+// presented is always verified. Dialed connections are to take their root
+// certificate authorities from cas as they dial.
+func (c *Config) internalTLSConfigs(cas *caReloader) (server, client *tls.Config, err error) {
+	cert, err := tls.LoadX509KeyPair(c.InternalCert, c.InternalKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("internal-cert: %s", err)
+	}
+
+	server = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+	}
+	if c.RequireClientCert {
+		server.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	client = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      cas.Pool(),
+	}
+	return withClientCAs(server, cas), client, nil
+}
+
+// withClientCAs has config verify client certificates against the
+// certificate authorities cas last loaded as each connection handshakes,
+// and returns it.
+func withClientCAs(config *tls.Config, cas *caReloader) *tls.Config {
+	config.ClientCAs = cas.Pool()
+	base := config.Clone()
+	config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		c := base.Clone()
+		c.ClientCAs = cas.Pool()
+		return c, nil
+	}
+	return config
+}
+
+// internalRootCAs returns the system certificate authorities along with
+// those of internal-ca, for the meta client to verify meta servers whose
+// HTTPS certificate internal-ca signed.
+func (c *Config) internalRootCAs() (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	buf, err := c.secretProvider().GetCA()
+	if err != nil {
+		return nil, fmt.Errorf("internal-ca: %s", err)
+	}
+	if !pool.AppendCertsFromPEM(buf) {
+		return nil, errors.New("internal-ca: no certificates found")
+	}
+	return pool, nil
+}
+
+// internalClientCertificate returns internal-cert, for the meta client to
+// present to meta servers asking for a client certificate. It is loaded on
+// every handshake, so a renewed certificate is picked up.
+func (c *Config) internalClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(c.InternalCert, c.InternalKey)
+	if err != nil {
+		return nil, fmt.Errorf("internal-cert: %s", err)
+	}
+	return &cert, nil
+}
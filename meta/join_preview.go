@@ -0,0 +1,167 @@
+package meta
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/raft"
+)
+
+// JoinPreview is what joining a meta node to the cluster would result in,
+// as returned by a dry-run join.
+type JoinPreview struct {
+	// Node is the meta node as it would be after joining.
+	Node NodeInfo `json:"node"`
+
+	// Peers are the raft peers, and MetaNodes the meta nodes, of the
+	// cluster after the join.
+	Peers     []string   `json:"peers"`
+	MetaNodes []NodeInfo `json:"metaNodes"`
+
+	// Quorum is the number of meta nodes the cluster would need to make
+	// progress after the join.
+	Quorum int `json:"quorum"`
+
+	// Warnings are the problems found that don't stop the node joining.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// previewJoin runs the checks join does for the node n and returns the
+// membership joining it would result in, without changing anything. Like
+// join, it must run on the leader.
+func (s *store) previewJoin(n *NodeInfo) (*JoinPreview, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.raftState == nil {
+		return nil, fmt.Errorf("store not open")
+	}
+	if s.data.TopologyFrozen {
+		return nil, ErrTopologyFrozen
+	}
+	if !s.raftState.isLeader() {
+		return nil, raft.ErrNotLeader
+	}
+	if s.raftState.pendingConfigChange() != "" {
+		return nil, ErrConfigChangeInProgress
+	}
+	peers, err := s.raftState.peers()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &JoinPreview{}
+	for _, m := range s.data.MetaNodes {
+		if m.TCPHost == n.TCPHost {
+			p.Warnings = append(p.Warnings, fmt.Sprintf("raft address %s is already meta node %d; joining would only update its HTTP address", n.TCPHost, m.ID))
+		} else if m.Host == n.Host {
+			p.Warnings = append(p.Warnings, fmt.Sprintf("HTTP address %s is already advertised by meta node %d", n.Host, m.ID))
+		}
+	}
+
+	data := s.data.Clone()
+	if err := data.CreateMetaNode(n.Host, n.TCPHost); err != nil {
+		return nil, err
+	}
+	for _, m := range data.MetaNodes {
+		if m.TCPHost == n.TCPHost {
+			p.Node = m
+		}
+	}
+	p.MetaNodes = data.MetaNodes
+
+	p.Peers = append(p.Peers, peers...)
+	if !Peers(peers).Contains(n.TCPHost) {
+		p.Peers = append(p.Peers, n.TCPHost)
+	}
+	sort.Strings(p.Peers)
+	p.Quorum = len(p.Peers)/2 + 1
+	if len(p.Peers)%2 == 0 {
+		p.Warnings = append(p.Warnings, fmt.Sprintf("the cluster would have %d raft peers, which tolerate no more failures than %d", len(p.Peers), len(p.Peers)-1))
+	}
+	return p, nil
+}
+
+// serveJoinPreview responds to a dry-run join of the node n.
+func (h *handler) serveJoinPreview(w http.ResponseWriter, r *http.Request, n *NodeInfo) {
+	p, err := h.store.previewJoin(n)
+	if err == raft.ErrNotLeader {
+		l := h.store.leaderHTTP()
+		if l == "" {
+			// No cluster leader. Client will have to try again later.
+			h.httpError(errors.New("no leader"), w, http.StatusServiceUnavailable)
+			return
+		}
+		scheme := "http://"
+		if h.config.HTTPSEnabled {
+			scheme = "https://"
+		}
+		http.Redirect(w, r, scheme+l+"/join?dry-run=true", http.StatusTemporaryRedirect)
+		return
+	}
+
+	switch err {
+	case nil:
+	case ErrConfigChangeInProgress, ErrTopologyFrozen, ErrNodeExists:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	default:
+		h.httpError(err, w, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		h.httpError(err, w, http.StatusInternalServerError)
+	}
+}
+
+// PreviewJoin runs the checks JoinCluster does for joining this node to the
+// cluster through the meta server at addr, and returns the membership the
+// cluster would have after, without joining it. A metadata version that
+// differs from the cluster's but is within metadata-version-tolerance is
+// reported as a warning.
+func (c *Client) PreviewJoin(addr string) (*JoinPreview, error) {
+	httpAddr, raftAddr, err := c.config.advertisedAddrs()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := c.getSnapshot(addr, 0)
+	if err != nil {
+		return nil, err
+	} else if err := checkMetadataVersion(data.MetadataVersion, c.NodeMetadataVersion(), c.config.MetadataVersionTolerance); err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(&NodeInfo{Host: httpAddr, TCPHost: raftAddr})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient().Post(c.url(addr)+"/join?dry-run=true", "application/json", bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		if s := strings.TrimSpace(string(msg)); s != "" {
+			return nil, fmt.Errorf("meta server %s returned %s: %s", addr, resp.Status, s)
+		}
+		return nil, fmt.Errorf("meta server %s returned %s", addr, resp.Status)
+	}
+
+	p := &JoinPreview{}
+	if err := json.NewDecoder(resp.Body).Decode(p); err != nil {
+		return nil, err
+	}
+	if v := c.NodeMetadataVersion(); data.MetadataVersion != 0 && data.MetadataVersion != v {
+		p.Warnings = append(p.Warnings, fmt.Sprintf("metadata version %d of the cluster differs from version %d of this node", data.MetadataVersion, v))
+	}
+	return p, nil
+}
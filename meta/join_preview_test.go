@@ -0,0 +1,55 @@
+package meta_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// Ensure a dry-run join returns the membership joining would result in, and
+// leaves the cluster unchanged.
+func TestClient_PreviewJoin(t *testing.T) {
+	t.Parallel()
+
+	c := cloudMeta.NewTestCluster(t, 3)
+	defer c.Close()
+	leader := c.Leader(5 * time.Second)
+	follower := c.Services[0]
+	if follower == leader {
+		follower = c.Services[1]
+	}
+
+	// The node joining is never started, so its addresses are never used.
+	cfg := newConfig()
+	cfg.HTTPBindAddress = "127.0.0.1:18091"
+	cfg.BindAddress = "127.0.0.1:18088"
+	client := cloudMeta.NewClient(cfg)
+	defer client.Close()
+
+	// Preview through a follower, which redirects to the leader.
+	p, err := client.PreviewJoin(follower.HTTPAddr())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Node.ID != 4 || p.Node.Host != cfg.HTTPBindAddress || p.Node.TCPHost != cfg.BindAddress {
+		t.Fatalf("unexpected node: %+v", p.Node)
+	} else if len(p.MetaNodes) != 4 {
+		t.Fatalf("unexpected meta nodes: %+v", p.MetaNodes)
+	} else if len(p.Peers) != 4 || !cloudMeta.Peers(p.Peers).Contains(cfg.BindAddress) {
+		t.Fatalf("unexpected peers: %v", p.Peers)
+	} else if p.Quorum != 3 {
+		t.Fatalf("unexpected quorum: %d", p.Quorum)
+	} else if len(p.Warnings) != 1 || !strings.Contains(p.Warnings[0], "4 raft peers") {
+		t.Fatalf("unexpected warnings: %v", p.Warnings)
+	}
+
+	if nodes := clusterNodes(t, leader.HTTPAddr()); len(nodes) != 3 {
+		t.Fatalf("meta nodes changed: %+v", nodes)
+	}
+	if peers := raftPeers(t, leader.HTTPAddr()); len(peers) != 3 || cloudMeta.Peers(peers).Contains(cfg.BindAddress) {
+		t.Fatalf("raft peers changed: %v", peers)
+	}
+}
@@ -0,0 +1,133 @@
+package meta
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/uber-go/zap"
+)
+
+const (
+	// leaderWatchInterval is how often a node checks who leads the cluster
+	// for the leader change notifications.
+	leaderWatchInterval = 100 * time.Millisecond
+
+	// leaderChangeWebhookTimeout bounds each POST to leader-change-webhook,
+	// and leaderChangeWebhookRetryDelay is the wait before its one retry.
+	leaderChangeWebhookTimeout    = 5 * time.Second
+	leaderChangeWebhookRetryDelay = time.Second
+)
+
+// LeaderChange is a change of the leader of the cluster seen by a node, as
+// POSTed to leader-change-webhook.
+type LeaderChange struct {
+	// OldLeader is the HTTP address of the leader the node knew of before,
+	// empty for the first leader it sees, and NewLeader the one of the
+	// leader it knows of now.
+	OldLeader string `json:"oldLeader"`
+	NewLeader string `json:"newLeader"`
+
+	// Term is the raft term the node saw the new leader in, zero on an
+	// observer.
+	Term uint64 `json:"term"`
+
+	// Node is the HTTP address of the node reporting the change.
+	Node string `json:"node"`
+
+	Time time.Time `json:"time"`
+}
+
+// OnLeaderChange registers fn to be called with the HTTP addresses of the
+// old and new leader every time this node sees the leader of the cluster
+// change, with an empty oldLeader for the first leader it sees. A node
+// without a leader, as during an election, reports nothing until it knows
+// of the next one. Changes are reported in order from a single goroutine,
+// so a slow fn delays the next ones, but never raft. It must be registered
+// before Open.
+func (s *Service) OnLeaderChange(fn func(oldLeader, newLeader string)) {
+	s.leaderChange = fn
+}
+
+// watchLeader reports the changes of the leader of the cluster to the hook
+// registered with OnLeaderChange and to leader-change-webhook, until the
+// service is closed. Raft only notifies a node of its own leadership, so
+// the leader is checked every leaderWatchInterval.
+func (s *Service) watchLeader() {
+	ticker := time.NewTicker(leaderWatchInterval)
+	defer ticker.Stop()
+
+	var client *http.Client
+	if s.config.LeaderChangeWebhook != "" {
+		client = &http.Client{Transport: newHTTPTransport(0), Timeout: leaderChangeWebhookTimeout}
+		defer client.CloseIdleConnections()
+	}
+
+	var leader string
+	for {
+		var term uint64
+		l := s.store.leaderHTTP()
+		if st := s.store.raftStatus(); st != nil {
+			term = st.Term
+		}
+		if l != "" && l != leader {
+			change := &LeaderChange{OldLeader: leader, NewLeader: l, Term: term, Node: s.store.httpAddr, Time: now()}
+			leader = l
+			s.Logger.Info("leader changed", zap.String("old", change.OldLeader), zap.String("new", change.NewLeader), zap.Uint64("term", term))
+
+			if s.leaderChange != nil {
+				s.leaderChange(change.OldLeader, change.NewLeader)
+			}
+			if client != nil {
+				s.postLeaderChange(client, change)
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-s.closing:
+			return
+		}
+	}
+}
+
+// postLeaderChange POSTs change to leader-change-webhook, retrying once.
+func (s *Service) postLeaderChange(client *http.Client, change *LeaderChange) {
+	b, err := json.Marshal(change)
+	if err != nil {
+		s.Logger.Info("leader change webhook failed", zap.Error(err))
+		return
+	}
+
+	for attempt := 0; ; attempt++ {
+		err = postJSON(client, s.config.LeaderChangeWebhook, b)
+		if err == nil {
+			return
+		} else if attempt > 0 {
+			break
+		}
+		select {
+		case <-time.After(leaderChangeWebhookRetryDelay):
+		case <-s.closing:
+			return
+		}
+	}
+	s.Logger.Info("leader change webhook failed, dropping notification",
+		zap.String("url", s.config.LeaderChangeWebhook), zap.String("new", change.NewLeader), zap.Error(err))
+}
+
+// postJSON POSTs the JSON b to url, and returns an error unless it is
+// answered with a 2xx status.
+func postJSON(client *http.Client, url string, b []byte) error {
+	resp, err := client.Post(url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package meta_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// Ensure the hook registered with OnLeaderChange is told of the first
+// leader a node sees.
+func TestService_OnLeaderChange(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	s := newService(cfg)
+	changes := make(chan [2]string, 1)
+	s.OnLeaderChange(func(oldLeader, newLeader string) {
+		changes <- [2]string{oldLeader, newLeader}
+	})
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	select {
+	case change := <-changes:
+		if change != [2]string{"", s.HTTPAddr()} {
+			t.Fatalf("unexpected leader change: %q", change)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the leader change")
+	}
+}
+
+// Ensure every node POSTs the leader changes it sees to
+// leader-change-webhook, retrying a failed POST.
+func TestService_LeaderChangeWebhook(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var changes []cloudMeta.LeaderChange
+	var failed bool
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !failed {
+			failed = true
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var change cloudMeta.LeaderChange
+		if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
+			t.Errorf("bad payload: %s", err)
+		}
+		changes = append(changes, change)
+	}))
+	defer hook.Close()
+
+	// seen returns the last change from oldLeader POSTed by each node.
+	seen := func(oldLeader string) map[string]cloudMeta.LeaderChange {
+		mu.Lock()
+		defer mu.Unlock()
+		m := make(map[string]cloudMeta.LeaderChange)
+		for _, change := range changes {
+			if change.OldLeader == oldLeader {
+				m[change.Node] = change
+			}
+		}
+		return m
+	}
+	waitFor := func(oldLeader string, n int) map[string]cloudMeta.LeaderChange {
+		deadline := time.Now().Add(10 * time.Second)
+		for {
+			if m := seen(oldLeader); len(m) >= n {
+				return m
+			} else if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for leader changes from %q: %+v", oldLeader, m)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+
+	c := cloudMeta.NewTestClusterWithConfig(t, 3, func(i int, cfg *cloudMeta.Config) {
+		cfg.LeaderChangeWebhook = hook.URL
+	})
+	defer c.Close()
+
+	leader := c.Leader(5 * time.Second)
+	for node, change := range waitFor("", 3) {
+		if change.NewLeader != leader.HTTPAddr() || change.Term == 0 || change.Time.IsZero() {
+			t.Fatalf("unexpected leader change seen by %s: %+v", node, change)
+		}
+	}
+
+	if err := leader.Close(); err != nil {
+		t.Fatal(err)
+	}
+	newLeader := c.Leader(10 * time.Second)
+	if newLeader == nil {
+		t.Fatal("timed out waiting for a new leader")
+	}
+	for node, change := range waitFor(leader.HTTPAddr(), 2) {
+		if change.NewLeader != newLeader.HTTPAddr() {
+			t.Fatalf("unexpected leader change seen by %s: %+v", node, change)
+		}
+	}
+}
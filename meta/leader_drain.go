@@ -0,0 +1,105 @@
+package meta
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// connContextKey is the request context key of the connection a request
+// came in on.
+type connContextKey struct{}
+
+// connContext is an http.Server ConnContext hook that records the
+// connection of every request in its context.
+func connContext(ctx context.Context, conn net.Conn) context.Context {
+	return context.WithValue(ctx, connContextKey{}, conn)
+}
+
+// writeConns tracks the HTTP API connections that carried writes, so they
+// can be drained when the node loses leadership. Their clients then dial
+// again and find the new leader, rather than keep sending writes to a
+// follower that only redirects them.
+type writeConns struct {
+	mu    sync.Mutex
+	conns map[net.Conn]*writeConn
+}
+
+// writeConn is the state of a connection tracked by writeConns.
+type writeConn struct {
+	idle     bool
+	draining bool
+}
+
+func newWriteConns() *writeConns {
+	return &writeConns{conns: make(map[net.Conn]*writeConn)}
+}
+
+// add marks the connection r came in on as having carried a write.
+func (t *writeConns) add(r *http.Request) {
+	conn, ok := r.Context().Value(connContextKey{}).(net.Conn)
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	if _, ok := t.conns[conn]; !ok {
+		t.conns[conn] = &writeConn{}
+	}
+	t.mu.Unlock()
+}
+
+// connState is an http.Server ConnState hook. A draining connection is
+// closed as soon as it has served its request.
+func (t *writeConns) connState(conn net.Conn, state http.ConnState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	wc, ok := t.conns[conn]
+	if !ok {
+		return
+	}
+	switch state {
+	case http.StateActive:
+		wc.idle = false
+	case http.StateIdle:
+		wc.idle = true
+		if wc.draining {
+			delete(t.conns, conn)
+			conn.Close()
+		}
+	case http.StateClosed, http.StateHijacked:
+		delete(t.conns, conn)
+	}
+}
+
+// drain closes the idle connections that carried writes, and the others
+// once they have served the request in progress, or after timeout at the
+// latest. It returns the number of connections drained.
+func (t *writeConns) drain(timeout time.Duration) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := len(t.conns)
+	for conn, wc := range t.conns {
+		if wc.idle {
+			delete(t.conns, conn)
+			conn.Close()
+			continue
+		}
+		wc.draining = true
+	}
+
+	if len(t.conns) > 0 {
+		time.AfterFunc(timeout, func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			for conn, wc := range t.conns {
+				if wc.draining {
+					delete(t.conns, conn)
+					conn.Close()
+				}
+			}
+		})
+	}
+	return n
+}
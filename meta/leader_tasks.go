@@ -0,0 +1,179 @@
+package meta
+
+import (
+	"sync"
+	"time"
+
+	"github.com/uber-go/zap"
+)
+
+// leaderTask is a periodic task that only runs while the node is the raft
+// leader.
+type leaderTask struct {
+	name     string
+	interval time.Duration
+	fn       func()
+
+	// running is held while fn runs so pausing can wait for a run in
+	// progress.
+	running sync.Mutex
+}
+
+// leaderScheduler runs the registered leader tasks while the node holds
+// leadership and stops them as soon as it loses it.
+type leaderScheduler struct {
+	mu       sync.Mutex
+	tasks    []*leaderTask
+	paused   map[string]bool
+	leader   bool
+	stopping chan struct{}
+	wg       sync.WaitGroup
+
+	// registry, if set, lists each run of a task while it runs. Canceling
+	// a run there doesn't stop it; pausing the task does.
+	registry *taskRegistry
+
+	logger zap.Logger
+}
+
+func newLeaderScheduler(logger zap.Logger) *leaderScheduler {
+	return &leaderScheduler{
+		paused: make(map[string]bool),
+		logger: logger,
+	}
+}
+
+// register adds a task, starting it right away if the node is already the
+// leader.
+func (s *leaderScheduler) register(t *leaderTask) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tasks = append(s.tasks, t)
+	if s.leader {
+		s.start(t)
+	}
+}
+
+// setLeader starts or stops every task according to the node's leadership.
+func (s *leaderScheduler) setLeader(leader bool) {
+	s.mu.Lock()
+	if s.leader == leader {
+		s.mu.Unlock()
+		return
+	}
+	s.leader = leader
+
+	if leader {
+		s.logger.Info("gained leadership, starting leader tasks")
+		s.stopping = make(chan struct{})
+		for _, t := range s.tasks {
+			s.start(t)
+		}
+		s.mu.Unlock()
+		return
+	}
+
+	s.logger.Info("lost leadership, stopping leader tasks")
+	close(s.stopping)
+	s.mu.Unlock()
+
+	// Wait outside the lock so a running task can't deadlock against us.
+	s.wg.Wait()
+}
+
+// pause stops running the named task until it is resumed, waiting for a run
+// in progress to finish. The task keeps its schedule so it survives
+// leadership changes paused.
+func (s *leaderScheduler) pause(name string) error {
+	s.mu.Lock()
+	t := s.task(name)
+	if t == nil {
+		s.mu.Unlock()
+		return ErrLeaderTaskNotFound
+	}
+	s.paused[name] = true
+	s.mu.Unlock()
+
+	t.running.Lock()
+	t.running.Unlock()
+	return nil
+}
+
+// resume runs the named task again from its next tick.
+func (s *leaderScheduler) resume(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.task(name) == nil {
+		return ErrLeaderTaskNotFound
+	}
+	delete(s.paused, name)
+	return nil
+}
+
+// pausedTasks returns whether each registered task is paused, keyed by name.
+func (s *leaderScheduler) pausedTasks() map[string]bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := make(map[string]bool, len(s.tasks))
+	for _, t := range s.tasks {
+		m[t.name] = s.paused[t.name]
+	}
+	return m
+}
+
+// task returns the named task, or nil. s.mu must be held.
+func (s *leaderScheduler) task(name string) *leaderTask {
+	for _, t := range s.tasks {
+		if t.name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// run calls t.fn unless t is paused.
+func (s *leaderScheduler) run(t *leaderTask) {
+	t.running.Lock()
+	defer t.running.Unlock()
+
+	s.mu.Lock()
+	paused := s.paused[t.name]
+	s.mu.Unlock()
+	if paused {
+		return
+	} else if s.registry == nil {
+		t.fn()
+		return
+	}
+	s.registry.run(t.name, func(*Task) { t.fn() })
+}
+
+// stop stops every running task.
+func (s *leaderScheduler) stop() {
+	s.setLeader(false)
+}
+
+// start runs t until the scheduler stops. s.mu must be held.
+func (s *leaderScheduler) start(t *leaderTask) {
+	stopping := s.stopping
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(t.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopping:
+				return
+			case <-ticker.C:
+				// Leadership may have been lost while waiting for the tick.
+				select {
+				case <-stopping:
+					return
+				default:
+				}
+				s.run(t)
+			}
+		}
+	}()
+}
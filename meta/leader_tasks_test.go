@@ -0,0 +1,149 @@
+package meta_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// Ensure the paused shard group reaper purges nothing until it is resumed.
+func TestMetaService_PauseLeaderTask(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	clock := &fakeClock{t: time.Now()}
+	cfg.Clock = clock
+	cfg.ShardGroupReapInterval = toml.Duration(10 * time.Millisecond)
+	s := openService(t, cfg)
+	defer os.RemoveAll(cfg.Dir)
+	defer s.Close()
+	c := newClient(s)
+	defer c.Close()
+
+	if _, err := c.CreateDataNode("foo:8180", "bar:8281"); err != nil {
+		t.Fatal(err)
+	} else if _, err := c.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+	sg, err := c.CreateShardGroup("db0", "default", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	} else if err := c.DeleteShardGroup("db0", "default", sg.ID); err != nil {
+		t.Fatal(err)
+	}
+	pending := c.PendingDeletions()
+	if len(pending) != 1 {
+		t.Fatalf("unexpected pending deletions: %+v", pending)
+	}
+
+	if err := c.PauseLeaderTask(cloudMeta.ShardGroupReaperTask); err != nil {
+		t.Fatal(err)
+	}
+	clock.Set(pending[0].PurgeAt.Add(time.Hour))
+	time.Sleep(100 * time.Millisecond)
+	if _, err := c.Refresh(); err != nil {
+		t.Fatal(err)
+	} else if n := len(c.PendingDeletions()); n != 1 {
+		t.Fatalf("shard group purged while the reaper was paused")
+	}
+
+	resp, err := http.Get("http://" + s.HTTPAddr() + "/debug/features")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var features map[string]bool
+	if err := json.NewDecoder(resp.Body).Decode(&features); err != nil {
+		t.Fatal(err)
+	} else if !features["shard-group-reaper-paused"] {
+		t.Fatalf("task not reported paused: %v", features)
+	} else if paused, ok := features["shard-precreator-paused"]; !ok || paused {
+		t.Fatalf("shard precreator not reported running: %v", features)
+	} else if paused, ok := features["heartbeat-checker-paused"]; !ok || paused {
+		t.Fatalf("heartbeat checker not reported running: %v", features)
+	}
+
+	if err := c.ResumeLeaderTask(cloudMeta.ShardGroupReaperTask); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; len(c.PendingDeletions()) != 0; i++ {
+		if i == 100 {
+			t.Fatal("timed out waiting for the shard group to be purged")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := c.PauseLeaderTask("precreator"); err != cloudMeta.ErrLeaderTaskNotFound {
+		t.Fatalf("unexpected error pausing an unknown task: %v", err)
+	}
+}
+
+// Ensure a leader task is paused on every meta server or none.
+func TestMetaService_PauseLeaderTask_AllOrNothing(t *testing.T) {
+	t.Parallel()
+
+	c := cloudMeta.NewTestClusterWithConfig(t, 2, func(i int, cfg *cloudMeta.Config) {
+		if i == 1 {
+			cfg.ShardGroupReapInterval = 0
+		}
+	})
+	defer c.Close()
+
+	if err := c.Client.PauseLeaderTask(cloudMeta.ShardGroupReaperTask); err != cloudMeta.ErrLeaderTaskNotFound {
+		t.Fatalf("unexpected error pausing a task one server doesn't run: %v", err)
+	}
+
+	resp, err := http.Get("http://" + c.Services[0].HTTPAddr() + "/debug/features")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var features map[string]bool
+	if err := json.NewDecoder(resp.Body).Decode(&features); err != nil {
+		t.Fatal(err)
+	} else if features["shard-group-reaper-paused"] {
+		t.Fatal("task left paused on a server after the pause failed")
+	}
+}
+
+// Ensure the leader creates the next shard group once the last one of a
+// retention policy ends within the advance period.
+func TestMetaService_PrecreateShardGroups(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	clock := &fakeClock{t: time.Now()}
+	cfg.Clock = clock
+	cfg.ShardPrecreationCheckInterval = toml.Duration(10 * time.Millisecond)
+	cfg.ShardPrecreationAdvancePeriod = toml.Duration(30 * time.Minute)
+	s := openService(t, cfg)
+	defer os.RemoveAll(cfg.Dir)
+	defer s.Close()
+	c := newClient(s)
+	defer c.Close()
+
+	if _, err := c.CreateDataNode("foo:8180", "bar:8281"); err != nil {
+		t.Fatal(err)
+	} else if _, err := c.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+	sg, err := c.CreateShardGroup("db0", "default", clock.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Set(sg.EndTime.Add(-10 * time.Minute))
+	for i := 0; ; i++ {
+		if next, _ := c.ShardGroupsByTimeRange("db0", "default", sg.EndTime.Add(time.Nanosecond), sg.EndTime.Add(time.Nanosecond)); len(next) == 1 && next[0].ID != sg.ID {
+			break
+		} else if i == 100 {
+			t.Fatal("timed out waiting for the next shard group to be precreated")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
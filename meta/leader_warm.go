@@ -0,0 +1,184 @@
+package meta
+
+import (
+	"sync"
+	"time"
+
+	"github.com/uber-go/zap"
+)
+
+// leaderWarmer is a step run when the node gains leadership, before it
+// reports itself ready.
+type leaderWarmer struct {
+	name string
+	fn   func()
+}
+
+// leaderWarmup runs the registered warmers each time the node gains
+// leadership, for at most timeout, and tracks whether it is still warming.
+// Warming up runs in the background and is cancelled when leadership is
+// lost, so raft's leadership notifications are never held up.
+type leaderWarmup struct {
+	mu      sync.Mutex
+	warmers []*leaderWarmer
+	warming bool
+	timeout time.Duration
+
+	// leader is whether the node gained leadership and hasn't lost it
+	// since, and cancel stops the warm-up in progress, if any.
+	leader bool
+	cancel chan struct{}
+
+	logger zap.Logger
+}
+
+func newLeaderWarmup(timeout time.Duration, logger zap.Logger) *leaderWarmup {
+	return &leaderWarmup{timeout: timeout, logger: logger}
+}
+
+// register adds a warmer, run from the next leadership change.
+func (w *leaderWarmup) register(lw *leaderWarmer) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.warmers = append(w.warmers, lw)
+}
+
+// isWarming returns true while the warmers run after gaining leadership.
+func (w *leaderWarmup) isWarming() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.warming
+}
+
+// start starts warming up in the background, unless the node is already
+// leading, and calls warm once the warmers finish or time out. warm isn't
+// called if stop is called first.
+func (w *leaderWarmup) start(warm func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.leader {
+		return
+	}
+	w.leader = true
+	w.warming = true
+	cancel := make(chan struct{})
+	w.cancel = cancel
+	warmers := append([]*leaderWarmer(nil), w.warmers...)
+
+	go func() {
+		if !w.run(warmers, cancel) {
+			return
+		}
+		// warm is called with w.mu held, so a concurrent stop either
+		// cancels it or comes after it.
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.cancel != cancel {
+			return
+		}
+		w.cancel = nil
+		w.warming = false
+		warm()
+	}()
+}
+
+// stop cancels the warm-up in progress, if any, as leadership was lost.
+func (w *leaderWarmup) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.cancel != nil {
+		close(w.cancel)
+		w.cancel = nil
+	}
+	w.leader = false
+	w.warming = false
+}
+
+// run runs every warmer concurrently and waits for them to finish or for the
+// timeout, whichever comes first. Warmers still running at the timeout are
+// left to finish in the background; the node becomes ready regardless. It
+// returns false if cancel is closed first.
+func (w *leaderWarmup) run(warmers []*leaderWarmer, cancel <-chan struct{}) bool {
+	if w.timeout == 0 || len(warmers) == 0 {
+		return true
+	}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, lw := range warmers {
+		wg.Add(1)
+		go func(lw *leaderWarmer) {
+			defer wg.Done()
+			lw.fn()
+		}(lw)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(w.timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		w.logger.Info("leader warm up complete", zap.Duration("took", time.Since(start)))
+	case <-timer.C:
+		w.logger.Info("leader warm up timed out, reporting ready", zap.Duration("timeout", w.timeout))
+	case <-cancel:
+		w.logger.Info("lost leadership while warming up")
+		return false
+	}
+	return true
+}
+
+// RegisterLeaderWarmer registers fn to be run when this node gains
+// leadership, before it reports ready on /ready and before the leader tasks
+// start. Warmers run concurrently and are bounded by leader-warm-timeout.
+func (s *Service) RegisterLeaderWarmer(name string, fn func()) {
+	s.leaderWarmup.register(&leaderWarmer{name: name, fn: fn})
+}
+
+// Ready returns false while the node is warming up after gaining leadership.
+func (s *Service) Ready() bool {
+	return !s.leaderWarmup.isWarming()
+}
+
+// leaderChanged warms up the node in the background when it gains
+// leadership, then starts the leader tasks if it still leads. On losing
+// leadership it stops them and drains the connections that carried writes.
+func (s *Service) leaderChanged(leader bool) {
+	if leader {
+		s.leaderWarmup.start(func() {
+			if s.store.isLeader() {
+				s.leaderTasks.setLeader(true)
+			}
+		})
+		return
+	}
+	s.leaderWarmup.stop()
+	s.leaderTasks.setLeader(false)
+
+	if s.config.LeaderDrainTimeout > 0 {
+		if n := s.writeConns.drain(time.Duration(s.config.LeaderDrainTimeout)); n > 0 {
+			s.Logger.Info("lost leadership, draining connections that carried writes", zap.Int("connections", n))
+		}
+	}
+}
+
+// warmState waits for the commands committed by previous leaders to be
+// applied, so the first reads served as leader reflect them.
+func (s *Service) warmState() {
+	if err := s.store.applied(time.Duration(s.config.LeaderWarmTimeout)); err != nil {
+		s.Logger.Info("leader warm up barrier failed", zap.Error(err))
+	}
+}
+
+// warmSnapshot encodes the metadata for the clients that poll for it again
+// once they find the new leader.
+func (s *Service) warmSnapshot() {
+	if _, err := s.store.marshaledSnapshot(); err != nil {
+		s.Logger.Info("leader warm up snapshot failed", zap.Error(err))
+	}
+}
@@ -0,0 +1,42 @@
+package meta
+
+import (
+	"testing"
+	"time"
+
+	"github.com/uber-go/zap"
+)
+
+// Ensure losing leadership while warming up cancels the warm-up, so the
+// node isn't marked warm, and that gaining it again warms up anew.
+func TestLeaderWarmup_Stop(t *testing.T) {
+	w := newLeaderWarmup(time.Second, zap.New(zap.NullEncoder()))
+	release := make(chan struct{})
+	w.register(&leaderWarmer{name: "blocked", fn: func() { <-release }})
+
+	warm := make(chan struct{}, 2)
+	w.start(func() { warm <- struct{}{} })
+	if !w.isWarming() {
+		t.Fatal("not warming after gaining leadership")
+	}
+	w.stop()
+	close(release)
+	select {
+	case <-warm:
+		t.Fatal("warmed up after losing leadership")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if w.isWarming() {
+		t.Fatal("still warming after losing leadership")
+	}
+
+	w.start(func() { warm <- struct{}{} })
+	select {
+	case <-warm:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the warm up")
+	}
+	if w.isWarming() {
+		t.Fatal("still warming once warm")
+	}
+}
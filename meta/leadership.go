@@ -0,0 +1,116 @@
+package meta
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/uber-go/zap"
+)
+
+// errAvoidingLeadership fails the raft RPCs of a node avoiding leadership.
+var errAvoidingLeadership = errors.New("avoiding leadership")
+
+// avoidingLeadership returns whether the node is to avoid leading the
+// cluster: while resigning leadership, in maintenance, or within an
+// avoid-leadership maintenance window.
+func (s *Service) avoidingLeadership() bool {
+	return atomic.LoadInt32(&s.resigning) > 0 || s.Maintenance() || s.InMaintenance(MaintenanceAvoidLeadership)
+}
+
+// ResignLeadership has this node, if it is the leader, transfer leadership
+// to another meta node while staying a member of the cluster, and returns
+// the raft address of the leader. The vendored raft can't hand leadership
+// to a given node, so the node avoids leadership until the followers elect
+// one of themselves: it sends no raft RPCs, so it steps down once its
+// leader-lease-timeout runs out. It returns ErrNoLeadershipTarget if no
+// other meta node could take over, and ErrLeadershipTransferTimeout if none
+// was elected within timeout.
+func (s *Service) ResignLeadership(timeout time.Duration) (string, error) {
+	if !s.store.isLeader() {
+		return s.store.leader(), nil
+	}
+	if st := s.store.raftStatus(); st == nil || len(st.Peers) < 2 {
+		return "", ErrNoLeadershipTarget
+	}
+
+	atomic.AddInt32(&s.resigning, 1)
+	defer atomic.AddInt32(&s.resigning, -1)
+	s.Logger.Info("resigning leadership")
+
+	deadline := time.Now().Add(timeout)
+	for {
+		if l := s.store.leader(); l != "" && l != s.raftAddr {
+			s.Logger.Info("resigned leadership", zap.String("leader", l))
+			return l, nil
+		}
+		if time.Now().After(deadline) {
+			return "", ErrLeadershipTransferTimeout
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// avoidingTransport fails every raft RPC the node sends while avoid
+// returns true. A follower only sends RPCs to ask for votes, so it can't
+// become leader, and a leader can't reach its followers, so it steps down.
+//
+// This is blunter than a leadership transfer, which the vendored raft
+// lacks. A leader that starts avoiding leadership commits nothing until it
+// steps down, so writes stall for up to leader-lease-timeout and then for
+// the election. A follower that stops hearing from a leader keeps starting
+// elections it can't send, raising its term each time; the higher term
+// then makes the leader it next answers step down, costing another
+// election.
+type avoidingTransport struct {
+	*raft.NetworkTransport
+	avoid func() bool
+}
+
+func (t *avoidingTransport) AppendEntriesPipeline(target string) (raft.AppendPipeline, error) {
+	if t.avoid() {
+		return nil, errAvoidingLeadership
+	}
+	p, err := t.NetworkTransport.AppendEntriesPipeline(target)
+	if err != nil {
+		return nil, err
+	}
+	return &avoidingPipeline{AppendPipeline: p, avoid: t.avoid}, nil
+}
+
+func (t *avoidingTransport) AppendEntries(target string, args *raft.AppendEntriesRequest, resp *raft.AppendEntriesResponse) error {
+	if t.avoid() {
+		return errAvoidingLeadership
+	}
+	return t.NetworkTransport.AppendEntries(target, args, resp)
+}
+
+func (t *avoidingTransport) RequestVote(target string, args *raft.RequestVoteRequest, resp *raft.RequestVoteResponse) error {
+	if t.avoid() {
+		return errAvoidingLeadership
+	}
+	return t.NetworkTransport.RequestVote(target, args, resp)
+}
+
+func (t *avoidingTransport) InstallSnapshot(target string, args *raft.InstallSnapshotRequest, resp *raft.InstallSnapshotResponse, data io.Reader) error {
+	if t.avoid() {
+		return errAvoidingLeadership
+	}
+	return t.NetworkTransport.InstallSnapshot(target, args, resp, data)
+}
+
+// avoidingPipeline fails the entries sent through a pipeline opened before
+// the node started avoiding leadership.
+type avoidingPipeline struct {
+	raft.AppendPipeline
+	avoid func() bool
+}
+
+func (p *avoidingPipeline) AppendEntries(args *raft.AppendEntriesRequest, resp *raft.AppendEntriesResponse) (raft.AppendFuture, error) {
+	if p.avoid() {
+		return nil, errAvoidingLeadership
+	}
+	return p.AppendPipeline.AppendEntries(args, resp)
+}
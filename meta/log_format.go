@@ -0,0 +1,257 @@
+package meta
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uber-go/zap"
+)
+
+// NewLogger returns a logger for service that writes to w in format. Text
+// lines start with prefix, as they always have. The structured formats
+// leave the prefix out and log service, and nodeID if it isn't zero, as
+// fields of each entry instead.
+func NewLogger(w io.Writer, format, prefix, service string, nodeID uint64) *log.Logger {
+	if format == "" || format == LogFormatText {
+		return log.New(w, prefix, log.LstdFlags)
+	}
+	return log.New(&logWriter{w: w, format: format, fields: logFields(service, nodeID)}, "", 0)
+}
+
+// ReformatLogger makes l log as NewLogger(w, format, prefix, service,
+// nodeID) would, in place, so everything holding l picks up the change.
+func ReformatLogger(l *log.Logger, w io.Writer, format, prefix, service string, nodeID uint64) {
+	n := NewLogger(w, format, prefix, service, nodeID)
+	l.SetOutput(n.Writer())
+	l.SetPrefix(n.Prefix())
+	l.SetFlags(n.Flags())
+}
+
+// newZapLogger returns a zap logger for service that writes to w in format.
+func newZapLogger(w io.Writer, format, service string, nodeID uint64) zap.Logger {
+	if format == "" || format == LogFormatText {
+		return zap.New(zap.NewTextEncoder(), zap.Output(zap.AddSync(w)))
+	}
+	enc := zap.NewJSONEncoder(
+		zap.LevelString("level"),
+		zap.MessageKey("msg"),
+		zap.TimeFormatter(func(t time.Time) zap.Field {
+			return zap.String("ts", t.UTC().Format(time.RFC3339Nano))
+		}),
+	)
+	var fields []zap.Field
+	for k, v := range logFields(service, nodeID) {
+		fields = append(fields, zap.String(k, v.(string)))
+	}
+	return zap.New(enc, zap.Output(zap.AddSync(&zapLogWriter{w: w, format: format})), zap.Fields(fields...))
+}
+
+// logFields returns the fields every structured entry of service carries.
+func logFields(service string, nodeID uint64) map[string]interface{} {
+	fields := map[string]interface{}{"service": service}
+	if nodeID != 0 {
+		fields["node_id"] = strconv.FormatUint(nodeID, 10)
+	}
+	return fields
+}
+
+// logWriter turns the lines of a stdlib logger into structured entries.
+type logWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	format string
+	fields map[string]interface{}
+}
+
+func (lw *logWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimRight(string(p), "\n")
+	entry := make(map[string]interface{}, len(lw.fields)+3)
+	for k, v := range lw.fields {
+		entry[k] = v
+	}
+	entry["level"], entry["msg"] = logLevel(msg)
+	entry["ts"] = now().UTC().Format(time.RFC3339Nano)
+
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	if _, err := lw.w.Write(encodeLogEntry(lw.format, entry)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// logLevel returns the level of a free-text log line, going by the markers
+// raft and this package start their warnings and errors with, and the line
+// without its marker.
+func logLevel(msg string) (string, string) {
+	for _, l := range []struct{ marker, level string }{
+		{"[ERR] ", "error"},
+		{"[WARN] ", "warn"},
+		{"[INFO] ", "info"},
+		{"[DEBUG] ", "debug"},
+		{"ERROR: ", "error"},
+		{"WARNING: ", "warn"},
+	} {
+		if strings.HasPrefix(msg, l.marker) {
+			return l.level, strings.TrimPrefix(msg, l.marker)
+		}
+	}
+	return "info", msg
+}
+
+// zapLogWriter re-encodes the JSON entries of a zap logger in format, so
+// they match the entries of the stdlib loggers.
+type zapLogWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	format string
+}
+
+func (zw *zapLogWriter) Write(p []byte) (int, error) {
+	var entry map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(p))
+	dec.UseNumber()
+	if err := dec.Decode(&entry); err != nil {
+		return 0, err
+	}
+
+	zw.mu.Lock()
+	defer zw.mu.Unlock()
+	if _, err := zw.w.Write(encodeLogEntry(zw.format, entry)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Sync implements zap.WriteSyncer.
+func (zw *zapLogWriter) Sync() error { return nil }
+
+// encodeLogEntry encodes entry as a line in format. The level, time and
+// message come first, followed by the other fields in key order.
+func encodeLogEntry(format string, entry map[string]interface{}) []byte {
+	keys := make([]string, 0, len(entry))
+	for _, k := range []string{"level", "ts", "msg"} {
+		if _, ok := entry[k]; ok {
+			keys = append(keys, k)
+		}
+	}
+	var rest []string
+	for k := range entry {
+		if k != "level" && k != "ts" && k != "msg" {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+	keys = append(keys, rest...)
+
+	var buf bytes.Buffer
+	if format == LogFormatJSON {
+		buf.WriteByte('{')
+	}
+	for i, k := range keys {
+		if format == LogFormatJSON {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			kb, _ := json.Marshal(k)
+			vb, _ := json.Marshal(entry[k])
+			buf.Write(kb)
+			buf.WriteByte(':')
+			buf.Write(vb)
+			continue
+		}
+
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(k)
+		buf.WriteByte('=')
+		buf.WriteString(logfmtValue(entry[k]))
+	}
+	if format == LogFormatJSON {
+		buf.WriteByte('}')
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
+// logfmtValue formats v as a logfmt value, quoting it if it is empty or
+// holds spaces, quotes, equals signs or control characters.
+func logfmtValue(v interface{}) string {
+	var s string
+	switch v := v.(type) {
+	case string:
+		s = v
+	default:
+		b, _ := json.Marshal(v)
+		s = string(b)
+	}
+	if s == "" || strings.IndexFunc(s, func(r rune) bool {
+		return r <= ' ' || r == '"' || r == '=' || r == '\\'
+	}) >= 0 {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// switchLogger is a zap logger that logs through another one, which may be
+// switched while it is in use, as when the log format is reloaded. Loggers
+// made with With log through whatever it was switched to, too.
+type switchLogger struct {
+	target *switchTarget
+	fields []zap.Field
+}
+
+type switchTarget struct {
+	mu sync.RWMutex
+	l  zap.Logger
+}
+
+func newSwitchLogger(l zap.Logger) *switchLogger {
+	return &switchLogger{target: &switchTarget{l: l}}
+}
+
+// set switches the logger logged through to l.
+func (s *switchLogger) set(l zap.Logger) {
+	s.target.mu.Lock()
+	defer s.target.mu.Unlock()
+	s.target.l = l
+}
+
+func (s *switchLogger) logger() zap.Logger {
+	s.target.mu.RLock()
+	l := s.target.l
+	s.target.mu.RUnlock()
+	if len(s.fields) > 0 {
+		l = l.With(s.fields...)
+	}
+	return l
+}
+
+func (s *switchLogger) With(fields ...zap.Field) zap.Logger {
+	f := make([]zap.Field, 0, len(s.fields)+len(fields))
+	return &switchLogger{target: s.target, fields: append(append(f, s.fields...), fields...)}
+}
+
+func (s *switchLogger) Check(lvl zap.Level, msg string) *zap.CheckedMessage {
+	return s.logger().Check(lvl, msg)
+}
+
+func (s *switchLogger) Log(lvl zap.Level, msg string, fields ...zap.Field) {
+	s.logger().Log(lvl, msg, fields...)
+}
+
+func (s *switchLogger) Debug(msg string, fields ...zap.Field)  { s.logger().Debug(msg, fields...) }
+func (s *switchLogger) Info(msg string, fields ...zap.Field)   { s.logger().Info(msg, fields...) }
+func (s *switchLogger) Warn(msg string, fields ...zap.Field)   { s.logger().Warn(msg, fields...) }
+func (s *switchLogger) Error(msg string, fields ...zap.Field)  { s.logger().Error(msg, fields...) }
+func (s *switchLogger) Panic(msg string, fields ...zap.Field)  { s.logger().Panic(msg, fields...) }
+func (s *switchLogger) Fatal(msg string, fields ...zap.Field)  { s.logger().Fatal(msg, fields...) }
+func (s *switchLogger) DFatal(msg string, fields ...zap.Field) { s.logger().DFatal(msg, fields...) }
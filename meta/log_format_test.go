@@ -0,0 +1,70 @@
+package meta
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// Ensure stdlib loggers emit one JSON object per line in the json format.
+func TestNewLogger_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger(&buf, LogFormatJSON, "[metastore] ", "metastore", 3)
+	l.Printf("Using data dir: %s", "/var/lib/meta")
+	l.Println("[WARN] raft: Heartbeat timeout reached")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("unexpected log output:\n%s", buf.String())
+	}
+	for i, exp := range []map[string]string{
+		{"level": "info", "msg": "Using data dir: /var/lib/meta", "service": "metastore", "node_id": "3"},
+		{"level": "warn", "msg": "raft: Heartbeat timeout reached", "service": "metastore", "node_id": "3"},
+	} {
+		var entry map[string]string
+		if err := json.Unmarshal([]byte(lines[i]), &entry); err != nil {
+			t.Fatalf("line %d is not JSON: %s: %q", i, err, lines[i])
+		}
+		if entry["ts"] == "" {
+			t.Fatalf("line %d has no time: %q", i, lines[i])
+		}
+		delete(entry, "ts")
+		if len(entry) != len(exp) {
+			t.Fatalf("unexpected entry: %v", entry)
+		}
+		for k, v := range exp {
+			if entry[k] != v {
+				t.Fatalf("unexpected %s: got %q, expected %q", k, entry[k], v)
+			}
+		}
+	}
+}
+
+// Ensure the service's zap logger and the stdlib loggers log the same fields
+// in the logfmt format.
+func TestNewLogger_Logfmt(t *testing.T) {
+	var buf bytes.Buffer
+	NewLogger(&buf, LogFormatLogfmt, "", "server", 0).Println("Opening Server for meta service")
+	newZapLogger(&buf, LogFormatLogfmt, "meta", 2).Info("leader warm up complete")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("unexpected log output:\n%s", buf.String())
+	}
+	if !strings.HasPrefix(lines[0], "level=info ts=") || !strings.HasSuffix(lines[0], ` msg="Opening Server for meta service" service=server`) {
+		t.Fatalf("unexpected line: %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "level=info ts=") || !strings.HasSuffix(lines[1], ` msg="leader warm up complete" node_id=2 service=meta`) {
+		t.Fatalf("unexpected line: %q", lines[1])
+	}
+}
+
+// Ensure the text format is left as it always was.
+func TestNewLogger_Text(t *testing.T) {
+	var buf bytes.Buffer
+	NewLogger(&buf, LogFormatText, "[metaclient] ", "metaclient", 1).Println("redirect to leader")
+	if !strings.HasPrefix(buf.String(), "[metaclient] ") || !strings.HasSuffix(buf.String(), " redirect to leader\n") {
+		t.Fatalf("unexpected line: %q", buf.String())
+	}
+}
@@ -0,0 +1,133 @@
+package meta
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// logTailBuffer is how many log lines a tailer may fall behind by before
+// lines are dropped for it.
+const logTailBuffer = 256
+
+// errTooManyTailers is returned when subscribing to a log tail that already
+// has its maximum number of tailers.
+var errTooManyTailers = errors.New("too many log tailers")
+
+// logTail writes everything written to it to w, and fans each write out to
+// the tailers subscribed. The loggers write one entry per write. A tailer
+// that falls behind misses lines rather than holding up the loggers.
+type logTail struct {
+	w io.Writer
+
+	mu      sync.Mutex
+	tailers map[chan []byte]struct{}
+	max     int
+}
+
+// newLogTail returns a log tail writing to w that allows up to max tailers.
+func newLogTail(w io.Writer, max int) *logTail {
+	return &logTail{
+		w:       w,
+		tailers: make(map[chan []byte]struct{}),
+		max:     max,
+	}
+}
+
+func (t *logTail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	if len(t.tailers) > 0 {
+		b := make([]byte, len(p))
+		copy(b, p)
+		for ch := range t.tailers {
+			select {
+			case ch <- b:
+			default:
+			}
+		}
+	}
+	t.mu.Unlock()
+
+	return t.w.Write(p)
+}
+
+// subscribe returns a channel receiving every write from now on, or
+// errTooManyTailers. The channel must be released with unsubscribe.
+func (t *logTail) subscribe() (chan []byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.tailers) >= t.max {
+		return nil, errTooManyTailers
+	}
+	ch := make(chan []byte, logTailBuffer)
+	t.tailers[ch] = struct{}{}
+	return ch, nil
+}
+
+// unsubscribe stops sending writes to ch.
+func (t *logTail) unsubscribe(ch chan []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.tailers, ch)
+}
+
+// serveLogTail streams the service's log lines as server-sent events, one
+// event per line, until the client goes away or the service closes. The
+// request must carry log-tail-token as a bearer token.
+func (h *handler) serveLogTail(w http.ResponseWriter, r *http.Request) {
+	if h.config.LogTailToken == "" {
+		http.Error(w, "log tail disabled: log-tail-token is not set", http.StatusForbidden)
+		return
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.config.LogTailToken)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="meta"`)
+		http.Error(w, "invalid log tail token", http.StatusUnauthorized)
+		return
+	}
+
+	var tail *logTail
+	if h.s != nil {
+		tail = h.s.logTail
+	}
+	if tail == nil {
+		http.Error(w, "log output not set", http.StatusServiceUnavailable)
+		return
+	}
+	ch, err := tail.subscribe()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	defer tail.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flush := func() {
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+	flush()
+
+	for {
+		select {
+		case b := <-ch:
+			for _, line := range strings.Split(strings.TrimRight(string(b), "\n"), "\n") {
+				if _, err := fmt.Fprintf(w, "data: %s\n\n", line); err != nil {
+					return
+				}
+			}
+			flush()
+		case <-r.Context().Done():
+			return
+		case <-h.closing:
+			return
+		}
+	}
+}
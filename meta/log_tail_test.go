@@ -0,0 +1,92 @@
+package meta_test
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Ensure /debug/log/tail streams log lines emitted after the client
+// connected, to clients with the token only, up to the configured number.
+func TestMetaService_LogTail(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	cfg.LogTailToken = "s3cret"
+	cfg.LogTailMaxTailers = 1
+	s := newService(cfg)
+	s.SetLogOutput(ioutil.Discard)
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	url := "http://" + s.HTTPAddr() + "/debug/log/tail"
+	tail := func(token string) *http.Response {
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	for _, token := range []string{"", "wrong"} {
+		resp := tail(token)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("token %q: unexpected status: %s", token, resp.Status)
+		}
+	}
+
+	resp := tail("s3cret")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status: %s", resp.Status)
+	} else if typ := resp.Header.Get("Content-Type"); typ != "text/event-stream" {
+		t.Fatalf("unexpected content type: %s", typ)
+	}
+
+	if other := tail("s3cret"); other.StatusCode != http.StatusTooManyRequests {
+		other.Body.Close()
+		t.Fatalf("unexpected status for a second tailer: %s", other.Status)
+	} else {
+		other.Body.Close()
+	}
+
+	s.Logger.Info("log tail test line")
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatal("stream ended before the log line was received")
+			}
+			if strings.HasPrefix(line, "data: ") && strings.Contains(line, "log tail test line") {
+				return
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for the log line")
+		}
+	}
+}
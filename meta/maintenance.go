@@ -0,0 +1,226 @@
+package meta
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+	"github.com/uber-go/zap"
+)
+
+// What a meta node does during a maintenance window, as set by
+// MaintenanceWindow.Mode.
+const (
+	// MaintenanceReadOnly refuses writes with ErrMaintenanceReadOnly.
+	MaintenanceReadOnly = "read-only"
+
+	// MaintenanceAvoidLeadership keeps the node from leading the cluster:
+	// it sends no raft RPCs, so it can't win an election, and a leader
+	// steps down once it can't reach a quorum within leader-lease-timeout.
+	// Writes stall until then and a new leader is elected, and the window
+	// closing may cost another election; see avoidingTransport. A node
+	// that is the only meta node still leads.
+	MaintenanceAvoidLeadership = "avoid-leadership"
+)
+
+// maintenanceCheckInterval is how often the service checks whether it
+// entered or left a maintenance window, to log it.
+const maintenanceCheckInterval = time.Second
+
+// ErrMaintenanceReadOnly is returned for writes to a meta node within a
+// read-only maintenance window.
+var ErrMaintenanceReadOnly = errors.New("meta node is in a read-only maintenance window")
+
+// ErrMaintenance is returned for writes to a meta node put in maintenance
+// with SetMaintenance.
+var ErrMaintenance = errors.New("meta node is in maintenance")
+
+// maintenanceRefused are the handlers that change the metadata, which a
+// node in maintenance refuses.
+var maintenanceRefused = map[string]bool{
+	"execute":          true,
+	"join":             true,
+	"update-meta-node": true,
+	"drop-node":        true,
+}
+
+// Clock tells the time.
+type Clock interface {
+	Now() time.Time
+}
+
+// weekdays are the days of MaintenanceWindow.Days.
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// MaintenanceWindow is a recurring time window during which the node is in
+// maintenance, as set by a [[maintenance-window]] table.
+type MaintenanceWindow struct {
+	// Days are the days of the week, as "mon" to "sun", the window opens
+	// on. It opens every day if there are none.
+	Days []string `toml:"days"`
+
+	// Start is when the window opens, as "15:04" in UTC, and Duration how
+	// long it stays open, up to a week. A window may run past midnight.
+	Start    string        `toml:"start"`
+	Duration toml.Duration `toml:"duration"`
+
+	// Mode is MaintenanceReadOnly or MaintenanceAvoidLeadership.
+	Mode string `toml:"mode"`
+}
+
+// validate returns what is wrong with the window, if anything.
+func (w *MaintenanceWindow) validate() error {
+	for _, d := range w.Days {
+		if _, ok := weekdays[strings.ToLower(d)]; !ok {
+			return fmt.Errorf("unknown day %q, expected mon to sun", d)
+		}
+	}
+	if _, err := time.Parse("15:04", w.Start); err != nil {
+		return fmt.Errorf("start %q is not a time of day as 15:04", w.Start)
+	}
+	if w.Duration <= 0 || time.Duration(w.Duration) > 7*24*time.Hour {
+		return fmt.Errorf("duration must be positive and at most a week, got %s", w.Duration)
+	}
+	if w.Mode != MaintenanceReadOnly && w.Mode != MaintenanceAvoidLeadership {
+		return fmt.Errorf("mode must be %q or %q, got %q", MaintenanceReadOnly, MaintenanceAvoidLeadership, w.Mode)
+	}
+	return nil
+}
+
+// contains returns whether the window is open at t.
+func (w *MaintenanceWindow) contains(t time.Time) bool {
+	start, err := time.Parse("15:04", w.Start)
+	if err != nil {
+		return false
+	}
+	t = t.UTC()
+
+	// The window may have opened on any of the past week's days.
+	for i := 0; i <= 7; i++ {
+		day := t.AddDate(0, 0, -i)
+		open := time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), 0, 0, time.UTC)
+		if !w.opensOn(open.Weekday()) {
+			continue
+		}
+		if !t.Before(open) && t.Before(open.Add(time.Duration(w.Duration))) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *MaintenanceWindow) opensOn(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if weekdays[strings.ToLower(d)] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// InMaintenance returns whether the node is within a maintenance window of
+// mode, by the Clock of its config.
+func (s *Service) InMaintenance(mode string) bool {
+	t := s.now()
+	for i := range s.config.MaintenanceWindows {
+		if w := &s.config.MaintenanceWindows[i]; w.Mode == mode && w.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetMaintenance puts the node in maintenance, or takes it out, until the
+// service is closed. In maintenance the node refuses writes with 503
+// Service Unavailable, so clients turn to another meta server, reports
+// "maintenance" on /ready and avoids leadership as in an avoid-leadership
+// window: a leader steps down within leader-lease-timeout, stalling the
+// cluster's writes until another node is elected. It stays a member of the
+// cluster and keeps replicating as a follower.
+func (s *Service) SetMaintenance(on bool) {
+	var v int32
+	if on {
+		v = 1
+	}
+	if atomic.SwapInt32(&s.maintenance, v) == v {
+		return
+	}
+	if !on {
+		s.Logger.Info("left maintenance")
+	} else if s.IsLeader() {
+		s.Logger.Info("entered maintenance, stepping down as leader")
+	} else {
+		s.Logger.Info("entered maintenance")
+	}
+}
+
+// Maintenance returns whether the node was put in maintenance with
+// SetMaintenance.
+func (s *Service) Maintenance() bool {
+	return atomic.LoadInt32(&s.maintenance) == 1
+}
+
+// refusingMaintenance answers 503 Service Unavailable to the requests to
+// the handler name that a node in maintenance refuses.
+func refusingMaintenance(inner http.Handler, name string, h *handler) http.Handler {
+	if !maintenanceRefused[name] {
+		return inner
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.s != nil && h.s.Maintenance() {
+			h.httpError(ErrMaintenance, w, http.StatusServiceUnavailable)
+			return
+		}
+		inner.ServeHTTP(w, r)
+	})
+}
+
+// now returns the time by the Clock of the service's config.
+func (s *Service) now() time.Time {
+	if s.config.Clock != nil {
+		return s.config.Clock.Now()
+	}
+	return now()
+}
+
+// watchMaintenanceWindows logs the node entering and leaving its
+// maintenance windows, until the service is closed.
+func (s *Service) watchMaintenanceWindows() {
+	ticker := time.NewTicker(maintenanceCheckInterval)
+	defer ticker.Stop()
+
+	in := make(map[string]bool)
+	for {
+		for _, mode := range []string{MaintenanceReadOnly, MaintenanceAvoidLeadership} {
+			if open := s.InMaintenance(mode); open != in[mode] {
+				in[mode] = open
+				if open {
+					s.Logger.Info("entered maintenance window", zap.String("mode", mode))
+				} else {
+					s.Logger.Info("left maintenance window", zap.String("mode", mode))
+				}
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-s.closing:
+			return
+		}
+	}
+}
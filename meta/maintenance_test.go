@@ -0,0 +1,225 @@
+package meta_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// Ensure a node refuses writes within a read-only maintenance window, one
+// running past midnight included, and accepts them again once it closes.
+func TestMetaService_MaintenanceWindow_ReadOnly(t *testing.T) {
+	t.Parallel()
+
+	// 2026-10-17 is a Saturday.
+	clock := &fakeClock{t: time.Date(2026, 10, 17, 22, 59, 0, 0, time.UTC)}
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	cfg.Clock = clock
+	cfg.MaintenanceWindows = []cloudMeta.MaintenanceWindow{
+		{Days: []string{"sat"}, Start: "23:00", Duration: toml.Duration(2 * time.Hour), Mode: cloudMeta.MaintenanceReadOnly},
+	}
+	s := openService(t, cfg)
+	defer s.Close()
+	c := newClient(s)
+	defer c.Close()
+
+	if s.InMaintenance(cloudMeta.MaintenanceReadOnly) {
+		t.Fatal("in maintenance before the window opened")
+	} else if _, err := c.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, at := range []time.Time{
+		time.Date(2026, 10, 17, 23, 0, 0, 0, time.UTC),
+		time.Date(2026, 10, 18, 0, 30, 0, 0, time.UTC),
+	} {
+		clock.Set(at)
+		if !s.InMaintenance(cloudMeta.MaintenanceReadOnly) {
+			t.Fatalf("not in maintenance at %s", at)
+		} else if s.InMaintenance(cloudMeta.MaintenanceAvoidLeadership) {
+			t.Fatalf("avoiding leadership at %s", at)
+		}
+		if _, err := c.CreateDatabase("db1"); err != cloudMeta.ErrMaintenanceReadOnly {
+			t.Fatalf("unexpected error writing at %s: %v", at, err)
+		}
+	}
+
+	// The window opens on Saturdays only.
+	clock.Set(time.Date(2026, 10, 18, 23, 30, 0, 0, time.UTC))
+	if s.InMaintenance(cloudMeta.MaintenanceReadOnly) {
+		t.Fatal("in maintenance on a Sunday")
+	} else if _, err := c.CreateDatabase("db1"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Ensure a leader steps down within an avoid-leadership maintenance window,
+// and doesn't lead again until it closes.
+func TestMetaService_MaintenanceWindow_AvoidLeadership(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2026, 10, 17, 11, 0, 0, 0, time.UTC)
+	clocks := make([]*fakeClock, 3)
+	c := cloudMeta.NewTestClusterWithConfig(t, 3, func(i int, cfg *cloudMeta.Config) {
+		clocks[i] = &fakeClock{t: start}
+		cfg.Clock = clocks[i]
+		cfg.MaintenanceWindows = []cloudMeta.MaintenanceWindow{
+			{Start: "12:00", Duration: toml.Duration(time.Hour), Mode: cloudMeta.MaintenanceAvoidLeadership},
+		}
+	})
+	defer c.Close()
+
+	leader := c.Leader(5 * time.Second)
+	var i int
+	for i = range c.Services {
+		if c.Services[i] == leader {
+			break
+		}
+	}
+
+	clocks[i].Set(start.Add(90 * time.Minute))
+	if !leader.InMaintenance(cloudMeta.MaintenanceAvoidLeadership) {
+		t.Fatal("leader not in maintenance")
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if l := c.Leader(time.Second); l != nil && l != leader {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatal("leader didn't step down")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// It stays a follower for as long as the window is open.
+	time.Sleep(2 * time.Second)
+	if leader.IsLeader() {
+		t.Fatal("node in maintenance is leading again")
+	}
+
+	clocks[i].Set(start.Add(3 * time.Hour))
+	if leader.InMaintenance(cloudMeta.MaintenanceAvoidLeadership) {
+		t.Fatal("in maintenance after the window closed")
+	}
+	if _, err := c.Client.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Ensure a leader put in maintenance over the HTTP API steps down, refuses
+// writes and isn't ready, but keeps replicating, and is back to normal once
+// taken out.
+func TestMetaService_SetMaintenance(t *testing.T) {
+	t.Parallel()
+
+	c := cloudMeta.NewTestCluster(t, 3)
+	defer c.Close()
+
+	leader := c.Leader(5 * time.Second)
+	setMaintenance := func(enabled string) {
+		resp, err := http.Post("http://"+leader.HTTPAddr()+"/cluster/maintenance?enabled="+enabled, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNoContent {
+			t.Fatalf("unexpected status: %s", resp.Status)
+		}
+	}
+	ready := func() (int, string) {
+		resp, err := http.Get("http://" + leader.HTTPAddr() + "/ready")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var health struct {
+			Status string `json:"status"`
+		}
+		json.NewDecoder(resp.Body).Decode(&health)
+		return resp.StatusCode, health.Status
+	}
+
+	setMaintenance("true")
+	if !leader.Maintenance() {
+		t.Fatal("node not in maintenance")
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if l := c.Leader(time.Second); l != nil && l != leader {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatal("leader didn't step down")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	if code, status := ready(); code != http.StatusServiceUnavailable || status != "maintenance" {
+		t.Fatalf("unexpected readiness in maintenance: %d %q", code, status)
+	}
+	resp, err := http.Post("http://"+leader.HTTPAddr()+"/execute", "application/octet-stream", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected write status in maintenance: %s", resp.Status)
+	}
+
+	// Writes through the other nodes still replicate to it.
+	if _, err := c.Client.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = http.Get("http://" + c.Leader(time.Second).HTTPAddr() + "/raft-status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var st struct {
+		CommitIndex uint64 `json:"commitIndex"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&st)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := leader.WaitForApplied(st.CommitIndex, 10*time.Second); err != nil {
+		t.Fatalf("write not replicated to the node in maintenance: %v", err)
+	}
+
+	setMaintenance("false")
+	deadline = time.Now().Add(10 * time.Second)
+	for {
+		if code, status := ready(); code == http.StatusOK {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("not ready after leaving maintenance: %d %q", code, status)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// Ensure the restart lock expires by the leader's clock rather than the
+// client's.
+func TestMetaService_RestartLock_LeaderTime(t *testing.T) {
+	t.Parallel()
+
+	acquired := time.Date(2026, 10, 17, 11, 0, 0, 0, time.UTC)
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	cfg.Clock = &fakeClock{t: acquired}
+	s := openService(t, cfg)
+	defer s.Close()
+	c := newClient(s)
+	defer c.Close()
+
+	if err := c.AcquireRestartLock("orchestrator-a", 1, time.Minute); err != nil {
+		t.Fatal(err)
+	} else if l := c.RestartLock(); l == nil || !l.Expiration.Equal(acquired.Add(time.Minute)) {
+		t.Fatalf("unexpected restart lock: %+v", l)
+	}
+}
@@ -0,0 +1,168 @@
+package meta
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/zhexuany/influxcloud/meta/internal"
+)
+
+// MetadataVersion is the version of the metadata schema this build reads
+// and writes, the one of the latest of metadataReleases. It is raised when
+// a release changes the metadata in a way older releases can't follow.
+const MetadataVersion uint64 = 1
+
+// metadataReleases lists, oldest first, the first release reading and
+// writing each metadata version.
+var metadataReleases = []struct {
+	release string
+	version uint64
+}{
+	{"0.0.0", 1},
+}
+
+// MetadataVersion returns the version of the metadata schema the build
+// reads and writes, the one of the latest of metadataReleases it is at or
+// past. A build of no release, as a development build, has this package's
+// MetadataVersion.
+func (info BuildInfo) MetadataVersion() uint64 {
+	release, ok := parseRelease(info.Version)
+	if !ok {
+		return MetadataVersion
+	}
+	version := MetadataVersion
+	for _, r := range metadataReleases {
+		if first, _ := parseRelease(r.release); compareReleases(release, first) < 0 {
+			break
+		}
+		version = r.version
+	}
+	return version
+}
+
+// parseRelease returns the major, minor and patch numbers of a release
+// version, as "1.2.3", "v1.2" or "1.2.3-rc1", and whether v is one.
+func parseRelease(v string) ([3]uint64, bool) {
+	var release [3]uint64
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+~"); i >= 0 {
+		v = v[:i]
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) > len(release) {
+		return release, false
+	}
+	for i, p := range parts {
+		n, err := strconv.ParseUint(p, 10, 64)
+		if err != nil {
+			return release, false
+		}
+		release[i] = n
+	}
+	return release, true
+}
+
+// compareReleases returns -1, 0 or 1 as a is before, the same as or after b.
+func compareReleases(a, b [3]uint64) int {
+	for i := range a {
+		if a[i] < b[i] {
+			return -1
+		} else if a[i] > b[i] {
+			return 1
+		}
+	}
+	return 0
+}
+
+// checkMetadataVersion returns an error if a node reading and writing
+// metadata version node may not take part in a cluster whose metadata is at
+// version cluster, as the two are more than tolerance versions apart. A
+// cluster created before the version was recorded has version zero, and is
+// accepted.
+func checkMetadataVersion(cluster, node uint64, tolerance int) error {
+	if cluster == 0 {
+		return nil
+	}
+	diff := cluster - node
+	if cluster < node {
+		diff = node - cluster
+	}
+	if diff > uint64(tolerance) {
+		return fmt.Errorf("metadata version %d of the cluster is incompatible with version %d of this node, more than metadata-version-tolerance %d apart",
+			cluster, node, tolerance)
+	}
+	return nil
+}
+
+// SetBuildInfo sets the build the node runs, whose metadata version the
+// client checks the cluster's against.
+func (c *Client) SetBuildInfo(info BuildInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.metadataVersion = info.MetadataVersion()
+}
+
+// NodeMetadataVersion returns the version of the metadata schema the node
+// reads and writes, by the build SetBuildInfo set.
+func (c *Client) NodeMetadataVersion() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.metadataVersion == 0 {
+		return MetadataVersion
+	}
+	return c.metadataVersion
+}
+
+// MetadataVersion returns the version of the metadata schema of the
+// cluster, or zero if the cluster predates it being recorded.
+func (c *Client) MetadataVersion() uint64 {
+	return c.data().MetadataVersion
+}
+
+// CheckMetadataVersion returns an error if the metadata version of the
+// cluster is more than metadata-version-tolerance away from the one of this
+// node.
+func (c *Client) CheckMetadataVersion() error {
+	return checkMetadataVersion(c.MetadataVersion(), c.NodeMetadataVersion(), c.config.MetadataVersionTolerance)
+}
+
+// RaiseMetadataVersion raises the metadata version of the cluster to
+// version, once every node runs a build reading and writing it, so nodes of
+// the builds before fall out of metadata-version-tolerance in turn. It
+// refuses a version past the one of this node, and the cluster refuses to
+// lower its version with ErrMetadataVersionLowered.
+func (c *Client) RaiseMetadataVersion(version uint64) error {
+	if node := c.NodeMetadataVersion(); version > node {
+		return fmt.Errorf("metadata version %d is past version %d of this node", version, node)
+	}
+	return c.retryUntilExec(internal.Command_SetMetadataVersionCommand, internal.E_SetMetadataVersionCommand_Command,
+		&internal.SetMetadataVersionCommand{
+			Version: proto.Uint64(version),
+		},
+	)
+}
+
+// RaiseMetadataVersion sets the metadata version of the cluster to version,
+// returning ErrMetadataVersionLowered if it is below the current one.
+func (data *Data) RaiseMetadataVersion(version uint64) error {
+	if version < data.MetadataVersion {
+		return ErrMetadataVersionLowered
+	}
+	data.MetadataVersion = version
+	return nil
+}
+
+func (fsm *storeFSM) applySetMetadataVersionCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_SetMetadataVersionCommand_Command)
+	v := ext.(*internal.SetMetadataVersionCommand)
+
+	other := fsm.data.Clone()
+	if err := other.RaiseMetadataVersion(v.GetVersion()); err != nil {
+		return err
+	}
+
+	fsm.data = other
+	return nil
+}
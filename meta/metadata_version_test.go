@@ -0,0 +1,82 @@
+package meta_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// Ensure a cluster records the metadata version it was created with, and a
+// node refuses to join one whose version is beyond its tolerance.
+func TestClient_MetadataVersion(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	if v := c.MetadataVersion(); v != cloudMeta.MetadataVersion {
+		t.Fatalf("unexpected metadata version: %d", v)
+	} else if err := c.CheckMetadataVersion(); err != nil {
+		t.Fatal(err)
+	}
+
+	data := c.Data()
+	data.MetadataVersion = cloudMeta.MetadataVersion + 2
+	if err := c.SetData(data); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	cfg.BindAddress = "127.0.0.1:8088"
+	cfg.HTTPBindAddress = "127.0.0.1:8091"
+	joiner := cloudMeta.NewClient(cfg)
+	if _, err := joiner.JoinCluster(s.HTTPAddr()); err == nil || !strings.Contains(err.Error(), "metadata version") {
+		t.Fatalf("unexpected join error: %v", err)
+	}
+	if n := len(c.Data().MetaNodes); n != 1 {
+		t.Fatalf("node joined despite its metadata version: %d meta nodes", n)
+	}
+}
+
+// Ensure a build's metadata version is the one of its release, and a
+// development build's the one of the package.
+func TestBuildInfo_MetadataVersion(t *testing.T) {
+	for _, v := range []string{"", "unknown", "0.1.0", "v1.2", "1.2.3-rc1"} {
+		if got := (cloudMeta.BuildInfo{Version: v}).MetadataVersion(); got != cloudMeta.MetadataVersion {
+			t.Errorf("%q: got metadata version %d, want %d", v, got, cloudMeta.MetadataVersion)
+		}
+	}
+}
+
+// Ensure the metadata version of a cluster can be raised up to the one of
+// the node's build, but not past it nor lowered.
+func TestClient_RaiseMetadataVersion(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	if err := c.RaiseMetadataVersion(cloudMeta.MetadataVersion); err != nil {
+		t.Fatal(err)
+	} else if err := c.RaiseMetadataVersion(cloudMeta.MetadataVersion + 1); err == nil || !strings.Contains(err.Error(), "past version") {
+		t.Fatalf("unexpected error raising past the node's version: %v", err)
+	}
+
+	data := c.Data()
+	data.MetadataVersion = cloudMeta.MetadataVersion + 1
+	if err := c.SetData(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.RaiseMetadataVersion(cloudMeta.MetadataVersion); err == nil || err.Error() != cloudMeta.ErrMetadataVersionLowered.Error() {
+		t.Fatalf("unexpected error lowering the version: %v", err)
+	} else if v := c.MetadataVersion(); v != cloudMeta.MetadataVersion+1 {
+		t.Fatalf("got metadata version %d after lowering it, want %d", v, cloudMeta.MetadataVersion+1)
+	}
+}
@@ -0,0 +1,279 @@
+package meta
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// prometheusContentType is the legacy Prometheus text exposition format.
+	prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+	// openMetricsContentType is the OpenMetrics text exposition format.
+	openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// metricType is the type of a metric family.
+type metricType string
+
+const (
+	counterType metricType = "counter"
+	gaugeType   metricType = "gauge"
+)
+
+// Registry holds the metrics exported by a meta node. Families are written
+// in the order they were registered.
+type Registry struct {
+	mu       sync.Mutex
+	families []*metricFamily
+
+	// constNames and constValues label every exported sample.
+	constNames  []string
+	constValues []string
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// metricFamily is a named group of series sharing a type and label names.
+type metricFamily struct {
+	name       string
+	help       string
+	unit       string
+	typ        metricType
+	labelNames []string
+
+	mu     sync.Mutex
+	series map[string]*metricSeries
+	fn     func() float64
+}
+
+// metricSeries is a single labeled value of a family.
+type metricSeries struct {
+	labelValues []string
+	value       float64
+}
+
+// Counter is a monotonically increasing metric. Its name must not carry the
+// _total suffix; it is added on export.
+type Counter struct{ f *metricFamily }
+
+// Inc increments the counter for the given label values by one.
+func (c *Counter) Inc(labelValues ...string) { c.f.add(1, labelValues) }
+
+// Add increments the counter for the given label values by v.
+func (c *Counter) Add(v float64, labelValues ...string) { c.f.add(v, labelValues) }
+
+// Value returns the current value for the given label values.
+func (c *Counter) Value(labelValues ...string) float64 { return c.f.get(labelValues) }
+
+// Gauge is a metric that can go up and down.
+type Gauge struct{ f *metricFamily }
+
+// Set sets the gauge for the given label values.
+func (g *Gauge) Set(v float64, labelValues ...string) { g.f.set(v, labelValues) }
+
+// Add adds v to the gauge for the given label values.
+func (g *Gauge) Add(v float64, labelValues ...string) { g.f.add(v, labelValues) }
+
+// Value returns the current value for the given label values.
+func (g *Gauge) Value(labelValues ...string) float64 { return g.f.get(labelValues) }
+
+// NewCounter registers a counter family.
+func (r *Registry) NewCounter(name, help string, labelNames ...string) *Counter {
+	return &Counter{f: r.register(&metricFamily{name: name, help: help, typ: counterType, labelNames: labelNames})}
+}
+
+// NewGauge registers a gauge family.
+func (r *Registry) NewGauge(name, unit, help string, labelNames ...string) *Gauge {
+	return &Gauge{f: r.register(&metricFamily{name: name, help: help, unit: unit, typ: gaugeType, labelNames: labelNames})}
+}
+
+// NewGaugeFunc registers an unlabeled gauge whose value is read from fn at
+// export time.
+func (r *Registry) NewGaugeFunc(name, unit, help string, fn func() float64) {
+	r.register(&metricFamily{name: name, help: help, unit: unit, typ: gaugeType, fn: fn})
+}
+
+// NewCounterFunc registers an unlabeled counter whose value is read from fn
+// at export time. fn must never return less than it did before.
+func (r *Registry) NewCounterFunc(name, unit, help string, fn func() float64) {
+	r.register(&metricFamily{name: name, help: help, unit: unit, typ: counterType, fn: fn})
+}
+
+// SetConstLabels sets labels written on every sample of every family, ahead
+// of the family's own labels. Their values never change per series, so they
+// add no cardinality.
+func (r *Registry) SetConstLabels(names, values []string) {
+	if len(names) != len(values) {
+		panic(fmt.Sprintf("got %d constant label values, expected %d", len(values), len(names)))
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.constNames = append([]string(nil), names...)
+	r.constValues = append([]string(nil), values...)
+}
+
+func (r *Registry) register(f *metricFamily) *metricFamily {
+	f.series = make(map[string]*metricSeries)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, other := range r.families {
+		if other.name == f.name {
+			panic(fmt.Sprintf("metric %s registered twice", f.name))
+		}
+	}
+	r.families = append(r.families, f)
+	return f
+}
+
+func (f *metricFamily) lookup(labelValues []string) *metricSeries {
+	if len(labelValues) != len(f.labelNames) {
+		panic(fmt.Sprintf("metric %s: got %d label values, expected %d", f.name, len(labelValues), len(f.labelNames)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &metricSeries{labelValues: append([]string(nil), labelValues...)}
+		f.series[key] = s
+	}
+	return s
+}
+
+func (f *metricFamily) add(v float64, labelValues []string) {
+	f.mu.Lock()
+	f.lookup(labelValues).value += v
+	f.mu.Unlock()
+}
+
+func (f *metricFamily) set(v float64, labelValues []string) {
+	f.mu.Lock()
+	f.lookup(labelValues).value = v
+	f.mu.Unlock()
+}
+
+func (f *metricFamily) get(labelValues []string) float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.lookup(labelValues).value
+}
+
+// samples returns a sorted copy of the family's series.
+func (f *metricFamily) samples() []metricSeries {
+	if f.fn != nil {
+		return []metricSeries{{value: f.fn()}}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	a := make([]metricSeries, 0, len(f.series))
+	for _, s := range f.series {
+		a = append(a, *s)
+	}
+	sort.Slice(a, func(i, j int) bool {
+		return strings.Join(a[i].labelValues, "\xff") < strings.Join(a[j].labelValues, "\xff")
+	})
+	return a
+}
+
+// WritePrometheus writes every metric in the legacy Prometheus text format.
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	return r.write(w, false)
+}
+
+// WriteOpenMetrics writes every metric in the OpenMetrics text format.
+func (r *Registry) WriteOpenMetrics(w io.Writer) error {
+	return r.write(w, true)
+}
+
+func (r *Registry) write(w io.Writer, openMetrics bool) error {
+	r.mu.Lock()
+	families := append([]*metricFamily(nil), r.families...)
+	constNames, constValues := r.constNames, r.constValues
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		sampleName := f.name
+		if f.typ == counterType {
+			sampleName += "_total"
+		}
+
+		// OpenMetrics names the family without the _total suffix.
+		familyName := sampleName
+		if openMetrics {
+			familyName = f.name
+		}
+
+		fmt.Fprintf(bw, "# TYPE %s %s\n", familyName, f.typ)
+		if openMetrics && f.unit != "" {
+			fmt.Fprintf(bw, "# UNIT %s %s\n", familyName, f.unit)
+		}
+		fmt.Fprintf(bw, "# HELP %s %s\n", familyName, escapeHelp(f.help))
+
+		labelNames := append(constNames[:len(constNames):len(constNames)], f.labelNames...)
+		for _, s := range f.samples() {
+			bw.WriteString(sampleName)
+			writeLabels(bw, labelNames, append(constValues[:len(constValues):len(constValues)], s.labelValues...))
+			bw.WriteByte(' ')
+			bw.WriteString(formatFloat(s.value))
+			bw.WriteByte('\n')
+		}
+	}
+
+	if openMetrics {
+		bw.WriteString("# EOF\n")
+	}
+	return bw.Flush()
+}
+
+func writeLabels(w *bufio.Writer, names, values []string) {
+	if len(names) == 0 {
+		return
+	}
+	w.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		fmt.Fprintf(w, "%s=%q", name, values[i])
+	}
+	w.WriteByte('}')
+}
+
+func escapeHelp(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	return strings.Replace(s, "\n", `\n`, -1)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// acceptsOpenMetrics reports whether an Accept header asks for OpenMetrics.
+func acceptsOpenMetrics(accept string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		if mediaType == "application/openmetrics-text" {
+			return true
+		}
+	}
+	return false
+}
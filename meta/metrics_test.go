@@ -0,0 +1,147 @@
+package meta_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMetaService_MetricsOpenMetrics(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	get := func(accept string) (string, string) {
+		req, err := http.NewRequest("GET", "http://"+s.HTTPAddr()+"/metrics", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Header.Get("Content-Type"), string(b)
+	}
+
+	typ, body := get("application/openmetrics-text; version=1.0.0,text/plain;q=0.5")
+	if !strings.HasPrefix(typ, "application/openmetrics-text") {
+		t.Fatalf("unexpected content type: %s", typ)
+	} else if !strings.HasSuffix(body, "# EOF\n") {
+		t.Fatalf("openmetrics output does not end with # EOF:\n%s", body)
+	}
+	for _, line := range []string{
+		"# TYPE influxcloud_meta_http_requests counter\n",
+		"# TYPE influxcloud_meta_uptime_seconds gauge\n",
+		"# UNIT influxcloud_meta_uptime_seconds seconds\n",
+		"# TYPE influxcloud_meta_is_leader gauge\n",
+		"influxcloud_meta_is_leader 1\n",
+	} {
+		if !strings.Contains(body, line) {
+			t.Fatalf("openmetrics output missing %q:\n%s", line, body)
+		}
+	}
+
+	// Without negotiation the legacy text format is served.
+	typ, body = get("")
+	if !strings.HasPrefix(typ, "text/plain") {
+		t.Fatalf("unexpected content type: %s", typ)
+	} else if strings.Contains(body, "# EOF") || strings.Contains(body, "# UNIT") {
+		t.Fatalf("unexpected openmetrics metadata in text output:\n%s", body)
+	} else if !strings.Contains(body, "# TYPE influxcloud_meta_http_requests_total counter\n") {
+		t.Fatalf("text output missing counter type:\n%s", body)
+	}
+}
+
+// Ensure /metrics reports the raft state, and counts applied commands and
+// HTTP requests as they are served.
+func TestMetaService_MetricsRaftState(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	if _, err := c.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if err := c.DropDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if _, err := c.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Get("http://" + s.HTTPAddr() + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := string(b)
+
+	for _, line := range []string{
+		"# TYPE influxcloud_meta_apply_total counter\n",
+		`influxcloud_meta_apply_total{command="CreateDatabaseCommand"} 2` + "\n",
+		`influxcloud_meta_apply_total{command="DropDatabaseCommand"} 1` + "\n",
+		`influxcloud_meta_http_requests_total{handler="execute"} 3` + "\n",
+		"influxcloud_meta_is_leader 1\n",
+		"influxcloud_meta_peers 1\n",
+		"influxcloud_meta_raft_term 1\n",
+	} {
+		if !strings.Contains(body, line) {
+			t.Fatalf("metrics missing %q:\n%s", line, body)
+		}
+	}
+}
+
+// Ensure every exported metric carries the node labels when they are enabled.
+func TestMetaService_MetricsNodeLabels(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	cfg.MetricsNodeLabels = true
+	cfg.MetricsNodeID = "meta-7"
+	cfg.MetricsClusterName = "prod"
+	s := openService(t, cfg)
+	defer s.Close()
+
+	resp, err := http.Get("http://" + s.HTTPAddr() + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		n++
+		if !strings.Contains(line, `{node_id="meta-7",node_addr="`) || !strings.Contains(line, `cluster_name="prod"`) {
+			t.Fatalf("metric without node labels: %q", line)
+		}
+	}
+	if n == 0 {
+		t.Fatalf("no metrics exported:\n%s", b)
+	}
+}
@@ -0,0 +1,48 @@
+package meta
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/influxdata/influxdb/tcp"
+)
+
+// NewMux returns a mux for the connections to bind-address that closes
+// those not sending a known mux header byte within mux-handshake-timeout.
+// It logs them to w and counts them on /metrics by reason: timeout, for
+// one that sent nothing in time, unknown-header, or error.
+func (s *Service) NewMux(w io.Writer) *tcp.Mux {
+	mux := tcp.NewMux()
+	mux.Timeout = time.Duration(s.config.MuxHandshakeTimeout)
+	mux.Logger = NewLogger(&muxLogWriter{w: w, rejected: s.muxRejected}, s.config.LogFormat, "[tcp] ", "tcp", s.nodeID())
+	return mux
+}
+
+// The text tcp.Mux logs when it closes a connection that sent a header byte
+// with no handler, and the error it logs for one that sent nothing within its
+// timeout. tcp.Mux doesn't report why it closed a connection other than in
+// its log, so these are pinned to the vendored copy by TestMuxLogText.
+const (
+	muxUnknownHeaderText = "handler not registered"
+	muxTimeoutText       = "i/o timeout"
+)
+
+// muxLogWriter counts the connections the mux closes as it logs them. The
+// mux only logs when it closes a connection it couldn't route.
+type muxLogWriter struct {
+	w        io.Writer
+	rejected *Counter
+}
+
+func (w *muxLogWriter) Write(p []byte) (int, error) {
+	switch {
+	case bytes.Contains(p, []byte(muxUnknownHeaderText)):
+		w.rejected.Inc("unknown-header")
+	case bytes.Contains(p, []byte(muxTimeoutText)):
+		w.rejected.Inc("timeout")
+	default:
+		w.rejected.Inc("error")
+	}
+	return w.w.Write(p)
+}
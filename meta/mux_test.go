@@ -0,0 +1,70 @@
+package meta
+
+import (
+	"io"
+	"log"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/tcp"
+)
+
+// Ensure the vendored tcp.Mux still logs the text muxLogWriter classifies
+// closed connections by. If this fails after bumping the vendored influxdb,
+// update muxUnknownHeaderText and muxTimeoutText to match.
+func TestMuxLogText(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// The mux logs after it closes a connection, so wait for each line.
+	lines := make(chan string, 2)
+	mux := tcp.NewMux()
+	mux.Timeout = 100 * time.Millisecond
+	mux.Logger = log.New(writerFunc(func(p []byte) (int, error) {
+		lines <- string(p)
+		return len(p), nil
+	}), "", 0)
+	mux.Listen(MuxHeader)
+	go mux.Serve(ln)
+
+	for _, tt := range []struct {
+		header []byte
+		text   string
+	}{
+		{header: []byte{0xff}, text: muxUnknownHeaderText},
+		{header: nil, text: muxTimeoutText},
+	} {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.header != nil {
+			if _, err := conn.Write(tt.header); err != nil {
+				t.Fatal(err)
+			}
+		}
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+			t.Fatalf("expected the mux to close the connection, got %v", err)
+		}
+		conn.Close()
+
+		select {
+		case line := <-lines:
+			if !strings.Contains(line, tt.text) {
+				t.Fatalf("tcp.Mux log %q doesn't contain %q", line, tt.text)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("tcp.Mux didn't log the closed connection")
+		}
+	}
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
@@ -0,0 +1,134 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// nodeProbeTimeout bounds how long a starting node waits for the meta
+// server at an address it is recorded at to tell its node ID.
+const nodeProbeTimeout = 2 * time.Second
+
+// DuplicateNodeError is returned when a meta node starts with the ID of a
+// meta node that is live at another address, as when node.json was copied
+// between hosts. Both would otherwise act as the same raft member.
+type DuplicateNodeError struct {
+	ID uint64
+
+	// Addr is the HTTP address the live meta node serves at, and LocalAddr
+	// the one this node advertises.
+	Addr      string
+	LocalAddr string
+}
+
+func (e *DuplicateNodeError) Error() string {
+	return fmt.Sprintf("meta node %d is already live at %s, so this node at %s can't start as it; node.json was likely copied from %s: remove it here to join the cluster as a new node",
+		e.ID, e.Addr, e.LocalAddr, e.Addr)
+}
+
+// liveNodeID returns the ID of the meta node serving at the HTTP address
+// addr, asked through c, or 0 if it can't be reached in time.
+func liveNodeID(addr string, c *Client) uint64 {
+	ctx, cancel := context.WithTimeout(context.Background(), nodeProbeTimeout)
+	defer cancel()
+	st, err := c.raftStatusContext(ctx, addr)
+	if err != nil {
+		return 0
+	}
+	return st.NodeID
+}
+
+// checkNodeIdentity checks, before raft opens, that the meta node named by
+// node.json isn't live elsewhere: at the address node.json records, or as
+// any meta server the node is to join through. It only reads node.json and
+// the config, so it costs no more as the raft log grows.
+func (s *store) checkNodeIdentity() error {
+	if s.node == nil || s.node.ID == 0 {
+		return nil
+	}
+	addrs := []string{s.node.HTTPAddr, s.config.JoinAddress}
+	for _, peer := range s.config.JoinPeers {
+		if !strings.Contains(peer, "://") {
+			addrs = append(addrs, peer)
+		}
+	}
+	return s.checkDuplicateNode(addrs)
+}
+
+// checkRecordedIdentity checks node.json against the metadata, once raft
+// has opened and caught up: the cluster mustn't record the address the
+// node advertises as another meta node, nor another of its meta nodes be
+// live with the node's ID.
+func (s *store) checkRecordedIdentity() error {
+	if s.node == nil || s.node.ID == 0 {
+		return nil
+	}
+	s.mu.RLock()
+	err := s.checkRecordedNodeID(s.data)
+	addrs := make([]string, 0, len(s.data.MetaNodes))
+	for _, n := range s.data.MetaNodes {
+		addrs = append(addrs, n.Host)
+	}
+	s.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	return s.checkDuplicateNode(addrs)
+}
+
+// checkDuplicateNode returns a *DuplicateNodeError if the meta node the
+// node is, by node.json, is live at any of addrs but its own address. The
+// addresses are probed at once, so the check takes nodeProbeTimeout at
+// most.
+func (s *store) checkDuplicateNode(addrs []string) error {
+	seen := map[string]bool{"": true, s.httpAddr: true}
+	c := NewClient(s.config)
+	c.SetTLS(s.config.HTTPSEnabled)
+	defer c.Close()
+
+	dups := make(chan string, len(addrs))
+	var wg sync.WaitGroup
+	for _, addr := range addrs {
+		if seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+			if liveNodeID(addr, c) == s.node.ID {
+				dups <- addr
+			}
+		}(addr)
+	}
+	wg.Wait()
+	close(dups)
+
+	if addr, ok := <-dups; ok {
+		return &DuplicateNodeError{ID: s.node.ID, Addr: addr, LocalAddr: s.httpAddr}
+	}
+	return nil
+}
+
+// checkRecordedNodeID returns an error if data records the address the node
+// advertises as another meta node than the one in node.json.
+func (s *store) checkRecordedNodeID(data *Data) error {
+	if data == nil {
+		return nil
+	}
+	for _, n := range data.MetaNodes {
+		if n.Host != s.httpAddr || n.ID == s.node.ID {
+			continue
+		}
+		if data.MetaNode(s.node.ID) == nil {
+			return fmt.Errorf("node.json names meta node %d, which is no longer a member of the cluster, and the cluster records %s as meta node %d; remove node.json to join the cluster as a new node",
+				s.node.ID, s.httpAddr, n.ID)
+		}
+		return fmt.Errorf("node.json names meta node %d, but the cluster records %s as meta node %d; restore the node.json of meta node %d, or remove it to join the cluster as a new node",
+			s.node.ID, s.httpAddr, n.ID, n.ID)
+	}
+	return nil
+}
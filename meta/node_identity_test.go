@@ -0,0 +1,111 @@
+package meta_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhexuany/influxcloud"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// Ensure a node started with a node.json copied from a live meta node is
+// refused, before opening raft, with an error naming both addresses, and the
+// cluster keeps the original node's membership.
+func TestService_DuplicateNodeID(t *testing.T) {
+	t.Parallel()
+
+	c := cloudMeta.NewTestCluster(t, 3)
+	defer c.Close()
+
+	leader := c.Leader(5 * time.Second)
+	i := 0
+	for c.Services[i] == leader {
+		i++
+	}
+	orig := c.Services[i]
+
+	buf, err := ioutil.ReadFile(filepath.Join(c.Configs[i].Dir, "node.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "TestService_DuplicateNodeID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "node.json"), buf, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := *c.Configs[i]
+	cfg.Dir = dir
+	cfg.HTTPBindAddress = "127.0.0.1:0"
+	cfg.JoinPeers = nil
+	cfg.JoinAddress = leader.HTTPAddr()
+	cfg.BindAddress = "127.0.0.1:0"
+	copied := newService(&cfg)
+	defer copied.Close()
+	err = copied.Open()
+	dup, ok := err.(*cloudMeta.DuplicateNodeError)
+	if !ok {
+		t.Fatalf("unexpected error: %v", err)
+	} else if dup.ID != orig.Node.ID || dup.Addr != orig.HTTPAddr() || dup.LocalAddr != copied.HTTPAddr() {
+		t.Fatalf("unexpected duplicate: %+v", dup)
+	} else if msg := err.Error(); !strings.Contains(msg, orig.HTTPAddr()) || !strings.Contains(msg, copied.HTTPAddr()) {
+		t.Fatalf("error doesn't name both addresses: %s", msg)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "raft.db")); !os.IsNotExist(err) {
+		t.Fatalf("raft opened before the node was refused: %v", err)
+	}
+
+	for _, n := range clusterNodes(t, leader.HTTPAddr()) {
+		if n.ID == orig.Node.ID && n.HTTPAddr != orig.HTTPAddr() {
+			t.Fatalf("meta node %d taken over: %+v", n.ID, n)
+		}
+	}
+}
+
+// Ensure a node whose node.json names another meta node than the cluster
+// records for its address is refused.
+func TestService_NodeIDMismatch(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	s := openService(t, cfg)
+	httpAddr, raftAddr := s.HTTPAddr(), s.RaftAddr()
+	nodes := clusterNodes(t, httpAddr)
+	if len(nodes) != 1 || nodes[0].HTTPAddr != httpAddr {
+		t.Fatalf("unexpected cluster nodes: %+v", nodes)
+	}
+	id := nodes[0].ID
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A single meta node doesn't write node.json, so write one naming
+	// another node.
+	node := influxcloud.NewNode(cfg.Dir)
+	node.ID, node.HTTPAddr, node.TCPAddr = id+98, httpAddr, raftAddr
+	if err := node.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.HTTPBindAddress, cfg.BindAddress = httpAddr, raftAddr
+	restarted := newService(cfg)
+	defer restarted.Close()
+	err := restarted.Open()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, fmt.Sprintf("node.json names meta node %d,", id+98)) ||
+		!strings.Contains(msg, fmt.Sprintf("the cluster records %s as meta node %d;", httpAddr, id)) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
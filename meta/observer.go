@@ -0,0 +1,113 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// observerLeaderTimeout bounds how long an observer asks the meta nodes of
+// the cluster which of them leads, to redirect a write there.
+const observerLeaderTimeout = 2 * time.Second
+
+// observerLeaderTTL is how long an observer redirects writes to the leader
+// it last found before asking the meta nodes again.
+const observerLeaderTTL = 5 * time.Second
+
+// openObserver opens the store of an observer, which takes no part in raft:
+// it follows the metadata of the meta servers in remote-hostnames, long
+// polling them over HTTP the way the meta client does, and serves it to
+// readers. Writes are redirected to the leader of the cluster.
+//
+// An observer isn't a non-voting raft member, as the vendored raft has no
+// such role: it sees a change once a meta server has applied it and
+// answered its poll, rather than as the log is replicated, and the leader
+// sends it no log entries.
+func (s *store) openObserver() error {
+	if err := s.setOpen(); err != nil {
+		return err
+	}
+	s.startedAt = now()
+
+	c := NewClient(s.config)
+	c.SetMetaServers(s.config.MetaServers())
+	c.SetTLS(s.config.HTTPSEnabled)
+	c.SetLogger(s.logger)
+	if err := c.Open(); err != nil {
+		return fmt.Errorf("follow cluster: %s", err)
+	}
+	s.mu.Lock()
+	s.observed = c
+	s.mu.Unlock()
+
+	changed := c.WaitForDataChanged()
+	s.setObservedData(c.Data())
+	go s.followCluster(c, changed)
+	return nil
+}
+
+// followCluster keeps the data of an observer up to date with c's until
+// the store is closed.
+func (s *store) followCluster(c *Client, changed chan struct{}) {
+	for {
+		select {
+		case <-changed:
+		case <-s.closing:
+			return
+		}
+		changed = c.WaitForDataChanged()
+		s.setObservedData(c.Data())
+	}
+}
+
+// setObservedData replaces the data of an observer with newer data followed
+// from the cluster, and wakes the readers waiting for a change.
+func (s *store) setObservedData(data *Data) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if data.Data.Index <= s.data.Data.Index {
+		return
+	}
+	s.data = data
+	close(s.dataChanged)
+	s.dataChanged = make(chan struct{})
+}
+
+// observedLeaderHTTP returns the HTTP address of the leader of the cluster
+// an observer follows, as reported by the raft status of its meta nodes, or
+// "" if none of them knows of one. The leader found is cached for
+// observerLeaderTTL, so redirecting writes doesn't ask every time.
+func (s *store) observedLeaderHTTP() string {
+	s.mu.RLock()
+	leader, at := s.observedLeader, s.observedLeaderAt
+	c, data := s.observed, s.data
+	s.mu.RUnlock()
+	if leader != "" && now().Sub(at) < observerLeaderTTL {
+		return leader
+	} else if c == nil {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), observerLeaderTimeout)
+	defer cancel()
+	leader = ""
+	for _, n := range data.MetaNodes {
+		st, err := c.raftStatusContext(ctx, n.Host)
+		if err != nil || st.Leader == "" {
+			continue
+		}
+		for _, m := range data.MetaNodes {
+			if m.TCPHost == st.Leader {
+				leader = m.Host
+			}
+		}
+		if leader != "" {
+			break
+		}
+	}
+
+	s.mu.Lock()
+	s.observedLeader, s.observedLeaderAt = leader, now()
+	s.mu.Unlock()
+	return leader
+}
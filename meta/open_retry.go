@@ -0,0 +1,70 @@
+package meta
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// openRetryBase is the time the client first waits before trying the meta
+// servers again while opening. It doubles with every attempt, up to
+// open-max-interval.
+const openRetryBase = 100 * time.Millisecond
+
+// errStatus is returned when a meta server answers with a status other
+// than 200 OK.
+type errStatus struct {
+	code   int
+	status string
+}
+
+func (e errStatus) Error() string { return "meta server returned non-200: " + e.status }
+
+// openRetryable returns whether opening the client should be retried after
+// err: the meta server couldn't be reached, or couldn't serve the metadata
+// yet, as while the cluster elects a leader. Certificate and authorization
+// failures don't pass by themselves, so they aren't retried.
+func openRetryable(err error) bool {
+	switch e := err.(type) {
+	case *url.Error:
+		return !isTLSError(e.Err)
+	case errStatus:
+		return e.code != http.StatusUnauthorized && e.code != http.StatusForbidden
+	}
+	return false
+}
+
+// isTLSError returns whether err is a failure to set up TLS with a meta
+// server, such as an untrusted certificate.
+func isTLSError(err error) bool {
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	var header tls.RecordHeaderError
+	if errors.As(err, &unknownAuthority) || errors.As(err, &hostname) ||
+		errors.As(err, &invalid) || errors.As(err, &header) {
+		return true
+	}
+	// Alerts sent by the server, such as for a rejected client
+	// certificate, have no exported type.
+	return strings.Contains(err.Error(), "tls: ")
+}
+
+// openBackoff returns how long to wait after the failed attempt, counting
+// from one, to open the client: an exponential backoff up to
+// open-max-interval, with jitter so that nodes starting together don't
+// retry in step.
+func (c *Client) openBackoff(attempt int) time.Duration {
+	d := time.Duration(c.config.OpenMaxInterval)
+	if attempt < 32 {
+		if exp := openRetryBase << uint(attempt-1); exp < d || d <= 0 {
+			d = exp
+		}
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
@@ -0,0 +1,65 @@
+package meta_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/influxdata/influxdb/services/meta"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// Ensure opening a client retries meta servers that have no leader yet,
+// and gives up at once on one that refuses it, but not on the others.
+func TestClient_Open_Retry(t *testing.T) {
+	t.Parallel()
+
+	snapshot, err := (&cloudMeta.Data{Data: &meta.Data{Index: 1}}).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var requests int32
+	electing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) <= 3 {
+			http.Error(w, "no leader", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(snapshot)
+	}))
+	defer electing.Close()
+
+	c := cloudMeta.NewClient(newConfig())
+	c.SetMetaServers([]string{strings.TrimPrefix(electing.URL, "http://")})
+	if err := c.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if n := atomic.LoadInt32(&requests); n != 4 {
+		t.Fatalf("unexpected requests: %d", n)
+	}
+
+	var refused int32
+	forbidden := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&refused, 1)
+		http.Error(w, "not authorized", http.StatusForbidden)
+	}))
+	defer forbidden.Close()
+
+	c2 := cloudMeta.NewClient(newConfig())
+	c2.SetMetaServers([]string{strings.TrimPrefix(forbidden.URL, "http://")})
+	if err := c2.Open(); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("unexpected error: %v", err)
+	} else if n := atomic.LoadInt32(&refused); n != 1 {
+		t.Fatalf("refused open retried: %d requests", n)
+	}
+
+	// A server that refuses it doesn't keep the client from the others.
+	c3 := cloudMeta.NewClient(newConfig())
+	c3.SetMetaServers([]string{strings.TrimPrefix(forbidden.URL, "http://"), strings.TrimPrefix(electing.URL, "http://")})
+	if err := c3.Open(); err != nil {
+		t.Fatal(err)
+	}
+	c3.Close()
+}
@@ -0,0 +1,194 @@
+package meta
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/uber-go/zap"
+	"github.com/zhexuany/influxcloud/meta/internal"
+)
+
+// The kinds of orphaned metadata.
+const (
+	// OrphanPendingShardOwner is a node's pending ownership of a shard that
+	// no longer exists, as its shard group, retention policy or database
+	// was dropped.
+	OrphanPendingShardOwner = "pending-shard-owner"
+
+	// OrphanShardGroupQuota is the shard group quota of a database that no
+	// longer exists.
+	OrphanShardGroupQuota = "shard-group-quota"
+
+	// OrphanDatabaseAnnotations are the annotations of a database that no
+	// longer exists.
+	OrphanDatabaseAnnotations = "database-annotations"
+
+	// OrphanWriteBlock is the write block of a database that no longer
+	// exists.
+	OrphanWriteBlock = "write-block"
+)
+
+// Orphan is metadata left referring to a shard or database that no longer
+// exists. Shard groups are kept within their retention policy and dropped
+// with it, so it is the state held elsewhere about them that a crash part
+// way through a drop, or an older version, can leave behind.
+type Orphan struct {
+	Kind     string `json:"kind"`
+	Database string `json:"database,omitempty"`
+	NodeID   uint64 `json:"nodeID,omitempty"`
+	ShardID  uint64 `json:"shardID,omitempty"`
+}
+
+// Orphans returns the orphaned metadata of data, ordered by kind.
+func (data *Data) Orphans() []Orphan {
+	shards := make(map[uint64]bool)
+	for _, dbi := range data.Data.Databases {
+		for _, rpi := range dbi.RetentionPolicies {
+			for _, sgi := range rpi.ShardGroups {
+				for _, si := range sgi.Shards {
+					shards[si.ID] = true
+				}
+			}
+		}
+	}
+
+	var orphans []Orphan
+	for _, nodes := range []NodeInfos{data.MetaNodes, data.DataNodes} {
+		for _, n := range nodes {
+			for _, id := range n.PendingShardOwners {
+				if !shards[id] {
+					orphans = append(orphans, Orphan{Kind: OrphanPendingShardOwner, NodeID: n.ID, ShardID: id})
+				}
+			}
+		}
+	}
+
+	var dbs []Orphan
+	for db := range data.ShardGroupQuotas {
+		if data.Database(db) == nil {
+			dbs = append(dbs, Orphan{Kind: OrphanShardGroupQuota, Database: db})
+		}
+	}
+	for db := range data.DatabaseAnnotations {
+		if data.Database(db) == nil {
+			dbs = append(dbs, Orphan{Kind: OrphanDatabaseAnnotations, Database: db})
+		}
+	}
+	for db := range data.WriteBlockedDatabases {
+		if data.Database(db) == nil {
+			dbs = append(dbs, Orphan{Kind: OrphanWriteBlock, Database: db})
+		}
+	}
+	sort.Slice(dbs, func(i, j int) bool {
+		if dbs[i].Kind != dbs[j].Kind {
+			return dbs[i].Kind < dbs[j].Kind
+		}
+		return dbs[i].Database < dbs[j].Database
+	})
+
+	return append(orphans, dbs...)
+}
+
+// PurgeOrphans removes the orphaned metadata of data and returns how much
+// it removed.
+func (data *Data) PurgeOrphans() int {
+	orphans := data.Orphans()
+	for _, o := range orphans {
+		switch o.Kind {
+		case OrphanPendingShardOwner:
+			for _, nodes := range []NodeInfos{data.MetaNodes, data.DataNodes} {
+				for i := range nodes {
+					if nodes[i].ID == o.NodeID {
+						nodes[i].PendingShardOwners = removeUint64(nodes[i].PendingShardOwners, o.ShardID)
+					}
+				}
+			}
+		case OrphanShardGroupQuota:
+			delete(data.ShardGroupQuotas, o.Database)
+		case OrphanDatabaseAnnotations:
+			delete(data.DatabaseAnnotations, o.Database)
+		case OrphanWriteBlock:
+			delete(data.WriteBlockedDatabases, o.Database)
+		}
+	}
+	return len(orphans)
+}
+
+// removeUint64 returns a without any v.
+func removeUint64(a uint64arr, v uint64) uint64arr {
+	other := make(uint64arr, 0, len(a))
+	for _, x := range a {
+		if x != v {
+			other = append(other, x)
+		}
+	}
+	return other
+}
+
+func (fsm *storeFSM) applyPurgeOrphansCommand(cmd *internal.Command) interface{} {
+	// The orphans are found again here, so every node purges the same ones
+	// whatever the leader saw when it proposed the command.
+	other := fsm.data.Clone()
+	if other.PurgeOrphans() == 0 {
+		return nil
+	}
+	fsm.data = other
+	return nil
+}
+
+// reconcileOrphans logs the orphaned metadata, and purges it if
+// purge-orphans is set.
+func (s *Service) reconcileOrphans() {
+	data, err := s.store.snapshot()
+	if err != nil {
+		s.Logger.Error("orphan check: snapshot failed", zap.Error(err))
+		return
+	}
+	orphans := data.Orphans()
+	if len(orphans) == 0 {
+		return
+	}
+	for _, o := range orphans {
+		s.Logger.Warn("orphan check: found orphaned metadata",
+			zap.String("kind", o.Kind),
+			zap.String("database", o.Database),
+			zap.Uint64("node-id", o.NodeID),
+			zap.Uint64("shard-id", o.ShardID))
+	}
+	if !s.config.PurgeOrphans {
+		s.Logger.Warn("orphan check: not purging, purge-orphans is disabled", zap.Int("orphans", len(orphans)))
+		return
+	}
+
+	t := internal.Command_PurgeOrphansCommand
+	cmd := &internal.Command{Type: &t}
+	if err := proto.SetExtension(cmd, internal.E_PurgeOrphansCommand_Command, &internal.PurgeOrphansCommand{}); err != nil {
+		panic(err)
+	}
+	b, err := proto.Marshal(cmd)
+	if err != nil {
+		panic(err)
+	}
+	if err := s.store.apply(b); err != nil {
+		s.Logger.Error("orphan check: purge failed", zap.Error(err))
+		return
+	}
+	s.Logger.Info("orphan check: purged orphaned metadata", zap.Int("orphans", len(orphans)))
+}
+
+// serveOrphans responds with the orphaned metadata as JSON.
+func (h *handler) serveOrphans(w http.ResponseWriter, r *http.Request) {
+	data, err := h.store.snapshot()
+	if err != nil {
+		h.httpError(err, w, http.StatusInternalServerError)
+		return
+	}
+	orphans := data.Orphans()
+	if orphans == nil {
+		orphans = []Orphan{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(orphans)
+}
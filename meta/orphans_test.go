@@ -0,0 +1,77 @@
+package meta_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// Ensure the orphan check finds metadata left behind by a dropped database,
+// lists it on /debug/orphans, and purges it only when purge-orphans is set.
+func TestMetaService_OrphanCheck(t *testing.T) {
+	t.Parallel()
+
+	for _, purge := range []bool{false, true} {
+		cfg := newConfig()
+		defer os.RemoveAll(cfg.Dir)
+		cfg.OrphanCheckInterval = toml.Duration(50 * time.Millisecond)
+		cfg.PurgeOrphans = purge
+		s := newService(cfg)
+		var logs lockedBuffer
+		s.SetLogOutput(&logs)
+		if err := s.Open(); err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+		c := newClient(s)
+		defer c.Close()
+
+		// Metadata of an older version may hold the quota of a database it
+		// has since dropped.
+		data := c.Data()
+		data.ShardGroupQuotas = map[string]uint64{"db0": 10}
+		if err := c.SetData(data); err != nil {
+			t.Fatal(err)
+		}
+
+		orphan := []cloudMeta.Orphan{{Kind: cloudMeta.OrphanShardGroupQuota, Database: "db0"}}
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			detected := strings.Contains(logs.String(), "found orphaned metadata")
+			orphans := getOrphans(t, s)
+			if !purge && detected {
+				if !reflect.DeepEqual(orphans, orphan) {
+					t.Fatalf("unexpected orphans: %+v", orphans)
+				}
+				break
+			} else if purge && detected && len(orphans) == 0 {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("purge=%v: detected=%v, orphans %+v", purge, detected, orphans)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+}
+
+// getOrphans returns the orphans listed by s.
+func getOrphans(t *testing.T, s *testService) []cloudMeta.Orphan {
+	resp, err := http.Get("http://" + s.HTTPAddr() + "/debug/orphans")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var orphans []cloudMeta.Orphan
+	if err := json.NewDecoder(resp.Body).Decode(&orphans); err != nil {
+		t.Fatal(err)
+	}
+	return orphans
+}
@@ -0,0 +1,182 @@
+//go:build raftpartition
+// +build raftpartition
+
+package meta_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// Ensure verifyLeader reads fail on a leader cut off from its peers, which
+// would otherwise serve stale metadata, and succeed on the new leader.
+func TestService_VerifyLeader(t *testing.T) {
+	c := cloudMeta.NewTestCluster(t, 3)
+	defer c.Close()
+
+	get := func(s *cloudMeta.Service, query string) int {
+		resp, err := http.Get("http://" + s.HTTPAddr() + "/databases" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	leader := c.Leader(time.Second)
+	if code := get(leader, "?verifyLeader=true"); code != http.StatusOK {
+		t.Fatalf("unexpected status from the leader: %d", code)
+	}
+
+	c.Partition(leader)
+	defer c.Heal(leader)
+
+	if code := get(leader, "?verifyLeader=true"); code != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected status from the partitioned leader: %d", code)
+	}
+	if code := get(leader, ""); code != http.StatusOK {
+		t.Fatalf("unexpected status from a plain read: %d", code)
+	}
+
+	// The rest of the cluster elects a new leader that can verify itself.
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		var verified bool
+		for _, s := range c.Services {
+			if s != leader && get(s, "?verifyLeader=true") == http.StatusOK {
+				verified = true
+			}
+		}
+		if verified {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for a new leader")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// Ensure a write while the cluster has no quorum fails fast with
+// ErrQuorumLost within the quorum-loss-timeout, rather than hanging.
+func TestClient_QuorumLossFailFast(t *testing.T) {
+	c := cloudMeta.NewTestCluster(t, 3)
+	defer c.Close()
+
+	timeout := 2 * time.Second
+	for _, cfg := range c.Configs {
+		cfg.QuorumLossTimeout = toml.Duration(timeout)
+	}
+
+	// Cut both followers off, so that nobody can gather a quorum.
+	leader := c.Leader(time.Second)
+	for _, s := range c.Services {
+		if s != leader {
+			c.Partition(s)
+			defer c.Heal(s)
+		}
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for c.Leader(0) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the leader to step down")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	start := time.Now()
+	if err := c.Client.SetShardGroupQuota("db0", 10); err != cloudMeta.ErrQuorumLost {
+		t.Fatalf("got %v, expected %v", err, cloudMeta.ErrQuorumLost)
+	}
+	// A retry pass may be under way when the timeout passes.
+	if elapsed := time.Since(start); elapsed > timeout+2*time.Second {
+		t.Fatalf("write failed after %s, expected about %s", elapsed, timeout)
+	}
+}
+
+// Ensure a write made with WriteDurabilityAll is acknowledged only once the
+// raft log of every meta node holds it, while writes made with the default
+// WriteDurabilityQuorum aren't held up by a node that is cut off.
+func TestClient_WriteDurabilityAll(t *testing.T) {
+	c := cloudMeta.NewTestCluster(t, 3)
+	defer c.Close()
+
+	timeout := time.Second
+	var addrs []string
+	for i, cfg := range c.Configs {
+		cfg.WriteDurabilityTimeout = toml.Duration(timeout)
+		addrs = append(addrs, c.Services[i].HTTPAddr())
+	}
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	cfg.WriteDurability = cloudMeta.WriteDurabilityAll
+	strict := cloudMeta.NewClient(cfg)
+	strict.SetMetaServers(addrs)
+	if err := strict.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer strict.Close()
+
+	// The raft status of each node shows its log held the write by the
+	// time it was acknowledged.
+	if _, err := strict.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+	index := strict.Data().Data.Index
+	for _, s := range c.Services {
+		var status struct {
+			LastIndex uint64 `json:"lastIndex"`
+		}
+		resp, err := http.Get("http://" + s.HTTPAddr() + "/raft-status")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&status)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		} else if status.LastIndex < index {
+			t.Fatalf("write at index %d acknowledged before %s logged it, at index %d", index, s.HTTPAddr(), status.LastIndex)
+		}
+	}
+
+	leader := c.Leader(time.Second)
+	for _, s := range c.Services {
+		if s != leader {
+			c.Partition(s)
+			defer c.Heal(s)
+			break
+		}
+	}
+
+	start := time.Now()
+	if _, err := strict.CreateDatabase("db1"); err != cloudMeta.ErrWriteNotDurable {
+		t.Fatalf("got %v, expected %v", err, cloudMeta.ErrWriteNotDurable)
+	} else if elapsed := time.Since(start); elapsed < timeout {
+		t.Fatalf("write failed after %s, before the durability timeout", elapsed)
+	}
+
+	// The client of the cluster may be polling the node cut off for
+	// updates, so talk to the leader only.
+	cfg = newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	quorum := cloudMeta.NewClient(cfg)
+	quorum.SetMetaServers([]string{leader.HTTPAddr()})
+	if err := quorum.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer quorum.Close()
+	if _, err := quorum.CreateDatabase("db2"); err != nil {
+		t.Fatalf("quorum write: %s", err)
+	}
+	// The write that wasn't durable enough is committed all the same.
+	if db, _ := quorum.Database("db1"); db == nil {
+		t.Fatal("write that wasn't durable enough was rolled back")
+	}
+}
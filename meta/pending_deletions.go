@@ -0,0 +1,56 @@
+package meta
+
+import (
+	"sort"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+// PendingDeletion is a shard group marked deleted, whose shards the
+// retention service of the data nodes removes, and whose metadata it purges
+// at PurgeAt.
+type PendingDeletion struct {
+	Database        string
+	RetentionPolicy string
+	ShardGroupID    uint64
+	StartTime       time.Time
+	EndTime         time.Time
+	DeletedAt       time.Time
+
+	// PurgeAt is when the shard group is purged from the metadata, once it
+	// has been marked deleted for the retention service's expiration.
+	PurgeAt time.Time
+}
+
+// PendingDeletions returns the shard groups marked deleted in data, in the
+// order they are purged.
+func (data *Data) PendingDeletions() []PendingDeletion {
+	var a []PendingDeletion
+	for _, db := range data.Data.Databases {
+		for _, rp := range db.RetentionPolicies {
+			for _, sg := range rp.ShardGroups {
+				if !sg.Deleted() {
+					continue
+				}
+				a = append(a, PendingDeletion{
+					Database:        db.Name,
+					RetentionPolicy: rp.Name,
+					ShardGroupID:    sg.ID,
+					StartTime:       sg.StartTime,
+					EndTime:         sg.EndTime,
+					DeletedAt:       sg.DeletedAt,
+					PurgeAt:         sg.DeletedAt.Add(-meta.ShardGroupDeletedExpiration),
+				})
+			}
+		}
+	}
+	sort.SliceStable(a, func(i, j int) bool { return a[i].PurgeAt.Before(a[j].PurgeAt) })
+	return a
+}
+
+// PendingDeletions returns the shard groups marked deleted, with when they
+// are purged from the metadata, in that order.
+func (c *Client) PendingDeletions() []PendingDeletion {
+	return c.data().PendingDeletions()
+}
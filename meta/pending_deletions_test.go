@@ -0,0 +1,128 @@
+package meta_test
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// Ensure PendingDeletions lists the shard groups marked deleted, purged
+// once the retention service's expiration has passed since, in that order.
+func TestClient_PendingDeletions(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	if _, err := c.CreateDataNode("foo:8180", "bar:8281"); err != nil {
+		t.Fatal(err)
+	} else if _, err := c.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var groups []*meta.ShardGroupInfo
+	for i := 0; i < 3; i++ {
+		sg, err := c.CreateShardGroup("db0", "default", start.Add(time.Duration(i)*7*24*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		groups = append(groups, sg)
+	}
+	if got := c.PendingDeletions(); len(got) != 0 {
+		t.Fatalf("unexpected pending deletions: %+v", got)
+	}
+
+	// The last group is deleted first, so it is purged first.
+	deleted := map[uint64]time.Time{
+		groups[0].ID: start.Add(48 * time.Hour),
+		groups[2].ID: start.Add(24 * time.Hour),
+	}
+	data := c.Data()
+	sgs := data.Database("db0").RetentionPolicy("default").ShardGroups
+	for i := range sgs {
+		sgs[i].DeletedAt = deleted[sgs[i].ID]
+	}
+	if err := c.SetData(data); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []cloudMeta.PendingDeletion{}
+	for _, sg := range []*meta.ShardGroupInfo{groups[2], groups[0]} {
+		exp = append(exp, cloudMeta.PendingDeletion{
+			Database:        "db0",
+			RetentionPolicy: "default",
+			ShardGroupID:    sg.ID,
+			StartTime:       sg.StartTime,
+			EndTime:         sg.EndTime,
+			DeletedAt:       deleted[sg.ID],
+			PurgeAt:         deleted[sg.ID].Add(-meta.ShardGroupDeletedExpiration),
+		})
+	}
+	if got := c.PendingDeletions(); !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected pending deletions:\n got %+v\nwant %+v", got, exp)
+	}
+}
+
+// Ensure shard groups deleted through the client are deleted at the time of
+// the leader's clock, and purged the retention service's expiration after.
+func TestClient_PendingDeletions_Clock(t *testing.T) {
+	t.Parallel()
+
+	clock := &fakeClock{t: time.Date(2026, 10, 17, 11, 0, 0, 0, time.UTC)}
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	cfg.Clock = clock
+	s := openService(t, cfg)
+	defer s.Close()
+	c := newClient(s)
+	defer c.Close()
+
+	if _, err := c.CreateDataNode("foo:8180", "bar:8281"); err != nil {
+		t.Fatal(err)
+	} else if _, err := c.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var groups []*meta.ShardGroupInfo
+	for i := 0; i < 2; i++ {
+		sg, err := c.CreateShardGroup("db0", "default", start.Add(time.Duration(i)*7*24*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		groups = append(groups, sg)
+	}
+
+	// The last group is deleted an hour before the first.
+	deleted := map[uint64]time.Time{
+		groups[1].ID: clock.Now(),
+		groups[0].ID: clock.Now().Add(time.Hour),
+	}
+	for _, sg := range []*meta.ShardGroupInfo{groups[1], groups[0]} {
+		clock.Set(deleted[sg.ID])
+		if err := c.DeleteShardGroup("db0", "default", sg.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exp := []cloudMeta.PendingDeletion{}
+	for _, sg := range []*meta.ShardGroupInfo{groups[1], groups[0]} {
+		exp = append(exp, cloudMeta.PendingDeletion{
+			Database:        "db0",
+			RetentionPolicy: "default",
+			ShardGroupID:    sg.ID,
+			StartTime:       sg.StartTime,
+			EndTime:         sg.EndTime,
+			DeletedAt:       deleted[sg.ID],
+			PurgeAt:         deleted[sg.ID].Add(-meta.ShardGroupDeletedExpiration),
+		})
+	}
+	if got := c.PendingDeletions(); !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected pending deletions:\n got %+v\nwant %+v", got, exp)
+	}
+}
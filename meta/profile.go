@@ -0,0 +1,59 @@
+package meta
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/pprof"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultCPUProfileSeconds is how long /debug/profile/cpu profiles
+	// without a seconds parameter, and maxCPUProfileSeconds the longest it
+	// profiles for.
+	defaultCPUProfileSeconds = 30
+	maxCPUProfileSeconds     = 300
+)
+
+// serveCPUProfile profiles the CPU for the seconds parameter, 30 by default,
+// and streams the profile in the pprof format as it is taken, so it never
+// touches the filesystem. The process runs one CPU profile at a time, so it
+// answers 409 Conflict while another is running, including one started with
+// -cpuprofile or on the pprof listener. The profile is listed on
+// /admin/tasks as cpu-profile while it runs, and canceling it there ends it
+// early.
+func (h *handler) serveCPUProfile(w http.ResponseWriter, r *http.Request) {
+	seconds := defaultCPUProfileSeconds
+	if v := r.URL.Query().Get("seconds"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxCPUProfileSeconds {
+			http.Error(w, fmt.Sprintf("seconds must be between 1 and %d, got %q", maxCPUProfileSeconds, v), http.StatusBadRequest)
+			return
+		}
+		seconds = n
+	}
+
+	// The profile is written from another goroutine once started, so the
+	// headers are set first.
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="cpu.pprof"`)
+	if err := pprof.StartCPUProfile(w); err != nil {
+		w.Header().Del("Content-Disposition")
+		http.Error(w, fmt.Sprintf("could not start CPU profile: %s", err), http.StatusConflict)
+		return
+	}
+	defer pprof.StopCPUProfile()
+
+	h.s.tasks.run("cpu-profile", func(t *Task) {
+		t.SetProgress(0, int64(seconds))
+		timer := time.NewTimer(time.Duration(seconds) * time.Second)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+		case <-h.closing:
+		case <-t.Context().Done():
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package meta_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// Ensure /debug/profile/cpu streams a CPU profile of the requested length,
+// and refuses a second one while it runs.
+func TestMetaService_CPUProfile(t *testing.T) {
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	s := openService(t, cfg)
+	defer s.Close()
+	url := "http://" + s.HTTPAddr() + "/debug/profile/cpu"
+
+	if resp, err := http.Get(url + "?seconds=0"); err != nil {
+		t.Fatal(err)
+	} else if resp.Body.Close(); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("unexpected status for seconds=0: %s", resp.Status)
+	}
+
+	type result struct {
+		status int
+		body   []byte
+		err    error
+	}
+	done := make(chan result, 1)
+	start := time.Now()
+	go func() {
+		resp, err := http.Get(url + "?seconds=2")
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		done <- result{status: resp.StatusCode, body: b, err: err}
+	}()
+
+	time.Sleep(500 * time.Millisecond)
+	if resp, err := http.Get(url + "?seconds=1"); err != nil {
+		t.Fatal(err)
+	} else if resp.Body.Close(); resp.StatusCode != http.StatusConflict {
+		t.Fatalf("unexpected status for a concurrent profile: %s", resp.Status)
+	}
+
+	res := <-done
+	if res.err != nil {
+		t.Fatal(res.err)
+	} else if res.status != http.StatusOK {
+		t.Fatalf("unexpected status: %d", res.status)
+	} else if d := time.Since(start); d < 2*time.Second {
+		t.Fatalf("profiled for %s only", d)
+	} else if !bytes.HasPrefix(res.body, []byte{0x1f, 0x8b}) {
+		t.Fatalf("not a gzipped pprof profile: %q", res.body)
+	}
+}
+
+// Ensure a CPU profile is listed on /admin/tasks while it runs, and that
+// canceling it there ends it early.
+func TestMetaService_CPUProfileTask(t *testing.T) {
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	s := openService(t, cfg)
+	defer s.Close()
+
+	done := make(chan error, 1)
+	start := time.Now()
+	go func() {
+		resp, err := http.Get("http://" + s.HTTPAddr() + "/debug/profile/cpu?seconds=30")
+		if err == nil {
+			_, err = ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+		done <- err
+	}()
+
+	var task cloudMeta.TaskInfo
+	for i := 0; task.Name != "cpu-profile"; i++ {
+		if i == 100 {
+			t.Fatal("cpu-profile never listed on /admin/tasks")
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		resp, err := http.Get("http://" + s.HTTPAddr() + "/admin/tasks")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var tasks []cloudMeta.TaskInfo
+		err = json.NewDecoder(resp.Body).Decode(&tasks)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, ti := range tasks {
+			if ti.Name == "cpu-profile" {
+				task = ti
+			}
+		}
+	}
+	if task.Total != 30 {
+		t.Fatalf("unexpected task: %+v", task)
+	}
+
+	resp, err := http.Post(fmt.Sprintf("http://%s/admin/tasks/cancel?id=%d", s.HTTPAddr(), task.ID), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if resp.Body.Close(); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("unexpected status canceling the profile: %s", resp.Status)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("profile didn't end once canceled")
+	}
+	if d := time.Since(start); d > 10*time.Second {
+		t.Fatalf("profile ran for %s after being canceled", d)
+	}
+}
@@ -0,0 +1,224 @@
+package meta
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+)
+
+// raftRPCInstallSnapshot is the byte the raft network transport frames an
+// InstallSnapshot request with.
+const raftRPCInstallSnapshot = 2
+
+// maxRaftMessageDepth is how deeply the arrays and maps of a raft request
+// may nest. Raft's own requests nest three deep.
+const maxRaftMessageDepth = 16
+
+// limitedRaftConn is an accepted raft connection that fails once the peer
+// sends a string or byte array larger than max, such as the command of a
+// log entry past max-raft-message-size, before the transport reads it in.
+// It walks the msgpack of each request the transport decodes to tell where
+// the request ends, and passes the snapshot following an InstallSnapshot
+// request through unchecked, as max-snapshot-size bounds it.
+type limitedRaftConn struct {
+	net.Conn
+	r   *bufio.Reader
+	max uint64
+
+	// n is the number of bytes left to pass through before the next token
+	// is checked, and snapshot the bytes of the snapshot after the request.
+	n        uint64
+	snapshot uint64
+
+	// stack holds the number of items left in each open array and map of
+	// the request, the request itself first. It is empty between requests.
+	stack   []uint64
+	rpcType byte
+
+	// key is the last key read of the request, and size the value of its
+	// Size key.
+	key  string
+	size uint64
+
+	err error
+}
+
+func newLimitedRaftConn(conn net.Conn, max int64) *limitedRaftConn {
+	return &limitedRaftConn{Conn: conn, r: bufio.NewReader(conn), max: uint64(max)}
+}
+
+// Read reads no further than the tokens checked so far.
+func (c *limitedRaftConn) Read(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	if c.n == 0 {
+		if err := c.next(); err != nil {
+			c.err = err
+			return 0, err
+		}
+	}
+	if uint64(len(p)) > c.n {
+		p = p[:c.n]
+	}
+	n, err := c.r.Read(p)
+	c.n -= uint64(n)
+	return n, err
+}
+
+// next checks the next token of the connection and sets how much of it may
+// be read.
+func (c *limitedRaftConn) next() error {
+	if c.snapshot > 0 {
+		c.n, c.snapshot = c.snapshot, 0
+		return nil
+	}
+	if len(c.stack) == 0 {
+		b, err := c.r.Peek(1)
+		if err != nil {
+			return err
+		}
+		c.rpcType, c.n = b[0], 1
+		c.stack = append(c.stack, 1)
+		c.key, c.size = "", 0
+		return nil
+	}
+
+	b, err := c.r.Peek(1)
+	if err != nil {
+		return err
+	}
+	header, length, items, err := c.token(b[0])
+	if err != nil {
+		return err
+	}
+	if length > c.max {
+		return fmt.Errorf("raft message of %d bytes exceeds max-raft-message-size of %d bytes", length, c.max)
+	}
+	c.n = header + length
+
+	// A token directly in the request is one of its keys or values.
+	top := len(c.stack) - 1
+	if top == 1 {
+		if c.stack[top]%2 == 0 {
+			c.key = ""
+			if isMsgpackString(b[0]) && length <= 16 {
+				if buf, err := c.r.Peek(int(header + length)); err == nil {
+					c.key = string(buf[header:])
+				}
+			}
+		} else if c.key == "Size" {
+			if buf, err := c.r.Peek(int(header)); err == nil {
+				c.size = msgpackUint(buf)
+			}
+		}
+	}
+	c.stack[top]--
+
+	if items > 0 {
+		if len(c.stack) == maxRaftMessageDepth {
+			return fmt.Errorf("raft message nests deeper than %d", maxRaftMessageDepth)
+		}
+		c.stack = append(c.stack, items)
+	}
+	for len(c.stack) > 0 && c.stack[len(c.stack)-1] == 0 {
+		c.stack = c.stack[:len(c.stack)-1]
+	}
+	if len(c.stack) == 0 && c.rpcType == raftRPCInstallSnapshot {
+		c.snapshot = c.size
+	}
+	return nil
+}
+
+// token returns the size of the header of the msgpack token starting with
+// b, the length of the string or byte array that follows it, and the number
+// of items of the array or map it starts.
+func (c *limitedRaftConn) token(b byte) (header, length, items uint64, err error) {
+	// uintHeader reads the big endian length of n bytes after b.
+	uintHeader := func(n int) (uint64, error) {
+		buf, err := c.r.Peek(1 + n)
+		if err != nil {
+			return 0, err
+		}
+		return msgpackUint(buf), nil
+	}
+
+	switch {
+	case b <= 0x7f, b >= 0xe0, b == 0xc0, b == 0xc2, b == 0xc3:
+		return 1, 0, 0, nil
+	case b <= 0x8f:
+		return 1, 0, 2 * uint64(b&0x0f), nil
+	case b <= 0x9f:
+		return 1, 0, uint64(b & 0x0f), nil
+	case b <= 0xbf:
+		return 1, uint64(b & 0x1f), 0, nil
+	}
+
+	switch b {
+	case 0xc4, 0xd9:
+		length, err = uintHeader(1)
+		return 2, length, 0, err
+	case 0xc5, 0xda:
+		length, err = uintHeader(2)
+		return 3, length, 0, err
+	case 0xc6, 0xdb:
+		length, err = uintHeader(4)
+		return 5, length, 0, err
+	case 0xc7:
+		length, err = uintHeader(1)
+		return 3, length, 0, err
+	case 0xc8:
+		length, err = uintHeader(2)
+		return 4, length, 0, err
+	case 0xc9:
+		length, err = uintHeader(4)
+		return 6, length, 0, err
+	case 0xca, 0xce, 0xd2:
+		return 5, 0, 0, nil
+	case 0xcb, 0xcf, 0xd3:
+		return 9, 0, 0, nil
+	case 0xcc, 0xd0:
+		return 2, 0, 0, nil
+	case 0xcd, 0xd1:
+		return 3, 0, 0, nil
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return 2 + 1<<(b-0xd4), 0, 0, nil
+	case 0xdc, 0xde:
+		items, err = uintHeader(2)
+	case 0xdd, 0xdf:
+		items, err = uintHeader(4)
+	default:
+		return 0, 0, 0, fmt.Errorf("raft message has invalid msgpack type 0x%x", b)
+	}
+	if items > c.max {
+		return 0, 0, 0, fmt.Errorf("raft message of %d items exceeds max-raft-message-size of %d bytes", items, c.max)
+	}
+	if b == 0xde || b == 0xdf {
+		items *= 2
+	}
+	if b == 0xdc || b == 0xde {
+		return 3, 0, items, err
+	}
+	return 5, 0, items, err
+}
+
+// isMsgpackString returns whether b starts a msgpack string.
+func isMsgpackString(b byte) bool {
+	return (b >= 0xa0 && b <= 0xbf) || b == 0xd9 || b == 0xda || b == 0xdb
+}
+
+// msgpackUint returns the unsigned integer of the msgpack token in buf, or
+// of the big endian bytes after its first, and zero for a negative one.
+func msgpackUint(buf []byte) uint64 {
+	if buf[0] <= 0x7f {
+		return uint64(buf[0])
+	}
+	var v uint64
+	for _, b := range buf[1:] {
+		v = v<<8 | uint64(b)
+	}
+	if buf[0] >= 0xd0 && buf[0] <= 0xd3 && len(buf) > 1 && buf[1]&0x80 != 0 {
+		return 0
+	}
+	return v
+}
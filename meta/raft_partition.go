@@ -0,0 +1,59 @@
+//go:build raftpartition
+// +build raftpartition
+
+package meta
+
+import (
+	"errors"
+	"net"
+	"sync"
+)
+
+// Built with the raftpartition tag, for tests, a raft layer can be cut off
+// from its peers to simulate a network partition.
+
+// errPartitioned is returned when dialing a peer from a partitioned node.
+var errPartitioned = errors.New("raft layer is partitioned")
+
+// partitioned holds the raft layers cut off from their peers.
+var partitioned = struct {
+	sync.Mutex
+	layers map[*raftLayer]bool
+}{layers: make(map[*raftLayer]bool)}
+
+// checkPartition returns errPartitioned if the layer is cut off from its
+// peers, in which case its connections are dropped.
+func (l *raftLayer) checkPartition() error {
+	partitioned.Lock()
+	defer partitioned.Unlock()
+	if partitioned.layers[l] {
+		return errPartitioned
+	}
+	return nil
+}
+
+// setPartitioned cuts the node off from its peers, or reconnects it. Cutting
+// it off closes every open raft connection.
+func (l *raftLayer) setPartitioned(on bool) {
+	partitioned.Lock()
+	if on {
+		partitioned.layers[l] = true
+	} else {
+		delete(partitioned.layers, l)
+	}
+	partitioned.Unlock()
+	if !on {
+		return
+	}
+
+	l.mu.Lock()
+	var conns []net.Conn
+	for conn := range l.conns {
+		conns = append(conns, conn)
+	}
+	l.mu.Unlock()
+
+	for _, conn := range conns {
+		conn.Close()
+	}
+}
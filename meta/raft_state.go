@@ -1,13 +1,16 @@
 package meta
 
 import (
+	"crypto/tls"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/hashicorp/raft"
@@ -32,11 +35,32 @@ type raftState struct {
 	transport *raft.NetworkTransport
 	peerStore raft.PeerStore
 	raftStore *raftboltdb.BoltStore
+	snapshots raft.SnapshotStore
 	raftLayer *raftLayer
 	ln        net.Listener
 	addr      string
 	logger    *log.Logger
 	path      string
+
+	// avoidLeadership, if set, returns whether the node is to avoid leading
+	// the cluster, as while resigning leadership or in maintenance. Raft
+	// then sends through an avoidingTransport, which fails every RPC.
+	avoidLeadership func() bool
+
+	// cas, if set, holds the certificate authorities raft connections are
+	// verified against when internal-cert is set, as the service reloads
+	// them. Otherwise they are loaded once on open.
+	cas *caReloader
+
+	// configChange describes the membership change being applied, if any.
+	// Raft applies them one at a time so concurrent ones are refused.
+	configChangeMu sync.Mutex
+	configChange   string
+
+	// singleNode is whether raft runs with the single-node timeouts, until
+	// promote restarts it with the configured ones.
+	singleNodeMu sync.Mutex
+	singleNode   bool
 }
 
 func newRaftState(c *Config, addr string) *raftState {
@@ -47,26 +71,35 @@ func newRaftState(c *Config, addr string) *raftState {
 }
 
 func (r *raftState) open(s *store, ln net.Listener, initializePeers []string) error {
+	// Refuse stores we can't read before touching anything on disk.
+	if err := r.checkStoreVersion(); err != nil {
+		return err
+	}
+
 	r.ln = ln
 	r.closing = make(chan struct{})
 
 	// Setup raft configuration.
-	config := raft.DefaultConfig()
-	config.LogOutput = ioutil.Discard
-
-	if r.config.ClusterTracing {
-		config.Logger = r.logger
-	}
-	config.HeartbeatTimeout = time.Duration(r.config.HeartbeatTimeout)
-	config.ElectionTimeout = time.Duration(r.config.ElectionTimeout)
-	config.LeaderLeaseTimeout = time.Duration(r.config.LeaderLeaseTimeout)
-	config.CommitTimeout = time.Duration(r.config.CommitTimeout)
-	// Since we actually never call `removePeer` this is safe.
-	// If in the future we decide to call remove peer we have to re-evaluate how to handle this
-	config.ShutdownOnRemove = false
+	config := r.raftConfig()
 
 	// Build raft layer to multiplex listener.
 	r.raftLayer = newRaftLayer(r.addr, r.ln)
+	r.raftLayer.logger = r.logger
+	r.raftLayer.maxMessageSize = r.config.MaxRaftMessageSize
+	if r.config.InternalCert != "" {
+		cas := r.cas
+		if cas == nil {
+			var err error
+			if cas, err = newCAReloader(r.config.secretProvider()); err != nil {
+				return err
+			}
+		}
+		server, client, err := r.config.internalTLSConfigs(cas)
+		if err != nil {
+			return err
+		}
+		r.raftLayer.serverTLS, r.raftLayer.clientTLS, r.raftLayer.cas = server, client, cas
+	}
 
 	// Create a transport layer
 	r.transport = raft.NewNetworkTransport(r.raftLayer, 3, 10*time.Second, config.LogOutput)
@@ -106,7 +139,10 @@ func (r *raftState) open(s *store, ln net.Listener, initializePeers []string) er
 	}
 
 	// Create the log store and stable store.
-	store, err := raftboltdb.NewBoltStore(filepath.Join(r.path, "raft.db"))
+	store, err := raftboltdb.New(raftboltdb.Options{
+		Path:   filepath.Join(r.path, "raft.db"),
+		NoSync: !r.config.SyncWrites,
+	})
 	if err != nil {
 		return fmt.Errorf("new bolt store: %s", err)
 	}
@@ -117,21 +153,128 @@ func (r *raftState) open(s *store, ln net.Listener, initializePeers []string) er
 	if err != nil {
 		return fmt.Errorf("file snapshot store: %s", err)
 	}
+	r.snapshots = snapshots
+
+	// The single-node timeouts are too short for raft over a network, so
+	// they're only used while the raft peers are this node alone, as the
+	// last snapshot records them. Promotion snapshots, so a node that was
+	// joined isn't started in single-node mode again.
+	if r.config.SingleNode && len(initializePeers) <= 1 {
+		recorded, err := r.snapshotPeers()
+		if err != nil {
+			return fmt.Errorf("snapshot peers: %s", err)
+		}
+		if len(recorded) <= 1 {
+			config.HeartbeatTimeout = SingleNodeRaftTimeout
+			config.ElectionTimeout = SingleNodeRaftTimeout
+			config.LeaderLeaseTimeout = SingleNodeRaftTimeout / 2
+			r.singleNode = true
+		}
+	}
 
 	// Create raft log.
-	ra, err := raft.NewRaft(config, (*storeFSM)(s), store, store, snapshots, r.peerStore, r.transport)
+	ra, err := r.newRaft(s, config)
+	if err != nil {
+		return fmt.Errorf("new raft: %s", err)
+	}
+	r.raft = ra
+
+	r.wg.Add(1)
+	go r.logLeaderChanges(s)
+
+	return nil
+}
+
+// raftConfig returns the raft configuration set by the config.
+func (r *raftState) raftConfig() *raft.Config {
+	config := raft.DefaultConfig()
+	config.LogOutput = ioutil.Discard
+
+	if r.config.ClusterTracing {
+		config.Logger = r.logger
+	}
+	config.HeartbeatTimeout = time.Duration(r.config.HeartbeatTimeout)
+	config.ElectionTimeout = time.Duration(r.config.ElectionTimeout)
+	config.LeaderLeaseTimeout = time.Duration(r.config.LeaderLeaseTimeout)
+	config.CommitTimeout = time.Duration(r.config.CommitTimeout)
+	// Since we actually never call `removePeer` this is safe.
+	// If in the future we decide to call remove peer we have to re-evaluate how to handle this
+	config.ShutdownOnRemove = false
+	return config
+}
+
+// newRaft starts raft with config over the stores and transport of r.
+func (r *raftState) newRaft(s *store, config *raft.Config) (*raft.Raft, error) {
+	var transport raft.Transport = r.transport
+	if r.avoidLeadership != nil {
+		transport = &avoidingTransport{NetworkTransport: r.transport, avoid: r.avoidLeadership}
+	}
+	return raft.NewRaft(config, (*storeFSM)(s), r.raftStore, r.raftStore, r.snapshots, r.peerStore, transport)
+}
+
+// snapshotPeers returns the raft peers the latest snapshot records, or nil
+// if there is no snapshot.
+func (r *raftState) snapshotPeers() ([]string, error) {
+	snapshots, err := r.snapshots.List()
+	if err != nil || len(snapshots) == 0 {
+		return nil, err
+	}
+	return decodeRaftPeers(snapshots[0].Peers)
+}
+
+// inSingleNodeMode returns whether raft runs with the single-node timeouts.
+func (r *raftState) inSingleNodeMode() bool {
+	r.singleNodeMu.Lock()
+	defer r.singleNodeMu.Unlock()
+	return r.singleNode
+}
+
+// promote restarts raft with the configured timeouts in place of the
+// single-node ones, once another meta node has joined a node in single-node
+// mode, so the node goes on as a member of the cluster without a restart.
+// It snapshots first, so the restarted raft restores the metadata from the
+// snapshot rather than applying the whole log again over it, and so the
+// node isn't started in single-node mode again. The node then starts over
+// as a follower until the members elect a leader. The vendored raft can't
+// change its timeouts while running, so this costs the node leadership.
+// The store serializes it with close.
+func (r *raftState) promote(s *store) error {
+	r.singleNodeMu.Lock()
+	defer r.singleNodeMu.Unlock()
+	if !r.singleNode {
+		return nil
+	}
+
+	if err := r.raft.Snapshot().Error(); err != nil {
+		return fmt.Errorf("snapshot: %s", err)
+	}
+	if err := r.raft.Shutdown().Error(); err != nil {
+		return err
+	}
+	close(r.closing)
+	r.wg.Wait()
+	// close closes the new channel, even if raft fails to restart.
+	r.closing = make(chan struct{})
+
+	ra, err := r.newRaft(s, r.raftConfig())
 	if err != nil {
 		return fmt.Errorf("new raft: %s", err)
 	}
+	s.mu.Lock()
 	r.raft = ra
+	r.singleNode = false
+	s.mu.Unlock()
 
 	r.wg.Add(1)
-	go r.logLeaderChanges()
+	go r.logLeaderChanges(s)
 
+	if s.leaderChanged != nil {
+		s.leaderChanged(false)
+	}
 	return nil
 }
 
-func (r *raftState) logLeaderChanges() {
+func (r *raftState) logLeaderChanges(s *store) {
 	defer r.wg.Done()
 	// Logs our current state (Node at 1.2.3.4:8088 [Follower])
 	r.logger.Printf(r.raft.String())
@@ -145,6 +288,12 @@ func (r *raftState) logLeaderChanges() {
 				r.logger.Printf("failed to lookup peers: %v", err)
 			}
 			r.logger.Printf("%v. peers=%v", r.raft.String(), peers)
+
+			// Raft drops notifications nobody is waiting for, so report the
+			// current state rather than the value received.
+			if s.leaderChanged != nil {
+				s.leaderChanged(r.isLeader())
+			}
 		}
 	}
 }
@@ -188,9 +337,12 @@ func (r *raftState) apply(b []byte) error {
 	if r.isClosed() {
 		return raft.ErrRaftShutdown
 	}
+	if max := r.config.MaxRaftMessageSize; max > 0 && int64(len(b)) > max {
+		return fmt.Errorf("command of %d bytes exceeds max-raft-message-size of %d bytes: split it into smaller batches", len(b), max)
+	}
 	// Apply to raft log.
 	f := r.raft.Apply(b, 0)
-	if err := f.Error(); err != nil {
+	if err := r.waitApply(f); err != nil {
 		return err
 	}
 
@@ -207,6 +359,42 @@ func (r *raftState) apply(b []byte) error {
 	return nil
 }
 
+// waitApply waits for f to be applied. A leader that lost its quorum waits
+// for as long as its lease holds, so when writes fail fast it gives up after
+// the quorum-loss-timeout instead: with ErrQuorumLost if a quorum doesn't
+// confirm its leadership within a heartbeat-timeout, and with
+// ErrCommitTimeout if one does, as the commit is then only slow. The entry
+// is still applied if it commits later.
+func (r *raftState) waitApply(f raft.ApplyFuture) error {
+	if r.config.QuorumLossWrites == QuorumLossBlock {
+		return f.Error()
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- f.Error() }()
+	timer := time.NewTimer(time.Duration(r.config.QuorumLossTimeout))
+	defer timer.Stop()
+	select {
+	case err := <-errc:
+		return err
+	case <-timer.C:
+	}
+
+	verified := make(chan error, 1)
+	v := r.raft.VerifyLeader()
+	go func() { verified <- v.Error() }()
+	select {
+	case err := <-errc:
+		return err
+	case err := <-verified:
+		if err == nil {
+			return ErrCommitTimeout
+		}
+	case <-time.After(time.Duration(r.config.HeartbeatTimeout)):
+	}
+	return ErrQuorumLost
+}
+
 func (r *raftState) lastIndex() uint64 {
 	return r.raft.LastIndex()
 }
@@ -229,6 +417,11 @@ func (r *raftState) addPeer(addr string) error {
 		}
 	}
 
+	if err := r.beginConfigChange("add " + addr); err != nil {
+		return err
+	}
+	defer r.endConfigChange()
+
 	if fut := r.raft.AddPeer(addr); fut.Error() != nil {
 		return fut.Error()
 	}
@@ -260,12 +453,126 @@ func (r *raftState) removePeer(addr string) error {
 		return nil
 	}
 
+	if err := r.beginConfigChange("remove " + addr); err != nil {
+		return err
+	}
+	defer r.endConfigChange()
+
 	if fut := r.raft.RemovePeer(addr); fut.Error() != nil {
 		return fut.Error()
 	}
 	return nil
 }
 
+// raftStatus is the JSON representation of the local raft state returned by
+// /raft-status.
+type raftStatus struct {
+	State  string `json:"state"`
+	Leader string `json:"leader"`
+
+	// LeaderHTTP is the HTTP address of the leader, if the metadata has
+	// its meta node yet.
+	LeaderHTTP string `json:"leaderHTTP,omitempty"`
+
+	// NodeID is the ID of the meta node reporting the status, if it has
+	// one, by which a starting node tells that its ID is taken.
+	NodeID uint64 `json:"nodeID,omitempty"`
+
+	Peers     []string `json:"peers"`
+	Term      uint64   `json:"term"`
+	LastIndex uint64   `json:"lastIndex"`
+
+	// CommitIndex is the index of the last entry known to be committed, and
+	// AppliedIndex the last one applied to the local state machine.
+	CommitIndex  uint64 `json:"commitIndex"`
+	AppliedIndex uint64 `json:"appliedIndex"`
+
+	// ApplyLag is the number of committed entries not yet applied, which
+	// reads on this node don't see yet.
+	ApplyLag uint64 `json:"applyLag"`
+
+	// PendingConfigChanges is the number of membership changes in the log
+	// that aren't committed yet, whoever started them. ConfigChange
+	// describes the one this node is applying, if any.
+	PendingConfigChanges int    `json:"pendingConfigChanges"`
+	ConfigChange         string `json:"configChange,omitempty"`
+}
+
+// status returns the current raft status.
+func (r *raftState) status() *raftStatus {
+	st := &raftStatus{
+		State:        r.raft.State().String(),
+		Leader:       r.raft.Leader(),
+		LastIndex:    r.raft.LastIndex(),
+		ConfigChange: r.pendingConfigChange(),
+	}
+	st.Peers, _ = r.peers()
+	stats := r.raft.Stats()
+	st.Term, _ = strconv.ParseUint(stats["term"], 10, 64)
+	st.CommitIndex, _ = strconv.ParseUint(stats["commit_index"], 10, 64)
+	st.AppliedIndex, _ = strconv.ParseUint(stats["applied_index"], 10, 64)
+	if st.CommitIndex > st.AppliedIndex {
+		st.ApplyLag = st.CommitIndex - st.AppliedIndex
+	}
+	st.PendingConfigChanges = r.uncommittedConfigChanges(st.CommitIndex, st.LastIndex)
+	return st
+}
+
+// uncommittedConfigChanges returns the number of membership changes in the
+// log after commit, up to last.
+func (r *raftState) uncommittedConfigChanges(commit, last uint64) int {
+	var n int
+	for i := commit + 1; i <= last; i++ {
+		var l raft.Log
+		if err := r.raftStore.GetLog(i, &l); err != nil {
+			continue
+		}
+		if l.Type == raft.LogAddPeer || l.Type == raft.LogRemovePeer {
+			n++
+		}
+	}
+	return n
+}
+
+// contacts returns when the node last heard from each of its raft peers, by
+// address: the followers answering the leader, and the leader of a
+// follower.
+func (r *raftState) contacts() map[string]time.Time {
+	m := r.raftLayer.contacts()
+	if leader := r.raft.Leader(); leader != "" {
+		if t := r.raft.LastContact(); t.After(m[leader]) {
+			m[leader] = t
+		}
+	}
+	return m
+}
+
+// beginConfigChange marks a membership change described by desc as in
+// progress. It returns ErrConfigChangeInProgress if one already is.
+func (r *raftState) beginConfigChange(desc string) error {
+	r.configChangeMu.Lock()
+	defer r.configChangeMu.Unlock()
+	if r.configChange != "" {
+		return ErrConfigChangeInProgress
+	}
+	r.configChange = desc
+	return nil
+}
+
+func (r *raftState) endConfigChange() {
+	r.configChangeMu.Lock()
+	r.configChange = ""
+	r.configChangeMu.Unlock()
+}
+
+// pendingConfigChange returns the membership change being applied, or an
+// empty string if there is none.
+func (r *raftState) pendingConfigChange() string {
+	r.configChangeMu.Lock()
+	defer r.configChangeMu.Unlock()
+	return r.configChange
+}
+
 func (r *raftState) peers() ([]string, error) {
 	return r.peerStore.Peers()
 }
@@ -291,6 +598,23 @@ type raftLayer struct {
 	ln     net.Listener
 	conn   chan net.Conn
 	closed chan struct{}
+	logger *log.Logger
+
+	// serverTLS and clientTLS, if set, secure the raft connections the
+	// layer accepts and dials. Dialed connections verify the peer against
+	// the certificate authorities cas last loaded.
+	serverTLS *tls.Config
+	clientTLS *tls.Config
+	cas       *caReloader
+
+	// maxMessageSize, if set, fails accepted connections sending a string
+	// or byte array larger than it.
+	maxMessageSize int64
+
+	// conns holds every open raft connection, so the contact with the
+	// peers they were dialed to can be read off them.
+	mu    sync.Mutex
+	conns map[*raftLayerConn]struct{}
 }
 
 type raftLayerAddr struct {
@@ -312,6 +636,7 @@ func newRaftLayer(addr string, ln net.Listener) *raftLayer {
 		ln:     ln,
 		conn:   make(chan net.Conn),
 		closed: make(chan struct{}),
+		conns:  make(map[*raftLayerConn]struct{}),
 	}
 }
 
@@ -322,6 +647,10 @@ func (l *raftLayer) Addr() net.Addr {
 
 // Dial creates a new network connection.
 func (l *raftLayer) Dial(addr string, timeout time.Duration) (net.Conn, error) {
+	if err := l.checkPartition(); err != nil {
+		return nil, err
+	}
+
 	conn, err := net.DialTimeout("tcp", addr, timeout)
 	if err != nil {
 		return nil, err
@@ -332,11 +661,148 @@ func (l *raftLayer) Dial(addr string, timeout time.Duration) (net.Conn, error) {
 		conn.Close()
 		return nil, err
 	}
-	return conn, err
+
+	if l.clientTLS != nil {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		config := l.clientTLS.Clone()
+		config.ServerName = host
+		if l.cas != nil {
+			config.RootCAs = l.cas.Pool()
+		}
+		tc := tls.Client(conn, config)
+		if err := handshake(tc, timeout); err != nil {
+			tc.Close()
+			return nil, fmt.Errorf("raft TLS handshake with %s: %s", addr, err)
+		}
+		conn = tc
+	}
+	return l.track(conn, addr), nil
 }
 
-// Accept waits for the next connection.
-func (l *raftLayer) Accept() (net.Conn, error) { return l.ln.Accept() }
+// Accept waits for the next connection. A TLS connection is returned before
+// its handshake, which runs once it is first read or written.
+func (l *raftLayer) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.ln.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if l.checkPartition() != nil {
+			conn.Close()
+			continue
+		}
+
+		if l.serverTLS != nil {
+			conn = &handshakingConn{Conn: tls.Server(conn, l.serverTLS), logger: l.logger}
+		}
+		if l.maxMessageSize > 0 {
+			conn = newLimitedRaftConn(conn, l.maxMessageSize)
+		}
+		return l.track(conn, ""), nil
+	}
+}
+
+// handshakingConn runs the TLS handshake of an accepted raft connection on
+// its first read or write, within raftHandshakeTimeout, so a peer slow to
+// handshake only holds up its own connection and not Accept.
+type handshakingConn struct {
+	*tls.Conn
+	logger *log.Logger
+
+	once sync.Once
+	err  error
+}
+
+func (c *handshakingConn) handshake() error {
+	c.once.Do(func() {
+		if c.err = handshake(c.Conn, raftHandshakeTimeout); c.err != nil {
+			c.logger.Printf("Rejected raft connection from %s: %s", c.RemoteAddr(), c.err)
+			c.Conn.Close()
+		}
+	})
+	return c.err
+}
+
+func (c *handshakingConn) Read(b []byte) (int, error) {
+	if err := c.handshake(); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(b)
+}
+
+func (c *handshakingConn) Write(b []byte) (int, error) {
+	if err := c.handshake(); err != nil {
+		return 0, err
+	}
+	return c.Conn.Write(b)
+}
+
+// handshake runs the TLS handshake of conn, giving up after timeout.
+func handshake(conn *tls.Conn, timeout time.Duration) error {
+	conn.SetDeadline(time.Now().Add(timeout))
+	if err := conn.Handshake(); err != nil {
+		return err
+	}
+	return conn.SetDeadline(time.Time{})
+}
+
+// track records conn as open until it is closed.
+func (l *raftLayer) track(conn net.Conn, peer string) net.Conn {
+	tc := &raftLayerConn{Conn: conn, l: l, peer: peer}
+	l.mu.Lock()
+	l.conns[tc] = struct{}{}
+	l.mu.Unlock()
+	return tc
+}
+
+// raftLayerConn is a raft connection that stops being tracked once closed.
+// Reads on a connection dialed to peer record contact with it.
+type raftLayerConn struct {
+	// lastRead is when data was last read, in Unix nanoseconds, accessed
+	// atomically.
+	lastRead int64
+
+	net.Conn
+	l    *raftLayer
+	peer string
+}
+
+func (c *raftLayerConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 && c.peer != "" {
+		atomic.StoreInt64(&c.lastRead, time.Now().UnixNano())
+	}
+	return n, err
+}
+
+func (c *raftLayerConn) Close() error {
+	c.l.mu.Lock()
+	delete(c.l.conns, c)
+	c.l.mu.Unlock()
+	return c.Conn.Close()
+}
+
+// contacts returns when each peer dialed last sent data back on a
+// connection still open, by address.
+func (l *raftLayer) contacts() map[string]time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	m := make(map[string]time.Time)
+	for c := range l.conns {
+		ns := atomic.LoadInt64(&c.lastRead)
+		if c.peer == "" || ns == 0 {
+			continue
+		}
+		if t := time.Unix(0, ns); t.After(m[c.peer]) {
+			m[c.peer] = t
+		}
+	}
+	return m
+}
 
 // Close closes the layer.
 func (l *raftLayer) Close() error { return l.ln.Close() }
@@ -0,0 +1,86 @@
+package meta
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-msgpack/codec"
+	"github.com/hashicorp/raft"
+)
+
+// Ensure reads on a raft connection dialed to a peer record contact with it
+// until the connection is closed.
+func TestRaftLayer_Contacts(t *testing.T) {
+	l := newRaftLayer("127.0.0.1:8088", nil)
+
+	local, remote := net.Pipe()
+	defer remote.Close()
+	conn := l.track(local, "127.0.0.1:8089")
+	if m := l.contacts(); len(m) != 0 {
+		t.Fatalf("contact before any read: %v", m)
+	}
+
+	go remote.Write([]byte("x"))
+	if _, err := conn.Read(make([]byte, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if m := l.contacts(); m["127.0.0.1:8089"].IsZero() {
+		t.Fatalf("no contact recorded: %v", m)
+	}
+
+	conn.Close()
+	if m := l.contacts(); len(m) != 0 {
+		t.Fatalf("contact kept after close: %v", m)
+	}
+}
+
+// Ensure a raft connection passes requests through untouched, along with the
+// snapshot after an InstallSnapshot request however large, and fails on a
+// log entry larger than max-raft-message-size.
+func TestLimitedRaftConn(t *testing.T) {
+	var in bytes.Buffer
+	enc := codec.NewEncoder(&in, &codec.MsgpackHandle{})
+	send := func(rpcType byte, req interface{}) {
+		in.WriteByte(rpcType)
+		if err := enc.Encode(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	small := &raft.AppendEntriesRequest{Term: 1, Leader: []byte("127.0.0.1:8089"), Entries: []*raft.Log{
+		{Index: 1, Term: 1, Type: raft.LogCommand, Data: bytes.Repeat([]byte("x"), 64)},
+	}}
+	send(0, small)
+	send(raftRPCInstallSnapshot, &raft.InstallSnapshotRequest{Term: 1, Size: 1000})
+	in.Write(bytes.Repeat([]byte{0xc6}, 1000))
+	send(0, small)
+
+	want := in.Bytes()
+	conn := newLimitedRaftConn(&readerConn{Reader: bytes.NewReader(want)}, 100)
+	got, err := ioutil.ReadAll(conn)
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(got, want) {
+		t.Fatal("requests changed passing through")
+	}
+
+	in.Reset()
+	send(0, &raft.AppendEntriesRequest{Term: 1, Entries: []*raft.Log{
+		{Index: 1, Term: 1, Type: raft.LogCommand, Data: bytes.Repeat([]byte("x"), 101)},
+	}})
+	conn = newLimitedRaftConn(&readerConn{Reader: bytes.NewReader(in.Bytes())}, 100)
+	if _, err := ioutil.ReadAll(conn); err == nil || !strings.Contains(err.Error(), "max-raft-message-size") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// readerConn is a connection reading from Reader.
+type readerConn struct {
+	net.Conn
+	io.Reader
+}
+
+func (c *readerConn) Read(p []byte) (int, error) { return c.Reader.Read(p) }
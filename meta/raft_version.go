@@ -0,0 +1,100 @@
+package meta
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// raftStoreVersion is the on-disk raft store format this binary writes.
+	raftStoreVersion = 1
+
+	// raftVersionFile holds the raft store format version in the data dir.
+	raftVersionFile = "raft.version"
+)
+
+// raftStoreMigrations upgrade a raft store from the version they're keyed by
+// to the next one. Stores written before the version file existed are
+// version 0 and need no change to be read as version 1.
+var raftStoreMigrations = map[int]func(path string) error{
+	0: func(path string) error { return nil },
+}
+
+// checkStoreVersion makes sure the raft store at r.path can be read by this
+// binary. A newer store is refused since writing to it could corrupt it; an
+// older one is migrated step by step, after taking a backup of the log.
+func (r *raftState) checkStoreVersion() error {
+	versionPath := filepath.Join(r.path, raftVersionFile)
+	dbPath := filepath.Join(r.path, "raft.db")
+
+	version, err := readRaftStoreVersion(versionPath)
+	if os.IsNotExist(err) {
+		if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+			// A brand new store.
+			return writeRaftStoreVersion(versionPath, raftStoreVersion)
+		}
+		version = 0
+	} else if err != nil {
+		return err
+	}
+
+	if version > raftStoreVersion {
+		return fmt.Errorf("raft store version %d is newer than the supported version %d, refusing to start: upgrade influxd-meta or restore a compatible data dir", version, raftStoreVersion)
+	} else if version == raftStoreVersion {
+		return nil
+	}
+
+	backup := fmt.Sprintf("%s.v%d.bak", dbPath, version)
+	if err := copyFile(dbPath, backup); err != nil {
+		return fmt.Errorf("back up raft store before migration: %s", err)
+	}
+
+	for ; version < raftStoreVersion; version++ {
+		r.logger.Printf("migrating raft store from version %d to %d", version, version+1)
+		migrate, ok := raftStoreMigrations[version]
+		if !ok {
+			return fmt.Errorf("no migration for raft store version %d", version)
+		}
+		if err := migrate(r.path); err != nil {
+			return fmt.Errorf("migrate raft store from version %d: %s (backup kept at %s)", version, err, backup)
+		}
+		// Record every step so an interrupted migration resumes where it
+		// stopped.
+		if err := writeRaftStoreVersion(versionPath, version+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readRaftStoreVersion(path string) (int, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid raft store version in %s: %s", path, err)
+	}
+	return v, nil
+}
+
+func writeRaftStoreVersion(path string, v int) error {
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(strconv.Itoa(v)+"\n"), 0666); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func copyFile(src, dst string) error {
+	b, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(dst, b, 0666)
+}
@@ -0,0 +1,74 @@
+package meta_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Ensure a raft store written by a newer version is refused on open.
+func TestMetaService_Open_NewerRaftStoreVersion(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	if err := ioutil.WriteFile(filepath.Join(cfg.Dir, "raft.version"), []byte("1000\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newService(cfg)
+	defer s.Close()
+	err := s.Open()
+	if err == nil {
+		t.Fatal("expected open to fail")
+	} else if !strings.Contains(err.Error(), "raft store version 1000 is newer than the supported version") {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The store must be left untouched.
+	if _, err := os.Stat(filepath.Join(cfg.Dir, "raft.db")); !os.IsNotExist(err) {
+		t.Fatalf("expected no raft.db to be created: %v", err)
+	}
+}
+
+// Ensure a raft store written before versioning is migrated on open.
+func TestMetaService_Open_MigratesUnversionedRaftStore(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	if _, err := c.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Make it look like a store from before the version file existed.
+	if err := os.Remove(filepath.Join(d, "raft.version")); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := newConfig()
+	os.RemoveAll(cfg.Dir)
+	cfg.Dir = d
+	s = openService(t, cfg)
+	defer s.Close()
+	c = newClient(s)
+	defer c.Close()
+
+	if b, err := ioutil.ReadFile(filepath.Join(d, "raft.version")); err != nil {
+		t.Fatal(err)
+	} else if strings.TrimSpace(string(b)) != "1" {
+		t.Fatalf("unexpected raft store version: %q", b)
+	}
+	if _, err := os.Stat(filepath.Join(d, "raft.db.v0.bak")); err != nil {
+		t.Fatalf("expected a backup of the raft store: %s", err)
+	}
+	if db, err := c.Database("db0"); err != nil || db == nil {
+		t.Fatalf("database lost in migration: %v, %v", db, err)
+	}
+}
@@ -0,0 +1,265 @@
+package meta
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultHTTPRateLimitBurst is the default number of requests a remote IP
+// may make at once, beyond http-rate-limit.
+const DefaultHTTPRateLimitBurst = 50
+
+// maxRateLimitBuckets is how many remote IPs the rate limiter tracks before
+// it drops those that are back to a full bucket.
+const maxRateLimitBuckets = 10000
+
+// rateLimiter is a token bucket per remote IP, refilled at rate tokens a
+// second up to burst. Each request takes a token. A rate of zero limits
+// nothing.
+type rateLimiter struct {
+	// isPeer returns whether an IP is that of a node of the cluster.
+	isPeer func(ip net.IP) bool
+
+	// rejected counts the requests answered 429, by handler.
+	rejected *Counter
+
+	mu    sync.Mutex
+	rate  float64
+	burst float64
+
+	// allow holds the networks never limited, besides the cluster's nodes.
+	allow []*net.IPNet
+
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate float64, burst int, allow []*net.IPNet, rejected *Counter) *rateLimiter {
+	return &rateLimiter{
+		rate:     rate,
+		burst:    float64(burst),
+		allow:    allow,
+		rejected: rejected,
+		buckets:  make(map[string]*tokenBucket),
+	}
+}
+
+// parseAllowlist parses the IPs and CIDR networks of http-rate-limit-allowlist.
+func parseAllowlist(a []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, s := range a {
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address %q", s)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// set changes the rate, burst and allowlist of the limiter. Every remote IP
+// starts over with a full bucket.
+func (l *rateLimiter) set(rate float64, burst int, allow []*net.IPNet) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate, l.burst, l.allow = rate, float64(burst), allow
+	l.buckets = make(map[string]*tokenBucket)
+}
+
+// enabled returns whether the limiter limits anything.
+func (l *rateLimiter) enabled() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate > 0
+}
+
+// allowed returns whether ip is never limited.
+func (l *rateLimiter) allowed(ip net.IP) bool {
+	l.mu.Lock()
+	allow := l.allow
+	l.mu.Unlock()
+	for _, n := range allow {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return l.isPeer != nil && l.isPeer(ip)
+}
+
+// take takes a token from the bucket of key at now. If there is none, it
+// returns false and how long until there is.
+func (l *rateLimiter) take(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.buckets[key]
+	if b == nil {
+		if len(l.buckets) >= maxRateLimitBuckets {
+			l.dropFull(now)
+		}
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// dropFull drops the buckets that have refilled by now, which are the same
+// as new ones.
+func (l *rateLimiter) dropFull(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// rateLimiting answers 429 Too Many Requests, with a Retry-After header, to
+// a remote IP that made more requests than http-rate-limit allows. The
+// cluster's own nodes and the allowlist are never limited, nor are requests
+// over a Unix socket, which have no remote IP.
+func rateLimiting(inner http.Handler, name string, h *handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.s == nil || h.s.rateLimiter == nil || !h.s.rateLimiter.enabled() {
+			inner.ServeHTTP(w, r)
+			return
+		}
+		l := h.s.rateLimiter
+
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		ip := net.ParseIP(host)
+		if ip == nil || l.allowed(ip) {
+			inner.ServeHTTP(w, r)
+			return
+		}
+
+		if ok, wait := l.take(ip.String(), time.Now()); !ok {
+			l.rejected.Inc(name)
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		inner.ServeHTTP(w, r)
+	})
+}
+
+// clusterPeersRefreshInterval is how often the IPs of the cluster's nodes
+// are resolved again even though their hosts didn't change, as DNS may have.
+const clusterPeersRefreshInterval = time.Minute
+
+// clusterPeers caches the IPs of the meta and data nodes of the cluster.
+// watchClusterPeers resolves them again off the request path whenever
+// their hosts change.
+type clusterPeers struct {
+	mu  sync.RWMutex
+	ips map[string]bool
+}
+
+// isClusterPeer returns whether ip is that of a meta or data node of the
+// cluster, as the local metadata last had it.
+func (s *Service) isClusterPeer(ip net.IP) bool {
+	p := &s.clusterPeers
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.ips[ip.String()]
+}
+
+// watchClusterPeers resolves the IPs isClusterPeer checks against every time
+// the hosts of the cluster's nodes change, and every
+// clusterPeersRefreshInterval, until the service closes.
+func (s *Service) watchClusterPeers() {
+	ticker := time.NewTicker(clusterPeersRefreshInterval)
+	defer ticker.Stop()
+
+	var last string
+	for {
+		index, hosts := s.store.nodeHosts()
+		if key := strings.Join(hosts, ","); key != last {
+			ips := resolveHosts(hosts)
+			s.clusterPeers.mu.Lock()
+			s.clusterPeers.ips = ips
+			s.clusterPeers.mu.Unlock()
+			last = key
+		}
+
+		select {
+		case <-s.store.afterIndex(index):
+		case <-ticker.C:
+			last = ""
+		case <-s.closing:
+			return
+		}
+	}
+}
+
+// nodeHosts returns the sorted hosts of the meta and data nodes of the
+// cluster, along with the index of the metadata they are from.
+func (s *store) nodeHosts() (uint64, []string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var hosts []string
+	for _, nodes := range []NodeInfos{s.data.MetaNodes, s.data.DataNodes} {
+		for _, n := range nodes {
+			for _, addr := range []string{n.Host, n.TCPHost} {
+				if host, _, err := net.SplitHostPort(addr); err == nil {
+					hosts = append(hosts, host)
+				}
+			}
+		}
+	}
+	sort.Strings(hosts)
+	return s.data.Data.Index, hosts
+}
+
+// resolveHosts returns the IPs of hosts. Those that don't resolve are left
+// out.
+func resolveHosts(hosts []string) map[string]bool {
+	ips := make(map[string]bool)
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			ips[ip.String()] = true
+			continue
+		}
+		addrs, err := net.LookupIP(host)
+		if err != nil {
+			continue
+		}
+		for _, ip := range addrs {
+			ips[ip.String()] = true
+		}
+	}
+	return ips
+}
@@ -0,0 +1,160 @@
+package meta
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Ensure a token bucket lets a burst through, then refills at the rate.
+func TestRateLimiter_Take(t *testing.T) {
+	l := newRateLimiter(2, 3, nil, NewRegistry().NewCounter("rejected", "", "handler"))
+	now := time.Unix(0, 0)
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.take("192.0.2.1", now); !ok {
+			t.Fatalf("request %d of the burst limited", i)
+		}
+	}
+	if ok, wait := l.take("192.0.2.1", now); ok || wait != 500*time.Millisecond {
+		t.Fatalf("unexpected take past the burst: %v, %s", ok, wait)
+	}
+
+	// Another IP has a bucket of its own.
+	if ok, _ := l.take("192.0.2.2", now); !ok {
+		t.Fatal("other IP limited")
+	}
+
+	if ok, _ := l.take("192.0.2.1", now.Add(500*time.Millisecond)); !ok {
+		t.Fatal("bucket not refilled")
+	}
+	if ok, _ := l.take("192.0.2.1", now.Add(500*time.Millisecond)); ok {
+		t.Fatal("bucket refilled past the rate")
+	}
+}
+
+// Ensure the API answers 429 with Retry-After past the rate limit, except to
+// the allowlist and the cluster's nodes, and counts the requests rejected.
+func TestRateLimiting(t *testing.T) {
+	c := NewConfig()
+	c.HTTPRateLimit = 1
+	c.HTTPRateLimitBurst = 2
+	c.HTTPRateLimitAllowlist = []string{"10.1.0.0/16", "192.0.2.8"}
+	s := NewService(c)
+	s.rateLimiter.isPeer = func(ip net.IP) bool { return ip.Equal(net.ParseIP("192.0.2.9")) }
+
+	h := newHandler(c, s).WrapHandler("ping", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	get := func(remoteAddr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/ping", nil)
+		r.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := get("192.0.2.1:5000"); w.Code != http.StatusNoContent {
+			t.Fatalf("request %d of the burst limited: %d", i, w.Code)
+		}
+	}
+	w := get("192.0.2.1:5001")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("unexpected status past the limit: %d", w.Code)
+	} else if v := w.Header().Get("Retry-After"); v != "1" {
+		t.Fatalf("unexpected Retry-After: %q", v)
+	}
+
+	for _, addr := range []string{"10.1.2.3:5000", "192.0.2.8:5000", "192.0.2.9:5000"} {
+		for i := 0; i < 5; i++ {
+			if w := get(addr); w.Code != http.StatusNoContent {
+				t.Fatalf("%s limited: %d", addr, w.Code)
+			}
+		}
+	}
+
+	if n := s.rateLimited.Value("ping"); n != 1 {
+		t.Fatalf("unexpected rejected count: %v", n)
+	}
+}
+
+// Ensure an invalid allowlist entry fails validation.
+func TestConfig_Validate_HTTPRateLimitAllowlist(t *testing.T) {
+	c := NewConfig()
+	c.HTTPRateLimitAllowlist = []string{"10.0.0.0/8", "not-an-ip"}
+	if err := c.Validate(); err == nil || !strings.Contains(err.Error(), `http-rate-limit-allowlist: invalid IP address "not-an-ip"`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure the rate limit can be turned on and off while the service runs.
+func TestService_SetHTTPRateLimit(t *testing.T) {
+	c := NewConfig()
+	s := NewService(c)
+	h := newHandler(c, s).WrapHandler("ping", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	get := func() int {
+		r := httptest.NewRequest("GET", "/ping", nil)
+		r.RemoteAddr = "192.0.2.1:5000"
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	for i := 0; i < 3; i++ {
+		if code := get(); code != http.StatusNoContent {
+			t.Fatalf("limited without a rate limit: %d", code)
+		}
+	}
+
+	if err := s.SetHTTPRateLimit(1, 1, nil); err != nil {
+		t.Fatal(err)
+	}
+	if code := get(); code != http.StatusNoContent {
+		t.Fatalf("first request limited: %d", code)
+	} else if code := get(); code != http.StatusTooManyRequests {
+		t.Fatalf("unexpected status past the limit: %d", code)
+	}
+
+	if err := s.SetHTTPRateLimit(1, 1, []string{"bad"}); err == nil {
+		t.Fatal("expected an error for an invalid allowlist")
+	}
+	if err := s.SetHTTPRateLimit(0, 1, nil); err != nil {
+		t.Fatal(err)
+	} else if code := get(); code != http.StatusNoContent {
+		t.Fatalf("limited after turning the rate limit off: %d", code)
+	}
+}
+
+// Ensure the IPs of the cluster's nodes are resolved again once their hosts
+// change, and not on the request path.
+func TestService_WatchClusterPeers(t *testing.T) {
+	s := NewService(NewConfig())
+	s.store = newStore(s.config, "", "")
+	s.store.data.MetaNodes = NodeInfos{{ID: 1, Host: "192.0.2.1:8091", TCPHost: "192.0.2.1:8089"}}
+	go s.watchClusterPeers()
+	defer close(s.closing)
+
+	waitPeer := func(ip string, want bool) {
+		for deadline := time.Now().Add(5 * time.Second); s.isClusterPeer(net.ParseIP(ip)) != want; time.Sleep(10 * time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("%s: got cluster peer %v, want %v", ip, !want, want)
+			}
+		}
+	}
+	waitPeer("192.0.2.1", true)
+
+	s.store.mu.Lock()
+	s.store.data = s.store.data.Clone()
+	s.store.data.Data.Index++
+	s.store.data.DataNodes = NodeInfos{{ID: 2, Host: "192.0.2.2:8086", TCPHost: "192.0.2.2:8088"}}
+	close(s.store.dataChanged)
+	s.store.dataChanged = make(chan struct{})
+	s.store.mu.Unlock()
+	waitPeer("192.0.2.2", true)
+	waitPeer("192.0.2.3", false)
+}
@@ -0,0 +1,126 @@
+package meta
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/hashicorp/go-msgpack/codec"
+	"github.com/hashicorp/raft"
+	"github.com/hashicorp/raft-boltdb"
+)
+
+// recoverBackupSuffix is appended to the raft log and snapshots moved aside
+// by RecoverSingleNode.
+const recoverBackupSuffix = ".pre-recover"
+
+// RecoverSingleNode resets the meta data dir at dir, of a stopped meta node,
+// into a single node cluster whose only member and voter is the meta node id.
+// The metadata rebuilt from the raft log is kept, with every other meta node
+// removed, as a fresh snapshot; the old raft log and snapshots are moved
+// aside. The node must then be started without join peers, and becomes the
+// leader on its own.
+func RecoverSingleNode(dir string, id uint64) (*Data, error) {
+	if err := checkRaftLogUnlocked(filepath.Join(dir, "raft.db")); err != nil {
+		return nil, err
+	}
+
+	start, entries, last, err := readRaftLog(dir)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ReplayCommands(start, entries)
+	if err != nil {
+		return nil, err
+	}
+
+	self := data.MetaNode(id)
+	if self == nil {
+		return nil, fmt.Errorf("meta node %d: %s", id, ErrNodeNotFound)
+	}
+	data.MetaNodes = NodeInfos{*self}
+	data.Data.Index, data.Data.Term = last.Index, last.Term
+
+	for _, name := range []string{"raft.db", "snapshots"} {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		if err := os.Rename(path, path+recoverBackupSuffix); err != nil {
+			return nil, err
+		}
+	}
+
+	// Raft must not start a term it already voted in.
+	logs, err := raftboltdb.NewBoltStore(filepath.Join(dir, "raft.db"))
+	if err != nil {
+		return nil, err
+	}
+	defer logs.Close()
+	if err := logs.SetUint64([]byte("CurrentTerm"), last.Term); err != nil {
+		return nil, err
+	}
+
+	peers, err := encodeRaftPeers([]string{self.TCPHost})
+	if err != nil {
+		return nil, err
+	}
+	snapshots, err := raft.NewFileSnapshotStore(dir, raftSnapshotsRetained, ioutil.Discard)
+	if err != nil {
+		return nil, err
+	}
+	sink, err := snapshots.Create(last.Index, last.Term, peers)
+	if err != nil {
+		return nil, err
+	}
+	if err := (&storeFSMSnapshot{Data: data}).Persist(sink); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// checkRaftLogUnlocked returns an error if the raft log at path is held open
+// by a running meta node.
+func checkRaftLogUnlocked(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second, ReadOnly: true})
+	if err == bolt.ErrTimeout {
+		return fmt.Errorf("%s is in use: stop the meta node first", path)
+	} else if err != nil {
+		return err
+	}
+	return db.Close()
+}
+
+// encodeRaftPeers encodes peers the way raft stores them in snapshots.
+func encodeRaftPeers(peers []string) ([]byte, error) {
+	enc := make([][]byte, len(peers))
+	for i, p := range peers {
+		enc[i] = []byte(p)
+	}
+	var buf bytes.Buffer
+	if err := codec.NewEncoder(&buf, &codec.MsgpackHandle{}).Encode(enc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeRaftPeers decodes peers encoded the way raft stores them in
+// snapshots.
+func decodeRaftPeers(b []byte) ([]string, error) {
+	var enc [][]byte
+	if err := codec.NewDecoder(bytes.NewReader(b), &codec.MsgpackHandle{}).Decode(&enc); err != nil {
+		return nil, err
+	}
+	peers := make([]string, len(enc))
+	for i, p := range enc {
+		peers[i] = string(p)
+	}
+	return peers, nil
+}
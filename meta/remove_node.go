@@ -0,0 +1,114 @@
+package meta
+
+import (
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+// removeNodeLeaderTimeout is how long removing the leading meta node waits
+// for the rest of the cluster to elect a new leader.
+const removeNodeLeaderTimeout = 10 * time.Second
+
+// RemoveNodeResult describes what removing a node from the cluster did.
+type RemoveNodeResult struct {
+	NodeID   uint64 `json:"nodeID"`
+	NodeType string `json:"nodeType"`
+	Host     string `json:"host"`
+
+	// RaftPeerRemoved is the address removed from the raft configuration,
+	// when removing a meta node.
+	RaftPeerRemoved string `json:"raftPeerRemoved,omitempty"`
+
+	// LeaderBefore and LeaderAfter are the raft leader before and after
+	// removing a meta node. LeaderAfter is empty if no new leader was
+	// elected in time.
+	LeaderBefore          string `json:"leaderBefore,omitempty"`
+	LeaderAfter           string `json:"leaderAfter,omitempty"`
+	LeadershipTransferred bool   `json:"leadershipTransferred"`
+
+	// ReassignedShards are the shards of a removed data node that were
+	// given to other data nodes, and DeletedShardGroups the shard groups
+	// that were deleted since none of their shards had an owner left.
+	ReassignedShards   []ShardReassignment `json:"reassignedShards"`
+	DeletedShardGroups []uint64            `json:"deletedShardGroups"`
+}
+
+// ShardReassignment is a shard that lost an owner and the data nodes it was
+// given to instead.
+type ShardReassignment struct {
+	Database        string   `json:"database"`
+	RetentionPolicy string   `json:"rp"`
+	ShardGroupID    uint64   `json:"shardGroupID"`
+	ShardID         uint64   `json:"shardID"`
+	From            uint64   `json:"from"`
+	To              []uint64 `json:"to"`
+}
+
+// shardReassignments compares the shards owned by the data node id in
+// before with after, once the node is removed, and returns the shards given
+// new owners and the shard groups deleted.
+func shardReassignments(before, after *Data, id uint64) ([]ShardReassignment, []uint64) {
+	reassigned, deleted := []ShardReassignment{}, []uint64{}
+	for _, di := range before.Data.Databases {
+		for _, rpi := range di.RetentionPolicies {
+			for _, sgi := range rpi.ShardGroups {
+				if sgi.Deleted() || !shardGroupOwnedBy(&sgi, id) {
+					continue
+				}
+
+				// Find the shard group once the node is gone.
+				other, _ := after.Data.RetentionPolicy(di.Name, rpi.Name)
+				var sg *meta.ShardGroupInfo
+				if other != nil {
+					for i := range other.ShardGroups {
+						if other.ShardGroups[i].ID == sgi.ID {
+							sg = &other.ShardGroups[i]
+						}
+					}
+				}
+				if sg == nil || sg.Deleted() {
+					deleted = append(deleted, sgi.ID)
+					continue
+				}
+
+				for _, si := range sgi.Shards {
+					if !si.OwnedBy(id) {
+						continue
+					}
+					r := ShardReassignment{
+						Database:        di.Name,
+						RetentionPolicy: rpi.Name,
+						ShardGroupID:    sgi.ID,
+						ShardID:         si.ID,
+						From:            id,
+					}
+					for _, s := range sg.Shards {
+						if s.ID != si.ID {
+							continue
+						}
+						for _, o := range s.Owners {
+							if !si.OwnedBy(o.NodeID) {
+								r.To = append(r.To, o.NodeID)
+							}
+						}
+					}
+					if len(r.To) > 0 {
+						reassigned = append(reassigned, r)
+					}
+				}
+			}
+		}
+	}
+	return reassigned, deleted
+}
+
+// shardGroupOwnedBy returns whether the data node id owns a shard of sgi.
+func shardGroupOwnedBy(sgi *meta.ShardGroupInfo, id uint64) bool {
+	for _, si := range sgi.Shards {
+		if si.OwnedBy(id) {
+			return true
+		}
+	}
+	return false
+}
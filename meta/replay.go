@@ -0,0 +1,138 @@
+package meta
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/raft"
+	"github.com/hashicorp/raft-boltdb"
+)
+
+// ReplayLog rebuilds the metadata held by the meta data dir at dir, usually
+// a backup of one, by applying its raft log to a throwaway in-memory state
+// machine, starting from its latest snapshot if it has one. Nothing in dir
+// is modified and no raft or network state is touched.
+func ReplayLog(dir string) (*Data, error) {
+	start, entries, _, err := readRaftLog(dir)
+	if err != nil {
+		return nil, err
+	}
+	return ReplayCommands(start, entries)
+}
+
+// readRaftLog returns the latest raft snapshot of the meta data dir at dir,
+// or nil if there is none, the log entries that follow it, and the index and
+// term of the last of them.
+func readRaftLog(dir string) (*Data, []*raft.Log, *raft.Log, error) {
+	// The bolt store writes on open, so read a copy of the log.
+	tmp, err := ioutil.TempDir("", "influxd-meta-replay")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer os.RemoveAll(tmp)
+	if err := copyFile(filepath.Join(dir, "raft.db"), filepath.Join(tmp, "raft.db")); err != nil {
+		return nil, nil, nil, err
+	}
+	logs, err := raftboltdb.NewBoltStore(filepath.Join(tmp, "raft.db"))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer logs.Close()
+
+	start, snapshot, err := latestSnapshot(dir)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	last := &raft.Log{}
+	if snapshot != nil {
+		last.Index, last.Term = snapshot.Index, snapshot.Term
+	}
+
+	first, err := logs.FirstIndex()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	lastIndex, err := logs.LastIndex()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if first > last.Index+1 && lastIndex > 0 {
+		return nil, nil, nil, fmt.Errorf("log starts at index %d but the latest snapshot is at index %d", first, last.Index)
+	}
+
+	var entries []*raft.Log
+	for i := last.Index + 1; i <= lastIndex; i++ {
+		var l raft.Log
+		if err := logs.GetLog(i, &l); err != nil {
+			return nil, nil, nil, fmt.Errorf("read log index %d: %s", i, err)
+		}
+		entries = append(entries, &l)
+		last = &l
+	}
+	return start, entries, last, nil
+}
+
+// latestSnapshot returns the latest raft snapshot in dir that passes its
+// checksum, and its metadata, or nil if there is none. As when raft loads
+// them, a corrupt snapshot is passed over for the one before it.
+func latestSnapshot(dir string) (*Data, *raft.SnapshotMeta, error) {
+	snapshots, err := openSnapshotStore(dir)
+	if err != nil || snapshots == nil {
+		return nil, nil, err
+	}
+	metas, err := snapshots.List()
+	if err != nil {
+		return nil, nil, err
+	} else if len(metas) == 0 {
+		return nil, nil, nil
+	}
+
+	var firstErr error
+	for _, m := range metas {
+		data, meta, err := readRaftSnapshot(snapshots, m.ID)
+		if err == nil {
+			return data, meta, nil
+		} else if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, nil, fmt.Errorf("no valid snapshot: %s", firstErr)
+}
+
+// ReplayCommands applies the command entries of logs, in order, to a copy of
+// start, or to empty metadata if start is nil, and returns the result.
+// Commands that fail to apply are skipped, as they were when first applied.
+func ReplayCommands(start *Data, logs []*raft.Log) (*Data, error) {
+	s := newStore(NewConfig(), "", "")
+	// Without raft the node is never the leader, so commands that would
+	// change raft membership leave it alone.
+	s.raftState = newRaftState(s.config, "")
+	if start != nil {
+		s.data = start.Clone()
+	}
+
+	fsm := (*storeFSM)(s)
+	for _, l := range logs {
+		if l.Type != raft.LogCommand {
+			continue
+		}
+		if err := replayCommand(fsm, l); err != nil {
+			return nil, err
+		}
+	}
+	return s.data, nil
+}
+
+// replayCommand applies l to fsm, turning the panics of corrupt or unknown
+// commands into errors.
+func replayCommand(fsm *storeFSM, l *raft.Log) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("replay log index %d: %v", l.Index, r)
+		}
+	}()
+	fsm.Apply(l)
+	return nil
+}
@@ -0,0 +1,91 @@
+package meta
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/hashicorp/raft"
+	"github.com/hashicorp/raft-boltdb"
+	"github.com/zhexuany/influxcloud/meta/internal"
+)
+
+// Ensure replaying a backup's raft log rebuilds the metadata the commands
+// describe, skipping the ones that failed to apply.
+func TestReplayLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	command := func(typ internal.Command_Type, desc *proto.ExtensionDesc, value interface{}) []byte {
+		cmd := &internal.Command{Type: &typ}
+		if err := proto.SetExtension(cmd, desc, value); err != nil {
+			t.Fatal(err)
+		}
+		b, err := proto.Marshal(cmd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	cmds := [][]byte{
+		command(internal.Command_CreateDataNodeCommand, internal.E_CreateDataNodeCommand_Command,
+			&internal.CreateDataNodeCommand{HTTPAddr: proto.String("foo:8086"), TCPAddr: proto.String("foo:8088")}),
+		command(internal.Command_CreateDatabaseCommand, internal.E_CreateDatabaseCommand_Command,
+			&internal.CreateDatabaseCommand{Name: proto.String("db0")}),
+		command(internal.Command_CreateDatabaseCommand, internal.E_CreateDatabaseCommand_Command,
+			&internal.CreateDatabaseCommand{Name: proto.String("db1")}),
+		command(internal.Command_DropDatabaseCommand, internal.E_DropDatabaseCommand_Command,
+			&internal.DropDatabaseCommand{Name: proto.String("db1")}),
+		// Fails to apply since there is no such node.
+		command(internal.Command_DeleteDataNodeCommand, internal.E_DeleteDataNodeCommand_Command,
+			&internal.DeleteDataNodeCommand{ID: proto.Uint64(42)}),
+		command(internal.Command_SetShardGroupQuotaCommand, internal.E_SetShardGroupQuotaCommand_Command,
+			&internal.SetShardGroupQuotaCommand{Database: proto.String("db0"), MaxShardGroups: proto.Uint64(3)}),
+	}
+
+	logs, err := raftboltdb.NewBoltStore(filepath.Join(dir, "raft.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := []*raft.Log{{Index: 1, Term: 1, Type: raft.LogNoop}}
+	for _, b := range cmds {
+		entries = append(entries, &raft.Log{Index: uint64(len(entries) + 1), Term: 1, Type: raft.LogCommand, Data: b})
+	}
+	if err := logs.StoreLogs(entries); err != nil {
+		t.Fatal(err)
+	}
+	logs.Close()
+
+	data, err := ReplayLog(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Data.Index != uint64(len(entries)) {
+		t.Fatalf("unexpected index: %d", data.Data.Index)
+	}
+	if len(data.DataNodes) != 1 || data.DataNodes[0].Host != "foo:8086" {
+		t.Fatalf("unexpected data nodes: %+v", data.DataNodes)
+	}
+	if len(data.Data.Databases) != 1 || data.Data.Databases[0].Name != "db0" {
+		t.Fatalf("unexpected databases: %+v", data.Data.Databases)
+	}
+	if q := data.ShardGroupQuotas["db0"]; q != 3 {
+		t.Fatalf("unexpected quota: %d", q)
+	}
+
+	// The backup itself is left alone.
+	if _, err := os.Stat(filepath.Join(dir, "snapshots")); !os.IsNotExist(err) {
+		t.Fatalf("replay wrote to the backup: %v", err)
+	}
+
+	// Replaying a corrupt command is an error, not a crash.
+	entries = append(entries, &raft.Log{Index: uint64(len(entries) + 1), Type: raft.LogCommand, Data: []byte("junk")})
+	if _, err := ReplayCommands(nil, entries); err == nil {
+		t.Fatal("expected an error replaying a corrupt command")
+	}
+}
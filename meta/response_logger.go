@@ -112,6 +112,11 @@ func buildLogLine(l *responseLogger, r *http.Request, start time.Time) string {
 		r.Header.Get("Request-Id"),
 		fmt.Sprintf("%s", time.Since(start)),
 	}
+	if r.TLS != nil {
+		fields = append(fields,
+			"tls="+tlsVersionName(r.TLS.Version),
+			"cipher="+tlsCipherSuiteName(r.TLS.CipherSuite))
+	}
 
 	return strings.Join(fields, " ")
 }
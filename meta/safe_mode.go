@@ -0,0 +1,96 @@
+package meta
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ErrSafeMode is returned for the endpoints a service in safe mode doesn't
+// serve.
+var ErrSafeMode = errors.New("meta service is in safe mode")
+
+// OpenSafeMode starts the service in safe mode, to inspect a node in a bad
+// state. It serves /health, which reports "safe-mode", /metrics and the
+// debug endpoints that don't need the store, including /debug/node for
+// node.json, on the HTTP and debug listeners. Everything else returns
+// ErrSafeMode. The store isn't opened, so the node takes no part in raft
+// and serves no metadata. Close it as usual.
+func (s *Service) OpenSafeMode() error {
+	if err := s.listen(); err != nil {
+		return err
+	}
+
+	handler := newHandler(s.config, s)
+	handler.logger = s.Logger
+	handler.safeMode = true
+	s.handler = handler
+	s.server = &http.Server{
+		Handler:     handler,
+		ConnContext: connContext,
+		ConnState:   s.tlsSessions.connState,
+	}
+	go s.serve(s.ln)
+
+	if s.config.DebugBindAddress != "" {
+		if err := s.openDebug(handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// serveSafeModeHTTP responds to requests to a service in safe mode, on both
+// the HTTP and debug listeners.
+func (h *handler) serveSafeModeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, ErrSafeMode.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/health", "/ready":
+		h.WrapHandler("health", h.serveSafeModeHealth).ServeHTTP(w, r)
+	case "/metrics":
+		h.WrapHandler("metrics", h.serveMetrics).ServeHTTP(w, r)
+	case "/debug/config":
+		h.WrapHandler("config", h.serveConfig).ServeHTTP(w, r)
+	case "/debug/requests":
+		h.WrapHandler("requests", h.serveRequests).ServeHTTP(w, r)
+	case "/debug/apply-errors":
+		h.WrapHandler("apply-errors", h.serveApplyErrors).ServeHTTP(w, r)
+	case "/debug/tls":
+		h.WrapHandler("tls", h.serveTLSSessions).ServeHTTP(w, r)
+	case "/debug/log/tail":
+		h.WrapHandler("log-tail", h.serveLogTail).ServeHTTP(w, r)
+	case "/debug/version":
+		h.WrapHandler("version", h.serveVersion).ServeHTTP(w, r)
+	case "/debug/node":
+		h.WrapHandler("node", h.serveNode).ServeHTTP(w, r)
+	default:
+		http.Error(w, ErrSafeMode.Error(), http.StatusServiceUnavailable)
+	}
+}
+
+// serveSafeModeHealth reports the node as in safe mode, with the ID from its
+// node.json if it has one. It is a 503, as the node serves no metadata.
+func (h *handler) serveSafeModeHealth(w http.ResponseWriter, r *http.Request) {
+	health := healthJSON{Status: "safe-mode"}
+	if h.s.Node != nil {
+		health.NodeID = h.s.Node.ID
+	}
+	h.writeHealth(w, health)
+}
+
+// serveNode returns the node.json the service was started with, or 404 if
+// the node has none.
+func (h *handler) serveNode(w http.ResponseWriter, r *http.Request) {
+	if h.s.Node == nil {
+		http.Error(w, "no node.json", http.StatusNotFound)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.s.Node); err != nil {
+		h.httpError(err, w, http.StatusInternalServerError)
+	}
+}
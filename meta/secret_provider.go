@@ -0,0 +1,50 @@
+package meta
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+)
+
+// SecretProvider supplies the TLS material of a meta node, for deployments
+// that fetch it from a secret store such as Vault or a KMS rather than keep
+// it on disk. It is set as Config.SecretProvider; without one, the files
+// named by https-certificate and internal-ca are read.
+type SecretProvider interface {
+	// GetCertificate returns the certificate and key the HTTPS API serves.
+	// It is called again on every reload, so a provider rotates the
+	// certificate by returning a new one.
+	GetCertificate() (*tls.Certificate, error)
+
+	// GetCA returns the PEM encoded certificate authorities that raft
+	// connections between meta nodes, and the client certificates
+	// presented to the HTTPS API, are verified against.
+	GetCA() ([]byte, error)
+}
+
+// fileSecretProvider is the SecretProvider reading the PEM files named in
+// the config.
+type fileSecretProvider struct {
+	config *Config
+}
+
+// GetCertificate loads the certificate and key in https-certificate.
+func (p fileSecretProvider) GetCertificate() (*tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(p.config.HTTPSCertificate, p.config.HTTPSCertificate)
+	if err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// GetCA reads internal-ca.
+func (p fileSecretProvider) GetCA() ([]byte, error) {
+	return ioutil.ReadFile(p.config.InternalCA)
+}
+
+// secretProvider returns the provider of the TLS material of the node.
+func (c *Config) secretProvider() SecretProvider {
+	if c.SecretProvider != nil {
+		return c.SecretProvider
+	}
+	return fileSecretProvider{config: c}
+}
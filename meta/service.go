@@ -2,15 +2,19 @@ package meta // import "github.com/zhexuany/influxcloud/meta"
 
 import (
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/uber-go/zap"
 	"github.com/zhexuany/influxcloud"
+	"google.golang.org/grpc"
 )
 
 var mutex sync.RWMutex
@@ -25,32 +29,160 @@ const (
 type Service struct {
 	RaftListener net.Listener
 
-	version string
+	// HTTPListener, if set, is where the HTTP API is served instead of a
+	// listener opened on http-bind-address, which must then be its
+	// address. A listener reopened after it failed is opened on the
+	// address.
+	HTTPListener net.Listener
+
+	version   string
+	buildInfo BuildInfo
+
+	config  *Config
+	handler *handler
+	server  *http.Server
+
+	// mu guards ln, which ReopenListener replaces, against Close.
+	mu sync.Mutex
+	ln net.Listener
 
-	config   *Config
-	handler  *handler
-	ln       net.Listener
 	httpAddr string
 	raftAddr string
 	https    bool
-	cert     string
+	certs    *certReloader
+	cas      *caReloader
 	err      chan error
 	Logger   zap.Logger
 	store    *store
 
+	// logOutput is where the store logs, if set with SetLogOutput. It is
+	// logTail, which also streams the log to /debug/log/tail.
+	logOutput io.Writer
+	logTail   *logTail
+
+	// logSwitch is the Logger set by SetLogOutput, switched to another
+	// format by SetLogFormat.
+	logSwitch *switchLogger
+
+	// debugServer serves the debug endpoints on debugLn, if configured.
+	debugServer *http.Server
+	debugLn     net.Listener
+
+	// grpcServer serves the gRPC control API on grpcLn, if configured.
+	grpcServer *grpc.Server
+	grpcLn     net.Listener
+
+	// Metrics holds every metric the service exports on /metrics.
+	Metrics      *Registry
+	httpRequests *Counter
+	startedAt    time.Time
+
+	// applyErrors holds the raft commands that recently failed to apply.
+	applyErrors *applyErrorHistory
+
+	// appliedCommands counts the raft commands applied, by command.
+	appliedCommands *Counter
+
+	// rateLimiter limits the requests to the HTTP API of each remote IP, if
+	// http-rate-limit is set. rateLimited counts those it rejected.
+	rateLimiter  *rateLimiter
+	rateLimited  *Counter
+	clusterPeers clusterPeers
+
+	// unauthenticated counts the requests to the HTTP API refused for lack
+	// of credentials, by handler.
+	unauthenticated *Counter
+
+	// muxRejected counts the connections a mux made by NewMux closed, by
+	// reason.
+	muxRejected *Counter
+
+	// idempotency holds the responses of recent commands by idempotency key.
+	idempotency *idempotencyCache
+
+	// closing is closed by Close to stop the background compaction of
+	// idempotency.
+	closing chan struct{}
+
+	// tlsSessions tracks the TLS connections open on the HTTP API.
+	tlsSessions *tlsSessions
+
+	// writeConns tracks the HTTP API connections that carried writes, to
+	// drain them on leadership loss.
+	writeConns *writeConns
+
+	// leaderTasks runs the tasks registered with RegisterLeaderTask.
+	leaderTasks *leaderScheduler
+
+	// tasks tracks the background tasks started with StartTask.
+	tasks *taskRegistry
+
+	// leaderWarmup runs the warmers registered with RegisterLeaderWarmer.
+	leaderWarmup *leaderWarmup
+
+	// shardSizes holds the shard sizes reported by data nodes, used to tune
+	// shard group durations.
+	shardSizes *shardSizes
+
+	// resigning counts the ResignLeadership calls waiting for another node
+	// to take over leadership.
+	resigning int32
+
+	// liveness tracks the heartbeats of data nodes.
+	liveness *nodeLiveness
+
+	// shardGroupQuotaNear is the hook registered with OnShardGroupQuotaNear.
+	shardGroupQuotaNear func(database string, used, max uint64)
+
+	// maintenance is 1 while the node is in maintenance, as set by
+	// SetMaintenance.
+	maintenance int32
+
+	// leaderChange is the hook registered with OnLeaderChange.
+	leaderChange func(oldLeader, newLeader string)
+
+	// snapshotTransfers bounds the number of snapshots sent at once.
+	snapshotTransfers *transferLimiter
+
+	// joined is closed once the store is open and the node has joined the
+	// cluster.
+	joined chan struct{}
+
+	// listening is closed once the service listens, with its addresses
+	// known.
+	listening chan struct{}
+
 	Node *influxcloud.Node
 }
 
 // NewService returns a new instance of Service.
 func NewService(c *Config) *Service {
 	s := &Service{
-		config:   c,
-		httpAddr: c.HTTPBindAddress,
-		raftAddr: c.BindAddress,
-		https:    c.HTTPSEnabled,
-		cert:     c.HTTPSCertificate,
-		err:      make(chan error),
+		config:    c,
+		httpAddr:  c.HTTPBindAddress,
+		raftAddr:  c.BindAddress,
+		https:     c.HTTPSEnabled,
+		err:       make(chan error),
+		joined:    make(chan struct{}),
+		listening: make(chan struct{}),
+		closing:   make(chan struct{}),
+		Metrics:   NewRegistry(),
 	}
+	s.startedAt = now()
+	s.snapshotTransfers = newTransferLimiter(c.MaxConcurrentSnapshots)
+	s.registerMetrics()
+	// Validate reports an invalid allowlist.
+	allow, _ := parseAllowlist(c.HTTPRateLimitAllowlist)
+	s.rateLimiter = newRateLimiter(c.HTTPRateLimit, c.HTTPRateLimitBurst, allow, s.rateLimited)
+	s.rateLimiter.isPeer = s.isClusterPeer
+	s.tasks = newTaskRegistry()
+	s.leaderTasks = newLeaderScheduler(s.Logger)
+	s.leaderTasks.registry = s.tasks
+	s.leaderWarmup = newLeaderWarmup(time.Duration(c.LeaderWarmTimeout), s.Logger)
+	s.shardSizes = newShardSizes()
+	s.liveness = newNodeLiveness(time.Duration(c.DataNodeLivenessTimeout))
+	s.tlsSessions = newTLSSessions()
+	s.writeConns = newWriteConns()
 
 	if c.LoggingEnabled {
 		s.Logger = zap.New(zap.NullEncoder())
@@ -61,6 +193,107 @@ func NewService(c *Config) *Service {
 	return s
 }
 
+// registerMetrics registers the service level metrics.
+func (s *Service) registerMetrics() {
+	s.httpRequests = s.Metrics.NewCounter("influxcloud_meta_http_requests", "Number of HTTP requests served, by handler.", "handler")
+	s.appliedCommands = s.Metrics.NewCounter("influxcloud_meta_apply", "Number of raft commands applied to the local state machine, by command.", "command")
+	s.rateLimited = s.Metrics.NewCounter("influxcloud_meta_http_rate_limited", "Number of HTTP requests rejected by http-rate-limit, by handler.", "handler")
+	s.unauthenticated = s.Metrics.NewCounter("influxcloud_meta_http_unauthenticated", "Number of HTTP requests refused 401 Unauthorized for lack of credentials, by handler.", "handler")
+	s.muxRejected = s.Metrics.NewCounter("influxcloud_meta_mux_rejected_connections", "Number of connections to the bind address closed without a known mux header, by reason.", "reason")
+	s.applyErrors = newApplyErrorHistory(DefaultApplyErrorHistorySize,
+		s.Metrics.NewCounter("influxcloud_meta_raft_apply_errors", "Number of raft commands that failed to apply, by command.", "command"))
+	s.idempotency = newIdempotencyCache(s.config.IdempotencyCacheSize, time.Duration(s.config.IdempotencyCacheTTL),
+		s.Metrics.NewCounter("influxcloud_meta_idempotency_lookups", "Number of idempotency key lookups, by result: hit for a deduplicated retry, or miss.", "result"))
+	s.idempotency.maxBytes = s.config.IdempotencyCacheMaxBytes
+	s.idempotency.compacted = s.Metrics.NewCounter("influxcloud_meta_idempotency_cache_compacted", "Number of expired command responses dropped by compaction.")
+	s.Metrics.NewGaugeFunc("influxcloud_meta_idempotency_cache_entries", "", "Number of command responses held for idempotent retries.", func() float64 {
+		return float64(s.idempotency.len())
+	})
+	s.Metrics.NewGaugeFunc("influxcloud_meta_idempotency_cache_bytes", "bytes", "Size of the idempotency keys and command responses held for idempotent retries.", func() float64 {
+		return float64(s.idempotency.sizeBytes())
+	})
+	s.Metrics.NewGaugeFunc("influxcloud_meta_uptime_seconds", "seconds", "Time since the service was created.", func() float64 {
+		return now().Sub(s.startedAt).Seconds()
+	})
+	s.Metrics.NewGaugeFunc("influxcloud_meta_is_leader", "", "Whether this node is the raft leader.", func() float64 {
+		if s.store != nil && s.store.isLeader() {
+			return 1
+		}
+		return 0
+	})
+	s.Metrics.NewGaugeFunc("influxcloud_meta_raft_term", "", "Current raft term.", func() float64 {
+		if s.store == nil {
+			return 0
+		}
+		if st := s.store.raftStatus(); st != nil {
+			return float64(st.Term)
+		}
+		return 0
+	})
+	s.Metrics.NewGaugeFunc("influxcloud_meta_peers", "", "Number of raft peers, this node included.", func() float64 {
+		if s.store == nil {
+			return 0
+		}
+		if st := s.store.raftStatus(); st != nil {
+			return float64(len(st.Peers))
+		}
+		return 0
+	})
+	s.Metrics.NewGaugeFunc("influxcloud_meta_raft_pending_config_changes", "", "Number of raft membership changes in the log not yet committed.", func() float64 {
+		if s.store == nil {
+			return 0
+		}
+		return float64(s.store.pendingConfigChanges())
+	})
+	s.Metrics.NewGaugeFunc("influxcloud_meta_raft_apply_lag", "", "Number of committed raft entries not yet applied to the local state machine.", func() float64 {
+		if s.store == nil {
+			return 0
+		}
+		return float64(s.store.applyLag())
+	})
+	s.Metrics.NewGaugeFunc("influxcloud_meta_snapshot_transfers_active", "", "Number of snapshots being sent to clients.", func() float64 {
+		active, _ := s.snapshotTransfers.counts()
+		return float64(active)
+	})
+	s.Metrics.NewGaugeFunc("influxcloud_meta_snapshot_transfers_queued", "", "Number of snapshot requests waiting for max-concurrent-snapshots to allow them.", func() float64 {
+		_, queued := s.snapshotTransfers.counts()
+		return float64(queued)
+	})
+	s.registerFileMetrics()
+}
+
+// registerFileMetrics registers the metrics of node.json and config file
+// I/O, which are kept for the whole process.
+func (s *Service) registerFileMetrics() {
+	s.Metrics.NewCounterFunc("influxcloud_meta_node_file_reads", "", "Number of reads of node.json.", func() float64 {
+		return float64(influxcloud.Stats().NodeReads)
+	})
+	s.Metrics.NewCounterFunc("influxcloud_meta_node_file_read_errors", "", "Number of reads of node.json that failed.", func() float64 {
+		return float64(influxcloud.Stats().NodeReadErrors)
+	})
+	s.Metrics.NewCounterFunc("influxcloud_meta_node_file_read_seconds", "seconds", "Time spent reading node.json.", func() float64 {
+		return influxcloud.Stats().NodeReadTime.Seconds()
+	})
+	s.Metrics.NewCounterFunc("influxcloud_meta_node_file_writes", "", "Number of writes of node.json.", func() float64 {
+		return float64(influxcloud.Stats().NodeWrites)
+	})
+	s.Metrics.NewCounterFunc("influxcloud_meta_node_file_write_errors", "", "Number of writes of node.json that failed.", func() float64 {
+		return float64(influxcloud.Stats().NodeWriteErrors)
+	})
+	s.Metrics.NewCounterFunc("influxcloud_meta_node_file_write_seconds", "seconds", "Time spent writing node.json.", func() float64 {
+		return influxcloud.Stats().NodeWriteTime.Seconds()
+	})
+	s.Metrics.NewCounterFunc("influxcloud_meta_config_loads", "", "Number of loads of the config file.", func() float64 {
+		return float64(influxcloud.Stats().ConfigLoads)
+	})
+	s.Metrics.NewCounterFunc("influxcloud_meta_config_load_errors", "", "Number of loads of the config file that failed.", func() float64 {
+		return float64(influxcloud.Stats().ConfigLoadErrors)
+	})
+	s.Metrics.NewCounterFunc("influxcloud_meta_config_load_seconds", "seconds", "Time spent loading the config file.", func() float64 {
+		return influxcloud.Stats().ConfigLoadTime.Seconds()
+	})
+}
+
 func now() time.Time {
 	mutex.RLock()
 	t := time.Now().UTC()
@@ -68,6 +301,69 @@ func now() time.Time {
 	return t
 }
 
+// ReloadCertificate loads the HTTPS certificate and the certificate
+// authorities of internal-ca again, from their files or the SecretProvider,
+// and returns whether either changed since they were last loaded. New
+// connections are served the new certificate and verified against the new
+// authorities, while existing ones are kept. What can't be loaded stays in
+// use as it was.
+func (s *Service) ReloadCertificate() (bool, error) {
+	var changed bool
+	if s.certs != nil {
+		reloaded, err := s.certs.reload()
+		if err != nil {
+			return false, err
+		}
+		changed = reloaded
+	}
+	if s.cas != nil {
+		reloaded, err := s.cas.reload()
+		if err != nil {
+			return changed, err
+		}
+		changed = changed || reloaded
+	}
+	return changed, nil
+}
+
+// SetHTTPSCertificate serves the certificate and key in the file at path,
+// and reloads them from there from then on. It returns whether the
+// certificate changed. If it can't be loaded the current one stays in use.
+func (s *Service) SetHTTPSCertificate(path string) (bool, error) {
+	if s.certs == nil {
+		return false, errors.New("https isn't enabled")
+	} else if s.config.SecretProvider != nil {
+		return false, errors.New("the certificate comes from the secret provider")
+	}
+	c := *s.config
+	c.HTTPSCertificate = path
+	return s.certs.setProvider(fileSecretProvider{config: &c})
+}
+
+// SetHTTPRateLimit changes the rate, burst and allowlist of the HTTP API
+// rate limit, as http-rate-limit and the keys after it would set them.
+func (s *Service) SetHTTPRateLimit(rate float64, burst int, allowlist []string) error {
+	allow, err := parseAllowlist(allowlist)
+	if err != nil {
+		return err
+	}
+	s.rateLimiter.set(rate, burst, allow)
+	return nil
+}
+
+// SetLogFormat makes the service and its store log in format from now on.
+// It only takes effect on a service whose log output was set with
+// SetLogOutput.
+func (s *Service) SetLogFormat(format string) {
+	if s.logSwitch == nil {
+		return
+	}
+	s.logSwitch.set(newZapLogger(s.logTail, format, "meta", s.nodeID()))
+	if s.store != nil && s.config.LoggingEnabled {
+		ReformatLogger(s.store.logger, s.logOutput, format, "[metastore] ", "metastore", s.nodeID())
+	}
+}
+
 // SetVersion sets version.
 func (s *Service) SetVersion(version string) {
 	s.version = version
@@ -82,40 +378,277 @@ func (s *Service) Version() string {
 func (s *Service) Open() error {
 	// s.Logger.Info("Starting meta service at ", s.HTTPAddr())
 
-	if s.RaftListener == nil {
+	// An observer takes no part in raft.
+	if s.RaftListener == nil && !s.config.ObserverMode {
 		panic("no raft listener set")
 	}
 
-	// Open listener.
-	if s.https {
-		cert, err := tls.LoadX509KeyPair(s.cert, s.cert)
-		if err != nil {
+	if err := s.listen(); err != nil {
+		return err
+	}
+
+	// Open the store.  The addresses passed in are remotely accessible.
+	s.store = newStore(s.config, s.remoteAddr(s.httpAddr), s.remoteAddr(s.raftAddr))
+	s.store.node = s.Node
+	s.store.metadataVersion = s.buildInfo.MetadataVersion()
+	if s.logOutput != nil && s.config.LoggingEnabled {
+		s.store.logger = NewLogger(s.logOutput, s.config.LogFormat, "[metastore] ", "metastore", s.nodeID())
+	}
+	s.leaderTasks.logger = s.Logger
+	s.leaderWarmup.logger = s.Logger
+	s.store.leaderChanged = s.leaderChanged
+	s.RegisterLeaderWarmer("raft-barrier", s.warmState)
+	s.RegisterLeaderWarmer("snapshot", s.warmSnapshot)
+	s.store.applyErrors = s.applyErrors
+	s.store.avoidLeadership = s.avoidingLeadership
+	s.store.cas = s.cas
+	s.store.appliedCommands = s.appliedCommands
+	s.store.shardGroupQuotaNear = s.shardGroupQuotaNear
+	s.store.tasks = s.tasks
+	if len(s.config.MaintenanceWindows) > 0 {
+		go s.watchMaintenanceWindows()
+	}
+	if len(s.config.ShardGroupAutoTune) > 0 {
+		s.RegisterLeaderTask("shard-group-auto-tune", time.Duration(s.config.ShardGroupAutoTuneInterval), s.tuneShardGroupDurations)
+	}
+	if s.config.OrphanCheckInterval > 0 {
+		s.RegisterLeaderTask("orphan-check", time.Duration(s.config.OrphanCheckInterval), s.reconcileOrphans)
+	}
+	if s.config.ShardPrecreationCheckInterval > 0 {
+		s.RegisterLeaderTask(ShardPrecreatorTask, time.Duration(s.config.ShardPrecreationCheckInterval), s.precreateShardGroups)
+	}
+	if s.config.ShardGroupReapInterval > 0 {
+		s.RegisterLeaderTask(ShardGroupReaperTask, time.Duration(s.config.ShardGroupReapInterval), s.reapShardGroups)
+	}
+	if s.config.DataNodeHeartbeatCheckInterval > 0 {
+		s.RegisterLeaderTask(HeartbeatCheckerTask, time.Duration(s.config.DataNodeHeartbeatCheckInterval), s.checkHeartbeats)
+	}
+
+	if s.config.MetricsNodeLabels {
+		s.setMetricsNodeLabels()
+	}
+	if interval := time.Duration(s.config.IdempotencyCacheCompactInterval); interval > 0 {
+		go s.idempotency.compactEvery(interval, s.closing)
+	}
+
+	handler := newHandler(s.config, s)
+	handler.logger = s.Logger
+	handler.store = s.store
+	s.handler = handler
+	s.server = &http.Server{
+		Handler:     handler,
+		ConnContext: connContext,
+		ConnState: func(conn net.Conn, state http.ConnState) {
+			s.tlsSessions.connState(conn, state)
+			s.writeConns.connState(conn, state)
+		},
+	}
+
+	// Begin listening for requests in a separate goroutine.
+	go s.serve(s.ln)
+
+	if s.config.DebugBindAddress != "" {
+		if err := s.openDebug(handler); err != nil {
 			return err
 		}
+	}
+	if s.config.GRPCBindAddress != "" {
+		if err := s.openGRPC(handler); err != nil {
+			return err
+		}
+	}
 
-		listener, err := tls.Listen("tcp", s.httpAddr, &tls.Config{
-			Certificates: []tls.Certificate{cert},
-		})
-		if err != nil {
+	s.delayStartup()
+
+	if s.config.ObserverMode {
+		if err := s.store.openObserver(); err != nil {
 			return err
 		}
+	} else if err := s.store.open(s.RaftListener); err != nil {
+		return err
+	}
+	close(s.joined)
+	go s.watchClusterPeers()
 
-		//s.Logger.Info("Listening on HTTPS:", listener.Addr().String())
-		s.ln = listener
-	} else {
-		listener, err := net.Listen("tcp", s.httpAddr)
+	if s.leaderChange != nil || s.config.LeaderChangeWebhook != "" {
+		go s.watchLeader()
+	}
+
+	return nil
+}
+
+// RegisterLeaderTask registers fn to be called every interval while this node
+// is the raft leader. The task is started when the node gains leadership and
+// stopped when it loses it, so cluster wide work is never done twice.
+func (s *Service) RegisterLeaderTask(name string, interval time.Duration, fn func()) {
+	s.leaderTasks.register(&leaderTask{name: name, interval: interval, fn: fn})
+}
+
+// PauseLeaderTask stops running the named leader task on this node until it is
+// resumed, without unregistering it. It waits for a run in progress to finish.
+func (s *Service) PauseLeaderTask(name string) error {
+	return s.leaderTasks.pause(name)
+}
+
+// ResumeLeaderTask runs a paused leader task again.
+func (s *Service) ResumeLeaderTask(name string) error {
+	return s.leaderTasks.resume(name)
+}
+
+// StartTask runs fn in the background as a task named name, listed on
+// /admin/tasks until fn returns, and returns its ID. fn should report its
+// progress with t.SetProgress and return once t.Context() is done, which
+// happens when the task is canceled with CancelTask or the service closes.
+func (s *Service) StartTask(name string, fn func(t *Task)) uint64 {
+	return s.tasks.start(name, fn)
+}
+
+// Tasks returns the background tasks running on this node.
+func (s *Service) Tasks() []TaskInfo {
+	return s.tasks.list()
+}
+
+// CancelTask asks the background task id to stop, without waiting for it.
+func (s *Service) CancelTask(id uint64) error {
+	return s.tasks.cancel(id)
+}
+
+// OnShardGroupQuotaNear registers fn to be called when a new shard group
+// takes a database past shard-group-quota-near-ratio of its shard group
+// quota, so old shard groups can be evicted before creation starts failing.
+// It is only called on the leader, and must be registered before Open.
+func (s *Service) OnShardGroupQuotaNear(fn func(database string, used, max uint64)) {
+	s.shardGroupQuotaNear = fn
+}
+
+// initialized returns whether the store is open and the node has joined the
+// cluster.
+func (s *Service) initialized() bool {
+	select {
+	case <-s.joined:
+		return true
+	default:
+		return false
+	}
+}
+
+// ResetStore resets store.
+func (s *Service) ResetStore(st *store) {
+	s.store = st
+}
+
+func (s *Service) remoteAddr(addr string) string {
+	return s.config.remoteAddr(addr)
+}
+
+// setMetricsNodeLabels labels every exported metric with the node's identity.
+func (s *Service) setMetricsNodeLabels() {
+	id := s.config.MetricsNodeID
+	if id == "" && s.Node != nil {
+		id = strconv.FormatUint(s.Node.ID, 10)
+	}
+	s.Metrics.SetConstLabels(
+		[]string{"node_id", "node_addr", "cluster_name"},
+		[]string{id, s.remoteAddr(s.httpAddr), s.config.MetricsClusterName},
+	)
+}
+
+// ListenerError is sent on the Err channel of the service when the
+// listener of its HTTP API fails. The service stops serving the API until
+// ReopenListener is called.
+type ListenerError struct {
+	Addr string
+	Err  error
+}
+
+func (e *ListenerError) Error() string {
+	return fmt.Sprintf("listener failed: addr=%s, err=%s", e.Addr, e.Err)
+}
+
+// serve serves the handler from the listener.
+func (s *Service) serve(ln net.Listener) {
+	// The listener was closed so exit
+	// See https://github.com/golang/go/issues/4373
+	err := s.server.Serve(ln)
+	if err != nil && !strings.Contains(err.Error(), "closed") {
+		s.sendErr(&ListenerError{Addr: ln.Addr().String(), Err: err})
+	}
+}
+
+// sendErr sends err on the Err channel, unless the service closes first.
+func (s *Service) sendErr(err error) {
+	select {
+	case s.err <- err:
+	case <-s.closing:
+	}
+}
+
+// ReopenListener listens on the HTTP address of the service again and
+// serves the API on it, once its listener failed with a *ListenerError.
+func (s *Service) ReopenListener() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.closing:
+		return errors.New("service closed")
+	default:
+	}
+	ln, err := s.openHTTPListener()
+	if err != nil {
+		return err
+	}
+	s.ln = ln
+	go s.serve(ln)
+	return nil
+}
+
+// listen opens the listener of the HTTP API on http-bind-address, and
+// settles the HTTP and raft addresses of the service once their listeners
+// are up.
+func (s *Service) listen() error {
+	// Bind to the configured interfaces, if any.
+	httpAddr, err := ResolveBindAddress(s.httpAddr, s.config.HTTPBindInterface)
+	if err != nil {
+		return fmt.Errorf("http bind interface: %s", err)
+	}
+	raftAddr, err := ResolveBindAddress(s.raftAddr, s.config.BindInterface)
+	if err != nil {
+		return fmt.Errorf("raft bind interface: %s", err)
+	}
+	s.httpAddr, s.raftAddr = httpAddr, raftAddr
+
+	// Open listener.
+	if s.https {
+		certs, err := newCertReloader(s.config.secretProvider())
 		if err != nil {
 			return err
 		}
-
-		//s.Logger.Info("Listening on HTTP:", listener.Addr().String())
-		s.ln = listener
+		s.certs = certs
+	}
+	if s.config.InternalCert != "" {
+		cas, err := newCAReloader(s.config.secretProvider())
+		if err != nil {
+			return err
+		}
+		s.cas = cas
+	}
+	if s.HTTPListener != nil {
+		s.ln = s.HTTPListener
+		if s.https {
+			config, err := s.tlsConfig()
+			if err != nil {
+				return err
+			}
+			s.ln = tls.NewListener(s.HTTPListener, config)
+		}
+	} else if s.ln, err = s.openHTTPListener(); err != nil {
+		return err
 	}
 
 	// wait for the listeners to start
 	timeout := time.Now().Add(raftListenerStartupTimeout)
 	for {
-		if s.ln.Addr() != nil && s.RaftListener.Addr() != nil {
+		if s.ln.Addr() != nil && (s.RaftListener == nil || s.RaftListener.Addr() != nil) {
 			break
 		}
 
@@ -125,61 +658,94 @@ func (s *Service) Open() error {
 		time.Sleep(10 * time.Millisecond)
 	}
 
-	var err error
 	if autoAssignPort(s.httpAddr) {
 		s.httpAddr, err = combineHostAndAssignedPort(s.ln, s.httpAddr)
 	}
-	if autoAssignPort(s.raftAddr) {
+	if autoAssignPort(s.raftAddr) && s.RaftListener != nil {
 		s.raftAddr, err = combineHostAndAssignedPort(s.RaftListener, s.raftAddr)
 	}
 	if err != nil {
 		return err
 	}
+	close(s.listening)
+	return nil
+}
 
-	// Open the store.  The addresses passed in are remotely accessible.
-	s.store = newStore(s.config, s.remoteAddr(s.httpAddr), s.remoteAddr(s.raftAddr))
-	s.store.node = s.Node
+// openHTTPListener listens for the HTTP API on the HTTP address of the
+// service, with TLS if HTTPS is enabled.
+func (s *Service) openHTTPListener() (net.Listener, error) {
+	if !s.https {
+		return s.config.Listen(s.httpAddr)
+	}
 
-	handler := newHandler(s.config, s)
-	handler.logger = s.Logger
-	handler.store = s.store
-	s.handler = handler
+	config, err := s.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	return tls.Listen("tcp", s.httpAddr, config)
+}
 
-	// Begin listening for requests in a separate goroutine.
-	go s.serve()
+// tlsConfig returns the TLS config the HTTPS API and the gRPC control API
+// are served with, negotiating nextProtos.
+func (s *Service) tlsConfig(nextProtos ...string) (*tls.Config, error) {
+	config := &tls.Config{GetCertificate: s.certs.GetCertificate, NextProtos: nextProtos}
+	if s.cas != nil {
+		// Clients presenting a certificate signed by internal-ca are
+		// authorized as its common name.
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+		config = withClientCAs(config, s.cas)
+	}
+	return config, nil
+}
 
-	if err := s.store.open(s.RaftListener); err != nil {
+// openDebug starts serving the debug endpoints on the loopback only debug
+// listener.
+func (s *Service) openDebug(h *handler) error {
+	addr, err := DebugListenAddress(s.config.DebugBindAddress)
+	if err != nil {
+		return fmt.Errorf("debug bind address: %s", err)
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
 		return err
 	}
+	s.debugLn = ln
+	s.debugServer = &http.Server{Handler: http.HandlerFunc(h.serveDebugHTTP)}
 
+	go func() {
+		err := s.debugServer.Serve(ln)
+		if err != nil && !strings.Contains(err.Error(), "closed") {
+			s.sendErr(fmt.Errorf("debug listener failed: addr=%s, err=%s", ln.Addr(), err))
+		}
+	}()
 	return nil
 }
 
-// ResetStore resets store.
-func (s *Service) ResetStore(st *store) {
-	s.store = st
+// IsLeader returns whether this node is the raft leader of the meta cluster.
+// An observer never is, nor is a service in safe mode.
+func (s *Service) IsLeader() bool {
+	if s.config.ObserverMode || s.store == nil {
+		return false
+	}
+	return s.store.isLeader()
 }
 
-func (s *Service) remoteAddr(addr string) string {
-	hostname := s.config.RemoteHostname
-	if hostname == "" {
-		hostname = DefaultHostname
-	}
-	remote, err := DefaultHost(hostname, addr)
-	if err != nil {
-		return addr
-	}
-	return remote
+// TransferLeadership hands leadership off before this node shuts down, and
+// waits up to timeout for a follower to take over. It does nothing unless
+// this node is the leader. The vendored raft can't transfer leadership to
+// a chosen follower, so this stops raft on this node and the followers
+// elect a new leader among themselves once their heartbeat timeout runs
+// out. Unless it returns ErrNoLeadershipTarget, this node no longer takes
+// part in raft afterwards and should be closed.
+func (s *Service) TransferLeadership(timeout time.Duration) error {
+	return s.store.stepDown(timeout)
 }
 
-// serve serves the handler from the listener.
-func (s *Service) serve() {
-	// The listener was closed so exit
-	// See https://github.com/golang/go/issues/4373
-	err := http.Serve(s.ln, s.handler)
-	if err != nil && !strings.Contains(err.Error(), "closed") {
-		s.err <- fmt.Errorf("listener failed: addr=%s, err=%s", s.ln.Addr(), err)
-	}
+// WaitForApplied blocks until the raft log entry at index has been applied
+// to this node's state machine, so reads here see it. It returns
+// ErrApplyTimeout if that takes longer than timeout.
+func (s *Service) WaitForApplied(index uint64, timeout time.Duration) error {
+	return s.store.waitForApplied(index, timeout)
 }
 
 // Close closes the underlying listener.
@@ -188,12 +754,45 @@ func (s *Service) Close() error {
 		return err
 	}
 
-	if err := s.store.close(); err != nil {
-		return err
+	// A service in safe mode never opened its store.
+	if s.store != nil {
+		if err := s.store.close(); err != nil {
+			return err
+		}
+	}
+
+	// The store no longer reports leadership changes once closed.
+	s.leaderWarmup.stop()
+	s.leaderTasks.stop()
+	s.tasks.close()
+
+	// No listener is reopened once closing is closed.
+	s.mu.Lock()
+	select {
+	case <-s.closing:
+	default:
+		close(s.closing)
+	}
+	ln := s.ln
+	s.mu.Unlock()
+
+	if s.debugServer != nil {
+		if err := s.debugServer.Close(); err != nil {
+			return err
+		}
+	}
+	if s.grpcServer != nil {
+		s.grpcServer.Stop()
 	}
 
-	if s.ln != nil {
-		if err := s.ln.Close(); err != nil {
+	// Closing the server also closes the listener and drops any open
+	// connections, including long polling snapshot requests.
+	if s.server != nil {
+		if err := s.server.Close(); err != nil {
+			return err
+		}
+	} else if ln != nil {
+		if err := ln.Close(); err != nil {
 			return err
 		}
 	}
@@ -211,6 +810,24 @@ func (s *Service) HTTPAddr() string {
 	return s.httpAddr
 }
 
+// DebugAddr returns the address of the debug listener, or an empty string if
+// it isn't enabled.
+func (s *Service) DebugAddr() string {
+	if s.debugLn == nil {
+		return ""
+	}
+	return s.debugLn.Addr().String()
+}
+
+// GRPCAddr returns the address of the gRPC control API listener, or an
+// empty string if it isn't enabled.
+func (s *Service) GRPCAddr() string {
+	if s.grpcLn == nil {
+		return ""
+	}
+	return s.grpcLn.Addr().String()
+}
+
 // RemoteRaftAddr returns a remote raft httpAddr.
 func (s *Service) RemoteRaftAddr() string {
 	return s.remoteAddr(s.raftAddr)
@@ -221,9 +838,40 @@ func (s *Service) RaftAddr() string {
 	return s.raftAddr
 }
 
+// Listening returns a channel closed once the service listens, after which
+// HTTPAddr, RaftAddr and AdvertisedAddrs hold the ports assigned to a port
+// of 0.
+func (s *Service) Listening() <-chan struct{} {
+	return s.listening
+}
+
+// AdvertisedAddrs returns the HTTP and raft addresses the node registers
+// with the cluster: its bind addresses, with an unspecified host replaced
+// by the remote hostname.
+func (s *Service) AdvertisedAddrs() (httpAddr, raftAddr string) {
+	return s.remoteAddr(s.httpAddr), s.remoteAddr(s.raftAddr)
+}
+
 // Err returns a channel for fatal errors that occur on the listener.
 func (s *Service) Err() <-chan error { return s.err }
 
+// SetLogOutput makes the service and its store log to w, in the configured
+// log format. It must not be called after the Open method has been called.
+func (s *Service) SetLogOutput(w io.Writer) {
+	s.logTail = newLogTail(w, s.config.LogTailMaxTailers)
+	s.logSwitch = newSwitchLogger(newZapLogger(s.logTail, s.config.LogFormat, "meta", s.nodeID()))
+	s.Logger = s.logSwitch
+	s.logOutput = s.logTail
+}
+
+// nodeID returns the ID of the node, or zero if it isn't known yet.
+func (s *Service) nodeID() uint64 {
+	if s.Node == nil {
+		return 0
+	}
+	return s.Node.ID
+}
+
 // WithLogger sets the internal logger to the logger passed in
 func (s *Service) WithLogger(log zap.Logger) {
 	s.Logger = log.With(zap.String("service", "cluster"))
@@ -1,21 +1,20 @@
 package meta_test
 
 import (
-	"encoding/json"
+	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
+	"net/http"
 	"os"
-	"path"
 	"reflect"
-	"runtime"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
-	"github.com/influxdata/influxdb/influxql"
 	"github.com/influxdata/influxdb/services/meta"
-	"github.com/influxdata/influxdb/tcp"
 	"github.com/influxdata/influxdb/toml"
 	"github.com/zhexuany/influxcloud"
 	cloudMeta "github.com/zhexuany/influxcloud/meta"
@@ -573,10 +572,7 @@ func TestMetaService_CreateRemoveMetaNode(t *testing.T) {
 	defer os.RemoveAll(cfg3.Dir)
 
 	cfg3.JoinPeers = joinPeers[0:3]
-	s3 := newService(cfg3)
-	if err := s3.Open(); err != nil {
-		t.Fatal(err)
-	}
+	s3 := openService(t, cfg3)
 	defer s3.Close()
 
 	c1 := cloudMeta.NewClient(cfg3)
@@ -612,10 +608,7 @@ func TestMetaService_CreateRemoveMetaNode(t *testing.T) {
 	cfg4.BindAddress = freePort()
 	cfg4.JoinPeers = []string{joinPeers[0], joinPeers[1], cfg4.HTTPBindAddress}
 	defer os.RemoveAll(cfg4.Dir)
-	s4 := newService(cfg4)
-	if err := s4.Open(); err != nil {
-		t.Fatal(err)
-	}
+	s4 := openService(t, cfg4)
 	defer s4.Close()
 
 	c2 := cloudMeta.NewClient(cfg4)
@@ -828,6 +821,11 @@ func TestMetaService_CreateDataNode(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	if n.StartedAt.IsZero() {
+		t.Fatal("expected data node start time to be set")
+	}
+	exp.StartedAt = n.StartedAt
+
 	if !reflect.DeepEqual(n, exp) {
 		t.Fatalf("data node attributes wrong.Want:%v but %v", exp, n)
 	}
@@ -1125,115 +1123,177 @@ func TestMetaService_AcquireLease(t *testing.T) {
 	}
 }
 
-// newServiceAndClient returns new data directory, *Service, and *Client or panics.
-// Caller is responsible for deleting data dir and closing client.
-func newServiceAndClient() (string, *testService, *cloudMeta.Client) {
+// Ensure the mux closes connections that send no header byte within the
+// handshake timeout, or an unknown one, and counts them by reason.
+func TestMetaService_MuxHandshakeTimeout(t *testing.T) {
+	t.Parallel()
+
 	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	cfg.MuxHandshakeTimeout = toml.Duration(100 * time.Millisecond)
 	s := newService(cfg)
-	if err := s.Open(); err != nil {
-		panic(err)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
 	}
+	defer ln.Close()
+	mux := s.NewMux(ioutil.Discard)
+	mux.Listen(cloudMeta.MuxHeader)
+	go mux.Serve(ln)
 
-	c := newClient(s)
+	// closed waits for the mux to close conn, after sending it b if set.
+	closed := func(b []byte) {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		if b != nil {
+			if _, err := conn.Write(b); err != nil {
+				t.Fatal(err)
+			}
+		}
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+			t.Fatalf("expected the mux to close the connection, got %v", err)
+		}
+	}
+	closed(nil)
+	closed([]byte{0xff})
 
-	return cfg.Dir, s, c
+	var buf bytes.Buffer
+	if err := s.Metrics.WritePrometheus(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`influxcloud_meta_mux_rejected_connections_total{reason="timeout"} 1` + "\n",
+		`influxcloud_meta_mux_rejected_connections_total{reason="unknown-header"} 1` + "\n",
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Fatalf("metrics missing %q:\n%s", line, buf.String())
+		}
+	}
 }
 
-// newClient will create a meta client and also open it
-func newClient(s *testService) *cloudMeta.Client {
+// Ensure the HTTP API binds to the address of the configured interface.
+func TestMetaService_HTTPBindInterface(t *testing.T) {
+	t.Parallel()
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lo *net.Interface
+	for i := range ifaces {
+		if ifaces[i].Flags&net.FlagLoopback != 0 {
+			lo = &ifaces[i]
+			break
+		}
+	}
+	if lo == nil {
+		t.Skip("no loopback interface")
+	}
+
 	cfg := newConfig()
-	c := cloudMeta.NewClient(cfg)
-	c.SetMetaServers([]string{s.HTTPAddr()})
-	if err := c.Open(); err != nil {
-		panic(err)
+	defer os.RemoveAll(cfg.Dir)
+	cfg.HTTPBindAddress = ":0"
+	cfg.HTTPBindInterface = lo.Name
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
 	}
-	return c
-}
 
-func newConfig() *cloudMeta.Config {
-	cfg := cloudMeta.NewConfig()
-	cfg.BindAddress = "127.0.0.1:0"
-	cfg.HTTPBindAddress = "127.0.0.1:0"
-	cfg.Dir = testTempDir(2)
-	cfg.LeaseDuration = toml.Duration(1 * time.Second)
-	return cfg
-}
+	s := openService(t, cfg)
+	defer s.Close()
 
-func testTempDir(skip int) string {
-	// Get name of the calling function.
-	pc, _, _, ok := runtime.Caller(skip)
-	if !ok {
-		panic("failed to get name of test function")
+	host, _, err := net.SplitHostPort(s.HTTPAddr())
+	if err != nil {
+		t.Fatal(err)
 	}
-	_, prefix := path.Split(runtime.FuncForPC(pc).Name())
-	// Make a temp dir prefixed with calling function's name.
-	dir, err := ioutil.TempDir(os.TempDir(), prefix)
+	exp, err := cloudMeta.ResolveBindAddress(":0", lo.Name)
 	if err != nil {
-		panic(err)
+		t.Fatal(err)
 	}
-	return dir
-}
-
-type testService struct {
-	*cloudMeta.Service
-	ln net.Listener
-}
-
-func (t *testService) Close() error {
-	if err := t.Service.Close(); err != nil {
-		return err
+	if expHost, _, _ := net.SplitHostPort(exp); host != expHost {
+		t.Fatalf("bound to %s, expected the address of %s (%s)", s.HTTPAddr(), lo.Name, expHost)
 	}
-	return t.ln.Close()
-}
 
-func newService(cfg *cloudMeta.Config) *testService {
-	// Open shared TCP connection.
-	ln, err := net.Listen("tcp", cfg.BindAddress)
+	resp, err := http.Get("http://" + s.HTTPAddr() + "/ping")
 	if err != nil {
-		panic(err)
+		t.Fatal(err)
 	}
+	resp.Body.Close()
 
-	// Multiplex listener.
-	mux := tcp.NewMux()
-
-	if err != nil {
-		panic(err)
+	// Unknown interfaces and addresses not on the interface are rejected.
+	cfg.HTTPBindInterface = "no-such-interface0"
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected unknown interface to be rejected")
+	}
+	cfg.HTTPBindAddress = "192.0.2.1:0"
+	cfg.HTTPBindInterface = lo.Name
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected address not on the interface to be rejected")
 	}
-	s := cloudMeta.NewService(cfg)
-	s.Node = influxcloud.NewNode(cfg.Dir)
-	s.RaftListener = mux.Listen(cloudMeta.MuxHeader)
+}
 
-	go mux.Serve(ln)
+// Ensure the HTTP listener can be reopened while the service is open, and
+// not once it is closed.
+func TestMetaService_ReopenListener(t *testing.T) {
+	t.Parallel()
 
-	return &testService{Service: s, ln: ln}
-}
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer c.Close()
 
-func mustParseStatement(s string) influxql.Statement {
-	stmt, err := influxql.ParseStatement(s)
-	if err != nil {
-		panic(err)
+	// The listener is still up, so reopening it fails to bind.
+	if err := s.ReopenListener(); err == nil {
+		t.Fatal("reopened a listener that is still up")
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	} else if err := s.ReopenListener(); err == nil || err.Error() != "service closed" {
+		t.Fatalf("unexpected error: %v", err)
 	}
-	return stmt
 }
 
-func mustMarshalJSON(v interface{}) string {
-	b, e := json.Marshal(v)
-	if e != nil {
-		panic(e)
+// Ensure the metrics and debug endpoints are served on the loopback debug
+// listener and no longer on the HTTP API once it is enabled.
+func TestMetaService_DebugListener(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	cfg.DebugBindAddress = "127.0.0.1:0"
+	s := openService(t, cfg)
+	defer os.RemoveAll(cfg.Dir)
+	defer s.Close()
+
+	get := func(addr, path string) int {
+		resp, err := http.Get("http://" + addr + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
 	}
-	return string(b)
-}
 
-func freePort() string {
-	l, _ := net.Listen("tcp", "127.0.0.1:0")
-	defer l.Close()
-	return l.Addr().String()
-}
+	if s.DebugAddr() == "" {
+		t.Fatal("debug listener not started")
+	}
+	for _, path := range []string{"/metrics", "/debug/features", "/debug/requests", "/health"} {
+		if code := get(s.DebugAddr(), path); code != http.StatusOK {
+			t.Fatalf("unexpected status on debug listener for %s: %d", path, code)
+		}
+	}
+	if code := get(s.DebugAddr(), "/ping"); code != http.StatusNotFound {
+		t.Fatalf("debug listener serves the API: %d", code)
+	}
 
-func freePorts(i int) []string {
-	var ports []string
-	for j := 0; j < i; j++ {
-		ports = append(ports, freePort())
+	for _, path := range []string{"/metrics", "/debug/features", "/debug/requests"} {
+		if code := get(s.HTTPAddr(), path); code != http.StatusNotFound {
+			t.Fatalf("unexpected status on the HTTP API for %s: %d", path, code)
+		}
+	}
+	if code := get(s.HTTPAddr(), "/ping"); code != http.StatusOK {
+		t.Fatalf("unexpected ping status: %d", code)
 	}
-	return ports
 }
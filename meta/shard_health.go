@@ -0,0 +1,143 @@
+package meta
+
+import (
+	"sync"
+	"time"
+)
+
+// Shard replica health, as reported in ShardHealth.Status.
+const (
+	ShardHealthy     = "healthy"
+	ShardDegraded    = "degraded"
+	ShardUnavailable = "unavailable"
+)
+
+// ShardHealth describes the replicas of a shard: how many are assigned
+// against the retention policy's replication factor, and how many of them
+// are on live data nodes.
+type ShardHealth struct {
+	Database        string `json:"database"`
+	RetentionPolicy string `json:"rp"`
+	ShardGroupID    uint64 `json:"shardGroupID"`
+	ShardID         uint64 `json:"shardID"`
+
+	ReplicaN     int      `json:"replicaN"`
+	Replicas     int      `json:"replicas"`
+	LiveReplicas int      `json:"liveReplicas"`
+	DownOwners   []uint64 `json:"downOwners,omitempty"`
+
+	// Status is ShardHealthy when replicaN replicas are live, ShardDegraded
+	// when fewer are and ShardUnavailable when none are.
+	Status string `json:"status"`
+}
+
+// shardHealth returns the replica health of every shard in the live shard
+// groups of data, with live reporting whether a data node is up.
+func shardHealth(data *Data, live func(id uint64) bool) []ShardHealth {
+	a := []ShardHealth{}
+	for _, di := range data.Data.Databases {
+		for _, rpi := range di.RetentionPolicies {
+			for _, sgi := range rpi.ShardGroups {
+				if sgi.Deleted() {
+					continue
+				}
+				for _, si := range sgi.Shards {
+					h := ShardHealth{
+						Database:        di.Name,
+						RetentionPolicy: rpi.Name,
+						ShardGroupID:    sgi.ID,
+						ShardID:         si.ID,
+						ReplicaN:        rpi.ReplicaN,
+						Replicas:        len(si.Owners),
+					}
+					for _, o := range si.Owners {
+						if live(o.NodeID) {
+							h.LiveReplicas++
+						} else {
+							h.DownOwners = append(h.DownOwners, o.NodeID)
+						}
+					}
+					switch {
+					case h.LiveReplicas == 0:
+						h.Status = ShardUnavailable
+					case h.LiveReplicas < h.ReplicaN:
+						h.Status = ShardDegraded
+					default:
+						h.Status = ShardHealthy
+					}
+					a = append(a, h)
+				}
+			}
+		}
+	}
+	return a
+}
+
+// nodeLiveness tracks the heartbeats of data nodes. A node is live if it
+// sent one within the timeout. A node that never sent one is not counted
+// down: it may run no heartbeat sender, or not have started yet.
+type nodeLiveness struct {
+	mu       sync.Mutex
+	timeout  time.Duration
+	lastSeen map[uint64]time.Time
+
+	// down are the nodes changed last reported down.
+	down map[uint64]bool
+}
+
+func newNodeLiveness(timeout time.Duration) *nodeLiveness {
+	return &nodeLiveness{
+		timeout:  timeout,
+		lastSeen: make(map[uint64]time.Time),
+		down:     make(map[uint64]bool),
+	}
+}
+
+// heartbeat records that the data node id is up.
+func (l *nodeLiveness) heartbeat(id uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lastSeen[id] = now()
+}
+
+// live returns whether the data node id sent a heartbeat within the timeout,
+// or never sent one.
+func (l *nodeLiveness) live(id uint64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.liveLocked(id)
+}
+
+// liveLocked is live with l.mu held.
+func (l *nodeLiveness) liveLocked(id uint64) bool {
+	t, ok := l.lastSeen[id]
+	if !ok {
+		return true
+	}
+	return now().Sub(t) < l.timeout
+}
+
+// changed returns which of the data nodes ids went down and came back up
+// since it was last called. Nodes not in ids are forgotten.
+func (l *nodeLiveness) changed(ids []uint64) (down, up []uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	known := make(map[uint64]bool, len(ids))
+	for _, id := range ids {
+		known[id] = true
+		live := l.liveLocked(id)
+		if !live && !l.down[id] {
+			down = append(down, id)
+			l.down[id] = true
+		} else if live && l.down[id] {
+			up = append(up, id)
+			delete(l.down, id)
+		}
+	}
+	for id := range l.down {
+		if !known[id] {
+			delete(l.down, id)
+		}
+	}
+	return down, up
+}
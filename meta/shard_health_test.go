@@ -0,0 +1,176 @@
+package meta_test
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/toml"
+	"github.com/uber-go/zap"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// Ensure shards owned by a data node that stopped sending heartbeats report
+// degraded replica health.
+func TestMetaService_ShardHealth(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	cfg.DataNodeLivenessTimeout = toml.Duration(500 * time.Millisecond)
+	s := openService(t, cfg)
+	defer s.Close()
+	c := newClient(s)
+	defer c.Close()
+
+	n1, err := c.CreateDataNode("foo:8180", "bar:8281")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n2, err := c.CreateDataNode("foo:8280", "bar:8381")
+	if err != nil {
+		t.Fatal(err)
+	}
+	two := 2
+	if _, err := c.CreateDatabaseWithRetentionPolicy("db0", &meta.RetentionPolicySpec{
+		Name:     "rp0",
+		ReplicaN: &two,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreateShardGroup("db0", "rp0", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []uint64{n1.ID, n2.ID} {
+		if err := c.Heartbeat(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	health, err := c.ShardHealth()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(health) != 1 || health[0].Status != cloudMeta.ShardHealthy || health[0].LiveReplicas != 2 {
+		t.Fatalf("unexpected shard health: %+v", health)
+	}
+
+	// n2 goes quiet.
+	time.Sleep(600 * time.Millisecond)
+	if err := c.Heartbeat(n1.ID); err != nil {
+		t.Fatal(err)
+	}
+	health, err = c.ShardHealth()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(health) != 1 {
+		t.Fatalf("unexpected shard health: %+v", health)
+	}
+	h := health[0]
+	if h.Status != cloudMeta.ShardDegraded || h.ReplicaN != 2 || h.Replicas != 2 || h.LiveReplicas != 1 || !reflect.DeepEqual(h.DownOwners, []uint64{n2.ID}) {
+		t.Fatalf("unexpected shard health: %+v", h)
+	}
+}
+
+// Ensure the heartbeat checker logs nothing for a data node sending
+// heartbeats or one that never sent any, and warns once a node stops.
+func TestMetaService_HeartbeatChecker(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	cfg.DataNodeLivenessTimeout = toml.Duration(300 * time.Millisecond)
+	cfg.DataNodeHeartbeatCheckInterval = toml.Duration(20 * time.Millisecond)
+	s := newService(cfg)
+	var log lockedBuffer
+	s.WithLogger(zap.New(zap.NewTextEncoder(), zap.Output(zap.AddSync(&log))))
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	c := newClient(s)
+	defer c.Close()
+
+	n1, err := c.CreateDataNode("foo:8180", "bar:8281")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreateDataNode("foo:8280", "bar:8381"); err != nil {
+		t.Fatal(err)
+	}
+
+	const stopped = "data node stopped sending heartbeats"
+	for i := 0; i < 20; i++ {
+		if err := c.Heartbeat(n1.ID); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if strings.Contains(log.String(), stopped) {
+		t.Fatalf("unexpected warning while n1 sends heartbeats:\n%s", log.String())
+	}
+
+	// n1 goes quiet.
+	for i := 0; !strings.Contains(log.String(), stopped); i++ {
+		if i == 100 {
+			t.Fatal("no warning after n1 stopped sending heartbeats")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if exp := fmt.Sprintf("node-id=%d", n1.ID); strings.Count(log.String(), stopped) != 1 || !strings.Contains(log.String(), exp) {
+		t.Fatalf("expected a single warning for n1:\n%s", log.String())
+	}
+}
+
+// Ensure a heartbeat reaches the meta servers after one that is down, and
+// that a data node which never sent a heartbeat isn't counted down.
+func TestMetaService_HeartbeatAllServers(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	cfg.DataNodeLivenessTimeout = toml.Duration(500 * time.Millisecond)
+	s := openService(t, cfg)
+	defer s.Close()
+	c := newClient(s)
+	defer c.Close()
+
+	n1, err := c.CreateDataNode("foo:8180", "bar:8281")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreateDataNode("foo:8280", "bar:8381"); err != nil {
+		t.Fatal(err)
+	}
+	two := 2
+	if _, err := c.CreateDatabaseWithRetentionPolicy("db0", &meta.RetentionPolicySpec{
+		Name:     "rp0",
+		ReplicaN: &two,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreateShardGroup("db0", "rp0", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first meta server is down.
+	c.SetMetaServers([]string{"127.0.0.1:1", s.HTTPAddr()})
+	if err := c.Heartbeat(n1.ID); err == nil {
+		t.Fatal("expected an error from the meta server that is down")
+	}
+
+	// n1 goes quiet; n2 never sent a heartbeat.
+	time.Sleep(600 * time.Millisecond)
+	health, err := c.ShardHealth()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(health) != 1 {
+		t.Fatalf("unexpected shard health: %+v", health)
+	}
+	if h := health[0]; h.LiveReplicas != 1 || !reflect.DeepEqual(h.DownOwners, []uint64{n1.ID}) {
+		t.Fatalf("unexpected shard health: %+v", h)
+	}
+}
@@ -0,0 +1,58 @@
+package meta
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// Ensure a node in single-node mode drops the single-node timeouts once
+// another node joins it, goes on serving the cluster, and doesn't take them
+// up again when it restarts.
+func TestService_SingleNodePromotion(t *testing.T) {
+	cfg0 := newTestConfig(t, "TestService_SingleNodePromotion")
+	defer os.RemoveAll(cfg0.Dir)
+	cfg0.SingleNode = true
+	s0, ln0 := openTestService(t, cfg0)
+	if !s0.store.raftState.inSingleNodeMode() {
+		t.Fatal("not in single-node mode once open")
+	}
+
+	cfg1 := newTestConfig(t, "TestService_SingleNodePromotion")
+	defer os.RemoveAll(cfg1.Dir)
+	cfg1.JoinAddress = s0.HTTPAddr()
+	s1, ln1 := openTestService(t, cfg1)
+	defer ln1.Close()
+	// The node is promoted in the background once the join committed.
+	for deadline := time.Now().Add(10 * time.Second); s0.store.raftState.inSingleNodeMode(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("still in single-node mode once joined")
+		}
+	}
+
+	c := NewClient(cfg1)
+	c.SetMetaServers([]string{s0.HTTPAddr()})
+	if err := c.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, err := c.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+
+	httpAddr, raftAddr := s0.HTTPAddr(), s0.RaftAddr()
+	if err := s1.Close(); err != nil {
+		t.Fatal(err)
+	} else if err := s0.Close(); err != nil {
+		t.Fatal(err)
+	}
+	ln0.Close()
+
+	cfg0.HTTPBindAddress, cfg0.BindAddress = httpAddr, raftAddr
+	s0, ln0 = openTestService(t, cfg0)
+	defer ln0.Close()
+	defer s0.Close()
+	if s0.store.raftState.inSingleNodeMode() {
+		t.Fatal("in single-node mode again after a restart")
+	}
+}
@@ -0,0 +1,110 @@
+package meta
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/raft"
+)
+
+// snapshotMagic starts the raft snapshots written with a checksum. It is
+// followed by the SHA-256 of the encoded metadata, then the metadata itself.
+// Snapshots written before checksums were added start with the metadata.
+var snapshotMagic = []byte("ICMSNAP1")
+
+// encodeSnapshot returns the snapshot of the encoded metadata p.
+func encodeSnapshot(p []byte) []byte {
+	sum := sha256.Sum256(p)
+	b := make([]byte, 0, len(snapshotMagic)+len(sum)+len(p))
+	b = append(b, snapshotMagic...)
+	b = append(b, sum[:]...)
+	return append(b, p...)
+}
+
+// decodeSnapshot decodes the metadata of the snapshot b. If verify is set,
+// it returns ErrSnapshotChecksum if the metadata doesn't match the checksum
+// it was written with.
+func decodeSnapshot(b []byte, verify bool) (*Data, error) {
+	if bytes.HasPrefix(b, snapshotMagic) {
+		b = b[len(snapshotMagic):]
+		if len(b) < sha256.Size {
+			return nil, ErrSnapshotChecksum
+		}
+		sum, p := b[:sha256.Size], b[sha256.Size:]
+		if verify {
+			if computed := sha256.Sum256(p); !bytes.Equal(sum, computed[:]) {
+				return nil, ErrSnapshotChecksum
+			}
+		}
+		b = p
+	}
+
+	data := &Data{}
+	if err := data.UnmarshalBinary(b); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// SnapshotStatus is the result of verifying a raft snapshot.
+type SnapshotStatus struct {
+	ID    string
+	Index uint64
+	Term  uint64
+
+	// Err is why the snapshot can't be loaded, or nil if it can.
+	Err error
+}
+
+// VerifySnapshots checks every raft snapshot of the meta data dir at dir,
+// newest first, and returns whether each can be loaded. Nothing in dir is
+// modified.
+func VerifySnapshots(dir string) ([]SnapshotStatus, error) {
+	snapshots, err := openSnapshotStore(dir)
+	if err != nil || snapshots == nil {
+		return nil, err
+	}
+	metas, err := snapshots.List()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]SnapshotStatus, len(metas))
+	for i, m := range metas {
+		_, _, err := readRaftSnapshot(snapshots, m.ID)
+		statuses[i] = SnapshotStatus{ID: m.ID, Index: m.Index, Term: m.Term, Err: err}
+	}
+	return statuses, nil
+}
+
+// openSnapshotStore opens the raft snapshots of the meta data dir at dir,
+// or returns nil if it has none.
+func openSnapshotStore(dir string) (*raft.FileSnapshotStore, error) {
+	// The snapshot store creates its dir if it is missing.
+	if _, err := os.Stat(filepath.Join(dir, "snapshots")); os.IsNotExist(err) {
+		return nil, nil
+	}
+	return raft.NewFileSnapshotStore(dir, raftSnapshotsRetained, ioutil.Discard)
+}
+
+// readRaftSnapshot reads and verifies the snapshot id of snapshots.
+func readRaftSnapshot(snapshots *raft.FileSnapshotStore, id string) (*Data, *raft.SnapshotMeta, error) {
+	m, r, err := snapshots.Open(id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open snapshot %s: %s", id, err)
+	}
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := decodeSnapshot(b, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read snapshot %s: %s", id, err)
+	}
+	return data, m, nil
+}
@@ -0,0 +1,132 @@
+package meta
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/crc64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/raft"
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+// Ensure a snapshot corrupted after it was written is refused on load, and
+// the snapshot before it is loaded instead.
+func TestSnapshot_Checksum(t *testing.T) {
+	dir, err := ioutil.TempDir("", "influxcloud-snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	snapshots, err := raft.NewFileSnapshotStore(dir, raftSnapshotsRetained, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	peers, err := encodeRaftPeers([]string{"localhost:8089"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := &Data{Data: &meta.Data{Index: 10}}
+	for i, index := range []uint64{10, 20} {
+		data.Data.Index = index
+		if err := data.CreateDatabase(fmt.Sprintf("db%d", i+1)); err != nil {
+			t.Fatal(err)
+		}
+		sink, err := snapshots.Create(index, 1, peers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := (&storeFSMSnapshot{Data: data.Clone()}).Persist(sink); err != nil {
+			t.Fatal(err)
+		}
+	}
+	metas, err := snapshots.List()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(metas) != 2 || metas[0].Index != 20 {
+		t.Fatalf("unexpected snapshots: %+v", metas)
+	}
+
+	// Flip a bit of the newest snapshot's metadata, and update the CRC
+	// raft keeps with it, so only the checksum can tell.
+	path := filepath.Join(dir, "snapshots", metas[0].ID)
+	state, err := ioutil.ReadFile(filepath.Join(path, "state.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	state[len(state)-1] ^= 0x01
+	if err := ioutil.WriteFile(filepath.Join(path, "state.bin"), state, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]interface{}
+	b, err := ioutil.ReadFile(filepath.Join(path, "meta.json"))
+	if err != nil {
+		t.Fatal(err)
+	} else if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	crc := crc64.New(crc64.MakeTable(crc64.ECMA))
+	crc.Write(state)
+	m["CRC"] = crc.Sum(nil)
+	if b, err = json.Marshal(m); err != nil {
+		t.Fatal(err)
+	} else if err := ioutil.WriteFile(filepath.Join(path, "meta.json"), b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restoring the corrupt snapshot fails, which makes raft try the next.
+	fsm := (*storeFSM)(newStore(NewConfig(), "", ""))
+	if _, r, err := snapshots.Open(metas[0].ID); err != nil {
+		t.Fatal(err)
+	} else if err := fsm.Restore(r); err != ErrSnapshotChecksum {
+		t.Fatalf("unexpected restore error: %v", err)
+	}
+	if _, r, err := snapshots.Open(metas[1].ID); err != nil {
+		t.Fatal(err)
+	} else if err := fsm.Restore(r); err != nil {
+		t.Fatal(err)
+	} else if fsm.data.Data.Index != 10 || fsm.data.Database("db1") == nil || fsm.data.Database("db2") != nil {
+		t.Fatalf("unexpected restored data: %+v", fsm.data.Data)
+	}
+
+	// Reading the dir falls back to the older snapshot too.
+	latest, lm, err := latestSnapshot(dir)
+	if err != nil {
+		t.Fatal(err)
+	} else if lm.ID != metas[1].ID || latest.Database("db2") != nil {
+		t.Fatalf("unexpected snapshot loaded: %s", lm.ID)
+	}
+
+	statuses, err := VerifySnapshots(dir)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(statuses) != 2 {
+		t.Fatalf("unexpected statuses: %+v", statuses)
+	} else if statuses[0].Err == nil || statuses[1].Err != nil {
+		t.Fatalf("unexpected statuses: %+v", statuses)
+	}
+}
+
+// Ensure snapshots written without a checksum still load.
+func TestSnapshot_Legacy(t *testing.T) {
+	data := &Data{Data: &meta.Data{Index: 1}}
+	if err := data.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+	p, err := data.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fsm := (*storeFSM)(newStore(NewConfig(), "", ""))
+	if err := fsm.Restore(ioutil.NopCloser(bytes.NewReader(p))); err != nil {
+		t.Fatal(err)
+	} else if fsm.data.Database("db0") == nil {
+		t.Fatal("database not restored")
+	}
+}
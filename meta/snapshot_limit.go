@@ -0,0 +1,50 @@
+package meta
+
+import "sync/atomic"
+
+// transferLimiter bounds the number of snapshots sent at once. Requests
+// beyond the limit wait in line and are let through in turn.
+type transferLimiter struct {
+	slots  chan struct{}
+	active int64
+	queued int64
+}
+
+func newTransferLimiter(n int) *transferLimiter {
+	if n <= 0 {
+		n = DefaultMaxConcurrentSnapshots
+	}
+	return &transferLimiter{slots: make(chan struct{}, n)}
+}
+
+// acquire waits for a free slot. It returns false, without a slot, if done
+// is closed first.
+func (l *transferLimiter) acquire(done <-chan struct{}) bool {
+	select {
+	case l.slots <- struct{}{}:
+		atomic.AddInt64(&l.active, 1)
+		return true
+	default:
+	}
+
+	atomic.AddInt64(&l.queued, 1)
+	defer atomic.AddInt64(&l.queued, -1)
+	select {
+	case l.slots <- struct{}{}:
+		atomic.AddInt64(&l.active, 1)
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// release frees the slot taken by acquire.
+func (l *transferLimiter) release() {
+	atomic.AddInt64(&l.active, -1)
+	<-l.slots
+}
+
+// counts returns the number of transfers being sent and waiting.
+func (l *transferLimiter) counts() (active, queued int64) {
+	return atomic.LoadInt64(&l.active), atomic.LoadInt64(&l.queued)
+}
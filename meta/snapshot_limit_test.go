@@ -0,0 +1,84 @@
+package meta
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// Ensure snapshot transfers past the limit are queued, and let through one
+// by one as running transfers finish.
+func TestTransferLimiter_Queue(t *testing.T) {
+	l := newTransferLimiter(2)
+
+	waitCounts := func(active, queued int64) {
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			a, q := l.counts()
+			if a == active && q == queued {
+				return
+			} else if time.Now().After(deadline) {
+				t.Fatalf("unexpected counts: got %d active %d queued, expected %d active %d queued", a, q, active, queued)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	var wg sync.WaitGroup
+	proceed := make(chan struct{})
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !l.acquire(nil) {
+				t.Error("transfer not let through")
+				return
+			}
+			<-proceed
+			l.release()
+		}()
+	}
+	waitCounts(2, 4)
+
+	// Each finished transfer lets exactly one queued one through.
+	for queued := int64(3); queued >= 0; queued-- {
+		proceed <- struct{}{}
+		waitCounts(2, queued)
+	}
+	proceed <- struct{}{}
+	waitCounts(1, 0)
+	proceed <- struct{}{}
+	wg.Wait()
+	waitCounts(0, 0)
+}
+
+// Ensure a queued transfer whose client goes away leaves the queue without
+// a slot.
+func TestTransferLimiter_Cancel(t *testing.T) {
+	l := newTransferLimiter(1)
+	if !l.acquire(nil) {
+		t.Fatal("transfer not let through")
+	}
+
+	done := make(chan struct{})
+	acquired := make(chan bool)
+	go func() { acquired <- l.acquire(done) }()
+	for {
+		if _, q := l.counts(); q == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(done)
+	if <-acquired {
+		t.Fatal("canceled transfer let through")
+	}
+	if a, q := l.counts(); a != 1 || q != 0 {
+		t.Fatalf("unexpected counts: %d active %d queued", a, q)
+	}
+
+	l.release()
+	if !l.acquire(nil) {
+		t.Fatal("transfer not let through after release")
+	}
+}
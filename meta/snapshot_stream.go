@@ -0,0 +1,127 @@
+package meta
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/zhexuany/influxcloud/meta/internal"
+)
+
+// SnapshotStreamContentType is the content type of snapshots served as a
+// stream, with ?stream=true.
+const SnapshotStreamContentType = "application/x-influxcloud-snapshot-stream"
+
+// WriteStream writes data as a stream of length prefixed frames: first
+// everything but the databases, then one frame per database. A reader only
+// ever holds a single frame in memory.
+func (data *Data) WriteStream(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	writeFrame := func(b []byte) error {
+		var n [binary.MaxVarintLen64]byte
+		if _, err := bw.Write(n[:binary.PutUvarint(n[:], uint64(len(b)))]); err != nil {
+			return err
+		}
+		_, err := bw.Write(b)
+		return err
+	}
+
+	// The header is the snapshot without its databases.
+	header := *data
+	schema := *data.Data
+	schema.Databases = nil
+	header.Data = &schema
+	b, err := proto.Marshal(header.marshal())
+	if err != nil {
+		return err
+	}
+	if err := writeFrame(b); err != nil {
+		return err
+	}
+
+	for _, di := range data.Data.Databases {
+		b, err := (&meta.Data{Databases: []meta.DatabaseInfo{di}}).MarshalBinary()
+		if err != nil {
+			return err
+		}
+		if err := writeFrame(b); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadStream decodes a snapshot written by WriteStream, one frame at a time.
+// A frame larger than maxFrame bytes is an error; zero means no limit.
+func ReadStream(r io.Reader, maxFrame int64) (*Data, error) {
+	br := bufio.NewReader(r)
+	readFrame := func() ([]byte, error) {
+		n, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, err
+		}
+		if maxFrame > 0 && n > uint64(maxFrame) {
+			return nil, fmt.Errorf("snapshot frame of %d bytes exceeds max-snapshot-size of %d bytes", n, maxFrame)
+		}
+		b := make([]byte, n)
+		if _, err := io.ReadFull(br, b); err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
+
+	b, err := readFrame()
+	if err != nil {
+		return nil, fmt.Errorf("read snapshot header: %s", err)
+	}
+	var pb internal.ClusterData
+	if err := proto.Unmarshal(b, &pb); err != nil {
+		return nil, err
+	}
+	data := &Data{}
+	data.unmarshal(&pb)
+
+	for {
+		b, err := readFrame()
+		if err == io.EOF {
+			return data, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("read snapshot database: %s", err)
+		}
+		var dbs meta.Data
+		if err := dbs.UnmarshalBinary(b); err != nil {
+			return nil, err
+		}
+		data.Data.Databases = append(data.Data.Databases, dbs.Databases...)
+	}
+}
+
+// readSnapshot decodes a snapshot sent with the given content type, streamed
+// or not. A snapshot that isn't streamed is read into a single buffer, so it
+// is refused past max bytes; a streamed one may not have frames past max.
+// Zero means no limit.
+func readSnapshot(r io.Reader, contentType string, max int64) (*Data, error) {
+	if contentType == SnapshotStreamContentType {
+		return ReadStream(r, max)
+	}
+
+	if max > 0 {
+		r = io.LimitReader(r, max+1)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if max > 0 && int64(len(b)) > max {
+		return nil, fmt.Errorf("snapshot exceeds max-snapshot-size of %d bytes: raise it or enable snapshot-streaming", max)
+	}
+	data := &Data{}
+	if err := data.UnmarshalBinary(b); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,70 @@
+package meta
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+// Ensure a snapshot far larger than max-snapshot-size decodes when streamed,
+// since the reader only holds one database at a time, while reading it into
+// a single buffer is refused.
+func TestReadStream_LargeSnapshot(t *testing.T) {
+	data := &Data{Data: &meta.Data{Index: 42, Term: 3}}
+	if err := data.CreateDataNode("foo:8086", "foo:8088"); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Unix(0, 0).UTC()
+	for i := 0; i < 500; i++ {
+		db := fmt.Sprintf("db%d", i)
+		if err := data.Data.CreateDatabase(db); err != nil {
+			t.Fatal(err)
+		}
+		if err := data.Data.CreateRetentionPolicy(db, &meta.RetentionPolicyInfo{
+			Name:               "rp0",
+			ReplicaN:           1,
+			ShardGroupDuration: time.Hour,
+		}, true); err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j < 20; j++ {
+			if err := data.CreateShardGroup(db, "rp0", start.Add(time.Duration(j)*time.Hour)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	data.SetShardGroupQuota("db0", 10)
+
+	var plain, stream bytes.Buffer
+	b, err := data.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain.Write(b)
+	if err := data.WriteStream(&stream); err != nil {
+		t.Fatal(err)
+	}
+
+	// Far smaller than the snapshot, but larger than any one database.
+	max := int64(plain.Len() / 100)
+
+	if _, err := readSnapshot(&plain, "application/octet-stream", max); err == nil || !strings.Contains(err.Error(), "exceeds max-snapshot-size") {
+		t.Fatalf("unexpected error reading a large snapshot in one buffer: %v", err)
+	}
+
+	other, err := readSnapshot(&stream, SnapshotStreamContentType, max)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(other.marshal(), data.marshal()) {
+		t.Fatal("streamed snapshot differs from the original")
+	}
+	if len(other.Data.Databases) != 500 || other.ShardGroupQuotas["db0"] != 10 {
+		t.Fatalf("unexpected snapshot: %d databases, quotas %v", len(other.Data.Databases), other.ShardGroupQuotas)
+	}
+}
@@ -0,0 +1,30 @@
+package meta
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/uber-go/zap"
+)
+
+// delayStartup waits for a random delay within the configured startup delay
+// range, if any, to spread out the joins of nodes restarted together.
+func (s *Service) delayStartup() {
+	d := startupDelay(time.Duration(s.config.StartupDelayMin), time.Duration(s.config.StartupDelayMax), rand.Int63n)
+	if d <= 0 {
+		return
+	}
+	s.Logger.Info("delaying startup", zap.Duration("delay", d))
+	time.Sleep(d)
+}
+
+// startupDelay returns a random delay in [min, max], using int63n to pick
+// it. Zero for both means no delay.
+func startupDelay(min, max time.Duration, int63n func(int64) int64) time.Duration {
+	if max <= 0 {
+		return 0
+	} else if max <= min {
+		return min
+	}
+	return min + time.Duration(int63n(int64(max-min)+1))
+}
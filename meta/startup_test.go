@@ -0,0 +1,40 @@
+package meta
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// Ensure the startup delay falls within the configured range, covering both
+// ends, and that a zero range skips it.
+func TestStartupDelay(t *testing.T) {
+	min, max := 2*time.Second, 5*time.Second
+	for i := 0; i < 1000; i++ {
+		if d := startupDelay(min, max, rand.Int63n); d < min || d > max {
+			t.Fatalf("delay %s outside [%s, %s]", d, min, max)
+		}
+	}
+
+	lowest := func(n int64) int64 { return 0 }
+	highest := func(n int64) int64 { return n - 1 }
+	if d := startupDelay(min, max, lowest); d != min {
+		t.Fatalf("unexpected lowest delay: %s", d)
+	} else if d := startupDelay(min, max, highest); d != max {
+		t.Fatalf("unexpected highest delay: %s", d)
+	}
+
+	if d := startupDelay(0, 0, rand.Int63n); d != 0 {
+		t.Fatalf("unexpected delay for a zero range: %s", d)
+	}
+}
+
+// Ensure a service without a startup delay opens without waiting for one.
+func TestService_NoStartupDelay(t *testing.T) {
+	s := NewService(NewConfig())
+	start := time.Now()
+	s.delayStartup()
+	if d := time.Since(start); d > 100*time.Millisecond {
+		t.Fatalf("waited %s without a startup delay", d)
+	}
+}
@@ -47,11 +47,59 @@ type store struct {
 	opened      bool
 	logger      *log.Logger
 
+	// avoidLeadership, if set, returns whether the node is to avoid leading
+	// the cluster. It is passed on to raft.
+	avoidLeadership func() bool
+
+	// cas, if set, holds the certificate authorities raft is verified
+	// against. It is passed on to raft.
+	cas *caReloader
+
 	raftAddr string
 	httpAddr string
 
 	node *influxcloud.Node
 
+	// startedAt is the time the store was last opened.
+	startedAt time.Time
+
+	// metadataVersion is the metadata version of the build the node runs,
+	// which a cluster it creates starts at.
+	metadataVersion uint64
+
+	// leaderChanged, if set, is called with the node's leadership state
+	// every time raft reports a leadership change.
+	leaderChanged func(isLeader bool)
+
+	// applyErrors, if set, records the commands that fail to apply.
+	applyErrors *applyErrorHistory
+
+	// appliedCommands, if set, counts the commands applied, by command.
+	appliedCommands *Counter
+
+	// shardGroupQuotaNear, if set, is called on the leader when a new shard
+	// group takes a database within the configured ratio of its quota.
+	shardGroupQuotaNear func(database string, used, max uint64)
+
+	// observed follows the metadata of the cluster in observer-mode.
+	observed *Client
+
+	// observedLeader caches the HTTP address of the leader of the cluster
+	// an observer follows, as of observedLeaderAt.
+	observedLeader   string
+	observedLeaderAt time.Time
+
+	// tasks, if set, lists the raft snapshots being persisted or restored.
+	tasks *taskRegistry
+
+	// snapshotCache holds the metadata marshaled at snapshotIndex.
+	snapshotMu    sync.Mutex
+	snapshotIndex uint64
+	snapshotCache []byte
+
+	// promoteMu serializes leaving single-node mode with close.
+	promoteMu sync.Mutex
+
 	raftLn net.Listener
 }
 
@@ -63,15 +111,16 @@ func newStore(c *Config, httpAddr, raftAddr string) *store {
 				Index: 1,
 			},
 		},
-		closing:     make(chan struct{}),
-		dataChanged: make(chan struct{}),
-		path:        c.Dir,
-		config:      c,
-		httpAddr:    httpAddr,
-		raftAddr:    raftAddr,
+		closing:         make(chan struct{}),
+		dataChanged:     make(chan struct{}),
+		path:            c.RaftPath(),
+		config:          c,
+		httpAddr:        httpAddr,
+		raftAddr:        raftAddr,
+		metadataVersion: MetadataVersion,
 	}
 	if c.LoggingEnabled {
-		s.logger = log.New(os.Stderr, "[metastore] ", log.LstdFlags)
+		s.logger = NewLogger(os.Stderr, c.LogFormat, "[metastore] ", "metastore", 0)
 	} else {
 		s.logger = log.New(ioutil.Discard, "", 0)
 	}
@@ -88,42 +137,89 @@ func newStore(c *Config, httpAddr, raftAddr string) *store {
 func (s *store) open(raftln net.Listener) error {
 	s.logger.Printf("Using data dir: %v", s.path)
 
-	joinPeers, err := s.filterAddr(s.config.JoinPeers, s.httpAddr)
-	if err != nil {
-		return err
-	}
-
-	joinPeers = s.config.JoinPeers
-	var initializePeers []string
-	if len(joinPeers) > 0 {
+	var joinPeers, initializePeers []string
+	if len(s.config.JoinPeers) > 0 {
+		// The dns+srv join peers are resolved to the meta servers they
+		// name, again every discovery-interval while waiting for them.
 		c := NewClient(s.config)
-		c.SetMetaServers(joinPeers)
 		c.SetTLS(s.config.HTTPSEnabled)
+		defer c.Close()
+		if err := c.DiscoverMetaServers(s.config.JoinPeers); err != nil {
+			return err
+		}
+		if _, err := s.filterAddr(c.MetaServers(), s.httpAddr); err != nil {
+			return err
+		}
+
 		for {
+			joinPeers = c.MetaServers()
 			peers := c.peers()
 			if !Peers(peers).Contains(s.raftAddr) {
 				peers = append(peers, s.raftAddr)
 			}
-			if len(s.config.JoinPeers)-len(peers) == 0 {
+			if len(joinPeers)-len(peers) == 0 {
 				initializePeers = peers
 				break
 			}
 
-			if len(peers) > len(s.config.JoinPeers) {
-				s.logger.Printf("waiting for join peers to match config specified. found %v, config specified %v", peers, s.config.JoinPeers)
+			if len(peers) > len(joinPeers) {
+				s.logger.Printf("waiting for join peers to match config specified. found %v, config specified %v", peers, joinPeers)
 			} else {
-				s.logger.Printf("Waiting for %d join peers.  Have %v. Asking nodes: %v", len(s.config.JoinPeers)-len(peers), peers, joinPeers)
+				s.logger.Printf("Waiting for %d join peers.  Have %v. Asking nodes: %v", len(joinPeers)-len(peers), peers, joinPeers)
+			}
+			time.Sleep(time.Second)
+		}
+	} else if s.config.JoinAddress != "" && (s.node == nil || s.node.ID == 0) {
+		// A fresh node joining through join-address starts raft as one of
+		// the cluster's peers rather than as a cluster of its own. The
+		// leader takes it on once it has joined.
+		c := NewClient(s.config)
+		c.SetMetaServers([]string{s.config.JoinAddress})
+		c.SetTLS(s.config.HTTPSEnabled)
+		defer c.Close()
+		for {
+			if peers := c.peers(); len(peers) > 0 {
+				if !Peers(peers).Contains(s.raftAddr) {
+					peers = append(peers, s.raftAddr)
+				}
+				initializePeers = peers
+				break
 			}
+			s.logger.Printf("Waiting for the peers of %s", s.config.JoinAddress)
 			time.Sleep(time.Second)
 		}
 	}
 
+	// A node.json copied from another host names a meta node that is still
+	// live there. It isn't let into raft.
+	if err := s.checkNodeIdentity(); err != nil {
+		return err
+	}
+
+	// A node joining a cluster it can't take part in, by the metadata
+	// version, is refused before its raft starts.
+	var jc *Client
+	if len(joinPeers) > 0 {
+		jc = NewClient(s.config)
+		jc.SetMetaServers(joinPeers)
+		jc.SetTLS(s.config.HTTPSEnabled)
+		jc.metadataVersion = s.metadataVersion
+		if err := jc.Open(); err != nil {
+			return err
+		}
+		defer jc.Close()
+		if err := jc.CheckMetadataVersion(); err != nil {
+			return err
+		}
+	}
+
 	if err := s.setOpen(); err != nil {
 		return err
 	}
+	s.startedAt = now()
 
 	// Create the root directory if it doesn't already exist.
-	if err := os.MkdirAll(s.path, 0777); err != nil {
+	if err := MkdirAll(s.path); err != nil {
 		return fmt.Errorf("mkdir all: %s", err)
 	}
 
@@ -131,40 +227,62 @@ func (s *store) open(raftln net.Listener) error {
 	if err := s.openRaft(initializePeers, raftln); err != nil {
 		return fmt.Errorf("raft: %s", err)
 	}
-
-	if len(joinPeers) > 0 {
-		c := NewClient(s.config)
-		c.SetMetaServers(joinPeers)
-		c.SetTLS(s.config.HTTPSEnabled)
-		if err := c.Open(); err != nil {
-			return err
+	// Raft isn't left running on a store that failed to open.
+	opened := false
+	defer func() {
+		if !opened {
+			if err := s.close(); err != nil {
+				s.logger.Printf("close raft: %s", err)
+			}
 		}
-		defer c.Close()
+	}()
 
-		n, err := c.JoinMetaServer(s.httpAddr, s.raftAddr)
+	if jc != nil {
+		n, err := jc.JoinMetaServer(s.httpAddr, s.raftAddr)
 		if err != nil {
 			return err
 		}
 
 		s.node.ID = n.ID
+		s.node.Validated = time.Now().Unix()
 		if err := s.node.Save(); err != nil {
 			return err
 		}
 
 	}
 
+	// Peers can't reach a node at changed addresses, nor it find a leader,
+	// until its membership is updated.
+	if len(joinPeers) == 0 {
+		if err := s.checkAddressChange(); err != nil {
+			return err
+		}
+	}
+
 	// Wait for a leader to be elected so we know the raft log is loaded
 	// and up to date
 	if err := s.waitForLeader(0); err != nil {
 		return fmt.Errorf("raft: %s", err)
 	}
 
+	// Nor is one the cluster records at another address, or for which
+	// another of its meta nodes is live; raft is closed again.
+	if err := s.checkRecordedIdentity(); err != nil {
+		return err
+	}
+
 	// Make sure this server is in the list of metanodes
 	peers, err := s.raftState.peers()
 	if err != nil {
 		return err
 	}
 
+	// A node joined before it snapshotted starts in single-node mode, and
+	// leaves it once the log has the other peers.
+	if len(peers) > 1 && s.raftState.inSingleNodeMode() {
+		go s.promote()
+	}
+
 	if len(peers) <= 1 {
 		// we have to loop here because if the hostname has changed
 		// raft will take a little bit to normalize so that this host
@@ -178,6 +296,15 @@ func (s *store) open(raftln net.Listener) error {
 		}
 	}
 
+	if err := s.bootstrap(); err != nil {
+		return fmt.Errorf("bootstrap: %s", err)
+	}
+
+	if err := s.recordAddrs(); err != nil {
+		return fmt.Errorf("record addresses: %s", err)
+	}
+
+	opened = true
 	return nil
 }
 
@@ -186,9 +313,11 @@ func (s *store) open(raftln net.Listener) error {
 // that is there. It's used because hostnames can change
 func (s *store) setMetaNode(addr, raftAddr string) error {
 	val := &internal.SetMetaNodeCommand{
-		HTTPAddr: proto.String(addr),
-		TCPAddr:  proto.String(raftAddr),
-		Rand:     proto.Uint64(uint64(rand.Int63())),
+		HTTPAddr:        proto.String(addr),
+		TCPAddr:         proto.String(raftAddr),
+		Rand:            proto.Uint64(uint64(rand.Int63())),
+		StartedAt:       proto.Int64(s.startedAt.UnixNano()),
+		MetadataVersion: proto.Uint64(s.metadataVersion),
 	}
 	t := internal.Command_SetMetaNodeCommand
 	cmd := &internal.Command{Type: &t}
@@ -231,6 +360,17 @@ func (s *store) peers() []string {
 }
 
 func (s *store) filterAddr(addrs []string, filter string) ([]string, error) {
+	// A Unix socket is only ever named the one way.
+	if _, ok := unixSocketPath(filter); ok {
+		var joinPeers []string
+		for _, addr := range addrs {
+			if addr != filter {
+				joinPeers = append(joinPeers, addr)
+			}
+		}
+		return joinPeers, nil
+	}
+
 	host, port, err := net.SplitHostPort(filter)
 	if err != nil {
 		return nil, err
@@ -269,6 +409,8 @@ func (s *store) openRaft(initializePeers []string, raftln net.Listener) error {
 	rs := newRaftState(s.config, s.raftAddr)
 	rs.logger = s.logger
 	rs.path = s.path
+	rs.avoidLeadership = s.avoidLeadership
+	rs.cas = s.cas
 
 	if err := rs.open(s, raftln, initializePeers); err != nil {
 		return err
@@ -325,17 +467,56 @@ func (s *store) reset(st *store) error {
 }
 
 func (s *store) close() error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.promoteMu.Lock()
+	defer s.promoteMu.Unlock()
 
+	s.mu.Lock()
 	select {
 	case <-s.closing:
 		// already closed
+		s.mu.Unlock()
 		return nil
 	default:
 		//closing
 		close(s.closing)
-		return s.raftState.close()
+	}
+	rs := s.raftState
+	observed := s.observed
+	s.mu.Unlock()
+
+	if observed != nil {
+		observed.Close()
+	}
+
+	// Stop raft before taking the lock to tear it down. The FSM takes the
+	// lock while applying entries and raft waits for the FSM to exit.
+	if rs != nil && rs.raft != nil {
+		rs.raft.Shutdown().Error()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.raftState.close()
+}
+
+// promote has raft leave single-node mode once another meta node is a
+// member. join runs it in the background, so the join returns once the node
+// is committed rather than once raft restarted. A store closing meanwhile
+// isn't promoted.
+func (s *store) promote() {
+	s.promoteMu.Lock()
+	defer s.promoteMu.Unlock()
+
+	s.mu.RLock()
+	rs := s.raftState
+	s.mu.RUnlock()
+	select {
+	case <-s.closing:
+		return
+	default:
+	}
+	if err := rs.promote(s); err != nil {
+		s.logger.Printf("leave single-node mode: %s", err)
 	}
 }
 
@@ -345,6 +526,27 @@ func (s *store) snapshot() (*Data, error) {
 	return s.data.Clone(), nil
 }
 
+// marshaledSnapshot returns the metadata marshaled as the clients polling
+// for it are sent it. The encoding is kept until the metadata changes, so
+// the clients that all poll again after a change share one.
+func (s *store) marshaledSnapshot() ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	index := s.data.Data.Index
+
+	s.snapshotMu.Lock()
+	defer s.snapshotMu.Unlock()
+	if s.snapshotCache != nil && s.snapshotIndex == index {
+		return s.snapshotCache, nil
+	}
+	b, err := s.data.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	s.snapshotIndex, s.snapshotCache = index, b
+	return b, nil
+}
+
 func (s *store) setSnapshot(data *Data) error {
 	dataB, err := data.MarshalBinary()
 	if err != nil {
@@ -397,6 +599,29 @@ func (s *store) applied(timeout time.Duration) error {
 	return s.raftState.raft.Barrier(timeout).Error()
 }
 
+// verifyLeader returns nil if the node is the leader and a quorum of its peers
+// still acknowledges it within timeout.
+func (s *store) verifyLeader(timeout time.Duration) error {
+	s.mu.RLock()
+	if s.raftState == nil || s.raftState.raft == nil {
+		s.mu.RUnlock()
+		return raft.ErrNotLeader
+	}
+	f := s.raftState.raft.VerifyLeader()
+	s.mu.RUnlock()
+
+	// A leader cut off from its peers only finds out once its lease runs
+	// out.
+	errc := make(chan error, 1)
+	go func() { errc <- f.Error() }()
+	select {
+	case err := <-errc:
+		return err
+	case <-time.After(timeout):
+		return ErrLeaderNotVerified
+	}
+}
+
 // WaitForLeader sleeps until a leader is found or a timeout occurs.
 // timeout == 0 means to wait forever.
 func (s *store) waitForLeader(timeout time.Duration) error {
@@ -427,12 +652,139 @@ func (s *store) waitForLeader(timeout time.Duration) error {
 func (s *store) isLeader() bool {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	if s.raftState == nil {
+	if s.raftState == nil || s.raftState.raft == nil {
 		return false
 	}
 	return s.raftState.raft.State() == raft.Leader
 }
 
+// stepDown hands leadership to another meta node: it waits for the entries
+// already taken to commit, stops raft so the followers elect a new leader
+// and waits up to timeout for one of them to report it. This raft has no
+// way to pick the next leader, so the most up to date follower wins. It
+// returns ErrNoLeadershipTarget, leaving raft running, if no follower
+// answers.
+func (s *store) stepDown(timeout time.Duration) error {
+	if !s.isLeader() {
+		return nil
+	}
+
+	c := NewClient(s.config)
+	c.SetTLS(s.config.HTTPSEnabled)
+	defer c.Close()
+
+	var followers []string
+	for _, server := range s.otherMetaServersHTTP() {
+		if st, err := c.raftStatus(server); err == nil && st.State == raft.Follower.String() {
+			followers = append(followers, server)
+		}
+	}
+	if len(followers) == 0 {
+		return ErrNoLeadershipTarget
+	}
+	c.SetMetaServers(followers)
+
+	deadline := time.Now().Add(timeout)
+	if err := s.applied(timeout); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	rs := s.raftState
+	s.mu.RUnlock()
+	rs.raft.Shutdown().Error()
+
+	// The followers report this node as leader until they hold an election.
+	for {
+		if l := c.raftLeader(""); l != "" && l != s.raftAddr {
+			s.logger.Printf("Leadership transferred to %s", l)
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return ErrLeadershipTransferTimeout
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// raftStatus returns the local raft status, or nil if raft isn't running.
+func (s *store) raftStatus() *raftStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.raftState == nil || s.raftState.raft == nil {
+		return nil
+	}
+	st := s.raftState.status()
+	if s.node != nil {
+		st.NodeID = s.node.ID
+	}
+	for _, n := range s.data.MetaNodes {
+		if n.TCPHost == st.Leader {
+			st.LeaderHTTP = n.Host
+		}
+	}
+	return st
+}
+
+// raftContacts returns when the node last heard from each of its raft
+// peers, by address, or nil if raft isn't open.
+func (s *store) raftContacts() map[string]time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.raftState == nil || s.raftState.raft == nil {
+		return nil
+	}
+	return s.raftState.contacts()
+}
+
+// applyLag returns the number of committed raft entries not yet applied to
+// the local state machine.
+func (s *store) applyLag() uint64 {
+	if st := s.raftStatus(); st != nil {
+		return st.ApplyLag
+	}
+	return 0
+}
+
+// waitForApplied blocks until the raft log entry at index has been applied
+// to the local state machine. It returns ErrApplyTimeout if that takes
+// longer than timeout.
+func (s *store) waitForApplied(index uint64, timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	// Entries that aren't commands, such as a new leader's no-op, are
+	// applied without signaling dataChanged, so check now and then too.
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		s.mu.RLock()
+		changed := s.dataChanged
+		s.mu.RUnlock()
+		if st := s.raftStatus(); st != nil && st.AppliedIndex >= index {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ticker.C:
+		case <-timer.C:
+			return ErrApplyTimeout
+		case <-s.closing:
+			return ErrStoreClosed
+		}
+	}
+}
+
+// pendingConfigChanges returns the number of raft membership changes in the
+// log not yet committed.
+func (s *store) pendingConfigChanges() int {
+	if st := s.raftStatus(); st != nil {
+		return st.PendingConfigChanges
+	}
+	return 0
+}
+
 // leader returns what the store thinks is the current leader. An empty
 // string indicates no leader exists.
 func (s *store) leader() string {
@@ -447,9 +799,13 @@ func (s *store) leader() string {
 // leaderHTTP returns the HTTP API connection info for the metanode
 // that is the raft leader
 func (s *store) leaderHTTP() string {
+	if s.config.ObserverMode {
+		return s.observedLeaderHTTP()
+	}
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	if s.raftState == nil {
+	if s.raftState == nil || s.raftState.raft == nil {
 		return ""
 	}
 	l := s.raftState.raft.Leader()
@@ -479,6 +835,33 @@ func (s *store) otherMetaServersHTTP() []string {
 
 }
 
+// metaNodeID returns the ID of the meta node at raft address tcpHost, or
+// zero if there's none.
+func (s *store) metaNodeID(tcpHost string) uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, n := range s.data.MetaNodes {
+		if n.TCPHost == tcpHost {
+			return n.ID
+		}
+	}
+	return 0
+}
+
+// adminHash returns the password hash of the admin user name, and false if
+// name is no admin user.
+func (s *store) adminHash(name string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u := s.data.User(name)
+	if u == nil || !u.Admin {
+		return "", false
+	}
+	return u.Hash, true
+}
+
 // dataNode will return a data node info according to its id
 func (s *store) dataNode(id uint64) *NodeInfo {
 	s.mu.RLock()
@@ -521,6 +904,9 @@ func (s *store) index() uint64 {
 
 // apply applies a command to raft.
 func (s *store) apply(b []byte) error {
+	if s.config.ObserverMode {
+		return raft.ErrNotLeader
+	}
 	if s.raftState == nil {
 		return fmt.Errorf("store not open")
 	}
@@ -536,15 +922,23 @@ func (s *store) join(n *NodeInfo) (*NodeInfo, error) {
 		return nil, fmt.Errorf("store not open")
 	}
 
+	if s.data.TopologyFrozen {
+		s.mu.RUnlock()
+		return nil, ErrTopologyFrozen
+	}
+
 	if err := s.raftState.addPeer(n.TCPHost); err != nil {
 		s.mu.RUnlock()
 		return nil, err
 	}
 	s.mu.RUnlock()
 
-	if err := s.createMetaNode(n.Host, n.TCPHost); err != nil {
+	if err := s.createMetaNode(n.Host, n.TCPHost, n.StartedAt); err != nil {
 		return nil, err
 	}
+	if s.raftState.inSingleNodeMode() {
+		go s.promote()
+	}
 
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -567,11 +961,15 @@ func (s *store) removePeer(peer string) error {
 
 // createMetaNode is used by the join command to create the metanode int
 // the metastore
-func (s *store) createMetaNode(addr, raftAddr string) error {
+func (s *store) createMetaNode(addr, raftAddr string, startedAt time.Time) error {
 	val := &internal.CreateMetaNodeCommand{
-		HTTPAddr: proto.String(addr),
-		TCPAddr:  proto.String(raftAddr),
-		Rand:     proto.Uint64(uint64(rand.Int63())),
+		HTTPAddr:        proto.String(addr),
+		TCPAddr:         proto.String(raftAddr),
+		Rand:            proto.Uint64(uint64(rand.Int63())),
+		MetadataVersion: proto.Uint64(s.metadataVersion),
+	}
+	if !startedAt.IsZero() {
+		val.StartedAt = proto.Int64(startedAt.UnixNano())
 	}
 	t := internal.Command_CreateMetaNodeCommand
 	cmd := &internal.Command{Type: &t}
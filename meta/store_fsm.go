@@ -1,6 +1,7 @@
 package meta
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -27,61 +28,13 @@ func (fsm *storeFSM) Apply(l *raft.Log) interface{} {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	err := func() interface{} {
-		switch cmd.GetType() {
-		case internal.Command_CreateDatabaseCommand:
-			return fsm.applyCreateDatabaseCommand(&cmd)
-		case internal.Command_DropDatabaseCommand:
-			return fsm.applyDropDatabaseCommand(&cmd)
-		case internal.Command_CreateRetentionPolicyCommand:
-			return fsm.applyCreateRetentionPolicyCommand(&cmd)
-		case internal.Command_DropRetentionPolicyCommand:
-			return fsm.applyDropRetentionPolicyCommand(&cmd)
-		case internal.Command_SetDefaultRetentionPolicyCommand:
-			return fsm.applySetDefaultRetentionPolicyCommand(&cmd)
-		case internal.Command_UpdateRetentionPolicyCommand:
-			return fsm.applyUpdateRetentionPolicyCommand(&cmd)
-		case internal.Command_CreateShardGroupCommand:
-			return fsm.applyCreateShardGroupCommand(&cmd)
-		case internal.Command_DeleteShardGroupCommand:
-			return fsm.applyDeleteShardGroupCommand(&cmd)
-		case internal.Command_CreateContinuousQueryCommand:
-			return fsm.applyCreateContinuousQueryCommand(&cmd)
-		case internal.Command_DropContinuousQueryCommand:
-			return fsm.applyDropContinuousQueryCommand(&cmd)
-		case internal.Command_CreateSubscriptionCommand:
-			return fsm.applyCreateSubscriptionCommand(&cmd)
-		case internal.Command_DropSubscriptionCommand:
-			return fsm.applyDropSubscriptionCommand(&cmd)
-		case internal.Command_CreateUserCommand:
-			return fsm.applyCreateUserCommand(&cmd)
-		case internal.Command_DropUserCommand:
-			return fsm.applyDropUserCommand(&cmd)
-		case internal.Command_UpdateUserCommand:
-			return fsm.applyUpdateUserCommand(&cmd)
-		case internal.Command_SetPrivilegeCommand:
-			return fsm.applySetPrivilegeCommand(&cmd)
-		case internal.Command_SetAdminPrivilegeCommand:
-			return fsm.applySetAdminPrivilegeCommand(&cmd)
-		case internal.Command_SetDataCommand:
-			return fsm.applySetDataCommand(&cmd)
-		case internal.Command_CreateMetaNodeCommand:
-			return fsm.applyCreateMetaNodeCommand(&cmd)
-		case internal.Command_DeleteMetaNodeCommand:
-			return fsm.applyDeleteMetaNodeCommand(&cmd, s)
-		case internal.Command_SetMetaNodeCommand:
-			return fsm.applySetMetaNodeCommand(&cmd)
-		case internal.Command_CreateDataNodeCommand:
-			return fsm.applyCreateDataNodeCommand(&cmd)
-		case internal.Command_DeleteDataNodeCommand:
-			return fsm.applyDeleteDataNodeCommand(&cmd)
-		case internal.Command_AddShardOwnerCommand:
-			// return fsm.applyAddShardOwnerCommand(&cmd)
-		default:
-			panic(fmt.Errorf("cannot apply command: %x", l.Data))
-		}
-		return nil
-	}()
+	err := fsm.applyCommand(&cmd, s)
+	if s.appliedCommands != nil {
+		s.appliedCommands.Inc(cmd.GetType().String())
+	}
+	if e, ok := err.(error); ok && e != nil && s.applyErrors != nil {
+		s.applyErrors.add(l.Index, cmd.GetType(), e)
+	}
 
 	// Copy term and index to new metadata.
 	fsm.data.Data.Term = l.Term
@@ -94,6 +47,119 @@ func (fsm *storeFSM) Apply(l *raft.Log) interface{} {
 	return err
 }
 
+// applyCommand applies a single command to the FSM's data. The store lock
+// must be held.
+func (fsm *storeFSM) applyCommand(cmd *internal.Command, s *store) interface{} {
+	if fsm.data.TopologyFrozen && isTopologyCommand(cmd.GetType()) {
+		return ErrTopologyFrozen
+	}
+
+	switch cmd.GetType() {
+	case internal.Command_CreateDatabaseCommand:
+		return fsm.applyCreateDatabaseCommand(cmd)
+	case internal.Command_DropDatabaseCommand:
+		return fsm.applyDropDatabaseCommand(cmd)
+	case internal.Command_CreateRetentionPolicyCommand:
+		return fsm.applyCreateRetentionPolicyCommand(cmd)
+	case internal.Command_DropRetentionPolicyCommand:
+		return fsm.applyDropRetentionPolicyCommand(cmd)
+	case internal.Command_SetDefaultRetentionPolicyCommand:
+		return fsm.applySetDefaultRetentionPolicyCommand(cmd)
+	case internal.Command_UpdateRetentionPolicyCommand:
+		return fsm.applyUpdateRetentionPolicyCommand(cmd)
+	case internal.Command_CreateShardGroupCommand:
+		return fsm.applyCreateShardGroupCommand(cmd, s)
+	case internal.Command_DeleteShardGroupCommand:
+		return fsm.applyDeleteShardGroupCommand(cmd)
+	case internal.Command_CreateContinuousQueryCommand:
+		return fsm.applyCreateContinuousQueryCommand(cmd)
+	case internal.Command_DropContinuousQueryCommand:
+		return fsm.applyDropContinuousQueryCommand(cmd)
+	case internal.Command_CreateSubscriptionCommand:
+		return fsm.applyCreateSubscriptionCommand(cmd)
+	case internal.Command_DropSubscriptionCommand:
+		return fsm.applyDropSubscriptionCommand(cmd)
+	case internal.Command_CreateUserCommand:
+		return fsm.applyCreateUserCommand(cmd)
+	case internal.Command_DropUserCommand:
+		return fsm.applyDropUserCommand(cmd)
+	case internal.Command_UpdateUserCommand:
+		return fsm.applyUpdateUserCommand(cmd)
+	case internal.Command_SetPrivilegeCommand:
+		return fsm.applySetPrivilegeCommand(cmd)
+	case internal.Command_SetAdminPrivilegeCommand:
+		return fsm.applySetAdminPrivilegeCommand(cmd)
+	case internal.Command_SetDataCommand:
+		return fsm.applySetDataCommand(cmd)
+	case internal.Command_CreateMetaNodeCommand:
+		return fsm.applyCreateMetaNodeCommand(cmd)
+	case internal.Command_DeleteMetaNodeCommand:
+		return fsm.applyDeleteMetaNodeCommand(cmd, s)
+	case internal.Command_SetMetaNodeCommand:
+		return fsm.applySetMetaNodeCommand(cmd)
+	case internal.Command_UpdateMetaNodeCommand:
+		return fsm.applyUpdateMetaNodeCommand(cmd)
+	case internal.Command_CreateDataNodeCommand:
+		return fsm.applyCreateDataNodeCommand(cmd)
+	case internal.Command_DeleteDataNodeCommand:
+		return fsm.applyDeleteDataNodeCommand(cmd)
+	case internal.Command_SetTopologyFrozenCommand:
+		return fsm.applySetTopologyFrozenCommand(cmd)
+	case internal.Command_BootstrapCommand:
+		return fsm.applyBootstrapCommand(cmd, s)
+	case internal.Command_SetShardGroupQuotaCommand:
+		return fsm.applySetShardGroupQuotaCommand(cmd)
+	case internal.Command_SetDatabaseAnnotationCommand:
+		return fsm.applySetDatabaseAnnotationCommand(cmd)
+	case internal.Command_SetDatabaseWriteBlockedCommand:
+		return fsm.applySetDatabaseWriteBlockedCommand(cmd)
+	case internal.Command_PurgeOrphansCommand:
+		return fsm.applyPurgeOrphansCommand(cmd)
+	case internal.Command_PurgeShardGroupsCommand:
+		return fsm.applyPurgeShardGroupsCommand(cmd)
+	case internal.Command_SetMetadataVersionCommand:
+		return fsm.applySetMetadataVersionCommand(cmd)
+	case internal.Command_AlterRetentionPolicyReplicaNCommand:
+		return fsm.applyAlterRetentionPolicyReplicaNCommand(cmd)
+	case internal.Command_CommitPendingShardOwnerCommand:
+		return fsm.applyCommitPendingShardOwnerCommand(cmd)
+	case internal.Command_RemovePendingShardOwnerCommand:
+		return fsm.applyRemovePendingShardOwnerCommand(cmd)
+	case internal.Command_AcquireRestartLockCommand:
+		return fsm.applyAcquireRestartLockCommand(cmd)
+	case internal.Command_ReleaseRestartLockCommand:
+		return fsm.applyReleaseRestartLockCommand(cmd)
+	case internal.Command_AddShardOwnerCommand:
+		// return fsm.applyAddShardOwnerCommand(cmd)
+	default:
+		panic(fmt.Errorf("cannot apply command: %s", cmd.GetType()))
+	}
+	return nil
+}
+
+// isTopologyCommand returns true if typ changes cluster membership or the
+// placement of shards on nodes.
+func isTopologyCommand(typ internal.Command_Type) bool {
+	switch typ {
+	case internal.Command_CreateMetaNodeCommand,
+		internal.Command_UpdateMetaNodeCommand,
+		internal.Command_DeleteMetaNodeCommand,
+		internal.Command_CreateDataNodeCommand,
+		internal.Command_UpdateDataNodeCommand,
+		internal.Command_DeleteDataNodeCommand,
+		internal.Command_RemovePeerCommand,
+		internal.Command_AddShardOwnerCommand,
+		internal.Command_RemoveShardOwnerCommand,
+		internal.Command_AddPendingShardOwnerCommand,
+		internal.Command_CommitPendingShardOwnerCommand,
+		internal.Command_RemovePendingShardOwnerCommand,
+		internal.Command_CreateBalancedShardGroupCommand,
+		internal.Command_AlterRetentionPolicyReplicaNCommand:
+		return true
+	}
+	return false
+}
+
 func (fsm *storeFSM) applyUpdateDataNodeCommand(cmd *internal.Command) interface{} {
 	ext, err := proto.GetExtension(cmd, internal.E_CreateDataNodeCommand_Command)
 	if err != nil {
@@ -119,6 +185,20 @@ func (fsm *storeFSM) applyCreateDatabaseCommand(cmd *internal.Command) interface
 	ext, _ := proto.GetExtension(cmd, internal.E_CreateDatabaseCommand_Command)
 	v := ext.(*internal.CreateDatabaseCommand)
 
+	// Commands written before IfNotExists existed always succeed on a
+	// duplicate, so only an explicit false makes it an error.
+	if fsm.data.Database(v.GetName()) != nil {
+		if v.IfNotExists != nil && !v.GetIfNotExists() {
+			return ErrDatabaseExists
+		}
+		// The loser of a race to create the database leaves it as the
+		// winner created it. Only a retention policy asked for explicitly
+		// is checked against, or added to, the existing database.
+		if v.RetentionPolicy == nil {
+			return nil
+		}
+	}
+
 	// Copy data and update.
 	other := fsm.data.Clone()
 	if err := other.Data.CreateDatabase(v.GetName()); err != nil {
@@ -168,6 +248,9 @@ func (fsm *storeFSM) applyDropDatabaseCommand(cmd *internal.Command) interface{}
 	if err := other.Data.DropDatabase(v.GetName()); err != nil {
 		return err
 	}
+	delete(other.ShardGroupQuotas, v.GetName())
+	delete(other.DatabaseAnnotations, v.GetName())
+	delete(other.WriteBlockedDatabases, v.GetName())
 	fsm.data = other
 
 	return nil
@@ -179,6 +262,12 @@ func (fsm *storeFSM) applyCreateRetentionPolicyCommand(cmd *internal.Command) in
 	rpi := meta.RetentionPolicyInfo{}
 	_ = rpi.UnmarshalBinary(v.GetRetentionPolicy())
 
+	if v.IfNotExists != nil && !v.GetIfNotExists() {
+		if rp, _ := fsm.data.RetentionPolicy(v.GetDatabase(), rpi.Name); rp != nil {
+			return ErrRetentionPolicyExists
+		}
+	}
+
 	// Copy data and update.
 	other := fsm.data.Clone()
 	if err := other.Data.CreateRetentionPolicy(v.GetDatabase(), &rpi, false); err != nil {
@@ -232,6 +321,10 @@ func (fsm *storeFSM) applyUpdateRetentionPolicyCommand(cmd *internal.Command) in
 		value := int(v.GetReplicaN())
 		rpu.ReplicaN = &value
 	}
+	if v.ShardGroupDuration != nil {
+		value := time.Duration(v.GetShardGroupDuration())
+		rpu.ShardGroupDuration = &value
+	}
 
 	// Copy data and update.
 	other := fsm.data.Clone()
@@ -243,7 +336,44 @@ func (fsm *storeFSM) applyUpdateRetentionPolicyCommand(cmd *internal.Command) in
 	return nil
 }
 
-func (fsm *storeFSM) applyCreateShardGroupCommand(cmd *internal.Command) interface{} {
+func (fsm *storeFSM) applyAlterRetentionPolicyReplicaNCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_AlterRetentionPolicyReplicaNCommand_Command)
+	v := ext.(*internal.AlterRetentionPolicyReplicaNCommand)
+
+	other := fsm.data.Clone()
+	if err := other.AlterRetentionPolicyReplicaN(v.GetDatabase(), v.GetName(), int(v.GetReplicaN())); err != nil {
+		return err
+	}
+
+	fsm.data = other
+	return nil
+}
+
+func (fsm *storeFSM) applyCommitPendingShardOwnerCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_CommitPendingShardOwnerCommand_Command)
+	v := ext.(*internal.CommitPendingShardOwnerCommand)
+
+	other := fsm.data.Clone()
+	if err := other.CommitPendingShardOwner(v.GetID(), v.GetNodeID()); err != nil {
+		return err
+	}
+
+	fsm.data = other
+	return nil
+}
+
+func (fsm *storeFSM) applyRemovePendingShardOwnerCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_RemovePendingShardOwnerCommand_Command)
+	v := ext.(*internal.RemovePendingShardOwnerCommand)
+
+	other := fsm.data.Clone()
+	other.RemovePendingShardOwner(v.GetID(), v.GetNodeID())
+
+	fsm.data = other
+	return nil
+}
+
+func (fsm *storeFSM) applyCreateShardGroupCommand(cmd *internal.Command, s *store) interface{} {
 	ext, _ := proto.GetExtension(cmd, internal.E_CreateShardGroupCommand_Command)
 	v := ext.(*internal.CreateShardGroupCommand)
 
@@ -255,6 +385,94 @@ func (fsm *storeFSM) applyCreateShardGroupCommand(cmd *internal.Command) interfa
 	}
 	fsm.data = other
 
+	fsm.checkShardGroupQuota(v.GetDatabase(), s)
+	return nil
+}
+
+// checkShardGroupQuota calls the near quota hook if database is within the
+// configured ratio of its shard group quota. Only the leader calls it, so
+// it is called once per shard group.
+func (fsm *storeFSM) checkShardGroupQuota(database string, s *store) {
+	max := fsm.data.ShardGroupQuotas[database]
+	if max == 0 || s.shardGroupQuotaNear == nil {
+		return
+	}
+	if s.raftState == nil || s.raftState.raft == nil || s.raftState.raft.State() != raft.Leader {
+		return
+	}
+	used := fsm.data.ShardGroupCount(database)
+	if float64(used) < s.config.ShardGroupQuotaNearRatio*float64(max) {
+		return
+	}
+
+	// The hook may well talk to the meta service, so don't hold up the FSM.
+	go s.shardGroupQuotaNear(database, used, max)
+}
+
+func (fsm *storeFSM) applySetShardGroupQuotaCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_SetShardGroupQuotaCommand_Command)
+	v := ext.(*internal.SetShardGroupQuotaCommand)
+
+	other := fsm.data.Clone()
+	if err := other.SetShardGroupQuota(v.GetDatabase(), v.GetMaxShardGroups()); err != nil {
+		return err
+	}
+
+	fsm.data = other
+	return nil
+}
+
+func (fsm *storeFSM) applySetDatabaseAnnotationCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_SetDatabaseAnnotationCommand_Command)
+	v := ext.(*internal.SetDatabaseAnnotationCommand)
+
+	other := fsm.data.Clone()
+	if err := other.SetDatabaseAnnotation(v.GetDatabase(), v.GetKey(), v.GetValue()); err != nil {
+		return err
+	}
+
+	fsm.data = other
+	return nil
+}
+
+func (fsm *storeFSM) applySetDatabaseWriteBlockedCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_SetDatabaseWriteBlockedCommand_Command)
+	v := ext.(*internal.SetDatabaseWriteBlockedCommand)
+
+	other := fsm.data.Clone()
+	if err := other.SetDatabaseWriteBlocked(v.GetDatabase(), v.GetBlocked(), time.Unix(0, v.GetTime())); err != nil {
+		return err
+	}
+
+	fsm.data = other
+	return nil
+}
+
+func (fsm *storeFSM) applyAcquireRestartLockCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_AcquireRestartLockCommand_Command)
+	v := ext.(*internal.AcquireRestartLockCommand)
+
+	// Expiry is judged by the time in the command so that every member
+	// applies it alike.
+	other := fsm.data.Clone()
+	if err := other.AcquireRestartLock(v.GetHolder(), v.GetNodeID(), time.Unix(0, v.GetTime()), time.Duration(v.GetTTL())); err != nil {
+		return err
+	}
+
+	fsm.data = other
+	return nil
+}
+
+func (fsm *storeFSM) applyReleaseRestartLockCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_ReleaseRestartLockCommand_Command)
+	v := ext.(*internal.ReleaseRestartLockCommand)
+
+	other := fsm.data.Clone()
+	if err := other.ReleaseRestartLock(v.GetHolder()); err != nil {
+		return err
+	}
+
+	fsm.data = other
 	return nil
 }
 
@@ -267,6 +485,15 @@ func (fsm *storeFSM) applyDeleteShardGroupCommand(cmd *internal.Command) interfa
 	if err := other.Data.DeleteShardGroup(v.GetDatabase(), v.GetPolicy(), v.GetShardGroupID()); err != nil {
 		return err
 	}
+	// A command the leader did not stamp keeps the time of the node applying it.
+	if v.DeletedAt != nil {
+		rpi, _ := other.Data.RetentionPolicy(v.GetDatabase(), v.GetPolicy())
+		for i := range rpi.ShardGroups {
+			if rpi.ShardGroups[i].ID == v.GetShardGroupID() {
+				rpi.ShardGroups[i].DeletedAt = time.Unix(0, v.GetDeletedAt()).UTC()
+			}
+		}
+	}
 	fsm.data = other
 
 	return nil
@@ -410,11 +637,18 @@ func (fsm *storeFSM) applyCreateMetaNodeCommand(cmd *internal.Command) interface
 
 	other := fsm.data.Clone()
 	_ = other.CreateMetaNode(v.GetHTTPAddr(), v.GetTCPAddr())
+	if v.StartedAt != nil {
+		setNodeStartedAt(other.MetaNodes, v.GetTCPAddr(), time.Unix(0, v.GetStartedAt()).UTC())
+	}
 
 	// If the cluster ID hasn't been set then use the command's random number.
 	if other.Data.ClusterID == 0 {
 		other.Data.ClusterID = uint64(v.GetRand())
 	}
+	// The cluster keeps the metadata version of the node that created it.
+	if other.MetadataVersion == 0 {
+		other.MetadataVersion = v.GetMetadataVersion()
+	}
 
 	fsm.data = other
 	return nil
@@ -431,7 +665,27 @@ func (fsm *storeFSM) applySetMetaNodeCommand(cmd *internal.Command) interface{}
 		other.Data.ClusterID = uint64(v.GetRand())
 	}
 
+	if other.MetadataVersion == 0 {
+		other.MetadataVersion = v.GetMetadataVersion()
+	}
+
 	_ = other.SetMetaNode(other.Data.ClusterID, v.GetHTTPAddr(), v.GetTCPAddr())
+	if v.StartedAt != nil {
+		setNodeStartedAt(other.MetaNodes, v.GetTCPAddr(), time.Unix(0, v.GetStartedAt()).UTC())
+	}
+
+	fsm.data = other
+	return nil
+}
+
+func (fsm *storeFSM) applyUpdateMetaNodeCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_UpdateMetaNodeCommand_Command)
+	v := ext.(*internal.UpdateMetaNodeCommand)
+
+	other := fsm.data.Clone()
+	if err := other.UpdateMetaNode(v.GetID(), v.GetHTTPAddr(), v.GetTCPAddr()); err != nil {
+		return err
+	}
 
 	fsm.data = other
 	return nil
@@ -464,6 +718,9 @@ func (fsm *storeFSM) applyCreateDataNodeCommand(cmd *internal.Command) interface
 
 	other := fsm.data.Clone()
 	_ = other.CreateDataNode(v.GetHTTPAddr(), v.GetTCPAddr())
+	if v.StartedAt != nil {
+		setNodeStartedAt(other.DataNodes, v.GetTCPAddr(), time.Unix(0, v.GetStartedAt()).UTC())
+	}
 
 	fsm.data = other
 	return nil
@@ -481,6 +738,50 @@ func (fsm *storeFSM) applyDeleteDataNodeCommand(cmd *internal.Command) interface
 	return nil
 }
 
+// applyBootstrapCommand applies every command of the bootstrap batch, or none
+// of them if one fails. It is a no-op once the cluster has been bootstrapped.
+func (fsm *storeFSM) applyBootstrapCommand(cmd *internal.Command, s *store) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_BootstrapCommand_Command)
+	v := ext.(*internal.BootstrapCommand)
+
+	if fsm.data.Bootstrapped {
+		return nil
+	}
+
+	prev := fsm.data
+	for _, b := range v.GetCommands() {
+		var sub internal.Command
+		if err := proto.Unmarshal(b, &sub); err != nil {
+			fsm.data = prev
+			return err
+		}
+		if sub.GetType() == internal.Command_BootstrapCommand {
+			fsm.data = prev
+			return fmt.Errorf("bootstrap commands cannot be nested")
+		}
+		if err, ok := fsm.applyCommand(&sub, s).(error); ok && err != nil {
+			fsm.data = prev
+			return err
+		}
+	}
+
+	other := fsm.data.Clone()
+	other.Bootstrapped = true
+	fsm.data = other
+	return nil
+}
+
+func (fsm *storeFSM) applySetTopologyFrozenCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_SetTopologyFrozenCommand_Command)
+	v := ext.(*internal.SetTopologyFrozenCommand)
+
+	other := fsm.data.Clone()
+	other.TopologyFrozen = v.GetFrozen()
+
+	fsm.data = other
+	return nil
+}
+
 //TODO finish these functions
 // func (fsm *storeFSM) applyUpdateDataNode(cmd *internal.Command) (interface{})            {}
 // func (fsm *storeFSM) applyCreateDatabase(cmd *internal.Command) interface{} {}
@@ -542,19 +843,32 @@ func (fsm *storeFSM) Snapshot() (raft.FSMSnapshot, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	return &storeFSMSnapshot{Data: (*store)(fsm).data}, nil
+	return &storeFSMSnapshot{Data: (*store)(fsm).data, tasks: s.tasks}, nil
 }
 
+// Restore replaces the metadata with the snapshot r, listed as the task
+// raft-snapshot-restore while it runs. It can't be canceled: raft can't go
+// on without the metadata.
 func (fsm *storeFSM) Restore(r io.ReadCloser) error {
+	if fsm.tasks == nil {
+		return fsm.restore(r)
+	}
+	var err error
+	fsm.tasks.run("raft-snapshot-restore", func(*Task) { err = fsm.restore(r) })
+	return err
+}
+
+func (fsm *storeFSM) restore(r io.ReadCloser) error {
 	// Read all bytes.
 	b, err := ioutil.ReadAll(r)
 	if err != nil {
 		return err
 	}
 
-	// Decode metadata.
-	data := &Data{}
-	if err := data.UnmarshalBinary(b); err != nil {
+	// Decode metadata. Returning an error makes raft fall back to an
+	// earlier snapshot.
+	data, err := decodeSnapshot(b, fsm.config.VerifySnapshots)
+	if err != nil {
 		return err
 	}
 
@@ -568,18 +882,34 @@ func (fsm *storeFSM) Restore(r io.ReadCloser) error {
 
 type storeFSMSnapshot struct {
 	Data *Data
+
+	// tasks, if set, lists the snapshot as the task raft-snapshot while it
+	// is persisted. Canceling it fails the snapshot, which raft retries.
+	tasks *taskRegistry
 }
 
 func (s *storeFSMSnapshot) Persist(sink raft.SnapshotSink) error {
+	if s.tasks == nil {
+		return s.persist(context.Background(), sink)
+	}
+	var err error
+	s.tasks.run("raft-snapshot", func(t *Task) { err = s.persist(t.Context(), sink) })
+	return err
+}
+
+func (s *storeFSMSnapshot) persist(ctx context.Context, sink raft.SnapshotSink) error {
 	err := func() error {
 		// Encode data.
 		p, err := s.Data.MarshalBinary()
 		if err != nil {
 			return err
 		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
-		// Write data to sink.
-		if _, err := sink.Write(p); err != nil {
+		// Write data to sink, along with its checksum.
+		if _, err := sink.Write(encodeSnapshot(p)); err != nil {
 			return err
 		}
 
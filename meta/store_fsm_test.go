@@ -0,0 +1,525 @@
+package meta_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/toml"
+	"github.com/zhexuany/influxcloud"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// Ensure clients racing to create the same database create it once and all
+// get the result the duplicate-database policy calls for.
+func TestMetaService_CreateDatabaseRace(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	s := openService(t, cfg)
+	defer s.Close()
+
+	for _, policy := range []string{cloudMeta.DuplicateDatabaseIgnore, cloudMeta.DuplicateDatabaseError} {
+		const n = 20
+		clients := make([]*cloudMeta.Client, n)
+		for i := range clients {
+			cfg := newConfig()
+			defer os.RemoveAll(cfg.Dir)
+			cfg.DuplicateDatabase = policy
+			c := cloudMeta.NewClient(cfg)
+			c.SetMetaServers([]string{s.HTTPAddr()})
+			if err := c.Open(); err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			clients[i] = c
+		}
+
+		name := "db_" + policy
+		dbs := make([]*meta.DatabaseInfo, n)
+		errs := make([]error, n)
+		var wg sync.WaitGroup
+		for i, c := range clients {
+			wg.Add(1)
+			go func(i int, c *cloudMeta.Client) {
+				defer wg.Done()
+				dbs[i], errs[i] = c.CreateDatabase(name)
+			}(i, c)
+		}
+		wg.Wait()
+
+		var created int
+		for i, err := range errs {
+			if err == nil {
+				if dbs[i] == nil || dbs[i].Name != name || dbs[i].DefaultRetentionPolicy != "default" {
+					t.Fatalf("%s: unexpected database: %+v", policy, dbs[i])
+				}
+				created++
+			} else if policy != cloudMeta.DuplicateDatabaseError || err != cloudMeta.ErrDatabaseExists {
+				t.Fatalf("%s: unexpected error: %s", policy, err)
+			}
+		}
+		if policy == cloudMeta.DuplicateDatabaseIgnore && created != n {
+			t.Fatalf("%s: %d of %d creates succeeded", policy, created, n)
+		} else if policy == cloudMeta.DuplicateDatabaseError && created != 1 {
+			t.Fatalf("%s: %d creates succeeded, expected 1", policy, created)
+		}
+
+		databases, err := clients[0].Databases()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var found int
+		for _, db := range databases {
+			if db.Name == name {
+				found++
+			}
+		}
+		if found != 1 {
+			t.Fatalf("%s: %d databases named %s", policy, found, name)
+		}
+	}
+}
+
+func TestMetaService_CreateWithoutIfNotExists(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	if _, err := c.CreateDatabaseWithOptions("db0", cloudMeta.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreateDatabaseWithOptions("db0", cloudMeta.CreateOptions{}); err == nil || err.Error() != cloudMeta.ErrDatabaseExists.Error() {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.CreateDatabaseWithOptions("db0", cloudMeta.CreateOptions{IfNotExists: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	spec := &meta.RetentionPolicySpec{Name: "rp0"}
+	if _, err := c.CreateRetentionPolicyWithOptions("db0", spec, cloudMeta.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreateRetentionPolicyWithOptions("db0", spec, cloudMeta.CreateOptions{}); err == nil || err.Error() != cloudMeta.ErrRetentionPolicyExists.Error() {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rp, err := c.CreateRetentionPolicyWithOptions("db0", spec, cloudMeta.CreateOptions{IfNotExists: true}); err != nil {
+		t.Fatal(err)
+	} else if rp.Name != "rp0" {
+		t.Fatalf("rp name wrong: %s", rp.Name)
+	}
+}
+
+// Ensure raising a retention policy's replication factor gives its existing
+// shards a pending replica on another data node, which owns the shard once
+// committed.
+func TestMetaService_AlterRetentionPolicyReplicaN(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	for _, addr := range []string{"foo:8180", "foo:8280", "foo:8380"} {
+		if _, err := c.CreateDataNode(addr, addr+"1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rp := meta.NewRetentionPolicyInfo("rp0")
+	rp.ReplicaN = 1
+	if _, err := c.CreateDatabaseWithRetentionPolicy("db0", rpi2rps(rp)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreateShardGroup("db0", "rp0", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.AlterRetentionPolicyReplicaN("db0", "rp0", 2); err != nil {
+		t.Fatal(err)
+	}
+	rp, err := c.RetentionPolicy("db0", "rp0")
+	if err != nil {
+		t.Fatal(err)
+	} else if rp.ReplicaN != 2 {
+		t.Fatalf("unexpected replicaN: %d", rp.ReplicaN)
+	}
+
+	// The new replicas serve nothing until their data node copied the shard.
+	data := c.Data()
+	for _, si := range rp.ShardGroups[0].Shards {
+		if len(si.Owners) != 1 {
+			t.Fatalf("unexpected owners of shard %d: %v", si.ID, si.Owners)
+		}
+		var pending []uint64
+		for _, n := range data.DataNodes {
+			for _, id := range n.PendingShardOwners {
+				if id == si.ID {
+					pending = append(pending, n.ID)
+				}
+			}
+		}
+		if len(pending) != 1 || si.OwnedBy(pending[0]) {
+			t.Fatalf("unexpected pending owners of shard %d: %v", si.ID, pending)
+		}
+		if err := c.CommitPendingShardOwner(si.ID, pending[0]); err != nil {
+			t.Fatal(err)
+		} else if err := c.CommitPendingShardOwner(si.ID, pending[0]); err == nil || err.Error() != cloudMeta.ErrShardOwnerNotPending.Error() {
+			t.Fatalf("unexpected error committing again: %v", err)
+		}
+	}
+
+	rp, err = c.RetentionPolicy("db0", "rp0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, si := range rp.ShardGroups[0].Shards {
+		if len(si.Owners) != 2 || si.Owners[0].NodeID == si.Owners[1].NodeID {
+			t.Fatalf("unexpected owners of shard %d: %v", si.ID, si.Owners)
+		}
+	}
+	for _, n := range c.Data().DataNodes {
+		if len(n.PendingShardOwners) != 0 {
+			t.Fatalf("data node %d still pending: %v", n.ID, n.PendingShardOwners)
+		}
+	}
+
+	if err := c.AlterRetentionPolicyReplicaN("db0", "rp0", 0); err != cloudMeta.ErrReplicationFactorTooLow {
+		t.Fatalf("got %v, expected %v", err, cloudMeta.ErrReplicationFactorTooLow)
+	}
+}
+
+func TestMetaService_FreezeTopology(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	n1, err := c.CreateDataNode("foo:8180", "bar:8181")
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := c.CreateDataNode("foo:8280", "bar:8281"); err != nil {
+		t.Fatal(err)
+	}
+	rp := meta.NewRetentionPolicyInfo("rp0")
+	rp.ReplicaN = 1
+	if _, err := c.CreateDatabaseWithRetentionPolicy("db0", rpi2rps(rp)); err != nil {
+		t.Fatal(err)
+	} else if _, err := c.CreateShardGroup("db0", "rp0", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetTopologyFrozen(true); err != nil {
+		t.Fatal(err)
+	} else if !c.TopologyFrozen() {
+		t.Fatal("expected topology to be frozen")
+	}
+
+	// Membership changes must be rejected.
+	if _, err := c.CreateMetaNode("baz:8091", "baz:8088"); err == nil || err.Error() != cloudMeta.ErrTopologyFrozen.Error() {
+		t.Fatalf("got %v, expected %v", err, cloudMeta.ErrTopologyFrozen)
+	}
+	if err := c.DeleteDataNode(n1.ID); err == nil || err.Error() != cloudMeta.ErrTopologyFrozen.Error() {
+		t.Fatalf("got %v, expected %v", err, cloudMeta.ErrTopologyFrozen)
+	}
+
+	// So must a rebalance of the shards onto other data nodes.
+	if err := c.AlterRetentionPolicyReplicaN("db0", "rp0", 2); err == nil || err.Error() != cloudMeta.ErrTopologyFrozen.Error() {
+		t.Fatalf("got %v, expected %v", err, cloudMeta.ErrTopologyFrozen)
+	}
+	if rp, err := c.RetentionPolicy("db0", "rp0"); err != nil {
+		t.Fatal(err)
+	} else if rp.ReplicaN != 1 {
+		t.Fatalf("unexpected replicaN: %d", rp.ReplicaN)
+	}
+	for _, n := range c.Data().DataNodes {
+		if len(n.PendingShardOwners) != 0 {
+			t.Fatalf("shards rebalanced onto data node %d: %v", n.ID, n.PendingShardOwners)
+		}
+	}
+
+	// Schema changes are still allowed.
+	if _, err := c.CreateDatabase("db1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.SetTopologyFrozen(false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreateDataNode("foo:8380", "bar:8381"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Ensure database annotations are stored, served with the database
+// definitions and limited in size.
+func TestMetaService_DatabaseAnnotations(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	if _, err := c.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+	exp := map[string]string{"owner": "storage-team", "environment": "prod", "cost-center": "cc-42"}
+	for k, v := range exp {
+		if err := c.SetDatabaseAnnotation("db0", k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := c.DatabaseAnnotations("db0"); !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected annotations: %v", got)
+	}
+
+	resp, err := http.Get("http://" + s.HTTPAddr() + "/databases")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var dbs []struct {
+		Name        string            `json:"name"`
+		Annotations map[string]string `json:"annotations"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&dbs); err != nil {
+		t.Fatal(err)
+	} else if len(dbs) != 1 || !reflect.DeepEqual(dbs[0].Annotations, exp) {
+		t.Fatalf("unexpected databases: %+v", dbs)
+	}
+
+	// Values past the size limit are refused and leave the annotation alone.
+	big := strings.Repeat("x", cloudMeta.MaxAnnotationValueSize+1)
+	if err := c.SetDatabaseAnnotation("db0", "owner", big); err == nil || err.Error() != cloudMeta.ErrAnnotationTooLarge.Error() {
+		t.Fatalf("got %v, expected %v", err, cloudMeta.ErrAnnotationTooLarge)
+	} else if got := c.DatabaseAnnotations("db0")["owner"]; got != "storage-team" {
+		t.Fatalf("unexpected owner: %q", got)
+	}
+
+	// An empty value removes an annotation.
+	if err := c.SetDatabaseAnnotation("db0", "environment", ""); err != nil {
+		t.Fatal(err)
+	} else if _, ok := c.DatabaseAnnotations("db0")["environment"]; ok {
+		t.Fatal("annotation not removed")
+	}
+
+	if err := c.SetDatabaseAnnotation("db1", "owner", "nobody"); err == nil {
+		t.Fatal("expected an error annotating a missing database")
+	}
+}
+
+// Ensure draining a database blocks writes to it in the metadata and lets
+// it be dropped once writes settled.
+func TestMetaService_DrainDatabase(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	cfg.DrainSettleTime = toml.Duration(200 * time.Millisecond)
+	c2 := cloudMeta.NewClient(cfg)
+	c2.SetMetaServers([]string{s.HTTPAddr()})
+	if err := c2.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer c2.Close()
+
+	for _, name := range []string{"db0", "db1"} {
+		if _, err := c2.CreateDatabase(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	start := time.Now()
+	if err := c2.DrainDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if time.Since(start) < time.Duration(cfg.DrainSettleTime) {
+		t.Fatal("drain returned before writes settled")
+	}
+	if !c2.DatabaseWriteBlocked("db0") {
+		t.Fatal("drained database not write blocked")
+	} else if c2.DatabaseWriteBlocked("db1") {
+		t.Fatal("other database write blocked")
+	}
+
+	// Every client sees the block, and it survives a snapshot.
+	buf, err := c.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := &cloudMeta.Data{}
+	if err := data.UnmarshalBinary(buf); err != nil {
+		t.Fatal(err)
+	} else if _, ok := data.WriteBlockedDatabases["db0"]; !ok {
+		t.Fatal("write block lost in snapshot")
+	}
+
+	if err := c2.DropDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if db, _ := c2.Database("db0"); db != nil {
+		t.Fatal("drained database not dropped")
+	} else if c2.DatabaseWriteBlocked("db0") {
+		t.Fatal("write block outlived the database")
+	}
+
+	if err := c2.DrainDatabase("db2"); err == nil || err.Error() != influxcloud.ErrDatabaseNotFound("db2").Error() {
+		t.Fatalf("unexpected error draining a missing database: %v", err)
+	}
+
+	if err := c2.DrainDatabase("db1"); err != nil {
+		t.Fatal(err)
+	} else if err := c2.ResumeDatabaseWrites("db1"); err != nil {
+		t.Fatal(err)
+	} else if c2.DatabaseWriteBlocked("db1") {
+		t.Fatal("database still write blocked after resuming writes")
+	}
+}
+
+// Ensure only one holder has the restart lock at a time, and that it can be
+// taken over once it expires.
+func TestMetaService_RestartLock(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	ttl := 500 * time.Millisecond
+	if err := c.AcquireRestartLock("orchestrator-a", 1, ttl); err != nil {
+		t.Fatal(err)
+	} else if l := c.RestartLock(); l == nil || l.Holder != "orchestrator-a" || l.NodeID != 1 {
+		t.Fatalf("unexpected restart lock: %+v", l)
+	}
+
+	if err := c.AcquireRestartLock("orchestrator-b", 2, ttl); err == nil || err.Error() != cloudMeta.ErrRestartLockHeld.Error() {
+		t.Fatalf("got %v, expected %v", err, cloudMeta.ErrRestartLockHeld)
+	} else if err := c.ReleaseRestartLock("orchestrator-b"); err == nil || err.Error() != cloudMeta.ErrRestartLockNotHeld.Error() {
+		t.Fatalf("got %v, expected %v", err, cloudMeta.ErrRestartLockNotHeld)
+	}
+
+	// The first holder died without releasing the lock.
+	time.Sleep(ttl)
+	if err := c.AcquireRestartLock("orchestrator-b", 2, ttl); err != nil {
+		t.Fatal(err)
+	} else if l := c.RestartLock(); l == nil || l.Holder != "orchestrator-b" || l.NodeID != 2 {
+		t.Fatalf("unexpected restart lock: %+v", l)
+	}
+
+	if err := c.ReleaseRestartLock("orchestrator-b"); err != nil {
+		t.Fatal(err)
+	} else if l := c.RestartLock(); l != nil {
+		t.Fatalf("restart lock not released: %+v", l)
+	}
+	if err := c.AcquireRestartLock("orchestrator-a", 3, ttl); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Ensure a database's shard group quota rejects shard groups past it, for
+// that database only, and the near quota hook is called on the way.
+func TestMetaService_ShardGroupQuota(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	s := newService(cfg)
+	type near struct {
+		database  string
+		used, max uint64
+	}
+	nears := make(chan near, 10)
+	s.OnShardGroupQuotaNear(func(database string, used, max uint64) {
+		nears <- near{database, used, max}
+	})
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	c := newClient(s)
+	defer c.Close()
+
+	if _, err := c.CreateDataNode("foo:8180", "bar:8281"); err != nil {
+		t.Fatal(err)
+	}
+	day := 24 * time.Hour
+	for _, db := range []string{"db0", "db1"} {
+		if _, err := c.CreateDatabaseWithRetentionPolicy(db, &meta.RetentionPolicySpec{
+			Name:               "rp0",
+			ShardGroupDuration: day,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const quota = 2
+	if err := c.SetShardGroupQuota("db0", quota); err != nil {
+		t.Fatal(err)
+	} else if got := c.ShardGroupQuota("db0"); got != quota {
+		t.Fatalf("unexpected quota: %d", got)
+	} else if got := c.ShardGroupQuota("db1"); got != 0 {
+		t.Fatalf("unexpected quota for db1: %d", got)
+	}
+
+	start := time.Now().Add(-10 * day)
+	for i := 0; i < quota; i++ {
+		if _, err := c.CreateShardGroup("db0", "rp0", start.Add(time.Duration(i)*day)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.CreateShardGroup("db0", "rp0", start.Add(quota*day)); err == nil || err.Error() != cloudMeta.ErrShardGroupQuotaExceeded.Error() {
+		t.Fatalf("got %v, expected %v", err, cloudMeta.ErrShardGroupQuotaExceeded)
+	}
+
+	// Other databases aren't limited.
+	for i := 0; i <= quota; i++ {
+		if _, err := c.CreateShardGroup("db1", "rp0", start.Add(time.Duration(i)*day)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case n := <-nears:
+		if n != (near{"db0", quota, quota}) {
+			t.Fatalf("unexpected near quota call: %+v", n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("near quota hook not called")
+	}
+
+	// Removing the quota lifts the limit.
+	if err := c.SetShardGroupQuota("db0", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreateShardGroup("db0", "rp0", start.Add(quota*day)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Quotas are only set on databases that exist, and dropped with them.
+	if err := c.SetShardGroupQuota("db2", quota); err == nil || err.Error() != influxcloud.ErrDatabaseNotFound("db2").Error() {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := c.SetShardGroupQuota("db1", quota); err != nil {
+		t.Fatal(err)
+	} else if err := c.DropDatabase("db1"); err != nil {
+		t.Fatal(err)
+	} else if got := c.ShardGroupQuota("db1"); got != 0 {
+		t.Fatalf("unexpected quota for dropped db1: %d", got)
+	}
+}
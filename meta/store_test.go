@@ -0,0 +1,292 @@
+package meta_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// Ensure the bootstrap file is applied on first start only.
+func TestMetaService_BootstrapFile(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	cfg.BootstrapFile = filepath.Join(cfg.Dir, "bootstrap.toml")
+	if err := ioutil.WriteFile(cfg.BootstrapFile, []byte(`
+[[data-nodes]]
+  host = "data0:8086"
+  tcp-host = "data0:8088"
+
+[[data-nodes]]
+  host = "data1:8086"
+  tcp-host = "data1:8088"
+
+[[databases]]
+  name = "db0"
+  [[databases.retention-policies]]
+    name = "rp0"
+    duration = "168h"
+    replication = 2
+  [[databases.retention-policies]]
+    name = "rp1"
+    duration = "0s"
+`), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	s := openService(t, cfg)
+	c := newClient(s)
+
+	if nodes, err := c.DataNodes(); err != nil {
+		t.Fatal(err)
+	} else if len(nodes) != 2 || nodes[0].TCPHost != "data0:8088" || nodes[1].TCPHost != "data1:8088" {
+		t.Fatalf("unexpected data nodes: %v", nodes)
+	}
+
+	db, err := c.Database("db0")
+	if err != nil {
+		t.Fatal(err)
+	} else if db.DefaultRetentionPolicy != "rp0" {
+		t.Fatalf("unexpected default retention policy: %s", db.DefaultRetentionPolicy)
+	}
+	if rp := db.RetentionPolicy("rp0"); rp == nil || rp.Duration != 168*time.Hour || rp.ReplicaN != 2 {
+		t.Fatalf("unexpected retention policy rp0: %+v", rp)
+	}
+	if rp := db.RetentionPolicy("rp1"); rp == nil {
+		t.Fatal("expected retention policy rp1")
+	}
+
+	// Drop what was bootstrapped so a re-apply would be noticed.
+	if err := c.DropDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s = openService(t, cfg)
+	defer s.Close()
+	c = newClient(s)
+	defer c.Close()
+
+	if db, _ := c.Database("db0"); db != nil {
+		t.Fatal("bootstrap file applied on restart")
+	}
+}
+
+// Ensure the raft log and snapshots are kept in raft-dir, apart from the
+// meta dir.
+func TestMetaService_RaftDir(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	cfg.RaftDir = filepath.Join(testTempDir(1), "raft")
+	defer os.RemoveAll(cfg.Dir)
+	defer os.RemoveAll(filepath.Dir(cfg.RaftDir))
+
+	s := openService(t, cfg)
+	defer s.Close()
+	c := newClient(s)
+	defer c.Close()
+
+	if _, err := c.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+
+	if fi, err := os.Stat(cfg.RaftDir); err != nil {
+		t.Fatal(err)
+	} else if perm := fi.Mode().Perm(); perm != cloudMeta.DirMode {
+		t.Fatalf("unexpected raft dir mode: %s", perm)
+	}
+	for _, name := range []string{"raft.db", "snapshots"} {
+		if _, err := os.Stat(filepath.Join(cfg.RaftDir, name)); err != nil {
+			t.Fatalf("%s not in raft dir: %s", name, err)
+		}
+		if _, err := os.Stat(filepath.Join(cfg.Dir, name)); !os.IsNotExist(err) {
+			t.Fatalf("%s written to meta dir", name)
+		}
+	}
+}
+
+// Ensure WaitForApplied returns once the index is applied locally, and the
+// apply lag shows up in /raft-status.
+func TestMetaService_WaitForApplied(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	resp, err := http.Get("http://" + s.HTTPAddr() + "/raft-status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var st struct {
+		AppliedIndex uint64  `json:"appliedIndex"`
+		ApplyLag     *uint64 `json:"applyLag"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&st)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	} else if st.ApplyLag == nil {
+		t.Fatal("apply lag not reported")
+	}
+
+	// Applied entries don't block.
+	if err := s.WaitForApplied(st.AppliedIndex, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	// The next entry blocks until a write applies it.
+	next := st.AppliedIndex + 1
+	if err := s.WaitForApplied(next, 50*time.Millisecond); err != cloudMeta.ErrApplyTimeout {
+		t.Fatalf("unexpected error: got %v, expected %v", err, cloudMeta.ErrApplyTimeout)
+	}
+	done := make(chan error, 1)
+	go func() { done <- s.WaitForApplied(next, 5*time.Second) }()
+	select {
+	case err := <-done:
+		t.Fatalf("returned before the index was applied: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if _, err := c.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the index to be applied")
+	}
+}
+
+// Ensure a membership change is refused while another one is still being
+// applied, and that the pending change shows up in /raft-status for as long
+// as it is uncommitted.
+func TestMetaService_ConcurrentConfigChange(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	join := func(tcpHost string) (int, string) {
+		body := fmt.Sprintf(`{"Host":"127.0.0.1:0","TCPHost":%q}`, tcpHost)
+		resp, err := http.Post("http://"+s.HTTPAddr()+"/join", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Error(err)
+			return 0, ""
+		}
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(b)
+	}
+
+	status := func() (pending int, change string) {
+		resp, err := http.Get("http://" + s.HTTPAddr() + "/raft-status")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		var st struct {
+			PendingConfigChanges int    `json:"pendingConfigChanges"`
+			ConfigChange         string `json:"configChange"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&st); err != nil {
+			t.Fatal(err)
+		}
+		return st.PendingConfigChanges, st.ConfigChange
+	}
+
+	if n, _ := status(); n != 0 {
+		t.Fatalf("unexpected pending config changes: %d", n)
+	}
+
+	// Nothing listens on the first peer, so the change can't commit until
+	// the leader gives up on it.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		join("127.0.0.1:1")
+	}()
+
+	timeout := time.Now().Add(5 * time.Second)
+	for {
+		n, change := status()
+		if n == 1 {
+			if change != "add 127.0.0.1:1" {
+				t.Fatalf("unexpected config change: %q", change)
+			}
+			break
+		}
+		if time.Now().After(timeout) {
+			t.Fatal("first config change never reported as pending")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	code, body := join("127.0.0.1:2")
+	if code != http.StatusConflict {
+		t.Fatalf("unexpected status for concurrent change: %d %s", code, body)
+	}
+	if !strings.Contains(body, cloudMeta.ErrConfigChangeInProgress.Error()) {
+		t.Fatalf("unexpected error for concurrent change: %s", body)
+	}
+
+	// The leader gave up on the change, but it is still in its log waiting
+	// on a quorum it can't get.
+	<-done
+	if n, change := status(); change != "" {
+		t.Fatalf("config change still being applied after it finished: %q", change)
+	} else if n != 1 {
+		t.Fatalf("uncommitted config change not reported: %d", n)
+	}
+}
+
+// Ensure a command past max-raft-message-size is rejected with an error
+// naming the limit, and isn't applied.
+func TestMetaService_MaxRaftMessageSize(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	cfg.MaxRaftMessageSize = 4096
+	s := openService(t, cfg)
+	defer s.Close()
+	c := newClient(s)
+	defer c.Close()
+
+	if _, err := c.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+
+	data := c.Data()
+	for i := 0; i < 1000; i++ {
+		if err := data.CreateDatabase(fmt.Sprintf("database%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.SetData(data); err == nil || !strings.Contains(err.Error(), "exceeds max-raft-message-size of 4096 bytes") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := len(c.Data().Databases); n != 1 {
+		t.Fatalf("oversized command applied: %d databases", n)
+	}
+}
@@ -0,0 +1,206 @@
+package meta
+
+import (
+	"reflect"
+	"sync"
+	"time"
+)
+
+// subscriptionBufferSize is how many changes a subscriber can fall behind
+// before the oldest are dropped.
+const subscriptionBufferSize = 16
+
+// MetaChangeKind is what changed in the metadata.
+type MetaChangeKind string
+
+const (
+	// MetaChangeDatabases is a database created or dropped.
+	MetaChangeDatabases MetaChangeKind = "databases"
+
+	// MetaChangeRetentionPolicies is a retention policy created, altered
+	// or dropped, or a database's default changed.
+	MetaChangeRetentionPolicies MetaChangeKind = "retention-policies"
+
+	// MetaChangeNodes is a meta or data node added, dropped or moved to
+	// another address.
+	MetaChangeNodes MetaChangeKind = "nodes"
+
+	// MetaChangeOther is any other change, such as a shard group or user
+	// change.
+	MetaChangeOther MetaChangeKind = "other"
+)
+
+// MetaChange is a change of the metadata seen by a Client.
+type MetaChange struct {
+	Kind MetaChangeKind
+
+	// Index is the version of the metadata the change is part of.
+	Index uint64
+}
+
+// subscription is a channel returned by Client.Subscribe.
+type subscription struct {
+	ch   chan MetaChange
+	once sync.Once
+}
+
+func (s *subscription) close() {
+	s.once.Do(func() { close(s.ch) })
+}
+
+// Subscribe returns a channel receiving the changes of the metadata, as the
+// client sees a newer version of it, and a func ending the subscription.
+// Several changes seen in one update are each sent with the same index. The
+// client never waits on a subscriber: once one is subscriptionBufferSize
+// changes behind, the oldest are dropped with a warning, so it should
+// refetch what it needs rather than rely on every change being delivered.
+// The channel is closed by the cancel func or when the client is closed.
+func (c *Client) Subscribe() (<-chan MetaChange, func()) {
+	sub := &subscription{ch: make(chan MetaChange, subscriptionBufferSize)}
+
+	c.mu.Lock()
+	if c.closed() {
+		c.mu.Unlock()
+		sub.close()
+		return sub.ch, func() {}
+	}
+	if c.subscriptions == nil {
+		c.subscriptions = make(map[*subscription]struct{})
+	}
+	c.subscriptions[sub] = struct{}{}
+	c.mu.Unlock()
+
+	return sub.ch, func() {
+		c.mu.Lock()
+		delete(c.subscriptions, sub)
+		c.mu.Unlock()
+		sub.close()
+	}
+}
+
+// cacheNewer caches data, newer than the cached data, and notifies the
+// waiters on WaitForDataChanged and the subscribers of the change. c.mu
+// must be held.
+func (c *Client) cacheNewer(data *Data) {
+	prev := c.cacheData
+	c.cacheData = data
+	close(c.changed)
+	c.changed = make(chan struct{})
+
+	if len(c.subscriptions) == 0 {
+		return
+	}
+	for _, kind := range dataChanges(prev, data) {
+		change := MetaChange{Kind: kind, Index: data.Data.Index}
+		for sub := range c.subscriptions {
+			c.notify(sub, change)
+		}
+	}
+}
+
+// notify sends change to sub, dropping the oldest change it hasn't received
+// if it is full.
+func (c *Client) notify(sub *subscription, change MetaChange) {
+	for {
+		select {
+		case sub.ch <- change:
+			return
+		default:
+		}
+		select {
+		case dropped := <-sub.ch:
+			c.logger.Printf("subscriber is %d changes behind, dropped change %s at index %d", subscriptionBufferSize, dropped.Kind, dropped.Index)
+		default:
+		}
+	}
+}
+
+// closeSubscriptions closes the channel of every subscriber. c.mu must be
+// held.
+func (c *Client) closeSubscriptions() {
+	for sub := range c.subscriptions {
+		sub.close()
+	}
+	c.subscriptions = nil
+}
+
+// dataChanges returns the kinds of changes from prev to data.
+func dataChanges(prev, data *Data) []MetaChangeKind {
+	var kinds []MetaChangeKind
+	prevDBs, dbs := databaseRetentionPolicies(prev), databaseRetentionPolicies(data)
+	if !sameKeys(prevDBs, dbs) {
+		kinds = append(kinds, MetaChangeDatabases)
+	}
+	for name, rps := range dbs {
+		if prevRPs, ok := prevDBs[name]; ok && !reflect.DeepEqual(prevRPs, rps) {
+			kinds = append(kinds, MetaChangeRetentionPolicies)
+			break
+		}
+	}
+	if !reflect.DeepEqual(nodeAddrs(prev.MetaNodes), nodeAddrs(data.MetaNodes)) ||
+		!reflect.DeepEqual(nodeAddrs(prev.DataNodes), nodeAddrs(data.DataNodes)) {
+		kinds = append(kinds, MetaChangeNodes)
+	}
+	if len(kinds) == 0 {
+		kinds = append(kinds, MetaChangeOther)
+	}
+	return kinds
+}
+
+// retentionPolicySpec is a retention policy without its shard groups and
+// subscriptions.
+type retentionPolicySpec struct {
+	ReplicaN           int
+	Duration           time.Duration
+	ShardGroupDuration time.Duration
+	Default            bool
+}
+
+// databaseRetentionPolicies returns the retention policies of every
+// database of data, by name.
+func databaseRetentionPolicies(data *Data) map[string]map[string]retentionPolicySpec {
+	dbs := make(map[string]map[string]retentionPolicySpec)
+	if data == nil || data.Data == nil {
+		return dbs
+	}
+	for _, db := range data.Databases {
+		rps := make(map[string]retentionPolicySpec, len(db.RetentionPolicies))
+		for _, rp := range db.RetentionPolicies {
+			rps[rp.Name] = retentionPolicySpec{
+				ReplicaN:           rp.ReplicaN,
+				Duration:           rp.Duration,
+				ShardGroupDuration: rp.ShardGroupDuration,
+				Default:            rp.Name == db.DefaultRetentionPolicy,
+			}
+		}
+		dbs[db.Name] = rps
+	}
+	return dbs
+}
+
+func sameKeys(a, b map[string]map[string]retentionPolicySpec) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// nodeAddr is a node without what changes while its membership doesn't.
+type nodeAddr struct {
+	ID      uint64
+	Host    string
+	TCPHost string
+}
+
+func nodeAddrs(nodes NodeInfos) []nodeAddr {
+	addrs := make([]nodeAddr, 0, len(nodes))
+	for _, n := range nodes {
+		addrs = append(addrs, nodeAddr{ID: n.ID, Host: n.Host, TCPHost: n.TCPHost})
+	}
+	return addrs
+}
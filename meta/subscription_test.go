@@ -0,0 +1,85 @@
+package meta_test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// Ensure a subscriber is sent the kind and index of each change, and its
+// channel is closed once it cancels.
+func TestMetaClient_Subscribe(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	changes, cancel := c.Subscribe()
+	next := func() cloudMeta.MetaChange {
+		select {
+		case change := <-changes:
+			return change
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a change")
+		}
+		return cloudMeta.MetaChange{}
+	}
+
+	if _, err := c.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+	change := next()
+	if change.Kind != cloudMeta.MetaChangeDatabases || change.Index == 0 {
+		t.Fatalf("unexpected change: %+v", change)
+	}
+
+	duration := time.Hour
+	if _, err := c.CreateRetentionPolicy("db0", &meta.RetentionPolicySpec{Name: "rp0", Duration: &duration}); err != nil {
+		t.Fatal(err)
+	}
+	if next := next(); next.Kind != cloudMeta.MetaChangeRetentionPolicies || next.Index <= change.Index {
+		t.Fatalf("unexpected change after %+v: %+v", change, next)
+	}
+
+	cancel()
+	cancel()
+	for range changes {
+	}
+}
+
+// Ensure a subscriber that doesn't keep up loses the oldest changes rather
+// than blocking the client, and its channel is closed with the client.
+func TestMetaClient_Subscribe_SlowConsumer(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+
+	changes, _ := c.Subscribe()
+	for i := 0; i < 20; i++ {
+		if _, err := c.CreateDatabase(fmt.Sprintf("db%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	index := c.Data().Index
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+	var last cloudMeta.MetaChange
+	for change := range changes {
+		n++
+		last = change
+	}
+	if n > 16 || last.Index != index {
+		t.Fatalf("unexpected changes: %d, last %+v, expected index %d", n, last, index)
+	}
+}
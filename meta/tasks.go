@@ -0,0 +1,147 @@
+package meta
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TaskInfo describes a background task running on this node, as listed on
+// /admin/tasks.
+type TaskInfo struct {
+	ID        uint64    `json:"id"`
+	Name      string    `json:"name"`
+	StartedAt time.Time `json:"startedAt"`
+
+	// Done and Total are the progress the task last reported, in whatever
+	// unit it counts, such as shards. Total is zero when unknown.
+	Done  int64 `json:"done"`
+	Total int64 `json:"total"`
+
+	// Canceled is set once the task is asked to stop.
+	Canceled bool `json:"canceled"`
+}
+
+// Task is handed to a background task started with StartTask, to report its
+// progress and learn of its cancellation.
+type Task struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu   sync.Mutex
+	info TaskInfo
+}
+
+// Context returns a context that is canceled when the task is canceled or
+// the service closes.
+func (t *Task) Context() context.Context { return t.ctx }
+
+// SetProgress records that done out of total units of work are complete.
+func (t *Task) SetProgress(done, total int64) {
+	t.mu.Lock()
+	t.info.Done, t.info.Total = done, total
+	t.mu.Unlock()
+}
+
+func (t *Task) snapshot() TaskInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.info
+}
+
+// taskRegistry tracks the background tasks running on this node until they
+// return.
+type taskRegistry struct {
+	mu     sync.Mutex
+	nextID uint64
+	tasks  map[uint64]*Task
+	wg     sync.WaitGroup
+}
+
+func newTaskRegistry() *taskRegistry {
+	return &taskRegistry{tasks: make(map[uint64]*Task)}
+}
+
+// start runs fn in the background as the task name and returns its ID.
+func (r *taskRegistry) start(name string, fn func(t *Task)) uint64 {
+	t := r.add(name)
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer r.remove(t)
+		fn(t)
+	}()
+	return t.info.ID
+}
+
+// run runs fn as the task name in the calling goroutine, listed until it
+// returns.
+func (r *taskRegistry) run(name string, fn func(t *Task)) {
+	t := r.add(name)
+	defer r.remove(t)
+	fn(t)
+}
+
+// add lists a new task named name.
+func (r *taskRegistry) add(name string) *Task {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	t := &Task{
+		ctx:    ctx,
+		cancel: cancel,
+		info:   TaskInfo{ID: r.nextID, Name: name, StartedAt: now()},
+	}
+	r.tasks[t.info.ID] = t
+	return t
+}
+
+// remove unlists t once it returned.
+func (r *taskRegistry) remove(t *Task) {
+	r.mu.Lock()
+	delete(r.tasks, t.info.ID)
+	r.mu.Unlock()
+	t.cancel()
+}
+
+// list returns the running tasks, ordered by ID.
+func (r *taskRegistry) list() []TaskInfo {
+	r.mu.Lock()
+	a := make([]TaskInfo, 0, len(r.tasks))
+	for _, t := range r.tasks {
+		a = append(a, t.snapshot())
+	}
+	r.mu.Unlock()
+	sort.Slice(a, func(i, j int) bool { return a[i].ID < a[j].ID })
+	return a
+}
+
+// cancel asks the task id to stop. It returns ErrTaskNotFound if no such
+// task is running.
+func (r *taskRegistry) cancel(id uint64) error {
+	r.mu.Lock()
+	t := r.tasks[id]
+	r.mu.Unlock()
+	if t == nil {
+		return ErrTaskNotFound
+	}
+
+	t.mu.Lock()
+	t.info.Canceled = true
+	t.mu.Unlock()
+	t.cancel()
+	return nil
+}
+
+// close cancels every task and waits for the ones started in the
+// background to return.
+func (r *taskRegistry) close() {
+	r.mu.Lock()
+	for _, t := range r.tasks {
+		t.cancel()
+	}
+	r.mu.Unlock()
+	r.wg.Wait()
+}
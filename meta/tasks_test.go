@@ -0,0 +1,77 @@
+package meta_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// Ensure a running background task is listed on /admin/tasks with its
+// progress, and stops once canceled.
+func TestMetaService_CancelTask(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	started, stopped := make(chan struct{}), make(chan struct{})
+	id := s.StartTask("rebalance", func(task *cloudMeta.Task) {
+		defer close(stopped)
+		task.SetProgress(3, 10)
+		close(started)
+		<-task.Context().Done()
+	})
+	<-started
+
+	listTasks := func() []cloudMeta.TaskInfo {
+		resp, err := http.Get("http://" + s.HTTPAddr() + "/admin/tasks")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var tasks []cloudMeta.TaskInfo
+		if err := json.NewDecoder(resp.Body).Decode(&tasks); err != nil {
+			t.Fatal(err)
+		}
+		return tasks
+	}
+	if tasks := listTasks(); len(tasks) != 1 || tasks[0].ID != id || tasks[0].Name != "rebalance" ||
+		tasks[0].Done != 3 || tasks[0].Total != 10 || tasks[0].Canceled {
+		t.Fatalf("unexpected tasks: %+v", tasks)
+	}
+
+	cancel := func(id uint64) int {
+		resp, err := http.Post(fmt.Sprintf("http://%s/admin/tasks/cancel?id=%d", s.HTTPAddr(), id), "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := cancel(id); code != http.StatusAccepted {
+		t.Fatalf("unexpected status canceling the task: %d", code)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("task didn't stop once canceled")
+	}
+
+	// The task leaves the list once it returns.
+	for i := 0; len(listTasks()) != 0; i++ {
+		if i == 100 {
+			t.Fatalf("task still listed: %+v", listTasks())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if code := cancel(id); code != http.StatusNotFound {
+		t.Fatalf("unexpected status canceling a finished task: %d", code)
+	}
+}
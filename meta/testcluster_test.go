@@ -0,0 +1,590 @@
+package meta_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/zhexuany/influxcloud"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// Ensure a 3 node test cluster elects a single leader and replicates writes.
+func TestNewTestCluster(t *testing.T) {
+	c := cloudMeta.NewTestCluster(t, 3)
+	defer c.Close()
+
+	if c.Leader(time.Second) == nil {
+		t.Fatal("expected a leader")
+	}
+
+	if nodes, _ := c.Client.MetaNodes(); len(nodes) != 3 {
+		t.Fatalf("unexpected meta nodes: %v", nodes)
+	}
+
+	if _, err := c.Client.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if db, _ := c.Client.Database("db0"); db == nil {
+		t.Fatal("database not created")
+	}
+}
+
+// Ensure closing a test cluster releases the goroutines it started.
+func TestTestCluster_CloseNoLeaks(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	c := cloudMeta.NewTestCluster(t, 3)
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Goroutines take a moment to observe the shutdown.
+	var after int
+	for i := 0; i < 100; i++ {
+		if after = runtime.NumGoroutine(); after <= before {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	buf := make([]byte, 1<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+	t.Fatalf("leaked %d goroutines:\n%s", after-before, buf)
+}
+
+// Ensure leader tasks only run on the leader and move with leadership.
+func TestService_LeaderTasks(t *testing.T) {
+	c := cloudMeta.NewTestCluster(t, 3)
+	defer c.Close()
+
+	runs := make([]int64, len(c.Services))
+	for i, s := range c.Services {
+		i := i
+		s.RegisterLeaderTask("count", 10*time.Millisecond, func() {
+			atomic.AddInt64(&runs[i], 1)
+		})
+	}
+
+	leader := c.Leader(time.Second)
+	leaderIdx := -1
+	for i, s := range c.Services {
+		if s == leader {
+			leaderIdx = i
+		}
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	for i := range c.Services {
+		n := atomic.LoadInt64(&runs[i])
+		if i == leaderIdx && n == 0 {
+			t.Fatal("expected task to run on the leader")
+		} else if i != leaderIdx && n != 0 {
+			t.Fatalf("task ran %d times on follower %d", n, i)
+		}
+	}
+
+	// Stop the leader so leadership moves to another node.
+	if err := leader.Close(); err != nil {
+		t.Fatal(err)
+	}
+	stopped := atomic.LoadInt64(&runs[leaderIdx])
+
+	newLeader := c.Leader(10 * time.Second)
+	if newLeader == nil {
+		t.Fatal("timed out waiting for a new leader")
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	for i, s := range c.Services {
+		n := atomic.LoadInt64(&runs[i])
+		switch {
+		case i == leaderIdx && n != stopped:
+			t.Fatalf("task kept running after losing leadership: %d runs, expected %d", n, stopped)
+		case s == newLeader && n == 0:
+			t.Fatal("expected task to run on the new leader")
+		case i != leaderIdx && s != newLeader && n != 0:
+			t.Fatalf("task ran %d times on follower %d", n, i)
+		}
+	}
+}
+
+// Ensure a join retried through a new leader, after the first attempt was
+// applied but its response lost, leaves a single meta node entry.
+func TestClient_JoinMetaServer_LeaderChange(t *testing.T) {
+	// Five nodes keep quorum with the joined peer unreachable and the
+	// leader stopped.
+	c := cloudMeta.NewTestCluster(t, 5)
+	defer c.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpAddr, tcpAddr := "joining:8091", ln.Addr().String()
+	ln.Close()
+
+	n, err := c.Client.JoinMetaServer(httpAddr, tcpAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Lose the leader that applied the join, then join again as a client
+	// that never saw the response would.
+	if err := c.Leader(time.Second).Close(); err != nil {
+		t.Fatal(err)
+	}
+	if c.Leader(10*time.Second) == nil {
+		t.Fatal("timed out waiting for a new leader")
+	}
+	retried, err := c.Client.JoinMetaServer(httpAddr, tcpAddr)
+	if err != nil {
+		t.Fatal(err)
+	} else if retried.ID != n.ID {
+		t.Fatalf("retried join got a new id: %d, expected %d", retried.ID, n.ID)
+	}
+
+	if _, err := c.Client.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	var entries int
+	for _, mn := range c.Client.Data().MetaNodes {
+		if mn.TCPHost == tcpAddr {
+			entries++
+		}
+	}
+	if entries != 1 {
+		t.Fatalf("unexpected number of meta node entries: %d", entries)
+	}
+}
+
+// Ensure a leader shutting down hands leadership to a follower, and a lone
+// node reports it has nobody to hand it to.
+func TestService_TransferLeadership(t *testing.T) {
+	c := cloudMeta.NewTestCluster(t, 3)
+	defer c.Close()
+
+	leader := c.Leader(10 * time.Second)
+	if leader == nil {
+		t.Fatal("no leader elected")
+	} else if !leader.IsLeader() {
+		t.Fatal("leader doesn't report itself as leader")
+	}
+	if err := leader.TransferLeadership(10 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	var next *cloudMeta.Service
+	for _, s := range c.Services {
+		if s != leader && s.IsLeader() {
+			next = s
+		}
+	}
+	if next == nil {
+		t.Fatal("no follower took over leadership")
+	} else if leader.IsLeader() {
+		t.Fatal("old leader still reports itself as leader")
+	}
+
+	single := cloudMeta.NewTestCluster(t, 1)
+	defer single.Close()
+	if s := single.Leader(10 * time.Second); s == nil {
+		t.Fatal("no leader elected")
+	} else if err := s.TransferLeadership(time.Second); err != cloudMeta.ErrNoLeadershipTarget {
+		t.Fatalf("unexpected error: got %v, expected %v", err, cloudMeta.ErrNoLeadershipTarget)
+	} else if !s.IsLeader() {
+		t.Fatal("lone node gave up leadership")
+	}
+}
+
+// Ensure removing a meta node from 3 keeps quorum, while removing a second
+// one, leaving a single voter, is refused unless forced.
+func TestClient_RemoveMetaNodeQuorum(t *testing.T) {
+	c := cloudMeta.NewTestCluster(t, 3)
+	defer c.Close()
+
+	// Remove followers, so the leader stays put.
+	leader := c.Leader(time.Second)
+	follower := func() uint64 {
+		nodes, _ := c.Client.MetaNodes()
+		for _, n := range nodes {
+			if n.Host != leader.RemoteHTTPAddr(leader.HTTPAddr()) {
+				return n.ID
+			}
+		}
+		t.Fatalf("no follower in %v", nodes)
+		return 0
+	}
+
+	id := follower()
+	if qs, err := c.Client.QuorumStatus(id); err != nil {
+		t.Fatal(err)
+	} else if qs.Voters != 3 || qs.Quorum != 2 || !qs.RemovalSafe {
+		t.Fatalf("unexpected quorum status: %+v", qs)
+	}
+	if _, err := c.Client.RemoveMetaNode(id, false); err != nil {
+		t.Fatal(err)
+	}
+
+	id = follower()
+	if qs, err := c.Client.QuorumStatus(id); err != nil {
+		t.Fatal(err)
+	} else if qs.Voters != 2 || qs.Quorum != 2 || qs.RemovalSafe {
+		t.Fatalf("unexpected quorum status: %+v", qs)
+	}
+	if _, err := c.Client.RemoveMetaNode(id, false); err != cloudMeta.ErrQuorumUnsafe {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if nodes, _ := c.Client.MetaNodes(); len(nodes) != 2 {
+		t.Fatalf("unexpected meta nodes: %v", nodes)
+	}
+
+	if _, err := c.Client.QuorumStatus(100); err != cloudMeta.ErrNodeNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure removing a data node reports the shards handed to other nodes, and
+// removing the leading meta node reports the raft peer removed and the new
+// leader.
+func TestClient_RemoveNodeResult(t *testing.T) {
+	c := cloudMeta.NewTestCluster(t, 3)
+	defer c.Close()
+
+	n1, err := c.Client.CreateDataNode("foo:8180", "bar:8181")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n2, err := c.Client.CreateDataNode("foo:8280", "bar:8281")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rp := meta.NewRetentionPolicyInfo("rp0")
+	rp.ReplicaN = 1
+	if _, err := c.Client.CreateDatabaseWithRetentionPolicy("db0", rpi2rps(rp)); err != nil {
+		t.Fatal(err)
+	}
+	sg, err := c.Client.CreateShardGroup("db0", "rp0", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var owned []uint64
+	for _, si := range sg.Shards {
+		if si.OwnedBy(n1.ID) {
+			owned = append(owned, si.ID)
+		}
+	}
+	if len(owned) == 0 {
+		t.Fatalf("data node %d owns no shards: %+v", n1.ID, sg.Shards)
+	}
+
+	res, err := c.Client.RemoveDataNode(n1.ID)
+	if err != nil {
+		t.Fatal(err)
+	} else if res.NodeType != "data" || res.NodeID != n1.ID || len(res.DeletedShardGroups) != 0 {
+		t.Fatalf("unexpected result: %+v", res)
+	} else if len(res.ReassignedShards) != len(owned) {
+		t.Fatalf("unexpected reassigned shards: %+v", res.ReassignedShards)
+	}
+	for i, r := range res.ReassignedShards {
+		if r.Database != "db0" || r.RetentionPolicy != "rp0" || r.ShardGroupID != sg.ID ||
+			r.ShardID != owned[i] || r.From != n1.ID || len(r.To) != 1 || r.To[0] != n2.ID {
+			t.Fatalf("unexpected reassigned shard: %+v", r)
+		}
+	}
+
+	// Remove the leader itself, so leadership has to move.
+	leader := c.Leader(time.Second)
+	var id uint64
+	var raftAddr string
+	for _, n := range c.Client.Data().MetaNodes {
+		if n.Host == leader.RemoteHTTPAddr(leader.HTTPAddr()) {
+			id, raftAddr = n.ID, n.TCPHost
+		}
+	}
+	res, err = c.Client.RemoveMetaNode(id, false)
+	if err != nil {
+		t.Fatal(err)
+	} else if res.NodeType != "meta" || res.NodeID != id || res.RaftPeerRemoved != raftAddr {
+		t.Fatalf("unexpected result: %+v", res)
+	} else if res.LeaderBefore != raftAddr || res.LeaderAfter == "" || !res.LeadershipTransferred {
+		t.Fatalf("unexpected leadership change: %+v", res)
+	} else if len(res.ReassignedShards) != 0 {
+		t.Fatalf("unexpected reassigned shards: %+v", res.ReassignedShards)
+	}
+}
+
+// Ensure a node recovered into a single node cluster after losing its peers
+// becomes the leader on its own, with its metadata intact.
+func TestRecoverSingleNode(t *testing.T) {
+	c := cloudMeta.NewTestCluster(t, 3)
+	defer c.Close()
+
+	if _, err := c.Client.CreateDataNode("foo:8086", "foo:8088"); err != nil {
+		t.Fatal(err)
+	} else if _, err := c.Client.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Wait for the node being recovered to have applied everything.
+	index := c.Client.Data().Data.Index
+	dir := c.Configs[0].Dir
+	resp, err := http.Get("http://" + c.Services[0].HTTPAddr() + "/checksum?index=" + strconv.FormatUint(index, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	node, err := influxcloud.LoadNode(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpAddr := c.Services[0].HTTPAddr()
+	c.Client.Close()
+	for _, s := range c.Services {
+		s.Close()
+	}
+
+	if _, err := cloudMeta.RecoverSingleNode(dir, node.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// The recovered node comes back at the HTTP address it had, which is
+	// what its meta node is known by.
+	cfg := newConfig()
+	cfg.Dir = dir
+	cfg.HTTPBindAddress = httpAddr
+	s := openService(t, cfg)
+	defer s.Close()
+
+	var status struct {
+		State string `json:"state"`
+	}
+	resp, err = http.Get("http://" + s.HTTPAddr() + "/raft-status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	} else if status.State != "Leader" {
+		t.Fatalf("unexpected raft state: %s", status.State)
+	}
+
+	client := newClient(s)
+	defer client.Close()
+	if nodes, _ := client.MetaNodes(); len(nodes) != 1 || nodes[0].ID != node.ID {
+		t.Fatalf("unexpected meta nodes: %v", nodes)
+	} else if nodes, _ := client.DataNodes(); len(nodes) != 1 {
+		t.Fatalf("unexpected data nodes: %v", nodes)
+	} else if db, _ := client.Database("db0"); db == nil {
+		t.Fatal("database lost in recovery")
+	}
+
+	// The lone node takes writes again.
+	if _, err := client.CreateDatabase("db1"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Ensure a node leaves the cluster only while quorum holds, leaving again
+// is a no-op, and the local node's ID is cleared from node.json.
+func TestClient_LeaveCluster(t *testing.T) {
+	c := cloudMeta.NewTestCluster(t, 3)
+	defer c.Close()
+
+	dir := c.Configs[0].Dir
+	node, err := influxcloud.LoadNode(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Client.LeaveCluster(node.ID); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := influxcloud.LoadNode(dir); err != nil {
+		t.Fatal(err)
+	} else if n.ID != 0 {
+		t.Fatalf("node id not cleared: %d", n.ID)
+	}
+	if nodes, _ := c.Client.MetaNodes(); len(nodes) != 2 {
+		t.Fatalf("unexpected meta nodes: %v", nodes)
+	}
+
+	if err := c.Client.LeaveCluster(node.ID); err != nil {
+		t.Fatalf("leaving again: %s", err)
+	}
+
+	nodes, _ := c.Client.MetaNodes()
+	if err := c.Client.LeaveCluster(nodes[0].ID); err != cloudMeta.ErrQuorumUnsafe {
+		t.Fatalf("got %v, expected %v", err, cloudMeta.ErrQuorumUnsafe)
+	}
+}
+
+// Ensure the connections that carried writes to a leader are drained once it
+// hands leadership off, so their clients find the new leader, while other
+// connections stay open.
+func TestService_LeaderDrain(t *testing.T) {
+	c := cloudMeta.NewTestCluster(t, 3)
+	defer c.Close()
+	leader := c.Leader(time.Second)
+
+	// request sends a request on a new connection to the leader, and
+	// returns the connection once the response is read.
+	request := func(method, path string) (net.Conn, *bufio.Reader) {
+		conn, err := net.Dial("tcp", leader.HTTPAddr())
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest(method, "http://"+leader.HTTPAddr()+path, strings.NewReader("not a command"))
+		if err != nil {
+			t.Fatal(err)
+		} else if err := req.Write(conn); err != nil {
+			t.Fatal(err)
+		}
+		br := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(br, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return conn, br
+	}
+	writer, wr := request("POST", "/execute")
+	defer writer.Close()
+	reader, rr := request("GET", "/ping")
+	defer reader.Close()
+
+	if err := leader.TransferLeadership(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	writer.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := wr.ReadByte(); err == nil {
+		t.Fatal("unexpected data on the drained connection")
+	} else if e, ok := err.(net.Error); ok && e.Timeout() {
+		t.Fatal("connection that carried a write not drained")
+	}
+	reader.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if _, err := rr.ReadByte(); err == nil {
+		t.Fatal("unexpected data on the read connection")
+	} else if e, ok := err.(net.Error); !ok || !e.Timeout() {
+		t.Fatalf("read only connection closed: %v", err)
+	}
+}
+
+// Ensure WaitForLeader names the leader, and, when the leader stops mid-wait,
+// the new one as soon as it is elected rather than after the timeout.
+func TestClient_WaitForLeader(t *testing.T) {
+	c := cloudMeta.NewTestCluster(t, 3)
+	defer c.Close()
+
+	leader := c.Leader(5 * time.Second)
+	if addr, err := c.Client.WaitForLeader(5 * time.Second); err != nil {
+		t.Fatal(err)
+	} else if addr != leader.HTTPAddr() {
+		t.Fatalf("unexpected leader: %s, expected %s", addr, leader.HTTPAddr())
+	} else if c.Client.LeaderAddr() != addr {
+		t.Fatalf("unexpected known leader: %q", c.Client.LeaderAddr())
+	}
+
+	if err := leader.Close(); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	addr, err := c.Client.WaitForLeader(time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	} else if addr == leader.HTTPAddr() {
+		t.Fatal("stopped leader named")
+	} else if newLeader := c.Leader(time.Second); newLeader == nil || addr != newLeader.HTTPAddr() {
+		t.Fatalf("unexpected leader: %s", addr)
+	} else if d := time.Since(start); d > 20*time.Second {
+		t.Fatalf("waited %s for the new leader", d)
+	}
+
+	// A write learns of the leader too.
+	if _, err := c.Client.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if c.Client.LeaderAddr() != addr {
+		t.Fatalf("unexpected known leader after a write: %q", c.Client.LeaderAddr())
+	}
+}
+
+// Ensure WaitForLeader gives up after its timeout if there is no leader.
+func TestClient_WaitForLeader_Timeout(t *testing.T) {
+	c := cloudMeta.NewTestCluster(t, 3)
+	defer c.Close()
+
+	// The one node left has no quorum to lead.
+	for _, s := range c.Services[1:] {
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.Services[0].IsLeader() {
+		// Wait for its lease to run out.
+		time.Sleep(2 * time.Second)
+	}
+	if _, err := c.Client.WaitForLeader(time.Second); err != cloudMeta.ErrLeaderWaitTimeout {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure a join retried with the same idempotency key gets the response of
+// the join it retries, and isn't applied again.
+func TestClient_JoinMetaServer_IdempotencyKey(t *testing.T) {
+	c := cloudMeta.NewTestCluster(t, 3)
+	defer c.Close()
+
+	join := func(tcpAddr string) *meta.NodeInfo {
+		b, err := json.Marshal(&meta.NodeInfo{Host: "joining:8091", TCPHost: tcpAddr})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest("POST", "http://"+c.Leader(time.Second).HTTPAddr()+"/join", strings.NewReader(string(b)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", "join-0")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			body, _ := ioutil.ReadAll(resp.Body)
+			t.Fatalf("unexpected status: %d: %s", resp.StatusCode, body)
+		}
+		n := &meta.NodeInfo{}
+		if err := json.NewDecoder(resp.Body).Decode(n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	n := join("127.0.0.1:1")
+	// The retry differs from the join it retries, to tell whether it was
+	// applied: a retry that was would join 127.0.0.1:2.
+	if retried := join("127.0.0.1:2"); retried.ID != n.ID || retried.TCPHost != n.TCPHost {
+		t.Fatalf("retried join not answered with the original: %+v, expected %+v", retried, n)
+	}
+
+	if _, err := c.Client.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	for _, mn := range c.Client.Data().MetaNodes {
+		if mn.TCPHost == "127.0.0.1:2" {
+			t.Fatalf("retried join applied: %+v", mn)
+		}
+	}
+}
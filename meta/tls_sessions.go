@@ -0,0 +1,125 @@
+package meta
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// tlsVersionNames names the TLS versions for logs and /debug/tls.
+var tlsVersionNames = map[uint16]string{
+	tls.VersionSSL30: "SSL3.0",
+	tls.VersionTLS10: "TLS1.0",
+	tls.VersionTLS11: "TLS1.1",
+	tls.VersionTLS12: "TLS1.2",
+	0x0304:           "TLS1.3",
+}
+
+// tlsCipherSuiteNames names the cipher suites for logs and /debug/tls.
+var tlsCipherSuiteNames = map[uint16]string{
+	tls.TLS_RSA_WITH_RC4_128_SHA:                "TLS_RSA_WITH_RC4_128_SHA",
+	tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA:           "TLS_RSA_WITH_3DES_EDE_CBC_SHA",
+	tls.TLS_RSA_WITH_AES_128_CBC_SHA:            "TLS_RSA_WITH_AES_128_CBC_SHA",
+	tls.TLS_RSA_WITH_AES_256_CBC_SHA:            "TLS_RSA_WITH_AES_256_CBC_SHA",
+	tls.TLS_RSA_WITH_AES_128_CBC_SHA256:         "TLS_RSA_WITH_AES_128_CBC_SHA256",
+	tls.TLS_RSA_WITH_AES_128_GCM_SHA256:         "TLS_RSA_WITH_AES_128_GCM_SHA256",
+	tls.TLS_RSA_WITH_AES_256_GCM_SHA384:         "TLS_RSA_WITH_AES_256_GCM_SHA384",
+	tls.TLS_ECDHE_ECDSA_WITH_RC4_128_SHA:        "TLS_ECDHE_ECDSA_WITH_RC4_128_SHA",
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA:    "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA",
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA:    "TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA",
+	tls.TLS_ECDHE_RSA_WITH_RC4_128_SHA:          "TLS_ECDHE_RSA_WITH_RC4_128_SHA",
+	tls.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA:     "TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA",
+	tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA:      "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA",
+	tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA:      "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA",
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256: "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256",
+	tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256:   "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256",
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256:   "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256: "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384:   "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384: "TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305:    "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305",
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305:  "TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305",
+}
+
+func tlsVersionName(v uint16) string {
+	if name, ok := tlsVersionNames[v]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", v)
+}
+
+func tlsCipherSuiteName(c uint16) string {
+	if name, ok := tlsCipherSuiteNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", c)
+}
+
+// TLSSession describes the TLS parameters negotiated by a connection to the
+// HTTP API.
+type TLSSession struct {
+	RemoteAddr  string    `json:"remoteAddr"`
+	Version     string    `json:"version"`
+	CipherSuite string    `json:"cipherSuite"`
+	ServerName  string    `json:"serverName,omitempty"`
+	Since       time.Time `json:"since"`
+}
+
+// tlsSessions tracks the TLS connections open on the HTTP API, keyed by
+// remote address.
+type tlsSessions struct {
+	mu       sync.Mutex
+	sessions map[string]TLSSession
+}
+
+func newTLSSessions() *tlsSessions {
+	return &tlsSessions{sessions: make(map[string]TLSSession)}
+}
+
+// connState is an http.Server ConnState hook. The handshake is done by the
+// time a connection turns active.
+func (t *tlsSessions) connState(conn net.Conn, state http.ConnState) {
+	tc, ok := conn.(*tls.Conn)
+	if !ok {
+		return
+	}
+	addr := conn.RemoteAddr().String()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch state {
+	case http.StateActive:
+		if _, ok := t.sessions[addr]; ok {
+			return
+		}
+		cs := tc.ConnectionState()
+		if !cs.HandshakeComplete {
+			return
+		}
+		t.sessions[addr] = TLSSession{
+			RemoteAddr:  addr,
+			Version:     tlsVersionName(cs.Version),
+			CipherSuite: tlsCipherSuiteName(cs.CipherSuite),
+			ServerName:  cs.ServerName,
+			Since:       time.Now().UTC(),
+		}
+	case http.StateClosed, http.StateHijacked:
+		delete(t.sessions, addr)
+	}
+}
+
+// list returns the open sessions sorted by remote address.
+func (t *tlsSessions) list() []TLSSession {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	a := make([]TLSSession, 0, len(t.sessions))
+	for _, s := range t.sessions {
+		a = append(a, s)
+	}
+	sort.Slice(a, func(i, j int) bool { return a[i].RemoteAddr < a[j].RemoteAddr })
+	return a
+}
@@ -0,0 +1,495 @@
+package meta_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+	"github.com/uber-go/zap"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// Ensure the access log and /debug/tls report the TLS version and cipher
+// suite each connection negotiated.
+func TestMetaService_TLSSessions(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	cfg.HTTPSEnabled = true
+	cfg.HTTPSCertificate = writeTestCertificate(t, cfg.Dir)
+	cfg.ClusterTracing = true
+	s := newService(cfg)
+	var log lockedBuffer
+	s.WithLogger(zap.New(zap.NewTextEncoder(), zap.Output(zap.AddSync(&log))))
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	const cipher = "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		InsecureSkipVerify: true,
+		MaxVersion:         tls.VersionTLS12,
+		CipherSuites:       []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+	}}}
+
+	resp, err := client.Get("https://" + s.HTTPAddr() + "/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	// The request is logged once the handler returns, which may be after the
+	// client has its response.
+	var line string
+	for i := 0; i < 100 && line == ""; i++ {
+		for _, l := range strings.Split(log.String(), "\n") {
+			if strings.Contains(l, "/ping") {
+				line = l
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !strings.Contains(line, "tls=TLS1.2") || !strings.Contains(line, "cipher="+cipher) {
+		t.Fatalf("access log doesn't record the TLS session: %q", line)
+	}
+
+	// The connection is kept alive, so it is still listed.
+	resp, err = client.Get("https://" + s.HTTPAddr() + "/debug/tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var summary struct {
+		Sessions []struct {
+			Version     string `json:"version"`
+			CipherSuite string `json:"cipherSuite"`
+		} `json:"sessions"`
+		Versions map[string]int `json:"versions"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&summary); err != nil {
+		t.Fatal(err)
+	} else if len(summary.Sessions) != 1 || summary.Sessions[0].Version != "TLS1.2" || summary.Sessions[0].CipherSuite != cipher {
+		t.Fatalf("unexpected sessions: %+v", summary.Sessions)
+	} else if summary.Versions["TLS1.2"] != 1 {
+		t.Fatalf("unexpected versions: %v", summary.Versions)
+	}
+}
+
+// Ensure a changed certificate file is served to new connections once
+// reloaded, and one that can't be loaded leaves the previous certificate
+// in use.
+func TestMetaService_ReloadCertificate(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	cfg.HTTPSEnabled = true
+	cfg.HTTPSCertificate = writeTestCertificate(t, cfg.Dir)
+	s := openService(t, cfg)
+	defer s.Close()
+
+	// peerCertificate returns the certificate s serves on a new connection.
+	peerCertificate := func() []byte {
+		conn, err := tls.Dial("tcp", s.HTTPAddr(), &tls.Config{InsecureSkipVerify: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].Raw
+	}
+	old := peerCertificate()
+
+	if reloaded, err := s.ReloadCertificate(); err != nil || reloaded {
+		t.Fatalf("unchanged certificate reloaded: %v, %v", reloaded, err)
+	}
+
+	// Move the modification time on, as the rewrite may land in the same
+	// tick of a coarse filesystem clock.
+	writeTestCertificate(t, cfg.Dir)
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(cfg.HTTPSCertificate, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if reloaded, err := s.ReloadCertificate(); err != nil || !reloaded {
+		t.Fatalf("changed certificate not reloaded: %v, %v", reloaded, err)
+	}
+	current := peerCertificate()
+	if bytes.Equal(current, old) {
+		t.Fatal("previous certificate still served")
+	}
+
+	if err := ioutil.WriteFile(cfg.HTTPSCertificate, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ReloadCertificate(); err == nil {
+		t.Fatal("expected error reloading invalid certificate")
+	} else if !bytes.Equal(peerCertificate(), current) {
+		t.Fatal("certificate changed after failed reload")
+	}
+}
+
+// testSecretProvider is a SecretProvider serving the certificate and
+// certificate authorities last set, standing in for a secret store.
+type testSecretProvider struct {
+	mu   sync.Mutex
+	cert tls.Certificate
+	ca   []byte
+}
+
+func (p *testSecretProvider) set(cert tls.Certificate) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cert = cert
+}
+
+func (p *testSecretProvider) setCA(ca *testCertificate) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ca = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw})
+}
+
+func (p *testSecretProvider) GetCertificate() (*tls.Certificate, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	cert := p.cert
+	return &cert, nil
+}
+
+func (p *testSecretProvider) GetCA() ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.ca, nil
+}
+
+// Ensure the HTTPS API serves the certificate of a SecretProvider, and the
+// new one once the provider rotates it.
+func TestMetaService_SecretProvider(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	first := newTestCertificate(t, cfg.Dir, "first.pem", nil)
+	provider := &testSecretProvider{cert: first.tlsCertificate()}
+	cfg.HTTPSEnabled = true
+	cfg.SecretProvider = provider
+	s := openService(t, cfg)
+	defer s.Close()
+
+	// peerCertificate returns the certificate s serves on a new connection.
+	peerCertificate := func() []byte {
+		conn, err := tls.Dial("tcp", s.HTTPAddr(), &tls.Config{InsecureSkipVerify: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].Raw
+	}
+	if !bytes.Equal(peerCertificate(), first.cert.Raw) {
+		t.Fatal("provider certificate not served")
+	}
+
+	if reloaded, err := s.ReloadCertificate(); err != nil || reloaded {
+		t.Fatalf("unchanged certificate reloaded: %v, %v", reloaded, err)
+	}
+
+	second := newTestCertificate(t, cfg.Dir, "second.pem", nil)
+	provider.set(second.tlsCertificate())
+	if reloaded, err := s.ReloadCertificate(); err != nil || !reloaded {
+		t.Fatalf("rotated certificate not reloaded: %v, %v", reloaded, err)
+	}
+	if !bytes.Equal(peerCertificate(), second.cert.Raw) {
+		t.Fatal("rotated certificate not served")
+	}
+}
+
+// Ensure client certificates presented to the HTTPS API are verified against
+// the certificate authorities of the SecretProvider, and the new ones once
+// the provider rotates them.
+func TestMetaService_SecretProvider_RotateCA(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	first := newTestCertificate(t, cfg.Dir, "first-ca.pem", nil)
+	second := newTestCertificate(t, cfg.Dir, "second-ca.pem", nil)
+	node := newTestCertificate(t, cfg.Dir, "node.pem", first)
+	client := newTestCertificate(t, cfg.Dir, "client.pem", second)
+
+	provider := &testSecretProvider{cert: node.tlsCertificate()}
+	provider.setCA(first)
+	cfg.HTTPSEnabled = true
+	cfg.InternalCert = node.path
+	cfg.InternalKey = node.path
+	cfg.SecretProvider = provider
+	s := openService(t, cfg)
+	defer s.Close()
+
+	// ping pings s presenting the client certificate signed by the second
+	// certificate authority.
+	ping := func() error {
+		c := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			Certificates:       []tls.Certificate{client.tlsCertificate()},
+			InsecureSkipVerify: true,
+		}}}
+		resp, err := c.Get("https://" + s.HTTPAddr() + "/ping")
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+	if err := ping(); err == nil {
+		t.Fatal("client certificate of an untrusted authority accepted")
+	}
+
+	provider.setCA(second)
+	if reloaded, err := s.ReloadCertificate(); err != nil || !reloaded {
+		t.Fatalf("rotated certificate authority not reloaded: %v, %v", reloaded, err)
+	}
+	if err := ping(); err != nil {
+		t.Fatalf("client certificate of the rotated authority rejected: %s", err)
+	}
+}
+
+// Ensure meta nodes requiring client certificates form a cluster over
+// mutual TLS, and reject raft connections that present no certificate or
+// one not signed by internal-ca.
+func TestTestCluster_RaftMutualTLS(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "meta-mtls-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ca := newTestCertificate(t, dir, "ca.pem", nil)
+	node := newTestCertificate(t, dir, "node.pem", ca)
+	untrusted := newTestCertificate(t, dir, "untrusted.pem", nil)
+
+	c := cloudMeta.NewTestClusterWithConfig(t, 2, func(i int, cfg *cloudMeta.Config) {
+		cfg.InternalCA = ca.path
+		cfg.InternalCert = node.path
+		cfg.InternalKey = node.path
+		cfg.RequireClientCert = true
+	})
+	defer c.Close()
+	if _, err := c.Client.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	for _, tt := range []struct {
+		name     string
+		cert     *testCertificate
+		accepted bool
+	}{
+		{name: "no certificate"},
+		{name: "untrusted certificate", cert: untrusted},
+		{name: "trusted certificate", cert: node, accepted: true},
+	} {
+		conn, err := net.Dial("tcp", c.Configs[0].BindAddress)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := conn.Write([]byte{cloudMeta.MuxHeader}); err != nil {
+			t.Fatal(err)
+		}
+		config := &tls.Config{RootCAs: roots, ServerName: "127.0.0.1"}
+		if tt.cert != nil {
+			config.Certificates = []tls.Certificate{tt.cert.tlsCertificate()}
+		}
+		tc := tls.Client(conn, config)
+
+		// An accepted connection waits for a raft RPC, while a rejected one
+		// is closed, which the client may only see once it reads.
+		tc.SetDeadline(time.Now().Add(500 * time.Millisecond))
+		err = tc.Handshake()
+		if err == nil {
+			_, err = tc.Read(make([]byte, 1))
+		}
+		tc.Close()
+		if e, ok := err.(net.Error); (ok && e.Timeout()) != tt.accepted {
+			t.Errorf("%s: accepted=%v, got %v", tt.name, tt.accepted, err)
+		}
+	}
+}
+
+// writeTestCertificate writes a self-signed certificate for 127.0.0.1 and its
+// key to a single PEM file in dir and returns its path.
+func writeTestCertificate(t *testing.T, dir string) string {
+	return newTestCertificate(t, dir, "cert.pem", nil).path
+}
+
+// testCertificate is a certificate and its key, written to a PEM file.
+type testCertificate struct {
+	path string
+	cert *x509.Certificate
+	key  *rsa.PrivateKey
+}
+
+// tlsCertificate returns c for a tls.Config.
+func (c *testCertificate) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key}
+}
+
+// newTestCertificate writes a certificate for 127.0.0.1 and its key to a
+// single PEM file named name in dir. It is signed by ca, or is a self-signed
+// certificate authority if ca is nil.
+func newTestCertificate(t *testing.T, dir, name string, ca *testCertificate) *testCertificate {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	parent, parentKey := tmpl, key
+	if ca != nil {
+		parent, parentKey = ca.cert, ca.key
+	} else {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: der})
+	pem.Encode(&buf, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return &testCertificate{path: path, cert: cert, key: key}
+}
+
+// Ensure meta nodes whose HTTPS certificates internal-ca signed reach each
+// other's HTTP API, as a write waiting on every node's raft log does.
+func TestTestCluster_HTTPSInternalCA(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "meta-https-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ca := newTestCertificate(t, dir, "ca.pem", nil)
+	node := newTestCertificate(t, dir, "node.pem", ca)
+
+	c := cloudMeta.NewTestClusterWithConfig(t, 2, func(i int, cfg *cloudMeta.Config) {
+		cfg.HTTPSEnabled = true
+		cfg.HTTPSCertificate = node.path
+		cfg.InternalCA = ca.path
+		cfg.InternalCert = node.path
+		cfg.InternalKey = node.path
+		cfg.WriteDurability = cloudMeta.WriteDurabilityAll
+		cfg.WriteDurabilityTimeout = toml.Duration(5 * time.Second)
+	})
+	defer c.Close()
+
+	if _, err := c.Client.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Ensure the principal of a client certificate is only allowed what its
+// roles grant: a reader lists the cluster nodes but can't drop one.
+func TestMetaService_Authorizer(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	ca := newTestCertificate(t, cfg.Dir, "ca.pem", nil)
+	node := newTestCertificate(t, cfg.Dir, "node.pem", ca)
+	cfg.HTTPSEnabled = true
+	cfg.HTTPSCertificate = node.path
+	cfg.InternalCA = ca.path
+	cfg.InternalCert = node.path
+	cfg.InternalKey = node.path
+
+	authorizer := cloudMeta.NewRBACAuthorizer()
+	authorizer.AddRole("reader", cloudMeta.ActionRead)
+	authorizer.Grant(node.cert.Subject.CommonName, "reader")
+	cfg.Authorizer = authorizer
+
+	s := openService(t, cfg)
+	defer s.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	client := func(cert *testCertificate) *http.Client {
+		config := &tls.Config{RootCAs: roots}
+		if cert != nil {
+			config.Certificates = []tls.Certificate{cert.tlsCertificate()}
+		}
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+	}
+	reader, anonymous := client(node), client(nil)
+
+	for _, tt := range []struct {
+		name   string
+		client *http.Client
+		method string
+		path   string
+		status int
+	}{
+		{name: "reader read", client: reader, method: "GET", path: "/cluster/nodes", status: http.StatusOK},
+		{name: "reader node removal", client: reader, method: "POST", path: "/cluster/nodes/1/drop", status: http.StatusForbidden},
+		{name: "anonymous read", client: anonymous, method: "GET", path: "/cluster/nodes", status: http.StatusForbidden},
+	} {
+		req, err := http.NewRequest(tt.method, "https://"+s.HTTPAddr()+tt.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := tt.client.Do(req)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: got status %d, expected %d", tt.name, resp.StatusCode, tt.status)
+		}
+	}
+	if nodes, err := s.Nodes(); err != nil {
+		t.Fatal(err)
+	} else if len(nodes) != 1 {
+		t.Fatalf("node dropped: %+v", nodes)
+	}
+}
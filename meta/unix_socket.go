@@ -0,0 +1,138 @@
+package meta
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// unixSocketPrefix marks a bind address as the path of a Unix domain
+	// socket, as in unix:///var/run/influxdb/meta.sock.
+	unixSocketPrefix = "unix://"
+
+	// unixSocketDomain ends the made up host names the meta client gives
+	// the Unix sockets of meta servers, so its transport knows to dial them.
+	unixSocketDomain = ".unix-socket"
+)
+
+// unixSocketPath returns the socket path of addr and true if addr names a
+// Unix domain socket.
+func unixSocketPath(addr string) (string, bool) {
+	if !strings.HasPrefix(addr, unixSocketPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(addr, unixSocketPrefix), true
+}
+
+// unixSocketHost returns the host name URLs to the socket at path are built
+// with. The path is hex encoded, as a host can't hold slashes.
+func unixSocketHost(path string) string {
+	return hex.EncodeToString([]byte(path)) + unixSocketDomain
+}
+
+// dialUnixSocket wraps dial so that a host made by unixSocketHost is dialed
+// as the socket it names.
+func dialUnixSocket(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil || !strings.HasSuffix(host, unixSocketDomain) {
+			return dial(ctx, network, addr)
+		}
+		path, err := hex.DecodeString(strings.TrimSuffix(host, unixSocketDomain))
+		if err != nil {
+			return nil, fmt.Errorf("unix socket host %q: %s", host, err)
+		}
+		return dial(ctx, "unix", string(path))
+	}
+}
+
+// socketMode returns socket-permissions as a file mode.
+func (c *Config) socketMode() (os.FileMode, error) {
+	mode, err := strconv.ParseUint(c.SocketPermissions, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("must be octal file permissions, got %q", c.SocketPermissions)
+	}
+	return os.FileMode(mode), nil
+}
+
+// Listen listens on addr: on the Unix domain socket it names if it starts
+// with unix://, with socket-permissions, or else on TCP. A socket file left
+// behind by a node that didn't shut down cleanly is removed first, but any
+// other file at the path is refused. The socket file is removed again when
+// the listener is closed.
+func (c *Config) Listen(addr string) (net.Listener, error) {
+	path, ok := unixSocketPath(addr)
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+	mode, err := c.socketMode()
+	if err != nil {
+		return nil, fmt.Errorf("socket-permissions: %s", err)
+	}
+
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("listen unix %s: file exists and isn't a socket", path)
+		}
+		// Only remove the socket if nothing listens on it anymore.
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("listen unix %s: socket in use", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("remove stale socket: %s", err)
+		}
+	}
+
+	// The socket is created with the process's umask, so create it in a
+	// directory only this process can enter and move it into place once it
+	// has socket-permissions.
+	dir, err := ioutil.TempDir(filepath.Dir(path), ".socket")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	tmp := filepath.Join(dir, filepath.Base(path))
+	ln, err := net.ListenUnix("unix", &net.UnixAddr{Name: tmp, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	ln.SetUnlinkOnClose(false)
+	if err := os.Chmod(tmp, mode); err != nil {
+		ln.Close()
+		return nil, err
+	} else if err := os.Rename(tmp, path); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return &unixSocketListener{UnixListener: ln, path: path}, nil
+}
+
+// unixSocketListener is a listener on the Unix domain socket at path, moved
+// there after it was created, which it removes when closed.
+type unixSocketListener struct {
+	*net.UnixListener
+	path string
+}
+
+// Addr returns the address of the socket at its path.
+func (ln *unixSocketListener) Addr() net.Addr {
+	return &net.UnixAddr{Name: ln.path, Net: "unix"}
+}
+
+// Close stops listening and removes the socket file.
+func (ln *unixSocketListener) Close() error {
+	err := ln.UnixListener.Close()
+	if rerr := os.Remove(ln.path); rerr != nil && !os.IsNotExist(rerr) && err == nil {
+		err = rerr
+	}
+	return err
+}
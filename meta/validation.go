@@ -0,0 +1,70 @@
+package meta
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+// FieldError is a validation failure of a single field. Field is the path to
+// the offending field, such as "retentionPolicy.duration".
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError holds every field that failed validation so they can all
+// be reported at once.
+type ValidationError struct {
+	Errors []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	a := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		a[i] = fe.Error()
+	}
+	return strings.Join(a, "; ")
+}
+
+// add records a failure of field.
+func (e *ValidationError) add(field, format string, a ...interface{}) {
+	e.Errors = append(e.Errors, &FieldError{Field: field, Message: fmt.Sprintf(format, a...)})
+}
+
+// err returns e if any field failed and nil otherwise.
+func (e *ValidationError) err() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+// validateRetentionPolicySpec checks spec before it's sent to the cluster.
+// An unnamed spec is only an error if requireName is set: the retention
+// policy of a new database is named autogen when it has no name.
+func validateRetentionPolicySpec(spec *meta.RetentionPolicySpec, requireName bool) error {
+	var v ValidationError
+	if requireName && spec.Name == "" {
+		v.add("retentionPolicy.name", "%s", ErrRetentionPolicyNameRequired)
+	}
+	if spec.Duration != nil && *spec.Duration != 0 {
+		if *spec.Duration < MinRetentionPolicyDuration {
+			v.add("retentionPolicy.duration", "%s", ErrRetentionPolicyDurationTooLow)
+		} else if spec.ShardGroupDuration > *spec.Duration {
+			v.add("retentionPolicy.shardGroupDuration", "must not be longer than the duration %v", *spec.Duration)
+		}
+	}
+	if spec.ShardGroupDuration < 0 {
+		v.add("retentionPolicy.shardGroupDuration", "must not be negative")
+	}
+	if spec.ReplicaN != nil && *spec.ReplicaN < 1 {
+		v.add("retentionPolicy.replicaN", "%s", ErrReplicationFactorTooLow)
+	}
+	return v.err()
+}
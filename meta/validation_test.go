@@ -0,0 +1,81 @@
+package meta_test
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// Ensure a database created with an unnamed retention policy, as by
+// CREATE DATABASE db WITH DURATION 1d, gets it as autogen.
+func TestMetaService_CreateDatabaseWithRetentionPolicy_Unnamed(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	day := 24 * time.Hour
+	db, err := c.CreateDatabaseWithRetentionPolicy("db0", &meta.RetentionPolicySpec{Duration: &day})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.DefaultRetentionPolicy != "autogen" {
+		t.Fatalf("unexpected default retention policy: %q", db.DefaultRetentionPolicy)
+	} else if rp := db.RetentionPolicy("autogen"); rp == nil || rp.Duration != day {
+		t.Fatalf("unexpected retention policy: %+v", rp)
+	}
+}
+
+// Ensure an invalid retention policy reports every offending field.
+func TestMetaService_CreateRetentionPolicy_ValidationErrors(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	if _, err := c.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+	duration := time.Minute
+	replicaN := 0
+	_, err := c.CreateRetentionPolicy("db0", &meta.RetentionPolicySpec{
+		Duration: &duration,
+		ReplicaN: &replicaN,
+	})
+	verr, ok := err.(*cloudMeta.ValidationError)
+	if !ok {
+		t.Fatalf("expected a *ValidationError, got %T: %v", err, err)
+	}
+
+	got := make(map[string]string)
+	for _, fe := range verr.Errors {
+		got[fe.Field] = fe.Message
+	}
+	exp := map[string]string{
+		"retentionPolicy.name":     cloudMeta.ErrRetentionPolicyNameRequired.Error(),
+		"retentionPolicy.duration": cloudMeta.ErrRetentionPolicyDurationTooLow.Error(),
+		"retentionPolicy.replicaN": cloudMeta.ErrReplicationFactorTooLow.Error(),
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected field errors:\ngot: %v\nexp: %v", got, exp)
+	}
+
+	if db, _ := c.Database("db0"); len(db.RetentionPolicies) != 1 {
+		t.Fatalf("retention policy created despite being invalid: %+v", db.RetentionPolicies)
+	}
+
+	// The retention policy of a new database is checked the same way.
+	if _, err := c.CreateDatabaseWithRetentionPolicy("db1", &meta.RetentionPolicySpec{Duration: &duration}); err == nil {
+		t.Fatal("expected too short a duration to be rejected")
+	} else if db, _ := c.Database("db1"); db != nil {
+		t.Fatal("database created despite invalid retention policy")
+	}
+}
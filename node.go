@@ -1,9 +1,15 @@
 package influxcloud
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 const (
@@ -15,27 +21,134 @@ const (
 type Node struct {
 	path string
 	ID   uint64
+
+	// Validated is when, in Unix seconds, the node last confirmed with the
+	// cluster that it is a member. It is zero if it never has, as for a
+	// node file written by an older version.
+	Validated int64 `json:",omitempty"`
+
+	// HTTPAddr and TCPAddr are the addresses the node last advertised to
+	// the cluster, to tell when they changed between restarts.
+	HTTPAddr string `json:",omitempty"`
+	TCPAddr  string `json:",omitempty"`
 }
 
-// LoadNode will load the node information from disk if present
+// LoadNode will load the node information from disk if present. If the node
+// file doesn't exist, the error satisfies os.IsNotExist.
+//
+// A node file left behind by an interrupted upgrade may repeat a field.
+// Fields repeated with the same value are collapsed and the file is rewritten;
+// fields repeated with differing values are refused, as there is no way to
+// tell which one is current. A file written in an older schema is rewritten
+// the way Save writes it. An empty, truncated or otherwise malformed file is
+// refused with an error naming it.
 func LoadNode(path string) (*Node, error) {
 	n := &Node{
 		path: path,
 	}
 
-	f, err := os.Open(filepath.Join(path, nodeFile))
+	file := filepath.Join(path, nodeFile)
+	start := time.Now()
+	buf, err := ioutil.ReadFile(file)
+	// A node that hasn't joined a cluster yet has no node file.
+	if os.IsNotExist(err) {
+		recordNodeRead(time.Since(start), nil)
+	} else {
+		recordNodeRead(time.Since(start), err)
+	}
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
 
-	if err := json.NewDecoder(f).Decode(n); err != nil {
+	dup, err := checkNodeFields(buf)
+	if err != nil {
+		return nil, corruptNodeFile(file, buf, err)
+	}
+
+	if err := json.Unmarshal(buf, n); err != nil {
+		return nil, corruptNodeFile(file, buf, err)
+	}
+
+	// Compare the file with what Save would write to catch older schemas.
+	cur, err := json.Marshal(n)
+	if err != nil {
 		return nil, err
 	}
+	var old bytes.Buffer
+	if err := json.Compact(&old, buf); err != nil {
+		return nil, corruptNodeFile(file, buf, err)
+	}
+
+	if dup || !bytes.Equal(old.Bytes(), cur) {
+		if err := n.Save(); err != nil {
+			return nil, err
+		}
+	}
 
 	return n, nil
 }
 
+// corruptNodeFile returns the error for the node file at file, holding buf,
+// that couldn't be decoded because of err.
+func corruptNodeFile(file string, buf []byte, err error) error {
+	var v interface{}
+	if len(bytes.TrimSpace(buf)) == 0 {
+		err = fmt.Errorf("file is empty")
+	} else if se, ok := json.Unmarshal(buf, &v).(*json.SyntaxError); ok && se.Offset >= int64(len(buf)) {
+		err = fmt.Errorf("file is truncated")
+	}
+	return fmt.Errorf("%s: %s; restore it from a backup, or remove it to join the cluster as a new node", file, err)
+}
+
+// checkNodeFields reports whether the node file in buf repeats any top-level
+// field. Field names are compared case insensitively, the way encoding/json
+// matches them to struct fields. It returns an error if buf isn't a single
+// JSON object or a repeated field has differing values.
+func checkNodeFields(buf []byte) (bool, error) {
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	if tok, err := dec.Token(); err != nil {
+		return false, err
+	} else if tok != json.Delim('{') {
+		return false, fmt.Errorf("expected a JSON object, got %v", tok)
+	}
+
+	var dup bool
+	fields := make(map[string]string)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return false, err
+		}
+		key := tok.(string)
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return false, fmt.Errorf("field %q: %s", key, err)
+		}
+		var val bytes.Buffer
+		if err := json.Compact(&val, raw); err != nil {
+			return false, fmt.Errorf("field %q: %s", key, err)
+		}
+
+		name := strings.ToLower(key)
+		if prev, ok := fields[name]; ok {
+			if prev != val.String() {
+				return false, fmt.Errorf("conflicting values for field %q: %s and %s", key, prev, val.String())
+			}
+			dup = true
+		}
+		fields[name] = val.String()
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return false, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return false, fmt.Errorf("unexpected data after the JSON object")
+	}
+	return dup, nil
+}
+
 // NewNode will return a new node
 func NewNode(path string) *Node {
 	return &Node{
@@ -43,9 +156,25 @@ func NewNode(path string) *Node {
 	}
 }
 
+// ValidatedAt returns when the node last confirmed with the cluster that
+// it is a member, or the zero time if it never has.
+func (n *Node) ValidatedAt() time.Time {
+	if n.Validated == 0 {
+		return time.Time{}
+	}
+	return time.Unix(n.Validated, 0)
+}
+
 // Save will save the node file to disk and replace the existing one if present
 func (n *Node) Save() error {
 	file := filepath.Join(n.path, nodeFile)
+	start := time.Now()
+	err := n.save(file)
+	recordNodeWrite(file, time.Since(start), err)
+	return err
+}
+
+func (n *Node) save(file string) error {
 	tmpFile := file + "tmp"
 
 	f, err := os.Create(tmpFile)
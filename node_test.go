@@ -0,0 +1,159 @@
+package influxcloud_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zhexuany/influxcloud"
+)
+
+// Ensure a node file repeating a field with the same value loads and is
+// rewritten without the duplicate.
+func TestLoadNode_DuplicateField(t *testing.T) {
+	dir := mustWriteNodeFile(t, `{"ID":3,"id":3}`)
+	defer os.RemoveAll(dir)
+
+	n, err := influxcloud.LoadNode(dir)
+	if err != nil {
+		t.Fatal(err)
+	} else if n.ID != 3 {
+		t.Fatalf("unexpected id: %d", n.ID)
+	}
+
+	buf, err := ioutil.ReadFile(filepath.Join(dir, "node.json"))
+	if err != nil {
+		t.Fatal(err)
+	} else if got := strings.TrimSpace(string(buf)); got != `{"ID":3}` {
+		t.Fatalf("node file not repaired: %s", got)
+	}
+}
+
+// Ensure a node file that is malformed or repeats a field with differing
+// values is refused and left untouched.
+func TestLoadNode_Malformed(t *testing.T) {
+	for _, tt := range []struct {
+		data string
+		err  string
+	}{
+		{data: `{"ID":3,"id":4}`, err: `conflicting values for field "id": 3 and 4`},
+		{data: `{"ID":3}{"ID":4}`, err: "unexpected data after the JSON object"},
+		{data: `[3]`, err: "expected a JSON object"},
+	} {
+		dir := mustWriteNodeFile(t, tt.data)
+		defer os.RemoveAll(dir)
+
+		if _, err := influxcloud.LoadNode(dir); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: unexpected error: %v", tt.data, err)
+		}
+
+		buf, err := ioutil.ReadFile(filepath.Join(dir, "node.json"))
+		if err != nil {
+			t.Fatal(err)
+		} else if string(buf) != tt.data {
+			t.Errorf("%s: node file changed: %s", tt.data, buf)
+		}
+	}
+}
+
+// Ensure an empty or truncated node file is refused with an error naming
+// it, and left untouched.
+func TestLoadNode_Corrupt(t *testing.T) {
+	for _, tt := range []struct {
+		data string
+		err  string
+	}{
+		{data: ``, err: "file is empty"},
+		{data: "  \n", err: "file is empty"},
+		{data: `{"ID":3`, err: "file is truncated"},
+		{data: `{"ID":`, err: "file is truncated"},
+		{data: `{"ID":"three"}`, err: "cannot unmarshal string"},
+	} {
+		dir := mustWriteNodeFile(t, tt.data)
+		defer os.RemoveAll(dir)
+
+		_, err := influxcloud.LoadNode(dir)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%q: unexpected error: %v", tt.data, err)
+		} else if file := filepath.Join(dir, "node.json"); !strings.HasPrefix(err.Error(), file+": ") {
+			t.Errorf("%q: error doesn't name %s: %v", tt.data, file, err)
+		}
+
+		buf, err := ioutil.ReadFile(filepath.Join(dir, "node.json"))
+		if err != nil {
+			t.Fatal(err)
+		} else if string(buf) != tt.data {
+			t.Errorf("%q: node file changed: %s", tt.data, buf)
+		}
+	}
+}
+
+// Ensure a node file written in an older schema loads and is rewritten in
+// the current one, while a current one is left alone.
+func TestLoadNode_OldSchema(t *testing.T) {
+	for _, tt := range []struct {
+		data    string
+		written string
+	}{
+		{data: `{"id":3}`, written: `{"ID":3}`},
+		{data: `{"ID":3,"Peers":["localhost:8089"]}`, written: `{"ID":3}`},
+		{data: "{\"ID\":3}\n", written: "{\"ID\":3}\n"},
+	} {
+		dir := mustWriteNodeFile(t, tt.data)
+		defer os.RemoveAll(dir)
+
+		n, err := influxcloud.LoadNode(dir)
+		if err != nil {
+			t.Fatalf("%q: %s", tt.data, err)
+		} else if n.ID != 3 {
+			t.Fatalf("%q: unexpected id: %d", tt.data, n.ID)
+		}
+
+		buf, err := ioutil.ReadFile(filepath.Join(dir, "node.json"))
+		if err != nil {
+			t.Fatal(err)
+		} else if strings.TrimSpace(string(buf)) != strings.TrimSpace(tt.written) {
+			t.Errorf("%q: unexpected node file: %s", tt.data, buf)
+		}
+	}
+}
+
+// Ensure saving a node file counts the write and records how long it took.
+func TestNode_Save_Stats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "influxcloud-node-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	before := influxcloud.Stats()
+	n := influxcloud.NewNode(dir)
+	n.ID = 3
+	if err := n.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	after := influxcloud.Stats()
+	if after.NodeWrites <= before.NodeWrites {
+		t.Fatalf("write not counted: %d writes before, %d after", before.NodeWrites, after.NodeWrites)
+	} else if after.NodeWriteTime <= before.NodeWriteTime {
+		t.Fatalf("write latency not recorded: %s before, %s after", before.NodeWriteTime, after.NodeWriteTime)
+	} else if after.NodeWriteErrors != before.NodeWriteErrors {
+		t.Fatalf("write counted as failed: %d errors before, %d after", before.NodeWriteErrors, after.NodeWriteErrors)
+	}
+}
+
+// mustWriteNodeFile writes data as the node file of a new temporary directory
+// and returns the directory.
+func mustWriteNodeFile(t *testing.T, data string) string {
+	dir, err := ioutil.TempDir("", "influxcloud-node-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "node.json"), []byte(data), 0666); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}